@@ -0,0 +1,924 @@
+package ast
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/13k/go-steam-language/token"
+)
+
+var (
+	ErrNilSymbol       = errors.New("nil symbol")
+	ErrEmptySymbol     = errors.New("empty symbol")
+	ErrDuplicateSymbol = errors.New("duplicate symbol")
+)
+
+type Symbol struct {
+	Value string
+	Scope Node
+	Node  Node
+}
+
+type Node interface {
+	Name() string
+	NamePath() []string
+	Parent() Node
+	SetParent(Node)
+	Path() []Node
+	Ancestors() []Node
+	Children() []Node
+	AddChild(Node)
+	InsertChild(int, Node)
+	RemoveChild(Node) bool
+	AdoptChildren(Node)
+	ClearChildren()
+	Symbols() []*Symbol
+	CreateSymbol(string, Node) (*Symbol, error)
+	AddSymbol(*Symbol) error
+	FindSymbol(string, bool) *Symbol
+	FindNestedSymbol([]string) *Symbol
+	ImportSymbols(Node) error
+	RemoveSymbol(string) *Symbol
+	ClearSymbols()
+}
+
+// symbolTable indexes symbols by value while keeping insertion order, so that
+// Symbols() (and anything generated from it) is stable across runs.
+type symbolTable struct {
+	index map[string]*Symbol
+	order []*Symbol
+}
+
+func newSymbolTable() *symbolTable {
+	return &symbolTable{index: make(map[string]*Symbol)}
+}
+
+func (t *symbolTable) Get(value string) (*Symbol, bool) {
+	sym, ok := t.index[value]
+	return sym, ok
+}
+
+func (t *symbolTable) Add(sym *Symbol) {
+	t.index[sym.Value] = sym
+	t.order = append(t.order, sym)
+}
+
+func (t *symbolTable) Remove(value string) *Symbol {
+	sym, ok := t.index[value]
+
+	if !ok {
+		return nil
+	}
+
+	delete(t.index, value)
+
+	for i, s := range t.order {
+		if s == sym {
+			t.order = append(t.order[:i], t.order[i+1:]...)
+			break
+		}
+	}
+
+	return sym
+}
+
+func (t *symbolTable) Symbols() []*Symbol {
+	symbols := make([]*Symbol, len(t.order))
+	copy(symbols, t.order)
+	return symbols
+}
+
+func (t *symbolTable) Clear() {
+	t.index = make(map[string]*Symbol)
+	t.order = nil
+}
+
+type node struct {
+	parent   Node
+	owner    Node
+	children []Node
+	symbols  *symbolTable
+}
+
+func NewNode(parent Node) Node {
+	return newNode(parent, nil)
+}
+
+func newNode(parent Node, owner Node) *node {
+	n := &node{
+		parent:  parent,
+		owner:   owner,
+		symbols: newSymbolTable(),
+	}
+
+	if parent != nil {
+		parent.AddChild(n)
+	}
+
+	return n
+}
+
+func (n *node) self() Node {
+	if n.owner != nil {
+		return n.owner
+	}
+
+	return n
+}
+
+func (n *node) Name() string {
+	if n.owner != nil {
+		return n.owner.Name()
+	}
+
+	return fmt.Sprintf("%p", n)
+}
+
+func (n *node) NamePath() []string {
+	var namepath []string
+	path := n.Path()
+
+	for _, node := range path {
+		namepath = append(namepath, node.Name())
+	}
+
+	return namepath
+}
+
+func (n *node) Ancestors() []Node {
+	var path []Node
+
+	if n.Parent() != nil {
+		path = append(path, n.Parent().Ancestors()...)
+		path = append(path, n.Parent())
+	}
+
+	return path
+}
+
+func (n *node) Path() []Node {
+	path := n.Ancestors()
+
+	if n.owner != nil {
+		path = append(path, n.owner)
+	} else {
+		path = append(path, n)
+	}
+
+	return path
+}
+
+func (n *node) Parent() Node {
+	return n.parent
+}
+
+func (n *node) SetParent(parent Node) {
+	n.parent = parent
+}
+
+func (n *node) Children() []Node {
+	return n.children
+}
+
+func (n *node) AddChild(child Node) {
+	child.SetParent(n.self())
+	n.children = append(n.children, child)
+}
+
+// InsertChild inserts child at index i, clamped to the bounds of the children
+// list.
+func (n *node) InsertChild(i int, child Node) {
+	if i < 0 {
+		i = 0
+	}
+
+	if i > len(n.children) {
+		i = len(n.children)
+	}
+
+	child.SetParent(n.self())
+	n.children = append(n.children, nil)
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = child
+}
+
+// RemoveChild detaches child from n. It reports whether child was found.
+func (n *node) RemoveChild(child Node) bool {
+	for i, c := range n.children {
+		if c == child {
+			n.children = append(n.children[:i], n.children[i+1:]...)
+			child.SetParent(nil)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (n *node) AdoptChildren(other Node) {
+	for _, child := range other.Children() {
+		n.AddChild(child)
+	}
+
+	other.ClearChildren()
+}
+
+func (n *node) ClearChildren() {
+	n.children = nil
+}
+
+func (n *node) CreateSymbol(value string, node Node) (*Symbol, error) {
+	sym := &Symbol{Value: value, Node: node}
+
+	if err := n.AddSymbol(sym); err != nil {
+		return nil, err
+	}
+
+	return sym, nil
+}
+
+func (n *node) AddSymbol(s *Symbol) error {
+	if s == nil {
+		return fmt.Errorf("Trying to add %w to node %v", ErrNilSymbol, n.NamePath())
+	}
+
+	if s.Value == "" {
+		return fmt.Errorf("Trying to add %w to node %v", ErrEmptySymbol, n.NamePath())
+	}
+
+	if _, ok := n.symbols.Get(s.Value); ok {
+		return fmt.Errorf("Trying to add %w %q to node %v", ErrDuplicateSymbol, s.Value, n.NamePath())
+	}
+
+	s.Scope = n
+	n.symbols.Add(s)
+
+	return nil
+}
+
+func (n *node) FindSymbol(value string, create bool) *Symbol {
+	if sym, _ := n.symbols.Get(value); sym != nil {
+		return sym
+	}
+
+	if n.parent != nil {
+		if sym := n.parent.FindSymbol(value, create); sym != nil {
+			return sym
+		}
+	}
+
+	if create {
+		sym, _ := n.CreateSymbol(value, n.owner)
+		return sym
+	}
+
+	return nil
+}
+
+// FindNestedSymbol resolves a namespaced path like A::B::C. The first element
+// is looked up from n outwards, and each following one among the symbols of
+// the node the previous one names. The last element is looked up from that
+// node outwards if it isn't one of its symbols, and created if it doesn't
+// exist.
+func (n *node) FindNestedSymbol(path []string) *Symbol {
+	var sym *Symbol
+	var node Node = n
+	last := len(path) - 1
+
+	for i, value := range path {
+		switch {
+		case i == 0:
+			sym = node.FindSymbol(value, i == last)
+		case i < last:
+			sym = memberSymbol(node, value)
+		default:
+			if sym = memberSymbol(node, value); sym == nil {
+				sym = node.FindSymbol(value, true)
+			}
+		}
+
+		if sym == nil {
+			return nil
+		}
+
+		var next Node
+
+		if sym.Node != nil {
+			next = sym.Node
+		} else {
+			next = sym.Scope
+		}
+
+		node = next
+	}
+
+	return sym
+}
+
+// memberSymbol returns the symbol named value of n itself, not looking up its
+// ancestors.
+func memberSymbol(n Node, value string) *Symbol {
+	for _, sym := range n.Symbols() {
+		if sym.Value == value {
+			return sym
+		}
+	}
+
+	return nil
+}
+
+// ImportSymbols adds the symbols of other to n. Symbols conflicting with an
+// existing one are skipped and reported in the returned error; importing the
+// very same symbol twice isn't a conflict.
+func (n *node) ImportSymbols(other Node) error {
+	var conflicts []string
+
+	for _, sym := range other.Symbols() {
+		if existing, ok := n.symbols.Get(sym.Value); ok {
+			if existing != sym {
+				conflicts = append(conflicts, fmt.Sprintf("%q", sym.Value))
+			}
+
+			continue
+		}
+
+		if err := n.AddSymbol(sym); err != nil {
+			return err
+		}
+	}
+
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return fmt.Errorf("Trying to import %w %s to node %v", ErrDuplicateSymbol, strings.Join(conflicts, ", "), n.NamePath())
+	}
+
+	return nil
+}
+
+func (n *node) Symbols() []*Symbol {
+	return n.symbols.Symbols()
+}
+
+// RemoveSymbol detaches the symbol named value from n and returns it, or nil if
+// n has no such symbol. Parent scopes aren't searched.
+func (n *node) RemoveSymbol(value string) *Symbol {
+	return n.symbols.Remove(value)
+}
+
+func (n *node) ClearSymbols() {
+	n.symbols.Clear()
+}
+
+type QualifierKind int
+
+const (
+	QualifierEMsg QualifierKind = iota
+	QualifierStorageType
+	QualifierSize
+	QualifierDiscriminator
+)
+
+func (k QualifierKind) String() string {
+	switch k {
+	case QualifierEMsg:
+		return "emsg"
+	case QualifierStorageType:
+		return "storage-type"
+	case QualifierSize:
+		return "size"
+	case QualifierDiscriminator:
+		return "discriminator"
+	default:
+		panic(fmt.Errorf("Unknown QualifierKind %d", k))
+	}
+}
+
+// Qualifier is the `<...>` annotation following a class, enum, union or
+// property type. Symbol is set when the qualifier references a declared name
+// and Value when it is a literal (a builtin storage type or a numeric size).
+// Path is the reference as written in the source.
+type Qualifier struct {
+	Kind   QualifierKind
+	Symbol *Symbol
+	Value  string
+	Path   []string
+}
+
+func (q *Qualifier) IsLiteral() bool {
+	return q.Symbol == nil
+}
+
+// baseNode holds the name of a declaration or member. Row and Col locate the
+// name in the source, 1-based, and are zero for nodes built in code, as is Pos,
+// its position in the FileSet of the analysis.
+type baseNode struct {
+	Node
+	Value   []byte
+	Doc     string
+	Comment string
+	Row     int
+	Col     int
+	Pos     token.Pos
+	owner   Node
+}
+
+func newBaseNode(owner Node) *baseNode {
+	return &baseNode{Node: newNode(nil, owner), owner: owner}
+}
+
+func attachNode(parent Node, child Node) {
+	if parent != nil {
+		parent.AddChild(child)
+	}
+}
+
+func (n *baseNode) Symbol() *Symbol {
+	return &Symbol{Value: string(n.Value), Node: n.owner}
+}
+
+func (n *baseNode) Name() string {
+	return string(n.Value)
+}
+
+func (n *baseNode) setName(name string) {
+	n.Value = []byte(name)
+}
+
+// ClassNode is a class declaration. Base is the symbol of the class it
+// inherits the fields of, if any, named by BasePath.
+type ClassNode struct {
+	*baseNode
+	Qualifier *Qualifier
+	Base      *Symbol
+	BasePath  []string
+}
+
+func NewClassNode(parent Node) *ClassNode {
+	n := &ClassNode{}
+	n.baseNode = newBaseNode(n)
+	attachNode(parent, n)
+	return n
+}
+
+// Properties returns the fields of the class, in declaration order.
+func (n *ClassNode) Properties() []*PropertyNode {
+	return n.properties(false)
+}
+
+// BaseClass returns the class n inherits from, following typedefs, or nil.
+func (n *ClassNode) BaseClass() *ClassNode {
+	if base := Underlying(n.Base); base != nil {
+		if class, ok := base.Node.(*ClassNode); ok {
+			return class
+		}
+	}
+
+	return nil
+}
+
+// Fields returns the fields of the class in wire order: the ones inherited from
+// its base classes first, then its properties. Inheritance cycles are cut at
+// the first repeated class.
+func (n *ClassNode) Fields() []*PropertyNode {
+	var chain []*ClassNode
+
+	seen := make(map[*ClassNode]bool)
+
+	for c := n; c != nil && !seen[c]; c = c.BaseClass() {
+		seen[c] = true
+		chain = append([]*ClassNode{c}, chain...)
+	}
+
+	var fields []*PropertyNode
+
+	for _, c := range chain {
+		fields = append(fields, c.Properties()...)
+	}
+
+	return fields
+}
+
+// Consts returns the const properties of the class, in declaration order.
+func (n *ClassNode) Consts() []*PropertyNode {
+	return n.properties(true)
+}
+
+func (n *ClassNode) properties(consts bool) []*PropertyNode {
+	var props []*PropertyNode
+
+	for _, child := range n.Children() {
+		if prop, ok := child.(*PropertyNode); ok && prop.IsConst() == consts {
+			props = append(props, prop)
+		}
+	}
+
+	return props
+}
+
+// EnumNode is an enum declaration. Type is the storage type given by its
+// qualifier.
+type EnumNode struct {
+	*baseNode
+	Flags     bool
+	Qualifier *Qualifier
+	Type      StorageType
+}
+
+func NewEnumNode(parent Node) *EnumNode {
+	n := &EnumNode{}
+	n.baseNode = newBaseNode(n)
+	attachNode(parent, n)
+	return n
+}
+
+// Members returns the members of the enum, in declaration order.
+func (n *EnumNode) Members() []*EnumMemberNode {
+	var members []*EnumMemberNode
+
+	for _, child := range n.Children() {
+		if member, ok := child.(*EnumMemberNode); ok {
+			members = append(members, member)
+		}
+	}
+
+	return members
+}
+
+// NamespaceNode is a `namespace` block scoping the classes, enums and
+// namespaces declared in it.
+type NamespaceNode struct {
+	*baseNode
+}
+
+func NewNamespaceNode(parent Node) *NamespaceNode {
+	n := &NamespaceNode{}
+	n.baseNode = newBaseNode(n)
+	attachNode(parent, n)
+	return n
+}
+
+// Declarations returns the classes, enums, unions and typedefs of the
+// namespace, including the ones of nested namespaces and classes, in
+// declaration order.
+func (n *NamespaceNode) Declarations() []Node {
+	return types(n)
+}
+
+// NamespacePath returns the names of the namespaces enclosing n, outermost
+// first.
+func NamespacePath(n Node) []string {
+	var path []string
+
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		ns, ok := p.(*NamespaceNode)
+
+		if !ok {
+			break
+		}
+
+		path = append([]string{ns.Name()}, path...)
+	}
+
+	return path
+}
+
+// ScopePath returns the names of the namespaces and classes enclosing n,
+// outermost first.
+func ScopePath(n Node) []string {
+	var path []string
+
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		switch p.(type) {
+		case *NamespaceNode, *ClassNode:
+			path = append([]string{p.Name()}, path...)
+		default:
+			return path
+		}
+	}
+
+	return path
+}
+
+// UnionNode is a union declaration, one of alternative classes selected by a
+// discriminator, a member of the enum given by its qualifier, written before
+// the class on the wire.
+type UnionNode struct {
+	*baseNode
+	Qualifier *Qualifier
+}
+
+func NewUnionNode(parent Node) *UnionNode {
+	n := &UnionNode{}
+	n.baseNode = newBaseNode(n)
+	attachNode(parent, n)
+	return n
+}
+
+// Discriminator returns the enum selecting the variants of n, following
+// typedefs, or nil.
+func (n *UnionNode) Discriminator() *EnumNode {
+	if n.Qualifier == nil {
+		return nil
+	}
+
+	if sym := Underlying(n.Qualifier.Symbol); sym != nil {
+		if enum, ok := sym.Node.(*EnumNode); ok {
+			return enum
+		}
+	}
+
+	return nil
+}
+
+// Variants returns the variants of the union, in declaration order.
+func (n *UnionNode) Variants() []*UnionVariantNode {
+	var variants []*UnionVariantNode
+
+	for _, child := range n.Children() {
+		if variant, ok := child.(*UnionVariantNode); ok {
+			variants = append(variants, variant)
+		}
+	}
+
+	return variants
+}
+
+// Variant returns the variant selected by the discriminator value, or nil.
+// Members are compared by the values computed by Evaluate.
+func (n *UnionNode) Variant(value uint64) *UnionVariantNode {
+	for _, variant := range n.Variants() {
+		if member := variant.Discriminator(); member != nil {
+			if v, ok := member.ResolvedValue(); ok && v == value {
+				return variant
+			}
+		}
+	}
+
+	return nil
+}
+
+// UnionVariantNode is a `Type Member;` variant of a union, the class named by
+// Type selected by the discriminator member it's named after, Member once
+// resolved.
+type UnionVariantNode struct {
+	*baseNode
+	Type   *Symbol
+	Member *Symbol
+}
+
+func NewUnionVariantNode(parent Node) *UnionVariantNode {
+	n := &UnionVariantNode{}
+	n.baseNode = newBaseNode(n)
+	attachNode(parent, n)
+	return n
+}
+
+// Class returns the class of the variant, following typedefs, or nil.
+func (n *UnionVariantNode) Class() *ClassNode {
+	if sym := Underlying(n.Type); sym != nil {
+		if class, ok := sym.Node.(*ClassNode); ok {
+			return class
+		}
+	}
+
+	return nil
+}
+
+// Discriminator returns the enum member selecting the variant, or nil.
+func (n *UnionVariantNode) Discriminator() *EnumMemberNode {
+	if n.Member != nil {
+		if member, ok := n.Member.Node.(*EnumMemberNode); ok {
+			return member
+		}
+	}
+
+	return nil
+}
+
+// TypedefNode is a `typedef Name = Type;` declaration naming another type,
+// like a builtin one. Type is the symbol of the aliased type.
+type TypedefNode struct {
+	*baseNode
+	Type *Symbol
+}
+
+func NewTypedefNode(parent Node) *TypedefNode {
+	n := &TypedefNode{}
+	n.baseNode = newBaseNode(n)
+	attachNode(parent, n)
+	return n
+}
+
+// Underlying follows the typedefs sym names, if any, and returns the symbol of
+// the type they alias. It returns nil for typedefs aliasing each other in a
+// cycle or not resolved yet.
+func Underlying(sym *Symbol) *Symbol {
+	seen := make(map[*TypedefNode]bool)
+
+	for sym != nil {
+		typedef, ok := sym.Node.(*TypedefNode)
+
+		if !ok {
+			return sym
+		}
+
+		if seen[typedef] {
+			return nil
+		}
+
+		seen[typedef] = true
+		sym = typedef.Type
+	}
+
+	return nil
+}
+
+// PropertyNode is a class field or constant. Expr is its default value
+// expression and Default lists the operands of Expr in source order. Alias is
+// the typedef its type is named by, if any, and Type the type it aliases.
+// Optional fields may be left out of the payload, along with the optional
+// fields following them. Conditional fields are only present when the flags
+// field preceding them has the bit of Condition, the flags enum member named
+// by ConditionPath, set. Fields whose size Qualifier names a preceding field
+// instead of a constant have a variable length, given by that field. Endian is
+// the byte order of the values of the field, little-endian unless annotated
+// `endian:be`. Encoding and Termination are the character encoding of string
+// fields, UTF-8 unless annotated `encoding:utf16`, and how their end is found,
+// by their length field unless annotated `term:null`.
+type PropertyNode struct {
+	*baseNode
+	Optional       bool
+	Condition      *Symbol
+	ConditionPath  []string
+	Endian         ByteOrder
+	Encoding       StringEncoding
+	Termination    Termination
+	Flags          PropertyFlag
+	Qualifier      *Qualifier
+	Type           *Symbol
+	Alias          *Symbol
+	Default        []*Symbol
+	Expr           Expr
+	Obsolete       bool
+	ObsoleteReason string
+	Removed        bool
+	RemovedReason  string
+	value          uint64
+	resolved       bool
+}
+
+func NewPropertyNode(parent Node) *PropertyNode {
+	n := &PropertyNode{}
+	n.baseNode = newBaseNode(n)
+	attachNode(parent, n)
+	return n
+}
+
+func (n *PropertyNode) IsConst() bool {
+	return n.Flags == PropertyFlagConst
+}
+
+// ResolvedValue returns the value computed by Evaluate, and whether there is
+// one.
+func (n *PropertyNode) ResolvedValue() (uint64, bool) {
+	return n.value, n.resolved
+}
+
+// ConditionMember returns the flags enum member the presence of n depends on,
+// or nil.
+func (n *PropertyNode) ConditionMember() *EnumMemberNode {
+	if n.Condition == nil {
+		return nil
+	}
+
+	member, _ := n.Condition.Node.(*EnumMemberNode)
+
+	return member
+}
+
+// ConditionField returns the field tested by the condition of n: the nearest
+// field of its class, in wire order, preceding n and typed with the enum of
+// the condition. It returns nil if there is none.
+func (n *PropertyNode) ConditionField() *PropertyNode {
+	member := n.ConditionMember()
+	class, ok := n.Parent().(*ClassNode)
+
+	if member == nil || !ok {
+		return nil
+	}
+
+	var field *PropertyNode
+
+	for _, f := range class.Fields() {
+		if f == n {
+			return field
+		}
+
+		if t := Underlying(f.Type); t != nil && t.Node == member.Parent() {
+			field = f
+		}
+	}
+
+	return nil
+}
+
+// ArrayLen returns the number of elements of n when it's a fixed size array,
+// declared with a size qualifier like `uint<4>` or `MsgHdr<MAX>`, and whether
+// it is one. Fields of variable length aren't fixed size arrays.
+func (n *PropertyNode) ArrayLen() (int, bool, error) {
+	q := n.Qualifier
+
+	if q == nil || q.Kind != QualifierSize || n.LengthField() != nil {
+		return 0, false, nil
+	}
+
+	size, err := q.Size()
+
+	return size, true, err
+}
+
+// LengthField returns the field of its class giving the number of elements of
+// n, or nil if n doesn't have a variable length.
+func (n *PropertyNode) LengthField() *PropertyNode {
+	q := n.Qualifier
+
+	if q == nil || q.Kind != QualifierSize || q.Symbol == nil {
+		return nil
+	}
+
+	field, ok := q.Symbol.Node.(*PropertyNode)
+
+	if !ok || field.IsConst() || field.Parent() != n.Parent() {
+		return nil
+	}
+
+	return field
+}
+
+// LengthOf returns the field of its class whose number of elements n gives, or
+// nil.
+func (n *PropertyNode) LengthOf() *PropertyNode {
+	class, ok := n.Parent().(*ClassNode)
+
+	if !ok || n.IsConst() {
+		return nil
+	}
+
+	for _, f := range class.Properties() {
+		if f.LengthField() == n {
+			return f
+		}
+	}
+
+	return nil
+}
+
+func (n *PropertyNode) AddDefault(s *Symbol) error {
+	if s == nil {
+		return fmt.Errorf("Trying to add %w to PropertyNode %v", ErrNilSymbol, n.NamePath())
+	}
+
+	n.Default = append(n.Default, s)
+
+	return nil
+}
+
+// EnumMemberNode is a member of an enum. Expr is its value expression, nil when
+// the value is implicit, and Default lists the operands of Expr in source
+// order.
+type EnumMemberNode struct {
+	*baseNode
+	Default        []*Symbol
+	Expr           Expr
+	Obsolete       bool
+	ObsoleteReason string
+	Removed        bool
+	RemovedReason  string
+	value          uint64
+	resolved       bool
+}
+
+func NewEnumMemberNode(parent Node) *EnumMemberNode {
+	n := &EnumMemberNode{}
+	n.baseNode = newBaseNode(n)
+	attachNode(parent, n)
+	return n
+}
+
+// ResolvedValue returns the value computed by Evaluate, and whether there is
+// one.
+func (n *EnumMemberNode) ResolvedValue() (uint64, bool) {
+	return n.value, n.resolved
+}
+
+func (n *EnumMemberNode) AddDefault(s *Symbol) error {
+	if s == nil {
+		return fmt.Errorf("Trying to add %w to EnumMemberNode %v", ErrNilSymbol, n.NamePath())
+	}
+
+	n.Default = append(n.Default, s)
+
+	return nil
+}
@@ -0,0 +1,235 @@
+package ast
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNodeAddSymbol(t *testing.T) {
+	root := NewNode(nil)
+
+	if err := root.AddSymbol(&Symbol{Value: "a"}); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	tests := []struct {
+		sym      *Symbol
+		expected error
+	}{
+		{nil, ErrNilSymbol},
+		{&Symbol{}, ErrEmptySymbol},
+		{&Symbol{Value: "a"}, ErrDuplicateSymbol},
+	}
+
+	for _, test := range tests {
+		if err := root.AddSymbol(test.sym); !errors.Is(err, test.expected) {
+			t.Fatalf("expected error %v, got %v", test.expected, err)
+		}
+	}
+}
+
+func TestNodeSymbolsOrder(t *testing.T) {
+	root := NewNode(nil)
+	expected := []string{"z", "a", "m", "b", "y", "c"}
+
+	for _, value := range expected {
+		root.AddSymbol(&Symbol{Value: value})
+	}
+
+	for i := 0; i < 10; i++ {
+		symbols := root.Symbols()
+
+		if len(symbols) != len(expected) {
+			t.Fatalf("mismatch: got %d symbols, but expected %d", len(symbols), len(expected))
+		}
+
+		for j, sym := range symbols {
+			if sym.Value != expected[j] {
+				t.Fatalf("mismatch: got %q, but expected %q", sym.Value, expected[j])
+			}
+		}
+	}
+
+	root.ClearSymbols()
+
+	if symbols := root.Symbols(); len(symbols) != 0 {
+		t.Fatalf("mismatch: got %d symbols, but expected 0", len(symbols))
+	}
+}
+
+func TestNodeImportSymbols(t *testing.T) {
+	shared := &Symbol{Value: "shared"}
+	root := NewNode(nil)
+	root.AddSymbol(shared)
+	root.AddSymbol(&Symbol{Value: "a"})
+
+	other := NewNode(nil)
+	other.AddSymbol(shared)
+	other.AddSymbol(&Symbol{Value: "a"})
+	other.AddSymbol(&Symbol{Value: "b"})
+
+	if err := root.ImportSymbols(other); !errors.Is(err, ErrDuplicateSymbol) {
+		t.Fatalf("expected error %v, got %v", ErrDuplicateSymbol, err)
+	}
+
+	if root.FindSymbol("b", false) == nil {
+		t.Fatalf("expected non-conflicting symbol to be imported")
+	}
+
+	other = NewNode(nil)
+	other.AddSymbol(shared)
+
+	if err := root.ImportSymbols(other); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+}
+
+func TestNodeFindNestedSymbol(t *testing.T) {
+	doc := NewDocumentNode("test.steamd")
+	outer := NewClassNode(doc)
+	outer.Value = []byte("Outer")
+	inner := NewEnumNode(outer)
+	inner.Value = []byte("Inner")
+	member := NewEnumMemberNode(inner)
+	member.Value = []byte("Value")
+	other := NewEnumNode(doc)
+	other.Value = []byte("Other")
+
+	doc.AddSymbol(outer.Symbol())
+	doc.AddSymbol(other.Symbol())
+	outer.AddSymbol(inner.Symbol())
+	inner.AddSymbol(member.Symbol())
+
+	path := []string{"Outer", "Inner", "Value"}
+
+	if sym := doc.FindNestedSymbol(path); sym == nil || sym.Node != member {
+		t.Fatalf("mismatch: got %+v, but expected Outer::Inner::Value", sym)
+	}
+
+	if sym := LookupSymbol(member, path); sym == nil || sym.Node != member {
+		t.Fatalf("mismatch: got %+v, but expected Outer::Inner::Value", sym)
+	}
+
+	// Other isn't a member of Outer, even though it's visible from it
+	if sym := LookupSymbol(doc, []string{"Outer", "Other", "Value"}); sym != nil {
+		t.Fatalf("mismatch: got %+v, but expected no symbol", sym)
+	}
+
+	if sym := doc.FindNestedSymbol([]string{"Outer", "Other", "Value"}); sym != nil {
+		t.Fatalf("mismatch: got %+v, but expected no symbol", sym)
+	}
+
+	if sym := doc.FindNestedSymbol([]string{"Outer", "Inner", "Missing"}); sym == nil || sym.Node != doc {
+		t.Fatalf("mismatch: got %+v, but expected a placeholder", sym)
+	}
+}
+
+func TestPropertyNodeAddDefault(t *testing.T) {
+	prop := NewPropertyNode(nil)
+
+	if err := prop.AddDefault(nil); !errors.Is(err, ErrNilSymbol) {
+		t.Fatalf("expected error %v, got %v", ErrNilSymbol, err)
+	}
+}
+
+func TestClassNodeProperties(t *testing.T) {
+	class := NewClassNode(nil)
+
+	for _, name := range []string{"a", "B", "c", "D"} {
+		prop := NewPropertyNode(class)
+		prop.Value = []byte(name)
+
+		if name == "B" || name == "D" {
+			prop.Flags = PropertyFlagConst
+		}
+	}
+
+	NewEnumMemberNode(class)
+
+	tests := []struct {
+		props    []*PropertyNode
+		expected []string
+	}{
+		{class.Properties(), []string{"a", "c"}},
+		{class.Consts(), []string{"B", "D"}},
+	}
+
+	for _, test := range tests {
+		if len(test.props) != len(test.expected) {
+			t.Fatalf("mismatch: got %d properties, but expected %d", len(test.props), len(test.expected))
+		}
+
+		for i, prop := range test.props {
+			if prop.Name() != test.expected[i] {
+				t.Fatalf("mismatch: got %q, but expected %q", prop.Name(), test.expected[i])
+			}
+		}
+	}
+}
+
+func TestEnumNodeMembers(t *testing.T) {
+	enum := NewEnumNode(nil)
+	a := NewEnumMemberNode(enum)
+	NewPropertyNode(enum)
+	b := NewEnumMemberNode(enum)
+
+	if members := enum.Members(); len(members) != 2 || members[0] != a || members[1] != b {
+		t.Fatalf("mismatch: got members %v", members)
+	}
+}
+
+func TestStorageTypeFits(t *testing.T) {
+	tests := []struct {
+		name string
+		v    uint64
+		fits bool
+	}{
+		{"byte", 0xFF, true},
+		{"byte", 0x100, false},
+		{"sbyte", uint64(1<<64 - 128), true},
+		{"sbyte", uint64(1<<64 - 129), false},
+		{"ushort", 0xFFFF, true},
+		{"uint", 1 << 32, false},
+		{"int", 0xFFFFFFFF, true},
+		{"int", uint64(1<<64 - 1), true},
+		{"ulong", uint64(1<<64 - 1), true},
+	}
+
+	for _, test := range tests {
+		st, ok := ParseStorageType(test.name)
+
+		if !ok {
+			t.Fatalf("expected %q to be a storage type", test.name)
+		}
+
+		if st.Fits(test.v) != test.fits {
+			t.Fatalf("mismatch for %s(%#x): got %v, but expected %v", test.name, test.v, !test.fits, test.fits)
+		}
+	}
+
+	if _, ok := ParseStorageType("float"); ok {
+		t.Fatalf("expected float not to be a storage type")
+	}
+}
+
+func TestDocumentNodeDependencies(t *testing.T) {
+	main := NewDocumentNode("main")
+	a := NewDocumentNode("a")
+	b := NewDocumentNode("b")
+	c := NewDocumentNode("c")
+
+	main.Imports = []*Import{{Path: "a", Document: a}, {Path: "missing"}, {Path: "b", Document: b}}
+	a.Imports = []*Import{{Path: "c", Document: c}}
+	b.Imports = []*Import{{Path: "c", Document: c}, {Path: "main", Document: main}}
+
+	var names []string
+
+	for _, dep := range main.Dependencies() {
+		names = append(names, dep.Filename)
+	}
+
+	if strings.Join(names, ",") != "a,c,b" {
+		t.Fatalf("mismatch: got %q, but expected %q", names, []string{"a", "c", "b"})
+	}
+}
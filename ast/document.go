@@ -0,0 +1,113 @@
+package ast
+
+import "github.com/13k/go-steam-language/token"
+
+// Import is an `#import "path"` directive. Document is the analyzed imported
+// file, nil if it couldn't be read. Pos is the position of the path.
+type Import struct {
+	Path     string
+	Row      int
+	Col      int
+	Pos      token.Pos
+	Document *DocumentNode
+}
+
+// DocumentNode is the root of an analyzed file. Its children include the
+// declarations pulled in through imports, while Declarations only holds the
+// ones written in the file itself, namespaces and the declarations nested in
+// them included, in source order. Hash is the hex SHA-256 of the source, empty
+// for sources read from a stream.
+type DocumentNode struct {
+	Node
+	Filename     string
+	Size         int
+	Hash         string
+	Imports      []*Import
+	Declarations []Node
+}
+
+func NewDocumentNode(filename string) *DocumentNode {
+	n := &DocumentNode{Filename: filename}
+	n.Node = newNode(nil, n)
+	return n
+}
+
+func (n *DocumentNode) Name() string {
+	return n.Filename
+}
+
+// Types returns the classes, enums, unions and typedefs of n, including the
+// ones pulled in through imports and the ones declared in namespaces and
+// classes, in order.
+func (n *DocumentNode) Types() []Node {
+	return types(n)
+}
+
+func types(n Node) []Node {
+	var nodes []Node
+
+	for _, child := range n.Children() {
+		switch child := child.(type) {
+		case *EnumNode, *TypedefNode, *UnionNode:
+			nodes = append(nodes, child)
+		case *ClassNode:
+			nodes = append(nodes, child)
+			nodes = append(nodes, types(child)...)
+		case *NamespaceNode:
+			nodes = append(nodes, types(child)...)
+		}
+	}
+
+	return nodes
+}
+
+func (n *DocumentNode) Classes() []*ClassNode {
+	var classes []*ClassNode
+
+	for _, decl := range n.Declarations {
+		if class, ok := decl.(*ClassNode); ok {
+			classes = append(classes, class)
+		}
+	}
+
+	return classes
+}
+
+func (n *DocumentNode) Enums() []*EnumNode {
+	var enums []*EnumNode
+
+	for _, decl := range n.Declarations {
+		if enum, ok := decl.(*EnumNode); ok {
+			enums = append(enums, enum)
+		}
+	}
+
+	return enums
+}
+
+// Dependencies returns the documents n imports, directly or through other
+// imports, each once and in the order they're first imported. Imports that
+// couldn't be read are left out.
+func (n *DocumentNode) Dependencies() []*DocumentNode {
+	var deps []*DocumentNode
+
+	seen := map[*DocumentNode]bool{n: true}
+
+	var visit func(doc *DocumentNode)
+
+	visit = func(doc *DocumentNode) {
+		for _, imp := range doc.Imports {
+			if imp.Document == nil || seen[imp.Document] {
+				continue
+			}
+
+			seen[imp.Document] = true
+			deps = append(deps, imp.Document)
+			visit(imp.Document)
+		}
+	}
+
+	visit(n)
+
+	return deps
+}
@@ -0,0 +1,273 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DOT renders the tree rooted at n as a Graphviz digraph. Nodes are linked to
+// their children by solid edges, properties to the enums and classes of their
+// types by dashed edges, classes to their EMsg by dotted edges and to their
+// base class by bold edges.
+func DOT(n Node) string {
+	g := newDotGraph("ast")
+	var nodes []Node
+
+	Inspect(n, func(node Node) bool {
+		if node == nil {
+			return false
+		}
+
+		nodes = append(nodes, node)
+		g.node(node, dotLabel(node), dotShape(node))
+
+		if parent := node.Parent(); parent != nil && node != n {
+			g.edge(parent, node, "")
+		}
+
+		return true
+	})
+
+	for _, node := range nodes {
+		for _, ref := range dotRefs(node) {
+			g.edge(node, ref.node, ref.style)
+		}
+	}
+
+	return g.String()
+}
+
+// ImportGraphDOT renders doc and the documents it imports as a Graphviz
+// digraph, with a cluster per file holding its declarations. Files are linked
+// to the files they import, and classes to the enums and classes their fields
+// reference, with dashed edges.
+func ImportGraphDOT(doc *DocumentNode) string {
+	g := newDotGraph("imports")
+	docs := append([]*DocumentNode{doc}, doc.Dependencies()...)
+
+	g.printf("\trankdir=LR;\n")
+
+	for i, d := range docs {
+		g.printf("\tsubgraph cluster_%d {\n", i)
+		g.printf("\t\tlabel=%s;\n", strconv.Quote(d.Filename))
+		g.printf("\t")
+		g.node(d, d.Filename, "folder")
+
+		for _, decl := range d.Declarations {
+			g.printf("\t")
+			g.node(decl, dotLabel(decl), dotShape(decl))
+		}
+
+		g.printf("\t}\n")
+	}
+
+	for _, d := range docs {
+		for _, imp := range d.Imports {
+			if imp.Document != nil {
+				g.edge(d, imp.Document, "")
+			}
+		}
+
+		for _, decl := range d.Declarations {
+			seen := make(map[Node]bool)
+
+			for _, child := range decl.Children() {
+				for _, ref := range dotRefs(child) {
+					if ref.style == "dashed" && !seen[ref.node] {
+						seen[ref.node] = true
+						g.edge(decl, ref.node, ref.style)
+					}
+				}
+			}
+		}
+	}
+
+	return g.String()
+}
+
+type dotGraph struct {
+	b   strings.Builder
+	ids map[Node]string
+}
+
+func newDotGraph(name string) *dotGraph {
+	g := &dotGraph{ids: make(map[Node]string)}
+	g.printf("digraph %s {\n", name)
+	g.printf("\tnode [fontname=\"Helvetica\"];\n")
+	return g
+}
+
+func (g *dotGraph) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.b, format, args...)
+}
+
+func (g *dotGraph) node(n Node, label, shape string) {
+	id := fmt.Sprintf("n%d", len(g.ids))
+	g.ids[n] = id
+	g.printf("\t%s [label=%s, shape=%s];\n", id, strconv.Quote(label), shape)
+}
+
+// edge links from to to, if both are in the graph.
+func (g *dotGraph) edge(from, to Node, style string) {
+	src, ok := g.ids[from]
+	dst, found := g.ids[to]
+
+	if !ok || !found {
+		return
+	}
+
+	if style == "" {
+		g.printf("\t%s -> %s;\n", src, dst)
+	} else {
+		g.printf("\t%s -> %s [style=%s];\n", src, dst, style)
+	}
+}
+
+func (g *dotGraph) String() string {
+	return g.b.String() + "}\n"
+}
+
+type dotRef struct {
+	node  Node
+	style string
+}
+
+// dotRefs returns the declarations n references: the type of a property or
+// the EMsg and base of a class.
+func dotRefs(n Node) []dotRef {
+	switch n := n.(type) {
+	case *PropertyNode:
+		if n.Type != nil && n.Type.Node != nil {
+			switch n.Type.Node.(type) {
+			case *EnumNode, *ClassNode, *UnionNode:
+				return []dotRef{{n.Type.Node, "dashed"}}
+			}
+		}
+	case *UnionNode:
+		if q := n.Qualifier; q != nil && q.Symbol != nil && q.Symbol.Node != nil {
+			return []dotRef{{q.Symbol.Node, "dotted"}}
+		}
+	case *UnionVariantNode:
+		if class := n.Class(); class != nil {
+			return []dotRef{{class, "dashed"}}
+		}
+	case *ClassNode:
+		var refs []dotRef
+
+		if q := n.Qualifier; q != nil && q.Kind == QualifierEMsg && q.Symbol != nil && q.Symbol.Node != nil {
+			refs = append(refs, dotRef{q.Symbol.Node, "dotted"})
+		}
+
+		if base := n.BaseClass(); base != nil {
+			refs = append(refs, dotRef{base, "bold"})
+		}
+
+		return refs
+	}
+
+	return nil
+}
+
+func dotLabel(n Node) string {
+	switch n := n.(type) {
+	case *DocumentNode:
+		return n.Filename
+	case *NamespaceNode:
+		return "namespace " + n.Name()
+	case *TypedefNode:
+		return "typedef " + n.Name() + " = " + n.Type.Value
+	case *ClassNode:
+		label := "class " + n.Name() + dotQualifier(n.Qualifier)
+
+		if n.BasePath != nil {
+			label += " : " + strings.Join(n.BasePath, "::")
+		}
+
+		return label
+	case *EnumNode:
+		label := "enum " + n.Name() + dotQualifier(n.Qualifier)
+
+		if n.Flags {
+			label += " flags"
+		}
+
+		return label
+	case *UnionNode:
+		return "union " + n.Name() + dotQualifier(n.Qualifier)
+	case *UnionVariantNode:
+		return n.Type.Value + " " + n.Name()
+	case *PropertyNode:
+		var parts []string
+
+		if n.Optional {
+			parts = append(parts, "optional")
+		}
+
+		if n.Flags != PropertyFlagNone {
+			parts = append(parts, n.Flags.String())
+		}
+
+		if n.Alias != nil {
+			parts = append(parts, n.Alias.Value+dotQualifier(n.Qualifier))
+		} else if n.Type != nil {
+			parts = append(parts, n.Type.Value+dotQualifier(n.Qualifier))
+		}
+
+		parts = append(parts, n.Name())
+
+		if n.ConditionPath != nil {
+			parts = append(parts, "if:"+strings.Join(n.ConditionPath, "::"))
+		}
+
+		if n.Endian != LittleEndian {
+			parts = append(parts, "endian:"+n.Endian.String())
+		}
+
+		if n.Encoding != EncodingUTF8 {
+			parts = append(parts, "encoding:"+n.Encoding.String())
+		}
+
+		if n.Termination != TermLength {
+			parts = append(parts, "term:"+n.Termination.String())
+		}
+
+		if expr := ValueExpr(n); expr != nil {
+			parts = append(parts, "=", expr.String())
+		}
+
+		return strings.Join(parts, " ")
+	case *EnumMemberNode:
+		if expr := ValueExpr(n); expr != nil {
+			return n.Name() + " = " + expr.String()
+		}
+	}
+
+	return n.Name()
+}
+
+func dotQualifier(q *Qualifier) string {
+	switch {
+	case q == nil:
+		return ""
+	case len(q.Path) > 0:
+		return "<" + strings.Join(q.Path, "::") + ">"
+	case q.IsLiteral():
+		return "<" + q.Value + ">"
+	default:
+		return "<" + q.Symbol.Value + ">"
+	}
+}
+
+func dotShape(n Node) string {
+	switch n.(type) {
+	case *DocumentNode:
+		return "folder"
+	case *NamespaceNode:
+		return "tab"
+	case *ClassNode, *EnumNode, *TypedefNode, *UnionNode:
+		return "box"
+	}
+
+	return "ellipse"
+}
@@ -0,0 +1,84 @@
+package ast_test
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+func TestDOT(t *testing.T) {
+	root := analyzeString(t, `
+		enum EMsg { Multi = 1; };
+		enum EFlags flags { A = 1; };
+		class MsgHdr<EMsg::Multi> {
+			EFlags flags = EFlags::A;
+			byte<4> key;
+		};
+	`)
+
+	dot := ast.DOT(root)
+
+	expected := []string{
+		"digraph ast {\n",
+		`n1 [label="enum EMsg", shape=box];`,
+		`n2 [label="Multi = 1", shape=ellipse];`,
+		`n3 [label="enum EFlags flags", shape=box];`,
+		`n5 [label="class MsgHdr<EMsg::Multi>", shape=box];`,
+		`n6 [label="EFlags flags = EFlags::A", shape=ellipse];`,
+		`n7 [label="byte<4> key", shape=ellipse];`,
+		"n0 -> n1;\n",
+		"n5 -> n6;\n",
+		"n6 -> n3 [style=dashed];\n",
+		"n5 -> n2 [style=dotted];\n",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(dot, s) {
+			t.Fatalf("expected DOT to contain %q\n%s", s, dot)
+		}
+	}
+
+	if !strings.HasSuffix(dot, "}\n") {
+		t.Fatalf("expected DOT to end the graph\n%s", dot)
+	}
+}
+
+func TestImportGraphDOT(t *testing.T) {
+	fsys := fstest.MapFS{
+		"enums.steamd": {Data: []byte("enum EResult { OK = 1; };")},
+		"hdr.steamd":   {Data: []byte("#import \"enums.steamd\"\nclass MsgHdr { EResult result; };")},
+		"main.steamd":  {Data: []byte("#import \"hdr.steamd\"\nclass Msg { MsgHdr header; EResult result; uint size; };")},
+	}
+
+	data, _ := fs.ReadFile(fsys, "main.steamd")
+	analyzer := parse.NewAnalyzer(token.NewTokenizer(data), "main.steamd")
+	analyzer.SetFS(fsys)
+	doc, err := analyzer.Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	dot := ast.ImportGraphDOT(doc)
+
+	expected := []string{
+		"subgraph cluster_0 {\n\t\tlabel=\"main.steamd\";\n\t\tn0 [label=\"main.steamd\", shape=folder];\n\t\tn1 [label=\"class Msg\", shape=box];\n\t}",
+		"subgraph cluster_1 {\n\t\tlabel=\"hdr.steamd\";",
+		"n4 [label=\"enums.steamd\", shape=folder];\n\t\tn5 [label=\"enum EResult\", shape=box];",
+		"n0 -> n2;\n",
+		"n1 -> n3 [style=dashed];\n\tn1 -> n5 [style=dashed];\n",
+		"n2 -> n4;\n",
+		"n3 -> n5 [style=dashed];\n",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(dot, s) {
+			t.Fatalf("expected DOT to contain %q\n%s", s, dot)
+		}
+	}
+}
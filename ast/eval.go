@@ -0,0 +1,288 @@
+package ast
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EvalFlags evaluates a flags expression like "SteamGuard | TwoFactor" against
+// the members of enum. Operands may be member names, optionally qualified with
+// the enum name ("EFoo::Bar"), or numeric literals.
+func EvalFlags(enum *EnumNode, expr string) (uint64, error) {
+	e := newEnumEvaluator(enum)
+	var result uint64
+
+	for _, operand := range strings.Split(expr, "|") {
+		operand = strings.TrimSpace(operand)
+
+		if operand == "" {
+			return 0, fmt.Errorf("Invalid flags expression %q", expr)
+		}
+
+		if v, ok := parseIntLiteral(operand); ok {
+			result |= v
+			continue
+		}
+
+		name := strings.TrimPrefix(operand, enum.Name()+"::")
+		member := e.member(name)
+
+		if member == nil {
+			return 0, fmt.Errorf("Unknown member %q in enum %s", operand, enum.Name())
+		}
+
+		v, err := e.eval(member)
+
+		if err != nil {
+			return 0, err
+		}
+
+		result |= v
+	}
+
+	return result, nil
+}
+
+// DecomposeFlags splits v into the named members of enum whose bits it sets,
+// preferring members covering more bits, and returns the bits no member
+// accounts for as residual. Members are returned in ascending value order.
+func DecomposeFlags(enum *EnumNode, v uint64) ([]*EnumMemberNode, uint64, error) {
+	e := newEnumEvaluator(enum)
+	var members []*EnumMemberNode
+
+	for _, member := range enum.Members() {
+		value, err := e.eval(member)
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if value == 0 && v == 0 {
+			return []*EnumMemberNode{member}, 0, nil
+		}
+
+		if value != 0 {
+			members = append(members, member)
+		}
+	}
+
+	sort.SliceStable(members, func(i, j int) bool {
+		return e.values[members[i]] > e.values[members[j]]
+	})
+
+	var result []*EnumMemberNode
+	residual := v
+
+	for _, member := range members {
+		value := e.values[member]
+
+		if residual&value == value {
+			result = append(result, member)
+			residual &^= value
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return e.values[result[i]] < e.values[result[j]]
+	})
+
+	return result, residual, nil
+}
+
+// Evaluate resolves the value of every enum member and const property in the
+// tree rooted at root, following references to other declarations, including
+// imported ones. Values are then available from the nodes' ResolvedValue.
+// Evaluation stops at the first member that can't be resolved or whose value
+// doesn't fit the storage type of its enum, including implicit values wrapping
+// past the largest ulong.
+func Evaluate(root Node) error {
+	e := newEvaluator()
+	var err error
+
+	Walk(root, &Visitor{
+		Enter: func(Node) bool {
+			return err == nil
+		},
+		EnumMember: func(member *EnumMemberNode) bool {
+			member.value, err = e.eval(member)
+
+			if err == nil {
+				err = checkStorage(member, member.value)
+			}
+
+			if err == nil {
+				err = checkWrap(member, member.value)
+			}
+
+			member.resolved = err == nil
+			return false
+		},
+		Property: func(prop *PropertyNode) bool {
+			prop.resolved = false
+
+			if prop.IsConst() {
+				prop.value, err = e.eval(prop)
+				prop.resolved = err == nil
+			}
+
+			return false
+		},
+	})
+
+	return err
+}
+
+// evaluator computes member values. When enum is set, references are limited
+// to members of that enum.
+type evaluator struct {
+	enum     *EnumNode
+	values   map[Node]uint64
+	visiting map[Node]bool
+}
+
+func newEvaluator() *evaluator {
+	return &evaluator{
+		values:   make(map[Node]uint64),
+		visiting: make(map[Node]bool),
+	}
+}
+
+func (e *evaluator) eval(prop Node) (uint64, error) {
+	if v, ok := e.values[prop]; ok {
+		return v, nil
+	}
+
+	name := qualifiedName(prop)
+
+	if e.visiting[prop] {
+		return 0, fmt.Errorf("Circular value of %s", name)
+	}
+
+	expr := ValueExpr(prop)
+
+	if expr == nil {
+		return 0, fmt.Errorf("Member %s has no value", name)
+	}
+
+	e.visiting[prop] = true
+	defer delete(e.visiting, prop)
+
+	result, err := e.evalExpr(prop, expr)
+
+	if err != nil {
+		return 0, err
+	}
+
+	e.values[prop] = result
+
+	return result, nil
+}
+
+func (e *evaluator) evalExpr(prop Node, expr Expr) (uint64, error) {
+	switch x := expr.(type) {
+	case *LiteralExpr:
+		return x.Value, nil
+	case *ParenExpr:
+		return e.evalExpr(prop, x.X)
+	case *SymbolExpr:
+		var ref Node
+
+		if x.Symbol != nil {
+			switch n := x.Symbol.Node.(type) {
+			case *PropertyNode, *EnumMemberNode:
+				ref = n
+			}
+		}
+
+		if ref == nil || ref == prop || (e.enum != nil && ref.Parent() != e.enum) {
+			return 0, fmt.Errorf("Cannot evaluate %q in value of %s", x.String(), qualifiedName(prop))
+		}
+
+		return e.eval(ref)
+	case *BinaryExpr:
+		lhs, err := e.evalExpr(prop, x.X)
+
+		if err != nil {
+			return 0, err
+		}
+
+		rhs, err := e.evalExpr(prop, x.Y)
+
+		if err != nil {
+			return 0, err
+		}
+
+		switch x.Op {
+		case BinaryOr:
+			return lhs | rhs, nil
+		case BinaryAdd:
+			return lhs + rhs, nil
+		case BinaryShl:
+			return lhs << rhs, nil
+		case BinaryShr:
+			return lhs >> rhs, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Cannot evaluate %v in value of %s", expr, qualifiedName(prop))
+}
+
+func checkStorage(member *EnumMemberNode, v uint64) error {
+	enum, ok := member.Parent().(*EnumNode)
+
+	if !ok || enum.Type.Fits(v) {
+		return nil
+	}
+
+	return fmt.Errorf("Value %d of %s overflows %s", v, qualifiedName(member), enum.Type)
+}
+
+// checkWrap reports the implicit value v of member wrapping to 0 after the
+// largest value of a ulong, which fits the storage type.
+func checkWrap(member *EnumMemberNode, v uint64) error {
+	enum, ok := member.Parent().(*EnumNode)
+
+	if !ok || enum.Type != StorageULong || v != 0 || member.Expr != nil || len(member.Default) != 0 || previousMember(member) == nil {
+		return nil
+	}
+
+	return fmt.Errorf("Implicit value of %s overflows %s", qualifiedName(member), enum.Type)
+}
+
+func qualifiedName(prop Node) string {
+	if parent := prop.Parent(); parent != nil {
+		return parent.Name() + "::" + prop.Name()
+	}
+
+	return prop.Name()
+}
+
+func newEnumEvaluator(enum *EnumNode) *evaluator {
+	e := newEvaluator()
+	e.enum = enum
+	return e
+}
+
+func (e *evaluator) member(name string) *EnumMemberNode {
+	for _, member := range e.enum.Members() {
+		if member.Name() == name {
+			return member
+		}
+	}
+
+	return nil
+}
+
+func parseIntLiteral(s string) (uint64, bool) {
+	if v, err := strconv.ParseUint(s, 0, 64); err == nil {
+		return v, true
+	}
+
+	if v, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return uint64(v), true
+	}
+
+	return 0, false
+}
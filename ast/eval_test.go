@@ -0,0 +1,271 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+func analyzeString(t *testing.T, data string) ast.Node {
+	analyzer := parse.NewAnalyzer(token.NewTokenizer([]byte(data)), "")
+	root, err := analyzer.Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	return root
+}
+
+func TestEvalFlags(t *testing.T) {
+	root := analyzeString(t, `
+		enum EAccountFlags flags {
+			NormalUser = 0;
+			PersonaNameSet = 1;
+			Unbannable = 2;
+			PasswordSet = 0x04;
+			Admin = PersonaNameSet | Unbannable;
+			Shifted = (PersonaNameSet | Unbannable) << 4;
+			Mixed = PersonaNameSet | PasswordSet + 0x80 >> 1;
+		};
+	`)
+
+	enum := root.Children()[0].(*ast.EnumNode)
+
+	tests := []struct {
+		expr     string
+		expected uint64
+	}{
+		{"NormalUser", 0},
+		{"PersonaNameSet | PasswordSet", 5},
+		{"EAccountFlags::Admin|0x10", 19},
+		{"Shifted", 48},
+		{"Mixed", 67},
+	}
+
+	for _, test := range tests {
+		v, err := ast.EvalFlags(enum, test.expr)
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		if v != test.expected {
+			t.Fatalf("mismatch: got %d, but expected %d", v, test.expected)
+		}
+	}
+
+	for _, expr := range []string{"Unknown", "Admin |", ""} {
+		if _, err := ast.EvalFlags(enum, expr); err == nil {
+			t.Fatalf("expected error evaluating %q", expr)
+		}
+	}
+}
+
+func TestDecomposeFlags(t *testing.T) {
+	root := analyzeString(t, `
+		enum EAccountFlags flags {
+			NormalUser = 0;
+			PersonaNameSet = 1;
+			Unbannable = 2;
+			PasswordSet = 4;
+			Admin = PersonaNameSet | Unbannable;
+		};
+	`)
+
+	enum := root.Children()[0].(*ast.EnumNode)
+
+	tests := []struct {
+		value    uint64
+		names    []string
+		residual uint64
+	}{
+		{0, []string{"NormalUser"}, 0},
+		{4, []string{"PasswordSet"}, 0},
+		{7, []string{"Admin", "PasswordSet"}, 0},
+		{0x41, []string{"PersonaNameSet"}, 0x40},
+	}
+
+	for _, test := range tests {
+		members, residual, err := ast.DecomposeFlags(enum, test.value)
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		if len(members) != len(test.names) {
+			t.Fatalf("expected %d members for %#x, got %d", len(test.names), test.value, len(members))
+		}
+
+		for i, m := range members {
+			if m.Name() != test.names[i] {
+				t.Fatalf("mismatch: got %q, but expected %q", m.Name(), test.names[i])
+			}
+		}
+
+		if residual != test.residual {
+			t.Fatalf("mismatch: got residual %#x, but expected %#x", residual, test.residual)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	root := analyzeString(t, `
+		class Limits {
+			const uint MAX = 0x10;
+		};
+
+		enum EMsg {
+			Base = 100;
+			Hello = Base + 1;
+			Limit = Limits::MAX << 1;
+		};
+
+		enum EFlags flags {
+			A = 1;
+			B = A << 1;
+			Hello = EMsg::Hello | 0x1000;
+		};
+
+		class MsgHdr {
+			EMsg msg = EMsg::Hello;
+		};
+	`)
+
+	if err := ast.Evaluate(root); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expected := map[string]uint64{
+		"EMsg::Base":    100,
+		"EMsg::Hello":   101,
+		"EMsg::Limit":   32,
+		"EFlags::A":     1,
+		"EFlags::B":     2,
+		"EFlags::Hello": 0x1065,
+		"Limits::MAX":   16,
+	}
+
+	check := func(n ast.Node, v uint64, ok bool) bool {
+		name := n.Parent().Name() + "::" + n.Name()
+		ev, eok := expected[name]
+
+		if ok != eok || v != ev {
+			t.Fatalf("mismatch for %s: got %d (%v), but expected %d (%v)", name, v, ok, ev, eok)
+		}
+
+		return false
+	}
+
+	ast.Walk(root, &ast.Visitor{
+		EnumMember: func(member *ast.EnumMemberNode) bool {
+			v, ok := member.ResolvedValue()
+			return check(member, v, ok)
+		},
+		Property: func(prop *ast.PropertyNode) bool {
+			v, ok := prop.ResolvedValue()
+			return check(prop, v, ok)
+		},
+	})
+
+	root = analyzeString(t, `
+		enum E {
+			a = b;
+			b = a;
+		};
+	`)
+
+	if err := ast.Evaluate(root); err == nil {
+		t.Fatalf("expected circular value error")
+	}
+}
+
+func TestEvaluateImplicitValues(t *testing.T) {
+	root := analyzeString(t, `
+		enum E {
+			a;
+			b;
+			c = 10;
+			d;
+			e = 0x20;
+			f;
+		};
+	`)
+
+	if err := ast.Evaluate(root); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expected := []uint64{0, 1, 10, 11, 32, 33}
+
+	for i, child := range root.Children()[0].Children() {
+		prop := child.(*ast.EnumMemberNode)
+
+		if v, ok := prop.ResolvedValue(); !ok || v != expected[i] {
+			t.Fatalf("mismatch for %s: got %d, but expected %d", prop.Name(), v, expected[i])
+		}
+	}
+}
+
+func TestEvaluateLimits(t *testing.T) {
+	root := analyzeString(t, `
+		enum E<long> {
+			a = ulong.MaxValue;
+			b = long.MinValue;
+			c = int.MaxValue + 1;
+			d = byte.MinValue;
+		};
+	`)
+
+	if err := ast.Evaluate(root); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expected := []uint64{1<<64 - 1, 1 << 63, 1 << 31, 0}
+
+	for i, m := range root.Children()[0].(*ast.EnumNode).Members() {
+		if v, ok := m.ResolvedValue(); !ok || v != expected[i] {
+			t.Fatalf("mismatch for %s: got %d, but expected %d", m.Name(), v, expected[i])
+		}
+	}
+
+	for _, s := range []string{"ulong", "string.MaxValue", "int.Max"} {
+		if _, ok := ast.ParseLimit(s); ok {
+			t.Fatalf("expected %q not to be a limit", s)
+		}
+	}
+}
+
+func TestEvaluateStorageOverflow(t *testing.T) {
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{"enum E<byte> { a = 0xFF; };", ""},
+		{"enum E<byte> { a = 0xFE + 1; b; };", "Value 256 of E::b overflows byte"},
+		{"enum E<ushort> flags { a = 1; b = a << 16; };", "Value 65536 of E::b overflows ushort"},
+		{"enum E { a = 0xFFFFFFFF; };", ""},
+		{"enum E<ulong> { a = 0xFFFFFFFFFFFFFFFF; };", ""},
+		{"enum E<ulong> { a = 0xFFFFFFFFFFFFFFFE + 1; b; c = 0; };", "Implicit value of E::b overflows ulong"},
+		{"enum E<long> { a = -2; b; c; };", ""},
+	}
+
+	for _, test := range tests {
+		root := analyzeString(t, test.data)
+		err := ast.Evaluate(root)
+
+		if test.err == "" {
+			if err != nil {
+				t.Fatalf("not expected error %v", err)
+			}
+
+			continue
+		}
+
+		if err == nil || err.Error() != test.err {
+			t.Fatalf("mismatch: got %v, but expected %q", err, test.err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expr is the value expression of a property default or enum member.
+type Expr interface {
+	String() string
+	exprNode()
+}
+
+type BinaryOp int
+
+const (
+	BinaryOr BinaryOp = iota
+	BinaryAdd
+	BinaryShl
+	BinaryShr
+)
+
+func (op BinaryOp) String() string {
+	switch op {
+	case BinaryOr:
+		return "|"
+	case BinaryAdd:
+		return "+"
+	case BinaryShl:
+		return "<<"
+	case BinaryShr:
+		return ">>"
+	default:
+		panic(fmt.Errorf("Unknown BinaryOp %d", op))
+	}
+}
+
+// LiteralExpr is an integer literal, or the limit of a storage type like
+// ulong.MaxValue. Value holds negative literals in two's complement.
+type LiteralExpr struct {
+	Raw   string
+	Value uint64
+}
+
+// SymbolExpr is a reference to a declared name. Path is the reference as
+// written in the source.
+type SymbolExpr struct {
+	Path   []string
+	Symbol *Symbol
+}
+
+type BinaryExpr struct {
+	Op BinaryOp
+	X  Expr
+	Y  Expr
+}
+
+type ParenExpr struct {
+	X Expr
+}
+
+func (e *LiteralExpr) String() string {
+	return e.Raw
+}
+
+func (e *SymbolExpr) String() string {
+	if len(e.Path) == 0 && e.Symbol != nil {
+		return e.Symbol.Value
+	}
+
+	return strings.Join(e.Path, "::")
+}
+
+func (e *BinaryExpr) String() string {
+	return e.X.String() + " " + e.Op.String() + " " + e.Y.String()
+}
+
+func (e *ParenExpr) String() string {
+	return "(" + e.X.String() + ")"
+}
+
+func (*LiteralExpr) exprNode() {}
+func (*SymbolExpr) exprNode()  {}
+func (*BinaryExpr) exprNode()  {}
+func (*ParenExpr) exprNode()   {}
+
+// ValueExpr returns the value expression of a property or enum member. Nodes
+// built without one get an or-chain of their Default symbols, and enum members
+// without a value continue from the previous member, starting at 0.
+func ValueExpr(n Node) Expr {
+	switch n := n.(type) {
+	case *PropertyNode:
+		if n.Expr != nil {
+			return n.Expr
+		}
+
+		return orExpr(n.Default)
+	case *EnumMemberNode:
+		if n.Expr != nil {
+			return n.Expr
+		}
+
+		if len(n.Default) == 0 {
+			return implicitValue(n)
+		}
+
+		return orExpr(n.Default)
+	default:
+		return nil
+	}
+}
+
+func orExpr(syms []*Symbol) Expr {
+	var expr Expr
+
+	for _, sym := range syms {
+		var operand Expr
+
+		if v, ok := parseIntLiteral(sym.Value); ok {
+			operand = &LiteralExpr{Raw: sym.Value, Value: v}
+		} else if v, ok := ParseLimit(sym.Value); ok {
+			operand = &LiteralExpr{Raw: sym.Value, Value: v}
+		} else {
+			operand = &SymbolExpr{Path: []string{sym.Value}, Symbol: sym}
+		}
+
+		if expr == nil {
+			expr = operand
+		} else {
+			expr = &BinaryExpr{Op: BinaryOr, X: expr, Y: operand}
+		}
+	}
+
+	return expr
+}
+
+func implicitValue(member *EnumMemberNode) Expr {
+	prev := previousMember(member)
+
+	if prev == nil {
+		return &LiteralExpr{Raw: "0"}
+	}
+
+	return &BinaryExpr{
+		Op: BinaryAdd,
+		X:  &SymbolExpr{Path: []string{prev.Name()}, Symbol: &Symbol{Value: prev.Name(), Node: prev}},
+		Y:  &LiteralExpr{Raw: "1", Value: 1},
+	}
+}
+
+// previousMember returns the member declared before member in its enum, nil if
+// it's the first.
+func previousMember(member *EnumMemberNode) *EnumMemberNode {
+	var prev *EnumMemberNode
+
+	if parent := member.Parent(); parent != nil {
+		for _, child := range parent.Children() {
+			if child == member {
+				break
+			}
+
+			if m, ok := child.(*EnumMemberNode); ok {
+				prev = m
+			}
+		}
+	}
+
+	return prev
+}
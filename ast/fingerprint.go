@@ -0,0 +1,205 @@
+package ast
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// fingerprintVersion is hashed first, to be bumped whenever the canonical form
+// changes.
+const fingerprintVersion = "steamd-fingerprint-1"
+
+// Fingerprint returns the hex SHA-256 of a canonical form of the declarations
+// of doc, including imported ones, in order. Docs, comments, formatting,
+// attribute reasons and file names don't affect it, and values are hashed as
+// evaluated when possible, so equivalent schemas written differently share a
+// fingerprint.
+func Fingerprint(doc *DocumentNode) string {
+	// Values that can't be evaluated are hashed as written instead.
+	_ = Evaluate(doc)
+
+	var b strings.Builder
+
+	b.WriteString(fingerprintVersion + "\n")
+
+	for _, child := range doc.Types() {
+		switch n := child.(type) {
+		case *TypedefNode:
+			fmt.Fprintf(&b, "typedef %s = %s\n", namespacedName(n), canonicalSymbol(n.Type))
+		case *EnumNode:
+			fmt.Fprintf(&b, "enum %s<%s>", namespacedName(n), n.Type)
+
+			if n.Flags {
+				b.WriteString(" flags")
+			}
+
+			b.WriteString("\n")
+
+			for _, m := range n.Members() {
+				fmt.Fprintf(&b, "\t%s = %s%s\n", m.Name(), canonicalValue(m), canonicalAttributes(m.Obsolete, m.Removed))
+			}
+		case *ClassNode:
+			fmt.Fprintf(&b, "class %s%s", namespacedName(n), canonicalQualifier(n.Qualifier))
+
+			if n.Base != nil {
+				b.WriteString(" : " + canonicalSymbol(n.Base))
+			}
+
+			b.WriteString("\n")
+
+			for _, child := range n.Children() {
+				p, ok := child.(*PropertyNode)
+
+				if !ok {
+					continue
+				}
+
+				typ := "int"
+
+				if p.Alias != nil {
+					typ = canonicalSymbol(p.Alias)
+				} else if p.Type != nil {
+					typ = canonicalSymbol(p.Type)
+				}
+
+				b.WriteString("\t")
+
+				if p.Optional {
+					b.WriteString("optional ")
+				}
+
+				fmt.Fprintf(&b, "%s %s%s %s", p.Flags, typ, canonicalQualifier(p.Qualifier), p.Name())
+
+				if p.Condition != nil {
+					b.WriteString(" if:" + canonicalSymbol(p.Condition))
+				}
+
+				if p.Endian != LittleEndian {
+					b.WriteString(" endian:" + p.Endian.String())
+				}
+
+				if p.Encoding != EncodingUTF8 {
+					b.WriteString(" encoding:" + p.Encoding.String())
+				}
+
+				if p.Termination != TermLength {
+					b.WriteString(" term:" + p.Termination.String())
+				}
+
+				if value := canonicalValue(p); value != "" {
+					b.WriteString(" = " + value)
+				}
+
+				b.WriteString(canonicalAttributes(p.Obsolete, p.Removed) + "\n")
+			}
+		case *UnionNode:
+			fmt.Fprintf(&b, "union %s%s\n", namespacedName(n), canonicalQualifier(n.Qualifier))
+
+			for _, v := range n.Variants() {
+				fmt.Fprintf(&b, "\t%s %s\n", canonicalSymbol(v.Type), v.Name())
+			}
+		}
+	}
+
+	sum := sha256.Sum256([]byte(b.String()))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func canonicalValue(n Node) string {
+	var (
+		v  uint64
+		ok bool
+	)
+
+	switch n := n.(type) {
+	case *EnumMemberNode:
+		v, ok = n.ResolvedValue()
+	case *PropertyNode:
+		v, ok = n.ResolvedValue()
+	}
+
+	if ok {
+		return fmt.Sprintf("%d", v)
+	}
+
+	if expr := ValueExpr(n); expr != nil {
+		return canonicalExpr(expr)
+	}
+
+	return ""
+}
+
+// canonicalExpr renders expr with literals in decimal and references qualified
+// with the enum or class they belong to.
+func canonicalExpr(expr Expr) string {
+	switch e := expr.(type) {
+	case *LiteralExpr:
+		return fmt.Sprintf("%d", e.Value)
+	case *SymbolExpr:
+		if e.Symbol != nil {
+			return canonicalSymbol(e.Symbol)
+		}
+	case *BinaryExpr:
+		return canonicalExpr(e.X) + " " + e.Op.String() + " " + canonicalExpr(e.Y)
+	case *ParenExpr:
+		return "(" + canonicalExpr(e.X) + ")"
+	}
+
+	return expr.String()
+}
+
+// canonicalSymbol renders the declaration s references, qualified, or its
+// value for builtin types and unresolved names, whose placeholder symbols are
+// owned by the document.
+func canonicalSymbol(s *Symbol) string {
+	switch s.Node.(type) {
+	case *ClassNode, *EnumNode, *TypedefNode, *UnionNode, *EnumMemberNode, *PropertyNode:
+	default:
+		return s.Value
+	}
+
+	switch parent := s.Node.Parent().(type) {
+	case *ClassNode, *EnumNode:
+		return namespacedName(parent) + "::" + s.Node.Name()
+	}
+
+	return namespacedName(s.Node)
+}
+
+// namespacedName returns the name of n qualified with its namespaces and
+// enclosing classes.
+func namespacedName(n Node) string {
+	return strings.Join(append(ScopePath(n), n.Name()), "::")
+}
+
+func canonicalQualifier(q *Qualifier) string {
+	switch {
+	case q == nil:
+		return ""
+	case q.Kind == QualifierSize:
+		if size, err := q.Size(); err == nil {
+			return fmt.Sprintf("<%d>", size)
+		}
+	case q.Symbol != nil:
+		return "<" + canonicalSymbol(q.Symbol) + ">"
+	}
+
+	return "<" + q.Value + strings.Join(q.Path, "::") + ">"
+}
+
+func canonicalAttributes(obsolete, removed bool) string {
+	var s string
+
+	if obsolete {
+		s += " obsolete"
+	}
+
+	if removed {
+		s += " removed"
+	}
+
+	return s
+}
@@ -0,0 +1,54 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/13k/go-steam-language/ast"
+)
+
+const fingerprintSchema = `
+/// Message types.
+enum EMsg { Invalid = 0; Logon = 0x10; Logoff; };
+class MsgLogon<EMsg::Logon> {
+	const uint SIZE = 4;
+	uint version = MsgLogon::SIZE; // protocol version
+	byte<SIZE> key;
+};
+`
+
+func fingerprint(t *testing.T, src string) string {
+	return ast.Fingerprint(analyzeString(t, src).(*ast.DocumentNode))
+}
+
+func TestFingerprint(t *testing.T) {
+	fp := fingerprint(t, fingerprintSchema)
+
+	if len(fp) != 64 {
+		t.Fatalf("mismatch: got %q, but expected a hex SHA-256", fp)
+	}
+
+	if again := fingerprint(t, fingerprintSchema); again != fp {
+		t.Fatalf("mismatch: got %s, but expected %s", again, fp)
+	}
+
+	equivalent := `enum EMsg { Invalid = 0; Logon = 16; Logoff = 17; }; class MsgLogon<EMsg::Logon> { const uint SIZE = 4; uint version = SIZE; byte<4> key; };`
+
+	if actual := fingerprint(t, equivalent); actual != fp {
+		t.Fatalf("mismatch: got %s, but expected %s for an equivalent schema", actual, fp)
+	}
+
+	changed := []string{
+		`enum EMsg { Invalid = 0; Logon = 0x10; Logoff = 18; }; class MsgLogon<EMsg::Logon> { const uint SIZE = 4; uint version = SIZE; byte<SIZE> key; };`,
+		`enum EMsg { Invalid = 0; Logon = 0x10; Logoff; }; class MsgLogon<EMsg::Logoff> { const uint SIZE = 4; uint version = SIZE; byte<SIZE> key; };`,
+		`enum EMsg { Invalid = 0; Logon = 0x10; Logoff; }; class MsgLogon<EMsg::Logon> { const uint SIZE = 4; int version = SIZE; byte<SIZE> key; };`,
+		`enum EMsg { Invalid = 0; Logon = 0x10; Logoff; }; class MsgLogon<EMsg::Logon> { const uint SIZE = 4; byte<SIZE> key; uint version = SIZE; };`,
+		`enum EMsg { Invalid = 0; Logon = 0x10; Logoff; }; class MsgLogon<EMsg::Logon> { const uint SIZE = 4; uint version = SIZE; byte<SIZE> key; removed };`,
+		`enum EMsg<uint> { Invalid = 0; Logon = 0x10; Logoff; }; class MsgLogon<EMsg::Logon> { const uint SIZE = 4; uint version = SIZE; byte<SIZE> key; };`,
+	}
+
+	for _, src := range changed {
+		if actual := fingerprint(t, src); actual == fp {
+			t.Errorf("expected fingerprint of %q to differ", src)
+		}
+	}
+}
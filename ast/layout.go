@@ -0,0 +1,191 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// builtinSizes are the wire sizes of the builtin types. Types missing from the
+// table, like string, have a variable size.
+var builtinSizes = map[string]int{
+	"byte":   1,
+	"sbyte":  1,
+	"short":  2,
+	"ushort": 2,
+	"int":    4,
+	"uint":   4,
+	"long":   8,
+	"ulong":  8,
+	"float":  4,
+	"double": 8,
+	"bool":   1,
+	"char":   1,
+}
+
+// FieldLayout is the position of a field in the wire format of its class.
+// Offset is -1 for fields following a variable length one and Size is -1 for
+// variable length fields. Endian is the byte order of its values, the one of
+// the fields of nested classes being given by their own layouts.
+type FieldLayout struct {
+	Field  *PropertyNode
+	Offset int
+	Size   int
+	Endian ByteOrder
+}
+
+// ClassLayout is the wire format of a class: its fields in order, without
+// padding, and its total Size, -1 if the class has variable length, like the
+// classes with optional or conditional fields.
+type ClassLayout struct {
+	Class  *ClassNode
+	Fields []*FieldLayout
+	Size   int
+}
+
+// IsFixed reports whether every instance of the class has the same size.
+func (l *ClassLayout) IsFixed() bool {
+	return l.Size >= 0
+}
+
+// Layout computes the offset and size of each field of n, following nested
+// class fields. Constants aren't part of the layout.
+func Layout(n *ClassNode) (*ClassLayout, error) {
+	return layout(n, make(map[*ClassNode]bool))
+}
+
+func layout(n *ClassNode, visiting map[*ClassNode]bool) (*ClassLayout, error) {
+	if visiting[n] {
+		return nil, fmt.Errorf("Class %s contains itself", n.Name())
+	}
+
+	visiting[n] = true
+	defer delete(visiting, n)
+
+	l := &ClassLayout{Class: n}
+
+	for _, f := range n.Fields() {
+		size, err := fieldSize(f, visiting)
+
+		if err != nil {
+			return nil, err
+		}
+
+		field := &FieldLayout{Field: f, Offset: l.Size, Size: size, Endian: f.Endian}
+		l.Fields = append(l.Fields, field)
+
+		if l.Size >= 0 && size >= 0 && !f.Optional && f.Condition == nil {
+			l.Size += size
+		} else {
+			l.Size = -1
+		}
+	}
+
+	return l, nil
+}
+
+func fieldSize(f *PropertyNode, visiting map[*ClassNode]bool) (int, error) {
+	if f.Flags == PropertyFlagProto || f.LengthField() != nil {
+		return -1, nil
+	}
+
+	typeName := "int"
+
+	if wire := f.Flags.WireType(); wire != "" {
+		typeName = wire
+	} else if f.Type != nil {
+		switch n := f.Type.Node.(type) {
+		case *ClassNode:
+			l, err := layout(n, visiting)
+
+			if err != nil {
+				return 0, err
+			}
+
+			count, ok, err := f.ArrayLen()
+
+			if err != nil {
+				return 0, fmt.Errorf("%v in %s", err, qualifiedName(f))
+			}
+
+			if ok && l.Size >= 0 {
+				return l.Size * count, nil
+			}
+
+			return l.Size, nil
+		case *EnumNode:
+			typeName = n.Type.String()
+		case *UnionNode:
+			// the size depends on the variant
+			return -1, nil
+		default:
+			typeName = f.Type.Value
+
+			if !IsBuiltinType(typeName) {
+				return 0, fmt.Errorf("Unknown type %s of %s", typeName, qualifiedName(f))
+			}
+		}
+	}
+
+	size, ok := builtinSizes[typeName]
+
+	if !ok {
+		return -1, nil
+	}
+
+	count, ok, err := f.ArrayLen()
+
+	if err != nil {
+		return 0, fmt.Errorf("%v in %s", err, qualifiedName(f))
+	}
+
+	if ok {
+		size *= count
+	}
+
+	return size, nil
+}
+
+// Size returns the element count given by a size qualifier, either a literal
+// or a reference to a const or enum member. Qualifiers referencing a field
+// have a variable size, and return an error.
+func (q *Qualifier) Size() (int, error) {
+	if q.IsLiteral() {
+		size, err := strconv.ParseInt(q.Value, 0, 32)
+
+		if err != nil || size < 0 {
+			return 0, fmt.Errorf("Invalid size %q", q.Value)
+		}
+
+		return int(size), nil
+	}
+
+	var (
+		v  uint64
+		ok bool
+	)
+
+	switch n := q.Symbol.Node.(type) {
+	case *PropertyNode:
+		if !n.IsConst() {
+			return 0, fmt.Errorf("Variable size %q", q.Symbol.Value)
+		}
+
+		if err := Evaluate(n.Parent()); err != nil {
+			return 0, err
+		}
+
+		v, ok = n.ResolvedValue()
+	case *EnumMemberNode:
+		if err := Evaluate(n.Parent()); err != nil {
+			return 0, err
+		}
+
+		v, ok = n.ResolvedValue()
+	}
+
+	if !ok || v > 1<<31-1 {
+		return 0, fmt.Errorf("Invalid size %q", q.Symbol.Value)
+	}
+
+	return int(v), nil
+}
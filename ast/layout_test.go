@@ -0,0 +1,108 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/13k/go-steam-language/ast"
+)
+
+func TestLayout(t *testing.T) {
+	root := analyzeString(t, `
+		enum EUniverse<byte> {
+			Public = 1;
+		};
+
+		class MsgHdr {
+			const uint KEY_SIZE = 16;
+			protomask uint msg;
+			steamidmarshal ulong steamID;
+		};
+
+		class MsgLogon {
+			MsgHdr header;
+			EUniverse universe;
+			byte<MsgHdr::KEY_SIZE> key;
+			ushort<2> ports;
+			boolmarshal byte remember;
+		};
+
+		class MsgChat {
+			uint chatID;
+			string text;
+			uint flags;
+		};
+
+		class MsgList {
+			ushort count;
+			MsgHdr<2> headers;
+		};
+
+		class MsgBlob {
+			ushort size endian:be;
+			byte<size> data;
+			uint crc;
+		};
+	`)
+
+	tests := []struct {
+		class   string
+		offsets []int
+		sizes   []int
+		size    int
+	}{
+		{"MsgHdr", []int{0, 4}, []int{4, 8}, 12},
+		{"MsgLogon", []int{0, 12, 13, 29, 33}, []int{12, 1, 16, 4, 1}, 34},
+		{"MsgChat", []int{0, 4, -1}, []int{4, -1, 4}, -1},
+		{"MsgList", []int{0, 2}, []int{2, 24}, 26},
+		{"MsgBlob", []int{0, 2, -1}, []int{2, -1, 4}, -1},
+	}
+
+	for _, test := range tests {
+		class := root.FindSymbol(test.class, false).Node.(*ast.ClassNode)
+		l, err := ast.Layout(class)
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		if l.Size != test.size || l.IsFixed() != (test.size >= 0) {
+			t.Fatalf("mismatch for %s: got size %d, but expected %d", test.class, l.Size, test.size)
+		}
+
+		for i, f := range l.Fields {
+			if f.Offset != test.offsets[i] || f.Size != test.sizes[i] {
+				t.Fatalf("mismatch for %s::%s: got %d+%d, but expected %d+%d", test.class, f.Field.Name(), f.Offset, f.Size, test.offsets[i], test.sizes[i])
+			}
+		}
+	}
+
+	l, err := ast.Layout(root.FindSymbol("MsgBlob", false).Node.(*ast.ClassNode))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if l.Fields[0].Endian != ast.BigEndian || l.Fields[2].Endian != ast.LittleEndian {
+		t.Fatalf("mismatch: got %s %s, but expected be le", l.Fields[0].Endian, l.Fields[2].Endian)
+	}
+}
+
+func TestLayoutErrors(t *testing.T) {
+	root := analyzeString(t, `
+		class Loop {
+			Loop next;
+		};
+
+		class Unknown {
+			Missing field;
+		};
+	`)
+
+	for _, name := range []string{"Loop", "Unknown"} {
+		class := root.FindSymbol(name, false).Node.(*ast.ClassNode)
+
+		if _, err := ast.Layout(class); err == nil {
+			t.Fatalf("expected error for %s", name)
+		}
+	}
+}
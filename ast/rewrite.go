@@ -0,0 +1,279 @@
+package ast
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrNoParent = errors.New("node has no parent")
+
+// RewriteFunc is called by Rewrite for each node. See Rewrite for the meaning
+// of the returned value.
+type RewriteFunc func(*Cursor) bool
+
+// Cursor describes the node being visited by Rewrite and allows modifying the
+// tree around it. Nodes inserted through a cursor aren't visited.
+type Cursor struct {
+	parent Node
+	node   Node
+	index  int
+	after  int
+}
+
+func (c *Cursor) Node() Node {
+	return c.node
+}
+
+func (c *Cursor) Parent() Node {
+	return c.parent
+}
+
+// Index returns the position of the current node in its parent's children, or
+// -1 for the root.
+func (c *Cursor) Index() int {
+	return c.index
+}
+
+// Replace substitutes n for the current node. The symbols the parent scope
+// holds for the old node are dropped and one is added for n, failing on
+// conflicts.
+func (c *Cursor) Replace(n Node) error {
+	if c.parent == nil {
+		return fmt.Errorf("Cannot replace root: %w", ErrNoParent)
+	}
+
+	if c.node == nil {
+		return c.insert(c.index, n)
+	}
+
+	for _, sym := range c.parent.Symbols() {
+		if sym.Value == n.Name() && sym.Node != c.node {
+			return fmt.Errorf("Trying to replace node %v with %w %q", c.node.NamePath(), ErrDuplicateSymbol, sym.Value)
+		}
+	}
+
+	detachSymbols(c.parent, c.node)
+
+	if err := attachSymbol(c.parent, n); err != nil {
+		return err
+	}
+
+	c.parent.RemoveChild(c.node)
+	c.parent.InsertChild(c.index, n)
+	updateDeclarations(c.parent, c.node, n)
+	c.node = n
+
+	return nil
+}
+
+// Delete removes the current node and the symbols its parent holds for it.
+func (c *Cursor) Delete() error {
+	if c.parent == nil {
+		return fmt.Errorf("Cannot delete root: %w", ErrNoParent)
+	}
+
+	if c.node == nil {
+		return nil
+	}
+
+	detachSymbols(c.parent, c.node)
+	c.parent.RemoveChild(c.node)
+	updateDeclarations(c.parent, c.node, nil)
+	c.node = nil
+	c.index--
+
+	return nil
+}
+
+func (c *Cursor) InsertBefore(n Node) error {
+	if c.parent == nil {
+		return fmt.Errorf("Cannot insert before root: %w", ErrNoParent)
+	}
+
+	if err := c.insert(c.index, n); err != nil {
+		return err
+	}
+
+	c.index++
+
+	return nil
+}
+
+func (c *Cursor) InsertAfter(n Node) error {
+	if c.parent == nil {
+		return fmt.Errorf("Cannot insert after root: %w", ErrNoParent)
+	}
+
+	if err := c.insert(c.index+1+c.after, n); err != nil {
+		return err
+	}
+
+	c.after++
+
+	return nil
+}
+
+func (c *Cursor) insert(i int, n Node) error {
+	if err := attachSymbol(c.parent, n); err != nil {
+		return err
+	}
+
+	c.parent.InsertChild(i, n)
+	updateDeclarations(c.parent, nil, n)
+
+	return nil
+}
+
+// Rewrite traverses the tree rooted at root in depth-first order, calling pre
+// before a node's children and post after them. Either may be nil.
+//
+// If pre returns false, the node's children and post are skipped. If post
+// returns false, the traversal stops. Deleting the current node in pre skips
+// its children and post; replacing it descends into the replacement.
+func Rewrite(root Node, pre, post RewriteFunc) {
+	r := &rewriter{pre: pre, post: post}
+	r.apply(nil, -1, root)
+}
+
+type rewriter struct {
+	pre  RewriteFunc
+	post RewriteFunc
+	stop bool
+}
+
+// apply visits n and returns the index of the next sibling to visit.
+func (r *rewriter) apply(parent Node, index int, n Node) int {
+	c := &Cursor{parent: parent, node: n, index: index}
+
+	if r.pre != nil && !r.pre(c) || c.node == nil {
+		return c.index + 1 + c.after
+	}
+
+	children := c.node.Children
+
+	for i := 0; i < len(children()) && !r.stop; {
+		i = r.apply(c.node, i, children()[i])
+	}
+
+	if !r.stop && r.post != nil && !r.post(c) {
+		r.stop = true
+	}
+
+	return c.index + 1 + c.after
+}
+
+// Rename changes the name of a class, enum or property node. The symbol its
+// parent holds for it is renamed in place, so existing references follow.
+func Rename(n Node, name string) error {
+	named, ok := n.(interface{ setName(string) })
+
+	if !ok {
+		return fmt.Errorf("Cannot rename node %v", n.NamePath())
+	}
+
+	if name == "" {
+		return fmt.Errorf("Trying to rename node %v to an %w", n.NamePath(), ErrEmptySymbol)
+	}
+
+	parent := n.Parent()
+
+	if parent == nil {
+		named.setName(name)
+		return nil
+	}
+
+	var sym *Symbol
+
+	for _, s := range parent.Symbols() {
+		if s.Value == name {
+			return fmt.Errorf("Trying to rename node %v to %w %q", n.NamePath(), ErrDuplicateSymbol, name)
+		}
+
+		if s.Value == n.Name() && s.Node == n {
+			sym = s
+		}
+	}
+
+	named.setName(name)
+
+	if sym == nil {
+		return attachSymbol(parent, n)
+	}
+
+	parent.RemoveSymbol(sym.Value)
+	sym.Value = name
+
+	return parent.AddSymbol(sym)
+}
+
+func detachSymbols(scope Node, n Node) []*Symbol {
+	var detached []*Symbol
+
+	for _, sym := range scope.Symbols() {
+		if sym.Node == n {
+			detached = append(detached, scope.RemoveSymbol(sym.Value))
+		}
+	}
+
+	return detached
+}
+
+func attachSymbol(scope Node, n Node) error {
+	named, ok := n.(interface{ Symbol() *Symbol })
+
+	if !ok || n.Name() == "" {
+		return nil
+	}
+
+	return scope.AddSymbol(named.Symbol())
+}
+
+func updateDeclarations(parent Node, old, n Node) {
+	decl := n
+
+	if decl == nil {
+		decl = old
+	}
+
+	// classes and enums may be nested in classes
+	nested := false
+
+	switch decl.(type) {
+	case *ClassNode, *EnumNode:
+		nested = true
+	}
+
+	// the declarations of namespaces and classes are listed by their document
+	for {
+		if ns, ok := parent.(*NamespaceNode); ok {
+			parent = ns.Parent()
+		} else if class, ok := parent.(*ClassNode); ok && nested {
+			parent = class.Parent()
+		} else {
+			break
+		}
+	}
+
+	doc, ok := parent.(*DocumentNode)
+
+	if !ok {
+		return
+	}
+
+	if old == nil {
+		doc.Declarations = append(doc.Declarations, n)
+		return
+	}
+
+	for i, decl := range doc.Declarations {
+		if decl == old {
+			if n == nil {
+				doc.Declarations = append(doc.Declarations[:i], doc.Declarations[i+1:]...)
+			} else {
+				doc.Declarations[i] = n
+			}
+
+			return
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package ast_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/13k/go-steam-language/ast"
+)
+
+func childNames(n ast.Node) string {
+	var names []string
+
+	for _, child := range n.Children() {
+		names = append(names, child.Name())
+	}
+
+	return strings.Join(names, ",")
+}
+
+func symbolNames(n ast.Node) string {
+	var names []string
+
+	for _, sym := range n.Symbols() {
+		names = append(names, sym.Value)
+	}
+
+	return strings.Join(names, ",")
+}
+
+func TestRewriteDelete(t *testing.T) {
+	root := analyzeString(t, `
+		enum E {
+			a = 1;
+			b = 2; obsolete
+			c = 3; obsolete
+			d = 4;
+		};
+	`)
+
+	ast.Rewrite(root, func(c *ast.Cursor) bool {
+		if member, ok := c.Node().(*ast.EnumMemberNode); ok && member.Obsolete {
+			if err := c.Delete(); err != nil {
+				t.Fatalf("not expected error %v", err)
+			}
+		}
+
+		return true
+	}, nil)
+
+	enum := root.Children()[0]
+
+	if got := childNames(enum); got != "a,d" {
+		t.Fatalf("mismatch: got %q, but expected %q", got, "a,d")
+	}
+
+	if got := symbolNames(enum); got != "a,d" {
+		t.Fatalf("mismatch: got %q, but expected %q", got, "a,d")
+	}
+}
+
+func TestRewriteInsertReplace(t *testing.T) {
+	root := analyzeString(t, `enum E { a = 1; b = 2; };`)
+	var visited []string
+
+	ast.Rewrite(root, func(c *ast.Cursor) bool {
+		member, ok := c.Node().(*ast.EnumMemberNode)
+
+		if !ok {
+			return true
+		}
+
+		visited = append(visited, member.Name())
+
+		switch member.Name() {
+		case "a":
+			inserted := ast.NewEnumMemberNode(nil)
+			inserted.Value = []byte("x")
+
+			if err := c.InsertAfter(inserted); err != nil {
+				t.Fatalf("not expected error %v", err)
+			}
+		case "b":
+			replacement := ast.NewEnumMemberNode(nil)
+			replacement.Value = []byte("y")
+
+			if err := c.InsertBefore(replacement); err != nil {
+				t.Fatalf("not expected error %v", err)
+			}
+
+			duplicate := ast.NewEnumMemberNode(nil)
+			duplicate.Value = []byte("a")
+
+			if err := c.Replace(duplicate); !errors.Is(err, ast.ErrDuplicateSymbol) {
+				t.Fatalf("expected error %v, got %v", ast.ErrDuplicateSymbol, err)
+			}
+		}
+
+		return true
+	}, nil)
+
+	enum := root.Children()[0]
+
+	if got := strings.Join(visited, ","); got != "a,b" {
+		t.Fatalf("mismatch: got visited %q, but expected %q", got, "a,b")
+	}
+
+	if got := childNames(enum); got != "a,x,y,b" {
+		t.Fatalf("mismatch: got %q, but expected %q", got, "a,x,y,b")
+	}
+
+	if got := symbolNames(enum); got != "a,b,x,y" {
+		t.Fatalf("mismatch: got %q, but expected %q", got, "a,b,x,y")
+	}
+
+	ast.Rewrite(root, func(c *ast.Cursor) bool {
+		if c.Node().Name() == "b" {
+			replacement := ast.NewEnumMemberNode(nil)
+			replacement.Value = []byte("b")
+
+			if err := c.Replace(replacement); err != nil {
+				t.Fatalf("not expected error %v", err)
+			}
+
+			if enum.Children()[3] != replacement || enum.FindSymbol("b", false).Node != replacement {
+				t.Fatalf("node %q not replaced", "b")
+			}
+		}
+
+		return true
+	}, nil)
+
+	ast.Rewrite(root, nil, func(c *ast.Cursor) bool {
+		if c.Parent() != nil {
+			return true
+		}
+
+		if err := c.Delete(); !errors.Is(err, ast.ErrNoParent) {
+			t.Fatalf("expected error %v, got %v", ast.ErrNoParent, err)
+		}
+
+		return true
+	})
+}
+
+func TestRename(t *testing.T) {
+	root := analyzeString(t, `
+		enum E { a = 1; };
+		class C { E e; };
+	`)
+
+	enum := root.Children()[0]
+
+	if err := ast.Rename(enum, "C"); !errors.Is(err, ast.ErrDuplicateSymbol) {
+		t.Fatalf("expected error %v, got %v", ast.ErrDuplicateSymbol, err)
+	}
+
+	if err := ast.Rename(enum, "EResult"); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	prop := root.Children()[1].Children()[0].(*ast.PropertyNode)
+
+	if prop.Type.Value != "EResult" || prop.Type.Node != enum {
+		t.Fatalf("mismatch: got type %q, but expected %q", prop.Type.Value, "EResult")
+	}
+
+	if sym := root.FindSymbol("E", false); sym != nil {
+		t.Fatalf("not expected symbol %q", sym.Value)
+	}
+}
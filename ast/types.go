@@ -0,0 +1,379 @@
+package ast
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var builtinTypes = map[string]bool{
+	"byte":   true,
+	"sbyte":  true,
+	"short":  true,
+	"ushort": true,
+	"int":    true,
+	"uint":   true,
+	"long":   true,
+	"ulong":  true,
+	"float":  true,
+	"double": true,
+	"bool":   true,
+	"char":   true,
+	"string": true,
+}
+
+func IsBuiltinType(name string) bool {
+	return builtinTypes[name]
+}
+
+// BuiltinTypes returns the names of the builtin types, sorted.
+func BuiltinTypes() []string {
+	names := make([]string, 0, len(builtinTypes))
+
+	for name := range builtinTypes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// StorageType is the underlying integer type of an enum, given by its
+// qualifier. Enums without a qualifier are stored as int.
+type StorageType int
+
+const (
+	StorageInt StorageType = iota
+	StorageByte
+	StorageSByte
+	StorageShort
+	StorageUShort
+	StorageUInt
+	StorageLong
+	StorageULong
+)
+
+var storageTypes = map[string]StorageType{
+	"int":    StorageInt,
+	"byte":   StorageByte,
+	"sbyte":  StorageSByte,
+	"short":  StorageShort,
+	"ushort": StorageUShort,
+	"uint":   StorageUInt,
+	"long":   StorageLong,
+	"ulong":  StorageULong,
+}
+
+// ParseStorageType returns the storage type named by an enum qualifier.
+func ParseStorageType(name string) (StorageType, bool) {
+	t, ok := storageTypes[name]
+	return t, ok
+}
+
+func (t StorageType) String() string {
+	switch t {
+	case StorageInt:
+		return "int"
+	case StorageByte:
+		return "byte"
+	case StorageSByte:
+		return "sbyte"
+	case StorageShort:
+		return "short"
+	case StorageUShort:
+		return "ushort"
+	case StorageUInt:
+		return "uint"
+	case StorageLong:
+		return "long"
+	case StorageULong:
+		return "ulong"
+	default:
+		panic(fmt.Errorf("Unknown StorageType %d", t))
+	}
+}
+
+// Size returns the width of t in bytes.
+func (t StorageType) Size() int {
+	switch t {
+	case StorageByte, StorageSByte:
+		return 1
+	case StorageShort, StorageUShort:
+		return 2
+	case StorageLong, StorageULong:
+		return 8
+	default:
+		return 4
+	}
+}
+
+func (t StorageType) Signed() bool {
+	switch t {
+	case StorageSByte, StorageShort, StorageInt, StorageLong:
+		return true
+	default:
+		return false
+	}
+}
+
+// ByteOrder is the byte order of the values of a field. Steam's wire format is
+// little-endian, but some fields embed big-endian values.
+type ByteOrder int
+
+const (
+	LittleEndian ByteOrder = iota
+	BigEndian
+)
+
+var byteOrders = map[string]ByteOrder{
+	"le": LittleEndian,
+	"be": BigEndian,
+}
+
+// ParseByteOrder returns the byte order named by an `endian:` annotation.
+func ParseByteOrder(name string) (ByteOrder, bool) {
+	o, ok := byteOrders[name]
+	return o, ok
+}
+
+func (o ByteOrder) String() string {
+	switch o {
+	case LittleEndian:
+		return "le"
+	case BigEndian:
+		return "be"
+	default:
+		panic(fmt.Errorf("Unknown ByteOrder %d", o))
+	}
+}
+
+// StringEncoding is the character encoding of a string field. Lengths of
+// strings count code units, bytes in UTF-8 and 16-bit units in UTF-16.
+type StringEncoding int
+
+const (
+	EncodingUTF8 StringEncoding = iota
+	EncodingUTF16
+)
+
+var stringEncodings = map[string]StringEncoding{
+	"utf8":  EncodingUTF8,
+	"utf16": EncodingUTF16,
+}
+
+// ParseStringEncoding returns the encoding named by an `encoding:` annotation.
+func ParseStringEncoding(name string) (StringEncoding, bool) {
+	e, ok := stringEncodings[name]
+	return e, ok
+}
+
+func (e StringEncoding) String() string {
+	switch e {
+	case EncodingUTF8:
+		return "utf8"
+	case EncodingUTF16:
+		return "utf16"
+	default:
+		panic(fmt.Errorf("Unknown StringEncoding %d", e))
+	}
+}
+
+// UnitSize returns the size in bytes of the code units of e.
+func (e StringEncoding) UnitSize() int {
+	if e == EncodingUTF16 {
+		return 2
+	}
+
+	return 1
+}
+
+// Termination is how the end of a string field is found on the wire: by the
+// length field preceding it, or by a null code unit following it.
+type Termination int
+
+const (
+	TermLength Termination = iota
+	TermNull
+)
+
+var terminations = map[string]Termination{
+	"length": TermLength,
+	"null":   TermNull,
+}
+
+// ParseTermination returns the termination named by a `term:` annotation.
+func ParseTermination(name string) (Termination, bool) {
+	t, ok := terminations[name]
+	return t, ok
+}
+
+func (t Termination) String() string {
+	switch t {
+	case TermLength:
+		return "length"
+	case TermNull:
+		return "null"
+	default:
+		panic(fmt.Errorf("Unknown Termination %d", t))
+	}
+}
+
+// Fits reports whether v, as produced by Evaluate, can be stored in t. Values
+// are accepted as unsigned bit patterns of t's width and, for signed types, as
+// negative two's complement values, so `0xFFFFFFFF` and `-1` both fit an int.
+func (t StorageType) Fits(v uint64) bool {
+	bits := uint(t.Size() * 8)
+
+	if bits == 64 || v < 1<<bits {
+		return true
+	}
+
+	return t.Signed() && int64(v) < 0 && int64(v) >= -1<<(bits-1)
+}
+
+// ParseLimit returns the value of a limit of a storage type, spelled like C#'s
+// ulong.MaxValue or int.MinValue, with negative values in two's complement.
+func ParseLimit(s string) (uint64, bool) {
+	i := strings.LastIndex(s, ".")
+
+	if i < 0 {
+		return 0, false
+	}
+
+	t, ok := ParseStorageType(s[:i])
+
+	if !ok {
+		return 0, false
+	}
+
+	max := ^uint64(0) >> (64 - 8*uint(t.Size()))
+
+	if t.Signed() {
+		max >>= 1
+	}
+
+	switch s[i+1:] {
+	case "MaxValue":
+		return max, true
+	case "MinValue":
+		if t.Signed() {
+			return ^max, true
+		}
+
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// LookupSymbol resolves path from scope like Node.FindNestedSymbol, but never
+// creates placeholder symbols for unknown names, and only looks up elements
+// after the first among the symbols of the previous one.
+func LookupSymbol(scope Node, path []string) *Symbol {
+	var sym *Symbol
+	node := scope
+
+	for i, value := range path {
+		if node == nil {
+			return nil
+		}
+
+		if i == 0 {
+			sym = node.FindSymbol(value, false)
+		} else {
+			sym = memberSymbol(node, value)
+		}
+
+		if sym == nil {
+			return nil
+		}
+
+		if sym.Node != nil {
+			node = sym.Node
+		} else {
+			node = sym.Scope
+		}
+	}
+
+	return sym
+}
+
+// PropertyFlag is the modifier preceding the type of a property. Besides
+// const, SteamKit uses flags to mark fields whose wire representation differs
+// from their logical type.
+type PropertyFlag int
+
+const (
+	PropertyFlagNone PropertyFlag = iota
+	PropertyFlagConst
+	// PropertyFlagSteamIDMarshal marks a ulong field holding a SteamID.
+	PropertyFlagSteamIDMarshal
+	// PropertyFlagGameIDMarshal marks a ulong field holding a GameID.
+	PropertyFlagGameIDMarshal
+	// PropertyFlagBoolMarshal marks a bool field sent as a single byte.
+	PropertyFlagBoolMarshal
+	// PropertyFlagProtoMask marks an EMsg field sent with ProtoMask set.
+	PropertyFlagProtoMask
+	// PropertyFlagProtoMaskGC marks a GC message type sent with ProtoMask set.
+	PropertyFlagProtoMaskGC
+	// PropertyFlagProto marks a field holding a serialized protobuf message.
+	PropertyFlagProto
+)
+
+// ProtoMask is the bit set in the message type of protobuf-backed messages.
+const ProtoMask = 0x80000000
+
+var propertyFlags = map[string]PropertyFlag{
+	"const":          PropertyFlagConst,
+	"steamidmarshal": PropertyFlagSteamIDMarshal,
+	"gameidmarshal":  PropertyFlagGameIDMarshal,
+	"boolmarshal":    PropertyFlagBoolMarshal,
+	"protomask":      PropertyFlagProtoMask,
+	"protomaskgc":    PropertyFlagProtoMaskGC,
+	"proto":          PropertyFlagProto,
+}
+
+// ParsePropertyFlag returns the flag named by a property modifier.
+func ParsePropertyFlag(name string) (PropertyFlag, bool) {
+	f, ok := propertyFlags[name]
+	return f, ok
+}
+
+func (f PropertyFlag) String() string {
+	switch f {
+	case PropertyFlagNone:
+		return ""
+	case PropertyFlagConst:
+		return "const"
+	case PropertyFlagSteamIDMarshal:
+		return "steamidmarshal"
+	case PropertyFlagGameIDMarshal:
+		return "gameidmarshal"
+	case PropertyFlagBoolMarshal:
+		return "boolmarshal"
+	case PropertyFlagProtoMask:
+		return "protomask"
+	case PropertyFlagProtoMaskGC:
+		return "protomaskgc"
+	case PropertyFlagProto:
+		return "proto"
+	default:
+		panic(fmt.Errorf("Unknown PropertyFlag %d", f))
+	}
+}
+
+// WireType returns the builtin type a flagged field is sent as, or an empty
+// string if the flag doesn't constrain it.
+func (f PropertyFlag) WireType() string {
+	switch f {
+	case PropertyFlagSteamIDMarshal, PropertyFlagGameIDMarshal:
+		return "ulong"
+	case PropertyFlagBoolMarshal:
+		return "byte"
+	case PropertyFlagProtoMask, PropertyFlagProtoMaskGC:
+		return "uint"
+	default:
+		return ""
+	}
+}
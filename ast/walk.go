@@ -0,0 +1,68 @@
+package ast
+
+// Visitor holds the callbacks invoked by Walk. Any of them may be nil.
+//
+// Enter is called for every node before its children; the typed callbacks are
+// called right after it for the matching node types. If either returns false
+// the node's children are skipped and Exit isn't called for it. Exit is called
+// after all children have been walked.
+type Visitor struct {
+	Enter      func(Node) bool
+	Exit       func(Node)
+	Class      func(*ClassNode) bool
+	Enum       func(*EnumNode) bool
+	EnumMember func(*EnumMemberNode) bool
+	Property   func(*PropertyNode) bool
+}
+
+// Walk traverses the tree rooted at node in depth-first order.
+func Walk(node Node, v *Visitor) {
+	if node == nil || v == nil || !v.visit(node) {
+		return
+	}
+
+	for _, child := range node.Children() {
+		Walk(child, v)
+	}
+
+	if v.Exit != nil {
+		v.Exit(node)
+	}
+}
+
+func (v *Visitor) visit(node Node) bool {
+	if v.Enter != nil && !v.Enter(node) {
+		return false
+	}
+
+	switch n := node.(type) {
+	case *ClassNode:
+		if v.Class != nil {
+			return v.Class(n)
+		}
+	case *EnumNode:
+		if v.Enum != nil {
+			return v.Enum(n)
+		}
+	case *EnumMemberNode:
+		if v.EnumMember != nil {
+			return v.EnumMember(n)
+		}
+	case *PropertyNode:
+		if v.Property != nil {
+			return v.Property(n)
+		}
+	}
+
+	return true
+}
+
+// Inspect traverses the tree rooted at node in depth-first order, calling f
+// for every node. If f returns true, Inspect descends into the node's children
+// and calls f(nil) once they have been walked.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(node, &Visitor{
+		Enter: f,
+		Exit:  func(Node) { f(nil) },
+	})
+}
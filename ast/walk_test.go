@@ -0,0 +1,86 @@
+package ast_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/13k/go-steam-language/ast"
+)
+
+const walkInput = `
+	enum E { a = 1; b = 2; };
+	class C { uint x; E e; };
+`
+
+func TestWalk(t *testing.T) {
+	root := analyzeString(t, walkInput)
+
+	var events []string
+
+	ast.Walk(root, &ast.Visitor{
+		Enter: func(n ast.Node) bool {
+			events = append(events, "enter:"+n.Name())
+			return true
+		},
+		Exit: func(n ast.Node) {
+			events = append(events, "exit:"+n.Name())
+		},
+		Enum: func(n *ast.EnumNode) bool {
+			events = append(events, "enum:"+n.Name())
+			return false
+		},
+		Property: func(n *ast.PropertyNode) bool {
+			events = append(events, "property:"+n.Name())
+			return true
+		},
+	})
+
+	expected := strings.Join([]string{
+		"enter:",
+		"enter:E", "enum:E",
+		"enter:C",
+		"enter:x", "property:x", "exit:x",
+		"enter:e", "property:e", "exit:e",
+		"exit:C",
+		"exit:",
+	}, " ")
+
+	if got := strings.Join(events, " "); got != expected {
+		t.Fatalf("mismatch: got %q, but expected %q", got, expected)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	root := analyzeString(t, walkInput)
+
+	var names []string
+	depth, maxDepth := 0, 0
+
+	ast.Inspect(root, func(n ast.Node) bool {
+		if n == nil {
+			depth--
+			return true
+		}
+
+		switch n.(type) {
+		case *ast.PropertyNode, *ast.EnumMemberNode:
+			names = append(names, n.Name())
+		}
+
+		depth++
+
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+
+		return true
+	})
+
+	if got := strings.Join(names, ","); got != "a,b,x,e" {
+		t.Fatalf("mismatch: got %q, but expected %q", got, "a,b,x,e")
+	}
+
+	if depth != 0 || maxDepth != 3 {
+		t.Fatalf("mismatch: got depth %d/%d, but expected 0/3", depth, maxDepth)
+	}
+}
@@ -0,0 +1,107 @@
+// Package backend is the registry of code generators available to the
+// steamlang command.
+//
+// Backends register themselves from an init function, so that importing their
+// package makes them available:
+//
+//	import _ "github.com/13k/go-steam-language/generator"
+package backend
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/13k/go-steam-language/ast"
+)
+
+// Options configures a generation.
+type Options struct {
+	// Package is the package, namespace or module of the generated code.
+	Package string
+	// SkipRemoved omits members marked `removed` where the wire layout allows
+	// it.
+	SkipRemoved bool
+	// Params are backend-specific settings, like "steamid_type" or "template".
+	// Backends ignore the ones they don't know.
+	Params map[string]string
+	// Exclude holds the names of the top-level declarations already generated
+	// for another document. Backends whose outputs share a namespace skip them.
+	Exclude map[string]bool
+}
+
+// OutputFile is a generated file. Name is slash-separated and relative to the
+// output directory.
+type OutputFile struct {
+	Name    string
+	Content []byte
+}
+
+// Generator is a code generation backend.
+type Generator interface {
+	// Name identifies the backend, like "go".
+	Name() string
+	// Generate returns the files generated from doc, including the
+	// declarations it imports unless excluded.
+	Generate(doc *ast.DocumentNode, opts Options) ([]OutputFile, error)
+}
+
+var (
+	mu         sync.RWMutex
+	generators = make(map[string]Generator)
+)
+
+// Register makes g available by its name. It panics if the name is taken.
+func Register(g Generator) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	name := g.Name()
+
+	if _, ok := generators[name]; ok {
+		panic(fmt.Sprintf("backend: Register called twice for %q", name))
+	}
+
+	generators[name] = g
+}
+
+// Lookup returns the backend registered as name.
+func Lookup(name string) (Generator, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	g, ok := generators[name]
+
+	return g, ok
+}
+
+// Names returns the sorted names of the registered backends.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var names []string
+
+	for name := range generators {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// OutputName returns the base name of the file of doc with its extension
+// replaced by ext, or "main" followed by ext for documents without a file.
+func OutputName(doc *ast.DocumentNode, ext string) string {
+	if doc.Filename == "" {
+		return "main" + ext
+	}
+
+	base := path.Base(filepath.ToSlash(doc.Filename))
+
+	return strings.TrimSuffix(base, path.Ext(base)) + ext
+}
@@ -0,0 +1,123 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/13k/go-steam-language/ast"
+)
+
+type testGenerator struct{}
+
+func (testGenerator) Name() string {
+	return "test"
+}
+
+func (testGenerator) Generate(doc *ast.DocumentNode, opts Options) ([]OutputFile, error) {
+	return []OutputFile{{Name: OutputName(doc, ".txt")}}, nil
+}
+
+func TestRegister(t *testing.T) {
+	Register(testGenerator{})
+
+	if g, ok := Lookup("test"); !ok || g.Name() != "test" {
+		t.Fatalf("expected registered backend")
+	}
+
+	if _, ok := Lookup("missing"); ok {
+		t.Fatalf("expected missing backend not to be found")
+	}
+
+	if names := Names(); len(names) != 1 || names[0] != "test" {
+		t.Fatalf("mismatch: got %v, but expected [test]", names)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic registering a name twice")
+		}
+	}()
+
+	Register(testGenerator{})
+}
+
+func TestOutputName(t *testing.T) {
+	testCases := []struct {
+		filename string
+		expected string
+	}{
+		{"", "main.txt"},
+		{"emsg.steamd", "emsg.txt"},
+		{"dir/steammsg.steamd", "steammsg.txt"},
+		{"dir/noext", "noext.txt"},
+	}
+
+	for _, tc := range testCases {
+		doc := ast.NewDocumentNode(tc.filename)
+
+		if got := OutputName(doc, ".txt"); got != tc.expected {
+			t.Fatalf("mismatch: got %q, but expected %q", got, tc.expected)
+		}
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{"steamID", "steam_id"},
+		{"MsgClientLogon", "msg_client_logon"},
+		{"HTTPRequest", "http_request"},
+		{"key2Value", "key2_value"},
+		{"already_snake", "already_snake"},
+	}
+
+	for _, tc := range testCases {
+		if got := SnakeCase(tc.name); got != tc.expected {
+			t.Fatalf("mismatch: got %q, but expected %q", got, tc.expected)
+		}
+	}
+}
+
+func TestFormatStorageValue(t *testing.T) {
+	testCases := []struct {
+		value    uint64
+		t        ast.StorageType
+		expected string
+	}{
+		{0x80000000, ast.StorageInt, "-2147483648"},
+		{0x80000000, ast.StorageUInt, "2147483648"},
+		{^uint64(0), ast.StorageInt, "-1"},
+		{0xFFFFFFFF, ast.StorageInt, "-1"},
+		{0x8000, ast.StorageShort, "-32768"},
+		{0xFF, ast.StorageByte, "255"},
+		{0x80, ast.StorageSByte, "-128"},
+		{1 << 63, ast.StorageLong, "-9223372036854775808"},
+		{1 << 63, ast.StorageULong, "9223372036854775808"},
+	}
+
+	for _, tc := range testCases {
+		if got := FormatStorageValue(tc.value, tc.t); got != tc.expected {
+			t.Fatalf("mismatch: got %q, but expected %q", got, tc.expected)
+		}
+	}
+}
+
+func TestFormatLiteral(t *testing.T) {
+	testCases := []struct {
+		raw      string
+		value    uint64
+		expected string
+	}{
+		{"0x10", 16, "0x10"},
+		{"ulong.MaxValue", 1<<64 - 1, "18446744073709551615"},
+		{"int.MinValue", ^uint64(1<<31 - 1), "-2147483648"},
+		{"byte.MinValue", 0, "0"},
+	}
+
+	for _, tc := range testCases {
+		if got := FormatLiteral(&ast.LiteralExpr{Raw: tc.raw, Value: tc.value}); got != tc.expected {
+			t.Fatalf("mismatch: got %q, but expected %q", got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,569 @@
+// Package csharp is the "csharp" backend, generating C# enums and classes
+// following the conventions of SteamKit's generated code.
+//
+// Classes are serialized with BinaryWriter and BinaryReader, little-endian and
+// without padding, and implement ISteamSerializable, declared in the
+// SteamSerializable.cs output. With the "steamkit" param set to "true", for
+// code compiled into SteamKit, classes bound to a message type implement
+// SteamKit's ISteamSerializableMessage instead and the interface isn't
+// generated. The "steamid_type" and "gameid_type" params are the types of
+// steamidmarshal and gameidmarshal fields, which must convert from and to
+// ulong, like SteamKit's SteamID and GameID.
+package csharp
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/backend"
+)
+
+const header = "// <auto-generated>\n//     Generated by go-steam-language. DO NOT EDIT.\n// </auto-generated>\n"
+
+// readers are the BinaryReader methods reading each builtin type.
+var readers = map[string]string{
+	"byte":   "ReadByte",
+	"sbyte":  "ReadSByte",
+	"short":  "ReadInt16",
+	"ushort": "ReadUInt16",
+	"int":    "ReadInt32",
+	"uint":   "ReadUInt32",
+	"long":   "ReadInt64",
+	"ulong":  "ReadUInt64",
+	"float":  "ReadSingle",
+	"double": "ReadDouble",
+	"bool":   "ReadBoolean",
+}
+
+func init() {
+	backend.Register(csharpBackend{})
+}
+
+type csharpBackend struct{}
+
+func (csharpBackend) Name() string {
+	return "csharp"
+}
+
+func (csharpBackend) Generate(doc *ast.DocumentNode, opts backend.Options) ([]backend.OutputFile, error) {
+	g := &generator{opts: opts, steamKit: opts.Params["steamkit"] == "true"}
+	fmt.Fprintf(&g.buf, "%s\nusing System;\nusing System.IO;\n\nnamespace %s\n{\n", header, opts.Package)
+
+	for i, child := range backend.Nodes(doc, opts) {
+		if i > 0 {
+			g.buf.WriteString("\n")
+		}
+
+		var err error
+
+		switch n := child.(type) {
+		case *ast.EnumNode:
+			err = g.enum(n)
+		case *ast.ClassNode:
+			err = g.class(n)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	g.buf.WriteString("}\n")
+	files := []backend.OutputFile{{Name: backend.OutputName(doc, ".cs"), Content: g.buf.Bytes()}}
+
+	if !g.steamKit {
+		files = append(files, backend.OutputFile{Name: "SteamSerializable.cs", Content: []byte(interfaces(opts.Package))})
+	}
+
+	return files, nil
+}
+
+func interfaces(namespace string) string {
+	return fmt.Sprintf(`%s
+using System.IO;
+
+namespace %s
+{
+	public interface ISteamSerializable
+	{
+		void Serialize(Stream stream);
+		void Deserialize(Stream stream);
+	}
+}
+`, header, namespace)
+}
+
+type generator struct {
+	buf      bytes.Buffer
+	opts     backend.Options
+	steamKit bool
+}
+
+func (g *generator) printf(indent int, format string, args ...interface{}) {
+	g.buf.WriteString(strings.Repeat("\t", indent))
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) enum(n *ast.EnumNode) error {
+	g.doc(1, n.Doc)
+
+	if n.Flags {
+		g.printf(1, "[Flags]\n")
+	}
+
+	storage := ""
+
+	if n.Type != ast.StorageInt {
+		storage = " : " + n.Type.String()
+	}
+
+	g.printf(1, "public enum %s%s\n", n.Name(), storage)
+	g.printf(1, "{\n")
+
+	for _, m := range n.Members() {
+		if g.opts.SkipRemoved && m.Removed {
+			continue
+		}
+
+		value, reduced, err := backend.ReducedValue(m)
+
+		if err == nil && !reduced {
+			value, err = backend.FormatExpr(m, g.memberRef(n))
+		}
+
+		if err != nil {
+			return err
+		}
+
+		g.doc(2, m.Doc)
+		g.obsolete(2, m)
+		g.printf(2, "%s = %s,%s\n", m.Name(), value, lineComment(m.Comment))
+	}
+
+	g.printf(1, "}\n")
+
+	return nil
+}
+
+// memberRef renders references from the members of enum. Members of enum are
+// referenced by name, since C# gives them the storage type within enum
+// declarations, and values from elsewhere are converted to it.
+func (g *generator) memberRef(enum *ast.EnumNode) func(ast.Node) (string, error) {
+	return func(ref ast.Node) (string, error) {
+		if g.opts.SkipRemoved && backend.IsRemoved(ref) {
+			return g.value(ref)
+		}
+
+		if ref.Parent() == enum {
+			return ref.Name(), nil
+		}
+
+		return fmt.Sprintf("(%s)%s", enum.Type, qualifiedName(ref)), nil
+	}
+}
+
+func (g *generator) class(n *ast.ClassNode) error {
+	name := n.Name()
+	emsg := emsgMember(n)
+	iface := "ISteamSerializable"
+
+	if g.steamKit && emsg != nil {
+		iface = "ISteamSerializableMessage"
+	}
+
+	g.doc(1, n.Doc)
+	g.printf(1, "public class %s : %s\n", name, iface)
+	g.printf(1, "{\n")
+
+	if emsg != nil {
+		enum := emsg.Parent().Name()
+		value := qualifiedName(emsg)
+
+		if g.opts.SkipRemoved && emsg.Removed {
+			v, err := g.value(emsg)
+
+			if err != nil {
+				return err
+			}
+
+			value = fmt.Sprintf("(%s)%s", enum, v)
+		}
+
+		g.printf(2, "public %s GetEMsg() { return %s; }\n\n", enum, value)
+	}
+
+	consts := 0
+
+	for _, c := range n.Consts() {
+		if g.opts.SkipRemoved && c.Removed {
+			continue
+		}
+
+		value, err := g.propertyValue(c)
+
+		if err != nil {
+			return err
+		}
+
+		g.doc(2, c.Doc)
+		g.obsolete(2, c)
+		g.printf(2, "public const %s %s = %s;%s\n", g.fieldType(c), c.Name(), value, lineComment(c.Comment))
+		consts++
+	}
+
+	if consts > 0 {
+		g.buf.WriteString("\n")
+	}
+
+	fields := n.Fields()
+
+	for _, f := range fields {
+		g.doc(2, f.Doc)
+		g.obsolete(2, f)
+		g.printf(2, "public %s %s { get; set; }%s\n", g.fieldType(f), propertyName(f), lineComment(f.Comment))
+	}
+
+	if len(fields) > 0 {
+		g.buf.WriteString("\n")
+	}
+
+	g.printf(2, "public %s()\n", name)
+	g.printf(2, "{\n")
+
+	for _, f := range fields {
+		init, err := g.fieldInit(f)
+
+		if err != nil {
+			return err
+		}
+
+		if init != "" {
+			g.printf(3, "%s = %s;\n", propertyName(f), init)
+		}
+	}
+
+	g.printf(2, "}\n")
+
+	return g.serializers(n)
+}
+
+// emsgMember returns the message type n is bound to by its qualifier, if any.
+func emsgMember(n *ast.ClassNode) *ast.EnumMemberNode {
+	q := n.Qualifier
+
+	if q == nil || q.Kind != ast.QualifierEMsg || q.Symbol == nil {
+		return nil
+	}
+
+	member, _ := q.Symbol.Node.(*ast.EnumMemberNode)
+
+	return member
+}
+
+// fieldInit returns the initial value of f, empty if the C# default fits.
+func (g *generator) fieldInit(f *ast.PropertyNode) (string, error) {
+	if len(f.Default) > 0 || f.Expr != nil {
+		return g.propertyValue(f)
+	}
+
+	if size, ok, err := backend.FixedArraySize(f); ok {
+		return fmt.Sprintf("new %s[%d]", g.elemType(f), size), err
+	}
+
+	if f.Type != nil && f.Flags == ast.PropertyFlagNone {
+		if _, ok := f.Type.Node.(*ast.ClassNode); ok {
+			return fmt.Sprintf("new %s()", g.elemType(f)), nil
+		}
+	}
+
+	return "", nil
+}
+
+// propertyValue renders the value of a constant or field default, converted to
+// the type of p. C# converts enums and integers both ways only explicitly.
+func (g *generator) propertyValue(p *ast.PropertyNode) (string, error) {
+	typeName := g.fieldType(p)
+	enum := enumType(p)
+	refsEnum := false
+
+	value, err := backend.FormatExpr(p, func(ref ast.Node) (string, error) {
+		if g.opts.SkipRemoved && backend.IsRemoved(ref) {
+			return g.value(ref)
+		}
+
+		// fields are instance properties, so their defaults are inlined
+		if f, ok := ref.(*ast.PropertyNode); ok && !f.IsConst() {
+			return g.value(ref)
+		}
+
+		if _, ok := ref.(*ast.EnumMemberNode); !ok {
+			return qualifiedName(ref), nil
+		}
+
+		if enum == nil {
+			return fmt.Sprintf("(%s)%s", typeName, qualifiedName(ref)), nil
+		}
+
+		if ref.Parent() == enum {
+			refsEnum = true
+			return qualifiedName(ref), nil
+		}
+
+		return fmt.Sprintf("(%s)%s", enum.Type, qualifiedName(ref)), nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if p.Flags == ast.PropertyFlagBoolMarshal {
+		if lit, ok := ast.ValueExpr(p).(*ast.LiteralExpr); ok {
+			return fmt.Sprintf("%t", lit.Value != 0), nil
+		}
+
+		return "(" + value + ") != 0", nil
+	}
+
+	if enum != nil && !refsEnum && value != "0" {
+		value = fmt.Sprintf("(%s)(%s)", typeName, value)
+	}
+
+	return value, nil
+}
+
+// enumType returns the enum p is declared with, nil for other types.
+func enumType(p *ast.PropertyNode) *ast.EnumNode {
+	if p.Type == nil || p.Qualifier != nil && p.Qualifier.Kind == ast.QualifierSize {
+		return nil
+	}
+
+	switch p.Flags {
+	case ast.PropertyFlagNone, ast.PropertyFlagConst, ast.PropertyFlagProtoMask, ast.PropertyFlagProtoMaskGC:
+		enum, _ := p.Type.Node.(*ast.EnumNode)
+		return enum
+	}
+
+	return nil
+}
+
+func (g *generator) value(ref ast.Node) (string, error) {
+	return backend.FormatNodeValue(ref)
+}
+
+func (g *generator) fieldType(f *ast.PropertyNode) string {
+	if _, ok, _ := backend.FixedArraySize(f); ok {
+		return g.elemType(f) + "[]"
+	}
+
+	return g.elemType(f)
+}
+
+func (g *generator) elemType(f *ast.PropertyNode) string {
+	switch f.Flags {
+	case ast.PropertyFlagBoolMarshal:
+		return "bool"
+	case ast.PropertyFlagSteamIDMarshal:
+		return typeOrDefault(g.opts.Params["steamid_type"], "ulong")
+	case ast.PropertyFlagGameIDMarshal:
+		return typeOrDefault(g.opts.Params["gameid_type"], "ulong")
+	}
+
+	if f.Type == nil {
+		return "int"
+	}
+
+	return f.Type.Value
+}
+
+func (g *generator) serializers(n *ast.ClassNode) error {
+	var ser, de []string
+
+	for _, f := range n.Fields() {
+		s, d, err := g.fieldSerializer(f)
+
+		if err != nil {
+			return err
+		}
+
+		ser = append(ser, s...)
+		de = append(de, d...)
+	}
+
+	g.buf.WriteString("\n")
+	g.printf(2, "public void Serialize(Stream stream)\n")
+	g.printf(2, "{\n")
+	g.printf(3, "BinaryWriter bw = new BinaryWriter(stream);\n")
+	g.lines(3, ser)
+	g.printf(2, "}\n\n")
+	g.printf(2, "public void Deserialize(Stream stream)\n")
+	g.printf(2, "{\n")
+	g.printf(3, "BinaryReader br = new BinaryReader(stream);\n")
+	g.lines(3, de)
+	g.printf(2, "}\n")
+	g.printf(1, "}\n")
+
+	return nil
+}
+
+func (g *generator) lines(indent int, lines []string) {
+	if len(lines) > 0 {
+		g.buf.WriteString("\n")
+	}
+
+	for _, line := range lines {
+		g.printf(indent, "%s\n", line)
+	}
+}
+
+func (g *generator) fieldSerializer(f *ast.PropertyNode) ([]string, []string, error) {
+	field := propertyName(f)
+	typeName := g.fieldType(f)
+
+	if f.Endian == ast.BigEndian {
+		return nil, nil, fmt.Errorf("Cannot serialize big-endian field %s", backend.QualifiedName(f))
+	}
+
+	switch f.Flags {
+	case ast.PropertyFlagSteamIDMarshal, ast.PropertyFlagGameIDMarshal:
+		ser := fmt.Sprintf("bw.Write((ulong)%s);", field)
+		de := fmt.Sprintf("%s = (%s)br.ReadUInt64();", field, typeName)
+		return []string{ser}, []string{de}, nil
+	case ast.PropertyFlagBoolMarshal:
+		wire := "byte"
+
+		if f.Type != nil && readers[f.Type.Value] != "" {
+			wire = f.Type.Value
+		}
+
+		ser := fmt.Sprintf("bw.Write((%s)(%s ? 1 : 0));", wire, field)
+		de := fmt.Sprintf("%s = br.%s() != 0;", field, readers[wire])
+		return []string{ser}, []string{de}, nil
+	case ast.PropertyFlagProtoMask, ast.PropertyFlagProtoMaskGC:
+		ser := fmt.Sprintf("bw.Write((uint)%s | %#xu);", field, ast.ProtoMask)
+		de := fmt.Sprintf("%s = (%s)(br.ReadUInt32() & ~%#xu);", field, typeName, ast.ProtoMask)
+		return []string{ser}, []string{de}, nil
+	case ast.PropertyFlagProto:
+		return nil, nil, fmt.Errorf("Cannot serialize proto field %s", backend.QualifiedName(f))
+	}
+
+	if f.Optional {
+		return nil, nil, fmt.Errorf("Cannot serialize optional field %s", backend.QualifiedName(f))
+	}
+
+	if f.Condition != nil {
+		return nil, nil, fmt.Errorf("Cannot serialize conditional field %s", backend.QualifiedName(f))
+	}
+
+	if size, ok, err := backend.FixedArraySize(f); ok {
+		if err != nil {
+			return nil, nil, err
+		}
+
+		elem := g.elemType(f)
+
+		if elem == "byte" {
+			return []string{fmt.Sprintf("bw.Write(%s);", field)}, []string{fmt.Sprintf("%s = br.ReadBytes(%d);", field, size)}, nil
+		}
+
+		write, read, err := g.valueSerializer(f, "v", elem)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		ser := fmt.Sprintf("foreach (%s v in %s) { %s }", elem, field, write)
+		de := fmt.Sprintf("for (int i = 0; i < %s.Length; i++) { %s[i] = %s; }", field, field, read)
+		return []string{ser}, []string{de}, nil
+	}
+
+	write, read, err := g.valueSerializer(f, field, typeName)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if f.Type != nil {
+		if _, ok := f.Type.Node.(*ast.ClassNode); ok {
+			return []string{write}, []string{read}, nil
+		}
+	}
+
+	return []string{write}, []string{fmt.Sprintf("%s = %s;", field, read)}, nil
+}
+
+// valueSerializer returns the statement writing value, of the type of f or of
+// its elements, and the expression reading it, or the statement for classes.
+func (g *generator) valueSerializer(f *ast.PropertyNode, value, typeName string) (string, string, error) {
+	if f.Type != nil {
+		switch n := f.Type.Node.(type) {
+		case *ast.ClassNode:
+			return fmt.Sprintf("%s.Serialize(stream);", value), fmt.Sprintf("%s.Deserialize(stream);", value), nil
+		case *ast.EnumNode:
+			storage := n.Type.String()
+			return fmt.Sprintf("bw.Write((%s)%s);", storage, value), fmt.Sprintf("(%s)br.%s()", typeName, readers[storage]), nil
+		}
+
+		if readers[f.Type.Value] == "" {
+			return "", "", fmt.Errorf("Cannot serialize %s of type %s", backend.QualifiedName(f), f.Type.Value)
+		}
+
+		return fmt.Sprintf("bw.Write(%s);", value), fmt.Sprintf("br.%s()", readers[f.Type.Value]), nil
+	}
+
+	return fmt.Sprintf("bw.Write(%s);", value), "br.ReadInt32()", nil
+}
+
+func (g *generator) doc(indent int, doc string) {
+	if doc == "" {
+		return
+	}
+
+	g.printf(indent, "/// <summary>\n")
+
+	for _, line := range strings.Split(doc, "\n") {
+		g.printf(indent, "/// %s\n", escapeXML(line))
+	}
+
+	g.printf(indent, "/// </summary>\n")
+}
+
+func (g *generator) obsolete(indent int, n ast.Node) {
+	if reason, ok := backend.Deprecation(n); ok {
+		g.printf(indent, "[Obsolete(%q)]\n", reason)
+	}
+}
+
+func qualifiedName(n ast.Node) string {
+	return n.Parent().Name() + "." + n.Name()
+}
+
+// propertyName capitalizes the name of a field, like SteamKit.
+func propertyName(f *ast.PropertyNode) string {
+	name := f.Name()
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:]
+}
+
+func lineComment(comment string) string {
+	if comment == "" {
+		return ""
+	}
+
+	return " // " + comment
+}
+
+func typeOrDefault(typeName, def string) string {
+	if typeName == "" {
+		return def
+	}
+
+	return typeName
+}
+
+func escapeXML(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
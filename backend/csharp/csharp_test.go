@@ -0,0 +1,166 @@
+package csharp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/13k/go-steam-language/backend"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+const schema = `/// Message types.
+enum EMsg { Invalid = 0; Multi = 1; };
+enum EUniverse<byte> { Invalid = 0; Beta = 2; obsolete "not used anymore" };
+enum EFlags flags { A = 1; B = 2; AB = A | B; Old = 4; removed };
+enum EOther<short> { X = EFlags::B; Min = 0x8000; };
+class MsgHdr<EMsg::Multi> {
+	const uint SIZE = 4;
+	steamidmarshal ulong steamID;
+	boolmarshal byte valid = 1;
+	protomask EMsg msg = EMsg::Invalid;
+	EUniverse universe;
+	EFlags flags = 3;
+	uint mask = MsgHdr::SIZE | EFlags::A;
+	byte<20> key;
+	int<MsgHdr::SIZE> values;
+};
+class MsgWrapped { MsgHdr header; };
+`
+
+func generate(t *testing.T, opts backend.Options) []backend.OutputFile {
+	doc, err := parse.NewAnalyzer(token.NewTokenizer([]byte(schema)), "schemas/emsg.steamd").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	gen, ok := backend.Lookup("csharp")
+
+	if !ok {
+		t.Fatalf("expected csharp backend to be registered")
+	}
+
+	files, err := gen.Generate(doc, opts)
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	return files
+}
+
+func TestGenerate(t *testing.T) {
+	files := generate(t, backend.Options{Package: "SteamLang", Params: map[string]string{"steamid_type": "SteamID"}})
+
+	if len(files) != 2 || files[0].Name != "emsg.cs" || files[1].Name != "SteamSerializable.cs" {
+		t.Fatalf("mismatch: got %v, but expected emsg.cs and SteamSerializable.cs", files)
+	}
+
+	src := string(files[0].Content)
+
+	expected := []string{
+		"namespace SteamLang\n{",
+		"/// <summary>\n\t/// Message types.\n\t/// </summary>\n\tpublic enum EMsg\n",
+		"public enum EUniverse : byte",
+		"[Obsolete(\"not used anymore\")]\n\t\tBeta = 2,",
+		"[Flags]\n\tpublic enum EFlags\n",
+		"AB = A | B,",
+		"[Obsolete(\"removed\")]\n\t\tOld = 4,",
+		"X = (short)EFlags.B,",
+		"Min = -32768,",
+		"public class MsgHdr : ISteamSerializable",
+		"public EMsg GetEMsg() { return EMsg.Multi; }",
+		"public const uint SIZE = 4;",
+		"public SteamID SteamID { get; set; }",
+		"public bool Valid { get; set; }",
+		"public byte[] Key { get; set; }",
+		"public int[] Values { get; set; }",
+		"Valid = true;",
+		"Msg = EMsg.Invalid;",
+		"Flags = (EFlags)(3);",
+		"Mask = MsgHdr.SIZE | (uint)EFlags.A;",
+		"Key = new byte[20];",
+		"Values = new int[4];",
+		"bw.Write((ulong)SteamID);",
+		"SteamID = (SteamID)br.ReadUInt64();",
+		"bw.Write((byte)(Valid ? 1 : 0));",
+		"Valid = br.ReadByte() != 0;",
+		"bw.Write((uint)Msg | 0x80000000u);",
+		"Msg = (EMsg)(br.ReadUInt32() & ~0x80000000u);",
+		"bw.Write((byte)Universe);",
+		"Universe = (EUniverse)br.ReadByte();",
+		"Key = br.ReadBytes(20);",
+		"foreach (int v in Values) { bw.Write(v); }",
+		"for (int i = 0; i < Values.Length; i++) { Values[i] = br.ReadInt32(); }",
+		"Header = new MsgHdr();",
+		"Header.Serialize(stream);",
+		"Header.Deserialize(stream);",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(src, s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+}
+
+func TestGenerateSteamKit(t *testing.T) {
+	opts := backend.Options{
+		Package:     "SteamKit2.Internal",
+		SkipRemoved: true,
+		Params:      map[string]string{"steamkit": "true"},
+		Exclude:     map[string]bool{"EMsg": true},
+	}
+
+	files := generate(t, opts)
+
+	if len(files) != 1 {
+		t.Fatalf("mismatch: got %d files, but expected 1", len(files))
+	}
+
+	src := string(files[0].Content)
+
+	if strings.Contains(src, "enum EMsg") || strings.Contains(src, "Old") {
+		t.Fatalf("expected excluded and removed declarations to be skipped\n%s", src)
+	}
+
+	for _, s := range []string{"public class MsgHdr : ISteamSerializableMessage", "public class MsgWrapped : ISteamSerializable\n"} {
+		if !strings.Contains(src, s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+}
+
+func TestGenerateFieldReferences(t *testing.T) {
+	doc, err := parse.NewAnalyzer(token.NewTokenizer([]byte("enum EMsg { Multi = 1; };\nclass C { uint a = 3; uint b = a + 1; EMsg msg = EMsg::Multi; EMsg other = msg; };")), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	gen, _ := backend.Lookup("csharp")
+	files, err := gen.Generate(doc, backend.Options{Package: "Steam"})
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	src := string(files[0].Content)
+
+	for _, s := range []string{"B = 3 + 1;", "Other = (EMsg)(1);"} {
+		if !strings.Contains(src, s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+
+	doc, err = parse.NewAnalyzer(token.NewTokenizer([]byte("class C { uint a = b; uint b = a; };")), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if _, err := gen.Generate(doc, backend.Options{}); err == nil || !strings.Contains(err.Error(), "Circular value") {
+		t.Fatalf("mismatch: got %v, but expected circular value error", err)
+	}
+}
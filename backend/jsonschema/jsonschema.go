@@ -0,0 +1,369 @@
+// Package jsonschema is the "jsonschema" backend, exporting a JSON Schema
+// (draft 2020-12) per document that validates class payloads as decoded by the
+// codec package: objects keyed by field name, with enums as integers, byte
+// arrays as base64 strings and other arrays as fixed length arrays.
+//
+// The "enums" param selects how enum values are represented: "integer", the
+// default, or "string" for member names. Values of flags enums are always
+// integers since they combine members. Documents are self-contained, so
+// imported declarations are exported in each of them.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/backend"
+)
+
+const draft = "https://json-schema.org/draft/2020-12/schema"
+
+func init() {
+	backend.Register(jsonSchemaBackend{})
+}
+
+type jsonSchemaBackend struct{}
+
+func (jsonSchemaBackend) Name() string {
+	return "jsonschema"
+}
+
+func (jsonSchemaBackend) Generate(doc *ast.DocumentNode, opts backend.Options) ([]backend.OutputFile, error) {
+	g := &generator{opts: opts}
+
+	switch opts.Params["enums"] {
+	case "", "integer":
+	case "string":
+		g.enumNames = true
+	default:
+		return nil, fmt.Errorf("Unknown enums representation %q, expected integer or string", opts.Params["enums"])
+	}
+
+	root := &schema{Schema: draft, Title: backend.OutputName(doc, "")}
+
+	for _, child := range doc.Types() {
+		var (
+			def *schema
+			err error
+		)
+
+		switch n := child.(type) {
+		case *ast.EnumNode:
+			def, err = g.enum(n)
+		case *ast.ClassNode:
+			def, err = g.class(n)
+			root.AnyOf = append(root.AnyOf, &schema{Ref: ref(n)})
+		default:
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		root.Defs = append(root.Defs, definition{Name: child.Name(), Schema: def})
+	}
+
+	content, err := json.MarshalIndent(root, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []backend.OutputFile{{Name: backend.OutputName(doc, ".schema.json"), Content: append(content, '\n')}}, nil
+}
+
+// schema is the subset of JSON Schema keywords used by the exported documents,
+// in the order they're written.
+type schema struct {
+	Schema          string        `json:"$schema,omitempty"`
+	Ref             string        `json:"$ref,omitempty"`
+	Title           string        `json:"title,omitempty"`
+	Description     string        `json:"description,omitempty"`
+	Deprecated      bool          `json:"deprecated,omitempty"`
+	Type            string        `json:"type,omitempty"`
+	Enum            []interface{} `json:"enum,omitempty"`
+	EnumNames       []string      `json:"x-enumNames,omitempty"`
+	Minimum         json.Number   `json:"minimum,omitempty"`
+	Maximum         json.Number   `json:"maximum,omitempty"`
+	ContentEncoding string        `json:"contentEncoding,omitempty"`
+	Items           *schema       `json:"items,omitempty"`
+	MinItems        *int          `json:"minItems,omitempty"`
+	MaxItems        *int          `json:"maxItems,omitempty"`
+	Properties      definitions   `json:"properties,omitempty"`
+	Required        []string      `json:"required,omitempty"`
+	Additional      *bool         `json:"additionalProperties,omitempty"`
+	Default         interface{}   `json:"default,omitempty"`
+	AnyOf           []*schema     `json:"anyOf,omitempty"`
+	Defs            definitions   `json:"$defs,omitempty"`
+}
+
+type definition struct {
+	Name   string
+	Schema *schema
+}
+
+// definitions are named schemas, marshaled as an object keeping their order of
+// declaration.
+type definitions []definition
+
+func (defs definitions) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+
+	for i, def := range defs {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		value, err := json.Marshal(def.Schema)
+
+		if err != nil {
+			return nil, err
+		}
+
+		buf.WriteString(strconv.Quote(def.Name))
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+type generator struct {
+	opts      backend.Options
+	enumNames bool
+}
+
+func ref(n ast.Node) string {
+	return "#/$defs/" + n.Name()
+}
+
+func (g *generator) enum(n *ast.EnumNode) (*schema, error) {
+	def := &schema{Title: n.Name(), Description: n.Doc}
+
+	if n.Flags {
+		def.Type = "integer"
+		def.Minimum, def.Maximum = bounds(int(n.Type.Size()), n.Type.Signed())
+		return def, nil
+	}
+
+	if g.enumNames {
+		def.Type = "string"
+	} else {
+		def.Type = "integer"
+	}
+
+	seen := make(map[uint64]bool)
+
+	for _, m := range n.Members() {
+		if g.opts.SkipRemoved && m.Removed {
+			continue
+		}
+
+		if g.enumNames {
+			def.Enum = append(def.Enum, m.Name())
+			continue
+		}
+
+		v, err := backend.Value(m)
+
+		if err != nil {
+			return nil, err
+		}
+
+		v = backend.StorageValue(v, n.Type)
+
+		if seen[v] {
+			continue
+		}
+
+		seen[v] = true
+		def.Enum = append(def.Enum, number(v, n.Type.Signed()))
+		def.EnumNames = append(def.EnumNames, m.Name())
+	}
+
+	return def, nil
+}
+
+func (g *generator) class(n *ast.ClassNode) (*schema, error) {
+	additional := false
+	def := &schema{Title: n.Name(), Description: n.Doc, Type: "object", Additional: &additional}
+
+	for _, f := range n.Fields() {
+		prop, err := g.field(f)
+
+		if err != nil {
+			return nil, err
+		}
+
+		def.Properties = append(def.Properties, definition{Name: f.Name(), Schema: prop})
+
+		if !f.Optional && f.Condition == nil {
+			def.Required = append(def.Required, f.Name())
+		}
+	}
+
+	return def, nil
+}
+
+func (g *generator) field(f *ast.PropertyNode) (*schema, error) {
+	prop, err := g.value(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if size, ok, err := backend.ArraySize(f); ok {
+		if err != nil {
+			return nil, err
+		}
+
+		isType := func(name string) bool {
+			return f.Flags == ast.PropertyFlagNone && f.Type != nil && f.Type.Value == name
+		}
+
+		switch {
+		case isType("byte"):
+			prop = &schema{Type: "string", ContentEncoding: "base64"}
+		case isType("string") && size < 0:
+			// strings of variable length hold text
+		case size < 0:
+			prop = &schema{Type: "array", Items: prop}
+		default:
+			prop = &schema{Type: "array", Items: prop, MinItems: &size, MaxItems: &size}
+		}
+	}
+
+	prop.Description = f.Doc
+	_, prop.Deprecated = backend.Deprecation(f)
+
+	if len(f.Default) > 0 || f.Expr != nil {
+		if prop.Default, err = g.defaultValue(f); err != nil {
+			return nil, err
+		}
+	}
+
+	return prop, nil
+}
+
+// value returns the schema of the values of f, or of its elements.
+func (g *generator) value(f *ast.PropertyNode) (*schema, error) {
+	switch f.Flags {
+	case ast.PropertyFlagBoolMarshal:
+		return &schema{Type: "boolean"}, nil
+	case ast.PropertyFlagSteamIDMarshal, ast.PropertyFlagGameIDMarshal:
+		min, max := bounds(8, false)
+		return &schema{Type: "integer", Minimum: min, Maximum: max}, nil
+	}
+
+	if f.Type == nil {
+		min, max := bounds(4, true)
+		return &schema{Type: "integer", Minimum: min, Maximum: max}, nil
+	}
+
+	switch n := f.Type.Node.(type) {
+	case *ast.EnumNode, *ast.ClassNode:
+		return &schema{Ref: ref(n)}, nil
+	}
+
+	switch f.Type.Value {
+	case "float", "double":
+		return &schema{Type: "number"}, nil
+	case "bool":
+		return &schema{Type: "boolean"}, nil
+	case "string":
+		return &schema{Type: "string"}, nil
+	}
+
+	if size, signed, ok := integerType(f.Type.Value); ok {
+		min, max := bounds(size, signed)
+		return &schema{Type: "integer", Minimum: min, Maximum: max}, nil
+	}
+
+	return nil, fmt.Errorf("Cannot export %s of type %s", backend.QualifiedName(f), f.Type.Value)
+}
+
+func (g *generator) defaultValue(f *ast.PropertyNode) (interface{}, error) {
+	v, err := backend.Value(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if f.Flags == ast.PropertyFlagBoolMarshal || (f.Type != nil && f.Type.Value == "bool") {
+		return v != 0, nil
+	}
+
+	if f.Type == nil {
+		return number(v, true), nil
+	}
+
+	if enum, ok := f.Type.Node.(*ast.EnumNode); ok {
+		if g.enumNames && !enum.Flags {
+			for _, m := range enum.Members() {
+				if mv, err := backend.Value(m); err == nil && mv == v && !(g.opts.SkipRemoved && m.Removed) {
+					return m.Name(), nil
+				}
+			}
+
+			return nil, nil
+		}
+
+		return number(v, enum.Type.Signed()), nil
+	}
+
+	_, signed, _ := integerType(f.Type.Value)
+
+	return number(v, signed || f.Type.Value == "float" || f.Type.Value == "double"), nil
+}
+
+// integerType returns the size and signedness of a builtin integer type.
+func integerType(name string) (int, bool, bool) {
+	switch name {
+	case "byte", "char":
+		return 1, false, true
+	case "sbyte":
+		return 1, true, true
+	case "short":
+		return 2, true, true
+	case "ushort":
+		return 2, false, true
+	case "int":
+		return 4, true, true
+	case "uint":
+		return 4, false, true
+	case "long":
+		return 8, true, true
+	case "ulong":
+		return 8, false, true
+	}
+
+	return 0, false, false
+}
+
+// bounds returns the minimum and maximum values of an integer of size bytes.
+func bounds(size int, signed bool) (json.Number, json.Number) {
+	bits := uint(size * 8)
+
+	if signed {
+		max := uint64(1)<<(bits-1) - 1
+		return json.Number("-" + strconv.FormatUint(max+1, 10)), json.Number(strconv.FormatUint(max, 10))
+	}
+
+	return json.Number("0"), json.Number(strconv.FormatUint(1<<bits-1, 10))
+}
+
+func number(v uint64, signed bool) json.Number {
+	if signed {
+		return json.Number(backend.FormatValue(v))
+	}
+
+	return json.Number(strconv.FormatUint(v, 10))
+}
@@ -0,0 +1,134 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/13k/go-steam-language/backend"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+const schemaSource = `/// Message types.
+enum EMsg { Invalid = 0; Multi = 1; Alias = 1; };
+enum EFlags<byte> flags { A = 1; B = 2; };
+enum EOther<short> { X = -1; Y = 7; Old = 8; removed };
+class MsgHdr<EMsg::Multi> {
+	const uint SIZE = 4;
+	/// Sender.
+	steamidmarshal ulong steamID;
+	boolmarshal byte valid = 1;
+	protomask EMsg msg = EMsg::Multi;
+	EFlags flags;
+	byte<20> key;
+	EOther<MsgHdr::SIZE> others;
+	short old; obsolete
+};
+class MsgWrapped { MsgHdr header; };
+`
+
+func generate(t *testing.T, opts backend.Options) map[string]interface{} {
+	doc, err := parse.NewAnalyzer(token.NewTokenizer([]byte(schemaSource)), "schemas/emsg.steamd").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	gen, ok := backend.Lookup("jsonschema")
+
+	if !ok {
+		t.Fatalf("expected jsonschema backend to be registered")
+	}
+
+	files, err := gen.Generate(doc, opts)
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if len(files) != 1 || files[0].Name != "emsg.schema.json" {
+		t.Fatalf("mismatch: got %v, but expected emsg.schema.json only", files)
+	}
+
+	var root map[string]interface{}
+
+	if err := json.Unmarshal(files[0].Content, &root); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	return root
+}
+
+// lookup returns the value at the slash separated path of keys in root.
+func lookup(t *testing.T, root map[string]interface{}, path string) interface{} {
+	var value interface{} = root
+
+	for _, key := range strings.Split(path, "/") {
+		obj, ok := value.(map[string]interface{})
+
+		if !ok {
+			t.Fatalf("expected %q to be an object in path %s", key, path)
+		}
+
+		value = obj[key]
+	}
+
+	return value
+}
+
+func TestGenerate(t *testing.T) {
+	root := generate(t, backend.Options{})
+
+	tests := []struct {
+		path     string
+		expected interface{}
+	}{
+		{"$schema", draft},
+		{"title", "emsg"},
+		{"$defs/EMsg/description", "Message types."},
+		{"$defs/EMsg/enum", []interface{}{0.0, 1.0}},
+		{"$defs/EMsg/x-enumNames", []interface{}{"Invalid", "Multi"}},
+		{"$defs/EFlags/type", "integer"},
+		{"$defs/EFlags/maximum", 255.0},
+		{"$defs/EOther/enum", []interface{}{-1.0, 7.0, 8.0}},
+		{"$defs/MsgHdr/required", []interface{}{"steamID", "valid", "msg", "flags", "key", "others", "old"}},
+		{"$defs/MsgHdr/additionalProperties", false},
+		{"$defs/MsgHdr/properties/steamID/description", "Sender."},
+		{"$defs/MsgHdr/properties/steamID/minimum", 0.0},
+		{"$defs/MsgHdr/properties/valid", map[string]interface{}{"type": "boolean", "default": true}},
+		{"$defs/MsgHdr/properties/msg", map[string]interface{}{"$ref": "#/$defs/EMsg", "default": 1.0}},
+		{"$defs/MsgHdr/properties/key", map[string]interface{}{"type": "string", "contentEncoding": "base64"}},
+		{"$defs/MsgHdr/properties/others/items", map[string]interface{}{"$ref": "#/$defs/EOther"}},
+		{"$defs/MsgHdr/properties/others/maxItems", 4.0},
+		{"$defs/MsgHdr/properties/old/deprecated", true},
+		{"$defs/MsgWrapped/properties/header", map[string]interface{}{"$ref": "#/$defs/MsgHdr"}},
+		{"anyOf", []interface{}{
+			map[string]interface{}{"$ref": "#/$defs/MsgHdr"},
+			map[string]interface{}{"$ref": "#/$defs/MsgWrapped"},
+		}},
+	}
+
+	for _, test := range tests {
+		if actual := lookup(t, root, test.path); !reflect.DeepEqual(actual, test.expected) {
+			t.Fatalf("mismatch at %s: got %v, but expected %v", test.path, actual, test.expected)
+		}
+	}
+}
+
+func TestGenerateEnumNames(t *testing.T) {
+	root := generate(t, backend.Options{SkipRemoved: true, Params: map[string]string{"enums": "string"}})
+
+	if actual := lookup(t, root, "$defs/EOther/enum"); !reflect.DeepEqual(actual, []interface{}{"X", "Y"}) {
+		t.Fatalf("mismatch: got %v, but expected [X Y]", actual)
+	}
+
+	if actual := lookup(t, root, "$defs/MsgHdr/properties/msg/default"); actual != "Multi" {
+		t.Fatalf("mismatch: got %v, but expected Multi", actual)
+	}
+
+	if actual := lookup(t, root, "$defs/EFlags/type"); actual != "integer" {
+		t.Fatalf("mismatch: got %v, but expected integer", actual)
+	}
+}
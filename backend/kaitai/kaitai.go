@@ -0,0 +1,456 @@
+// Package kaitai is the "kaitai" backend, exporting a Kaitai Struct spec per
+// class, named after it in snake case, for inspecting payloads in the Kaitai
+// IDE and compiling parsers for the languages it supports.
+//
+// Specs are little-endian, fields annotated `endian:be` aside, and list the
+// fields of the class with their types and sizes. Nested classes are imported
+// from their own specs, and the enums a class uses are declared in its spec.
+// protomask fields are read raw, with the mask cleared by an instance of the
+// field name.
+package kaitai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/backend"
+)
+
+const header = "# Code generated by go-steam-language. DO NOT EDIT.\n"
+
+// types are the Kaitai types of the builtin types.
+var types = map[string]string{
+	"byte":   "u1",
+	"sbyte":  "s1",
+	"short":  "s2",
+	"ushort": "u2",
+	"int":    "s4",
+	"uint":   "u4",
+	"long":   "s8",
+	"ulong":  "u8",
+	"float":  "f4",
+	"double": "f8",
+	"bool":   "u1",
+	"char":   "u1",
+}
+
+func init() {
+	backend.Register(kaitaiBackend{})
+}
+
+type kaitaiBackend struct{}
+
+func (kaitaiBackend) Name() string {
+	return "kaitai"
+}
+
+func (kaitaiBackend) Generate(doc *ast.DocumentNode, opts backend.Options) ([]backend.OutputFile, error) {
+	var files []backend.OutputFile
+
+	for _, child := range backend.Nodes(doc, opts) {
+		class, ok := child.(*ast.ClassNode)
+
+		if !ok {
+			continue
+		}
+
+		g := &generator{opts: opts}
+		id := identifier(class.Name())
+
+		if err := g.class(class); err != nil {
+			return nil, err
+		}
+
+		files = append(files, backend.OutputFile{Name: id + ".ksy", Content: g.buf.Bytes()})
+	}
+
+	return files, nil
+}
+
+type generator struct {
+	buf  bytes.Buffer
+	opts backend.Options
+}
+
+func (g *generator) printf(indent int, format string, args ...interface{}) {
+	g.buf.WriteString(strings.Repeat("  ", indent))
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// instance is a value computed from a raw field.
+type instance struct {
+	id, value, enum string
+}
+
+func (g *generator) class(n *ast.ClassNode) error {
+	var (
+		imports   = make(map[string]bool)
+		enums     []*ast.EnumNode
+		instances []instance
+	)
+
+	seenEnums := make(map[*ast.EnumNode]bool)
+
+	for _, f := range n.Fields() {
+		if class := classType(f); class != nil {
+			imports[identifier(class.Name())] = true
+		}
+
+		if enum := enumType(f); enum != nil && !seenEnums[enum] {
+			seenEnums[enum] = true
+			enums = append(enums, enum)
+		}
+	}
+
+	g.buf.WriteString(header)
+	g.printf(0, "meta:\n")
+	g.printf(1, "id: %s\n", identifier(n.Name()))
+	g.printf(1, "title: %s\n", n.Name())
+	g.printf(1, "endian: le\n")
+
+	if len(imports) > 0 {
+		names := make([]string, 0, len(imports))
+
+		for name := range imports {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+		g.printf(1, "imports:\n")
+
+		for _, name := range names {
+			g.printf(2, "- %s\n", name)
+		}
+	}
+
+	g.doc(0, n.Doc)
+	g.printf(0, "seq:\n")
+
+	for _, f := range n.Fields() {
+		inst, err := g.field(f)
+
+		if err != nil {
+			return err
+		}
+
+		if inst != nil {
+			instances = append(instances, *inst)
+		}
+	}
+
+	if len(instances) > 0 {
+		g.printf(0, "instances:\n")
+
+		for _, inst := range instances {
+			g.printf(1, "%s:\n", inst.id)
+			g.printf(2, "value: %s\n", inst.value)
+
+			if inst.enum != "" {
+				g.printf(2, "enum: %s\n", inst.enum)
+			}
+		}
+	}
+
+	if len(enums) > 0 {
+		g.printf(0, "enums:\n")
+
+		for _, enum := range enums {
+			if err := g.enum(enum); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// field writes the seq entry of f, returning the instance clearing its
+// protomask if any.
+func (g *generator) field(f *ast.PropertyNode) (*instance, error) {
+	var (
+		id   = identifier(f.Name())
+		inst *instance
+		doc  []string
+	)
+
+	for _, text := range []string{f.Doc, f.Comment} {
+		if text != "" {
+			doc = append(doc, text)
+		}
+	}
+
+	if f.Flags != ast.PropertyFlagNone {
+		doc = append(doc, f.Flags.String())
+	}
+
+	if reason, ok := backend.Deprecation(f); ok {
+		doc = append(doc, "Deprecated: "+reason)
+	}
+
+	if f.Flags == ast.PropertyFlagProtoMask || f.Flags == ast.PropertyFlagProtoMaskGC {
+		inst = &instance{id: id, value: fmt.Sprintf("%s_raw & %#x", id, ^uint32(ast.ProtoMask))}
+		id += "_raw"
+
+		if enum := enumType(f); enum != nil {
+			inst.enum = identifier(enum.Name())
+		}
+	}
+
+	g.printf(1, "- id: %s\n", id)
+
+	size, isArray, err := arraySize(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if isArray && f.Flags == ast.PropertyFlagNone && f.Type != nil && f.Type.Value == "byte" {
+		g.printf(2, "size: %s\n", size)
+	} else if isArray && f.Flags == ast.PropertyFlagNone && f.Type != nil && f.Type.Value == "string" {
+		// sizes count code units, and Kaitai sizes bytes
+		if f.Encoding == ast.EncodingUTF16 {
+			size += " * 2"
+		}
+
+		g.printf(2, "type: str\n")
+		g.printf(2, "encoding: %s\n", encoding(f))
+		g.printf(2, "size: %s\n", size)
+	} else if f.Termination == ast.TermNull {
+		// terminators are single bytes
+		if f.Encoding == ast.EncodingUTF16 {
+			return nil, fmt.Errorf("Cannot export %s, a null-terminated UTF-16 string", backend.QualifiedName(f))
+		}
+
+		g.printf(2, "type: strz\n")
+		g.printf(2, "encoding: UTF-8\n")
+	} else {
+		typ, err := g.fieldType(f)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// single bytes have no byte order
+		if f.Endian == ast.BigEndian && typ != "u1" && typ != "s1" {
+			typ += "be"
+		}
+
+		g.printf(2, "type: %s\n", typ)
+
+		if enum := enumType(f); enum != nil && inst == nil {
+			g.printf(2, "enum: %s\n", identifier(enum.Name()))
+		}
+
+		if isArray {
+			g.printf(2, "repeat: expr\n")
+			g.printf(2, "repeat-expr: %s\n", size)
+		}
+	}
+
+	// absent optional fields end the payload
+	if f.Optional {
+		g.printf(2, "if: not _io.eof\n")
+	}
+
+	if f.Condition != nil {
+		cond, err := condition(f)
+
+		if err != nil {
+			return nil, err
+		}
+
+		g.printf(2, "if: %s\n", cond)
+	}
+
+	if len(doc) > 0 {
+		g.printf(2, "doc: %s\n", quote(strings.Join(doc, "\n")))
+	}
+
+	return inst, nil
+}
+
+// encoding returns the Kaitai name of the encoding of the string field f.
+func encoding(f *ast.PropertyNode) string {
+	if f.Encoding != ast.EncodingUTF16 {
+		return "UTF-8"
+	}
+
+	if f.Endian == ast.BigEndian {
+		return "UTF-16BE"
+	}
+
+	return "UTF-16LE"
+}
+
+// condition returns the expression testing the flag a conditional field f
+// depends on.
+func condition(f *ast.PropertyNode) (string, error) {
+	member, ctrl := f.ConditionMember(), f.ConditionField()
+
+	if member == nil || ctrl == nil {
+		return "", fmt.Errorf("Cannot export %s, its condition is unresolved", backend.QualifiedName(f))
+	}
+
+	flag, err := backend.Value(member)
+
+	if err != nil {
+		return "", err
+	}
+
+	id := identifier(ctrl.Name())
+
+	// enum values have to be converted for bitwise operators
+	if enumType(ctrl) != nil {
+		id += ".to_i"
+	}
+
+	return fmt.Sprintf("(%s & %d) != 0", id, flag), nil
+}
+
+// fieldType returns the Kaitai type of f, or of its elements.
+func (g *generator) fieldType(f *ast.PropertyNode) (string, error) {
+	switch f.Flags {
+	case ast.PropertyFlagSteamIDMarshal, ast.PropertyFlagGameIDMarshal, ast.PropertyFlagProtoMask, ast.PropertyFlagProtoMaskGC:
+		return types[f.Flags.WireType()], nil
+	case ast.PropertyFlagBoolMarshal:
+		if f.Type != nil && types[f.Type.Value] != "" {
+			return types[f.Type.Value], nil
+		}
+
+		return types[f.Flags.WireType()], nil
+	case ast.PropertyFlagProto:
+		return "", fmt.Errorf("Cannot export proto field %s", backend.QualifiedName(f))
+	}
+
+	if f.Type == nil {
+		return types["int"], nil
+	}
+
+	switch n := f.Type.Node.(type) {
+	case *ast.EnumNode:
+		return types[n.Type.String()], nil
+	case *ast.ClassNode:
+		return identifier(n.Name()), nil
+	}
+
+	if t, ok := types[f.Type.Value]; ok {
+		return t, nil
+	}
+
+	return "", fmt.Errorf("Cannot export %s of type %s", backend.QualifiedName(f), f.Type.Value)
+}
+
+func (g *generator) enum(n *ast.EnumNode) error {
+	g.printf(1, "%s:\n", identifier(n.Name()))
+	seen := make(map[uint64]bool)
+
+	for _, m := range n.Members() {
+		if g.opts.SkipRemoved && m.Removed {
+			continue
+		}
+
+		v, err := backend.Value(m)
+
+		if err != nil {
+			return err
+		}
+
+		// Keys are the values as read, 0x80000000 being -2147483648 in an s4.
+		v = backend.StorageValue(v, n.Type)
+
+		// Keys are unique, so aliases of a previous value are left out.
+		if seen[v] {
+			continue
+		}
+
+		seen[v] = true
+		value := fmt.Sprintf("%d", v)
+
+		if n.Type.Signed() {
+			value = backend.FormatValue(v)
+		}
+
+		if m.Doc == "" {
+			g.printf(2, "%s: %s\n", value, identifier(m.Name()))
+			continue
+		}
+
+		g.printf(2, "%s:\n", value)
+		g.printf(3, "id: %s\n", identifier(m.Name()))
+		g.printf(3, "doc: %s\n", quote(m.Doc))
+	}
+
+	return nil
+}
+
+func (g *generator) doc(indent int, doc string) {
+	if doc != "" {
+		g.printf(indent, "doc: %s\n", quote(doc))
+	}
+}
+
+// quote renders s as a double-quoted YAML scalar, which shares the escapes of
+// JSON strings.
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func enumType(f *ast.PropertyNode) *ast.EnumNode {
+	if f.Type == nil || f.Flags == ast.PropertyFlagBoolMarshal {
+		return nil
+	}
+
+	enum, _ := f.Type.Node.(*ast.EnumNode)
+
+	return enum
+}
+
+func classType(f *ast.PropertyNode) *ast.ClassNode {
+	if f.Type == nil || f.Flags != ast.PropertyFlagNone {
+		return nil
+	}
+
+	class, _ := f.Type.Node.(*ast.ClassNode)
+
+	return class
+}
+
+// arraySize returns the number of elements of f, a number or the id of the
+// field giving it, and whether f is an array.
+func arraySize(f *ast.PropertyNode) (string, bool, error) {
+	size, ok, err := backend.ArraySize(f)
+
+	switch {
+	case !ok:
+		return "", false, nil
+	case size < 0:
+		return identifier(f.LengthField().Name()), true, nil
+	}
+
+	return strconv.Itoa(size), true, err
+}
+
+// identifier converts name to a Kaitai identifier, in snake case, like
+// "msg_client_logon" for "MsgClientLogon".
+func identifier(name string) string {
+	id := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+
+		return '_'
+	}, backend.SnakeCase(name))
+
+	if id == "" || !unicode.IsLetter(rune(id[0])) {
+		id = "v" + id
+	}
+
+	return id
+}
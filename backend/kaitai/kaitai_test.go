@@ -0,0 +1,97 @@
+package kaitai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/13k/go-steam-language/backend"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+const schema = `enum EMsg { Invalid = 0; Multi = 1; Alias = 1; };
+enum EOther<short> { X = -1; /// Seven.
+Y = 7; Old = 8; removed };
+/// Message header.
+class MsgHdr<EMsg::Multi> {
+	const uint SIZE = 4;
+	steamidmarshal ulong steamID;
+	boolmarshal byte valid = 1;
+	protomask EMsg msg = EMsg::Multi;
+	byte<20> key; // session key
+	EOther<MsgHdr::SIZE> others;
+};
+class MsgWrapped { MsgHdr header; MsgHdr<2> more; };
+`
+
+func generate(t *testing.T, opts backend.Options) []backend.OutputFile {
+	doc, err := parse.NewAnalyzer(token.NewTokenizer([]byte(schema)), "schemas/emsg.steamd").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	gen, ok := backend.Lookup("kaitai")
+
+	if !ok {
+		t.Fatalf("expected kaitai backend to be registered")
+	}
+
+	files, err := gen.Generate(doc, opts)
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	return files
+}
+
+func TestGenerate(t *testing.T) {
+	files := generate(t, backend.Options{})
+
+	if len(files) != 2 || files[0].Name != "msg_hdr.ksy" || files[1].Name != "msg_wrapped.ksy" {
+		t.Fatalf("mismatch: got %v, but expected msg_hdr.ksy and msg_wrapped.ksy", files)
+	}
+
+	src := string(files[0].Content)
+
+	expected := []string{
+		"meta:\n  id: msg_hdr\n  title: MsgHdr\n  endian: le\n",
+		"doc: \"Message header.\"\nseq:\n",
+		"  - id: steam_id\n    type: u8\n    doc: \"steamidmarshal\"\n",
+		"  - id: valid\n    type: u1\n",
+		"  - id: msg_raw\n    type: u4\n",
+		"  - id: key\n    size: 20\n    doc: \"session key\"\n",
+		"  - id: others\n    type: s2\n    enum: e_other\n    repeat: expr\n    repeat-expr: 4\n",
+		"instances:\n  msg:\n    value: msg_raw & 0x7fffffff\n    enum: e_msg\n",
+		"  e_msg:\n    0: invalid\n    1: multi\n  e_other:\n    -1: x\n    7:\n      id: y\n      doc: \"Seven.\"\n    8: old\n",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(src, s) {
+			t.Fatalf("expected generated spec to contain %q\n%s", s, src)
+		}
+	}
+
+	src = string(files[1].Content)
+
+	expected = []string{
+		"  imports:\n    - msg_hdr\n",
+		"  - id: header\n    type: msg_hdr\n",
+		"  - id: more\n    type: msg_hdr\n    repeat: expr\n    repeat-expr: 2\n",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(src, s) {
+			t.Fatalf("expected generated spec to contain %q\n%s", s, src)
+		}
+	}
+}
+
+func TestGenerateExclude(t *testing.T) {
+	files := generate(t, backend.Options{SkipRemoved: true, Exclude: map[string]bool{"MsgHdr": true}})
+
+	if len(files) != 1 || files[0].Name != "msg_wrapped.ksy" {
+		t.Fatalf("mismatch: got %v, but expected msg_wrapped.ksy only", files)
+	}
+}
@@ -0,0 +1,391 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/13k/go-steam-language/ast"
+)
+
+// Nodes returns the top-level declarations of doc that aren't excluded by
+// opts.
+func Nodes(doc *ast.DocumentNode, opts Options) []ast.Node {
+	var nodes []ast.Node
+
+	for _, child := range doc.Types() {
+		if !opts.Exclude[child.Name()] {
+			nodes = append(nodes, child)
+		}
+	}
+
+	return nodes
+}
+
+// QualifiedName returns the name of n within its document, like
+// "EResult::OK".
+func QualifiedName(n ast.Node) string {
+	return strings.Join(n.NamePath()[1:], "::")
+}
+
+// IsRemoved reports whether n is a property or enum member marked removed.
+func IsRemoved(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.PropertyNode:
+		return n.Removed
+	case *ast.EnumMemberNode:
+		return n.Removed
+	}
+
+	return false
+}
+
+// Deprecation returns why n, a property or enum member, is deprecated, like
+// "removed: merged into Timeout; not used anymore", and whether it is. Reasons
+// spanning lines are joined into one, to fit in line comments.
+func Deprecation(n ast.Node) (string, bool) {
+	var (
+		obsolete, removed             bool
+		obsoleteReason, removedReason string
+	)
+
+	switch n := n.(type) {
+	case *ast.PropertyNode:
+		obsolete, obsoleteReason, removed, removedReason = n.Obsolete, n.ObsoleteReason, n.Removed, n.RemovedReason
+	case *ast.EnumMemberNode:
+		obsolete, obsoleteReason, removed, removedReason = n.Obsolete, n.ObsoleteReason, n.Removed, n.RemovedReason
+	}
+
+	var reasons []string
+
+	if removed {
+		if removedReason != "" {
+			removedReason = "removed: " + removedReason
+		} else {
+			removedReason = "removed"
+		}
+
+		reasons = append(reasons, removedReason)
+	}
+
+	if obsolete {
+		if obsoleteReason == "" {
+			obsoleteReason = "obsolete"
+		}
+
+		reasons = append(reasons, obsoleteReason)
+	}
+
+	return strings.Replace(strings.Join(reasons, "; "), "\n", " ", -1), len(reasons) > 0
+}
+
+// SnakeCase converts name to snake case, like "steam_id" for "steamID" and
+// "msg_client_logon" for "MsgClientLogon".
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteRune('_')
+			}
+		}
+
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
+
+// ArraySize returns the number of elements of f, -1 if another field gives it,
+// and whether f is an array.
+func ArraySize(f *ast.PropertyNode) (int, bool, error) {
+	q := f.Qualifier
+
+	if q == nil || q.Kind != ast.QualifierSize {
+		return 0, false, nil
+	}
+
+	if f.LengthField() != nil {
+		return -1, true, nil
+	}
+
+	size, err := q.Size()
+
+	return size, true, err
+}
+
+// FixedArraySize is ArraySize for languages generating fixed size arrays only.
+// Arrays of variable length are an error.
+func FixedArraySize(f *ast.PropertyNode) (int, bool, error) {
+	size, ok, err := ArraySize(f)
+
+	if ok && size < 0 {
+		return 0, true, fmt.Errorf("Cannot generate %s of variable length", QualifiedName(f))
+	}
+
+	return size, ok, err
+}
+
+// Value evaluates the declaration of n, a property or enum member, and
+// returns the value of n. Default values of fields, which ast.Evaluate leaves
+// alone, are computed from the values they reference. Defaults referencing
+// each other in a cycle are an error.
+func Value(n ast.Node) (uint64, error) {
+	return value(n, make(map[ast.Node]bool))
+}
+
+// value evaluates n, where visiting holds the field defaults being evaluated.
+func value(n ast.Node, visiting map[ast.Node]bool) (uint64, error) {
+	if err := ast.Evaluate(n.Parent()); err != nil {
+		return 0, err
+	}
+
+	var (
+		v  uint64
+		ok bool
+	)
+
+	switch n := n.(type) {
+	case *ast.PropertyNode:
+		if !n.IsConst() && ast.ValueExpr(n) != nil {
+			if visiting[n] {
+				return 0, fmt.Errorf("Circular value of %s", QualifiedName(n))
+			}
+
+			visiting[n] = true
+			defer delete(visiting, n)
+
+			return evalExpr(n, ast.ValueExpr(n), visiting)
+		}
+
+		v, ok = n.ResolvedValue()
+	case *ast.EnumMemberNode:
+		v, ok = n.ResolvedValue()
+	}
+
+	if !ok {
+		return 0, fmt.Errorf("Cannot resolve value of %s", QualifiedName(n))
+	}
+
+	return v, nil
+}
+
+func evalExpr(n ast.Node, expr ast.Expr, visiting map[ast.Node]bool) (uint64, error) {
+	switch x := expr.(type) {
+	case *ast.LiteralExpr:
+		return x.Value, nil
+	case *ast.ParenExpr:
+		return evalExpr(n, x.X, visiting)
+	case *ast.SymbolExpr:
+		if x.Symbol != nil && x.Symbol.Node != n {
+			switch ref := x.Symbol.Node.(type) {
+			case *ast.PropertyNode, *ast.EnumMemberNode:
+				return value(ref, visiting)
+			}
+		}
+
+		return 0, fmt.Errorf("Cannot resolve %q in value of %s", x.String(), QualifiedName(n))
+	case *ast.BinaryExpr:
+		lhs, err := evalExpr(n, x.X, visiting)
+
+		if err != nil {
+			return 0, err
+		}
+
+		rhs, err := evalExpr(n, x.Y, visiting)
+
+		if err != nil {
+			return 0, err
+		}
+
+		switch x.Op {
+		case ast.BinaryOr:
+			return lhs | rhs, nil
+		case ast.BinaryAdd:
+			return lhs + rhs, nil
+		case ast.BinaryShl:
+			return lhs << rhs, nil
+		case ast.BinaryShr:
+			return lhs >> rhs, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Cannot resolve value of %s", QualifiedName(n))
+}
+
+// StorageValue reduces v, as produced by Value, to the width of t, sign
+// extended for signed types, so that 0x80000000 is -2147483648 in an int.
+func StorageValue(v uint64, t ast.StorageType) uint64 {
+	bits := 8 * uint(t.Size())
+
+	if bits == 64 {
+		return v
+	}
+
+	v &= 1<<bits - 1
+
+	if t.Signed() && v&(1<<(bits-1)) != 0 {
+		v |= ^uint64(0) << bits
+	}
+
+	return v
+}
+
+// FormatStorageValue renders v reduced to t in decimal, negative values of
+// signed types included.
+func FormatStorageValue(v uint64, t ast.StorageType) string {
+	v = StorageValue(v, t)
+
+	if t.Signed() {
+		return FormatValue(v)
+	}
+
+	return fmt.Sprintf("%d", v)
+}
+
+// ReducedValue returns the value of m in decimal, and true, when it differs
+// from the value reduced to the storage type of its enum, like 0x80000000 in
+// an int enum. Expressions of such values don't compile in languages checking
+// the range of constants, which get the reduced value instead.
+func ReducedValue(m *ast.EnumMemberNode) (string, bool, error) {
+	enum, ok := m.Parent().(*ast.EnumNode)
+
+	if !ok {
+		return "", false, nil
+	}
+
+	v, err := Value(m)
+
+	if err != nil || StorageValue(v, enum.Type) == v {
+		return "", false, err
+	}
+
+	return FormatStorageValue(v, enum.Type), true, nil
+}
+
+// FormatNodeValue renders the value of n in decimal, reduced to the storage
+// type of its enum if n is a member.
+func FormatNodeValue(n ast.Node) (string, error) {
+	v, err := Value(n)
+
+	if err != nil {
+		return "", err
+	}
+
+	if enum, ok := n.Parent().(*ast.EnumNode); ok {
+		return FormatStorageValue(v, enum.Type), nil
+	}
+
+	return FormatValue(v), nil
+}
+
+// FormatLiteral renders x as written, except for the limits of storage types,
+// like ulong.MaxValue, which are rendered in decimal.
+func FormatLiteral(x *ast.LiteralExpr) string {
+	if _, ok := ast.ParseLimit(x.Raw); !ok {
+		return x.Raw
+	}
+
+	if strings.HasSuffix(x.Raw, ".MinValue") {
+		return FormatValue(x.Value)
+	}
+
+	return fmt.Sprintf("%d", x.Value)
+}
+
+// FormatValue renders v, as produced by Value, in decimal. Values of 1<<63 and
+// above are two's complement negative values.
+func FormatValue(v uint64) string {
+	if v >= 1<<63 {
+		return fmt.Sprintf("%d", int64(v))
+	}
+
+	return fmt.Sprintf("%d", v)
+}
+
+// FormatExpr renders the value expression of n, a property or enum member, for
+// languages sharing the operator precedence of steamd: `+` binds tighter than
+// shifts, which bind tighter than `|`. ref renders the properties and enum
+// members referenced.
+func FormatExpr(n ast.Node, ref func(ast.Node) (string, error)) (string, error) {
+	return formatExpr(n, ast.ValueExpr(n), ref)
+}
+
+func formatExpr(n ast.Node, expr ast.Expr, ref func(ast.Node) (string, error)) (string, error) {
+	switch x := expr.(type) {
+	case *ast.LiteralExpr:
+		return FormatLiteral(x), nil
+	case *ast.ParenExpr:
+		inner, err := formatExpr(n, x.X, ref)
+		return "(" + inner + ")", err
+	case *ast.SymbolExpr:
+		var target ast.Node
+
+		if x.Symbol != nil {
+			switch sym := x.Symbol.Node.(type) {
+			case *ast.PropertyNode, *ast.EnumMemberNode:
+				target = sym
+			}
+		}
+
+		if target == nil || target == n {
+			return "", fmt.Errorf("Cannot resolve %q in value of %s", x.String(), QualifiedName(n))
+		}
+
+		return ref(target)
+	case *ast.BinaryExpr:
+		lhs, err := formatOperand(n, x, x.X, false, ref)
+
+		if err != nil {
+			return "", err
+		}
+
+		rhs, err := formatOperand(n, x, x.Y, true, ref)
+
+		if err != nil {
+			return "", err
+		}
+
+		return lhs + " " + x.Op.String() + " " + rhs, nil
+	case nil:
+		return "", fmt.Errorf("Missing value of %s", QualifiedName(n))
+	default:
+		return "", fmt.Errorf("Cannot generate %v in value of %s", expr, QualifiedName(n))
+	}
+}
+
+// formatOperand parenthesizes operands whose grouping would otherwise change,
+// like the ones built by ast.ValueExpr.
+func formatOperand(n ast.Node, parent *ast.BinaryExpr, operand ast.Expr, right bool, ref func(ast.Node) (string, error)) (string, error) {
+	s, err := formatExpr(n, operand, ref)
+
+	if err != nil {
+		return "", err
+	}
+
+	if x, ok := operand.(*ast.BinaryExpr); ok {
+		p, q := precedence(x.Op), precedence(parent.Op)
+
+		if p < q || (p == q && right) {
+			s = "(" + s + ")"
+		}
+	}
+
+	return s, nil
+}
+
+func precedence(op ast.BinaryOp) int {
+	switch op {
+	case ast.BinaryOr:
+		return 1
+	case ast.BinaryShl, ast.BinaryShr:
+		return 2
+	default:
+		return 3
+	}
+}
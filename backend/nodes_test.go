@@ -0,0 +1,50 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+func TestValueCircularDefault(t *testing.T) {
+	testCases := []struct {
+		src      string
+		expected uint64
+		err      bool
+	}{
+		{"class C { uint a = b; uint b = a; };", 0, true},
+		{"class C { uint a = b; uint b = c; uint c = a; };", 0, true},
+		{"enum E { A = 1; }; class C { E a = b; E b = a; };", 0, true},
+		{"enum E { A = 1; }; class C { E a = b; E b = E::A; };", 1, false},
+		{"class C { uint a = b | c; uint b = c; uint c = 2; };", 2, false},
+		{"class C { uint a = D::x; }; class D { uint x = C::a; };", 0, true},
+	}
+
+	for _, tc := range testCases {
+		doc, err := parse.NewAnalyzer(token.NewTokenizer([]byte(tc.src)), "").Analyze()
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		v, err := Value(doc.Classes()[0].Fields()[0])
+
+		if tc.err {
+			if err == nil || !strings.Contains(err.Error(), "Circular value") {
+				t.Fatalf("mismatch: got %v, but expected circular value error for %q", err, tc.src)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		if v != tc.expected {
+			t.Fatalf("mismatch: got %d, but expected %d", v, tc.expected)
+		}
+	}
+}
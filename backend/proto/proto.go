@@ -0,0 +1,386 @@
+// Package proto is the "proto" backend, exporting a proto3 file per document
+// with a message per class and an enum per enum, to help migrating messages
+// defined in steamd to protobuf.
+//
+// Enum values follow Steam's naming, like k_EResultOK, and enums not starting
+// at zero get a k_<Enum>_Unspecified value. Fields are numbered in declaration
+// order. Constructs without a proto3 equivalent are flagged with a "steamd:"
+// comment quoting the original declaration: fixed size arrays, fields of flags
+// enums, narrow integers widened to 32 bits, marshaled fields, defaults and
+// constants. Values that don't fit the int32 range of proto3 enums are left
+// out, in a comment. Files are self-contained, so imported declarations are
+// exported in each of them.
+package proto
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/backend"
+)
+
+const header = "// Code generated by go-steam-language. DO NOT EDIT.\n"
+
+// types are the proto3 scalar types of the builtin types. Types narrower than
+// 32 bits are widened.
+var types = map[string]string{
+	"byte":   "uint32",
+	"sbyte":  "int32",
+	"short":  "int32",
+	"ushort": "uint32",
+	"int":    "int32",
+	"uint":   "uint32",
+	"long":   "int64",
+	"ulong":  "uint64",
+	"float":  "float",
+	"double": "double",
+	"bool":   "bool",
+	"char":   "uint32",
+	"string": "string",
+}
+
+var narrowTypes = map[string]bool{
+	"byte":   true,
+	"sbyte":  true,
+	"short":  true,
+	"ushort": true,
+	"char":   true,
+}
+
+func init() {
+	backend.Register(protoBackend{})
+}
+
+type protoBackend struct{}
+
+func (protoBackend) Name() string {
+	return "proto"
+}
+
+func (protoBackend) Generate(doc *ast.DocumentNode, opts backend.Options) ([]backend.OutputFile, error) {
+	g := &generator{opts: opts}
+
+	g.buf.WriteString(header)
+	g.printf(0, "syntax = \"proto3\";\n")
+
+	if opts.Package != "" {
+		g.printf(0, "\npackage %s;\n", opts.Package)
+	}
+
+	for _, child := range doc.Types() {
+		var err error
+
+		switch n := child.(type) {
+		case *ast.EnumNode:
+			g.buf.WriteString("\n")
+			err = g.enum(n)
+		case *ast.ClassNode:
+			g.buf.WriteString("\n")
+			err = g.class(n)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return []backend.OutputFile{{Name: backend.OutputName(doc, ".proto"), Content: g.buf.Bytes()}}, nil
+}
+
+type generator struct {
+	buf  bytes.Buffer
+	opts backend.Options
+}
+
+func (g *generator) printf(indent int, format string, args ...interface{}) {
+	g.buf.WriteString(strings.Repeat("  ", indent))
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) enum(n *ast.EnumNode) error {
+	type value struct {
+		member *ast.EnumMemberNode
+		value  int64
+		fits   bool
+	}
+
+	var (
+		values []value
+		alias  bool
+		zero   bool
+	)
+
+	seen := make(map[int64]bool)
+
+	for _, m := range n.Members() {
+		if g.opts.SkipRemoved && m.Removed {
+			continue
+		}
+
+		v, err := backend.Value(m)
+
+		if err != nil {
+			return err
+		}
+
+		v = backend.StorageValue(v, n.Type)
+		x := int64(v)
+		fits := x >= math.MinInt32 && x <= math.MaxInt32
+
+		if !n.Type.Signed() {
+			fits = v <= math.MaxInt32
+		}
+
+		if fits {
+			alias = alias || seen[x]
+			zero = zero || x == 0
+			seen[x] = true
+		}
+
+		values = append(values, value{member: m, value: x, fits: fits})
+	}
+
+	g.comments(0, n.Doc)
+
+	if n.Flags {
+		g.printf(0, "// steamd: flags enum, values combine members and fields are integers\n")
+	}
+
+	g.printf(0, "enum %s {\n", n.Name())
+
+	if alias {
+		g.printf(1, "option allow_alias = true;\n")
+	}
+
+	if !zero {
+		g.printf(1, "%s = 0; // steamd: added, proto3 enums start at zero\n", valueName(n, "_Unspecified"))
+	}
+
+	for _, v := range values {
+		m := v.member
+		name := valueName(n, m.Name())
+
+		if !v.fits {
+			g.printf(1, "// steamd: %s = %s doesn't fit in int32\n", name, formatValue(uint64(v.value), n.Type.Signed()))
+			continue
+		}
+
+		g.comments(1, m.Doc)
+		g.printf(1, "%s = %d%s;%s\n", name, v.value, deprecated(m), lineComment(m.Comment))
+	}
+
+	g.printf(0, "}\n")
+
+	return nil
+}
+
+func (g *generator) class(n *ast.ClassNode) error {
+	g.comments(0, n.Doc)
+
+	if q := n.Qualifier; q != nil && q.Kind == ast.QualifierEMsg && q.Symbol != nil && q.Symbol.Node != nil {
+		g.printf(0, "// steamd: sent as %s\n", backend.QualifiedName(q.Symbol.Node))
+	}
+
+	g.printf(0, "message %s {\n", n.Name())
+
+	for _, c := range n.Consts() {
+		if g.opts.SkipRemoved && c.Removed {
+			continue
+		}
+
+		g.printf(1, "// steamd: %s\n", declaration(c))
+	}
+
+	var reserved []string
+
+	for i, f := range n.Fields() {
+		number := i + 1
+
+		if g.opts.SkipRemoved && f.Removed {
+			reserved = append(reserved, fmt.Sprintf("%d", number))
+			continue
+		}
+
+		typ, lossy, err := g.fieldType(f)
+
+		if err != nil {
+			return err
+		}
+
+		// proto3 tracks the presence of singular fields marked optional
+		if (f.Optional || f.Condition != nil) && !strings.HasPrefix(typ, "repeated ") {
+			typ = "optional " + typ
+		}
+
+		comment := f.Comment
+
+		if lossy || f.Flags != ast.PropertyFlagNone || len(f.Default) > 0 || f.Expr != nil || f.Condition != nil || f.Endian != ast.LittleEndian ||
+			f.Encoding != ast.EncodingUTF8 || f.Termination != ast.TermLength {
+			comment = strings.TrimSpace("steamd: " + declaration(f) + " " + comment)
+		}
+
+		g.comments(1, f.Doc)
+		g.printf(1, "%s %s = %d%s;%s\n", typ, backend.SnakeCase(f.Name()), number, deprecated(f), lineComment(comment))
+	}
+
+	if len(reserved) > 0 {
+		g.printf(1, "reserved %s;\n", strings.Join(reserved, ", "))
+	}
+
+	g.printf(0, "}\n")
+
+	return nil
+}
+
+// fieldType returns the proto3 type of f, and whether it loses information
+// about the steamd type.
+func (g *generator) fieldType(f *ast.PropertyNode) (string, bool, error) {
+	var (
+		typ   string
+		lossy bool
+	)
+
+	switch f.Flags {
+	case ast.PropertyFlagSteamIDMarshal, ast.PropertyFlagGameIDMarshal:
+		typ = "fixed64"
+	case ast.PropertyFlagBoolMarshal:
+		typ = "bool"
+	case ast.PropertyFlagProto:
+		return "", false, fmt.Errorf("Cannot export proto field %s", backend.QualifiedName(f))
+	}
+
+	_, isArray, err := backend.ArraySize(f)
+
+	if err != nil {
+		return "", false, err
+	}
+
+	if typ == "" {
+		switch {
+		case f.Type == nil:
+			typ = types["int"]
+		case isArray && f.Type.Value == "byte":
+			return "bytes", true, nil
+		case isArray && f.Type.Value == "string" && f.LengthField() != nil:
+			return "string", true, nil
+		default:
+			switch n := f.Type.Node.(type) {
+			case *ast.EnumNode:
+				typ = n.Name()
+
+				if n.Flags {
+					typ, lossy = types[n.Type.String()], true
+				}
+			case *ast.ClassNode:
+				typ = n.Name()
+			default:
+				typ = types[f.Type.Value]
+				lossy = narrowTypes[f.Type.Value]
+			}
+		}
+	}
+
+	if typ == "" {
+		return "", false, fmt.Errorf("Cannot export %s of type %s", backend.QualifiedName(f), f.Type.Value)
+	}
+
+	if isArray {
+		return "repeated " + typ, true, nil
+	}
+
+	return typ, lossy, nil
+}
+
+// declaration renders the steamd declaration of p, without attributes.
+func declaration(p *ast.PropertyNode) string {
+	var parts []string
+
+	if p.Optional {
+		parts = append(parts, "optional")
+	}
+
+	if p.Flags != ast.PropertyFlagNone {
+		parts = append(parts, p.Flags.String())
+	}
+
+	if p.Type != nil {
+		typ := p.Type.Value
+
+		if q := p.Qualifier; q != nil && q.Kind == ast.QualifierSize {
+			if size, err := q.Size(); err == nil {
+				typ += fmt.Sprintf("<%d>", size)
+			} else if p.LengthField() != nil {
+				typ += "<" + strings.Join(q.Path, "::") + ">"
+			}
+		}
+
+		parts = append(parts, typ)
+	}
+
+	parts = append(parts, p.Name())
+
+	if p.Condition != nil {
+		parts = append(parts, "if:"+strings.Join(p.ConditionPath, "::"))
+	}
+
+	if p.Endian != ast.LittleEndian {
+		parts = append(parts, "endian:"+p.Endian.String())
+	}
+
+	if p.Encoding != ast.EncodingUTF8 {
+		parts = append(parts, "encoding:"+p.Encoding.String())
+	}
+
+	if p.Termination != ast.TermLength {
+		parts = append(parts, "term:"+p.Termination.String())
+	}
+
+	if expr := ast.ValueExpr(p); expr != nil && (len(p.Default) > 0 || p.Expr != nil) {
+		parts = append(parts, "=", expr.String())
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func deprecated(n ast.Node) string {
+	if _, ok := backend.Deprecation(n); ok {
+		return " [deprecated = true]"
+	}
+
+	return ""
+}
+
+func (g *generator) comments(indent int, doc string) {
+	if doc == "" {
+		return
+	}
+
+	for _, line := range strings.Split(doc, "\n") {
+		g.printf(indent, "// %s\n", line)
+	}
+}
+
+func lineComment(comment string) string {
+	if comment == "" {
+		return ""
+	}
+
+	return " // " + comment
+}
+
+// valueName returns the name of a value of enum, prefixed as in Steam's
+// protobufs since values share the scope of their enum.
+func valueName(enum *ast.EnumNode, name string) string {
+	return "k_" + enum.Name() + name
+}
+
+func formatValue(v uint64, signed bool) string {
+	if signed {
+		return backend.FormatValue(v)
+	}
+
+	return fmt.Sprintf("%d", v)
+}
@@ -0,0 +1,93 @@
+package proto
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/13k/go-steam-language/backend"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+const schema = `/// Message types.
+enum EMsg { Invalid = 0; Multi = 1; Alias = 1; };
+enum EFlags<byte> flags { A = 1; B = 2; };
+enum EOther<uint> { X = 5; Big = 0x80000000; Old = 8; removed };
+class MsgHdr<EMsg::Multi> {
+	const uint SIZE = 4;
+	steamidmarshal ulong steamID;
+	boolmarshal byte valid = 1;
+	protomask EMsg msg = EMsg::Multi;
+	EFlags flags;
+	byte<20> key;
+	EOther<MsgHdr::SIZE> others;
+	short old; removed
+	uint count; // items
+	optional ulong jobID;
+};
+`
+
+func generate(t *testing.T, opts backend.Options) string {
+	doc, err := parse.NewAnalyzer(token.NewTokenizer([]byte(schema)), "schemas/emsg.steamd").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	gen, ok := backend.Lookup("proto")
+
+	if !ok {
+		t.Fatalf("expected proto backend to be registered")
+	}
+
+	files, err := gen.Generate(doc, opts)
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if len(files) != 1 || files[0].Name != "emsg.proto" {
+		t.Fatalf("mismatch: got %v, but expected emsg.proto only", files)
+	}
+
+	return string(files[0].Content)
+}
+
+func TestGenerate(t *testing.T) {
+	src := generate(t, backend.Options{Package: "steam.legacy"})
+
+	expected := []string{
+		"syntax = \"proto3\";\n\npackage steam.legacy;\n",
+		"// Message types.\nenum EMsg {\n  option allow_alias = true;\n  k_EMsgInvalid = 0;\n  k_EMsgMulti = 1;\n  k_EMsgAlias = 1;\n}",
+		"// steamd: flags enum, values combine members and fields are integers\nenum EFlags {\n  k_EFlags_Unspecified = 0;",
+		"k_EOtherX = 5;\n  // steamd: k_EOtherBig = 2147483648 doesn't fit in int32\n  k_EOtherOld = 8 [deprecated = true];",
+		"// steamd: sent as EMsg::Multi\nmessage MsgHdr {\n  // steamd: const uint SIZE = 4\n",
+		"fixed64 steam_id = 1; // steamd: steamidmarshal ulong steamID\n",
+		"bool valid = 2; // steamd: boolmarshal byte valid = 1\n",
+		"EMsg msg = 3; // steamd: protomask EMsg msg = EMsg::Multi\n",
+		"uint32 flags = 4; // steamd: EFlags flags\n",
+		"bytes key = 5; // steamd: byte<20> key\n",
+		"repeated EOther others = 6; // steamd: EOther<4> others\n",
+		"int32 old = 7 [deprecated = true]; // steamd: short old\n",
+		"uint32 count = 8; // items\n",
+		"optional uint64 job_id = 9;\n",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(src, s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+}
+
+func TestGenerateSkipRemoved(t *testing.T) {
+	src := generate(t, backend.Options{SkipRemoved: true})
+
+	if strings.Contains(src, "old") || strings.Contains(src, "package") {
+		t.Fatalf("expected removed declarations and package to be skipped\n%s", src)
+	}
+
+	if !strings.Contains(src, "optional uint64 job_id = 9;\n  reserved 7;\n}") {
+		t.Fatalf("expected field numbers of removed fields to be reserved\n%s", src)
+	}
+}
@@ -0,0 +1,573 @@
+// Package python is the "python" backend, generating a Python module per
+// document with IntEnum and IntFlag enums and dataclasses serialized with the
+// struct module, little-endian and without padding.
+//
+// Modules are self-contained, so imported declarations are generated in each
+// of them. Enum fields decode to the enum's members, and to plain ints for
+// values it doesn't declare.
+package python
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/backend"
+)
+
+const header = `# Code generated by go-steam-language. DO NOT EDIT.
+
+from __future__ import annotations
+
+import enum
+import struct
+from dataclasses import dataclass, field
+
+
+def _enum(cls, value):
+    try:
+        return cls(value)
+    except ValueError:
+        return value
+`
+
+// formats are the struct formats of the builtin types.
+var formats = map[string]string{
+	"byte":   "B",
+	"sbyte":  "b",
+	"short":  "h",
+	"ushort": "H",
+	"int":    "i",
+	"uint":   "I",
+	"long":   "q",
+	"ulong":  "Q",
+	"float":  "f",
+	"double": "d",
+	"bool":   "?",
+}
+
+var keywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true,
+	"assert": true, "async": true, "await": true, "break": true, "class": true,
+	"continue": true, "def": true, "del": true, "elif": true, "else": true,
+	"except": true, "finally": true, "for": true, "from": true, "global": true,
+	"if": true, "import": true, "in": true, "is": true, "lambda": true,
+	"nonlocal": true, "not": true, "or": true, "pass": true, "raise": true,
+	"return": true, "try": true, "while": true, "with": true, "yield": true,
+}
+
+func init() {
+	backend.Register(pythonBackend{})
+}
+
+type pythonBackend struct{}
+
+func (pythonBackend) Name() string {
+	return "python"
+}
+
+func (pythonBackend) Generate(doc *ast.DocumentNode, opts backend.Options) ([]backend.OutputFile, error) {
+	g := &generator{opts: opts}
+	g.buf.WriteString(header)
+
+	for _, child := range doc.Types() {
+		var err error
+
+		switch n := child.(type) {
+		case *ast.EnumNode:
+			g.buf.WriteString("\n\n")
+			err = g.enum(n)
+		case *ast.ClassNode:
+			g.buf.WriteString("\n\n")
+			err = g.class(n)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return []backend.OutputFile{{Name: backend.OutputName(doc, ".py"), Content: g.buf.Bytes()}}, nil
+}
+
+type generator struct {
+	buf  bytes.Buffer
+	opts backend.Options
+}
+
+func (g *generator) printf(indent int, format string, args ...interface{}) {
+	g.buf.WriteString(strings.Repeat("    ", indent))
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) enum(n *ast.EnumNode) error {
+	base := "enum.IntEnum"
+
+	if n.Flags {
+		base = "enum.IntFlag"
+	}
+
+	g.printf(0, "class %s(%s):\n", n.Name(), base)
+	g.docstring(1, n.Doc)
+
+	members := 0
+
+	for _, m := range n.Members() {
+		if g.opts.SkipRemoved && m.Removed {
+			continue
+		}
+
+		// Values out of the range of the storage type fail to pack.
+		value, reduced, err := backend.ReducedValue(m)
+
+		if err == nil && !reduced {
+			value, err = backend.FormatExpr(m, func(ref ast.Node) (string, error) {
+				if ref.Parent() == n && !(g.opts.SkipRemoved && backend.IsRemoved(ref)) {
+					return identifier(ref.Name()), nil
+				}
+
+				return g.ref(ref, true)
+			})
+		}
+
+		if err != nil {
+			return err
+		}
+
+		g.comments(1, m)
+		g.printf(1, "%s = %s%s\n", identifier(m.Name()), value, lineComment(m.Comment))
+		members++
+	}
+
+	if members == 0 && n.Doc == "" {
+		g.printf(1, "pass\n")
+	}
+
+	return nil
+}
+
+// ref renders a reference to a property or enum member from outside of its
+// declaration. In enum bodies, members of other enums are referenced by value.
+func (g *generator) ref(ref ast.Node, value bool) (string, error) {
+	if g.opts.SkipRemoved && backend.IsRemoved(ref) {
+		return backend.FormatNodeValue(ref)
+	}
+
+	name := ref.Parent().Name() + "." + identifier(ref.Name())
+
+	if _, ok := ref.(*ast.EnumMemberNode); ok && value {
+		name += ".value"
+	}
+
+	return name, nil
+}
+
+func (g *generator) class(n *ast.ClassNode) error {
+	g.printf(0, "@dataclass\n")
+	g.printf(0, "class %s:\n", n.Name())
+	g.docstring(1, n.Doc)
+
+	if q := n.Qualifier; q != nil && q.Kind == ast.QualifierEMsg && q.Symbol != nil {
+		if member, ok := q.Symbol.Node.(*ast.EnumMemberNode); ok {
+			value, err := g.ref(member, false)
+
+			if err != nil {
+				return err
+			}
+
+			enum := member.Parent().Name()
+
+			if g.opts.SkipRemoved && member.Removed {
+				value = fmt.Sprintf("%s(%s)", enum, value)
+			}
+
+			g.printf(1, "@staticmethod\n")
+			g.printf(1, "def emsg() -> %s:\n", enum)
+			g.printf(2, "return %s\n\n", value)
+		}
+	}
+
+	for _, c := range n.Consts() {
+		if g.opts.SkipRemoved && c.Removed {
+			continue
+		}
+
+		value, err := g.propertyValue(n, c)
+
+		if err != nil {
+			return err
+		}
+
+		g.comments(1, c)
+		g.printf(1, "%s = %s%s\n", identifier(c.Name()), value, lineComment(c.Comment))
+	}
+
+	for _, f := range n.Fields() {
+		value, err := g.fieldDefault(n, f)
+
+		if err != nil {
+			return err
+		}
+
+		g.comments(1, f)
+		g.printf(1, "%s: %s = %s%s\n", fieldName(f), g.fieldType(f), value, lineComment(f.Comment))
+	}
+
+	return g.serializers(n)
+}
+
+// propertyValue renders the value of a constant or field default. Constants of
+// n are referenced by name since the class isn't defined yet.
+func (g *generator) propertyValue(n *ast.ClassNode, p *ast.PropertyNode) (string, error) {
+	value, err := backend.FormatExpr(p, func(ref ast.Node) (string, error) {
+		if ref.Parent() == n && !(g.opts.SkipRemoved && backend.IsRemoved(ref)) {
+			return identifier(ref.Name()), nil
+		}
+
+		return g.ref(ref, false)
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if p.Flags == ast.PropertyFlagBoolMarshal {
+		if lit, ok := ast.ValueExpr(p).(*ast.LiteralExpr); ok {
+			if lit.Value != 0 {
+				return "True", nil
+			}
+
+			return "False", nil
+		}
+
+		return "bool(" + value + ")", nil
+	}
+
+	return value, nil
+}
+
+func (g *generator) fieldDefault(n *ast.ClassNode, f *ast.PropertyNode) (string, error) {
+	if len(f.Default) > 0 || f.Expr != nil {
+		return g.propertyValue(n, f)
+	}
+
+	if size, ok, err := backend.FixedArraySize(f); ok {
+		if err != nil {
+			return "", err
+		}
+
+		if isBytes(f) {
+			return fmt.Sprintf("field(default_factory=lambda: bytes(%d))", size), nil
+		}
+
+		return fmt.Sprintf("field(default_factory=lambda: [%s] * %d)", zeroValue(f), size), nil
+	}
+
+	if class := classType(f); class != nil {
+		return fmt.Sprintf("field(default_factory=%s)", class.Name()), nil
+	}
+
+	return zeroValue(f), nil
+}
+
+func zeroValue(f *ast.PropertyNode) string {
+	switch {
+	case f.Flags == ast.PropertyFlagBoolMarshal:
+		return "False"
+	case f.Type != nil && (f.Type.Value == "float" || f.Type.Value == "double"):
+		return "0.0"
+	case f.Type != nil && f.Type.Value == "bool":
+		return "False"
+	}
+
+	return "0"
+}
+
+func (g *generator) fieldType(f *ast.PropertyNode) string {
+	elem := g.elemType(f)
+
+	if isBytes(f) {
+		return "bytes"
+	}
+
+	if _, ok, _ := backend.FixedArraySize(f); ok {
+		return "list[" + elem + "]"
+	}
+
+	return elem
+}
+
+func (g *generator) elemType(f *ast.PropertyNode) string {
+	switch f.Flags {
+	case ast.PropertyFlagBoolMarshal:
+		return "bool"
+	case ast.PropertyFlagSteamIDMarshal, ast.PropertyFlagGameIDMarshal:
+		return "int"
+	}
+
+	if f.Type == nil {
+		return "int"
+	}
+
+	switch f.Type.Node.(type) {
+	case *ast.EnumNode, *ast.ClassNode:
+		return f.Type.Value
+	}
+
+	switch f.Type.Value {
+	case "float", "double":
+		return "float"
+	case "bool":
+		return "bool"
+	case "string":
+		return "str"
+	}
+
+	return "int"
+}
+
+// elemFormat returns the struct format of a value of the type of f, or of its
+// elements, empty for classes and unsupported types.
+func (g *generator) elemFormat(f *ast.PropertyNode) string {
+	switch f.Flags {
+	case ast.PropertyFlagSteamIDMarshal, ast.PropertyFlagGameIDMarshal:
+		return "Q"
+	case ast.PropertyFlagProtoMask, ast.PropertyFlagProtoMaskGC:
+		return "I"
+	}
+
+	if f.Type == nil {
+		return "i"
+	}
+
+	if enum, ok := f.Type.Node.(*ast.EnumNode); ok {
+		return formats[enum.Type.String()]
+	}
+
+	return formats[f.Type.Value]
+}
+
+func (g *generator) serializers(n *ast.ClassNode) error {
+	var ser, de []string
+
+	for _, f := range n.Fields() {
+		s, d, err := g.fieldSerializer(f)
+
+		if err != nil {
+			return err
+		}
+
+		ser = append(ser, s...)
+		de = append(de, d...)
+	}
+
+	g.buf.WriteString("\n")
+	g.printf(1, "def serialize(self) -> bytes:\n")
+	g.printf(2, "buf = bytearray()\n")
+
+	for _, line := range ser {
+		g.printf(2, "%s\n", line)
+	}
+
+	g.printf(2, "return bytes(buf)\n\n")
+	g.printf(1, "@classmethod\n")
+	g.printf(1, "def deserialize(cls, data: bytes, offset: int = 0) -> tuple[%s, int]:\n", n.Name())
+	g.printf(2, "\"\"\"Returns the instance read from data at offset and the offset following it.\"\"\"\n")
+	g.printf(2, "m = cls()\n")
+
+	for _, line := range de {
+		g.printf(2, "%s\n", line)
+	}
+
+	g.printf(2, "return m, offset\n")
+
+	return nil
+}
+
+func (g *generator) fieldSerializer(f *ast.PropertyNode) ([]string, []string, error) {
+	field := "m." + fieldName(f)
+	self := "self." + fieldName(f)
+	format := g.elemFormat(f)
+
+	if f.Flags == ast.PropertyFlagProto {
+		return nil, nil, fmt.Errorf("Cannot serialize proto field %s", backend.QualifiedName(f))
+	}
+
+	if f.Optional {
+		return nil, nil, fmt.Errorf("Cannot serialize optional field %s", backend.QualifiedName(f))
+	}
+
+	if f.Condition != nil {
+		return nil, nil, fmt.Errorf("Cannot serialize conditional field %s", backend.QualifiedName(f))
+	}
+
+	if class := classType(f); class != nil {
+		if _, ok, _ := backend.FixedArraySize(f); ok {
+			return nil, nil, fmt.Errorf("Cannot serialize %s, an array of classes", backend.QualifiedName(f))
+		}
+
+		ser := fmt.Sprintf("buf += %s.serialize()", self)
+		de := fmt.Sprintf("%s, offset = %s.deserialize(data, offset)", field, class.Name())
+		return []string{ser}, []string{de}, nil
+	}
+
+	if format == "" {
+		return nil, nil, fmt.Errorf("Cannot serialize %s of type %s", backend.QualifiedName(f), f.Type.Value)
+	}
+
+	order := "<"
+
+	if f.Endian == ast.BigEndian {
+		order = ">"
+	}
+
+	if size, ok, err := backend.FixedArraySize(f); ok {
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if isBytes(f) {
+			format = fmt.Sprintf("%s%ds", order, size)
+			return pack(format, self), unpack(format, field, "v"), nil
+		}
+
+		format = fmt.Sprintf("%s%d%s", order, size, format)
+		value := "list(v)"
+
+		if enum := enumType(f); enum != nil {
+			value = fmt.Sprintf("[_enum(%s, x) for x in v]", enum.Name())
+		}
+
+		ser := fmt.Sprintf("buf += struct.pack(\"%s\", *%s)", format, self)
+		de := []string{
+			fmt.Sprintf("v = struct.unpack_from(\"%s\", data, offset)", format),
+			fmt.Sprintf("%s = %s", field, value),
+			fmt.Sprintf("offset += struct.calcsize(\"%s\")", format),
+		}
+
+		return []string{ser}, de, nil
+	}
+
+	format = order + format
+
+	switch f.Flags {
+	case ast.PropertyFlagBoolMarshal:
+		return pack(format, "int("+self+")"), unpack(format, field, "bool(v)"), nil
+	case ast.PropertyFlagProtoMask, ast.PropertyFlagProtoMaskGC:
+		value := fmt.Sprintf("v & ~%#x", ast.ProtoMask)
+
+		if enum := enumType(f); enum != nil {
+			value = fmt.Sprintf("_enum(%s, %s)", enum.Name(), value)
+		}
+
+		return pack(format, fmt.Sprintf("%s | %#x", self, ast.ProtoMask)), unpack(format, field, value), nil
+	}
+
+	if enum := enumType(f); enum != nil {
+		return pack(format, self), unpack(format, field, fmt.Sprintf("_enum(%s, v)", enum.Name())), nil
+	}
+
+	return pack(format, self), unpack(format, field, "v"), nil
+}
+
+// pack writes value with format, starting with its byte order.
+func pack(format, value string) []string {
+	return []string{fmt.Sprintf("buf += struct.pack(\"%s\", %s)", format, value)}
+}
+
+// unpack reads a value as v, assigning value to field.
+func unpack(format, field, value string) []string {
+	return []string{
+		fmt.Sprintf("(v,) = struct.unpack_from(\"%s\", data, offset)", format),
+		fmt.Sprintf("%s = %s", field, value),
+		fmt.Sprintf("offset += struct.calcsize(\"%s\")", format),
+	}
+}
+
+func enumType(f *ast.PropertyNode) *ast.EnumNode {
+	if f.Type == nil || f.Flags == ast.PropertyFlagBoolMarshal {
+		return nil
+	}
+
+	enum, _ := f.Type.Node.(*ast.EnumNode)
+
+	return enum
+}
+
+func classType(f *ast.PropertyNode) *ast.ClassNode {
+	if f.Type == nil || f.Flags != ast.PropertyFlagNone {
+		return nil
+	}
+
+	class, _ := f.Type.Node.(*ast.ClassNode)
+
+	return class
+}
+
+// isBytes reports whether f is a byte array, generated as bytes.
+func isBytes(f *ast.PropertyNode) bool {
+	_, ok, _ := backend.FixedArraySize(f)
+	return ok && f.Flags == ast.PropertyFlagNone && f.Type != nil && f.Type.Value == "byte"
+}
+
+func (g *generator) docstring(indent int, doc string) {
+	if doc == "" {
+		return
+	}
+
+	doc = strings.Replace(doc, `"""`, `\"\"\"`, -1)
+
+	if !strings.Contains(doc, "\n") {
+		g.printf(indent, "\"\"\"%s\"\"\"\n\n", doc)
+		return
+	}
+
+	g.printf(indent, "\"\"\"%s\n", strings.Replace(doc, "\n", "\n"+strings.Repeat("    ", indent), -1))
+	g.printf(indent, "\"\"\"\n\n")
+}
+
+// comments writes the doc and deprecation comments of a member.
+func (g *generator) comments(indent int, n ast.Node) {
+	var doc string
+
+	switch n := n.(type) {
+	case *ast.PropertyNode:
+		doc = n.Doc
+	case *ast.EnumMemberNode:
+		doc = n.Doc
+	}
+
+	if doc != "" {
+		for _, line := range strings.Split(doc, "\n") {
+			g.printf(indent, "# %s\n", line)
+		}
+	}
+
+	if reason, ok := backend.Deprecation(n); ok {
+		g.printf(indent, "# Deprecated: %s\n", reason)
+	}
+}
+
+func lineComment(comment string) string {
+	if comment == "" {
+		return ""
+	}
+
+	return "  # " + comment
+}
+
+// fieldName returns the attribute of a field, its name in snake case.
+func fieldName(f *ast.PropertyNode) string {
+	return identifier(backend.SnakeCase(f.Name()))
+}
+
+// identifier escapes Python keywords, like "None" which is a common enum
+// member.
+func identifier(name string) string {
+	if keywords[name] {
+		return name + "_"
+	}
+
+	return name
+}
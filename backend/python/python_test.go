@@ -0,0 +1,127 @@
+package python
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/13k/go-steam-language/backend"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+const schema = `/// Message types.
+enum EMsg { Invalid = 0; Multi = 1; };
+enum EFlags flags { None = 0; A = 1; B = 2; AB = A | B; Old = 4; removed };
+enum EOther<short> { X = EFlags::B; };
+class MsgHdr<EMsg::Multi> {
+	const uint SIZE = 4;
+	steamidmarshal ulong steamID;
+	boolmarshal byte valid = 1;
+	protomask EMsg msg = EMsg::Multi;
+	EFlags flags = EFlags::AB;
+	uint mask = MsgHdr::SIZE | EFlags::A;
+	byte<20> key;
+	EOther<MsgHdr::SIZE> others;
+};
+class MsgWrapped { MsgHdr header; uint size; };
+`
+
+func generate(t *testing.T, opts backend.Options) string {
+	doc, err := parse.NewAnalyzer(token.NewTokenizer([]byte(schema)), "schemas/emsg.steamd").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	gen, ok := backend.Lookup("python")
+
+	if !ok {
+		t.Fatalf("expected python backend to be registered")
+	}
+
+	files, err := gen.Generate(doc, opts)
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if len(files) != 1 || files[0].Name != "emsg.py" {
+		t.Fatalf("mismatch: got %v, but expected emsg.py only", files)
+	}
+
+	return string(files[0].Content)
+}
+
+func TestGenerate(t *testing.T) {
+	src := generate(t, backend.Options{Exclude: map[string]bool{"EMsg": true}})
+
+	expected := []string{
+		"class EMsg(enum.IntEnum):\n    \"\"\"Message types.\"\"\"\n",
+		"class EFlags(enum.IntFlag):\n    None_ = 0\n",
+		"    AB = A | B\n",
+		"    # Deprecated: removed\n    Old = 4\n",
+		"    X = EFlags.B.value\n",
+		"@dataclass\nclass MsgHdr:\n",
+		"    def emsg() -> EMsg:\n        return EMsg.Multi\n",
+		"    SIZE = 4\n",
+		"    steam_id: int = 0\n",
+		"    valid: bool = True\n",
+		"    msg: EMsg = EMsg.Multi\n",
+		"    mask: int = SIZE | EFlags.A\n",
+		"    key: bytes = field(default_factory=lambda: bytes(20))\n",
+		"    others: list[EOther] = field(default_factory=lambda: [0] * 4)\n",
+		"buf += struct.pack(\"<I\", self.msg | 0x80000000)",
+		"m.msg = _enum(EMsg, v & ~0x80000000)",
+		"buf += struct.pack(\"<4h\", *self.others)",
+		"m.others = [_enum(EOther, x) for x in v]",
+		"    header: MsgHdr = field(default_factory=MsgHdr)\n",
+		"m.header, offset = MsgHdr.deserialize(data, offset)",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(src, s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+
+	if skipped := generate(t, backend.Options{SkipRemoved: true}); strings.Contains(skipped, "Old") {
+		t.Fatalf("expected removed member to be skipped\n%s", skipped)
+	}
+}
+
+const roundTrip = `
+import emsg
+
+m = emsg.MsgWrapped(size=7)
+m.header.steam_id = 76561197960287930
+m.header.others = [emsg.EOther.X, 9, 0, 1]
+data = m.serialize()
+assert len(data) == 8 + 1 + 4 + 4 + 4 + 20 + 8 + 4, len(data)
+r, offset = emsg.MsgWrapped.deserialize(data)
+assert r == m and offset == len(data), (r, offset)
+assert r.header.msg is emsg.EMsg.Multi and r.header.flags is emsg.EFlags.AB
+`
+
+func TestGenerateRoundTrip(t *testing.T) {
+	python, err := exec.LookPath("python3")
+
+	if err != nil {
+		t.Skip("python3 not found")
+	}
+
+	dir := t.TempDir()
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "emsg.py"), []byte(generate(t, backend.Options{})), 0644); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	cmd := exec.Command(python, "-c", roundTrip)
+	cmd.Dir = dir
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("not expected error %v\n%s", err, out)
+	}
+}
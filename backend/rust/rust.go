@@ -0,0 +1,733 @@
+// Package rust is the "rust" backend, generating a Rust module per document
+// with #[repr] enums and structs reading and writing their wire format,
+// without padding and little-endian unless annotated `endian:be`, with the
+// byteorder crate.
+//
+// Flags enums, whose values combine members, are generated as newtypes with an
+// associated constant per member, as are enums without members. Members
+// sharing the value of a previous one are associated constants too, since
+// discriminants must be unique. Modules are self-contained, so imported
+// declarations are generated in each of them.
+package rust
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/backend"
+)
+
+const header = `// Code generated by go-steam-language. DO NOT EDIT.
+
+#![allow(deprecated, unused, non_camel_case_types, non_snake_case, non_upper_case_globals)]
+
+use byteorder::{LittleEndian, ReadBytesExt, WriteBytesExt};
+use std::io::{self, Read, Write};
+
+fn invalid_value(name: &str) -> io::Error {
+    io::Error::new(io::ErrorKind::InvalidData, format!("invalid value of {}", name))
+}
+`
+
+var types = map[string]string{
+	"byte":   "u8",
+	"sbyte":  "i8",
+	"short":  "i16",
+	"ushort": "u16",
+	"int":    "i32",
+	"uint":   "u32",
+	"long":   "i64",
+	"ulong":  "u64",
+	"float":  "f32",
+	"double": "f64",
+	"bool":   "bool",
+}
+
+var keywords = map[string]bool{
+	"as": true, "async": true, "await": true, "break": true, "const": true,
+	"continue": true, "crate": true, "dyn": true, "else": true, "enum": true,
+	"extern": true, "false": true, "fn": true, "for": true, "if": true,
+	"impl": true, "in": true, "let": true, "loop": true, "match": true,
+	"mod": true, "move": true, "mut": true, "pub": true, "ref": true,
+	"return": true, "self": true, "Self": true, "static": true, "struct": true,
+	"super": true, "trait": true, "true": true, "type": true, "unsafe": true,
+	"use": true, "where": true, "while": true, "abstract": true, "become": true,
+	"box": true, "do": true, "final": true, "macro": true, "override": true,
+	"priv": true, "try": true, "typeof": true, "unsized": true, "virtual": true,
+	"yield": true,
+}
+
+func init() {
+	backend.Register(rustBackend{})
+}
+
+type rustBackend struct{}
+
+func (rustBackend) Name() string {
+	return "rust"
+}
+
+func (rustBackend) Generate(doc *ast.DocumentNode, opts backend.Options) ([]backend.OutputFile, error) {
+	g := &generator{opts: opts}
+	g.buf.WriteString(header)
+
+	for _, child := range doc.Types() {
+		var err error
+
+		switch n := child.(type) {
+		case *ast.EnumNode:
+			g.buf.WriteString("\n")
+			err = g.enum(n)
+		case *ast.ClassNode:
+			g.buf.WriteString("\n")
+			err = g.class(n)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return []backend.OutputFile{{Name: backend.OutputName(doc, ".rs"), Content: g.buf.Bytes()}}, nil
+}
+
+type generator struct {
+	buf  bytes.Buffer
+	opts backend.Options
+}
+
+func (g *generator) printf(indent int, format string, args ...interface{}) {
+	g.buf.WriteString(strings.Repeat("    ", indent))
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// member is a generated enum member. Aliases name the member whose value they
+// share.
+type member struct {
+	node  *ast.EnumMemberNode
+	value uint64
+	alias string
+}
+
+func (g *generator) members(n *ast.EnumNode) ([]member, error) {
+	var members []member
+	seen := make(map[uint64]string)
+
+	for _, m := range n.Members() {
+		if g.opts.SkipRemoved && m.Removed {
+			continue
+		}
+
+		v, err := backend.Value(m)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Rust rejects literals out of the range of the repr, like 0x80000000
+		// for i32.
+		v = backend.StorageValue(v, n.Type)
+		name := identifier(m.Name())
+		members = append(members, member{node: m, value: v, alias: seen[v]})
+
+		if _, ok := seen[v]; !ok {
+			seen[v] = name
+		}
+	}
+
+	return members, nil
+}
+
+// isNewtype reports whether n is generated as a newtype rather than an enum.
+func (g *generator) isNewtype(n *ast.EnumNode) bool {
+	if n.Flags {
+		return true
+	}
+
+	for _, m := range n.Members() {
+		if !(g.opts.SkipRemoved && m.Removed) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (g *generator) enum(n *ast.EnumNode) error {
+	name := n.Name()
+	repr := types[n.Type.String()]
+	members, err := g.members(n)
+
+	if err != nil {
+		return err
+	}
+
+	if g.isNewtype(n) {
+		g.doc(0, n.Doc, nil)
+		g.printf(0, "#[repr(transparent)]\n")
+		g.printf(0, "#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Hash)]\n")
+		g.printf(0, "pub struct %s(pub %s);\n\n", name, repr)
+		g.printf(0, "impl %s {\n", name)
+
+		for _, m := range members {
+			g.doc(1, m.node.Doc, m.node)
+			g.printf(1, "pub const %s: Self = Self(%s);%s\n", identifier(m.node.Name()), formatValue(m.value, n.Type.Signed()), lineComment(m.node.Comment))
+		}
+
+		if len(members) > 0 {
+			g.buf.WriteString("\n")
+		}
+
+		g.printf(1, "/// Reports whether all the bits of other are set.\n")
+		g.printf(1, "pub fn contains(self, other: Self) -> bool {\n")
+		g.printf(2, "self.0 & other.0 == other.0\n")
+		g.printf(1, "}\n")
+		g.printf(0, "}\n\n")
+		g.printf(0, "impl std::ops::BitOr for %s {\n", name)
+		g.printf(1, "type Output = Self;\n\n")
+		g.printf(1, "fn bitor(self, rhs: Self) -> Self {\n")
+		g.printf(2, "Self(self.0 | rhs.0)\n")
+		g.printf(1, "}\n")
+		g.printf(0, "}\n")
+
+		return nil
+	}
+
+	g.doc(0, n.Doc, nil)
+	g.printf(0, "#[repr(%s)]\n", repr)
+	g.printf(0, "#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash)]\n")
+	g.printf(0, "pub enum %s {\n", name)
+
+	var aliases []member
+
+	for _, m := range members {
+		if m.alias != "" {
+			aliases = append(aliases, m)
+			continue
+		}
+
+		g.doc(1, m.node.Doc, m.node)
+		g.printf(1, "%s = %s,%s\n", identifier(m.node.Name()), formatValue(m.value, n.Type.Signed()), lineComment(m.node.Comment))
+	}
+
+	g.printf(0, "}\n\n")
+	g.printf(0, "impl %s {\n", name)
+
+	for _, m := range aliases {
+		g.doc(1, m.node.Doc, m.node)
+		g.printf(1, "pub const %s: Self = Self::%s;%s\n", identifier(m.node.Name()), m.alias, lineComment(m.node.Comment))
+	}
+
+	if len(aliases) > 0 {
+		g.buf.WriteString("\n")
+	}
+
+	g.printf(1, "/// Returns the member with value v.\n")
+	g.printf(1, "pub fn from_repr(v: %s) -> Option<Self> {\n", repr)
+	g.printf(2, "match v {\n")
+
+	for _, m := range members {
+		if m.alias == "" {
+			g.printf(3, "%s => Some(Self::%s),\n", formatValue(m.value, n.Type.Signed()), identifier(m.node.Name()))
+		}
+	}
+
+	g.printf(3, "_ => None,\n")
+	g.printf(2, "}\n")
+	g.printf(1, "}\n")
+	g.printf(0, "}\n\n")
+
+	// The default is the zero member, if any, like the zero value of Go enums.
+	zero := members[0]
+
+	for _, m := range members {
+		if m.value == 0 {
+			zero = m
+			break
+		}
+	}
+
+	g.printf(0, "impl Default for %s {\n", name)
+	g.printf(1, "fn default() -> Self {\n")
+	g.printf(2, "Self::%s\n", identifier(zero.node.Name()))
+	g.printf(1, "}\n")
+	g.printf(0, "}\n")
+
+	return nil
+}
+
+// enumValue renders the value v of enum, preferring member.
+func (g *generator) enumValue(enum *ast.EnumNode, v uint64, member *ast.EnumMemberNode) (string, error) {
+	generated := func(m *ast.EnumMemberNode) bool {
+		return m.Parent() == enum && !(g.opts.SkipRemoved && m.Removed)
+	}
+
+	if member != nil && generated(member) {
+		return enum.Name() + "::" + identifier(member.Name()), nil
+	}
+
+	v = backend.StorageValue(v, enum.Type)
+
+	if g.isNewtype(enum) {
+		return fmt.Sprintf("%s(%s)", enum.Name(), formatValue(v, enum.Type.Signed())), nil
+	}
+
+	for _, m := range enum.Members() {
+		if !generated(m) {
+			continue
+		}
+
+		mv, err := backend.Value(m)
+
+		if err != nil {
+			return "", err
+		}
+
+		if backend.StorageValue(mv, enum.Type) == v {
+			return enum.Name() + "::" + identifier(m.Name()), nil
+		}
+	}
+
+	return "", fmt.Errorf("Cannot represent %s as a member of %s", formatValue(v, enum.Type.Signed()), enum.Name())
+}
+
+func (g *generator) class(n *ast.ClassNode) error {
+	name := n.Name()
+	layout, err := ast.Layout(n)
+
+	if err != nil {
+		return err
+	}
+
+	if !layout.IsFixed() {
+		return fmt.Errorf("Cannot serialize %s, it has variable length", name)
+	}
+
+	fields := n.Fields()
+
+	g.doc(0, n.Doc, nil)
+	g.printf(0, "#[derive(Debug, Clone, PartialEq)]\n")
+	g.printf(0, "pub struct %s {\n", name)
+
+	for _, f := range fields {
+		g.doc(1, f.Doc, f)
+		g.printf(1, "pub %s: %s,%s\n", fieldName(f), g.fieldType(f), lineComment(f.Comment))
+	}
+
+	g.printf(0, "}\n\n")
+	g.printf(0, "impl %s {\n", name)
+
+	if q := n.Qualifier; q != nil && q.Kind == ast.QualifierEMsg && q.Symbol != nil {
+		if m, ok := q.Symbol.Node.(*ast.EnumMemberNode); ok {
+			v, err := backend.Value(m)
+
+			if err != nil {
+				return err
+			}
+
+			enum := m.Parent().(*ast.EnumNode)
+			value, err := g.enumValue(enum, v, m)
+
+			if err != nil {
+				return err
+			}
+
+			g.printf(1, "pub const EMSG: %s = %s;\n", enum.Name(), value)
+		}
+	}
+
+	for _, c := range n.Consts() {
+		if g.opts.SkipRemoved && c.Removed {
+			continue
+		}
+
+		value, err := g.propertyValue(c)
+
+		if err != nil {
+			return err
+		}
+
+		g.doc(1, c.Doc, c)
+		g.printf(1, "pub const %s: %s = %s;%s\n", identifier(c.Name()), g.fieldType(c), value, lineComment(c.Comment))
+	}
+
+	g.printf(1, "/// Size of the wire format in bytes.\n")
+	g.printf(1, "pub const WIRE_SIZE: usize = %d;\n", layout.Size)
+
+	if err := g.serializers(n); err != nil {
+		return err
+	}
+
+	g.printf(0, "}\n\n")
+	g.printf(0, "impl Default for %s {\n", name)
+	g.printf(1, "fn default() -> Self {\n")
+	g.printf(2, "Self {\n")
+
+	for _, f := range fields {
+		value, err := g.fieldDefault(f)
+
+		if err != nil {
+			return err
+		}
+
+		g.printf(3, "%s: %s,\n", fieldName(f), value)
+	}
+
+	g.printf(2, "}\n")
+	g.printf(1, "}\n")
+	g.printf(0, "}\n")
+
+	return nil
+}
+
+// propertyValue renders the evaluated value of a constant or field default.
+func (g *generator) propertyValue(p *ast.PropertyNode) (string, error) {
+	v, err := backend.Value(p)
+
+	if err != nil {
+		return "", err
+	}
+
+	if enum := enumType(p); enum != nil {
+		var member *ast.EnumMemberNode
+
+		if x, ok := ast.ValueExpr(p).(*ast.SymbolExpr); ok && x.Symbol != nil {
+			member, _ = x.Symbol.Node.(*ast.EnumMemberNode)
+		}
+
+		return g.enumValue(enum, v, member)
+	}
+
+	switch g.elemType(p) {
+	case "bool":
+		return fmt.Sprintf("%t", v != 0), nil
+	case "f32", "f64":
+		return formatValue(v, true) + ".0", nil
+	}
+
+	return formatValue(v, signed(p)), nil
+}
+
+func (g *generator) fieldDefault(f *ast.PropertyNode) (string, error) {
+	if len(f.Default) > 0 || f.Expr != nil {
+		return g.propertyValue(f)
+	}
+
+	if size, ok, err := backend.FixedArraySize(f); ok {
+		return fmt.Sprintf("[%s; %d]", g.zeroValue(f), size), err
+	}
+
+	return g.zeroValue(f), nil
+}
+
+func (g *generator) zeroValue(f *ast.PropertyNode) string {
+	if enum := enumType(f); enum != nil {
+		return enum.Name() + "::default()"
+	}
+
+	if class := classType(f); class != nil {
+		return class.Name() + "::default()"
+	}
+
+	switch g.elemType(f) {
+	case "bool":
+		return "false"
+	case "f32", "f64":
+		return "0.0"
+	}
+
+	return "0"
+}
+
+func (g *generator) fieldType(f *ast.PropertyNode) string {
+	if size, ok, _ := backend.FixedArraySize(f); ok {
+		return fmt.Sprintf("[%s; %d]", g.elemType(f), size)
+	}
+
+	return g.elemType(f)
+}
+
+func (g *generator) elemType(f *ast.PropertyNode) string {
+	switch f.Flags {
+	case ast.PropertyFlagBoolMarshal:
+		return "bool"
+	case ast.PropertyFlagSteamIDMarshal, ast.PropertyFlagGameIDMarshal:
+		return "u64"
+	}
+
+	if f.Type == nil {
+		return "i32"
+	}
+
+	switch f.Type.Node.(type) {
+	case *ast.EnumNode, *ast.ClassNode:
+		return f.Type.Value
+	}
+
+	if t, ok := types[f.Type.Value]; ok {
+		return t
+	}
+
+	return f.Type.Value
+}
+
+func (g *generator) serializers(n *ast.ClassNode) error {
+	var write, read []string
+
+	for _, f := range n.Fields() {
+		w, r, err := g.fieldSerializer(f)
+
+		if err != nil {
+			return err
+		}
+
+		write = append(write, w...)
+		read = append(read, r...)
+	}
+
+	g.buf.WriteString("\n")
+	g.printf(1, "/// Writes the wire format of the message to w.\n")
+	g.printf(1, "pub fn write_to<W: Write>(&self, w: &mut W) -> io::Result<()> {\n")
+	g.lines(2, write)
+	g.printf(2, "Ok(())\n")
+	g.printf(1, "}\n\n")
+	g.printf(1, "/// Reads a message in wire format from r.\n")
+	g.printf(1, "pub fn read_from<R: Read>(r: &mut R) -> io::Result<Self> {\n")
+	g.printf(2, "let mut m = Self::default();\n")
+	g.lines(2, read)
+	g.printf(2, "Ok(m)\n")
+	g.printf(1, "}\n")
+
+	return nil
+}
+
+func (g *generator) lines(indent int, lines []string) {
+	for _, line := range lines {
+		g.printf(indent, "%s\n", strings.Replace(line, "\n", "\n"+strings.Repeat("    ", indent), -1))
+	}
+}
+
+func (g *generator) fieldSerializer(f *ast.PropertyNode) ([]string, []string, error) {
+	name := fieldName(f)
+
+	if f.Flags == ast.PropertyFlagProto {
+		return nil, nil, fmt.Errorf("Cannot serialize proto field %s", backend.QualifiedName(f))
+	}
+
+	if class := classType(f); class != nil {
+		if _, ok, _ := backend.FixedArraySize(f); ok {
+			return nil, nil, fmt.Errorf("Cannot serialize %s, an array of classes", backend.QualifiedName(f))
+		}
+
+		write := fmt.Sprintf("self.%s.write_to(w)?;", name)
+		read := fmt.Sprintf("m.%s = %s::read_from(r)?;", name, class.Name())
+		return []string{write}, []string{read}, nil
+	}
+
+	if _, ok, err := backend.FixedArraySize(f); ok {
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if isBytes(f) {
+			return []string{fmt.Sprintf("w.write_all(&self.%s)?;", name)}, []string{fmt.Sprintf("r.read_exact(&mut m.%s)?;", name)}, nil
+		}
+
+		w, r, err := g.valueSerializer(f, "v", "*v")
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		write := fmt.Sprintf("for &v in self.%s.iter() {\n    %s\n}", name, w)
+		read := fmt.Sprintf("for v in m.%s.iter_mut() {\n    %s\n}", name, r)
+		return []string{write}, []string{read}, nil
+	}
+
+	w, r, err := g.valueSerializer(f, "self."+name, "m."+name)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return []string{w}, []string{r}, nil
+}
+
+// valueSerializer returns the statements writing value and reading target, of
+// the type of f or of its elements.
+func (g *generator) valueSerializer(f *ast.PropertyNode, value, target string) (string, string, error) {
+	var (
+		wire  = "i32"
+		write = value
+		read  = "%s"
+	)
+
+	enum := enumType(f)
+
+	switch {
+	case f.Flags == ast.PropertyFlagSteamIDMarshal || f.Flags == ast.PropertyFlagGameIDMarshal:
+		wire = "u64"
+	case f.Flags == ast.PropertyFlagBoolMarshal:
+		wire = "u8"
+
+		if f.Type != nil && types[f.Type.Value] != "" && f.Type.Value != "bool" {
+			wire = types[f.Type.Value]
+		}
+
+		write, read = fmt.Sprintf("%s as %s", value, wire), "%s != 0"
+	case f.Flags == ast.PropertyFlagProtoMask || f.Flags == ast.PropertyFlagProtoMaskGC:
+		wire = "u32"
+		raw := fmt.Sprintf("(%%s & %#x)", ^uint32(ast.ProtoMask))
+
+		switch {
+		case enum != nil && g.isNewtype(enum):
+			write = fmt.Sprintf("%s.0 as u32", value)
+			read = fmt.Sprintf("%s(%s as %s)", enum.Name(), raw, types[enum.Type.String()])
+		case enum != nil:
+			write = fmt.Sprintf("%s as u32", value)
+			read = fmt.Sprintf("%s::from_repr(%s as %s).ok_or_else(|| invalid_value(%q))?", enum.Name(), raw, types[enum.Type.String()], backend.QualifiedName(f))
+		default:
+			write = fmt.Sprintf("%s as u32", value)
+			read = fmt.Sprintf("%s as %s", raw, g.elemType(f))
+		}
+
+		write = fmt.Sprintf("%s | %#x", write, ast.ProtoMask)
+	case enum != nil:
+		wire = types[enum.Type.String()]
+
+		if g.isNewtype(enum) {
+			write, read = value+".0", enum.Name()+"(%s)"
+		} else {
+			write = fmt.Sprintf("%s as %s", value, wire)
+			read = fmt.Sprintf("%s::from_repr(%%s).ok_or_else(|| invalid_value(%q))?", enum.Name(), backend.QualifiedName(f))
+		}
+	case f.Type != nil:
+		wire = types[f.Type.Value]
+
+		if wire == "" {
+			return "", "", fmt.Errorf("Cannot serialize %s of type %s", backend.QualifiedName(f), f.Type.Value)
+		}
+
+		if wire == "bool" {
+			wire = "u8"
+			write, read = value+" as u8", "%s != 0"
+		}
+	}
+
+	byteOrder := "::<LittleEndian>"
+
+	if f.Endian == ast.BigEndian {
+		byteOrder = "::<byteorder::BigEndian>"
+	}
+
+	if wire == "u8" || wire == "i8" {
+		byteOrder = ""
+	}
+
+	writeStmt := fmt.Sprintf("w.write_%s%s(%s)?;", wire, byteOrder, write)
+	readStmt := fmt.Sprintf("%s = %s;", target, fmt.Sprintf(read, fmt.Sprintf("r.read_%s%s()?", wire, byteOrder)))
+
+	return writeStmt, readStmt, nil
+}
+
+// formatValue renders v, as produced by ast.Evaluate, as a signed or unsigned
+// decimal.
+func formatValue(v uint64, signed bool) string {
+	if signed {
+		return backend.FormatValue(v)
+	}
+
+	return fmt.Sprintf("%d", v)
+}
+
+// signed reports whether the values of p are signed.
+func signed(p *ast.PropertyNode) bool {
+	if enum := enumType(p); enum != nil {
+		return enum.Type.Signed()
+	}
+
+	return strings.HasPrefix(typeOf(p), "i")
+}
+
+func typeOf(p *ast.PropertyNode) string {
+	if p.Flags == ast.PropertyFlagSteamIDMarshal || p.Flags == ast.PropertyFlagGameIDMarshal {
+		return "u64"
+	}
+
+	if p.Type == nil {
+		return "i32"
+	}
+
+	return types[p.Type.Value]
+}
+
+func enumType(f *ast.PropertyNode) *ast.EnumNode {
+	if f.Type == nil || f.Flags == ast.PropertyFlagBoolMarshal {
+		return nil
+	}
+
+	enum, _ := f.Type.Node.(*ast.EnumNode)
+
+	return enum
+}
+
+func classType(f *ast.PropertyNode) *ast.ClassNode {
+	if f.Type == nil || f.Flags != ast.PropertyFlagNone {
+		return nil
+	}
+
+	class, _ := f.Type.Node.(*ast.ClassNode)
+
+	return class
+}
+
+// isBytes reports whether f is a byte array, written and read at once.
+func isBytes(f *ast.PropertyNode) bool {
+	_, ok, _ := backend.FixedArraySize(f)
+	return ok && f.Flags == ast.PropertyFlagNone && f.Type != nil && f.Type.Value == "byte"
+}
+
+// doc writes the doc comment of a declaration and the deprecated attribute of
+// members.
+func (g *generator) doc(indent int, doc string, member ast.Node) {
+	if doc != "" {
+		for _, line := range strings.Split(doc, "\n") {
+			g.printf(indent, "/// %s\n", line)
+		}
+	}
+
+	if member != nil {
+		if reason, ok := backend.Deprecation(member); ok {
+			g.printf(indent, "#[deprecated(note = %q)]\n", reason)
+		}
+	}
+}
+
+func lineComment(comment string) string {
+	if comment == "" {
+		return ""
+	}
+
+	return " // " + comment
+}
+
+// fieldName returns the struct field of a field, its name in snake case.
+func fieldName(f *ast.PropertyNode) string {
+	return identifier(backend.SnakeCase(f.Name()))
+}
+
+// identifier escapes Rust keywords, as raw identifiers where possible.
+func identifier(name string) string {
+	if !keywords[name] {
+		return name
+	}
+
+	switch name {
+	case "crate", "self", "Self", "super":
+		return name + "_"
+	}
+
+	return "r#" + name
+}
@@ -0,0 +1,119 @@
+package rust
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/13k/go-steam-language/backend"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+const schema = `/// Message types.
+enum EMsg { Invalid = 0; Multi = 1; };
+enum EFlags<byte> flags { A = 1; B = 2; AB = A | B; Old = 4; removed };
+enum EOther<short> { X = 5; Y = 7; Min = 0x8000; };
+class MsgHdr<EMsg::Multi> {
+	const uint SIZE = 4;
+	const long BIG = 1 << 40;
+	steamidmarshal ulong steamID;
+	boolmarshal byte valid = 1;
+	protomask EMsg msg = EMsg::Multi;
+	EFlags flags = EFlags::AB;
+	uint mask = MsgHdr::SIZE | EFlags::A;
+	byte<20> key;
+	EOther<MsgHdr::SIZE> others;
+};
+class MsgWrapped { MsgHdr header; uint size; };
+`
+
+func generate(t *testing.T, opts backend.Options) string {
+	doc, err := parse.NewAnalyzer(token.NewTokenizer([]byte(schema)), "schemas/emsg.steamd").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	gen, ok := backend.Lookup("rust")
+
+	if !ok {
+		t.Fatalf("expected rust backend to be registered")
+	}
+
+	files, err := gen.Generate(doc, opts)
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if len(files) != 1 || files[0].Name != "emsg.rs" {
+		t.Fatalf("mismatch: got %v, but expected emsg.rs only", files)
+	}
+
+	return string(files[0].Content)
+}
+
+func TestGenerate(t *testing.T) {
+	src := generate(t, backend.Options{Exclude: map[string]bool{"EMsg": true}})
+
+	expected := []string{
+		"/// Message types.\n#[repr(i32)]\n#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash)]\npub enum EMsg {",
+		"pub struct EFlags(pub u8);",
+		"pub const AB: Self = Self(3);",
+		"#[deprecated(note = \"removed\")]\n    pub const Old: Self = Self(4);",
+		"#[repr(i16)]",
+		"7 => Some(Self::Y),",
+		"Min = -32768,",
+		"-32768 => Some(Self::Min),",
+		"pub struct MsgHdr {",
+		"pub steam_id: u64,",
+		"pub valid: bool,",
+		"pub key: [u8; 20],",
+		"pub others: [EOther; 4],",
+		"pub const EMSG: EMsg = EMsg::Multi;",
+		"pub const SIZE: u32 = 4;",
+		"pub const BIG: i64 = 1099511627776;",
+		"pub const WIRE_SIZE: usize = 46;",
+		"flags: EFlags::AB,",
+		"mask: 5,",
+		"others: [EOther::default(); 4],",
+		"w.write_u64::<LittleEndian>(self.steam_id)?;",
+		"w.write_u8(self.valid as u8)?;",
+		"m.valid = r.read_u8()? != 0;",
+		"w.write_u32::<LittleEndian>(self.msg as u32 | 0x80000000)?;",
+		"m.msg = EMsg::from_repr((r.read_u32::<LittleEndian>()? & 0x7fffffff) as i32).ok_or_else(|| invalid_value(\"MsgHdr::msg\"))?;",
+		"m.flags = EFlags(r.read_u8()?);",
+		"w.write_all(&self.key)?;",
+		"*v = EOther::from_repr(r.read_i16::<LittleEndian>()?).ok_or_else(|| invalid_value(\"MsgHdr::others\"))?;",
+		"self.header.write_to(w)?;",
+		"m.header = MsgHdr::read_from(r)?;",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(src, s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+}
+
+func TestGenerateInvalidEnumValue(t *testing.T) {
+	doc, err := parse.NewAnalyzer(token.NewTokenizer([]byte("enum E { A = 1; };\nclass C { E e = 2; };")), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	gen, _ := backend.Lookup("rust")
+
+	if _, err := gen.Generate(doc, backend.Options{}); err == nil {
+		t.Fatalf("expected error generating a value that isn't a member")
+	}
+}
+
+func TestGenerateSkipRemoved(t *testing.T) {
+	src := generate(t, backend.Options{SkipRemoved: true})
+
+	if strings.Contains(src, "Old") {
+		t.Fatalf("expected removed members to be skipped\n%s", src)
+	}
+}
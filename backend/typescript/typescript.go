@@ -0,0 +1,579 @@
+// Package typescript is the "typescript" backend, generating a TypeScript
+// module per document with enums, interfaces for classes and, in a namespace
+// of the same name, functions creating and encoding them with DataView,
+// without padding and little-endian unless annotated `endian:be`.
+//
+// Values are the ones computed by ast.Evaluate. 64-bit fields are bigints and
+// byte arrays are Uint8Arrays. Modules are self-contained, so imported
+// declarations are generated in each of them.
+package typescript
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/backend"
+)
+
+const header = "// Code generated by go-steam-language. DO NOT EDIT.\n"
+
+// accessors are the DataView methods reading and writing each builtin type,
+// without their get and set prefixes.
+var accessors = map[string]string{
+	"byte":   "Uint8",
+	"sbyte":  "Int8",
+	"short":  "Int16",
+	"ushort": "Uint16",
+	"int":    "Int32",
+	"uint":   "Uint32",
+	"long":   "BigInt64",
+	"ulong":  "BigUint64",
+	"float":  "Float32",
+	"double": "Float64",
+	"bool":   "Uint8",
+}
+
+func init() {
+	backend.Register(typescriptBackend{})
+}
+
+type typescriptBackend struct{}
+
+func (typescriptBackend) Name() string {
+	return "typescript"
+}
+
+func (typescriptBackend) Generate(doc *ast.DocumentNode, opts backend.Options) ([]backend.OutputFile, error) {
+	g := &generator{opts: opts}
+	g.buf.WriteString(header)
+
+	for _, child := range doc.Types() {
+		var err error
+
+		switch n := child.(type) {
+		case *ast.EnumNode:
+			g.buf.WriteString("\n")
+			err = g.enum(n)
+		case *ast.ClassNode:
+			g.buf.WriteString("\n")
+			err = g.class(n)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return []backend.OutputFile{{Name: backend.OutputName(doc, ".ts"), Content: g.buf.Bytes()}}, nil
+}
+
+type generator struct {
+	buf  bytes.Buffer
+	opts backend.Options
+}
+
+func (g *generator) printf(indent int, format string, args ...interface{}) {
+	g.buf.WriteString(strings.Repeat("  ", indent))
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) enum(n *ast.EnumNode) error {
+	g.doc(0, n.Doc, n)
+	g.printf(0, "export enum %s {\n", n.Name())
+
+	for _, m := range n.Members() {
+		if g.opts.SkipRemoved && m.Removed {
+			continue
+		}
+
+		v, err := backend.Value(m)
+
+		if err != nil {
+			return err
+		}
+
+		g.doc(1, m.Doc, m)
+		g.printf(1, "%s = %s,%s\n", m.Name(), backend.FormatStorageValue(v, n.Type), lineComment(m.Comment))
+	}
+
+	g.printf(0, "}\n")
+
+	return nil
+}
+
+func (g *generator) class(n *ast.ClassNode) error {
+	name := n.Name()
+	layout, err := ast.Layout(n)
+
+	if err != nil {
+		return err
+	}
+
+	if !layout.IsFixed() {
+		return fmt.Errorf("Cannot serialize %s, it has variable length", name)
+	}
+
+	fields := n.Fields()
+
+	g.doc(0, n.Doc, nil)
+	g.printf(0, "export interface %s {\n", name)
+
+	for _, f := range fields {
+		g.doc(1, f.Doc, f)
+		g.printf(1, "%s: %s;%s\n", f.Name(), g.fieldType(f), lineComment(f.Comment))
+	}
+
+	g.printf(0, "}\n\n")
+	g.printf(0, "export namespace %s {\n", name)
+
+	if q := n.Qualifier; q != nil && q.Kind == ast.QualifierEMsg && q.Symbol != nil {
+		if member, ok := q.Symbol.Node.(*ast.EnumMemberNode); ok {
+			value, err := g.enumValue(member.Parent().(*ast.EnumNode), member)
+
+			if err != nil {
+				return err
+			}
+
+			g.printf(1, "export const emsg = %s;\n", value)
+		}
+	}
+
+	for _, c := range n.Consts() {
+		if g.opts.SkipRemoved && c.Removed {
+			continue
+		}
+
+		value, err := g.propertyValue(c)
+
+		if err != nil {
+			return err
+		}
+
+		g.doc(1, c.Doc, c)
+		g.printf(1, "export const %s: %s = %s;%s\n", c.Name(), g.fieldType(c), value, lineComment(c.Comment))
+	}
+
+	g.printf(1, "/** Size of the wire format in bytes. */\n")
+	g.printf(1, "export const wireSize = %d;\n\n", layout.Size)
+	g.printf(1, "export function create(): %s {\n", name)
+	g.printf(2, "return {\n")
+
+	for _, f := range fields {
+		value, err := g.fieldDefault(f)
+
+		if err != nil {
+			return err
+		}
+
+		g.printf(3, "%s: %s,\n", f.Name(), value)
+	}
+
+	g.printf(2, "};\n")
+	g.printf(1, "}\n")
+
+	if err := g.serializers(n); err != nil {
+		return err
+	}
+
+	g.printf(0, "}\n")
+
+	return nil
+}
+
+// enumValue renders member of enum, or its value if it isn't generated.
+func (g *generator) enumValue(enum *ast.EnumNode, member *ast.EnumMemberNode) (string, error) {
+	if g.opts.SkipRemoved && member.Removed {
+		v, err := backend.Value(member)
+		return fmt.Sprintf("%s as %s", backend.FormatStorageValue(v, enum.Type), enum.Name()), err
+	}
+
+	return enum.Name() + "." + member.Name(), nil
+}
+
+// propertyValue renders the evaluated value of a constant or field default.
+// Enum values referencing a single member are rendered as the member.
+func (g *generator) propertyValue(p *ast.PropertyNode) (string, error) {
+	if enum := enumType(p); enum != nil {
+		if x, ok := ast.ValueExpr(p).(*ast.SymbolExpr); ok && x.Symbol != nil {
+			if member, ok := x.Symbol.Node.(*ast.EnumMemberNode); ok && member.Parent() == enum {
+				return g.enumValue(enum, member)
+			}
+		}
+	}
+
+	v, err := backend.Value(p)
+
+	if err != nil {
+		return "", err
+	}
+
+	switch g.fieldType(p) {
+	case "boolean":
+		return fmt.Sprintf("%t", v != 0), nil
+	case "bigint":
+		return formatValue(v, signed(p)) + "n", nil
+	}
+
+	value := formatValue(v, signed(p))
+
+	if enum := enumType(p); enum != nil {
+		value = fmt.Sprintf("%s as %s", value, enum.Name())
+	}
+
+	return value, nil
+}
+
+func (g *generator) fieldDefault(f *ast.PropertyNode) (string, error) {
+	if len(f.Default) > 0 || f.Expr != nil {
+		return g.propertyValue(f)
+	}
+
+	if size, ok, err := backend.FixedArraySize(f); ok {
+		if isBytes(f) {
+			return fmt.Sprintf("new Uint8Array(%d)", size), err
+		}
+
+		return fmt.Sprintf("new Array<%s>(%d).fill(%s)", g.elemType(f), size, zeroValue(g.elemType(f))), err
+	}
+
+	if class := classType(f); class != nil {
+		return class.Name() + ".create()", nil
+	}
+
+	return zeroValue(g.fieldType(f)), nil
+}
+
+func zeroValue(typeName string) string {
+	switch typeName {
+	case "boolean":
+		return "false"
+	case "bigint":
+		return "0n"
+	case "number":
+		return "0"
+	}
+
+	return "0 as " + typeName
+}
+
+func (g *generator) fieldType(f *ast.PropertyNode) string {
+	if isBytes(f) {
+		return "Uint8Array"
+	}
+
+	if _, ok, _ := backend.FixedArraySize(f); ok {
+		return g.elemType(f) + "[]"
+	}
+
+	return g.elemType(f)
+}
+
+func (g *generator) elemType(f *ast.PropertyNode) string {
+	switch f.Flags {
+	case ast.PropertyFlagBoolMarshal:
+		return "boolean"
+	case ast.PropertyFlagSteamIDMarshal, ast.PropertyFlagGameIDMarshal:
+		return "bigint"
+	}
+
+	if f.Type == nil {
+		return "number"
+	}
+
+	switch f.Type.Node.(type) {
+	case *ast.EnumNode, *ast.ClassNode:
+		return f.Type.Value
+	}
+
+	switch f.Type.Value {
+	case "long", "ulong":
+		return "bigint"
+	case "bool":
+		return "boolean"
+	case "string":
+		return "string"
+	}
+
+	return "number"
+}
+
+func (g *generator) serializers(n *ast.ClassNode) error {
+	var write, read []string
+
+	for _, f := range n.Fields() {
+		w, r, err := g.fieldSerializer(f)
+
+		if err != nil {
+			return err
+		}
+
+		write = append(write, w...)
+		read = append(read, r...)
+	}
+
+	name := n.Name()
+
+	g.buf.WriteString("\n")
+	g.printf(1, "/** Writes m to view at offset, returning the offset following it. */\n")
+	g.printf(1, "export function write(view: DataView, offset: number, m: %s): number {\n", name)
+	g.lines(2, write)
+	g.printf(2, "return offset;\n")
+	g.printf(1, "}\n\n")
+	g.printf(1, "/** Reads an instance from view at offset, returning it and the offset following it. */\n")
+	g.printf(1, "export function read(view: DataView, offset: number): [%s, number] {\n", name)
+	g.printf(2, "const m = create();\n")
+	g.lines(2, read)
+	g.printf(2, "return [m, offset];\n")
+	g.printf(1, "}\n\n")
+	g.printf(1, "export function encode(m: %s): Uint8Array {\n", name)
+	g.printf(2, "const data = new Uint8Array(wireSize);\n")
+	g.printf(2, "write(new DataView(data.buffer), 0, m);\n")
+	g.printf(2, "return data;\n")
+	g.printf(1, "}\n\n")
+	g.printf(1, "export function decode(data: Uint8Array): %s {\n", name)
+	g.printf(2, "return read(new DataView(data.buffer, data.byteOffset, data.byteLength), 0)[0];\n")
+	g.printf(1, "}\n")
+
+	return nil
+}
+
+func (g *generator) lines(indent int, lines []string) {
+	for _, line := range lines {
+		g.printf(indent, "%s\n", line)
+	}
+}
+
+func (g *generator) fieldSerializer(f *ast.PropertyNode) ([]string, []string, error) {
+	field := "m." + f.Name()
+
+	if f.Flags == ast.PropertyFlagProto {
+		return nil, nil, fmt.Errorf("Cannot serialize proto field %s", backend.QualifiedName(f))
+	}
+
+	if class := classType(f); class != nil {
+		if _, ok, _ := backend.FixedArraySize(f); ok {
+			return nil, nil, fmt.Errorf("Cannot serialize %s, an array of classes", backend.QualifiedName(f))
+		}
+
+		write := fmt.Sprintf("offset = %s.write(view, offset, %s);", class.Name(), field)
+		read := fmt.Sprintf("[%s, offset] = %s.read(view, offset);", field, class.Name())
+		return []string{write}, []string{read}, nil
+	}
+
+	if size, ok, err := backend.FixedArraySize(f); ok {
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if isBytes(f) {
+			write := []string{
+				fmt.Sprintf("new Uint8Array(view.buffer, view.byteOffset + offset, %d).set(%s);", size, field),
+				fmt.Sprintf("offset += %d;", size),
+			}
+			read := []string{
+				fmt.Sprintf("%s = new Uint8Array(view.buffer, view.byteOffset + offset, %d).slice();", field, size),
+				fmt.Sprintf("offset += %d;", size),
+			}
+			return write, read, nil
+		}
+
+		w, r, err := g.valueSerializer(f, field+"[i]")
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		write := fmt.Sprintf("for (let i = 0; i < %d; i++) {\n%s\n%s}", size, indentLines(3, w), strings.Repeat("  ", 2))
+		read := fmt.Sprintf("for (let i = 0; i < %d; i++) {\n%s\n%s}", size, indentLines(3, r), strings.Repeat("  ", 2))
+		return []string{write}, []string{read}, nil
+	}
+
+	return g.valueSerializer(f, field)
+}
+
+// valueSerializer returns the statements writing and reading value, of the type
+// of f or of its elements.
+func (g *generator) valueSerializer(f *ast.PropertyNode, value string) ([]string, []string, error) {
+	var (
+		accessor = "Int32"
+		write    = value
+		read     = "%s"
+	)
+
+	enum := enumType(f)
+
+	switch {
+	case f.Flags == ast.PropertyFlagSteamIDMarshal || f.Flags == ast.PropertyFlagGameIDMarshal:
+		accessor = "BigUint64"
+	case f.Flags == ast.PropertyFlagBoolMarshal:
+		accessor = "Uint8"
+
+		if f.Type != nil && accessors[f.Type.Value] != "" {
+			accessor = accessors[f.Type.Value]
+		}
+
+		if strings.HasPrefix(accessor, "Big") {
+			write, read = value+" ? 1n : 0n", "%s !== 0n"
+		} else {
+			write, read = value+" ? 1 : 0", "%s !== 0"
+		}
+	case f.Flags == ast.PropertyFlagProtoMask || f.Flags == ast.PropertyFlagProtoMaskGC:
+		accessor = "Uint32"
+		write = fmt.Sprintf("(%s | %#x) >>> 0", value, ast.ProtoMask)
+		read = fmt.Sprintf("%%s & %#x", ^uint32(ast.ProtoMask))
+
+		if enum != nil {
+			read = "(" + read + ") as " + enum.Name()
+		}
+	case enum != nil:
+		accessor = accessors[enum.Type.String()]
+		read = "%s as " + enum.Name()
+
+		if strings.HasPrefix(accessor, "Big") {
+			write, read = "BigInt("+value+")", "Number(%s) as "+enum.Name()
+		}
+	case f.Type != nil:
+		accessor = accessors[f.Type.Value]
+
+		if accessor == "" {
+			return nil, nil, fmt.Errorf("Cannot serialize %s of type %s", backend.QualifiedName(f), f.Type.Value)
+		}
+
+		if f.Type.Value == "bool" {
+			write, read = value+" ? 1 : 0", "%s !== 0"
+		}
+	}
+
+	size := accessorSizes[accessor]
+	littleEndian := ", true"
+
+	if f.Endian == ast.BigEndian {
+		littleEndian = ", false"
+	}
+
+	if size == 1 {
+		littleEndian = ""
+	}
+
+	writes := []string{
+		fmt.Sprintf("view.set%s(offset, %s%s);", accessor, write, littleEndian),
+		fmt.Sprintf("offset += %d;", size),
+	}
+	reads := []string{
+		fmt.Sprintf("%s = %s;", value, fmt.Sprintf(read, fmt.Sprintf("view.get%s(offset%s)", accessor, littleEndian))),
+		fmt.Sprintf("offset += %d;", size),
+	}
+
+	return writes, reads, nil
+}
+
+var accessorSizes = map[string]int{
+	"Uint8":     1,
+	"Int8":      1,
+	"Int16":     2,
+	"Uint16":    2,
+	"Int32":     4,
+	"Uint32":    4,
+	"BigInt64":  8,
+	"BigUint64": 8,
+	"Float32":   4,
+	"Float64":   8,
+}
+
+func indentLines(indent int, lines []string) string {
+	prefix := strings.Repeat("  ", indent)
+	return prefix + strings.Join(lines, "\n"+prefix)
+}
+
+// formatValue renders v, as produced by ast.Evaluate, as a signed or unsigned
+// decimal.
+func formatValue(v uint64, signed bool) string {
+	if signed {
+		return backend.FormatValue(v)
+	}
+
+	return fmt.Sprintf("%d", v)
+}
+
+// signed reports whether the values of p are signed.
+func signed(p *ast.PropertyNode) bool {
+	if enum := enumType(p); enum != nil {
+		return enum.Type.Signed()
+	}
+
+	if p.Type == nil {
+		return true
+	}
+
+	switch p.Type.Value {
+	case "byte", "ushort", "uint", "ulong":
+		return false
+	}
+
+	return p.Flags != ast.PropertyFlagSteamIDMarshal && p.Flags != ast.PropertyFlagGameIDMarshal
+}
+
+func enumType(f *ast.PropertyNode) *ast.EnumNode {
+	if f.Type == nil || f.Flags == ast.PropertyFlagBoolMarshal {
+		return nil
+	}
+
+	enum, _ := f.Type.Node.(*ast.EnumNode)
+
+	return enum
+}
+
+func classType(f *ast.PropertyNode) *ast.ClassNode {
+	if f.Type == nil || f.Flags != ast.PropertyFlagNone {
+		return nil
+	}
+
+	class, _ := f.Type.Node.(*ast.ClassNode)
+
+	return class
+}
+
+// isBytes reports whether f is a byte array, generated as a Uint8Array.
+func isBytes(f *ast.PropertyNode) bool {
+	_, ok, _ := backend.FixedArraySize(f)
+	return ok && f.Flags == ast.PropertyFlagNone && f.Type != nil && f.Type.Value == "byte"
+}
+
+// doc writes the JSDoc comment of a declaration, flagging deprecated members.
+func (g *generator) doc(indent int, doc string, member ast.Node) {
+	var lines []string
+
+	if doc != "" {
+		lines = strings.Split(strings.Replace(doc, "*/", "* /", -1), "\n")
+	}
+
+	if member != nil {
+		if reason, ok := backend.Deprecation(member); ok {
+			lines = append(lines, "@deprecated "+reason)
+		}
+	}
+
+	switch len(lines) {
+	case 0:
+	case 1:
+		g.printf(indent, "/** %s */\n", lines[0])
+	default:
+		g.printf(indent, "/**\n")
+
+		for _, line := range lines {
+			g.printf(indent, " * %s\n", line)
+		}
+
+		g.printf(indent, " */\n")
+	}
+}
+
+func lineComment(comment string) string {
+	if comment == "" {
+		return ""
+	}
+
+	return " // " + comment
+}
@@ -0,0 +1,100 @@
+package typescript
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/13k/go-steam-language/backend"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+const schema = `/// Message types.
+enum EMsg { Invalid = 0; Multi = 1; };
+enum EFlags<byte> flags { A = 1; B = 2; AB = A | B; Old = 4; removed };
+enum EOther<short> { X = 5; Y = 7; };
+class MsgHdr<EMsg::Multi> {
+	const uint SIZE = 4;
+	const long BIG = 1 << 40;
+	steamidmarshal ulong steamID;
+	boolmarshal byte valid = 1;
+	protomask EMsg msg = EMsg::Multi;
+	EFlags flags = EFlags::AB;
+	uint mask = MsgHdr::SIZE | EFlags::A;
+	byte<20> key;
+	EOther<MsgHdr::SIZE> others;
+};
+class MsgWrapped { MsgHdr header; uint size; };
+`
+
+func generate(t *testing.T, opts backend.Options) string {
+	doc, err := parse.NewAnalyzer(token.NewTokenizer([]byte(schema)), "schemas/emsg.steamd").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	gen, ok := backend.Lookup("typescript")
+
+	if !ok {
+		t.Fatalf("expected typescript backend to be registered")
+	}
+
+	files, err := gen.Generate(doc, opts)
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if len(files) != 1 || files[0].Name != "emsg.ts" {
+		t.Fatalf("mismatch: got %v, but expected emsg.ts only", files)
+	}
+
+	return string(files[0].Content)
+}
+
+func TestGenerate(t *testing.T) {
+	src := generate(t, backend.Options{Exclude: map[string]bool{"EMsg": true}})
+
+	expected := []string{
+		"/** Message types. */\nexport enum EMsg {",
+		"AB = 3,",
+		"/** @deprecated removed */\n  Old = 4,",
+		"export interface MsgHdr {",
+		"steamID: bigint;",
+		"valid: boolean;",
+		"key: Uint8Array;",
+		"others: EOther[];",
+		"export const emsg = EMsg.Multi;",
+		"export const SIZE: number = 4;",
+		"export const BIG: bigint = 1099511627776n;",
+		"export const wireSize = 46;",
+		"flags: EFlags.AB,",
+		"mask: 5,",
+		"others: new Array<EOther>(4).fill(0 as EOther),",
+		"view.setBigUint64(offset, m.steamID, true);",
+		"view.setUint8(offset, m.valid ? 1 : 0);",
+		"m.valid = view.getUint8(offset) !== 0;",
+		"view.setUint32(offset, (m.msg | 0x80000000) >>> 0, true);",
+		"m.msg = (view.getUint32(offset, true) & 0x7fffffff) as EMsg;",
+		"m.flags = view.getUint8(offset) as EFlags;",
+		"new Uint8Array(view.buffer, view.byteOffset + offset, 20).set(m.key);",
+		"m.others[i] = view.getInt16(offset, true) as EOther;",
+		"offset = MsgHdr.write(view, offset, m.header);",
+		"[m.header, offset] = MsgHdr.read(view, offset);",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(src, s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+}
+
+func TestGenerateSkipRemoved(t *testing.T) {
+	src := generate(t, backend.Options{SkipRemoved: true})
+
+	if strings.Contains(src, "Old") {
+		t.Fatalf("expected removed members to be skipped\n%s", src)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/sarif"
+	"github.com/13k/go-steam-language/token"
+)
+
+var checkCommand = &command{
+	name:  "check",
+	usage: "report the errors and warnings of schemas",
+	run:   runCheck,
+}
+
+func runCheck(args []string) error {
+	fs := flag.NewFlagSet("steamd check", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the diagnostics as JSON")
+	asSARIF := fs.Bool("sarif", false, "print the diagnostics as a SARIF log")
+	strict := fs.Bool("strict", false, "report references to undeclared names as errors")
+	placeholders := fs.Bool("placeholders", false, "report references to undeclared names as warnings")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: steamd check [-json | -sarif] [-strict | -placeholders] files...\n\n")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() == 0 || *asJSON && *asSARIF {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	diags, err := check(fs.Args(), *strict, *placeholders)
+
+	if err != nil {
+		return err
+	}
+
+	return printDiagnostics(diags, *asJSON, *asSARIF)
+}
+
+// printDiagnostics prints diags as JSON, as a SARIF log or with an excerpt
+// of their source, and fails if there are errors.
+func printDiagnostics(diags []parse.Diagnostic, asJSON, asSARIF bool) error {
+	switch {
+	case asJSON:
+		if err := parse.WriteDiagnosticsJSON(os.Stdout, diags); err != nil {
+			return err
+		}
+	case asSARIF:
+		if err := sarif.Write(os.Stdout, diags); err != nil {
+			return err
+		}
+	default:
+		sources := make(map[string][]byte)
+
+		for _, diag := range diags {
+			src, ok := sources[diag.Pos.Filename]
+
+			if !ok && diag.Pos.Filename != "" {
+				// diagnostics are still printed without their source
+				src, _ = ioutil.ReadFile(diag.Pos.Filename)
+				sources[diag.Pos.Filename] = src
+			}
+
+			if err := parse.WriteDiagnostic(os.Stdout, diag, src); err != nil {
+				return err
+			}
+		}
+	}
+
+	errs := 0
+
+	for _, diag := range diags {
+		if diag.Severity == parse.SeverityError {
+			errs++
+		}
+	}
+
+	if errs > 0 {
+		return fmt.Errorf("%d errors", errs)
+	}
+
+	return nil
+}
+
+// check analyzes files with recovery and returns their diagnostics, followed
+// by the references to placeholder symbols if asked. Files imported by several
+// of them are reported once.
+func check(files []string, strict, placeholders bool) ([]parse.Diagnostic, error) {
+	var diags []parse.Diagnostic
+	seen := make(map[parse.Diagnostic]bool)
+
+	for _, filename := range files {
+		data, err := ioutil.ReadFile(filename)
+
+		if err != nil {
+			return nil, err
+		}
+
+		analyzer := parse.NewAnalyzer(token.NewTokenizer(data), filename)
+		analyzer.SetRecovery(true)
+		analyzer.SetStrict(strict)
+		analyzer.Analyze()
+
+		fileDiags := analyzer.Diagnostics()
+
+		if placeholders {
+			for _, p := range analyzer.Placeholders() {
+				fileDiags = append(fileDiags, p.Refs...)
+			}
+		}
+
+		for _, diag := range fileDiags {
+			if !seen[diag] {
+				seen[diag] = true
+				diags = append(diags, diag)
+			}
+		}
+	}
+
+	return diags, nil
+}
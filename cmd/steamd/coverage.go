@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+var coverageCommand = &command{
+	name:  "coverage",
+	usage: "report grammar productions and tokens exercised by a corpus",
+	run:   runCoverage,
+}
+
+func runCoverage(args []string) error {
+	fs := flag.NewFlagSet("steamd coverage", flag.ExitOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: steamd coverage files...\n")
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	stats := parse.NewStats()
+
+	for _, filename := range fs.Args() {
+		data, err := ioutil.ReadFile(filename)
+
+		if err != nil {
+			return err
+		}
+
+		analyzer := parse.NewAnalyzer(token.NewTokenizer(data), filename)
+		analyzer.SetStats(stats)
+
+		if _, err := analyzer.Analyze(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
+	return stats.Report(os.Stdout)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/diff"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+var diffCommand = &command{
+	name:  "diff",
+	usage: "report the semantic and breaking changes between two versions of a schema",
+	run:   runDiff,
+}
+
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("steamd diff", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print a JSON report")
+	check := fs.Bool("check", false, "exit with status 1 if there are breaking changes")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: steamd diff [-json] [-check] old new\n\n")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var docs []*ast.DocumentNode
+
+	for _, filename := range fs.Args() {
+		data, err := ioutil.ReadFile(filename)
+
+		if err != nil {
+			return err
+		}
+
+		doc, err := parse.NewAnalyzer(token.NewTokenizer(data), filename).Analyze()
+
+		if err != nil {
+			return err
+		}
+
+		docs = append(docs, doc)
+	}
+
+	report, err := diff.Check(docs[0], docs[1])
+
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		for _, c := range report.Changes {
+			if c.Breaking {
+				fmt.Printf("%s (breaking: %s)\n", c, c.Reason)
+			} else {
+				fmt.Println(c)
+			}
+		}
+	}
+
+	if *check && !report.OK() {
+		return fmt.Errorf("%d breaking changes", report.Breaking)
+	}
+
+	return nil
+}
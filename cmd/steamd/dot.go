@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+var dotCommand = &command{
+	name:  "dot",
+	usage: "render the syntax tree or import graph of a file for Graphviz",
+	run:   runDOT,
+}
+
+func runDOT(args []string) error {
+	fs := flag.NewFlagSet("steamd dot", flag.ExitOnError)
+	imports := fs.Bool("imports", false, "render the import graph instead of the syntax tree")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: steamd dot [-imports] file\n\n")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	filename := fs.Arg(0)
+	data, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		return err
+	}
+
+	doc, err := parse.NewAnalyzer(token.NewTokenizer(data), filename).Analyze()
+
+	if err != nil {
+		return err
+	}
+
+	if *imports {
+		_, err = fmt.Print(ast.ImportGraphDOT(doc))
+	} else {
+		_, err = fmt.Print(ast.DOT(doc))
+	}
+
+	return err
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"github.com/13k/go-steam-language/parse"
+)
+
+var fixCommand = &command{
+	name:  "fix",
+	usage: "apply safe automated repairs to steamd files",
+	run:   runFix,
+}
+
+func runFix(args []string) error {
+	fs := flag.NewFlagSet("steamd fix", flag.ExitOnError)
+	write := fs.Bool("w", false, "write result to source files instead of stdout")
+	diff := fs.Bool("d", false, "display diffs instead of rewriting files")
+	verbose := fs.Bool("v", false, "list applied fixes on stderr")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: steamd fix [-w | -d] [-v] files...\n\n")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	failed := 0
+
+	for _, filename := range fs.Args() {
+		if err := fixFile(filename, *write, *diff, *verbose); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d files could not be fully repaired", failed)
+	}
+
+	return nil
+}
+
+func fixFile(filename string, write, diff, verbose bool) error {
+	data, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		return err
+	}
+
+	fixed, fixes, repairErr := parse.Repair(filename, data)
+
+	if verbose {
+		for _, f := range fixes {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", filename, f)
+		}
+	}
+
+	switch {
+	case diff:
+		if !bytes.Equal(data, fixed) {
+			out, err := diffBytes(filename, data, fixed)
+
+			if err != nil {
+				return err
+			}
+
+			os.Stdout.Write(out)
+		}
+	case write:
+		if !bytes.Equal(data, fixed) {
+			info, err := os.Stat(filename)
+
+			if err != nil {
+				return err
+			}
+
+			if err := ioutil.WriteFile(filename, fixed, info.Mode().Perm()); err != nil {
+				return err
+			}
+		}
+	default:
+		os.Stdout.Write(fixed)
+	}
+
+	return repairErr
+}
+
+func diffBytes(filename string, a, b []byte) ([]byte, error) {
+	fa, err := writeTempFile("steamd", a)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.Remove(fa)
+
+	fb, err := writeTempFile("steamd", b)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.Remove(fb)
+
+	out, err := exec.Command("diff", "-u", "--label", filename+".orig", "--label", filename, fa, fb).Output()
+
+	// diff exits with 1 when the inputs differ
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		err = nil
+	}
+
+	return out, err
+}
+
+func writeTempFile(prefix string, data []byte) (string, error) {
+	f, err := ioutil.TempFile("", prefix)
+
+	if err != nil {
+		return "", err
+	}
+
+	_, err = f.Write(data)
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), nil
+}
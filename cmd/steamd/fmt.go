@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/13k/go-steam-language/format"
+)
+
+var fmtCommand = &command{
+	name:  "fmt",
+	usage: "reformat steamd files in canonical style",
+	run:   runFmt,
+}
+
+func runFmt(args []string) error {
+	fs := flag.NewFlagSet("steamd fmt", flag.ExitOnError)
+	write := fs.Bool("w", false, "write result to source files instead of stdout")
+	diff := fs.Bool("d", false, "display diffs instead of rewriting files")
+	list := fs.Bool("l", false, "list files whose formatting differs")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: steamd fmt [-w | -d | -l] files...\n\n")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	failed := 0
+
+	for _, filename := range fs.Args() {
+		if err := fmtFile(filename, *write, *diff, *list); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d files could not be formatted", failed)
+	}
+
+	return nil
+}
+
+func fmtFile(filename string, write, diff, list bool) error {
+	data, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		return err
+	}
+
+	formatted, err := format.Source(filename, data)
+
+	if err != nil {
+		return err
+	}
+
+	changed := !bytes.Equal(data, formatted)
+
+	if list && changed {
+		fmt.Println(filename)
+	}
+
+	switch {
+	case diff:
+		if changed {
+			out, err := diffBytes(filename, data, formatted)
+
+			if err != nil {
+				return err
+			}
+
+			os.Stdout.Write(out)
+		}
+	case write:
+		if changed {
+			info, err := os.Stat(filename)
+
+			if err != nil {
+				return err
+			}
+
+			return ioutil.WriteFile(filename, formatted, info.Mode().Perm())
+		}
+	case !list:
+		os.Stdout.Write(formatted)
+	}
+
+	return nil
+}
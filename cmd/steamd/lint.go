@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/13k/go-steam-language/lint"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+var lintCommand = &command{
+	name:  "lint",
+	usage: "report likely mistakes and style issues in schemas",
+	run:   runLint,
+}
+
+func runLint(args []string) error {
+	fs := flag.NewFlagSet("steamd lint", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the diagnostics as JSON")
+	asSARIF := fs.Bool("sarif", false, "print the diagnostics as a SARIF log")
+	strict := fs.Bool("strict", false, "report some issues, like duplicate enum values, as errors")
+	ruleNames := fs.String("rules", "", "comma-separated rules to run instead of all of them")
+	list := fs.Bool("list", false, "list the rules and exit")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: steamd lint [-json | -sarif] [-strict] [-rules names] files...\n       steamd lint -list\n\n")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+
+	if *list {
+		for _, r := range lint.Rules() {
+			fmt.Printf("%-16s %s\n", r.Name(), r.Doc())
+		}
+
+		return nil
+	}
+
+	if fs.NArg() == 0 || *asJSON && *asSARIF {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	l := &lint.Linter{Strict: *strict}
+
+	if *ruleNames != "" {
+		rules, err := lint.Select(strings.Split(*ruleNames, ","))
+
+		if err != nil {
+			return err
+		}
+
+		l.Rules = rules
+	}
+
+	var diags []parse.Diagnostic
+
+	for _, filename := range fs.Args() {
+		data, err := ioutil.ReadFile(filename)
+
+		if err != nil {
+			return err
+		}
+
+		doc, err := parse.NewAnalyzer(token.NewTokenizer(data), filename).Analyze()
+
+		if err != nil {
+			return err
+		}
+
+		fileDiags, err := l.Lint(doc)
+
+		if err != nil {
+			return err
+		}
+
+		diags = append(diags, fileDiags...)
+	}
+
+	return printDiagnostics(diags, *asJSON, *asSARIF)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/13k/go-steam-language/lsp"
+)
+
+var lspCommand = &command{
+	name:  "lsp",
+	usage: "run a language server over stdin and stdout",
+	run:   runLSP,
+}
+
+func runLSP(args []string) error {
+	fs := flag.NewFlagSet("steamd lsp", flag.ExitOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: steamd lsp\n\n")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	return lsp.NewServer(os.Stdin, os.Stdout).Run()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []*command{
+	checkCommand,
+	coverageCommand,
+	diffCommand,
+	dotCommand,
+	fixCommand,
+	fmtCommand,
+	lintCommand,
+	lspCommand,
+	manifestCommand,
+	staleCommand,
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: steamd <command> [arguments]\n\ncommands:\n")
+
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.usage)
+	}
+
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	name := os.Args[1]
+
+	for _, cmd := range commands {
+		if cmd.name == name {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "steamd %s: %v\n", name, err)
+				os.Exit(1)
+			}
+
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "steamd: unknown command %q\n", name)
+	usage()
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/13k/go-steam-language/parse"
+)
+
+var manifestCommand = &command{
+	name:  "manifest",
+	usage: "record or verify provenance of vendored steamd files",
+	run:   runManifest,
+}
+
+func runManifest(args []string) error {
+	fs := flag.NewFlagSet("steamd manifest", flag.ExitOnError)
+	output := fs.String("m", "steamd.manifest.json", "manifest file")
+	verify := fs.Bool("verify", false, "verify files against the manifest instead of recording it")
+	upstream := fs.String("upstream", "https://github.com/SteamRE/SteamKit", "upstream repository")
+	commit := fs.String("commit", "", "upstream commit the files were taken from")
+	license := fs.String("license", "LGPL-2.1", "upstream license")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: steamd manifest [-m file] -commit sha [-upstream url] [-license id] files...\n")
+		fmt.Fprintf(os.Stderr, "       steamd manifest -verify [-m file]\n\n")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+
+	if *verify {
+		return verifyManifest(*output)
+	}
+
+	if *commit == "" || fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	m := parse.NewManifest(filepath.Dir(*output), *upstream, *commit, *license)
+
+	for _, filename := range fs.Args() {
+		data, err := ioutil.ReadFile(filename)
+
+		if err != nil {
+			return err
+		}
+
+		if err := m.Record(filename, data); err != nil {
+			return err
+		}
+	}
+
+	return m.Save(*output)
+}
+
+func verifyManifest(filename string) error {
+	m, err := parse.LoadManifest(filename)
+
+	if err != nil {
+		return err
+	}
+
+	modified := 0
+
+	for _, p := range m.Paths() {
+		path := filepath.Join(m.Root(), filepath.FromSlash(p))
+		data, err := ioutil.ReadFile(path)
+
+		if err != nil {
+			return err
+		}
+
+		if err := m.Verify(path, data); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+			modified++
+		}
+	}
+
+	if modified > 0 {
+		return fmt.Errorf("%d of %d files differ from %s@%s", modified, len(m.Files), m.Upstream, m.Commit)
+	}
+
+	return nil
+}
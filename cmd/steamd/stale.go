@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/stale"
+	"github.com/13k/go-steam-language/token"
+)
+
+var staleCommand = &command{
+	name:  "stale",
+	usage: "report generated Go and C# code that doesn't match the schemas anymore",
+	run:   runStale,
+}
+
+// pathList is a flag collecting the values of each occurrence.
+type pathList []string
+
+func (l *pathList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *pathList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func runStale(args []string) error {
+	fs := flag.NewFlagSet("steamd stale", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the issues as JSON")
+
+	var code pathList
+
+	fs.Var(&code, "code", "generated Go or C# file, or directory searched for them, may be repeated")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: steamd stale [-json] -code path... files...\n\n")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() == 0 || len(code) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var docs []*ast.DocumentNode
+
+	for _, filename := range fs.Args() {
+		data, err := ioutil.ReadFile(filename)
+
+		if err != nil {
+			return err
+		}
+
+		doc, err := parse.NewAnalyzer(token.NewTokenizer(data), filename).Analyze()
+
+		if err != nil {
+			return err
+		}
+
+		docs = append(docs, doc)
+	}
+
+	files, err := generatedFiles(code)
+
+	if err != nil {
+		return err
+	}
+
+	issues := []*stale.Issue{}
+
+	for _, filename := range files {
+		lang, ok := stale.LanguageOf(filename)
+
+		if !ok {
+			return fmt.Errorf("%s: Unknown language, expected a .go or .cs file", filename)
+		}
+
+		src, err := ioutil.ReadFile(filename)
+
+		if err != nil {
+			return err
+		}
+
+		fileIssues, err := stale.Check(lang, filename, src, docs...)
+
+		if err != nil {
+			return fmt.Errorf("%s: %v", filename, err)
+		}
+
+		issues = append(issues, fileIssues...)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+
+		if err := enc.Encode(issues); err != nil {
+			return err
+		}
+	} else {
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+	}
+
+	if len(issues) > 0 {
+		return fmt.Errorf("%d stale declarations", len(issues))
+	}
+
+	return nil
+}
+
+// generatedFiles returns the files of paths, and the Go and C# files found in
+// the directories of paths.
+func generatedFiles(paths []string) ([]string, error) {
+	var files []string
+
+	for _, p := range paths {
+		err := filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() {
+				return nil
+			}
+
+			// files given explicitly are checked whatever their name
+			if _, ok := stale.LanguageOf(path); ok || path == p {
+				files = append(files, path)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strings"
+)
+
+// depfileEscaper escapes filenames the way Make and ninja read them.
+var depfileEscaper = strings.NewReplacer(" ", `\ `, "#", `\#`, "$", "$$")
+
+// writeDepfile writes a Make-style rule for each output, listing the schemas
+// it was generated from.
+func writeDepfile(filename string, deps map[string][]string) error {
+	f, err := os.Create(filename)
+
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	outputs := make([]string, 0, len(deps))
+
+	for output := range deps {
+		outputs = append(outputs, output)
+	}
+
+	sort.Strings(outputs)
+
+	for _, output := range outputs {
+		w.WriteString(depfileEscaper.Replace(output) + ":")
+
+		for _, dep := range deps[output] {
+			w.WriteString(" \\\n  " + depfileEscaper.Replace(dep))
+		}
+
+		w.WriteString("\n")
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
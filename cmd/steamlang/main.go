@@ -0,0 +1,463 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/backend"
+	_ "github.com/13k/go-steam-language/backend/csharp"
+	_ "github.com/13k/go-steam-language/backend/jsonschema"
+	_ "github.com/13k/go-steam-language/backend/kaitai"
+	_ "github.com/13k/go-steam-language/backend/proto"
+	_ "github.com/13k/go-steam-language/backend/python"
+	_ "github.com/13k/go-steam-language/backend/rust"
+	_ "github.com/13k/go-steam-language/backend/typescript"
+	_ "github.com/13k/go-steam-language/generator"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+	"github.com/13k/go-steam-language/watch"
+)
+
+// stringList is a flag collecting the values of each occurrence.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+var includePath, params, outputNames stringList
+
+var (
+	outputDir    = flag.String("o", ".", "output directory")
+	genName      = flag.String("gen", "go", "backend generating the code, one of "+strings.Join(backend.Names(), ", "))
+	packageName  = flag.String("package", "steamlang", "package, namespace or module of the generated code")
+	skipRemoved  = flag.Bool("skip-removed", false, "omit members marked removed")
+	embedSource  = flag.Bool("embed-source", false, "comment generated types and members with their steamd declaration")
+	steamIDType  = flag.String("steamid-type", "", "Go type of steamidmarshal fields (default uint64)")
+	gameIDType   = flag.String("gameid-type", "", "Go type of gameidmarshal fields (default uint64)")
+	imports      = flag.String("imports", "", "comma-separated packages imported by the generated code")
+	workers      = flag.Int("j", runtime.NumCPU(), "number of imported files analyzed concurrently")
+	cacheDir     = flag.String("cache", "", "directory caching analyzed files across runs")
+	depfile      = flag.String("depfile", "", "write a Make-style depfile listing the schemas each output depends on")
+	watchMode    = flag.Bool("watch", false, "regenerate whenever the inputs or their imports change")
+	incremental  = flag.Bool("incremental", false, "only regenerate outputs whose inputs or options changed, tracked in "+outputManifestName)
+	templateFile = flag.String("template", "", "text/template generating each output instead of the built-in code")
+	pluginMode   = flag.Bool("plugin", false, "read a generation request from stdin and write the generated files to stdout")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: steamlang [-o dir] [-package name] [-I dir] [-skip-removed] files...\n       steamlang -plugin < request.json > response.json\n\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Var(&includePath, "I", "directory searched for imports, may be repeated")
+	flag.Var(&params, "param", "key=value passed to the backend, may be repeated")
+	flag.Var(&outputNames, "output-name", "[backend=]template naming the generated files, like {{.SourceBase}}_gen.go, may be repeated")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *pluginMode {
+		if err := runPlugin(context.Background(), os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "steamlang: %v\n", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if flag.NArg() == 0 {
+		usage()
+	}
+
+	if *watchMode {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		inputs := flag.Args()
+
+		if *templateFile != "" {
+			inputs = append(inputs, *templateFile)
+		}
+
+		err := watch.Watch(ctx, inputs, func(ctx context.Context) ([]*ast.DocumentNode, error) {
+			return run(ctx, flag.Args())
+		}, report)
+
+		if err != nil && !errors.Is(err, context.Canceled) {
+			fmt.Fprintf(os.Stderr, "steamlang: %v\n", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if _, err := run(context.Background(), flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "steamlang: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// report prints the outcome of each build in watch mode.
+func report(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "steamlang: %v\n", err)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "steamlang: generated into %s\n", *outputDir)
+}
+
+// options configures generation, from the command line or a plugin request.
+type options struct {
+	// Gen is the name of the backend, "go" when empty.
+	Gen         string   `json:"gen"`
+	Package     string   `json:"package"`
+	SkipRemoved bool     `json:"skip_removed"`
+	EmbedSource bool     `json:"embed_source"`
+	SteamIDType string   `json:"steamid_type"`
+	GameIDType  string   `json:"gameid_type"`
+	Imports     []string `json:"imports"`
+	IncludePath []string `json:"include_path"`
+	// Template is the file of a text/template replacing the built-in code.
+	Template string `json:"template"`
+	// OutputNames are text/templates naming the generated files, executed
+	// with a nameData, by backend. The one of the empty name applies to the
+	// backends without one.
+	OutputNames map[string]string `json:"output_names"`
+	// Params are passed to the backend, along with the options above it
+	// takes as params.
+	Params map[string]string `json:"params"`
+}
+
+func flagOptions() (*options, error) {
+	opts := &options{
+		Gen:         *genName,
+		Package:     *packageName,
+		SkipRemoved: *skipRemoved,
+		EmbedSource: *embedSource,
+		SteamIDType: *steamIDType,
+		GameIDType:  *gameIDType,
+		IncludePath: includePath,
+		Template:    *templateFile,
+		OutputNames: make(map[string]string),
+		Params:      make(map[string]string),
+	}
+
+	for _, name := range outputNames {
+		gen := ""
+
+		if i := strings.Index(name, "="); i > 0 {
+			if _, ok := backend.Lookup(name[:i]); ok {
+				gen, name = name[:i], name[i+1:]
+			}
+		}
+
+		opts.OutputNames[gen] = name
+	}
+
+	if *imports != "" {
+		opts.Imports = strings.Split(*imports, ",")
+	}
+
+	for _, param := range params {
+		i := strings.Index(param, "=")
+
+		if i < 0 {
+			return nil, fmt.Errorf("Invalid param %q, expected key=value", param)
+		}
+
+		opts.Params[param[:i]] = param[i+1:]
+	}
+
+	return opts, nil
+}
+
+// params returns the params of the backend, except for the template which is
+// read by generate.
+func (o *options) params() map[string]string {
+	params := make(map[string]string)
+
+	for k, v := range o.Params {
+		params[k] = v
+	}
+
+	if o.SteamIDType != "" {
+		params["steamid_type"] = o.SteamIDType
+	}
+
+	if o.GameIDType != "" {
+		params["gameid_type"] = o.GameIDType
+	}
+
+	if len(o.Imports) > 0 {
+		params["imports"] = strings.Join(o.Imports, ",")
+	}
+
+	if o.EmbedSource {
+		params["embed_source"] = "true"
+	}
+
+	return params
+}
+
+// String identifies the options affecting the generated code.
+func (o *options) String() string {
+	params := o.params()
+	keys := make([]string, 0, len(params))
+
+	for k := range params {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	s := fmt.Sprintf("gen=%s package=%s skip-removed=%v", o.Gen, o.Package, o.SkipRemoved)
+
+	for _, k := range keys {
+		s += fmt.Sprintf(" %s=%s", k, params[k])
+	}
+
+	if text := o.outputName(); text != "" {
+		s += fmt.Sprintf(" output-name=%s", text)
+	}
+
+	return s
+}
+
+// outputName returns the output name template of the backend, empty if the
+// files keep the names the backend gives them.
+func (o *options) outputName() string {
+	gen := o.Gen
+
+	if gen == "" {
+		gen = "go"
+	}
+
+	if text, ok := o.OutputNames[gen]; ok {
+		return text
+	}
+
+	return o.OutputNames[""]
+}
+
+// output is what an input generated.
+type output struct {
+	Input string
+	// Sources maps every file contributing to the output to its hash.
+	Sources map[string]string
+	// Files are the generated files, unless Skipped.
+	Files   []backend.OutputFile
+	Skipped bool
+}
+
+// generate analyzes each of inputs, read with read and configured with setup,
+// and generates its files unless skip returns true. It returns the documents
+// analyzed, the ones failing included.
+func generate(ctx context.Context, inputs []string, opts *options, read func(string) ([]byte, error), setup func(*parse.Analyzer), skip func(*output) bool) ([]*ast.DocumentNode, []*output, error) {
+	name := opts.Gen
+
+	if name == "" {
+		name = "go"
+	}
+
+	gen, ok := backend.Lookup(name)
+
+	if !ok {
+		return nil, nil, fmt.Errorf("Unknown backend %q, expected one of %s", name, strings.Join(backend.Names(), ", "))
+	}
+
+	namer, err := newOutputNamer(name, opts.Package, opts.outputName())
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bopts := backend.Options{
+		Package:     opts.Package,
+		SkipRemoved: opts.SkipRemoved,
+		Params:      opts.params(),
+		Exclude:     make(map[string]bool),
+	}
+
+	// the template is an input of every output
+	deps := make(map[string]string)
+
+	if opts.Template != "" {
+		text, err := read(opts.Template)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		bopts.Params["template"] = string(text)
+		deps[opts.Template] = hashData(text)
+	}
+
+	var (
+		docs    []*ast.DocumentNode
+		outputs []*output
+	)
+
+	for _, input := range inputs {
+		data, err := read(input)
+
+		if err != nil {
+			return docs, outputs, err
+		}
+
+		analyzer := parse.NewAnalyzer(token.NewTokenizer(data), input)
+		analyzer.SetRecovery(true)
+		analyzer.SetIncludePath(opts.IncludePath)
+		setup(analyzer)
+		root, err := analyzer.AnalyzeContext(ctx)
+
+		if root != nil {
+			docs = append(docs, root)
+		}
+
+		if err != nil {
+			return docs, outputs, err
+		}
+
+		addInputs(deps, root)
+		out := &output{Input: input, Sources: make(map[string]string)}
+
+		for dep, hash := range deps {
+			out.Sources[dep] = hash
+		}
+
+		outputs = append(outputs, out)
+		out.Skipped = skip != nil && skip(out)
+
+		if !out.Skipped {
+			if out.Files, err = gen.Generate(root, bopts); err != nil {
+				return docs, outputs, fmt.Errorf("%s: %v", input, err)
+			}
+
+			// files that can't be named aren't written
+			if err := namer.rename(input, out.Files); err != nil {
+				out.Files = nil
+				return docs, outputs, err
+			}
+		}
+
+		// declarations pulled in through #import are generated once, in the
+		// output of the first input that contains them
+		for _, child := range root.Types() {
+			bopts.Exclude[child.Name()] = true
+		}
+	}
+
+	return docs, outputs, nil
+}
+
+// run generates the outputs of inputs and returns the documents analyzed, the
+// ones failing included.
+func run(ctx context.Context, inputs []string) ([]*ast.DocumentNode, error) {
+	if err := os.MkdirAll(*outputDir, 0755); err != nil {
+		return nil, err
+	}
+
+	var cache *parse.Cache
+
+	if *cacheDir != "" {
+		cache = parse.NewCache(*cacheDir)
+	}
+
+	opts, err := flagOptions()
+
+	if err != nil {
+		return nil, err
+	}
+
+	manifestFile := filepath.Join(*outputDir, outputManifestName)
+
+	var manifest *outputManifest
+
+	if *incremental {
+		manifest = loadOutputManifest(manifestFile)
+	}
+
+	updated := newOutputManifest()
+
+	setup := func(analyzer *parse.Analyzer) {
+		analyzer.SetWorkers(*workers)
+		analyzer.SetCache(cache)
+	}
+
+	skip := func(out *output) bool {
+		rec := &outputRecord{Options: opts.String(), Sources: out.Sources}
+		return manifest != nil && manifest.upToDate(out.Input, *outputDir, rec)
+	}
+
+	docs, outputs, genErr := generate(ctx, inputs, opts, ioutil.ReadFile, setup, skip)
+	depRules := make(map[string][]string)
+
+	// outputs generated before a failure are still written
+	for _, out := range outputs {
+		rec := &outputRecord{Options: opts.String(), Sources: out.Sources, Outputs: make(map[string]string)}
+
+		if out.Skipped {
+			rec = manifest.Inputs[out.Input]
+		}
+
+		for _, file := range out.Files {
+			filename := filepath.Join(*outputDir, filepath.FromSlash(file.Name))
+
+			if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+				return docs, err
+			}
+
+			if err := ioutil.WriteFile(filename, file.Content, 0644); err != nil {
+				return docs, err
+			}
+
+			rec.Outputs[file.Name] = hashData(file.Content)
+		}
+
+		for name := range rec.Outputs {
+			filename := filepath.Join(*outputDir, filepath.FromSlash(name))
+
+			for dep := range out.Sources {
+				depRules[filename] = append(depRules[filename], dep)
+			}
+
+			sort.Strings(depRules[filename])
+		}
+
+		updated.Inputs[out.Input] = rec
+	}
+
+	if genErr != nil {
+		return docs, genErr
+	}
+
+	if *depfile != "" {
+		if err := writeDepfile(*depfile, depRules); err != nil {
+			return docs, err
+		}
+	}
+
+	if manifest != nil {
+		return docs, updated.save(manifestFile)
+	}
+
+	return docs, nil
+}
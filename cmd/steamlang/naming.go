@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/13k/go-steam-language/backend"
+)
+
+// nameData is what output name templates are executed with. Name is the name
+// the backend gives the file, and Base and Ext its parts. There's no field per
+// declaration, as files hold the declarations the backend puts together and
+// most backends can't generate one alone, referencing the others unimported.
+type nameData struct {
+	Backend    string
+	Package    string
+	Source     string
+	SourceBase string
+	SourceDir  string
+	Name       string
+	Base       string
+	Ext        string
+}
+
+// outputNamer renames the files generated by a backend with a template, and
+// detects files given the same name.
+type outputNamer struct {
+	backend string
+	pkg     string
+	tmpl    *template.Template
+	// seen maps the names given so far to the inputs generating them.
+	seen map[string]string
+}
+
+// newOutputNamer parses text, the output name template of the backend gen
+// generating the package pkg. Without a template, files keep the names their
+// backend gives them.
+func newOutputNamer(gen, pkg, text string) (*outputNamer, error) {
+	n := &outputNamer{backend: gen, pkg: pkg, seen: make(map[string]string)}
+
+	if text != "" {
+		tmpl, err := template.New("output-name").Option("missingkey=error").Parse(text)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid output name template: %v", err)
+		}
+
+		n.tmpl = tmpl
+	}
+
+	return n, nil
+}
+
+// rename names files, generated from input, and fails if a name is taken by
+// another file.
+func (n *outputNamer) rename(input string, files []backend.OutputFile) error {
+	for i := range files {
+		if n.tmpl != nil {
+			name, err := n.name(input, files[i].Name)
+
+			if err != nil {
+				return err
+			}
+
+			files[i].Name = name
+		}
+
+		if prev, ok := n.seen[files[i].Name]; ok {
+			return fmt.Errorf("Output %s of %s collides with the one of %s", files[i].Name, input, prev)
+		}
+
+		n.seen[files[i].Name] = input
+	}
+
+	return nil
+}
+
+func (n *outputNamer) name(input, name string) (string, error) {
+	source := filepath.ToSlash(input)
+	sourceBase := path.Base(source)
+	ext := path.Ext(name)
+
+	data := &nameData{
+		Backend:    n.backend,
+		Package:    n.pkg,
+		Source:     source,
+		SourceBase: strings.TrimSuffix(sourceBase, path.Ext(sourceBase)),
+		SourceDir:  path.Dir(source),
+		Name:       name,
+		Base:       strings.TrimSuffix(name, ext),
+		Ext:        ext,
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := n.tmpl.Execute(buf, data); err != nil {
+		return "", fmt.Errorf("Cannot name output %s of %s: %v", name, input, err)
+	}
+
+	result := path.Clean(strings.TrimSpace(buf.String()))
+
+	if result == "." || path.IsAbs(result) || strings.HasPrefix(result, "../") || result == ".." {
+		return "", fmt.Errorf("Invalid name %q for output %s of %s", buf.String(), name, input)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRunPluginOutputNames(t *testing.T) {
+	run := func(inputs, options string) *pluginResponse {
+		req := `{
+			"files": {
+				"schemas/emsg.steamd": "enum EMsg { Multi = 1; };",
+				"schemas/base.steamd": "enum EResult { OK = 1; };",
+				"schemas/msg.steamd": "class MsgHdr { uint size; };"
+			},
+			"inputs": ` + inputs + `,
+			"options": ` + options + `
+		}`
+
+		out := &bytes.Buffer{}
+
+		if err := runPlugin(context.Background(), strings.NewReader(req), out); err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		resp := &pluginResponse{}
+
+		if err := json.Unmarshal(out.Bytes(), resp); err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		return resp
+	}
+
+	names := func(resp *pluginResponse) string {
+		var names []string
+
+		for _, file := range resp.Files {
+			names = append(names, file.Name)
+		}
+
+		return strings.Join(names, " ")
+	}
+
+	tests := []struct {
+		inputs   string
+		options  string
+		expected string
+		err      string
+	}{
+		{`["schemas/emsg.steamd"]`, `{"output_names": {"": "{{.SourceDir}}/{{.SourceBase}}_gen{{.Ext}}"}}`, "schemas/emsg_gen.go", ""},
+		{`["schemas/emsg.steamd"]`, `{"output_names": {"": "{{.Name}}.txt", "go": "gen/{{.Base}}.go"}}`, "gen/emsg.go", ""},
+		{`["schemas/msg.steamd"]`, `{"gen": "kaitai", "output_names": {"kaitai": "types/{{.Base}}.ksy"}}`, "types/msg_hdr.ksy", ""},
+		{`["schemas/emsg.steamd", "schemas/base.steamd"]`, `{"output_names": {"": "{{.Package}}.go"}}`, "steamlang.go", "Output steamlang.go of schemas/base.steamd collides with the one of schemas/emsg.steamd"},
+		{`["schemas/emsg.steamd"]`, `{"output_names": {"": "../{{.Name}}"}}`, "", `Invalid name "../emsg.go" for output emsg.go of schemas/emsg.steamd`},
+		{`["schemas/emsg.steamd"]`, `{"output_names": {"": "{{.Missing}}"}}`, "", "Cannot name output emsg.go of schemas/emsg.steamd"},
+	}
+
+	for _, test := range tests {
+		resp := run(test.inputs, test.options)
+
+		if names(resp) != test.expected || !strings.HasPrefix(resp.Error, test.err) || (test.err == "") != (resp.Error == "") {
+			t.Fatalf("mismatch: got %q %q, but expected %q %q", names(resp), resp.Error, test.expected, test.err)
+		}
+	}
+
+	if resp := run(`["schemas/emsg.steamd"]`, `{"output_names": {"": "{{"}}`); !strings.HasPrefix(resp.Error, "Invalid output name template") {
+		t.Fatalf("expected invalid template error, got %q", resp.Error)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/13k/go-steam-language/ast"
+)
+
+// outputManifestName is the file, in the output directory, recording what
+// each output was generated from.
+const outputManifestName = ".steamlang.json"
+
+// outputManifestVersion is bumped whenever the generated code or the manifest
+// changes, so that upgrading regenerates every output.
+const outputManifestVersion = 2
+
+type outputManifest struct {
+	Version int                      `json:"version"`
+	Inputs  map[string]*outputRecord `json:"inputs"`
+}
+
+// outputRecord is what the outputs of an input were generated from: the
+// options and the SHA-256 of every schema contributing to them, including the
+// inputs preceding it on the command line since their declarations aren't
+// generated again. Outputs maps the files generated to their SHA-256, so that
+// edited outputs are regenerated as well.
+type outputRecord struct {
+	Options string            `json:"options"`
+	Sources map[string]string `json:"sources"`
+	Outputs map[string]string `json:"outputs"`
+}
+
+func newOutputManifest() *outputManifest {
+	return &outputManifest{
+		Version: outputManifestVersion,
+		Inputs:  make(map[string]*outputRecord),
+	}
+}
+
+// loadOutputManifest returns an empty manifest if filename is missing, invalid
+// or from another version.
+func loadOutputManifest(filename string) *outputManifest {
+	m := newOutputManifest()
+	data, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		return m
+	}
+
+	if err := json.Unmarshal(data, m); err != nil || m.Version != outputManifestVersion || m.Inputs == nil {
+		return newOutputManifest()
+	}
+
+	return m
+}
+
+func (m *outputManifest) save(filename string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// upToDate reports whether the outputs of input, in dir, were generated from
+// the same options and sources as rec and haven't changed since.
+func (m *outputManifest) upToDate(input, dir string, rec *outputRecord) bool {
+	old, ok := m.Inputs[input]
+
+	if !ok || old.Options != rec.Options || len(old.Sources) != len(rec.Sources) {
+		return false
+	}
+
+	for source, hash := range rec.Sources {
+		if old.Sources[source] != hash {
+			return false
+		}
+	}
+
+	for name, hash := range old.Outputs {
+		data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+
+		if err != nil || hashData(data) != hash {
+			return false
+		}
+	}
+
+	return true
+}
+
+// addInputs records the source hash of doc and of the files it imports.
+func addInputs(inputs map[string]string, doc *ast.DocumentNode) {
+	inputs[doc.Filename] = doc.Hash
+
+	for _, dep := range doc.Dependencies() {
+		inputs[dep.Filename] = dep.Hash
+	}
+}
+
+func hashData(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
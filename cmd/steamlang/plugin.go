@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"runtime"
+
+	"github.com/13k/go-steam-language/parse"
+)
+
+// pluginRequest is read from stdin in plugin mode. Files maps slash-separated
+// names to the contents of every schema that may be read, imports included;
+// Inputs are the names of the ones to generate, in order.
+type pluginRequest struct {
+	Files   map[string]string `json:"files"`
+	Inputs  []string          `json:"inputs"`
+	Options options           `json:"options"`
+}
+
+// pluginResponse is written to stdout in plugin mode. Error reports invalid
+// schemas, in which case Files holds the outputs generated before the failure.
+type pluginResponse struct {
+	Files []pluginFile `json:"files"`
+	Error string       `json:"error,omitempty"`
+}
+
+type pluginFile struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// requestFiles resolves imports from the files of a plugin request, the way
+// FSResolver does from a filesystem.
+type requestFiles map[string]string
+
+func (f requestFiles) Resolve(fromFile, importPath string) ([]byte, string, error) {
+	name := path.Join(path.Dir(fromFile), importPath)
+
+	if path.IsAbs(importPath) || !fs.ValidPath(name) {
+		return nil, "", fmt.Errorf("%w: %q is outside of the request", parse.ErrImportEscape, importPath)
+	}
+
+	data, ok := f[name]
+
+	if !ok {
+		return nil, "", &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return []byte(data), name, nil
+}
+
+func (f requestFiles) read(name string) ([]byte, error) {
+	data, ok := f[name]
+
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return []byte(data), nil
+}
+
+// runPlugin answers the request read from r on w, never touching the
+// filesystem. Failing to generate is reported in the response, only protocol
+// errors are returned.
+func runPlugin(ctx context.Context, r io.Reader, w io.Writer) error {
+	req := &pluginRequest{Options: options{Package: "steamlang"}}
+
+	if err := json.NewDecoder(r).Decode(req); err != nil {
+		return fmt.Errorf("Invalid plugin request: %v", err)
+	}
+
+	files := requestFiles(req.Files)
+
+	setup := func(analyzer *parse.Analyzer) {
+		analyzer.SetResolver(files)
+		analyzer.SetWorkers(runtime.NumCPU())
+	}
+
+	_, outputs, err := generate(ctx, req.Inputs, &req.Options, files.read, setup, nil)
+	resp := &pluginResponse{Files: []pluginFile{}}
+
+	for _, out := range outputs {
+		for _, file := range out.Files {
+			resp.Files = append(resp.Files, pluginFile{Name: file.Name, Content: string(file.Content)})
+		}
+	}
+
+	if err != nil {
+		resp.Error = err.Error()
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(resp)
+}
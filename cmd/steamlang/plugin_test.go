@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRunPlugin(t *testing.T) {
+	req := `{
+		"files": {
+			"schemas/emsg.steamd": "#import \"base.steamd\"\nclass MsgLogon { EResult result; };",
+			"schemas/base.steamd": "enum EResult { OK = 1; };",
+			"schemas/broken.steamd": "enum EBroken { A = ; };"
+		},
+		"inputs": ["schemas/emsg.steamd", "schemas/broken.steamd"],
+		"options": {"package": "protocol"}
+	}`
+
+	out := &bytes.Buffer{}
+
+	if err := runPlugin(context.Background(), strings.NewReader(req), out); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	resp := &pluginResponse{}
+
+	if err := json.Unmarshal(out.Bytes(), resp); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if len(resp.Files) != 1 || resp.Files[0].Name != "emsg.go" {
+		t.Fatalf("mismatch: got %v, but expected emsg.go only", resp.Files)
+	}
+
+	content := resp.Files[0].Content
+
+	for _, expected := range []string{"package protocol", "type MsgLogon struct", "EResult_OK"} {
+		if !strings.Contains(content, expected) {
+			t.Fatalf("expected %q in output:\n%s", expected, content)
+		}
+	}
+
+	if !strings.HasPrefix(resp.Error, "schemas/broken.steamd:1:") {
+		t.Fatalf("expected error in broken.steamd, got %q", resp.Error)
+	}
+
+	if err := runPlugin(context.Background(), strings.NewReader("{"), out); err == nil {
+		t.Fatalf("expected error for invalid request")
+	}
+}
+
+func TestRequestFilesResolve(t *testing.T) {
+	files := requestFiles{"a/b.steamd": "", "c.steamd": ""}
+
+	if _, name, err := files.Resolve("a/main.steamd", "../c.steamd"); err != nil || name != "c.steamd" {
+		t.Fatalf("mismatch: got %q %v, but expected %q", name, err, "c.steamd")
+	}
+
+	if _, _, err := files.Resolve("a/main.steamd", "../../c.steamd"); err == nil {
+		t.Fatalf("expected error for import escaping the request")
+	}
+
+	if _, _, err := files.Resolve("main.steamd", "missing.steamd"); err == nil {
+		t.Fatalf("expected error for missing import")
+	}
+}
+
+func TestRunPluginTemplate(t *testing.T) {
+	req := `{
+		"files": {
+			"emsg.steamd": "enum EMsg { Multi = 1; };",
+			"enums.tmpl": "package {{.Package}}\n{{range enums .Nodes}}const {{.Name}}Count = {{len .Members}}\n{{end}}"
+		},
+		"inputs": ["emsg.steamd"],
+		"options": {"package": "protocol", "template": "enums.tmpl"}
+	}`
+
+	out := &bytes.Buffer{}
+
+	if err := runPlugin(context.Background(), strings.NewReader(req), out); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	resp := &pluginResponse{}
+
+	if err := json.Unmarshal(out.Bytes(), resp); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expected := "package protocol\n\nconst EMsgCount = 1\n"
+
+	if resp.Error != "" || len(resp.Files) != 1 || resp.Files[0].Content != expected {
+		t.Fatalf("mismatch: got %v %q, but expected %q", resp.Files, resp.Error, expected)
+	}
+}
@@ -0,0 +1,683 @@
+// Package codec decodes and encodes the binary payloads of schema classes at
+// runtime, driven by the parsed ast instead of generated code.
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/13k/go-steam-language/ast"
+)
+
+// builtinKinds maps the fixed size builtin types to their Go types.
+var builtinKinds = map[string]reflect.Type{
+	"byte":   reflect.TypeOf(uint8(0)),
+	"sbyte":  reflect.TypeOf(int8(0)),
+	"short":  reflect.TypeOf(int16(0)),
+	"ushort": reflect.TypeOf(uint16(0)),
+	"int":    reflect.TypeOf(int32(0)),
+	"uint":   reflect.TypeOf(uint32(0)),
+	"long":   reflect.TypeOf(int64(0)),
+	"ulong":  reflect.TypeOf(uint64(0)),
+	"float":  reflect.TypeOf(float32(0)),
+	"double": reflect.TypeOf(float64(0)),
+	"bool":   reflect.TypeOf(false),
+}
+
+// Codec reads and writes instances of a class as maps keyed by field name.
+//
+// Values are decoded as the Go type matching the field type: uint32 for uint,
+// the storage type for enums, bool for boolmarshal fields, []byte for byte<N>
+// arrays, []interface{} for other arrays, nested maps for class fields and
+// Variant for union fields. Arrays of classes hold nested maps. Fields of
+// variable length are arrays as long as their length field, or a string for
+// string<len> fields, and length fields are encoded as the length of the value
+// of the field they measure. Strings are UTF-8 unless annotated
+// `encoding:utf16`, their lengths counting code units, and `term:null` strings
+// are followed by a null code unit instead.
+// protomask fields are decoded with ast.ProtoMask cleared and encoded with it
+// set. Values are little-endian unless annotated `endian:be`. Constants aren't
+// part of the payload. Optional fields missing from the input are left out of
+// the decoded map, and the payload is encoded up to the first optional field
+// missing from the values. Conditional fields whose flag isn't set are left out
+// of both.
+type Codec struct {
+	class *ast.ClassNode
+}
+
+// Variant is the value of a union field: the name of the discriminator member
+// selecting the variant, and the fields of its class.
+type Variant struct {
+	Case   string
+	Values map[string]interface{}
+}
+
+// New returns a Codec for the class named name, looked up from schema.
+func New(schema ast.Node, name string) (*Codec, error) {
+	sym := ast.LookupSymbol(schema, []string{name})
+
+	if sym == nil {
+		return nil, fmt.Errorf("Unknown class %q", name)
+	}
+
+	class, ok := sym.Node.(*ast.ClassNode)
+
+	if !ok {
+		return nil, fmt.Errorf("Symbol %q is not a class", name)
+	}
+
+	return &Codec{class: class}, nil
+}
+
+// Decode reads an instance of the class from r.
+func (c *Codec) Decode(r io.Reader) (map[string]interface{}, error) {
+	return decodeClass(r, c.class)
+}
+
+// Encode writes values as an instance of the class to w. Fields missing from
+// values are written as zero. Numeric values are converted to the field type.
+func (c *Codec) Encode(w io.Writer, values map[string]interface{}) error {
+	return encodeClass(w, c.class, values)
+}
+
+func decodeClass(r io.Reader, class *ast.ClassNode) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+
+	for _, f := range class.Fields() {
+		present, err := conditionMet(f, values)
+
+		if err != nil {
+			return nil, fmt.Errorf("Cannot decode %s::%s: %w", class.Name(), f.Name(), err)
+		}
+
+		if !present {
+			continue
+		}
+
+		cr := &countingReader{r: r}
+		v, err := decodeField(cr, f, values)
+
+		// the payload ends before an absent optional field
+		if f.Optional && cr.n == 0 && errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("Cannot decode %s::%s: %w", class.Name(), f.Name(), err)
+		}
+
+		values[f.Name()] = v
+	}
+
+	return values, nil
+}
+
+// decodeField reads f, following the fields of its class already read into
+// values.
+func decodeField(r io.Reader, f *ast.PropertyNode, values map[string]interface{}) (interface{}, error) {
+	t, err := fieldType(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if t.class != nil && t.length == nil && t.size < 0 {
+		return decodeClass(r, t.class)
+	}
+
+	if t.union != nil {
+		return decodeUnion(r, t.union)
+	}
+
+	if t.length != nil {
+		if t.size, err = lengthValue(values[t.length.Name()]); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.str {
+		return decodeString(r, t)
+	}
+
+	if t.size < 0 {
+		return decodeValue(r, t)
+	}
+
+	if t.class == nil && t.kind.Kind() == reflect.Uint8 {
+		buf := make([]byte, t.size)
+
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+
+		return buf, nil
+	}
+
+	items := make([]interface{}, t.size)
+
+	for i := range items {
+		if t.class != nil {
+			items[i], err = decodeClass(r, t.class)
+		} else {
+			items[i], err = decodeValue(r, t)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return items, nil
+}
+
+// decodeString reads a string of t.size code units, or up to a null one if it's
+// null-terminated.
+func decodeString(r io.Reader, t *wireType) (string, error) {
+	var units []uint16
+
+	for i := 0; t.null || i < t.size; i++ {
+		v, err := decodeValue(r, t)
+
+		if err != nil {
+			return "", err
+		}
+
+		unit := uint16(reflect.ValueOf(v).Uint())
+
+		if t.null && unit == 0 {
+			break
+		}
+
+		units = append(units, unit)
+	}
+
+	if t.encoding == ast.EncodingUTF16 {
+		return string(utf16.Decode(units)), nil
+	}
+
+	buf := make([]byte, len(units))
+
+	for i, unit := range units {
+		buf[i] = byte(unit)
+	}
+
+	return string(buf), nil
+}
+
+// lengthValue converts the decoded value of a length field to a number of
+// elements.
+func lengthValue(v interface{}) (int, error) {
+	rv := reflect.ValueOf(v)
+
+	switch rv.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n := rv.Int(); n >= 0 && n <= 1<<31-1 {
+			return int(n), nil
+		}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n := rv.Uint(); n <= 1<<31-1 {
+			return int(n), nil
+		}
+	}
+
+	return 0, fmt.Errorf("Invalid length %v", v)
+}
+
+func decodeUnion(r io.Reader, union *ast.UnionNode) (interface{}, error) {
+	t, err := discriminatorType(union)
+
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := decodeValue(r, t)
+
+	if err != nil {
+		return nil, err
+	}
+
+	value := reflect.ValueOf(d).Convert(reflect.TypeOf(uint64(0))).Uint()
+	variant := union.Variant(value)
+
+	if variant == nil || variant.Class() == nil {
+		return nil, fmt.Errorf("Unknown %s discriminator %d", union.Name(), value)
+	}
+
+	values, err := decodeClass(r, variant.Class())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Variant{Case: variant.Name(), Values: values}, nil
+}
+
+func decodeValue(r io.Reader, t *wireType) (interface{}, error) {
+	ptr := reflect.New(t.kind)
+
+	if err := binary.Read(r, t.byteOrder(), ptr.Interface()); err != nil {
+		return nil, err
+	}
+
+	v := ptr.Elem()
+
+	switch t.flags {
+	case ast.PropertyFlagBoolMarshal:
+		return v.Uint() != 0, nil
+	case ast.PropertyFlagProtoMask, ast.PropertyFlagProtoMaskGC:
+		v.SetUint(v.Uint() &^ ast.ProtoMask)
+	}
+
+	return v.Interface(), nil
+}
+
+func encodeClass(w io.Writer, class *ast.ClassNode, values map[string]interface{}) error {
+	for _, f := range class.Fields() {
+		if f.Optional && values[f.Name()] == nil {
+			break
+		}
+
+		present, err := conditionMet(f, values)
+
+		if err != nil {
+			return fmt.Errorf("Cannot encode %s::%s: %w", class.Name(), f.Name(), err)
+		}
+
+		if !present {
+			continue
+		}
+
+		value := values[f.Name()]
+
+		// lengths are taken from the field they measure
+		if sized := f.LengthOf(); sized != nil {
+			if value, err = itemCount(values[sized.Name()], sized.Encoding); err != nil {
+				return fmt.Errorf("Cannot encode %s::%s: %w", class.Name(), sized.Name(), err)
+			}
+		}
+
+		if err := encodeField(w, f, value); err != nil {
+			return fmt.Errorf("Cannot encode %s::%s: %w", class.Name(), f.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+func encodeField(w io.Writer, f *ast.PropertyNode, value interface{}) error {
+	t, err := fieldType(f)
+
+	if err != nil {
+		return err
+	}
+
+	if t.class != nil && t.length == nil && t.size < 0 {
+		return encodeClassValue(w, t.class, value)
+	}
+
+	if t.union != nil {
+		return encodeUnion(w, t.union, value)
+	}
+
+	if t.str {
+		return encodeString(w, t, value)
+	}
+
+	if t.size < 0 && t.length == nil {
+		return encodeValue(w, t, value)
+	}
+
+	var items []interface{}
+
+	switch v := value.(type) {
+	case nil:
+	case []byte:
+		for _, b := range v {
+			items = append(items, b)
+		}
+	case []interface{}:
+		items = v
+	default:
+		return fmt.Errorf("Expected an array, got %T", value)
+	}
+
+	// variable length fields are written whole
+	if t.length != nil {
+		t.size = len(items)
+	}
+
+	if len(items) > t.size {
+		return fmt.Errorf("Array of %d items exceeds size %d", len(items), t.size)
+	}
+
+	for i := 0; i < t.size; i++ {
+		var item interface{}
+
+		if i < len(items) {
+			item = items[i]
+		}
+
+		var err error
+
+		if t.class != nil {
+			err = encodeClassValue(w, t.class, item)
+		} else {
+			err = encodeValue(w, t, item)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeString writes value, a string, as the code units of t, followed by a
+// null one if it's null-terminated.
+func encodeString(w io.Writer, t *wireType, value interface{}) error {
+	s, ok := value.(string)
+
+	if !ok && value != nil {
+		return fmt.Errorf("Expected string, got %T", value)
+	}
+
+	if t.null && strings.IndexByte(s, 0) >= 0 {
+		return fmt.Errorf("String %q contains a null character", s)
+	}
+
+	var units interface{}
+
+	if t.encoding == ast.EncodingUTF16 {
+		u := utf16.Encode([]rune(s))
+
+		if t.null {
+			u = append(u, 0)
+		}
+
+		units = u
+	} else {
+		u := []byte(s)
+
+		if t.null {
+			u = append(u, 0)
+		}
+
+		units = u
+	}
+
+	return binary.Write(w, t.byteOrder(), units)
+}
+
+// encodeClassValue writes value, the fields of an instance of class.
+func encodeClassValue(w io.Writer, class *ast.ClassNode, value interface{}) error {
+	if value == nil {
+		return encodeClass(w, class, nil)
+	}
+
+	values, ok := value.(map[string]interface{})
+
+	if !ok {
+		return fmt.Errorf("Expected map[string]interface{}, got %T", value)
+	}
+
+	return encodeClass(w, class, values)
+}
+
+// itemCount returns the number of elements of value, the value of a field of
+// variable length, counting the code units of strings in encoding.
+func itemCount(value interface{}, encoding ast.StringEncoding) (int, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case []byte:
+		return len(v), nil
+	case string:
+		if encoding == ast.EncodingUTF16 {
+			return len(utf16.Encode([]rune(v))), nil
+		}
+
+		return len(v), nil
+	case []interface{}:
+		return len(v), nil
+	default:
+		return 0, fmt.Errorf("Expected an array, got %T", value)
+	}
+}
+
+func encodeUnion(w io.Writer, union *ast.UnionNode, value interface{}) error {
+	v, ok := value.(Variant)
+
+	if !ok {
+		return fmt.Errorf("Expected Variant, got %T", value)
+	}
+
+	t, err := discriminatorType(union)
+
+	if err != nil {
+		return err
+	}
+
+	var variant *ast.UnionVariantNode
+
+	for _, candidate := range union.Variants() {
+		if candidate.Name() == v.Case {
+			variant = candidate
+		}
+	}
+
+	if variant == nil || variant.Class() == nil || variant.Discriminator() == nil {
+		return fmt.Errorf("Unknown %s variant %q", union.Name(), v.Case)
+	}
+
+	d, ok := variant.Discriminator().ResolvedValue()
+
+	if !ok {
+		return fmt.Errorf("Unresolved value of %s::%s", union.Discriminator().Name(), v.Case)
+	}
+
+	if err := encodeValue(w, t, d); err != nil {
+		return err
+	}
+
+	return encodeClass(w, variant.Class(), v.Values)
+}
+
+func encodeValue(w io.Writer, t *wireType, value interface{}) error {
+	v := reflect.New(t.kind).Elem()
+
+	if value != nil {
+		if t.flags == ast.PropertyFlagBoolMarshal {
+			b, ok := value.(bool)
+
+			if !ok {
+				return fmt.Errorf("Expected bool, got %T", value)
+			}
+
+			if b {
+				value = 1
+			} else {
+				value = 0
+			}
+		}
+
+		rv := reflect.ValueOf(value)
+
+		if !convertible(rv.Type(), t.kind) {
+			return fmt.Errorf("Cannot convert %T to %s", value, t.kind)
+		}
+
+		v.Set(rv.Convert(t.kind))
+	}
+
+	switch t.flags {
+	case ast.PropertyFlagProtoMask, ast.PropertyFlagProtoMaskGC:
+		v.SetUint(v.Uint() | ast.ProtoMask)
+	}
+
+	return binary.Write(w, t.byteOrder(), v.Interface())
+}
+
+func convertible(from, to reflect.Type) bool {
+	if to.Kind() == reflect.Bool {
+		return from.Kind() == reflect.Bool
+	}
+
+	switch from.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// conditionMet reports whether f is present in a payload whose fields
+// preceding f are values: f isn't conditional, or the field its condition tests
+// has the flag set.
+func conditionMet(f *ast.PropertyNode, values map[string]interface{}) (bool, error) {
+	if f.Condition == nil {
+		return true, nil
+	}
+
+	member, field := f.ConditionMember(), f.ConditionField()
+
+	if member == nil || field == nil {
+		return false, fmt.Errorf("Unresolved condition %v", f.ConditionPath)
+	}
+
+	if err := ast.Evaluate(member.Parent()); err != nil {
+		return false, err
+	}
+
+	flag, _ := member.ResolvedValue()
+	v := values[field.Name()]
+
+	if v == nil {
+		return false, nil
+	}
+
+	rv := reflect.ValueOf(v)
+
+	if !convertible(rv.Type(), reflect.TypeOf(uint64(0))) {
+		return false, fmt.Errorf("Cannot convert %T to uint64", v)
+	}
+
+	return rv.Convert(reflect.TypeOf(uint64(0))).Uint()&flag != 0, nil
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += n
+	return n, err
+}
+
+// wireType is the encoding of a field. Fields are either a union, or a nested
+// class or single value of kind or, when size isn't negative, an array of
+// them. Arrays of variable length, of values or classes, have the number of
+// elements given by the field length, and str is set for strings, arrays of
+// code units of encoding, of variable length or null-terminated if null is set.
+type wireType struct {
+	class    *ast.ClassNode
+	union    *ast.UnionNode
+	kind     reflect.Type
+	size     int
+	length   *ast.PropertyNode
+	str      bool
+	null     bool
+	flags    ast.PropertyFlag
+	endian   ast.ByteOrder
+	encoding ast.StringEncoding
+}
+
+func (t *wireType) byteOrder() binary.ByteOrder {
+	if t.endian == ast.BigEndian {
+		return binary.BigEndian
+	}
+
+	return binary.LittleEndian
+}
+
+func fieldType(f *ast.PropertyNode) (*wireType, error) {
+	t := &wireType{size: -1, length: f.LengthField(), flags: f.Flags, endian: f.Endian}
+	typeName := "int"
+
+	if wire := f.Flags.WireType(); wire != "" {
+		typeName = wire
+	} else if f.Type != nil {
+		switch n := f.Type.Node.(type) {
+		case *ast.ClassNode:
+			t.class = n
+			return t, t.arrayLen(f)
+		case *ast.UnionNode:
+			if f.Qualifier != nil {
+				return nil, fmt.Errorf("Unsupported array of union %s", n.Name())
+			}
+
+			t.union = n
+			return t, nil
+		case *ast.EnumNode:
+			typeName = n.Type.String()
+		default:
+			typeName = f.Type.Value
+		}
+	}
+
+	if typeName == "string" && (t.length != nil || f.Termination == ast.TermNull) {
+		typeName, t.str, t.null, t.encoding = "byte", true, f.Termination == ast.TermNull, f.Encoding
+
+		if f.Encoding == ast.EncodingUTF16 {
+			typeName = "ushort"
+		}
+	}
+
+	if t.kind = builtinKinds[typeName]; t.kind == nil {
+		return nil, fmt.Errorf("Unsupported type %s", typeName)
+	}
+
+	if err := t.arrayLen(f); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// arrayLen sets the size of t to the number of elements of f, if it's a fixed
+// size array.
+func (t *wireType) arrayLen(f *ast.PropertyNode) error {
+	size, ok, err := f.ArrayLen()
+
+	if ok {
+		t.size = size
+	}
+
+	return err
+}
+
+// discriminatorType returns the encoding of the discriminator of union, the
+// storage type of its enum.
+func discriminatorType(union *ast.UnionNode) (*wireType, error) {
+	enum := union.Discriminator()
+
+	if enum == nil {
+		return nil, fmt.Errorf("Union %s has no discriminator enum", union.Name())
+	}
+
+	if err := ast.Evaluate(enum); err != nil {
+		return nil, err
+	}
+
+	return &wireType{kind: builtinKinds[enum.Type.String()], size: -1}, nil
+}
@@ -0,0 +1,421 @@
+package codec
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+const schema = `
+enum EMsg {
+	Invalid = 0;
+	Multi = 1;
+};
+
+enum EUniverse<byte> {
+	Invalid = 0;
+	Public = 1;
+};
+
+class MsgHdr {
+	const uint SIZE = 4;
+	protomask EMsg msg = EMsg::Invalid;
+	steamidmarshal ulong steamID;
+	boolmarshal byte valid;
+};
+
+class MsgLogon<EMsg::Multi> {
+	MsgHdr header;
+	EUniverse universe;
+	byte<MsgHdr::SIZE> key;
+	ushort<2> ports;
+};
+`
+
+func analyzeString(t *testing.T, data string) ast.Node {
+	root, err := parse.NewAnalyzer(token.NewTokenizer([]byte(data)), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	return root
+}
+
+func TestCodec(t *testing.T) {
+	c, err := New(analyzeString(t, schema), "MsgLogon")
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	values := map[string]interface{}{
+		"header": map[string]interface{}{
+			"msg":     1,
+			"steamID": uint64(0x0110000100000001),
+			"valid":   true,
+		},
+		"universe": 1,
+		"key":      []byte{1, 2, 3},
+		"ports":    []interface{}{27015, 27016},
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := c.Encode(buf, values); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expected := []byte{
+		0x01, 0x00, 0x00, 0x80,
+		0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x10, 0x01,
+		0x01,
+		0x01,
+		0x01, 0x02, 0x03, 0x00,
+		0x87, 0x69, 0x88, 0x69,
+	}
+
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("mismatch: got %x, but expected %x", buf.Bytes(), expected)
+	}
+
+	decoded, err := c.Decode(bytes.NewReader(expected))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expectedValues := map[string]interface{}{
+		"header": map[string]interface{}{
+			"msg":     uint32(1),
+			"steamID": uint64(0x0110000100000001),
+			"valid":   true,
+		},
+		"universe": uint8(1),
+		"key":      []byte{1, 2, 3, 0},
+		"ports":    []interface{}{uint16(27015), uint16(27016)},
+	}
+
+	if !reflect.DeepEqual(decoded, expectedValues) {
+		t.Fatalf("mismatch: got %v, but expected %v", decoded, expectedValues)
+	}
+}
+
+func TestCodecErrors(t *testing.T) {
+	root := analyzeString(t, schema+"class Named { string name; };")
+
+	if _, err := New(root, "Missing"); err == nil {
+		t.Fatalf("expected error for unknown class")
+	}
+
+	if _, err := New(root, "EMsg"); err == nil {
+		t.Fatalf("expected error for enum")
+	}
+
+	c, err := New(root, "Named")
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if _, err := c.Decode(bytes.NewReader(nil)); err == nil {
+		t.Fatalf("expected error for unsupported type")
+	}
+
+	c, err = New(root, "MsgLogon")
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if _, err := c.Decode(bytes.NewReader([]byte{1, 2})); err == nil {
+		t.Fatalf("expected error for short payload")
+	}
+
+	if err := c.Encode(&bytes.Buffer{}, map[string]interface{}{"universe": "public"}); err == nil {
+		t.Fatalf("expected error for invalid value")
+	}
+
+	if err := c.Encode(&bytes.Buffer{}, map[string]interface{}{"key": make([]byte, 5)}); err == nil {
+		t.Fatalf("expected error for oversized array")
+	}
+}
+
+func TestCodecOptionalFields(t *testing.T) {
+	root := analyzeString(t, schema+"class MsgJob { uint id; optional ulong jobID; optional MsgHdr header; };")
+	c, err := New(root, "MsgJob")
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := c.Encode(buf, map[string]interface{}{"id": 1, "header": map[string]interface{}{}}); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expected := []byte{0x01, 0x00, 0x00, 0x00}
+
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("mismatch: got %x, but expected %x", buf.Bytes(), expected)
+	}
+
+	decoded, err := c.Decode(bytes.NewReader(append(expected, 2, 0, 0, 0, 0, 0, 0, 0)))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expectedValues := map[string]interface{}{"id": uint32(1), "jobID": uint64(2)}
+
+	if !reflect.DeepEqual(decoded, expectedValues) {
+		t.Fatalf("mismatch: got %v, but expected %v", decoded, expectedValues)
+	}
+
+	if _, err := c.Decode(bytes.NewReader(append(expected, 2, 0))); err == nil {
+		t.Fatalf("expected error for truncated optional field")
+	}
+}
+
+func TestCodecConditionalFields(t *testing.T) {
+	root := analyzeString(t, "enum EFlags flags { A = 1; B = 2; };\nclass MsgJob { EFlags flags; uint a if:EFlags::A; ushort b if:EFlags::B; };")
+	c, err := New(root, "MsgJob")
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := c.Encode(buf, map[string]interface{}{"flags": 2, "a": 1, "b": 3}); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expected := []byte{0x02, 0x00, 0x00, 0x00, 0x03, 0x00}
+
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("mismatch: got %x, but expected %x", buf.Bytes(), expected)
+	}
+
+	decoded, err := c.Decode(bytes.NewReader([]byte{0x01, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00}))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expectedValues := map[string]interface{}{"flags": int32(1), "a": uint32(5)}
+
+	if !reflect.DeepEqual(decoded, expectedValues) {
+		t.Fatalf("mismatch: got %v, but expected %v", decoded, expectedValues)
+	}
+}
+
+func TestCodecArrays(t *testing.T) {
+	root := analyzeString(t, "class Item { ushort id; };\nclass MsgList { const uint MAX = 2; Item<MAX> items; uint<2> ids; };")
+	c, err := New(root, "MsgList")
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	values := map[string]interface{}{"items": []interface{}{map[string]interface{}{"id": 7}}, "ids": []interface{}{1, 2}}
+
+	if err := c.Encode(buf, values); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expected := []byte{0x07, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00}
+
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("mismatch: got %x, but expected %x", buf.Bytes(), expected)
+	}
+
+	decoded, err := c.Decode(bytes.NewReader(expected))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expectedValues := map[string]interface{}{
+		"items": []interface{}{map[string]interface{}{"id": uint16(7)}, map[string]interface{}{"id": uint16(0)}},
+		"ids":   []interface{}{uint32(1), uint32(2)},
+	}
+
+	if !reflect.DeepEqual(decoded, expectedValues) {
+		t.Fatalf("mismatch: got %v, but expected %v", decoded, expectedValues)
+	}
+}
+
+func TestCodecLengthFields(t *testing.T) {
+	root := analyzeString(t, "class Item { ushort id; };\nclass MsgBlob { byte size; byte<size> data; ushort length; string<length> name; uint count; Item<count> items; };")
+	c, err := New(root, "MsgBlob")
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	values := map[string]interface{}{
+		"size":  9,
+		"data":  []byte{0xaa, 0xbb},
+		"name":  "foo",
+		"items": []interface{}{map[string]interface{}{"id": 7}},
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := c.Encode(buf, values); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expected := []byte{0x02, 0xaa, 0xbb, 0x03, 0x00, 'f', 'o', 'o', 0x01, 0x00, 0x00, 0x00, 0x07, 0x00}
+
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("mismatch: got %x, but expected %x", buf.Bytes(), expected)
+	}
+
+	decoded, err := c.Decode(bytes.NewReader(expected))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expectedValues := map[string]interface{}{
+		"size":   uint8(2),
+		"data":   []byte{0xaa, 0xbb},
+		"length": uint16(3),
+		"name":   "foo",
+		"count":  uint32(1),
+		"items":  []interface{}{map[string]interface{}{"id": uint16(7)}},
+	}
+
+	if !reflect.DeepEqual(decoded, expectedValues) {
+		t.Fatalf("mismatch: got %v, but expected %v", decoded, expectedValues)
+	}
+
+	if _, err := c.Decode(bytes.NewReader(expected[:2])); err == nil {
+		t.Fatalf("expected error for truncated field")
+	}
+}
+
+func TestCodecEndian(t *testing.T) {
+	root := analyzeString(t, "class MsgPort { ushort port endian:be; ushort<2> ids endian:be; ushort id; };")
+	c, err := New(root, "MsgPort")
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := c.Encode(buf, map[string]interface{}{"port": 0x1234, "ids": []interface{}{1, 2}, "id": 3}); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expected := []byte{0x12, 0x34, 0x00, 0x01, 0x00, 0x02, 0x03, 0x00}
+
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("mismatch: got %x, but expected %x", buf.Bytes(), expected)
+	}
+
+	decoded, err := c.Decode(bytes.NewReader(expected))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expectedValues := map[string]interface{}{"port": uint16(0x1234), "ids": []interface{}{uint16(1), uint16(2)}, "id": uint16(3)}
+
+	if !reflect.DeepEqual(decoded, expectedValues) {
+		t.Fatalf("mismatch: got %v, but expected %v", decoded, expectedValues)
+	}
+}
+
+func TestCodecStrings(t *testing.T) {
+	root := analyzeString(t, "class MsgText { byte size; string<size> wide encoding:utf16; string name term:null; string title encoding:utf16 term:null endian:be; };")
+	c, err := New(root, "MsgText")
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := c.Encode(buf, map[string]interface{}{"wide": "hé", "name": "ab", "title": "z"}); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expected := []byte{0x02, 'h', 0x00, 0xe9, 0x00, 'a', 'b', 0x00, 0x00, 'z', 0x00, 0x00}
+
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("mismatch: got %x, but expected %x", buf.Bytes(), expected)
+	}
+
+	decoded, err := c.Decode(bytes.NewReader(expected))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expectedValues := map[string]interface{}{"size": uint8(2), "wide": "hé", "name": "ab", "title": "z"}
+
+	if !reflect.DeepEqual(decoded, expectedValues) {
+		t.Fatalf("mismatch: got %v, but expected %v", decoded, expectedValues)
+	}
+
+	if err := c.Encode(&bytes.Buffer{}, map[string]interface{}{"name": "a\x00b"}); err == nil {
+		t.Fatal("expected error encoding a null character")
+	}
+}
+
+func TestCodecUnions(t *testing.T) {
+	root := analyzeString(t, schema+`
+		class MsgPing { uint seq; };
+		class MsgFlag { boolmarshal byte on; };
+		union Body<EUniverse> { MsgPing Invalid; MsgFlag Public; };
+		class MsgJob { uint id; Body body; };
+	`)
+	c, err := New(root, "MsgJob")
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	values := map[string]interface{}{"id": 1, "body": Variant{Case: "Public", Values: map[string]interface{}{"on": true}}}
+
+	if err := c.Encode(buf, values); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expected := []byte{0x01, 0x00, 0x00, 0x00, 0x01, 0x01}
+
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("mismatch: got %x, but expected %x", buf.Bytes(), expected)
+	}
+
+	decoded, err := c.Decode(bytes.NewReader([]byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00}))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expectedValues := map[string]interface{}{"id": uint32(1), "body": Variant{Case: "Invalid", Values: map[string]interface{}{"seq": uint32(7)}}}
+
+	if !reflect.DeepEqual(decoded, expectedValues) {
+		t.Fatalf("mismatch: got %v, but expected %v", decoded, expectedValues)
+	}
+
+	if _, err := c.Decode(bytes.NewReader([]byte{0x01, 0x00, 0x00, 0x00, 0x02})); err == nil {
+		t.Fatalf("expected error for unknown discriminator")
+	}
+
+	if err := c.Encode(&bytes.Buffer{}, map[string]interface{}{"body": Variant{Case: "Multi"}}); err == nil {
+		t.Fatalf("expected error for unknown variant")
+	}
+}
@@ -0,0 +1,211 @@
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/13k/go-steam-language/ast"
+)
+
+// Report is the outcome of checking a schema upgrade, meant to be encoded as
+// JSON for CI pipelines. Breaking is the number of breaking changes.
+type Report struct {
+	Breaking int       `json:"breaking"`
+	Additive int       `json:"additive"`
+	Changes  []*Change `json:"changes"`
+}
+
+// OK reports whether the upgrade has no breaking changes.
+func (r *Report) OK() bool {
+	return r.Breaking == 0
+}
+
+// Check compares the schemas rooted at from and to like Schemas, counting the
+// breaking and additive changes.
+//
+// Changes are breaking when messages encoded with one schema can't be decoded
+// with the other, or when code generated from the old schema doesn't build
+// against the new one: removed and renamed declarations, members and fields,
+// moved fields, fields whose wire type, size, optionality, condition, byte
+// order or string encoding changed, classes whose wire size or EMsg changed,
+// and changed values or storage types of enums used on the wire. Other changes,
+// like additions, changed defaults and attributes, are additive.
+func Check(from, to *ast.DocumentNode) (*Report, error) {
+	changes, err := Schemas(from, to)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Report{Changes: changes}
+
+	for _, c := range changes {
+		if c.Breaking {
+			r.Breaking++
+		} else {
+			r.Additive++
+		}
+	}
+
+	return r, nil
+}
+
+// breaking returns why c is a breaking change, or an empty string if it isn't.
+func (d *differ) breaking(c *Change) string {
+	switch c.Kind {
+	case Removed:
+		if f, ok := c.OldNode.(*ast.PropertyNode); ok && !f.IsConst() {
+			return "changes the wire layout"
+		}
+
+		return "breaks code using it"
+	case Added:
+		if f, ok := c.NewNode.(*ast.PropertyNode); ok && !f.IsConst() {
+			return "changes the wire layout"
+		}
+	case Renamed:
+		return "breaks code using the old name"
+	case Moved:
+		return "changes the wire layout"
+	case AttributesChanged:
+		if f, ok := c.OldNode.(*ast.PropertyNode); ok {
+			n := c.NewNode.(*ast.PropertyNode)
+
+			if f.Optional != n.Optional || condition(f) != condition(n) {
+				return "changes whether the field may be absent"
+			}
+
+			if f.Endian != n.Endian && !f.IsConst() {
+				return "changes the byte order"
+			}
+
+			if (f.Encoding != n.Encoding || f.Termination != n.Termination) && !f.IsConst() {
+				return "changes the string encoding"
+			}
+		}
+	case SizeChanged:
+		return "changes the wire size"
+	case ValueChanged:
+		if m, ok := c.OldNode.(*ast.EnumMemberNode); ok && d.wire[m.Parent().(*ast.EnumNode)] {
+			return "changes a value sent on the wire"
+		}
+	case TypeChanged:
+		switch o := c.OldNode.(type) {
+		case *ast.EnumNode:
+			if o.Type != c.NewNode.(*ast.EnumNode).Type && d.wire[o] {
+				return "changes the wire size"
+			}
+		case *ast.ClassNode:
+			if c.Old == base(o) && c.New == base(c.NewNode.(*ast.ClassNode)) {
+				return "changes the inherited fields"
+			}
+
+			return "changes the EMsg the class is sent as"
+		case *ast.PropertyNode:
+			if !o.IsConst() && d.wireType(o) != wireType(c.NewNode.(*ast.PropertyNode)) {
+				return "changes the wire type"
+			}
+		}
+	}
+
+	return ""
+}
+
+// condition renders the enum member the presence of f depends on, qualified
+// with its enum, or an empty string.
+func condition(f *ast.PropertyNode) string {
+	if m := f.ConditionMember(); m != nil {
+		return m.Parent().Name() + "::" + m.Name()
+	}
+
+	return strings.Join(f.ConditionPath, "::")
+}
+
+// wireEnums returns the enums whose values are sent on the wire by the classes
+// of doc: as field types, and as the EMsg of classes.
+func wireEnums(doc *ast.DocumentNode) map[*ast.EnumNode]bool {
+	enums := make(map[*ast.EnumNode]bool)
+
+	for _, child := range doc.Types() {
+		class, ok := child.(*ast.ClassNode)
+
+		if !ok {
+			continue
+		}
+
+		if q := class.Qualifier; q != nil && q.Kind == ast.QualifierEMsg && q.Symbol != nil && q.Symbol.Node != nil {
+			if enum, ok := q.Symbol.Node.Parent().(*ast.EnumNode); ok {
+				enums[enum] = true
+			}
+		}
+
+		for _, f := range class.Properties() {
+			if f.Type == nil || f.Flags == ast.PropertyFlagBoolMarshal {
+				continue
+			}
+
+			if enum, ok := f.Type.Node.(*ast.EnumNode); ok {
+				enums[enum] = true
+			}
+		}
+	}
+
+	return enums
+}
+
+// wireType returns the type of p in the old schema as encoded on the wire, with
+// renamed classes under their new names.
+func (d *differ) wireType(p *ast.PropertyNode) string {
+	typ := wireType(p)
+
+	if p.Type != nil {
+		if name, ok := d.renames[p.Type.Value]; ok && typ == p.Type.Value {
+			return name
+		}
+	}
+
+	return typ
+}
+
+// wireType renders the type of p as encoded on the wire: the storage type of
+// enums and marshaled fields, and the array size.
+func wireType(p *ast.PropertyNode) string {
+	typ := "int"
+
+	switch {
+	case p.Flags == ast.PropertyFlagProto:
+		typ = "proto"
+	case p.Flags.WireType() != "":
+		typ = p.Flags.WireType()
+	case p.Type != nil:
+		typ = p.Type.Value
+
+		if enum, ok := p.Type.Node.(*ast.EnumNode); ok {
+			typ = enum.Type.String()
+		}
+	}
+
+	if q := p.Qualifier; q != nil && q.Kind == ast.QualifierSize {
+		if size, err := q.Size(); err == nil {
+			typ += fmt.Sprintf("<%d>", size)
+		}
+	}
+
+	return typ
+}
+
+// size renders the wire size of n, "variable" for classes of variable length,
+// or an empty string if it can't be computed.
+func size(n *ast.ClassNode) string {
+	l, err := ast.Layout(n)
+
+	switch {
+	case err != nil:
+		return ""
+	case !l.IsFixed():
+		return "variable"
+	}
+
+	return strconv.Itoa(l.Size)
+}
@@ -0,0 +1,87 @@
+package diff
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	r, err := Check(analyze(t, oldSchema), analyze(t, newSchema))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expected := map[string]bool{
+		"changed type of EResult from int to uint":                             true,
+		"renamed member EResult::Busy to EResult::Occupied":                    true,
+		"changed value of EResult::Fail from 3 to 4":                           true,
+		"added member EResult::Timeout":                                        false,
+		"removed enum EGone":                                                   true,
+		"changed type of MsgLogon from EMsg::Logon to EMsg::Logoff":            true,
+		"changed size of MsgLogon from 44 to 62":                               true,
+		"changed value of MsgLogon::VERSION from 1 to 2":                       false,
+		"moved MsgLogon::c from position 6 to 4":                               true,
+		"changed value of MsgLogon::protocol from 65575 to 65580":              false,
+		"changed type of MsgLogon::steamID from ulong to steamidmarshal ulong": false,
+		"changed type of MsgLogon::key from byte<16> to byte<32>":              true,
+		"added field MsgLogon::added":                                          true,
+		"renamed class MsgHdr to MsgHeader":                                    true,
+		"added enum EOld":                                                      false,
+	}
+
+	if len(r.Changes) != len(expected) {
+		t.Fatalf("mismatch: got %d changes, but expected %d", len(r.Changes), len(expected))
+	}
+
+	for _, c := range r.Changes {
+		breaking, ok := expected[c.String()]
+
+		if !ok {
+			t.Fatalf("unexpected change %q", c)
+		}
+
+		if c.Breaking != breaking {
+			t.Errorf("mismatch: got breaking %v (%s) for %q, but expected %v", c.Breaking, c.Reason, c, breaking)
+		}
+	}
+
+	if r.Breaking != 10 || r.Additive != 5 || r.OK() {
+		t.Fatalf("mismatch: got %d breaking and %d additive changes", r.Breaking, r.Additive)
+	}
+}
+
+func TestCheckUnusedEnum(t *testing.T) {
+	from := analyze(t, `enum E { A = 1; B = 2; }; class C { int x; };`)
+	to := analyze(t, `enum E<byte> flags { A = 1; B = 4; }; class C { int x; };`)
+
+	r, err := Check(from, to)
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if !r.OK() || r.Additive != 2 {
+		t.Fatalf("mismatch: got %v, but expected only additive changes", r.Changes)
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	r, err := Check(analyze(t, `enum E { A = 1; }; class C { E e; };`), analyze(t, `enum E { A = 2; }; class C { E e; };`))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	data, err := json.Marshal(r)
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expected := `{"breaking":1,"additive":0,"changes":[{"kind":"value","path":"E::A","old":"1","new":"2","breaking":true,"reason":"changes a value sent on the wire"}]}`
+
+	if string(data) != expected {
+		t.Fatalf("mismatch: got %s, but expected %s", data, expected)
+	}
+}
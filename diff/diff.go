@@ -0,0 +1,645 @@
+// Package diff compares two versions of a schema at a semantic level: which
+// classes, enums, members, fields and constants were added, removed or renamed,
+// and which changed their values, types, order or attributes.
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/13k/go-steam-language/ast"
+)
+
+// Kind is the kind of a Change.
+type Kind int
+
+const (
+	Added Kind = iota
+	Removed
+	Renamed
+	ValueChanged
+	TypeChanged
+	Moved
+	AttributesChanged
+	SizeChanged
+)
+
+var kindNames = []string{
+	Added:             "added",
+	Removed:           "removed",
+	Renamed:           "renamed",
+	ValueChanged:      "value",
+	TypeChanged:       "type",
+	Moved:             "moved",
+	AttributesChanged: "attributes",
+	SizeChanged:       "size",
+}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+
+	return kindNames[k]
+}
+
+// MarshalText encodes k as its name.
+func (k Kind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Change is a difference between two schemas. Path names the declaration or
+// member changed, like "EResult::Busy", in the old schema except for additions.
+// NewPath is the path in the new schema of renamed declarations and members.
+// Old and New describe what changed: the values, types, positions or
+// attributes before and after. Breaking reports whether the change breaks
+// peers or code built for the old schema, for the Reason given. OldNode and
+// NewNode are the nodes compared, nil for additions and removals respectively.
+type Change struct {
+	Kind     Kind     `json:"kind"`
+	Path     string   `json:"path"`
+	NewPath  string   `json:"new_path,omitempty"`
+	Old      string   `json:"old,omitempty"`
+	New      string   `json:"new,omitempty"`
+	Breaking bool     `json:"breaking"`
+	Reason   string   `json:"reason,omitempty"`
+	OldNode  ast.Node `json:"-"`
+	NewNode  ast.Node `json:"-"`
+}
+
+func (c *Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("added %s %s", kind(c.NewNode), c.Path)
+	case Removed:
+		return fmt.Sprintf("removed %s %s", kind(c.OldNode), c.Path)
+	case Renamed:
+		return fmt.Sprintf("renamed %s %s to %s", kind(c.OldNode), c.Path, c.NewPath)
+	case ValueChanged:
+		return fmt.Sprintf("changed value of %s from %s to %s", c.Path, quoteEmpty(c.Old), quoteEmpty(c.New))
+	case TypeChanged:
+		return fmt.Sprintf("changed type of %s from %s to %s", c.Path, quoteEmpty(c.Old), quoteEmpty(c.New))
+	case Moved:
+		return fmt.Sprintf("moved %s from position %s to %s", c.Path, c.Old, c.New)
+	case AttributesChanged:
+		return fmt.Sprintf("changed attributes of %s from %s to %s", c.Path, quoteEmpty(c.Old), quoteEmpty(c.New))
+	case SizeChanged:
+		return fmt.Sprintf("changed size of %s from %s to %s", c.Path, c.Old, c.New)
+	}
+
+	return fmt.Sprintf("%s %s", c.Kind, c.Path)
+}
+
+func quoteEmpty(s string) string {
+	if s == "" {
+		return "none"
+	}
+
+	return s
+}
+
+// kind names the kind of declaration or member n is.
+func kind(n ast.Node) string {
+	switch n := n.(type) {
+	case *ast.ClassNode:
+		return "class"
+	case *ast.EnumNode:
+		return "enum"
+	case *ast.EnumMemberNode:
+		return "member"
+	case *ast.PropertyNode:
+		if n.IsConst() {
+			return "const"
+		}
+
+		return "field"
+	}
+
+	return "node"
+}
+
+// Schemas returns the changes from the schema rooted at from to the one rooted
+// at to, declarations in the order of the old schema followed by additions.
+// Declarations imported by either document are compared too.
+//
+// Removed declarations and members replaced by added ones of the same kind and
+// shape are reported as renamed: enums with the same members, classes with the
+// same fields, enum members with the same value, constants with the same type
+// and value, and fields with the same type at the same position.
+func Schemas(from, to *ast.DocumentNode) ([]*Change, error) {
+	for _, doc := range []*ast.DocumentNode{from, to} {
+		if err := ast.Evaluate(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	d := &differ{renames: make(map[string]string), wire: wireEnums(from)}
+	oldDecls, newDecls := declarations(from), declarations(to)
+
+	pairs := match(oldDecls, newDecls, func(o, n ast.Node) bool {
+		return kind(o) == kind(n) && signature(o) == signature(n)
+	})
+
+	for o, n := range pairs {
+		if o.Name() != n.Name() {
+			d.renames[o.Name()] = n.Name()
+		}
+	}
+
+	for _, o := range oldDecls {
+		n, ok := pairs[o]
+
+		if !ok {
+			d.add(&Change{Kind: Removed, Path: o.Name(), OldNode: o})
+			continue
+		}
+
+		if o.Name() != n.Name() {
+			d.add(&Change{Kind: Renamed, Path: o.Name(), NewPath: n.Name(), OldNode: o, NewNode: n})
+		}
+
+		switch o := o.(type) {
+		case *ast.EnumNode:
+			d.enum(o, n.(*ast.EnumNode))
+		case *ast.ClassNode:
+			d.class(o, n.(*ast.ClassNode))
+		}
+	}
+
+	d.added(newDecls, pairs)
+
+	return d.changes, nil
+}
+
+type differ struct {
+	changes []*Change
+	// renames maps the names of renamed declarations to their new names.
+	renames map[string]string
+	// wire are the enums of the old schema whose values are sent on the wire.
+	wire map[*ast.EnumNode]bool
+}
+
+func (d *differ) add(c *Change) {
+	c.Reason = d.breaking(c)
+	c.Breaking = c.Reason != ""
+	d.changes = append(d.changes, c)
+}
+
+// added reports the nodes of news without a match in pairs.
+func (d *differ) added(news []ast.Node, pairs map[ast.Node]ast.Node) {
+	matched := make(map[ast.Node]bool)
+
+	for _, n := range pairs {
+		matched[n] = true
+	}
+
+	for _, n := range news {
+		if !matched[n] {
+			d.add(&Change{Kind: Added, Path: path(n), NewNode: n})
+		}
+	}
+}
+
+func (d *differ) enum(o, n *ast.EnumNode) {
+	if ot, nt := enumType(o), enumType(n); ot != nt {
+		d.add(&Change{Kind: TypeChanged, Path: o.Name(), Old: ot, New: nt, OldNode: o, NewNode: n})
+	}
+
+	olds, news := nodes(o.Members()), nodes(n.Members())
+
+	pairs := match(olds, news, func(om, nm ast.Node) bool {
+		return value(om) == value(nm)
+	})
+
+	for _, om := range olds {
+		nm, ok := pairs[om]
+
+		if !ok {
+			d.add(&Change{Kind: Removed, Path: path(om), OldNode: om})
+			continue
+		}
+
+		d.member(om, nm)
+	}
+
+	d.added(news, pairs)
+}
+
+func (d *differ) class(o, n *ast.ClassNode) {
+	if oq, nq := emsg(o), emsg(n); oq != nq {
+		d.add(&Change{Kind: TypeChanged, Path: o.Name(), Old: oq, New: nq, OldNode: o, NewNode: n})
+	}
+
+	if ob, nb := base(o), base(n); ob != nb {
+		d.add(&Change{Kind: TypeChanged, Path: o.Name(), Old: ob, New: nb, OldNode: o, NewNode: n})
+	}
+
+	if oldSize, newSize := size(o), size(n); oldSize != newSize && oldSize != "" && newSize != "" {
+		d.add(&Change{Kind: SizeChanged, Path: o.Name(), Old: oldSize, New: newSize, OldNode: o, NewNode: n})
+	}
+
+	olds, news := nodes(o.Consts()), nodes(n.Consts())
+
+	pairs := match(olds, news, func(oc, nc ast.Node) bool {
+		return value(oc) == value(nc) && d.propertyType(oc.(*ast.PropertyNode)) == propertyType(nc.(*ast.PropertyNode))
+	})
+
+	for _, oc := range olds {
+		if nc, ok := pairs[oc]; ok {
+			d.member(oc, nc)
+		} else {
+			d.add(&Change{Kind: Removed, Path: path(oc), OldNode: oc})
+		}
+	}
+
+	d.added(news, pairs)
+
+	oldFields, newFields := o.Properties(), n.Properties()
+	olds, news = nodes(oldFields), nodes(newFields)
+	index := make(map[ast.Node]int)
+
+	for i, f := range oldFields {
+		index[f] = i
+	}
+
+	for i, f := range newFields {
+		index[f] = i
+	}
+
+	pairs = match(olds, news, func(of, nf ast.Node) bool {
+		return index[of] == index[nf] && d.propertyType(of.(*ast.PropertyNode)) == propertyType(nf.(*ast.PropertyNode))
+	})
+
+	for _, of := range moved(olds, news, pairs) {
+		nf := pairs[of]
+		d.add(&Change{Kind: Moved, Path: path(of), Old: strconv.Itoa(index[of]), New: strconv.Itoa(index[nf]), OldNode: of, NewNode: nf})
+	}
+
+	for _, of := range olds {
+		if nf, ok := pairs[of]; ok {
+			d.member(of, nf)
+		} else {
+			d.add(&Change{Kind: Removed, Path: path(of), OldNode: of})
+		}
+	}
+
+	d.added(news, pairs)
+}
+
+// moved returns the matched nodes of olds out of order in news. Only the nodes
+// outside a longest sequence keeping its order are reported, so additions,
+// removals and a single move don't shift the nodes around them.
+func moved(olds, news []ast.Node, pairs map[ast.Node]ast.Node) []ast.Node {
+	var a, b []ast.Node
+
+	for _, o := range olds {
+		if _, ok := pairs[o]; ok {
+			a = append(a, o)
+		}
+	}
+
+	matched := make(map[ast.Node]bool)
+
+	for _, n := range pairs {
+		matched[n] = true
+	}
+
+	for _, n := range news {
+		if matched[n] {
+			b = append(b, n)
+		}
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and
+	// b[j:].
+	lcs := make([][]int, len(a)+1)
+
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case pairs[a[i]] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	kept := make(map[ast.Node]bool)
+
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case pairs[a[i]] == b[j]:
+			kept[a[i]] = true
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	var result []ast.Node
+
+	for _, o := range a {
+		if !kept[o] {
+			result = append(result, o)
+		}
+	}
+
+	return result
+}
+
+// member compares a matched enum member, constant or field.
+func (d *differ) member(o, n ast.Node) {
+	if o.Name() != n.Name() {
+		d.add(&Change{Kind: Renamed, Path: path(o), NewPath: path(n), OldNode: o, NewNode: n})
+	}
+
+	if op, ok := o.(*ast.PropertyNode); ok {
+		np := n.(*ast.PropertyNode)
+
+		if ot, nt := d.propertyType(op), propertyType(np); ot != nt {
+			d.add(&Change{Kind: TypeChanged, Path: path(o), Old: ot, New: nt, OldNode: o, NewNode: n})
+		}
+	}
+
+	if ov, nv := value(o), value(n); ov != nv {
+		d.add(&Change{Kind: ValueChanged, Path: path(o), Old: ov, New: nv, OldNode: o, NewNode: n})
+	}
+
+	if oa, na := attributes(o), attributes(n); oa != na {
+		d.add(&Change{Kind: AttributesChanged, Path: path(o), Old: oa, New: na, OldNode: o, NewNode: n})
+	}
+}
+
+// propertyType returns the type of p in the old schema, with renamed
+// declarations under their new names.
+func (d *differ) propertyType(p *ast.PropertyNode) string {
+	if p.Type != nil {
+		if name, ok := d.renames[p.Type.Value]; ok {
+			return strings.Replace(propertyType(p), p.Type.Value, name, 1)
+		}
+	}
+
+	return propertyType(p)
+}
+
+// match pairs the nodes of olds and news with the same name, then the
+// remaining ones for which same returns true, if each has a single candidate.
+func match(olds, news []ast.Node, same func(o, n ast.Node) bool) map[ast.Node]ast.Node {
+	pairs := make(map[ast.Node]ast.Node)
+	byName := make(map[string]ast.Node)
+	matched := make(map[ast.Node]bool)
+
+	for _, n := range news {
+		byName[n.Name()] = n
+	}
+
+	for _, o := range olds {
+		if n, ok := byName[o.Name()]; ok && kind(o) == kind(n) {
+			pairs[o] = n
+			matched[n] = true
+		}
+	}
+
+	for _, o := range olds {
+		if _, ok := pairs[o]; ok {
+			continue
+		}
+
+		var candidates []ast.Node
+
+		for _, n := range news {
+			if !matched[n] && same(o, n) {
+				candidates = append(candidates, n)
+			}
+		}
+
+		if len(candidates) != 1 {
+			continue
+		}
+
+		// The match must be unambiguous in both directions.
+		ambiguous := false
+
+		for _, other := range olds {
+			if _, ok := pairs[other]; !ok && other != o && same(other, candidates[0]) {
+				ambiguous = true
+				break
+			}
+		}
+
+		if !ambiguous {
+			pairs[o] = candidates[0]
+			matched[candidates[0]] = true
+		}
+	}
+
+	return pairs
+}
+
+func declarations(doc *ast.DocumentNode) []ast.Node {
+	var decls []ast.Node
+
+	for _, child := range doc.Types() {
+		switch child.(type) {
+		case *ast.ClassNode, *ast.EnumNode:
+			decls = append(decls, child)
+		}
+	}
+
+	return decls
+}
+
+func nodes(list interface{}) []ast.Node {
+	var result []ast.Node
+
+	switch list := list.(type) {
+	case []*ast.EnumMemberNode:
+		for _, n := range list {
+			result = append(result, n)
+		}
+	case []*ast.PropertyNode:
+		for _, n := range list {
+			result = append(result, n)
+		}
+	}
+
+	return result
+}
+
+func path(n ast.Node) string {
+	if parent := n.Parent(); parent != nil {
+		if _, ok := parent.(*ast.DocumentNode); !ok {
+			return parent.Name() + "::" + n.Name()
+		}
+	}
+
+	return n.Name()
+}
+
+// signature summarizes the shape of a declaration, to recognize renames.
+func signature(n ast.Node) string {
+	var parts []string
+
+	switch n := n.(type) {
+	case *ast.EnumNode:
+		parts = append(parts, enumType(n))
+
+		for _, m := range n.Members() {
+			parts = append(parts, m.Name()+"="+value(m))
+		}
+	case *ast.ClassNode:
+		for _, p := range n.Properties() {
+			parts = append(parts, propertyType(p)+" "+p.Name())
+		}
+	}
+
+	return strings.Join(parts, ";")
+}
+
+func enumType(n *ast.EnumNode) string {
+	if n.Flags {
+		return n.Type.String() + " flags"
+	}
+
+	return n.Type.String()
+}
+
+func emsg(n *ast.ClassNode) string {
+	if q := n.Qualifier; q != nil && q.Kind == ast.QualifierEMsg && q.Symbol != nil {
+		if q.Symbol.Node != nil {
+			return path(q.Symbol.Node)
+		}
+
+		return q.Symbol.Value
+	}
+
+	return ""
+}
+
+// base returns the name of the class n inherits from.
+func base(n *ast.ClassNode) string {
+	if c := n.BaseClass(); c != nil {
+		return path(c)
+	}
+
+	return strings.Join(n.BasePath, "::")
+}
+
+// propertyType renders the type of p with its flags and array size.
+func propertyType(p *ast.PropertyNode) string {
+	var parts []string
+
+	if p.Flags != ast.PropertyFlagNone && p.Flags != ast.PropertyFlagConst {
+		parts = append(parts, p.Flags.String())
+	}
+
+	typ := "int"
+
+	if p.Type != nil {
+		typ = p.Type.Value
+	}
+
+	if q := p.Qualifier; q != nil && q.Kind == ast.QualifierSize {
+		if size, err := q.Size(); err == nil {
+			typ += fmt.Sprintf("<%d>", size)
+		} else {
+			typ += "<" + strings.Join(q.Path, "::") + ">"
+		}
+	}
+
+	return strings.Join(append(parts, typ), " ")
+}
+
+// value renders the value of an enum member or constant, as evaluated, or the
+// default value expression of a field.
+func value(n ast.Node) string {
+	switch n := n.(type) {
+	case *ast.EnumMemberNode:
+		if v, ok := n.ResolvedValue(); ok {
+			return formatValue(v, n.Parent().(*ast.EnumNode).Type.Signed())
+		}
+	case *ast.PropertyNode:
+		if v, ok := n.ResolvedValue(); ok {
+			return formatValue(v, true)
+		}
+
+		if expr := ast.ValueExpr(n); expr != nil {
+			return expr.String()
+		}
+	}
+
+	return ""
+}
+
+func formatValue(v uint64, signed bool) string {
+	if signed {
+		return strconv.FormatInt(int64(v), 10)
+	}
+
+	return strconv.FormatUint(v, 10)
+}
+
+// attributes renders the obsolete and removed attributes of n.
+func attributes(n ast.Node) string {
+	var (
+		obsolete, removed             bool
+		obsoleteReason, removedReason string
+	)
+
+	switch n := n.(type) {
+	case *ast.PropertyNode:
+		obsolete, obsoleteReason, removed, removedReason = n.Obsolete, n.ObsoleteReason, n.Removed, n.RemovedReason
+	case *ast.EnumMemberNode:
+		obsolete, obsoleteReason, removed, removedReason = n.Obsolete, n.ObsoleteReason, n.Removed, n.RemovedReason
+	}
+
+	var attrs []string
+
+	if p, ok := n.(*ast.PropertyNode); ok && p.Optional {
+		attrs = append(attrs, "optional")
+	}
+
+	if p, ok := n.(*ast.PropertyNode); ok && p.ConditionPath != nil {
+		attrs = append(attrs, "if:"+strings.Join(p.ConditionPath, "::"))
+	}
+
+	if p, ok := n.(*ast.PropertyNode); ok && p.Endian != ast.LittleEndian {
+		attrs = append(attrs, "endian:"+p.Endian.String())
+	}
+
+	if p, ok := n.(*ast.PropertyNode); ok && p.Encoding != ast.EncodingUTF8 {
+		attrs = append(attrs, "encoding:"+p.Encoding.String())
+	}
+
+	if p, ok := n.(*ast.PropertyNode); ok && p.Termination != ast.TermLength {
+		attrs = append(attrs, "term:"+p.Termination.String())
+	}
+
+	for _, attr := range []struct {
+		set          bool
+		name, reason string
+	}{{obsolete, "obsolete", obsoleteReason}, {removed, "removed", removedReason}} {
+		if !attr.set {
+			continue
+		}
+
+		if attr.reason != "" {
+			attrs = append(attrs, fmt.Sprintf("%s %q", attr.name, attr.reason))
+		} else {
+			attrs = append(attrs, attr.name)
+		}
+	}
+
+	return strings.Join(attrs, " ")
+}
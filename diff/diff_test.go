@@ -0,0 +1,128 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+const oldSchema = `enum EMsg { Invalid = 0; Logon = 1; Logoff = 2; };
+enum EResult { OK = 1; Busy = 2; Fail = 3; };
+enum EGone { A = 1; };
+class MsgLogon<EMsg::Logon> {
+	const uint VERSION = 1;
+	uint protocol = 65575;
+	ulong steamID;
+	EResult result;
+	byte<16> key;
+	int a;
+	int b;
+	int c;
+};
+class MsgHdr { int x; int y; };
+`
+
+const newSchema = `enum EMsg { Invalid = 0; Logon = 1; Logoff = 2; };
+enum EResult<uint> { OK = 1; Occupied = 2; Fail = 4; Timeout = 5; obsolete };
+class MsgLogon<EMsg::Logoff> {
+	const uint VERSION = 2;
+	uint protocol = 65580;
+	steamidmarshal ulong steamID;
+	EResult result;
+	byte<32> key;
+	int c;
+	int a;
+	int b;
+	short added;
+};
+class MsgHeader { int x; int y; };
+enum EOld { A = 1; B = 2; };
+`
+
+func analyze(t *testing.T, src string) *ast.DocumentNode {
+	doc, err := parse.NewAnalyzer(token.NewTokenizer([]byte(src)), "schema.steamd").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	return doc
+}
+
+func TestSchemas(t *testing.T) {
+	changes, err := Schemas(analyze(t, oldSchema), analyze(t, newSchema))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	var actual []string
+
+	for _, c := range changes {
+		actual = append(actual, c.String())
+	}
+
+	expected := []string{
+		"changed type of EResult from int to uint",
+		"renamed member EResult::Busy to EResult::Occupied",
+		"changed value of EResult::Fail from 3 to 4",
+		"added member EResult::Timeout",
+		"removed enum EGone",
+		"changed type of MsgLogon from EMsg::Logon to EMsg::Logoff",
+		"changed size of MsgLogon from 44 to 62",
+		"changed value of MsgLogon::VERSION from 1 to 2",
+		"moved MsgLogon::c from position 6 to 4",
+		"changed value of MsgLogon::protocol from 65575 to 65580",
+		"changed type of MsgLogon::steamID from ulong to steamidmarshal ulong",
+		"changed type of MsgLogon::key from byte<16> to byte<32>",
+		"added field MsgLogon::added",
+		"renamed class MsgHdr to MsgHeader",
+		"added enum EOld",
+	}
+
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("mismatch: got\n%s\nbut expected\n%s", strings.Join(actual, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestSchemasAttributes(t *testing.T) {
+	from := analyze(t, `enum E { A = 1; B = 2; }; class C { int x; int y; };`)
+	to := analyze(t, `enum E { A = 1; B = 2; obsolete "use A" }; class C { int x; int z; removed };`)
+
+	changes, err := Schemas(from, to)
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	var actual []string
+
+	for _, c := range changes {
+		actual = append(actual, c.String())
+	}
+
+	expected := []string{
+		`changed attributes of E::B from none to obsolete "use A"`,
+		"renamed field C::y to C::z",
+		"changed attributes of C::y from none to removed",
+	}
+
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("mismatch: got\n%s\nbut expected\n%s", strings.Join(actual, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestSchemasEqual(t *testing.T) {
+	changes, err := Schemas(analyze(t, oldSchema), analyze(t, oldSchema))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if len(changes) != 0 {
+		t.Fatalf("mismatch: got %v, but expected no changes", changes)
+	}
+}
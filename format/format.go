@@ -0,0 +1,467 @@
+// Package format prints steamd ASTs as canonical source.
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+// Source parses src and returns it in canonical form. Comments the AST doesn't
+// keep are printed on their own line before the declaration, member or closing
+// brace that followed them.
+func Source(filename string, src []byte) ([]byte, error) {
+	doc, err := parse.NewAnalyzer(token.NewTokenizer(src), filename).Analyze()
+
+	if err != nil {
+		return nil, err
+	}
+
+	p := &printer{}
+
+	if err := p.scan(src); err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	p.w = bufio.NewWriter(buf)
+
+	if err := p.document(doc); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Document prints the imports and declarations of doc. Declarations pulled in
+// through imports aren't printed.
+func Document(w io.Writer, doc *ast.DocumentNode) error {
+	p := &printer{w: bufio.NewWriter(w)}
+	return p.document(doc)
+}
+
+func (p *printer) document(doc *ast.DocumentNode) error {
+	for _, imp := range doc.Imports {
+		p.comments(position{imp.Row, imp.Col})
+		p.printf("#import %s\n", token.Quote(imp.Path))
+	}
+
+	first := true
+
+	for _, decl := range doc.Declarations {
+		// nested declarations are printed with their namespace or class
+		if _, ok := decl.Parent().(*ast.DocumentNode); !ok {
+			continue
+		}
+
+		if !first || len(doc.Imports) > 0 {
+			p.printf("\n")
+		}
+
+		first = false
+
+		if err := p.node(decl); err != nil {
+			return err
+		}
+	}
+
+	if len(p.pending) > 0 && (len(doc.Imports) > 0 || !first) {
+		p.printf("\n")
+	}
+
+	p.comments(position{row: math.MaxInt32})
+
+	return p.flush()
+}
+
+// Node prints a single namespace, typedef, class, enum, union or property node.
+func Node(w io.Writer, n ast.Node) error {
+	p := &printer{w: bufio.NewWriter(w)}
+
+	if err := p.node(n); err != nil {
+		return err
+	}
+
+	return p.flush()
+}
+
+type printer struct {
+	w       *bufio.Writer
+	indent  int
+	err     error
+	pending []*comment
+	scopes  []scope
+}
+
+// position is the row and column of a token or node.
+type position struct {
+	row, col int
+}
+
+func (p position) before(other position) bool {
+	return p.row < other.row || p.row == other.row && p.col < other.col
+}
+
+// comment is a comment of the source yet to be printed.
+type comment struct {
+	pos      position
+	raw      string
+	value    string
+	trailing bool
+	blank    bool
+}
+
+// scope locates the braces of a namespace, class, enum or union.
+type scope struct {
+	open, close position
+}
+
+// scan collects the comments and braces of src. Comments following a token on
+// its line are trailing, and either become the line comment of a member or
+// are printed like the others.
+func (p *printer) scan(src []byte) error {
+	tokenizer := token.NewTokenizer(src)
+	tokenizer.SetTrivia(true)
+
+	var (
+		last  *comment
+		stack []int
+	)
+
+	add := func(trivia []*token.Token, trailing bool) {
+		for _, t := range trivia {
+			if t.Op == token.OpWhitespace && last != nil && strings.Count(t.ValueString(), "\n") > 1 {
+				last.blank = true
+			}
+
+			if t.Op != token.OpComment {
+				continue
+			}
+
+			last = &comment{
+				pos:      position{t.Row, t.Col},
+				raw:      strings.TrimRight(string(t.Raw), "\n"),
+				value:    strings.TrimSpace(t.ValueString()),
+				trailing: trailing,
+			}
+
+			p.pending = append(p.pending, last)
+		}
+	}
+
+	for {
+		t, err := tokenizer.Next()
+
+		if err == io.EOF {
+			add(tokenizer.Trivia(), false)
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		add(t.Leading, false)
+		last = nil
+
+		switch {
+		case t.Op == token.OpOperator && t.ValueEqualString("{"):
+			stack = append(stack, len(p.scopes))
+			p.scopes = append(p.scopes, scope{open: position{t.Row, t.Col}})
+		case t.Op == token.OpOperator && t.ValueEqualString("}") && len(stack) > 0:
+			p.scopes[stack[len(stack)-1]].close = position{t.Row, t.Col}
+			stack = stack[:len(stack)-1]
+		}
+
+		add(t.Trailing, true)
+	}
+}
+
+// comments prints the pending comments preceding pos.
+func (p *printer) comments(pos position) {
+	for len(p.pending) > 0 && p.pending[0].pos.before(pos) {
+		c := p.pending[0]
+		p.pending = p.pending[1:]
+		p.printf("%s%s\n", p.indentation(), c.raw)
+
+		if c.blank && len(p.pending) > 0 {
+			p.printf("\n")
+		}
+	}
+}
+
+// closing returns the position of the brace closing the scope of the node at
+// pos, or the end of the input if it's unknown.
+func (p *printer) closing(pos position) position {
+	for _, s := range p.scopes {
+		if pos.before(s.open) {
+			if s.close.row > 0 {
+				return s.close
+			}
+
+			break
+		}
+	}
+
+	return position{row: math.MaxInt32}
+}
+
+// lineComment prints the comment following a member on its line, dropping the
+// pending comment it was read from.
+func (p *printer) lineComment(text string) {
+	if text == "" {
+		return
+	}
+
+	for i, c := range p.pending {
+		if c.trailing && c.value == text {
+			p.pending = append(p.pending[:i:i], p.pending[i+1:]...)
+			break
+		}
+	}
+
+	p.printf(" // %s", text)
+}
+
+func (p *printer) printf(format string, v ...interface{}) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, v...)
+	}
+}
+
+func (p *printer) flush() error {
+	if p.err != nil {
+		return p.err
+	}
+
+	return p.w.Flush()
+}
+
+func (p *printer) node(n ast.Node) error {
+	switch n := n.(type) {
+	case *ast.NamespaceNode:
+		p.namespace(n)
+	case *ast.TypedefNode:
+		p.comments(position{n.Row, n.Col})
+		p.doc(n.Doc)
+		p.printf("%stypedef %s = %s;", p.indentation(), n.Name(), n.Type.Value)
+		p.lineComment(n.Comment)
+		p.printf("\n")
+	case *ast.ClassNode:
+		p.comments(position{n.Row, n.Col})
+		p.doc(n.Doc)
+		p.printf("%sclass %s%s", p.indentation(), n.Name(), qualifier(n.Qualifier))
+
+		if n.BasePath != nil {
+			p.printf(" : %s", strings.Join(n.BasePath, "::"))
+		}
+
+		p.scope(n, position{n.Row, n.Col})
+	case *ast.EnumNode:
+		p.comments(position{n.Row, n.Col})
+		p.doc(n.Doc)
+		p.printf("%senum %s%s", p.indentation(), n.Name(), qualifier(n.Qualifier))
+
+		if n.Flags {
+			p.printf(" flags")
+		}
+
+		p.scope(n, position{n.Row, n.Col})
+	case *ast.UnionNode:
+		p.comments(position{n.Row, n.Col})
+		p.doc(n.Doc)
+		p.printf("%sunion %s%s", p.indentation(), n.Name(), qualifier(n.Qualifier))
+		p.scope(n, position{n.Row, n.Col})
+	case *ast.UnionVariantNode:
+		p.variant(n)
+	case *ast.EnumMemberNode:
+		p.enumMember(n)
+	case *ast.PropertyNode:
+		p.property(n)
+	default:
+		return fmt.Errorf("Cannot format node %T", n)
+	}
+
+	return p.err
+}
+
+func (p *printer) scope(n ast.Node, pos position) {
+	p.printf(" {\n")
+	p.indent++
+
+	nested := false
+
+	for i, child := range n.Children() {
+		switch child := child.(type) {
+		case *ast.EnumMemberNode:
+			p.enumMember(child)
+		case *ast.UnionVariantNode:
+			p.variant(child)
+		case *ast.PropertyNode:
+			if nested {
+				p.printf("\n")
+			}
+
+			p.property(child)
+			nested = false
+		case *ast.ClassNode, *ast.EnumNode:
+			// nested declarations are set apart by blank lines
+			if i > 0 {
+				p.printf("\n")
+			}
+
+			p.node(child)
+			nested = true
+		}
+	}
+
+	p.comments(p.closing(pos))
+	p.indent--
+	p.printf("%s};\n", p.indentation())
+}
+
+func (p *printer) namespace(n *ast.NamespaceNode) {
+	p.comments(position{n.Row, n.Col})
+	p.doc(n.Doc)
+	p.printf("%snamespace %s {\n", p.indentation(), n.Name())
+	p.indent++
+
+	for i, child := range n.Children() {
+		if i > 0 {
+			p.printf("\n")
+		}
+
+		p.node(child)
+	}
+
+	p.comments(p.closing(position{n.Row, n.Col}))
+	p.indent--
+	p.printf("%s}\n", p.indentation())
+}
+
+func (p *printer) indentation() string {
+	return strings.Repeat("\t", p.indent)
+}
+
+func (p *printer) property(n *ast.PropertyNode) {
+	p.comments(position{n.Row, n.Col})
+	p.doc(n.Doc)
+	p.printf("%s", strings.Repeat("\t", p.indent))
+
+	if n.Optional {
+		p.printf("optional ")
+	}
+
+	if n.Flags != ast.PropertyFlagNone {
+		p.printf("%s ", n.Flags)
+	}
+
+	if n.Alias != nil {
+		p.printf("%s%s ", n.Alias.Value, qualifier(n.Qualifier))
+	} else if n.Type != nil {
+		p.printf("%s%s ", n.Type.Value, qualifier(n.Qualifier))
+	}
+
+	p.printf("%s", n.Name())
+
+	if n.ConditionPath != nil {
+		p.printf(" if:%s", strings.Join(n.ConditionPath, "::"))
+	}
+
+	if n.Endian != ast.LittleEndian {
+		p.printf(" endian:%s", n.Endian)
+	}
+
+	if n.Encoding != ast.EncodingUTF8 {
+		p.printf(" encoding:%s", n.Encoding)
+	}
+
+	if n.Termination != ast.TermLength {
+		p.printf(" term:%s", n.Termination)
+	}
+
+	p.value(n, n.Expr != nil || len(n.Default) > 0)
+	p.attributes(n.Obsolete, n.ObsoleteReason, n.Removed, n.RemovedReason, n.Comment)
+}
+
+func (p *printer) enumMember(n *ast.EnumMemberNode) {
+	p.comments(position{n.Row, n.Col})
+	p.doc(n.Doc)
+	p.printf("%s%s", strings.Repeat("\t", p.indent), n.Name())
+	p.value(n, n.Expr != nil || len(n.Default) > 0)
+	p.attributes(n.Obsolete, n.ObsoleteReason, n.Removed, n.RemovedReason, n.Comment)
+}
+
+func (p *printer) variant(n *ast.UnionVariantNode) {
+	p.comments(position{n.Row, n.Col})
+	p.doc(n.Doc)
+	p.printf("%s%s %s;", p.indentation(), n.Type.Value, n.Name())
+	p.attributes(false, "", false, "", n.Comment)
+}
+
+func (p *printer) value(n ast.Node, explicit bool) {
+	if explicit {
+		p.printf(" = %s", ast.ValueExpr(n))
+	}
+
+	p.printf(";")
+}
+
+func (p *printer) attributes(obsolete bool, obsoleteReason string, removed bool, removedReason string, comment string) {
+	if obsolete {
+		p.attribute("obsolete", obsoleteReason)
+	}
+
+	if removed {
+		p.attribute("removed", removedReason)
+	}
+
+	p.lineComment(comment)
+	p.printf("\n")
+}
+
+func (p *printer) attribute(name, reason string) {
+	p.printf(" %s", name)
+
+	if reason != "" {
+		p.printf(" %s", token.Quote(reason))
+	}
+}
+
+func (p *printer) doc(doc string) {
+	if doc == "" {
+		return
+	}
+
+	indent := strings.Repeat("\t", p.indent)
+
+	for _, line := range strings.Split(doc, "\n") {
+		if line == "" {
+			p.printf("%s///\n", indent)
+		} else {
+			p.printf("%s/// %s\n", indent, line)
+		}
+	}
+}
+
+func qualifier(q *ast.Qualifier) string {
+	if q == nil {
+		return ""
+	}
+
+	switch {
+	case len(q.Path) > 0:
+		return "<" + strings.Join(q.Path, "::") + ">"
+	case q.IsLiteral():
+		return "<" + q.Value + ">"
+	default:
+		return "<" + q.Symbol.Value + ">"
+	}
+}
@@ -0,0 +1,325 @@
+package format
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	input := `#import   "base.steamd"
+/// Message types.
+enum EMsg<uint>   {
+Invalid=0;
+Next ;
+ /// Multiple messages.
+     Multi = 1 ;   //  wrapped
+};
+enum EAccountFlags flags { NormalUser = 0; PersonaNameSet = 1; Unbannable = 0x2;
+	Admin = PersonaNameSet|EAccountFlags::Unbannable; Beta = 4; obsolete "not used" Gamma = 8; obsolete; Epsilon = 32;removed   "gone" obsolete
+	Delta=(Admin|0x10)<<1+NormalUser;
+};
+class MsgHdr<EMsg::Multi> { const uint VERSION = 1; byte<20> key; EMsg msg = EMsg::Invalid; };
+`
+
+	expected := `#import "base.steamd"
+
+/// Message types.
+enum EMsg<uint> {
+	Invalid = 0;
+	Next;
+	/// Multiple messages.
+	Multi = 1; // wrapped
+};
+
+enum EAccountFlags flags {
+	NormalUser = 0;
+	PersonaNameSet = 1;
+	Unbannable = 0x2;
+	Admin = PersonaNameSet | EAccountFlags::Unbannable;
+	Beta = 4; obsolete "not used"
+	Gamma = 8; obsolete
+	Epsilon = 32; obsolete removed "gone"
+	Delta = (Admin | 0x10) << 1 + NormalUser;
+};
+
+class MsgHdr<EMsg::Multi> {
+	const uint VERSION = 1;
+	byte<20> key;
+	EMsg msg = EMsg::Invalid;
+};
+`
+
+	dir, err := ioutil.TempDir("", "steamd")
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	base := filepath.Join(dir, "base.steamd")
+
+	if err := ioutil.WriteFile(base, []byte("enum EResult { OK = 1; };"), 0644); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	filename := filepath.Join(dir, "main.steamd")
+	got, err := Source(filename, []byte(input))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if string(got) != expected {
+		t.Fatalf("mismatch: got %q, but expected %q", got, expected)
+	}
+
+	again, err := Source(filename, got)
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if string(again) != string(got) {
+		t.Fatalf("mismatch: got %q, but expected %q", again, got)
+	}
+}
+
+func TestSourceNamespaces(t *testing.T) {
+	input := `namespace Steam { /// Message types.
+enum EMsg { Multi = 1; };
+namespace GC { class MsgGCHdr<EMsg::Multi> { EMsg msg; }; } }
+class Msg { };
+`
+
+	expected := `namespace Steam {
+	/// Message types.
+	enum EMsg {
+		Multi = 1;
+	};
+
+	namespace GC {
+		class MsgGCHdr<EMsg::Multi> {
+			EMsg msg;
+		};
+	}
+}
+
+class Msg {
+};
+`
+
+	got, err := Source("", []byte(input))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if string(got) != expected {
+		t.Fatalf("mismatch: got %q, but expected %q", got, expected)
+	}
+}
+
+func TestSourceComments(t *testing.T) {
+	input := `// Copyright header.
+// Second line.
+
+/* Message types. */
+enum EMsg { // the rest is reserved
+	// Pre-login.
+	Invalid = 0; // unused
+	Multi = 1;
+	// More to come.
+};
+
+namespace Steam {
+	typedef JobID = ulong; // job identifier
+	// Keep in sync with the client.
+	class Msg { uint a; /* inline */ uint b; };
+}
+
+// Trailing notes.
+`
+
+	expected := `// Copyright header.
+// Second line.
+
+/* Message types. */
+enum EMsg {
+	// the rest is reserved
+	// Pre-login.
+	Invalid = 0; // unused
+	Multi = 1;
+	// More to come.
+};
+
+namespace Steam {
+	typedef JobID = ulong; // job identifier
+
+	// Keep in sync with the client.
+	class Msg {
+		uint a; // inline
+		uint b;
+	};
+}
+
+// Trailing notes.
+`
+
+	got, err := Source("", []byte(input))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if string(got) != expected {
+		t.Fatalf("mismatch: got %q, but expected %q", got, expected)
+	}
+
+	again, err := Source("", got)
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if string(again) != string(got) {
+		t.Fatalf("mismatch: got %q, but expected %q", again, got)
+	}
+}
+
+func TestSourceBaseClasses(t *testing.T) {
+	input := "class MsgHdr { uint msg; };\nclass MsgFoo:MsgHdr{uint foo;};\n"
+	expected := "class MsgHdr {\n\tuint msg;\n};\n\nclass MsgFoo : MsgHdr {\n\tuint foo;\n};\n"
+
+	got, err := Source("", []byte(input))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if string(got) != expected {
+		t.Fatalf("mismatch: got %q, but expected %q", got, expected)
+	}
+}
+
+func TestSourceNestedDeclarations(t *testing.T) {
+	input := "class C {uint a;enum E { A = 1; };class D { E e; };E e;};\n"
+	expected := "class C {\n\tuint a;\n\n\tenum E {\n\t\tA = 1;\n\t};\n\n\tclass D {\n\t\tE e;\n\t};\n\n\tE e;\n};\n"
+
+	got, err := Source("", []byte(input))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if string(got) != expected {
+		t.Fatalf("mismatch: got %q, but expected %q", got, expected)
+	}
+}
+
+func TestSourceOptionalFields(t *testing.T) {
+	input := "class C { uint a; optional   steamidmarshal ulong id; };\n"
+	expected := "class C {\n\tuint a;\n\toptional steamidmarshal ulong id;\n};\n"
+
+	got, err := Source("", []byte(input))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if string(got) != expected {
+		t.Fatalf("mismatch: got %q, but expected %q", got, expected)
+	}
+}
+
+func TestSourceConditionalFields(t *testing.T) {
+	input := "class C { E flags; uint a  if:E::A=1; steamidmarshal ulong id if:E::B; };\n"
+	expected := "class C {\n\tE flags;\n\tuint a if:E::A = 1;\n\tsteamidmarshal ulong id if:E::B;\n};\n"
+
+	got, err := Source("", []byte(input))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if string(got) != expected {
+		t.Fatalf("mismatch: got %q, but expected %q", got, expected)
+	}
+}
+
+func TestSourceEndian(t *testing.T) {
+	input := "class C { E flags; uint a  if:E::A   endian:be=1; ushort<2> ids endian:le; };\n"
+	expected := "class C {\n\tE flags;\n\tuint a if:E::A endian:be = 1;\n\tushort<2> ids;\n};\n"
+
+	got, err := Source("", []byte(input))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if string(got) != expected {
+		t.Fatalf("mismatch: got %q, but expected %q", got, expected)
+	}
+}
+
+func TestSourceStrings(t *testing.T) {
+	input := "class C { byte n; string<n> s  term:length encoding:utf16; string name term:null encoding:utf8; };\n"
+	expected := "class C {\n\tbyte n;\n\tstring<n> s encoding:utf16;\n\tstring name term:null;\n};\n"
+
+	got, err := Source("", []byte(input))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if string(got) != expected {
+		t.Fatalf("mismatch: got %q, but expected %q", got, expected)
+	}
+}
+
+func TestSourceStringEscapes(t *testing.T) {
+	input := "enum E { A = 1; obsolete \"use \\\"B\\\"\\nnow\" };\n"
+	expected := "enum E {\n\tA = 1; obsolete \"use \\\"B\\\"\\nnow\"\n};\n"
+
+	got, err := Source("", []byte(input))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if string(got) != expected {
+		t.Fatalf("mismatch: got %q, but expected %q", got, expected)
+	}
+}
+
+func TestSourceUnions(t *testing.T) {
+	input := "enum E { A; B; };\n/// Payload.\nunion U<E>{ C A ;\n/// Second.\nD   B; // last\n};\n"
+	expected := "enum E {\n\tA;\n\tB;\n};\n\n/// Payload.\nunion U<E> {\n\tC A;\n\t/// Second.\n\tD B; // last\n};\n"
+
+	got, err := Source("", []byte(input))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if string(got) != expected {
+		t.Fatalf("mismatch: got %q, but expected %q", got, expected)
+	}
+}
+
+func TestSourceTypedefs(t *testing.T) {
+	input := "/// Job identifier.\ntypedef   JobID=ulong ;// unique\nclass C { JobID<2>   jobs; };\n"
+	expected := "/// Job identifier.\ntypedef JobID = ulong; // unique\n\nclass C {\n\tJobID<2> jobs;\n};\n"
+
+	got, err := Source("", []byte(input))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if string(got) != expected {
+		t.Fatalf("mismatch: got %q, but expected %q", got, expected)
+	}
+}
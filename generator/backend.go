@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/backend"
+)
+
+func init() {
+	backend.Register(goBackend{})
+}
+
+// goBackend generates a Go file per document with a Generator. It takes the
+// "steamid_type", "gameid_type", "imports" (comma-separated), "template" (the
+// text of the template), "fingerprint" (the name of the fingerprint constant,
+// "-" to leave it out) and "embed_source" ("true" to set EmbedSource) params.
+// The fingerprint constant is named after the file by default, like
+// SteammsgFingerprint.
+type goBackend struct{}
+
+func (goBackend) Name() string {
+	return "go"
+}
+
+func (goBackend) Generate(doc *ast.DocumentNode, opts backend.Options) ([]backend.OutputFile, error) {
+	g := NewGenerator(opts.Package)
+	g.SkipRemoved = opts.SkipRemoved
+	g.SteamIDType = opts.Params["steamid_type"]
+	g.GameIDType = opts.Params["gameid_type"]
+	g.Fingerprint = ast.Fingerprint(doc)
+	g.FingerprintConst = fingerprintConst(doc)
+	g.EmbedSource = opts.Params["embed_source"] == "true"
+
+	switch name := opts.Params["fingerprint"]; name {
+	case "":
+	case "-":
+		g.FingerprintConst = ""
+	default:
+		g.FingerprintConst = name
+	}
+
+	if imports := opts.Params["imports"]; imports != "" {
+		g.Imports = strings.Split(imports, ",")
+	}
+
+	if text, ok := opts.Params["template"]; ok {
+		if err := g.ParseTemplate("template", text); err != nil {
+			return nil, err
+		}
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := g.GenerateNodes(buf, backend.Nodes(doc, opts)); err != nil {
+		return nil, err
+	}
+
+	return []backend.OutputFile{{Name: backend.OutputName(doc, ".go"), Content: buf.Bytes()}}, nil
+}
+
+// fingerprintConst names the fingerprint constant of doc after its file.
+func fingerprintConst(doc *ast.DocumentNode) string {
+	var b strings.Builder
+	upper := true
+
+	for _, r := range strings.TrimSuffix(backend.OutputName(doc, ".go"), ".go") {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) && b.Len() > 0:
+			if upper {
+				r = unicode.ToUpper(r)
+			}
+
+			b.WriteRune(r)
+			upper = false
+		default:
+			upper = true
+		}
+	}
+
+	return b.String() + "Fingerprint"
+}
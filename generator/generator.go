@@ -0,0 +1,692 @@
+// Package generator is the "go" backend, generating Go structs and typed
+// constants from ast trees. It's the generation package of the token, ast and
+// parse layout, named generator rather than gen as importers already use it.
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/backend"
+	steamformat "github.com/13k/go-steam-language/format"
+)
+
+const (
+	header = "// Code generated by go-steam-language. DO NOT EDIT.\n\n"
+
+	defaultEnumType = "int32"
+)
+
+var builtinTypes = map[string]string{
+	"byte":   "uint8",
+	"sbyte":  "int8",
+	"short":  "int16",
+	"ushort": "uint16",
+	"int":    "int32",
+	"uint":   "uint32",
+	"long":   "int64",
+	"ulong":  "uint64",
+	"float":  "float32",
+	"double": "float64",
+	"bool":   "bool",
+	"string": "string",
+}
+
+// Generator emits Go source for the classes and enums of a parsed schema.
+type Generator struct {
+	Package string
+	// SkipRemoved omits enum members and class constants marked `removed`.
+	// Removed fields are always kept since they're part of the wire layout.
+	SkipRemoved bool
+	// SteamIDType and GameIDType are the Go types of fields flagged
+	// steamidmarshal and gameidmarshal. They must be convertible from uint64,
+	// which is used when empty.
+	SteamIDType string
+	GameIDType  string
+	// Imports are additional packages imported by the generated code, like the
+	// ones declaring SteamIDType and GameIDType.
+	Imports []string
+	// Template, when set, generates files instead of the built-in code. It's
+	// executed with a *TemplateData, see ParseTemplate. Its output is
+	// formatted as Go source.
+	Template *template.Template
+	// Fingerprint, when set, is declared as the FingerprintConst constant, to
+	// check code was generated from the expected schema, see ast.Fingerprint.
+	Fingerprint      string
+	FingerprintConst string
+	// EmbedSource adds the steamd declaration of each type and member, and
+	// where it's found, to its comment, to trace generated code back to the
+	// schema.
+	EmbedSource bool
+}
+
+func NewGenerator(pkg string) *Generator {
+	return &Generator{Package: pkg}
+}
+
+// Generate emits the classes, enums and unions of root, looking into
+// namespaces.
+func (g *Generator) Generate(w io.Writer, root ast.Node) error {
+	nodes := root.Children()
+
+	switch n := root.(type) {
+	case *ast.DocumentNode:
+		nodes = n.Types()
+	case *ast.NamespaceNode:
+		nodes = n.Declarations()
+	}
+
+	return g.GenerateNodes(w, nodes)
+}
+
+// GenerateNodes emits a single Go file containing the given top-level nodes.
+func (g *Generator) GenerateNodes(w io.Writer, nodes []ast.Node) error {
+	buf := &bytes.Buffer{}
+
+	if g.Template != nil {
+		if err := g.generateTemplate(buf, nodes); err != nil {
+			return err
+		}
+
+		return g.writeSource(w, buf)
+	}
+
+	fmt.Fprintf(buf, "%spackage %s\n", header, g.Package)
+
+	imports := stdImports(nodes)
+
+	if len(imports) > 0 || len(g.Imports) > 0 {
+		fmt.Fprintf(buf, "\nimport (\n")
+
+		for _, path := range imports {
+			fmt.Fprintf(buf, "%q\n", path)
+		}
+
+		if len(imports) > 0 && len(g.Imports) > 0 {
+			fmt.Fprintf(buf, "\n")
+		}
+
+		for _, path := range g.Imports {
+			fmt.Fprintf(buf, "%q\n", path)
+		}
+
+		fmt.Fprintf(buf, ")\n")
+	}
+
+	if g.Fingerprint != "" && g.FingerprintConst != "" {
+		fmt.Fprintf(buf, "\n// %s is the fingerprint of the schema this file was generated from.\n", g.FingerprintConst)
+		fmt.Fprintf(buf, "const %s = %q\n", g.FingerprintConst, g.Fingerprint)
+	}
+
+	for _, child := range nodes {
+		var err error
+
+		switch n := child.(type) {
+		case *ast.TypedefNode:
+			err = g.generateTypedef(buf, n)
+		case *ast.EnumNode:
+			err = g.generateEnum(buf, n)
+		case *ast.ClassNode:
+			err = g.generateClass(buf, n)
+		case *ast.UnionNode:
+			err = g.generateUnion(buf, n)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return g.writeSource(w, buf)
+}
+
+func (g *Generator) writeSource(w io.Writer, buf *bytes.Buffer) error {
+	src, err := format.Source(buf.Bytes())
+
+	if err != nil {
+		return fmt.Errorf("Generated invalid Go code: %v", err)
+	}
+
+	_, err = w.Write(src)
+
+	return err
+}
+
+// stdImports returns the standard library packages used by the code generated
+// for nodes.
+func stdImports(nodes []ast.Node) []string {
+	used := make(map[string]bool)
+
+	for _, child := range nodes {
+		switch n := child.(type) {
+		case *ast.EnumNode:
+			if len(n.Members()) > 0 {
+				used["fmt"] = true
+
+				if n.Flags {
+					used["strings"] = true
+				}
+			}
+		case *ast.ClassNode:
+			for _, path := range serializeImports {
+				used[path] = true
+			}
+
+			// lengths and null-terminated strings are checked
+			for _, f := range n.Fields() {
+				if f.LengthField() != nil {
+					used["fmt"] = true
+				}
+
+				if f.Termination == ast.TermNull {
+					used["fmt"], used["strings"] = true, true
+				}
+
+				if f.Encoding == ast.EncodingUTF16 {
+					used["unicode/utf16"] = true
+				}
+			}
+		case *ast.UnionNode:
+			for _, path := range serializeImports {
+				used[path] = true
+			}
+
+			used["fmt"] = true
+		}
+	}
+
+	var imports []string
+
+	for path := range used {
+		imports = append(imports, path)
+	}
+
+	sort.Strings(imports)
+
+	return imports
+}
+
+// generateTypedef emits a named type for typedefs of builtin types, so fields
+// declared with them aren't bare integers, and an alias for typedefs of
+// classes, enums and other typedefs, keeping their methods.
+func (g *Generator) generateTypedef(w io.Writer, n *ast.TypedefNode) error {
+	if t, ok := builtinTypes[n.Type.Value]; ok && !isDeclaration(n.Type) {
+		fmt.Fprintf(w, "\n%stype %s %s%s\n", g.withSource(docComment(n.Doc), n), typeName(n), t, lineComment(n.Comment))
+		return nil
+	}
+
+	if !isDeclaration(n.Type) {
+		return fmt.Errorf("Cannot generate typedef %s of unknown type %s", backend.QualifiedName(n), n.Type.Value)
+	}
+
+	fmt.Fprintf(w, "\n%stype %s = %s%s\n", g.withSource(docComment(n.Doc), n), typeName(n), typeName(n.Type.Node), lineComment(n.Comment))
+
+	return nil
+}
+
+// isDeclaration reports whether sym names a class, enum, union or typedef.
+func isDeclaration(sym *ast.Symbol) bool {
+	switch sym.Node.(type) {
+	case *ast.ClassNode, *ast.EnumNode, *ast.UnionNode, *ast.TypedefNode:
+		return true
+	}
+
+	return false
+}
+
+func (g *Generator) generateEnum(w io.Writer, n *ast.EnumNode) error {
+	name := typeName(n)
+	fmt.Fprintf(w, "\n%stype %s %s\n", g.withSource(docComment(n.Doc), n), name, enumType(n))
+
+	members := n.Members()
+
+	if len(members) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(w, "\nconst (\n")
+
+	for _, m := range members {
+		if g.SkipRemoved && m.Removed {
+			continue
+		}
+
+		value, reduced, err := backend.ReducedValue(m)
+
+		if err == nil && !reduced {
+			value, err = g.expr(m)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "%s%s %s = %s%s\n", g.withSource(memberComment(m), m), constName(n, m), name, value, lineComment(m.Comment))
+	}
+
+	fmt.Fprintf(w, ")\n")
+
+	if err := g.generateStringer(w, n); err != nil {
+		return err
+	}
+
+	return g.generateHelpers(w, n)
+}
+
+func (g *Generator) generateClass(w io.Writer, n *ast.ClassNode) error {
+	consts := n.Consts()
+	fields := n.Fields()
+	name := typeName(n)
+
+	if len(consts) > 0 {
+		fmt.Fprintf(w, "\nconst (\n")
+
+		for _, c := range consts {
+			if g.SkipRemoved && c.Removed {
+				continue
+			}
+
+			value, err := g.expr(c)
+
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(w, "%s%s %s = %s%s\n", g.withSource(memberComment(c), c), constName(n, c), g.fieldType(c), value, lineComment(c.Comment))
+		}
+
+		fmt.Fprintf(w, ")\n")
+	}
+
+	fmt.Fprintf(w, "\n%stype %s struct {\n", g.withSource(docComment(n.Doc), n), name)
+
+	for _, f := range fields {
+		typ := g.fieldType(f)
+
+		// optional fields are nil when absent
+		if f.Optional {
+			typ = "*" + typ
+		}
+
+		fmt.Fprintf(w, "%s%s %s%s\n", g.withSource(memberComment(f), f), fieldName(f.Name()), typ, lineComment(f.Comment))
+	}
+
+	fmt.Fprintf(w, "}\n")
+
+	var defaults []string
+
+	for _, f := range fields {
+		if len(f.Default) == 0 {
+			continue
+		}
+
+		value, err := g.expr(f)
+
+		if err != nil {
+			return err
+		}
+
+		if f.Flags == ast.PropertyFlagBoolMarshal {
+			value = boolValue(f, value)
+		}
+
+		defaults = append(defaults, fmt.Sprintf("%s: %s,", fieldName(f.Name()), value))
+	}
+
+	fmt.Fprintf(w, "\n// New%s returns a %s initialized with its default values.\n", name, name)
+	fmt.Fprintf(w, "func New%s() *%s {\nreturn &%s{\n%s\n}\n}\n", name, name, name, strings.Join(defaults, "\n"))
+
+	if err := g.generateEMsg(w, n); err != nil {
+		return err
+	}
+
+	return g.generateSerializers(w, n)
+}
+
+// generateEMsg emits a GetEMsg method returning the message type n is bound to
+// by its qualifier, if any.
+func (g *Generator) generateEMsg(w io.Writer, n *ast.ClassNode) error {
+	q := n.Qualifier
+
+	if q == nil || q.Kind != ast.QualifierEMsg || q.Symbol == nil {
+		return nil
+	}
+
+	member, ok := q.Symbol.Node.(*ast.EnumMemberNode)
+
+	if !ok {
+		return nil
+	}
+
+	enum := member.Parent()
+	value, err := g.memberValue(member)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\n// GetEMsg returns the message type of %s.\n", typeName(n))
+	fmt.Fprintf(w, "func (m *%s) GetEMsg() %s {\nreturn %s\n}\n", typeName(n), typeName(enum), value)
+
+	return nil
+}
+
+// memberValue returns the constant of member, or its value converted to the
+// enum type if SkipRemoved omits the constant.
+func (g *Generator) memberValue(member *ast.EnumMemberNode) (string, error) {
+	enum := member.Parent()
+
+	if !g.SkipRemoved || !member.Removed {
+		return constName(enum, member), nil
+	}
+
+	v, err := g.resolvedValue(member)
+
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s(%s)", typeName(enum), v), nil
+}
+
+func (g *Generator) fieldType(prop *ast.PropertyNode) string {
+	var name string
+
+	switch prop.Flags {
+	case ast.PropertyFlagBoolMarshal:
+		return "bool"
+	case ast.PropertyFlagSteamIDMarshal:
+		return typeOrDefault(g.SteamIDType, "uint64")
+	case ast.PropertyFlagGameIDMarshal:
+		return typeOrDefault(g.GameIDType, "uint64")
+	case ast.PropertyFlagProto:
+		// the encoded message, left to a protobuf package to decode
+		return "[]byte"
+	}
+
+	if prop.Alias != nil {
+		name = typeName(prop.Alias.Node)
+	} else if prop.Type == nil {
+		name = defaultEnumType
+	} else if t, ok := builtinTypes[prop.Type.Value]; ok {
+		name = t
+	} else if decl, ok := prop.Type.Node.(*ast.EnumNode); ok {
+		name = typeName(decl)
+	} else if decl, ok := prop.Type.Node.(*ast.ClassNode); ok && prop.Type.Value == decl.Name() {
+		name = typeName(decl)
+	} else if decl, ok := prop.Type.Node.(*ast.UnionNode); ok {
+		name = typeName(decl)
+	} else {
+		name = prop.Type.Value
+	}
+
+	if size, ok, err := prop.ArrayLen(); ok && err == nil {
+		if name == "uint8" {
+			name = "byte"
+		}
+
+		return fmt.Sprintf("[%d]%s", size, name)
+	}
+
+	// variable length strings hold bytes, and other types are slices
+	if prop.LengthField() != nil && name != "string" {
+		if name == "uint8" {
+			name = "byte"
+		}
+
+		return "[]" + name
+	}
+
+	return name
+}
+
+func (g *Generator) expr(prop ast.Node) (string, error) {
+	return g.exprString(prop, ast.ValueExpr(prop))
+}
+
+func (g *Generator) exprString(prop ast.Node, expr ast.Expr) (string, error) {
+	switch x := expr.(type) {
+	case *ast.LiteralExpr:
+		return backend.FormatLiteral(x), nil
+	case *ast.ParenExpr:
+		inner, err := g.exprString(prop, x.X)
+		return "(" + inner + ")", err
+	case *ast.SymbolExpr:
+		return g.symbolExpr(prop, x)
+	case *ast.BinaryExpr:
+		lhs, err := g.operandString(prop, x, x.X, false)
+
+		if err != nil {
+			return "", err
+		}
+
+		rhs, err := g.operandString(prop, x, x.Y, true)
+
+		if err != nil {
+			return "", err
+		}
+
+		return lhs + " " + x.Op.String() + " " + rhs, nil
+	case nil:
+		return "", fmt.Errorf("Missing value of %s", backend.QualifiedName(prop))
+	default:
+		return "", fmt.Errorf("Cannot generate %v in value of %s", expr, backend.QualifiedName(prop))
+	}
+}
+
+// operandString renders an operand of parent. Go gives `|` the same precedence
+// as `+` and ranks shifts above both, so operands whose grouping would change
+// are parenthesized.
+func (g *Generator) operandString(prop ast.Node, parent *ast.BinaryExpr, operand ast.Expr, right bool) (string, error) {
+	s, err := g.exprString(prop, operand)
+
+	if err != nil {
+		return "", err
+	}
+
+	if x, ok := operand.(*ast.BinaryExpr); ok {
+		p, q := goPrecedence(x.Op), goPrecedence(parent.Op)
+
+		if p < q || (p == q && right) {
+			s = "(" + s + ")"
+		}
+	}
+
+	return s, nil
+}
+
+func goPrecedence(op ast.BinaryOp) int {
+	switch op {
+	case ast.BinaryShl, ast.BinaryShr:
+		return 5
+	default:
+		return 4
+	}
+}
+
+// symbolExpr renders a reference to a constant or enum member by name. Fields
+// have no Go constant, so references to them render the value of their
+// default.
+func (g *Generator) symbolExpr(prop ast.Node, x *ast.SymbolExpr) (string, error) {
+	var ref ast.Node
+
+	if x.Symbol != nil {
+		switch n := x.Symbol.Node.(type) {
+		case *ast.PropertyNode, *ast.EnumMemberNode:
+			ref = n
+		}
+	}
+
+	if ref == nil || ref == prop {
+		return "", fmt.Errorf("Cannot resolve %q in value of %s", x.String(), backend.QualifiedName(prop))
+	}
+
+	if p, ok := ref.(*ast.PropertyNode); ok && !p.IsConst() {
+		return g.resolvedValue(ref)
+	}
+
+	if g.SkipRemoved && backend.IsRemoved(ref) {
+		return g.resolvedValue(ref)
+	}
+
+	return constName(ref.Parent(), ref), nil
+}
+
+// resolvedValue renders the numeric value of a member that isn't emitted, or
+// of a field default.
+func (g *Generator) resolvedValue(ref ast.Node) (string, error) {
+	return backend.FormatNodeValue(ref)
+}
+
+// boolValue converts the integer default of a boolmarshal field.
+func boolValue(prop *ast.PropertyNode, value string) string {
+	if lit, ok := ast.ValueExpr(prop).(*ast.LiteralExpr); ok {
+		return fmt.Sprintf("%t", lit.Value != 0)
+	}
+
+	return "(" + value + ") != 0"
+}
+
+func typeOrDefault(typeName, def string) string {
+	if typeName == "" {
+		return def
+	}
+
+	return typeName
+}
+
+func enumType(n *ast.EnumNode) string {
+	return builtinTypes[n.Type.String()]
+}
+
+// typeName returns the Go name of n, a class, enum or typedef, prefixed with
+// the names of its namespaces and enclosing classes, like SteamMsgHdr for
+// Steam::MsgHdr.
+func typeName(n ast.Node) string {
+	return strings.Join(append(ast.ScopePath(n), n.Name()), "")
+}
+
+func constName(scope ast.Node, prop ast.Node) string {
+	return typeName(scope) + "_" + prop.Name()
+}
+
+func fieldName(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:]
+}
+
+func docComment(doc string) string {
+	if doc == "" {
+		return ""
+	}
+
+	return "// " + strings.Replace(doc, "\n", "\n// ", -1) + "\n"
+}
+
+func lineComment(comment string) string {
+	if comment == "" {
+		return ""
+	}
+
+	return " // " + comment
+}
+
+// withSource appends the steamd declaration of n to comment, the comment of
+// its generated code, if EmbedSource is set. Declarations with a body are cut
+// before it.
+func (g *Generator) withSource(comment string, n ast.Node) string {
+	if !g.EmbedSource {
+		return comment
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := steamformat.Node(buf, n); err != nil {
+		return comment
+	}
+
+	var decl string
+
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "///") {
+			decl = strings.TrimSuffix(line, " {")
+			break
+		}
+	}
+
+	if comment != "" {
+		comment += "//\n"
+	}
+
+	if pos := sourcePosition(n); pos != "" {
+		return comment + "// Source: " + pos + ": " + decl + "\n"
+	}
+
+	return comment + "// Source: " + decl + "\n"
+}
+
+// sourcePosition returns the file and row declaring n, empty for nodes built
+// in code.
+func sourcePosition(n ast.Node) string {
+	row := 0
+
+	switch n := n.(type) {
+	case *ast.ClassNode:
+		row = n.Row
+	case *ast.EnumNode:
+		row = n.Row
+	case *ast.UnionNode:
+		row = n.Row
+	case *ast.TypedefNode:
+		row = n.Row
+	case *ast.PropertyNode:
+		row = n.Row
+	case *ast.EnumMemberNode:
+		row = n.Row
+	case *ast.UnionVariantNode:
+		row = n.Row
+	}
+
+	if row == 0 {
+		return ""
+	}
+
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if doc, ok := p.(*ast.DocumentNode); ok && doc.Filename != "" {
+			return fmt.Sprintf("%s:%d", doc.Filename, row)
+		}
+	}
+
+	return fmt.Sprintf("%d", row)
+}
+
+func memberComment(n ast.Node) string {
+	var doc string
+
+	switch n := n.(type) {
+	case *ast.PropertyNode:
+		doc = n.Doc
+	case *ast.EnumMemberNode:
+		doc = n.Doc
+	}
+
+	comment := docComment(doc)
+
+	if reason, ok := backend.Deprecation(n); ok {
+		if comment != "" {
+			comment += "//\n"
+		}
+
+		comment += "// Deprecated: " + reason + "\n"
+	}
+
+	return comment
+}
@@ -0,0 +1,925 @@
+package generator
+
+import (
+	"bytes"
+	goast "go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/backend"
+	"github.com/13k/go-steam-language/parse"
+	steamtoken "github.com/13k/go-steam-language/token"
+)
+
+func analyzeFile(t *testing.T, filename string) ast.Node {
+	data, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	root, err := parse.NewAnalyzer(steamtoken.NewTokenizer(data), filename).Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	return root
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func generate(t *testing.T, g *Generator, root ast.Node) string {
+	buf := &bytes.Buffer{}
+
+	if err := g.Generate(buf, root); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "", buf.Bytes(), 0); err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, buf.String())
+	}
+
+	return buf.String()
+}
+
+// typeCheck fails the test if src doesn't compile.
+func typeCheck(t *testing.T, src string) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, 0)
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+
+	if _, err := conf.Check("steamlang", fset, []*goast.File{file}, nil); err != nil {
+		t.Fatalf("generated code does not compile: %v\n%s", err, src)
+	}
+}
+
+func TestGeneratorGenerate(t *testing.T) {
+	root := analyzeFile(t, filepath.Join("testdata", "sample.steamd"))
+	src := generate(t, NewGenerator("steamlang"), root)
+	typeCheck(t, src)
+
+	expected := []string{
+		"package steamlang",
+		"// Message types.\ntype EMsg int32",
+		"EMsg_ChannelEncryptRequest EMsg = 1303",
+		"EMsg_Multi EMsg = 1 // wraps other messages",
+		"type EUniverse uint8",
+		"// Deprecated: not used anymore\n\tEUniverse_Beta EUniverse = 2",
+		"EResult_Invalid EResult = 0",
+		"EResult_OK EResult = EResult_Invalid + 1",
+		"// Deprecated: removed: merged into Timeout\n\tEResult_Busy EResult = 10",
+		"EResult_Timeout EResult = EResult_Busy + 1",
+		"EAccountFlags_Admin EAccountFlags = EAccountFlags_PersonaNameSet | EAccountFlags_Unbannable",
+		"EAccountFlags_Shifted EAccountFlags = (EAccountFlags_PersonaNameSet | EAccountFlags_Unbannable) << 4",
+		"EAccountFlags_Mixed EAccountFlags = EAccountFlags_NormalUser | (EAccountFlags_PersonaNameSet + 1)",
+		"func (e EMsg) String() string { switch e {",
+		"case EResult_Timeout: return \"Timeout\"",
+		"return fmt.Sprintf(\"EMsg(%d)\", e)",
+		"func (e EAccountFlags) String() string { if e == 0 { return \"NormalUser\" }",
+		"{EAccountFlags_Shifted, \"Shifted\"}, {EAccountFlags_Admin, \"Admin\"}, {EAccountFlags_Unbannable, \"Unbannable\"},",
+		"names = append(names, fmt.Sprintf(\"%#x\", uint64(rest)))",
+		"func EResultValues() []EResult { return []EResult{ EResult_Invalid, EResult_OK, EResult_Busy, EResult_Timeout, } }",
+		"func EAccountFlagsFromName(name string) (EAccountFlags, bool) {",
+		"case \"Mixed\": return EAccountFlags_Mixed, true",
+		"func (e EUniverse) IsValid() bool { switch e { case EUniverse_Invalid, EUniverse_Public, EUniverse_Beta: return true }",
+		"return e&^(EAccountFlags_PersonaNameSet|EAccountFlags_Unbannable|EAccountFlags_Admin|EAccountFlags_Shifted) == 0",
+		"func (e EAccountFlags) Decompose() (flags []EAccountFlags, rest EAccountFlags) {",
+		"for _, flag := range []EAccountFlags{EAccountFlags_Shifted, EAccountFlags_Admin, EAccountFlags_Unbannable, EAccountFlags_PersonaNameSet} {",
+		"MsgChannelEncryptRequest_PROTOCOL_VERSION uint32 = 1",
+		"type MsgChannelEncryptRequest struct {\n\tProtocolVersion uint32\n\tUniverse        EUniverse\n}",
+		"ProtocolVersion: MsgChannelEncryptRequest_PROTOCOL_VERSION,",
+		"Universe:        EUniverse_Invalid,",
+		"UniqueID uint32 // key identifier",
+		"LoginKey [20]byte",
+		"SteamID uint64",
+		"Valid bool",
+		"Valid: true,",
+		"Msg EMsg",
+		"func (m *MsgChannelEncryptRequest) GetEMsg() EMsg { return EMsg_ChannelEncryptRequest }",
+		"func (m *MsgClientNewLoginKey) GetEMsg() EMsg { return EMsg_Multi }",
+		"func (m *MsgClientNewLoginKey) Serialize(w io.Writer) error",
+		"binary.Write(w, binary.LittleEndian, m.LoginKey)",
+		"binary.Read(r, binary.LittleEndian, &m.LoginKey)",
+		"binary.Write(w, binary.LittleEndian, EMsg(uint32(m.Msg)|0x80000000))",
+		"m.Msg = EMsg(uint32(m.Msg) &^ 0x80000000)",
+		"if err := m.Header.Deserialize(r); err != nil {",
+		"// NewMsgClientNewLoginKey returns a MsgClientNewLoginKey initialized with its default values.\nfunc NewMsgClientNewLoginKey()",
+	}
+
+	normalized := collapseSpace(src)
+
+	for _, s := range expected {
+		if !strings.Contains(normalized, collapseSpace(s)) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+
+	// Mixed has the value of Unbannable, which is declared first
+	if strings.Contains(src, "case EAccountFlags_Mixed") {
+		t.Fatalf("expected duplicate value to be named after the first member\n%s", src)
+	}
+}
+
+func TestGeneratorNamespaces(t *testing.T) {
+	data := `
+		namespace Steam {
+			enum EMsg { Multi = 1; };
+			class MsgHdr<EMsg::Multi> { const uint SIZE = 4; EMsg msg = EMsg::Multi; };
+		}
+	`
+
+	root, err := parse.NewAnalyzer(steamtoken.NewTokenizer([]byte(data)), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	src := generate(t, NewGenerator("steamlang"), root)
+	typeCheck(t, src)
+
+	expected := []string{
+		"type SteamEMsg int32",
+		"SteamEMsg_Multi SteamEMsg = 1",
+		"SteamMsgHdr_SIZE uint32 = 4",
+		"type SteamMsgHdr struct { Msg SteamEMsg }",
+		"Msg: SteamEMsg_Multi,",
+		"func (m *SteamMsgHdr) GetEMsg() SteamEMsg { return SteamEMsg_Multi }",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(collapseSpace(src), s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+}
+
+func TestGeneratorTypedefs(t *testing.T) {
+	data := `
+		/// Identifies a job.
+		typedef JobID = ulong;
+		typedef SourceJobID = JobID;
+		enum EResult { OK = 1; };
+		typedef Result = EResult;
+		class MsgJob { SourceJobID source; JobID<2> targets; Result result = EResult::OK; };
+	`
+
+	root, err := parse.NewAnalyzer(steamtoken.NewTokenizer([]byte(data)), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	src := generate(t, NewGenerator("steamlang"), root)
+	typeCheck(t, src)
+
+	expected := []string{
+		"// Identifies a job. type JobID uint64",
+		"type SourceJobID = JobID",
+		"type Result = EResult",
+		"Source SourceJobID Targets [2]JobID Result Result }",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(collapseSpace(src), s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+}
+
+func TestGeneratorNestedDeclarations(t *testing.T) {
+	data := `
+		class MsgFoo {
+			enum EKind<byte> { A = 1; };
+			class Item { EKind kind = EKind::A; };
+			Item item;
+		};
+	`
+
+	root, err := parse.NewAnalyzer(steamtoken.NewTokenizer([]byte(data)), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	src := generate(t, NewGenerator("steamlang"), root)
+	typeCheck(t, src)
+
+	expected := []string{
+		"type MsgFooEKind uint8",
+		"MsgFooEKind_A MsgFooEKind = 1",
+		"type MsgFooItem struct { Kind MsgFooEKind }",
+		"type MsgFoo struct { Item MsgFooItem }",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(collapseSpace(src), s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+}
+
+func TestGeneratorBaseClasses(t *testing.T) {
+	data := `
+		enum EMsg { Foo = 1; };
+		class MsgHdr { EMsg msg = EMsg::Foo; ulong jobID; };
+		class MsgFoo<EMsg::Foo> : MsgHdr { uint foo = 2; };
+	`
+
+	root, err := parse.NewAnalyzer(steamtoken.NewTokenizer([]byte(data)), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	src := generate(t, NewGenerator("steamlang"), root)
+	typeCheck(t, src)
+
+	expected := []string{
+		"type MsgFoo struct { Msg EMsg JobID uint64 Foo uint32 }",
+		"Msg: EMsg_Foo, Foo: 2,",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(collapseSpace(src), s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+}
+
+func TestGeneratorOptionalFields(t *testing.T) {
+	data := `
+		enum EResult { OK = 1; };
+		class MsgHdr { ulong jobID; };
+		class MsgFoo {
+			uint id;
+			optional EResult result;
+			optional steamidmarshal ulong steamID;
+			optional byte<4> key;
+			optional MsgHdr hdr;
+		};
+	`
+
+	root, err := parse.NewAnalyzer(steamtoken.NewTokenizer([]byte(data)), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	src := generate(t, NewGenerator("steamlang"), root)
+	typeCheck(t, src)
+
+	expected := []string{
+		"Id uint32 Result *EResult SteamID *uint64 Key *[4]byte Hdr *MsgHdr }",
+		"if m.Result == nil { return nil }",
+		"m.Hdr = new(MsgHdr)",
+		"if err == io.EOF { return nil }",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(collapseSpace(src), s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+}
+
+func TestGeneratorConditionalFields(t *testing.T) {
+	data := `
+		enum EFlags flags { HasExtra = 1; HasID = 2; };
+		class MsgFoo {
+			EFlags flags;
+			uint extra if:EFlags::HasExtra;
+			steamidmarshal ulong steamID if:EFlags::HasID;
+			byte<4> key if:EFlags::HasExtra;
+		};
+	`
+
+	root, err := parse.NewAnalyzer(steamtoken.NewTokenizer([]byte(data)), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	src := generate(t, NewGenerator("steamlang"), root)
+	typeCheck(t, src)
+
+	expected := []string{
+		"if m.Flags&EFlags_HasExtra != 0 {",
+		"if m.Flags&EFlags_HasID != 0 {",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(collapseSpace(src), s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+}
+
+func TestGeneratorArrays(t *testing.T) {
+	data := `
+		enum EType<byte> { A = 1; };
+		class Item { protomask uint id; };
+		class MsgFoo {
+			const uint MAX = 3;
+			uint<4> ids;
+			Item<2> items;
+			EType<MAX> types;
+			byte<0x10> key;
+		};
+	`
+
+	root, err := parse.NewAnalyzer(steamtoken.NewTokenizer([]byte(data)), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	src := generate(t, NewGenerator("steamlang"), root)
+	typeCheck(t, src)
+
+	expected := []string{
+		"Ids [4]uint32 Items [2]Item Types [3]EType Key [16]byte }",
+		"for i := range m.Items { if err := m.Items[i].Serialize(w); err != nil {",
+		"for i := range m.Items { if err := m.Items[i].Deserialize(r); err != nil {",
+		"binary.Read(r, binary.LittleEndian, &m.Ids)",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(collapseSpace(src), s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+}
+
+func TestGeneratorLengthFields(t *testing.T) {
+	data := `
+		enum EType<byte> { A = 1; };
+		class Item { uint id; };
+		class MsgFoo {
+			byte size;
+			byte<size> data;
+			int length;
+			string<length> name;
+			ushort count;
+			Item<count> items;
+			uint n;
+			EType<n> types;
+		};
+	`
+
+	root, err := parse.NewAnalyzer(steamtoken.NewTokenizer([]byte(data)), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	src := generate(t, NewGenerator("steamlang"), root)
+	typeCheck(t, src)
+
+	expected := []string{
+		"Size uint8 Data []byte Length int32 Name string Count uint16 Items []Item N uint32 Types []EType }",
+		"binary.Write(w, binary.LittleEndian, uint8(len(m.Data)))",
+		"if uint64(len(m.Data)) > 255 {",
+		"if m.Length < 0 {",
+		"m.Items = make([]Item, m.Count)",
+		"m.Types = make([]EType, m.N)",
+		"if _, err := io.WriteString(w, m.Name); err != nil {",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(collapseSpace(src), s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+}
+
+func TestGeneratorUnions(t *testing.T) {
+	data := `
+		enum EMsg { Logon = 1; Logoff = 2; };
+		class MsgLogon { uint version; };
+		class MsgLogoff { uint reason; };
+		union Body<EMsg> { MsgLogon Logon; MsgLogoff Logoff; };
+		class MsgJob { ulong jobID; Body body; };
+	`
+
+	root, err := parse.NewAnalyzer(steamtoken.NewTokenizer([]byte(data)), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	src := generate(t, NewGenerator("steamlang"), root)
+	typeCheck(t, src)
+
+	expected := []string{
+		"type Body interface { // BodyDiscriminator returns the EMsg selecting the variant. BodyDiscriminator() EMsg",
+		"func (m *MsgLogoff) BodyDiscriminator() EMsg { return EMsg_Logoff }",
+		"case EMsg_Logon: return NewMsgLogon()",
+		"JobID uint64 Body Body }",
+		"if err := WriteBody(w, m.Body); err != nil {",
+		"v, err := ReadBody(r)",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(collapseSpace(src), s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+
+	invalid := `
+		enum EMsg { Logon = 1; };
+		class MsgLogon { uint version; };
+		union Body<EMsg> { MsgLogon Logon; };
+		class MsgJob { optional Body body; };
+	`
+
+	root, err = parse.NewAnalyzer(steamtoken.NewTokenizer([]byte(invalid)), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if err := NewGenerator("steamlang").Generate(&bytes.Buffer{}, root); err == nil {
+		t.Fatalf("expected error generating optional union field")
+	}
+}
+
+func TestGeneratorSkipRemoved(t *testing.T) {
+	root := analyzeFile(t, filepath.Join("testdata", "sample.steamd"))
+	g := NewGenerator("steamlang")
+	g.SkipRemoved = true
+	src := generate(t, g, root)
+
+	if strings.Contains(src, "EResult_Busy") {
+		t.Fatalf("expected removed member to be skipped\n%s", src)
+	}
+
+	expected := "EResult_Timeout EResult = 10 + 1"
+
+	if !strings.Contains(collapseSpace(src), expected) {
+		t.Fatalf("expected generated code to contain %q\n%s", expected, src)
+	}
+}
+
+const decomposeMain = `package main
+
+import (
+	"fmt"
+	"reflect"
+)
+
+func main() {
+	for _, tc := range []struct {
+		value, rest EFlags
+		flags       []EFlags
+	}{
+		{0, 0, []EFlags{EFlags_None}},
+		{EFlags_AB | EFlags_C | 0x40, 0x40, []EFlags{EFlags_AB, EFlags_C}},
+		{EFlags_A | EFlags_C, 0, []EFlags{EFlags_A, EFlags_C}},
+		{0x40, 0x40, nil},
+	} {
+		flags, rest := tc.value.Decompose()
+
+		if !reflect.DeepEqual(flags, tc.flags) || rest != tc.rest {
+			panic(fmt.Sprintf("%v: got %v %#x, but expected %v %#x", uint32(tc.value), flags, uint32(rest), tc.flags, uint32(tc.rest)))
+		}
+	}
+}
+`
+
+func TestGeneratorDecompose(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+
+	if err != nil {
+		t.Skip("go not found")
+	}
+
+	data := "enum EFlags<uint> flags { None = 0; A = 1; B = 2; AB = A | B; C = 4; };"
+	root, err := parse.NewAnalyzer(steamtoken.NewTokenizer([]byte(data)), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	dir := t.TempDir()
+	src := generate(t, NewGenerator("main"), root)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "flags.go"), []byte(src), 0644); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(decomposeMain), 0644); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	cmd := exec.Command(goTool, "run", "main.go", "flags.go")
+	cmd.Dir = dir
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("not expected error %v\n%s", err, out)
+	}
+}
+
+func TestGeneratorHighBitValues(t *testing.T) {
+	data := `
+		enum EPermission flags { Owner = 1; Only = 0x80000000; Both = Only | Owner; Gone = 0xC0000000; removed };
+		enum EByte<byte> { Max = 0xFF; };
+		enum ESmall<short> { Min = 0x8000; };
+		class Msg { EPermission permission = EPermission::Gone; };
+	`
+
+	root, err := parse.NewAnalyzer(steamtoken.NewTokenizer([]byte(data)), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	g := NewGenerator("steamlang")
+	g.SkipRemoved = true
+	src := generate(t, g, root)
+	typeCheck(t, src)
+
+	expected := []string{
+		"EPermission_Owner EPermission = 1",
+		"EPermission_Only EPermission = -2147483648",
+		"EPermission_Both EPermission = -2147483647",
+		"EByte_Max EByte = 0xFF",
+		"ESmall_Min ESmall = -32768",
+		"Permission: -1073741824,",
+	}
+
+	normalized := collapseSpace(src)
+
+	for _, s := range expected {
+		if !strings.Contains(normalized, s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+}
+
+func TestGeneratorEndian(t *testing.T) {
+	data := `
+		class MsgFoo {
+			ushort size endian:be;
+			uint<size> ids endian:be;
+			ulong id endian:be;
+			int port;
+		};
+	`
+
+	root, err := parse.NewAnalyzer(steamtoken.NewTokenizer([]byte(data)), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	src := generate(t, NewGenerator("steamlang"), root)
+	typeCheck(t, src)
+
+	expected := []string{
+		"binary.Write(w, binary.BigEndian, uint16(len(m.Ids)))",
+		"binary.Write(w, binary.BigEndian, m.Ids)",
+		"binary.Write(w, binary.BigEndian, m.Id)",
+		"binary.Write(w, binary.LittleEndian, m.Port)",
+		"binary.Read(r, binary.BigEndian, &m.Id)",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(collapseSpace(src), s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+}
+
+func TestGeneratorStrings(t *testing.T) {
+	data := `
+		class MsgFoo {
+			ushort size;
+			string<size> wide encoding:utf16;
+			string name term:null;
+			optional string title encoding:utf16 term:null endian:be;
+		};
+	`
+
+	root, err := parse.NewAnalyzer(steamtoken.NewTokenizer([]byte(data)), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	src := generate(t, NewGenerator("steamlang"), root)
+	typeCheck(t, src)
+
+	expected := []string{
+		"binary.Write(w, binary.LittleEndian, uint16(len(utf16.Encode([]rune(m.Wide)))))",
+		"m.Wide = string(utf16.Decode(u))",
+		"if strings.IndexByte(m.Name, 0) >= 0 {",
+		"binary.Write(w, binary.LittleEndian, append([]byte(m.Name), 0))",
+		"binary.Write(w, binary.BigEndian, append(utf16.Encode([]rune(*m.Title)), 0))",
+		"*m.Title = string(utf16.Decode(s))",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(collapseSpace(src), s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+}
+
+func TestGeneratorMarshalTypes(t *testing.T) {
+	root := analyzeFile(t, filepath.Join("testdata", "sample.steamd"))
+	g := NewGenerator("steamlang")
+	g.SteamIDType = "steamid.SteamID"
+	g.Imports = []string{"github.com/example/steamid"}
+	src := collapseSpace(generate(t, g, root))
+
+	expected := []string{
+		`"strings" "github.com/example/steamid" )`,
+		"SteamID steamid.SteamID",
+		"GameID uint64",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(src, s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+}
+
+func TestGeneratorSerializeUnsupported(t *testing.T) {
+	root, err := parse.NewAnalyzer(steamtoken.NewTokenizer([]byte("class C { string name; };")), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	err = NewGenerator("steamlang").Generate(&bytes.Buffer{}, root)
+	expected := "Cannot serialize C::name of type string"
+
+	if err == nil || err.Error() != expected {
+		t.Fatalf("mismatch: got %v, but expected %q", err, expected)
+	}
+}
+
+func TestGeneratorSteamKit(t *testing.T) {
+	root := analyzeFile(t, filepath.Join("testdata", "steamkit", "steammsg.steamd"))
+	src := generate(t, NewGenerator("steamlang"), root)
+	typeCheck(t, src)
+
+	expected := []string{
+		"type MsgHdrProtoBuf struct {\n\tMsg EMsg\n\tHeaderLength int32\n\tProto []byte\n}",
+		"if _, err := w.Write(m.Proto); err != nil {",
+		"m.Proto = make([]byte, m.HeaderLength)\n\nif _, err := io.ReadFull(r, m.Proto); err != nil {",
+		"TargetJobID: 18446744073709551615,",
+		"EClanPermission_OGGOnly EClanPermission = -2147483648",
+	}
+
+	normalized := collapseSpace(src)
+
+	for _, s := range expected {
+		if !strings.Contains(normalized, collapseSpace(s)) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+}
+
+const testTemplate = `package {{.Package}}
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+{{range enums .Nodes}}
+{{docComment .Doc}}type {{.Name}} {{enumType .}}
+{{$enum := .}}
+const (
+{{range .Members}}{{if not (skipped .)}}{{constName $enum .}} {{$enum.Name}} = {{value .}} // {{resolvedValue .}}
+{{end}}{{end}})
+{{end}}
+{{range classes .Nodes}}{{class .}}{{end}}
+`
+
+func TestGeneratorTemplate(t *testing.T) {
+	root := analyzeFile(t, filepath.Join("testdata", "sample.steamd"))
+	g := NewGenerator("steamlang")
+	g.SkipRemoved = true
+
+	if err := g.ParseTemplate("file", testTemplate); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	src := generate(t, g, root)
+	typeCheck(t, src)
+	normalized := collapseSpace(src)
+
+	expected := []string{
+		"// Message types. type EMsg int32",
+		"EMsg_Multi EMsg = 1 // 1",
+		"EResult_OK EResult = EResult_Invalid + 1 // 1",
+		"EResult_Timeout EResult = 10 + 1 // 11",
+		"func (m *MsgClientNewLoginKey) Serialize(w io.Writer) error",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(normalized, s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+
+	if strings.Contains(src, "String() string") {
+		t.Fatalf("expected enums generated by the template only\n%s", src)
+	}
+
+	if err := g.ParseTemplate("file", "{{value .Package}}"); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if err := g.Generate(&bytes.Buffer{}, root); err == nil || !strings.HasPrefix(err.Error(), "Cannot execute template:") {
+		t.Fatalf("expected template execution error, got %v", err)
+	}
+}
+
+func TestGoBackend(t *testing.T) {
+	root, err := parse.NewAnalyzer(steamtoken.NewTokenizer([]byte("enum EA { X = 1; }; enum EB { Y = 1; };")), "dir/enums.steamd").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	gen, ok := backend.Lookup("go")
+
+	if !ok {
+		t.Fatalf("expected go backend to be registered")
+	}
+
+	opts := backend.Options{
+		Package: "protocol",
+		Params:  map[string]string{"imports": "github.com/example/steamid"},
+		Exclude: map[string]bool{"EA": true},
+	}
+
+	files, err := gen.Generate(root, opts)
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if len(files) != 1 || files[0].Name != "enums.go" {
+		t.Fatalf("mismatch: got %v, but expected enums.go only", files)
+	}
+
+	src := string(files[0].Content)
+
+	if !strings.Contains(src, "package protocol") || !strings.Contains(src, `"github.com/example/steamid"`) || strings.Contains(src, "EA") || !strings.Contains(src, "EB_Y") {
+		t.Fatalf("unexpected generated code\n%s", src)
+	}
+}
+
+func TestGoBackendFingerprint(t *testing.T) {
+	root, err := parse.NewAnalyzer(steamtoken.NewTokenizer([]byte("enum EA { X = 1; };")), "dir/steam-msg.steamd").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	gen, _ := backend.Lookup("go")
+
+	for param, expected := range map[string]string{
+		"":         "const SteamMsgFingerprint = \"" + ast.Fingerprint(root) + "\"",
+		"Revision": "const Revision = \"",
+		"-":        "",
+	} {
+		opts := backend.Options{Package: "protocol", Params: map[string]string{"fingerprint": param}}
+		files, err := gen.Generate(root, opts)
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		src := string(files[0].Content)
+
+		if expected == "" && strings.Contains(src, "Fingerprint") {
+			t.Fatalf("expected generated code not to declare a fingerprint\n%s", src)
+		}
+
+		if !strings.Contains(src, expected) {
+			t.Fatalf("expected generated code to contain %q\n%s", expected, src)
+		}
+	}
+}
+
+func TestGeneratorFieldReferences(t *testing.T) {
+	source := `enum EMsg { Invalid = 0; Multi = 1; };
+class Msg {
+	const uint SIZE = 4;
+	uint a = Msg::SIZE + 1;
+	uint b = a;
+	uint c = b << 1;
+	EMsg msg = EMsg::Multi;
+	EMsg other = msg;
+	boolmarshal byte valid = a;
+};`
+	root, err := parse.NewAnalyzer(steamtoken.NewTokenizer([]byte(source)), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	src := generate(t, NewGenerator("steamlang"), root)
+	typeCheck(t, src)
+
+	expected := []string{
+		"A: Msg_SIZE + 1,",
+		"B: 5,",
+		"C: 5 << 1,",
+		"Other: 1,",
+		"Valid: (5) != 0,",
+	}
+
+	normalized := collapseSpace(src)
+
+	for _, s := range expected {
+		if !strings.Contains(normalized, collapseSpace(s)) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+
+	root, err = parse.NewAnalyzer(steamtoken.NewTokenizer([]byte("class C { uint a = b; uint b = a; };")), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if err := NewGenerator("steamlang").Generate(&bytes.Buffer{}, root); err == nil || !strings.Contains(err.Error(), "Circular value") {
+		t.Fatalf("mismatch: got %v, but expected circular value error", err)
+	}
+}
+
+func TestGoBackendEmbedSource(t *testing.T) {
+	source := `/// Message types.
+enum EMsg { Invalid = 0; Multi = 1; /* wraps */ };
+typedef JobID = ulong;
+class MsgHdr<EMsg::Multi> {
+	const uint SIZE = 4;
+	/// Sender.
+	ulong steamID;
+	byte<4> key;
+};
+union Body<EMsg> { MsgHdr Multi; };
+`
+	root, err := parse.NewAnalyzer(steamtoken.NewTokenizer([]byte(source)), "schemas/emsg.steamd").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	gen, _ := backend.Lookup("go")
+	files, err := gen.Generate(root, backend.Options{Package: "protocol", Params: map[string]string{"embed_source": "true"}})
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	src := string(files[0].Content)
+	typeCheck(t, src)
+
+	expected := []string{
+		"// Message types.\n//\n// Source: schemas/emsg.steamd:2: enum EMsg\ntype EMsg int32",
+		"// Source: schemas/emsg.steamd:2: Multi = 1; // wraps\n\tEMsg_Multi EMsg = 1",
+		"// Source: schemas/emsg.steamd:3: typedef JobID = ulong;\ntype JobID uint64",
+		"// Source: schemas/emsg.steamd:5: const uint SIZE = 4;\n\tMsgHdr_SIZE uint32 = 4",
+		"// Source: schemas/emsg.steamd:4: class MsgHdr<EMsg::Multi>\ntype MsgHdr struct {",
+		"// Sender.\n\t//\n\t// Source: schemas/emsg.steamd:7: ulong steamID;\n\tSteamID uint64",
+		"// Source: schemas/emsg.steamd:8: byte<4> key;\n\tKey [4]byte",
+		"// Source: schemas/emsg.steamd:10: union Body<EMsg>\ntype Body interface {",
+		"// Source: schemas/emsg.steamd:10: MsgHdr Multi;\n\tcase EMsg_Multi:",
+	}
+
+	for _, s := range expected {
+		if !strings.Contains(src, s) {
+			t.Fatalf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+
+	files, _ = gen.Generate(root, backend.Options{Package: "protocol"})
+
+	if strings.Contains(string(files[0].Content), "Source:") {
+		t.Fatalf("expected no source comments by default\n%s", files[0].Content)
+	}
+}
@@ -0,0 +1,317 @@
+package generator
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/backend"
+)
+
+// serializeImports are the packages used by the generated serializers.
+var serializeImports = []string{"encoding/binary", "io"}
+
+// generateSerializers emits Serialize and Deserialize methods reading and
+// writing the fields of n in wire order, without padding, and little-endian
+// unless annotated `endian:be`. Constants aren't part of the wire format.
+// Absent optional fields end the payload: they aren't written, and the input
+// ending before one leaves it and the ones following it nil. Conditional fields
+// are only read and written when their flag is set.
+func (g *Generator) generateSerializers(w io.Writer, n *ast.ClassNode) error {
+	var ser, de []string
+
+	for _, f := range n.Fields() {
+		s, d, err := g.fieldSerializer(f)
+
+		if err != nil {
+			return err
+		}
+
+		if f.Condition != nil {
+			cond, err := g.condition(f)
+
+			if err != nil {
+				return err
+			}
+
+			s = fmt.Sprintf("if %s {\n%s}\n", cond, s)
+			d = fmt.Sprintf("if %s {\n%s}\n", cond, d)
+		}
+
+		if f.Optional {
+			field := "m." + fieldName(f.Name())
+			s = fmt.Sprintf("if %s == nil {\nreturn nil\n}\n\n%s", field, s)
+			d = fmt.Sprintf("%s = new(%s)\n\nif err := func() error {\n%s\nreturn nil\n}(); err != nil {\n%s = nil\n\nif err == io.EOF {\nreturn nil\n}\n\nreturn err\n}\n", field, g.fieldType(f), d, field)
+		}
+
+		ser = append(ser, s)
+		de = append(de, d)
+	}
+
+	name := typeName(n)
+
+	fmt.Fprintf(w, "\n// Serialize writes m to w in Steam's wire format.\n")
+	fmt.Fprintf(w, "func (m *%s) Serialize(w io.Writer) error {\n%s\nreturn nil\n}\n", name, strings.Join(ser, "\n"))
+	fmt.Fprintf(w, "\n// Deserialize reads m from r in Steam's wire format.\n")
+	fmt.Fprintf(w, "func (m *%s) Deserialize(r io.Reader) error {\n%s\nreturn nil\n}\n", name, strings.Join(de, "\n"))
+
+	return nil
+}
+
+// condition returns the Go expression testing the flag the presence of f
+// depends on.
+func (g *Generator) condition(f *ast.PropertyNode) (string, error) {
+	member, field := f.ConditionMember(), f.ConditionField()
+
+	if member == nil || field == nil {
+		return "", fmt.Errorf("Cannot serialize %s with unresolved condition %s", backend.QualifiedName(f), strings.Join(f.ConditionPath, "::"))
+	}
+
+	value, err := g.memberValue(member)
+
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("m.%s&%s != 0", fieldName(field.Name()), value), nil
+}
+
+func (g *Generator) fieldSerializer(f *ast.PropertyNode) (string, string, error) {
+	ref := "m." + fieldName(f.Name())
+	field, ptr := ref, "&"+ref
+	goType := g.fieldType(f)
+
+	// optional fields are pointers
+	if f.Optional {
+		field, ptr = "*"+ref, ref
+	}
+
+	if length := f.LengthField(); length != nil {
+		return g.variableSerializer(f, length)
+	}
+
+	if sized := f.LengthOf(); sized != nil {
+		return g.lengthSerializer(f, sized)
+	}
+
+	if f.Termination == ast.TermNull {
+		return nullTerminatedSerializer(f, field)
+	}
+
+	_, isArray, err := f.ArrayLen()
+
+	if err != nil {
+		return "", "", fmt.Errorf("Cannot serialize %s: %v", backend.QualifiedName(f), err)
+	}
+
+	// arrays are written element by element, as their element type
+	if isArray && f.Flags != ast.PropertyFlagNone {
+		return "", "", fmt.Errorf("Cannot serialize %s, an array of %s fields", backend.QualifiedName(f), f.Flags)
+	}
+
+	switch f.Flags {
+	case ast.PropertyFlagSteamIDMarshal, ast.PropertyFlagGameIDMarshal:
+		ser := writeValue("uint64("+field+")", f.Endian)
+		de := fmt.Sprintf("{\nvar v uint64\n\n%s\n%s = %s(v)\n}\n", readValue("&v", f.Endian), field, goType)
+		return ser, de, nil
+	case ast.PropertyFlagProtoMask, ast.PropertyFlagProtoMaskGC:
+		ser := writeValue(fmt.Sprintf("%s(uint32(%s) | %#x)", goType, field, ast.ProtoMask), f.Endian)
+		de := fmt.Sprintf("%s\n%s = %s(uint32(%s) &^ %#x)\n", readValue(ptr, f.Endian), field, goType, field, ast.ProtoMask)
+		return ser, de, nil
+	case ast.PropertyFlagProto:
+		return "", "", fmt.Errorf("Cannot serialize proto field %s", backend.QualifiedName(f))
+	}
+
+	if f.Type != nil {
+		switch n := f.Type.Node.(type) {
+		case *ast.UnionNode:
+			if f.Optional || f.Qualifier != nil {
+				return "", "", fmt.Errorf("Cannot serialize %s of union %s, only single required fields are supported", backend.QualifiedName(f), typeName(n))
+			}
+
+			ser := fmt.Sprintf("if err := Write%s(w, %s); err != nil {\nreturn err\n}\n", typeName(n), ref)
+			de := fmt.Sprintf("{\nv, err := Read%s(r)\n\nif err != nil {\nreturn err\n}\n\n%s = v\n}\n", typeName(n), ref)
+			return ser, de, nil
+		case *ast.ClassNode:
+			if isArray {
+				ser := fmt.Sprintf("for i := range %s {\nif err := %s[i].Serialize(w); err != nil {\nreturn err\n}\n}\n", ref, ref)
+				de := fmt.Sprintf("for i := range %s {\nif err := %s[i].Deserialize(r); err != nil {\nreturn err\n}\n}\n", ref, ref)
+				return ser, de, nil
+			}
+
+			ser := fmt.Sprintf("if err := %s.Serialize(w); err != nil {\nreturn err\n}\n", ref)
+			de := fmt.Sprintf("if err := %s.Deserialize(r); err != nil {\nreturn err\n}\n", ref)
+			return ser, de, nil
+		case *ast.EnumNode:
+			// written as its storage type
+		default:
+			if !fixedSizeTypes[f.Type.Value] {
+				return "", "", fmt.Errorf("Cannot serialize %s of type %s", backend.QualifiedName(f), f.Type.Value)
+			}
+		}
+	}
+
+	return writeValue(field, f.Endian), readValue(ptr, f.Endian), nil
+}
+
+// variableSerializer returns the code writing and reading f, whose number of
+// elements is given by the field length.
+func (g *Generator) variableSerializer(f, length *ast.PropertyNode) (string, string, error) {
+	field := "m." + fieldName(f.Name())
+	count := "m." + fieldName(length.Name())
+	var ser, de string
+
+	// proto fields hold the encoded message, whose length in bytes is given
+	if f.Flags == ast.PropertyFlagProto {
+		ser = fmt.Sprintf("if _, err := w.Write(%s); err != nil {\nreturn err\n}\n", field)
+		de = fmt.Sprintf("%s = make([]byte, %s)\n\nif _, err := io.ReadFull(r, %s); err != nil {\nreturn err\n}\n", field, count, field)
+	} else {
+		var err error
+
+		if ser, de, err = g.elementsSerializer(f, field, count); err != nil {
+			return "", "", err
+		}
+	}
+
+	// negative lengths can't be allocated
+	if t := lengthType(length); t.Signed() {
+		de = fmt.Sprintf("if %s < 0 {\nreturn fmt.Errorf(\"Negative length %%d of %s\", %s)\n}\n\n%s", count, backend.QualifiedName(f), count, de)
+	}
+
+	return ser, de, nil
+}
+
+// elementsSerializer returns the code writing and reading the elements of f,
+// count of them.
+func (g *Generator) elementsSerializer(f *ast.PropertyNode, field, count string) (string, string, error) {
+	goType := g.fieldType(f)
+	var ser, de string
+
+	if f.Flags != ast.PropertyFlagNone || f.Type == nil {
+		return "", "", fmt.Errorf("Cannot serialize %s of variable length", backend.QualifiedName(f))
+	}
+
+	switch f.Type.Node.(type) {
+	case *ast.ClassNode:
+		ser = fmt.Sprintf("for i := range %s {\nif err := %s[i].Serialize(w); err != nil {\nreturn err\n}\n}\n", field, field)
+		de = fmt.Sprintf("%s = make(%s, %s)\n\nfor i := range %s {\nif err := %s[i].Deserialize(r); err != nil {\nreturn err\n}\n}\n", field, goType, count, field, field)
+	case *ast.EnumNode:
+		ser = writeValue(field, f.Endian)
+		de = fmt.Sprintf("%s = make(%s, %s)\n\n%s", field, goType, count, readValue(field, f.Endian))
+	default:
+		switch {
+		case f.Type.Value == "string" && f.Encoding == ast.EncodingUTF16:
+			unit, encode, decode := stringUnits(f)
+			ser = writeValue(fmt.Sprintf(encode, field), f.Endian)
+			de = fmt.Sprintf("{\nu := make([]%s, %s)\n\n%s\n%s = %s\n}\n", unit, count, readValue("u", f.Endian), field, fmt.Sprintf(decode, "u"))
+		case f.Type.Value == "string":
+			ser = fmt.Sprintf("if _, err := io.WriteString(w, %s); err != nil {\nreturn err\n}\n", field)
+			de = fmt.Sprintf("{\nb := make([]byte, %s)\n\nif _, err := io.ReadFull(r, b); err != nil {\nreturn err\n}\n\n%s = string(b)\n}\n", count, field)
+		case fixedSizeTypes[f.Type.Value]:
+			ser = writeValue(field, f.Endian)
+			de = fmt.Sprintf("%s = make(%s, %s)\n\n%s", field, goType, count, readValue(field, f.Endian))
+		default:
+			return "", "", fmt.Errorf("Cannot serialize %s of variable length and type %s", backend.QualifiedName(f), f.Type.Value)
+		}
+	}
+
+	return ser, de, nil
+}
+
+// lengthSerializer returns the code writing and reading the field length,
+// giving the number of elements of sized. The length written is the one of
+// sized, so that the two are always consistent.
+func (g *Generator) lengthSerializer(length, sized *ast.PropertyNode) (string, string, error) {
+	field := "m." + fieldName(length.Name())
+	count := fmt.Sprintf("len(m.%s)", fieldName(sized.Name()))
+	t := lengthType(length)
+	var ser string
+
+	// UTF-16 strings are measured in code units
+	if sized.Encoding == ast.EncodingUTF16 {
+		_, encode, _ := stringUnits(sized)
+		count = fmt.Sprintf("len(%s)", fmt.Sprintf(encode, "m."+fieldName(sized.Name())))
+	}
+
+	if size := t.Size(); size < 8 {
+		max := uint64(1)<<(8*size) - 1
+
+		if t.Signed() {
+			max >>= 1
+		}
+
+		ser = fmt.Sprintf("if uint64(%s) > %d {\nreturn fmt.Errorf(\"Length %%d of %s overflows %s\", %s)\n}\n\n", count, max, backend.QualifiedName(sized), t, count)
+	}
+
+	ser += writeValue(fmt.Sprintf("%s(%s)", g.fieldType(length), count), length.Endian)
+
+	return ser, readValue("&"+field, length.Endian), nil
+}
+
+// nullTerminatedSerializer returns the code writing and reading the string
+// field, followed on the wire by a null code unit. Strings holding a null
+// character can't be written, since they would be read truncated.
+func nullTerminatedSerializer(f *ast.PropertyNode, field string) (string, string, error) {
+	if f.Type == nil || f.Flags != ast.PropertyFlagNone || f.Qualifier != nil {
+		return "", "", fmt.Errorf("Cannot serialize %s as a null-terminated string", backend.QualifiedName(f))
+	}
+
+	unit, encode, decode := stringUnits(f)
+	ser := fmt.Sprintf("if strings.IndexByte(%s, 0) >= 0 {\nreturn fmt.Errorf(\"String %s contains a null character\")\n}\n\n%s", field, backend.QualifiedName(f), writeValue(fmt.Sprintf("append(%s, 0)", fmt.Sprintf(encode, field)), f.Endian))
+	de := fmt.Sprintf("{\nvar s []%s\n\nfor {\nvar c %s\n\n%s\nif c == 0 {\nbreak\n}\n\ns = append(s, c)\n}\n\n%s = %s\n}\n", unit, unit, readValue("&c", f.Endian), field, fmt.Sprintf(decode, "s"))
+
+	return ser, de, nil
+}
+
+// stringUnits returns the Go type of the code units of the string field f,
+// and the formats of the expressions converting a string to a slice of them
+// and back.
+func stringUnits(f *ast.PropertyNode) (string, string, string) {
+	if f.Encoding == ast.EncodingUTF16 {
+		return "uint16", "utf16.Encode([]rune(%s))", "string(utf16.Decode(%s))"
+	}
+
+	return "byte", "[]byte(%s)", "string(%s)"
+}
+
+// lengthType returns the integer type of a length field, validated by the
+// analyzer.
+func lengthType(length *ast.PropertyNode) ast.StorageType {
+	var t ast.StorageType
+
+	if sym := ast.Underlying(length.Type); sym != nil {
+		t, _ = ast.ParseStorageType(sym.Value)
+	}
+
+	return t
+}
+
+// fixedSizeTypes are the builtin types with a fixed size wire encoding.
+var fixedSizeTypes = map[string]bool{
+	"byte":   true,
+	"sbyte":  true,
+	"short":  true,
+	"ushort": true,
+	"int":    true,
+	"uint":   true,
+	"long":   true,
+	"ulong":  true,
+	"float":  true,
+	"double": true,
+	"bool":   true,
+}
+
+// byteOrders are the encoding/binary byte orders of the values of fields.
+var byteOrders = map[ast.ByteOrder]string{
+	ast.LittleEndian: "binary.LittleEndian",
+	ast.BigEndian:    "binary.BigEndian",
+}
+
+func writeValue(value string, order ast.ByteOrder) string {
+	return fmt.Sprintf("if err := binary.Write(w, %s, %s); err != nil {\nreturn err\n}\n", byteOrders[order], value)
+}
+
+func readValue(ptr string, order ast.ByteOrder) string {
+	return fmt.Sprintf("if err := binary.Read(r, %s, %s); err != nil {\nreturn err\n}\n", byteOrders[order], ptr)
+}
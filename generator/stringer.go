@@ -0,0 +1,210 @@
+package generator
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/backend"
+)
+
+// namedMembers returns the members of n that are emitted, leaving out the ones
+// whose value is taken by an earlier member.
+func (g *Generator) namedMembers(n *ast.EnumNode) ([]*ast.EnumMemberNode, error) {
+	if err := ast.Evaluate(n); err != nil {
+		return nil, err
+	}
+
+	var members []*ast.EnumMemberNode
+	seen := make(map[uint64]bool)
+
+	for _, m := range n.Members() {
+		if g.SkipRemoved && m.Removed {
+			continue
+		}
+
+		v, _ := m.ResolvedValue()
+		v = backend.StorageValue(v, n.Type)
+
+		if !seen[v] {
+			seen[v] = true
+			members = append(members, m)
+		}
+	}
+
+	return members, nil
+}
+
+// generateStringer emits a String method returning the declared name of each
+// value, or the numeric value for unknown ones.
+func (g *Generator) generateStringer(w io.Writer, n *ast.EnumNode) error {
+	members, err := g.namedMembers(n)
+
+	if err != nil {
+		return err
+	}
+
+	if n.Flags {
+		g.generateFlagsStringer(w, n, members)
+		return nil
+	}
+
+	name := typeName(n)
+
+	fmt.Fprintf(w, "\n// String returns the declared name of e.\n")
+	fmt.Fprintf(w, "func (e %s) String() string {\nswitch e {\n", name)
+
+	for _, m := range members {
+		fmt.Fprintf(w, "case %s:\nreturn %q\n", constName(n, m), m.Name())
+	}
+
+	fmt.Fprintf(w, "}\n\nreturn fmt.Sprintf(\"%s(%%d)\", e)\n}\n", name)
+
+	return nil
+}
+
+// generateFlagsStringer emits a String method listing the names of the flags
+// set in a value, like "FlagA | FlagB | 0x40", decomposed the same way as
+// ast.DecomposeFlags: members with larger values are taken first and unnamed
+// bits are appended in hex.
+func (g *Generator) generateFlagsStringer(w io.Writer, n *ast.EnumNode, members []*ast.EnumMemberNode) {
+	name := typeName(n)
+	zero := "0"
+	member, flags := splitFlags(members)
+
+	if member != nil {
+		zero = member.Name()
+	}
+
+	fmt.Fprintf(w, "\n// String returns the names of the flags set in e, joined by \" | \".\n")
+	fmt.Fprintf(w, "func (e %s) String() string {\n", name)
+	fmt.Fprintf(w, "if e == 0 {\nreturn %q\n}\n\n", zero)
+	fmt.Fprintf(w, "var names []string\nrest := e\n\n")
+	fmt.Fprintf(w, "for _, flag := range []struct {\nvalue %s\nname string\n}{\n", name)
+
+	for _, m := range flags {
+		fmt.Fprintf(w, "{%s, %q},\n", constName(n, m), m.Name())
+	}
+
+	fmt.Fprintf(w, "} {\nif rest&flag.value == flag.value {\nnames = append([]string{flag.name}, names...)\nrest &^= flag.value\n}\n}\n\n")
+	fmt.Fprintf(w, "if rest != 0 {\nnames = append(names, fmt.Sprintf(\"%%#x\", uint64(rest)))\n}\n\n")
+	fmt.Fprintf(w, "return strings.Join(names, \" | \")\n}\n")
+}
+
+// splitFlags returns the zero member, if any, and the other members sorted by
+// descending value, the order in which flags are taken out of a value.
+func splitFlags(members []*ast.EnumMemberNode) (*ast.EnumMemberNode, []*ast.EnumMemberNode) {
+	var (
+		zero  *ast.EnumMemberNode
+		flags []*ast.EnumMemberNode
+	)
+
+	for _, m := range members {
+		if v, _ := m.ResolvedValue(); v == 0 {
+			zero = m
+		} else {
+			flags = append(flags, m)
+		}
+	}
+
+	sort.SliceStable(flags, func(i, j int) bool {
+		vi, _ := flags[i].ResolvedValue()
+		vj, _ := flags[j].ResolvedValue()
+		return vi > vj
+	})
+
+	return zero, flags
+}
+
+// generateDecompose emits a Decompose method splitting a value of a flags enum
+// into its declared flags and the bits left over, like ast.DecomposeFlags.
+func (g *Generator) generateDecompose(w io.Writer, n *ast.EnumNode, members []*ast.EnumMemberNode) {
+	name := typeName(n)
+	zero, flags := splitFlags(members)
+
+	fmt.Fprintf(w, "\n// Decompose returns the declared flags set in e, in ascending value order, and\n// the bits no flag accounts for as rest.\n")
+	fmt.Fprintf(w, "func (e %s) Decompose() (flags []%s, rest %s) {\n", name, name, name)
+
+	if zero != nil {
+		fmt.Fprintf(w, "if e == 0 {\nreturn []%s{%s}, 0\n}\n\n", name, constName(n, zero))
+	}
+
+	fmt.Fprintf(w, "rest = e\n\nfor _, flag := range []%s{", name)
+
+	for i, m := range flags {
+		if i > 0 {
+			fmt.Fprintf(w, ", ")
+		}
+
+		fmt.Fprintf(w, "%s", constName(n, m))
+	}
+
+	fmt.Fprintf(w, "} {\nif rest&flag == flag {\nflags = append([]%s{flag}, flags...)\nrest &^= flag\n}\n}\n\nreturn flags, rest\n}\n", name)
+}
+
+// generateHelpers emits the Values, FromName and IsValid helpers of n, and
+// Decompose for flags enums.
+func (g *Generator) generateHelpers(w io.Writer, n *ast.EnumNode) error {
+	members, err := g.namedMembers(n)
+
+	if err != nil {
+		return err
+	}
+
+	name := typeName(n)
+
+	if len(members) == 0 {
+		return nil
+	}
+
+	var values []string
+
+	for _, m := range members {
+		values = append(values, constName(n, m))
+	}
+
+	fmt.Fprintf(w, "\n// %sValues returns the distinct values of %s, in declaration order.\n", name, name)
+	fmt.Fprintf(w, "func %sValues() []%s {\nreturn []%s{\n%s,\n}\n}\n", name, name, name, strings.Join(values, ",\n"))
+
+	fmt.Fprintf(w, "\n// %sFromName returns the %s value declared as name.\n", name, name)
+	fmt.Fprintf(w, "func %sFromName(name string) (%s, bool) {\nswitch name {\n", name, name)
+
+	for _, m := range n.Members() {
+		if g.SkipRemoved && m.Removed {
+			continue
+		}
+
+		fmt.Fprintf(w, "case %q:\nreturn %s, true\n", m.Name(), constName(n, m))
+	}
+
+	fmt.Fprintf(w, "}\n\nreturn 0, false\n}\n")
+
+	if n.Flags {
+		var flags []string
+
+		for _, m := range members {
+			if v, _ := m.ResolvedValue(); v != 0 {
+				flags = append(flags, constName(n, m))
+			}
+		}
+
+		fmt.Fprintf(w, "\n// IsValid reports whether e only sets bits of declared flags.\n")
+
+		if len(flags) == 0 {
+			fmt.Fprintf(w, "func (e %s) IsValid() bool {\nreturn e == 0\n}\n", name)
+		} else {
+			fmt.Fprintf(w, "func (e %s) IsValid() bool {\nreturn e&^(%s) == 0\n}\n", name, strings.Join(flags, " | "))
+		}
+
+		g.generateDecompose(w, n, members)
+
+		return nil
+	}
+
+	fmt.Fprintf(w, "\n// IsValid reports whether e is a declared value of %s.\n", name)
+	fmt.Fprintf(w, "func (e %s) IsValid() bool {\nswitch e {\ncase %s:\nreturn true\n}\n\nreturn false\n}\n", name, strings.Join(values, ", "))
+
+	return nil
+}
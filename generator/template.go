@@ -0,0 +1,153 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/backend"
+)
+
+// TemplateData is the context of templates generating files.
+type TemplateData struct {
+	Package string
+	// Imports are the packages of Generator.Imports. Packages used by the
+	// template itself must be imported by it, see the stdImports function.
+	Imports []string
+	// Nodes are the top-level enums, classes, unions and typedefs of the file.
+	Nodes []ast.Node
+	// Fingerprint is Generator.Fingerprint.
+	Fingerprint string
+}
+
+// ParseTemplate parses text as the template generating files, with the
+// functions of Funcs available, and sets it as Template.
+func (g *Generator) ParseTemplate(name, text string) error {
+	t, err := template.New(name).Funcs(g.Funcs()).Parse(text)
+
+	if err != nil {
+		return err
+	}
+
+	g.Template = t
+
+	return nil
+}
+
+// Funcs returns the functions available to templates:
+//
+//	enums NODES, classes NODES  the enums or classes among NODES
+//	typedefs NODES              the typedefs among NODES
+//	unions NODES                the unions among NODES
+//	stdImports NODES            standard packages used by the built-in code
+//	enum ENUM, class CLASS      the built-in code of a declaration
+//	typedef TYPEDEF             the built-in code of a typedef
+//	union UNION                 the built-in code of a union
+//	goType PROP                 Go type of a class member
+//	enumType ENUM               Go type of an enum
+//	value NODE                  Go expression of the value of a member
+//	resolvedValue NODE          evaluated value of a member
+//	skipped NODE                whether SkipRemoved omits a member
+//	typeName DECL               Go name of a class, enum or typedef
+//	constName SCOPE NODE        name of the constant of a member
+//	fieldName NAME              exported name of a field
+//	docComment DOC, lineComment COMMENT, memberComment NODE
+//	                            Go comments of a declaration
+//
+// They're bound to g, so options changed afterwards apply.
+func (g *Generator) Funcs() template.FuncMap {
+	render := func(generate func(buf *bytes.Buffer) error) (string, error) {
+		buf := &bytes.Buffer{}
+		err := generate(buf)
+		return buf.String(), err
+	}
+
+	return template.FuncMap{
+		"enums": func(nodes []ast.Node) []*ast.EnumNode {
+			var enums []*ast.EnumNode
+
+			for _, n := range nodes {
+				if e, ok := n.(*ast.EnumNode); ok {
+					enums = append(enums, e)
+				}
+			}
+
+			return enums
+		},
+		"classes": func(nodes []ast.Node) []*ast.ClassNode {
+			var classes []*ast.ClassNode
+
+			for _, n := range nodes {
+				if c, ok := n.(*ast.ClassNode); ok {
+					classes = append(classes, c)
+				}
+			}
+
+			return classes
+		},
+		"typedefs": func(nodes []ast.Node) []*ast.TypedefNode {
+			var typedefs []*ast.TypedefNode
+
+			for _, n := range nodes {
+				if t, ok := n.(*ast.TypedefNode); ok {
+					typedefs = append(typedefs, t)
+				}
+			}
+
+			return typedefs
+		},
+		"unions": func(nodes []ast.Node) []*ast.UnionNode {
+			var unions []*ast.UnionNode
+
+			for _, n := range nodes {
+				if u, ok := n.(*ast.UnionNode); ok {
+					unions = append(unions, u)
+				}
+			}
+
+			return unions
+		},
+		"stdImports": stdImports,
+		"enum": func(n *ast.EnumNode) (string, error) {
+			return render(func(buf *bytes.Buffer) error { return g.generateEnum(buf, n) })
+		},
+		"class": func(n *ast.ClassNode) (string, error) {
+			return render(func(buf *bytes.Buffer) error { return g.generateClass(buf, n) })
+		},
+		"typedef": func(n *ast.TypedefNode) (string, error) {
+			return render(func(buf *bytes.Buffer) error { return g.generateTypedef(buf, n) })
+		},
+		"union": func(n *ast.UnionNode) (string, error) {
+			return render(func(buf *bytes.Buffer) error { return g.generateUnion(buf, n) })
+		},
+		"goType":        g.fieldType,
+		"enumType":      enumType,
+		"value":         g.expr,
+		"resolvedValue": g.resolvedValue,
+		"skipped": func(n ast.Node) bool {
+			return g.SkipRemoved && backend.IsRemoved(n)
+		},
+		"typeName":      typeName,
+		"constName":     constName,
+		"fieldName":     fieldName,
+		"docComment":    docComment,
+		"lineComment":   lineComment,
+		"memberComment": memberComment,
+	}
+}
+
+func (g *Generator) generateTemplate(buf *bytes.Buffer, nodes []ast.Node) error {
+	data := &TemplateData{
+		Package:     g.Package,
+		Imports:     g.Imports,
+		Nodes:       nodes,
+		Fingerprint: g.Fingerprint,
+	}
+
+	if err := g.Template.Execute(buf, data); err != nil {
+		return fmt.Errorf("Cannot execute template: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package generator
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/backend"
+)
+
+// generateUnion emits an interface implemented by the classes of the variants
+// of n, each returning the discriminator selecting it, along with functions
+// creating, reading and writing variants preceded by their discriminator.
+func (g *Generator) generateUnion(w io.Writer, n *ast.UnionNode) error {
+	enum := n.Discriminator()
+
+	if enum == nil {
+		return fmt.Errorf("Cannot generate union %s without a discriminator enum", backend.QualifiedName(n))
+	}
+
+	if err := ast.Evaluate(enum); err != nil {
+		return err
+	}
+
+	name := typeName(n)
+	discriminator := typeName(enum)
+	method := name + "Discriminator"
+	seen := make(map[uint64]*ast.UnionVariantNode)
+
+	var methods, cases []string
+
+	for _, v := range n.Variants() {
+		class, member := v.Class(), v.Discriminator()
+
+		if class == nil || member == nil {
+			return fmt.Errorf("Cannot generate unresolved variant %s of union %s", v.Name(), backend.QualifiedName(n))
+		}
+
+		if value, ok := member.ResolvedValue(); ok {
+			if other, ok := seen[value]; ok {
+				return fmt.Errorf("Variants %s and %s of union %s share a discriminator value", other.Name(), v.Name(), backend.QualifiedName(n))
+			}
+
+			seen[value] = v
+		}
+
+		value, err := g.memberValue(member)
+
+		if err != nil {
+			return err
+		}
+
+		methods = append(methods, fmt.Sprintf("\n// %s returns the %s selecting %s in a %s.\nfunc (m *%s) %s() %s {\nreturn %s\n}\n", method, discriminator, typeName(class), name, typeName(class), method, discriminator, value))
+		cases = append(cases, fmt.Sprintf("%scase %s:%s\nreturn New%s()", g.withSource(docComment(v.Doc), v), value, lineComment(v.Comment), typeName(class)))
+	}
+
+	doc := n.Doc
+
+	if doc == "" {
+		doc = fmt.Sprintf("%s is one of the classes selected by a %s.", name, discriminator)
+	}
+
+	fmt.Fprintf(w, "\n%stype %s interface {\n", g.withSource(docComment(doc), n), name)
+	fmt.Fprintf(w, "// %s returns the %s selecting the variant.\n%s() %s\n", method, discriminator, method, discriminator)
+	fmt.Fprintf(w, "Serialize(w io.Writer) error\nDeserialize(r io.Reader) error\n}\n")
+	fmt.Fprintf(w, "%s", strings.Join(methods, ""))
+
+	fmt.Fprintf(w, "\n// New%s returns a new %s of the variant selected by d, or nil\n// if there is none.\n", name, name)
+	fmt.Fprintf(w, "func New%s(d %s) %s {\nswitch d {\n%s\n}\n\nreturn nil\n}\n", name, discriminator, name, strings.Join(cases, "\n"))
+
+	fmt.Fprintf(w, "\n// Write%s writes v to w preceded by its discriminator.\n", name)
+	fmt.Fprintf(w, "func Write%s(w io.Writer, v %s) error {\nif v == nil {\nreturn fmt.Errorf(\"Cannot write nil %s\")\n}\n\n%s\nreturn v.Serialize(w)\n}\n", name, name, name, writeValue("v."+method+"()", ast.LittleEndian))
+
+	fmt.Fprintf(w, "\n// Read%s reads a %s from r, of the variant selected by the\n// discriminator preceding it.\n", name, name)
+	fmt.Fprintf(w, "func Read%s(r io.Reader) (%s, error) {\nvar d %s\n\n%s\nv := New%s(d)\n\n", name, name, discriminator, strings.Replace(readValue("&d", ast.LittleEndian), "return err", "return nil, err", 1), name)
+	fmt.Fprintf(w, "if v == nil {\nreturn nil, fmt.Errorf(\"Unknown %s discriminator %%v\", d)\n}\n\nif err := v.Deserialize(r); err != nil {\nreturn nil, err\n}\n\nreturn v, nil\n}\n", name)
+
+	return nil
+}
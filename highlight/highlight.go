@@ -0,0 +1,575 @@
+// Package highlight classifies the tokens of steamd sources for syntax
+// highlighting: keywords, type names, enum members, properties, numbers,
+// strings and comments, marking the names of obsolete members.
+//
+// Names are classified by what they resolve to in the analyzed document, so a
+// reference like EMsg::Multi is a type followed by an enum member. Names that
+// don't resolve, like the ones declared in files that couldn't be imported,
+// are classified by where they appear.
+package highlight
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+// Class is the highlight class of a token.
+type Class int
+
+const (
+	Keyword Class = iota
+	Type
+	EnumMember
+	Property
+	Number
+	String
+	Comment
+)
+
+var classNames = []string{
+	Keyword:    "keyword",
+	Type:       "type",
+	EnumMember: "enum-member",
+	Property:   "property",
+	Number:     "number",
+	String:     "string",
+	Comment:    "comment",
+}
+
+func (c Class) String() string {
+	if c < 0 || int(c) >= len(classNames) {
+		return fmt.Sprintf("Class(%d)", int(c))
+	}
+
+	return classNames[c]
+}
+
+// MarshalText encodes c as its name.
+func (c Class) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// Span is a classified token. Offset and End are the byte offsets of the token
+// in the source, Row and Col its 1-based start, with Col and Len counted in
+// runes. Obsolete marks the names of members flagged obsolete or removed, and
+// references to them.
+type Span struct {
+	Class    Class `json:"class"`
+	Offset   int   `json:"offset"`
+	End      int   `json:"end"`
+	Row      int   `json:"row"`
+	Col      int   `json:"col"`
+	Len      int   `json:"len"`
+	Obsolete bool  `json:"obsolete,omitempty"`
+}
+
+// Highlight classifies the tokens of src in source order. The source is
+// analyzed without reading its imports, so imported names are classified by
+// where they appear. Tokens following one that can't be tokenized are left
+// out.
+func Highlight(src []byte) []Span {
+	a := parse.NewAnalyzer(token.NewTokenizer(src), "")
+	a.SetRecovery(true)
+	a.SetResolver(parse.ImportResolverFunc(func(fromFile, importPath string) ([]byte, string, error) {
+		return nil, "", fmt.Errorf("Imports are not read")
+	}))
+
+	doc, _ := a.Analyze()
+
+	return HighlightDocument(src, doc)
+}
+
+// HighlightDocument is like Highlight, resolving names with doc, an analysis
+// of src, which may be nil.
+func HighlightDocument(src []byte, doc *ast.DocumentNode) []Span {
+	h := &highlighter{doc: doc, decls: make(map[[2]int]ast.Node)}
+
+	if doc != nil {
+		for _, decl := range doc.Declarations {
+			h.declare(decl)
+
+			for _, child := range decl.Children() {
+				h.declare(child)
+			}
+		}
+	}
+
+	t := token.NewTokenizer(src)
+	t.SetTrivia(true)
+
+	for {
+		tok, err := t.Next()
+
+		if err != nil {
+			break
+		}
+
+		h.comments(tok.Leading)
+		h.tokens = append(h.tokens, tok)
+		h.comments(tok.Trailing)
+	}
+
+	h.comments(t.Trivia())
+	h.classify()
+
+	return h.spans
+}
+
+type highlighter struct {
+	doc    *ast.DocumentNode
+	decls  map[[2]int]ast.Node
+	tokens []*token.Token
+	spans  []Span
+	// pending are the comments preceding the next token, emitted in order.
+	pending []*token.Token
+}
+
+func (h *highlighter) declare(n ast.Node) {
+	if row, col := position(n); row > 0 {
+		h.decls[[2]int{row, col}] = n
+	}
+}
+
+func (h *highlighter) comments(trivia []*token.Token) {
+	for _, t := range trivia {
+		if t.Op == token.OpComment {
+			h.pending = append(h.pending, t)
+		}
+	}
+}
+
+func (h *highlighter) emit(t *token.Token, class Class, obsolete bool) {
+	h.spans = append(h.spans, Span{
+		Class:    class,
+		Offset:   t.Start,
+		End:      t.End,
+		Row:      t.Row,
+		Col:      t.Col,
+		Len:      utf8.RuneCount(t.Raw),
+		Obsolete: obsolete,
+	})
+}
+
+// emitLines emits a span per line of t, a block comment that may span lines,
+// since editors highlight tokens line by line.
+func (h *highlighter) emitLines(t *token.Token, class Class) {
+	offset, row, col := t.Start, t.Row, t.Col
+
+	for i, line := range bytes.Split(t.Raw, []byte("\n")) {
+		if i > 0 {
+			row, col = row+1, 1
+		}
+
+		if len(line) > 0 {
+			h.spans = append(h.spans, Span{
+				Class:  class,
+				Offset: offset,
+				End:    offset + len(line),
+				Row:    row,
+				Col:    col,
+				Len:    utf8.RuneCount(line),
+			})
+		}
+
+		offset += len(line) + 1
+	}
+}
+
+// context is where a name appears.
+type context int
+
+const (
+	contextDeclaration context = iota
+	contextType
+	contextName
+	contextValue
+)
+
+func (h *highlighter) classify() {
+	var (
+		depth     int
+		scope     ast.Node = h.doc
+		scopeEnum bool
+		// head holds the indexes of the identifiers of the current statement
+		// up to its name, and afterName is set once it's complete.
+		head      []int
+		afterName bool
+		inValue   bool
+		qualifier int
+		// braces tells, for each open brace, whether it opens a namespace,
+		// whose braces don't count towards depth.
+		braces []bool
+		// outer holds the scopes enclosing the classes and enums nested in
+		// classes, restored when they're closed.
+		outer []outerScope
+	)
+
+	if h.doc == nil {
+		scope = nil
+	}
+
+	// Comments are interleaved with tokens by offset.
+	commentIdx := 0
+	flushComments := func(before int) {
+		for commentIdx < len(h.pending) {
+			c := h.pending[commentIdx]
+
+			if c.Start > before {
+				return
+			}
+
+			h.emitLines(c, Comment)
+			commentIdx++
+		}
+	}
+
+	for i := 0; i < len(h.tokens); i++ {
+		t := h.tokens[i]
+		flushComments(t.Start)
+
+		switch t.Op {
+		case token.OpDoc:
+			h.emit(t, Comment, false)
+			continue
+		case token.OpString:
+			h.emit(t, String, false)
+			continue
+		case token.OpNumber:
+			h.emit(t, Number, false)
+			continue
+		case token.OpPreprocess:
+			h.emit(t, Keyword, false)
+			continue
+		case token.OpTerminator:
+			head, afterName, inValue = nil, false, false
+			continue
+		case token.OpOperator:
+			switch t.ValueString() {
+			case "{":
+				namespace := depth == 0 && len(head) > 0 && h.tokens[head[0]].Keyword == token.KeywordNamespace
+				braces = append(braces, namespace)
+
+				if !namespace {
+					depth++
+				}
+
+				head, afterName, inValue = nil, false, false
+			case "}":
+				namespace := len(braces) > 0 && braces[len(braces)-1]
+
+				if len(braces) > 0 {
+					braces = braces[:len(braces)-1]
+				}
+
+				if depth > 0 && !namespace {
+					depth--
+				}
+
+				if depth == 0 {
+					scope, scopeEnum = h.doc, false
+
+					if h.doc == nil {
+						scope = nil
+					}
+				} else if n := len(outer); n > 0 && outer[n-1].depth == depth {
+					scope, scopeEnum = outer[n-1].scope, outer[n-1].enum
+					outer = outer[:n-1]
+				}
+			case "<":
+				qualifier++
+			case ">":
+				if qualifier > 0 {
+					qualifier--
+				}
+			case "=":
+				afterName, inValue = true, true
+			}
+
+			continue
+		case token.OpIdentifier:
+		default:
+			continue
+		}
+
+		value := t.ValueString()
+		// declaring is set in the head of a declaration nested in a class
+		declaring := depth > 0 && len(head) > 0 && isNestedKeyword(h.tokens[head[0]].Keyword)
+
+		// Declaration keywords and attributes.
+		if depth == 0 && qualifier == 0 && t.Keyword.IsDeclaration() && len(head) == 0 {
+			h.emit(t, Keyword, false)
+			scopeEnum = t.Keyword == token.KeywordEnum
+			head = append(head, i)
+			continue
+		}
+
+		if depth > 0 && qualifier == 0 && !scopeEnum && !inValue && len(head) == 0 && isNestedKeyword(t.Keyword) {
+			h.emit(t, Keyword, false)
+			outer = append(outer, outerScope{scope: scope, enum: scopeEnum, depth: depth})
+			scopeEnum = t.Keyword == token.KeywordEnum
+			head = append(head, i)
+			continue
+		}
+
+		if qualifier == 0 && t.Keyword == token.KeywordFlags && (depth == 0 || declaring) && len(head) > 0 {
+			h.emit(t, Keyword, false)
+			continue
+		}
+
+		if depth > 0 && qualifier == 0 && len(head) == 0 && !inValue && isAttribute(t) && i > 0 && isAttributePosition(h.tokens[i-1]) {
+			h.emit(t, Keyword, false)
+			continue
+		}
+
+		// byte orders, encodings and terminations are keywords too
+		if depth > 0 && i >= 2 && h.tokens[i-2].ValueString() != "if" && h.isAnnotation(i-2) {
+			h.emit(t, Keyword, false)
+			continue
+		}
+
+		// the annotations of a field, whose values aren't names
+		if depth > 0 && qualifier == 0 && !scopeEnum && h.isAnnotation(i) {
+			h.emit(t, Keyword, false)
+			afterName, inValue = true, true
+			continue
+		}
+
+		if depth > 0 && qualifier == 0 && !scopeEnum && len(head) == 0 && !inValue {
+			// the optional modifier precedes the flag, if any
+			if t.Keyword == token.KeywordOptional && h.headLength(i) >= 3 {
+				h.emit(t, Keyword, false)
+				continue
+			}
+
+			if _, ok := ast.ParsePropertyFlag(value); ok && h.headLength(i) == 3 {
+				h.emit(t, Keyword, false)
+				head = append(head, i)
+				continue
+			}
+		}
+
+		// A namespaced path, classified segment by segment.
+		path := []*token.Token{t}
+
+		for i+2 < len(h.tokens) && h.tokens[i+1].Op == token.OpNamespace && h.tokens[i+2].Op == token.OpIdentifier {
+			path = append(path, h.tokens[i+2])
+			i += 2
+		}
+
+		ctx := contextValue
+
+		switch {
+		case qualifier > 0 || inValue:
+			ctx = contextValue
+		case depth == 0:
+			ctx = contextDeclaration
+		case !afterName && h.headLength(i) == 1:
+			ctx = contextName
+			afterName = true
+		case !afterName:
+			ctx = contextType
+		}
+
+		if depth == 0 && qualifier == 0 {
+			head = append(head, i)
+		} else if depth > 0 && qualifier == 0 && !inValue && !afterName {
+			head = append(head, i)
+		}
+
+		if (depth == 0 && ctx == contextDeclaration || declaring) && len(path) == 1 {
+			if n, ok := h.decls[[2]int{t.Row, t.Col}]; ok {
+				scope = n
+			}
+		}
+
+		h.path(path, scope, ctx, scopeEnum)
+	}
+
+	flushComments(int(^uint(0) >> 1))
+}
+
+// outerScope is the scope enclosing a nested declaration, at depth.
+type outerScope struct {
+	scope ast.Node
+	enum  bool
+	depth int
+}
+
+// headLength returns the number of identifiers in the head of the statement
+// starting at tokens[i], skipping qualifiers, up to a value or terminator.
+func (h *highlighter) headLength(i int) int {
+	n := 0
+	qualifier := 0
+
+	for ; i < len(h.tokens); i++ {
+		t := h.tokens[i]
+
+		switch {
+		case t.Op == token.OpOperator && t.ValueString() == "<":
+			qualifier++
+		case t.Op == token.OpOperator && t.ValueString() == ">":
+			qualifier--
+		case qualifier > 0:
+		case t.Op == token.OpIdentifier && h.isAnnotation(i):
+			return n
+		case t.Op == token.OpIdentifier:
+			n++
+		case t.Op == token.OpNamespace:
+			n--
+		default:
+			return n
+		}
+	}
+
+	return n
+}
+
+// isAnnotation reports whether tokens[i] is the keyword starting an annotation
+// of a field, like its condition, followed by a colon.
+func (h *highlighter) isAnnotation(i int) bool {
+	switch h.tokens[i].ValueString() {
+	case "if", "endian", "encoding", "term":
+	default:
+		return false
+	}
+
+	if i+1 >= len(h.tokens) {
+		return false
+	}
+
+	next := h.tokens[i+1]
+
+	return next.Op == token.OpOperator && next.ValueString() == ":"
+}
+
+// isNestedKeyword reports whether k starts a declaration nested in a class.
+func isNestedKeyword(k token.Keyword) bool {
+	return k == token.KeywordClass || k == token.KeywordEnum
+}
+
+// isAttribute reports whether t is the obsolete or removed attribute.
+func isAttribute(t *token.Token) bool {
+	return t.Keyword == token.KeywordObsolete || t.Keyword == token.KeywordRemoved
+}
+
+// isAttributePosition reports whether an attribute can follow prev: a
+// terminator, another attribute or its reason.
+func isAttributePosition(prev *token.Token) bool {
+	switch prev.Op {
+	case token.OpTerminator, token.OpString:
+		return true
+	case token.OpIdentifier:
+		return isAttribute(prev)
+	}
+
+	return false
+}
+
+// path classifies the segments of a name, resolving them from scope when
+// possible.
+func (h *highlighter) path(path []*token.Token, scope ast.Node, ctx context, inEnum bool) {
+	var values []string
+
+	for i, t := range path {
+		values = append(values, t.ValueString())
+		last := i == len(path)-1
+
+		if last && len(path) == 1 && ast.IsBuiltinType(values[0]) {
+			h.emit(t, Type, false)
+			continue
+		}
+
+		if n, ok := h.decls[[2]int{t.Row, t.Col}]; ok {
+			class, obsolete := nodeClass(n)
+			h.emit(t, class, obsolete)
+			continue
+		}
+
+		if n := h.resolve(scope, values); n != nil {
+			class, obsolete := nodeClass(n)
+			h.emit(t, class, obsolete)
+			continue
+		}
+
+		switch {
+		case !last:
+			h.emit(t, Type, false)
+		case len(path) > 1:
+			h.emit(t, EnumMember, false)
+		case ctx == contextDeclaration, ctx == contextType:
+			h.emit(t, Type, false)
+		case inEnum:
+			h.emit(t, EnumMember, false)
+		default:
+			h.emit(t, Property, false)
+		}
+	}
+}
+
+// resolve looks up path from scope, then from the document.
+func (h *highlighter) resolve(scope ast.Node, path []string) ast.Node {
+	for _, n := range []ast.Node{scope, h.doc} {
+		if n == nil {
+			continue
+		}
+
+		sym := ast.LookupSymbol(n, path)
+
+		if sym == nil {
+			continue
+		}
+
+		switch sym.Node.(type) {
+		case *ast.NamespaceNode, *ast.TypedefNode, *ast.ClassNode, *ast.EnumNode, *ast.UnionNode, *ast.EnumMemberNode, *ast.PropertyNode:
+			return sym.Node
+		}
+	}
+
+	return nil
+}
+
+func nodeClass(n ast.Node) (Class, bool) {
+	switch n := n.(type) {
+	case *ast.EnumMemberNode:
+		return EnumMember, n.Obsolete || n.Removed
+	case *ast.PropertyNode:
+		return Property, n.Obsolete || n.Removed
+	case *ast.UnionVariantNode:
+		// variants are named after the members of the discriminator
+		if m := n.Discriminator(); m != nil {
+			return EnumMember, m.Obsolete || m.Removed
+		}
+
+		return EnumMember, false
+	}
+
+	return Type, false
+}
+
+func position(n ast.Node) (int, int) {
+	switch n := n.(type) {
+	case *ast.NamespaceNode:
+		return n.Row, n.Col
+	case *ast.TypedefNode:
+		return n.Row, n.Col
+	case *ast.ClassNode:
+		return n.Row, n.Col
+	case *ast.EnumNode:
+		return n.Row, n.Col
+	case *ast.UnionNode:
+		return n.Row, n.Col
+	case *ast.UnionVariantNode:
+		return n.Row, n.Col
+	case *ast.EnumMemberNode:
+		return n.Row, n.Col
+	case *ast.PropertyNode:
+		return n.Row, n.Col
+	}
+
+	return 0, 0
+}
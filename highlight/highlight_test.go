@@ -0,0 +1,216 @@
+package highlight
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const source = `#import "emsg.steamd"
+
+/// Results of requests.
+enum EResult<byte> flags {
+	OK = 1;
+	Busy = 2; removed "merged into Timeout"
+	Timeout = OK | 4; // timed out
+};
+
+class MsgClientLogon<EMsg::ClientLogon> {
+	const uint VERSION = 2;
+	steamidmarshal ulong steamID;
+	byte<VERSION> key = 0x10;
+	EResult result = EResult::Busy;
+};
+`
+
+func TestHighlight(t *testing.T) {
+	expected := []string{
+		`1:1 keyword "#import"`,
+		`1:9 string "\"emsg.steamd\""`,
+		`3:1 comment "/// Results of requests."`,
+		`4:1 keyword "enum"`,
+		`4:6 type "EResult"`,
+		`4:14 type "byte"`,
+		`4:20 keyword "flags"`,
+		`5:2 enum-member "OK"`,
+		`5:7 number "1"`,
+		`6:2 enum-member "Busy" obsolete`,
+		`6:9 number "2"`,
+		`6:12 keyword "removed"`,
+		`6:20 string "\"merged into Timeout\""`,
+		`7:2 enum-member "Timeout"`,
+		`7:12 enum-member "OK"`,
+		`7:17 number "4"`,
+		`7:20 comment "// timed out"`,
+		`10:1 keyword "class"`,
+		`10:7 type "MsgClientLogon"`,
+		`10:22 type "EMsg"`,
+		`10:28 enum-member "ClientLogon"`,
+		`11:2 keyword "const"`,
+		`11:8 type "uint"`,
+		`11:13 property "VERSION"`,
+		`11:23 number "2"`,
+		`12:2 keyword "steamidmarshal"`,
+		`12:17 type "ulong"`,
+		`12:23 property "steamID"`,
+		`13:2 type "byte"`,
+		`13:7 property "VERSION"`,
+		`13:16 property "key"`,
+		`13:22 number "0x10"`,
+		`14:2 type "EResult"`,
+		`14:10 property "result"`,
+		`14:19 type "EResult"`,
+		`14:28 enum-member "Busy" obsolete`,
+	}
+
+	var actual []string
+
+	for _, span := range Highlight([]byte(source)) {
+		s := fmt.Sprintf("%d:%d %s %q", span.Row, span.Col, span.Class, source[span.Offset:span.End])
+
+		if span.Obsolete {
+			s += " obsolete"
+		}
+
+		actual = append(actual, s)
+	}
+
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("mismatch: got\n%s\nbut expected\n%s", strings.Join(actual, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestHighlightNamespaces(t *testing.T) {
+	src := "namespace Steam {\n\tenum E { A; };\n\tclass C { E e = E::A; };\n}\nclass D<Steam::E::A> { };\n"
+
+	expected := []string{
+		`keyword "namespace"`,
+		`type "Steam"`,
+		`keyword "enum"`,
+		`type "E"`,
+		`enum-member "A"`,
+		`keyword "class"`,
+		`type "C"`,
+		`type "E"`,
+		`property "e"`,
+		`type "E"`,
+		`enum-member "A"`,
+		`keyword "class"`,
+		`type "D"`,
+		`type "Steam"`,
+		`type "E"`,
+		`enum-member "A"`,
+	}
+
+	var actual []string
+
+	for _, span := range Highlight([]byte(src)) {
+		actual = append(actual, fmt.Sprintf("%s %q", span.Class, src[span.Offset:span.End]))
+	}
+
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("mismatch: got\n%s\nbut expected\n%s", strings.Join(actual, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestHighlightNestedDeclarations(t *testing.T) {
+	src := "class C {\n\tenum E flags { A = 1; B = A; };\n\tE e;\n};\n"
+
+	expected := []string{
+		`keyword "class"`,
+		`type "C"`,
+		`keyword "enum"`,
+		`type "E"`,
+		`keyword "flags"`,
+		`enum-member "A"`,
+		`number "1"`,
+		`enum-member "B"`,
+		`enum-member "A"`,
+		`type "E"`,
+		`property "e"`,
+	}
+
+	var actual []string
+
+	for _, span := range Highlight([]byte(src)) {
+		actual = append(actual, fmt.Sprintf("%s %q", span.Class, src[span.Offset:span.End]))
+	}
+
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("mismatch: got\n%s\nbut expected\n%s", strings.Join(actual, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestHighlightUnions(t *testing.T) {
+	src := "enum E { A; };\nclass C {};\nunion U<E> { C A; };\n"
+
+	expected := []string{
+		`keyword "enum"`,
+		`type "E"`,
+		`enum-member "A"`,
+		`keyword "class"`,
+		`type "C"`,
+		`keyword "union"`,
+		`type "U"`,
+		`type "E"`,
+		`type "C"`,
+		`enum-member "A"`,
+	}
+
+	var actual []string
+
+	for _, span := range Highlight([]byte(src)) {
+		actual = append(actual, fmt.Sprintf("%s %q", span.Class, src[span.Offset:span.End]))
+	}
+
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("mismatch: got\n%s\nbut expected\n%s", strings.Join(actual, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestHighlightUnicode(t *testing.T) {
+	spans := Highlight([]byte("// é\nenum E { A; }; // é\n"))
+
+	if len(spans) != 5 {
+		t.Fatalf("mismatch: got %+v, but expected 5 spans", spans)
+	}
+
+	if span := spans[0]; span.Len != 4 || span.End-span.Offset != 5 {
+		t.Fatalf("mismatch: got %+v, but expected a comment of 4 runes and 5 bytes", span)
+	}
+
+	if span := spans[4]; span.Class != Comment || span.Row != 2 || span.Col != 16 {
+		t.Fatalf("mismatch: got %+v, but expected a comment at 2:16", span)
+	}
+}
+
+func TestHighlightBlockComments(t *testing.T) {
+	src := "/* é\n\n end */ enum E { A; };"
+	spans := Highlight([]byte(src))
+	var comments []string
+
+	for _, span := range spans {
+		if span.Class == Comment {
+			comments = append(comments, fmt.Sprintf("%d:%d+%d %q", span.Row, span.Col, span.Len, src[span.Offset:span.End]))
+		}
+	}
+
+	if expected := `1:1+4 "/* é" 3:1+7 " end */"`; strings.Join(comments, " ") != expected {
+		t.Fatalf("mismatch: got %v, but expected %s", comments, expected)
+	}
+}
+
+func TestSpanJSON(t *testing.T) {
+	b, err := json.Marshal(Span{Class: EnumMember, Offset: 1, End: 3, Row: 1, Col: 2, Len: 2, Obsolete: true})
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expected := `{"class":"enum-member","offset":1,"end":3,"row":1,"col":2,"len":2,"obsolete":true}`
+
+	if string(b) != expected {
+		t.Fatalf("mismatch: got %s, but expected %s", b, expected)
+	}
+}
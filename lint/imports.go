@@ -0,0 +1,141 @@
+package lint
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/parse"
+)
+
+func init() {
+	Register(unusedImportsRule{})
+}
+
+// unusedImportsRule checks that each import declares, directly or through its
+// own imports, something the importing file references.
+type unusedImportsRule struct{}
+
+func (unusedImportsRule) Name() string {
+	return "unused-imports"
+}
+
+func (unusedImportsRule) Doc() string {
+	return "Imported files declare something the importing file references."
+}
+
+func (unusedImportsRule) Check(p *Pass) {
+	if len(p.Doc.Imports) == 0 {
+		return
+	}
+
+	// the document declaring each imported declaration
+	owners := make(map[ast.Node]*ast.DocumentNode)
+
+	for _, dep := range p.Doc.Dependencies() {
+		for _, decl := range dep.Declarations {
+			owners[decl] = dep
+		}
+	}
+
+	used := make(map[*ast.DocumentNode]bool)
+
+	for n := range references(p.Doc) {
+		if owner, ok := owners[declaration(n)]; ok {
+			used[owner] = true
+		}
+	}
+
+	for _, imp := range p.Doc.Imports {
+		if imp.Document == nil || used[imp.Document] {
+			continue
+		}
+
+		transitive := false
+
+		for _, dep := range imp.Document.Dependencies() {
+			if used[dep] {
+				transitive = true
+				break
+			}
+		}
+
+		if !transitive {
+			// the position is the one of the quoted path
+			length := utf8.RuneCountInString(imp.Path) + 2
+			p.ReportAt(imp.Row, imp.Col, length, parse.SeverityWarning, fmt.Sprintf("Import %q is unused", imp.Path))
+		}
+	}
+}
+
+// references returns the declarations and members referenced by the
+// declarations of doc, as types, qualifiers or in values.
+func references(doc *ast.DocumentNode) map[ast.Node]bool {
+	refs := make(map[ast.Node]bool)
+
+	add := func(sym *ast.Symbol) {
+		if sym == nil {
+			return
+		}
+
+		switch sym.Node.(type) {
+		case *ast.ClassNode, *ast.EnumNode, *ast.TypedefNode, *ast.UnionNode, *ast.EnumMemberNode, *ast.PropertyNode:
+			refs[sym.Node] = true
+		}
+	}
+
+	qualifier := func(q *ast.Qualifier) {
+		if q != nil {
+			add(q.Symbol)
+		}
+	}
+
+	for _, decl := range doc.Declarations {
+		switch n := decl.(type) {
+		case *ast.ClassNode:
+			qualifier(n.Qualifier)
+			add(n.Base)
+		case *ast.EnumNode:
+			qualifier(n.Qualifier)
+		case *ast.TypedefNode:
+			add(n.Type)
+		case *ast.UnionNode:
+			qualifier(n.Qualifier)
+		}
+
+		for _, child := range decl.Children() {
+			switch n := child.(type) {
+			case *ast.PropertyNode:
+				add(n.Type)
+				add(n.Alias)
+				qualifier(n.Qualifier)
+
+				for _, sym := range n.Default {
+					add(sym)
+				}
+			case *ast.EnumMemberNode:
+				for _, sym := range n.Default {
+					add(sym)
+				}
+			case *ast.UnionVariantNode:
+				add(n.Type)
+				add(n.Member)
+			}
+		}
+	}
+
+	return refs
+}
+
+// declaration returns the top-level declaration of n, n itself if it's one.
+func declaration(n ast.Node) ast.Node {
+	for n.Parent() != nil {
+		if _, ok := n.Parent().(*ast.DocumentNode); ok {
+			return n
+		}
+
+		n = n.Parent()
+	}
+
+	return n
+}
@@ -0,0 +1,215 @@
+// Package lint checks analyzed schemas for likely mistakes and style issues,
+// reporting them as diagnostics.
+//
+// Checks are rules run over the declarations of a document. The default
+// rules are registered by this package, and others can be added with
+// Register:
+//
+//	func init() {
+//		lint.Register(myRule{})
+//	}
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/parse"
+)
+
+// Rule is a check run over a document.
+type Rule interface {
+	// Name identifies the rule, and is the code of its diagnostics.
+	Name() string
+	// Doc describes what the rule reports in a sentence.
+	Doc() string
+	Check(p *Pass)
+}
+
+var (
+	mu    sync.RWMutex
+	rules = make(map[string]Rule)
+)
+
+// Register makes r available by its name. It panics if the name is taken.
+func Register(r Rule) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	name := r.Name()
+
+	if _, ok := rules[name]; ok {
+		panic(fmt.Sprintf("lint: Register called twice for %q", name))
+	}
+
+	rules[name] = r
+}
+
+// Lookup returns the rule registered as name.
+func Lookup(name string) (Rule, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	r, ok := rules[name]
+
+	return r, ok
+}
+
+// Rules returns the registered rules, sorted by name.
+func Rules() []Rule {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var list []Rule
+
+	for _, r := range rules {
+		list = append(list, r)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name() < list[j].Name()
+	})
+
+	return list
+}
+
+// Select returns the registered rules with the given names.
+func Select(names []string) ([]Rule, error) {
+	var list []Rule
+
+	for _, name := range names {
+		r, ok := Lookup(name)
+
+		if !ok {
+			return nil, fmt.Errorf("Unknown rule %q", name)
+		}
+
+		list = append(list, r)
+	}
+
+	return list, nil
+}
+
+// Linter runs rules over documents.
+type Linter struct {
+	// Rules are the rules run, all the registered ones if nil.
+	Rules []Rule
+	// Strict makes rules report some issues as errors instead of warnings.
+	Strict bool
+	// Severity overrides the severity of the diagnostics of rules, by name.
+	Severity map[string]parse.Severity
+}
+
+// Lint runs the registered rules over doc.
+func Lint(doc *ast.DocumentNode) ([]parse.Diagnostic, error) {
+	return (&Linter{}).Lint(doc)
+}
+
+// Lint returns the diagnostics of the rules over doc, sorted by position.
+// Imported declarations are left to the analysis of their own files.
+func (l *Linter) Lint(doc *ast.DocumentNode) ([]parse.Diagnostic, error) {
+	if err := ast.Evaluate(doc); err != nil {
+		return nil, err
+	}
+
+	list := l.Rules
+
+	if list == nil {
+		list = Rules()
+	}
+
+	var diags []parse.Diagnostic
+
+	for _, r := range list {
+		p := &Pass{Doc: doc, Strict: l.Strict, rule: r}
+		r.Check(p)
+
+		if severity, ok := l.Severity[r.Name()]; ok {
+			for i := range p.diags {
+				p.diags[i].Severity = severity
+			}
+		}
+
+		diags = append(diags, p.diags...)
+	}
+
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i].Pos, diags[j].Pos
+
+		if a.Row != b.Row {
+			return a.Row < b.Row
+		}
+
+		return a.Col < b.Col
+	})
+
+	return diags, nil
+}
+
+// Pass is a run of a rule over a document, with its values evaluated.
+type Pass struct {
+	Doc    *ast.DocumentNode
+	Strict bool
+	rule   Rule
+	diags  []parse.Diagnostic
+}
+
+// Declarations returns the declarations of the document itself.
+func (p *Pass) Declarations() []ast.Node {
+	return p.Doc.Declarations
+}
+
+// Reportf reports a warning at the name of n.
+func (p *Pass) Reportf(n ast.Node, format string, v ...interface{}) {
+	p.Report(n, parse.SeverityWarning, fmt.Sprintf(format, v...))
+}
+
+// Report reports message at the name of n.
+func (p *Pass) Report(n ast.Node, severity parse.Severity, message string) {
+	row, col := Position(n)
+	p.ReportAt(row, col, utf8.RuneCountInString(n.Name()), severity, message)
+}
+
+// ReportAt reports message at a 1-based row and col of the document, spanning
+// length runes.
+func (p *Pass) ReportAt(row, col, length int, severity parse.Severity, message string) {
+	pos := parse.Position{Filename: p.Doc.Filename, Row: row, Col: col}
+	end := pos
+
+	if row > 0 {
+		end.Col += length
+	}
+
+	p.diags = append(p.diags, parse.Diagnostic{
+		Severity: severity,
+		Pos:      pos,
+		End:      end,
+		Message:  message,
+		Code:     p.rule.Name(),
+	})
+}
+
+// Position returns the position of the name of a declaration or member, 0 for
+// other nodes.
+func Position(n ast.Node) (row, col int) {
+	switch n := n.(type) {
+	case *ast.ClassNode:
+		return n.Row, n.Col
+	case *ast.EnumNode:
+		return n.Row, n.Col
+	case *ast.EnumMemberNode:
+		return n.Row, n.Col
+	case *ast.PropertyNode:
+		return n.Row, n.Col
+	}
+
+	return 0, 0
+}
+
+// qualifiedName returns the name of a member qualified by its declaration.
+func qualifiedName(n ast.Node) string {
+	return n.Parent().Name() + "::" + n.Name()
+}
@@ -0,0 +1,247 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+func analyze(t *testing.T, data string) *ast.DocumentNode {
+	doc, err := parse.NewAnalyzer(token.NewTokenizer([]byte(data)), "test.steamd").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	return doc
+}
+
+func format(diags []parse.Diagnostic) string {
+	var lines []string
+
+	for _, diag := range diags {
+		lines = append(lines, fmt.Sprintf("%s [%s]", diag, diag.Code))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func lint(t *testing.T, l *Linter, data string) string {
+	diags, err := l.Lint(analyze(t, data))
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	return format(diags)
+}
+
+func TestNamingRule(t *testing.T) {
+	data := `enum eResult {
+	OK = 1;
+	busy = 2;
+};
+
+class MsgLogon {
+	const uint version = 1;
+	uint ProtocolVersion;
+	uint steamID;
+};
+`
+
+	expected := `test.steamd:1:6: warning: Enum name eResult should start with an uppercase letter [naming]
+test.steamd:3:2: warning: Enum member eResult::busy should start with an uppercase letter [naming]
+test.steamd:8:7: warning: Field MsgLogon::ProtocolVersion should start with a lowercase letter [naming]`
+
+	if actual := lint(t, &Linter{Rules: []Rule{namingRule{}}}, data); actual != expected {
+		t.Fatalf("mismatch: got\n%s\nbut expected\n%s", actual, expected)
+	}
+}
+
+func TestFlagValuesRule(t *testing.T) {
+	data := `enum EFlags flags {
+	None = 0;
+	A = 1;
+	B = 2;
+	C;
+	AB = 3;
+	Bad = 12;
+	All = A | B;
+};
+
+enum ECount {
+	One = 1;
+	Two;
+	Three;
+};
+`
+
+	expected := `test.steamd:5:2: warning: Flags member EFlags::C has the implicit value 0x3, which isn't a single bit [flag-values]
+test.steamd:7:2: warning: Flags member EFlags::Bad has the value 0xc, which isn't a single bit nor a combination of other members [flag-values]`
+
+	if actual := lint(t, &Linter{Rules: []Rule{flagValuesRule{}}}, data); actual != expected {
+		t.Fatalf("mismatch: got\n%s\nbut expected\n%s", actual, expected)
+	}
+}
+
+func TestObsoleteReasonRule(t *testing.T) {
+	data := `enum EResult {
+	OK = 1;
+	Busy = 2; obsolete
+	Fail = 3; obsolete " "
+	Timeout = 4; obsolete "use Busy"
+	Gone = 5; removed
+};
+
+class MsgLogon {
+	uint version; obsolete
+};
+`
+
+	expected := `test.steamd:3:2: warning: Obsolete member EResult::Busy has no reason [obsolete-reason]
+test.steamd:4:2: warning: Obsolete member EResult::Fail has no reason [obsolete-reason]
+test.steamd:10:7: warning: Obsolete member MsgLogon::version has no reason [obsolete-reason]`
+
+	if actual := lint(t, &Linter{Rules: []Rule{obsoleteReasonRule{}}}, data); actual != expected {
+		t.Fatalf("mismatch: got\n%s\nbut expected\n%s", actual, expected)
+	}
+}
+
+func TestDuplicateValuesRule(t *testing.T) {
+	data := `enum EResult {
+	OK = 1;
+	Busy = 2;
+	Occupied = Busy;
+	Timeout;
+	Fail = 0x3;
+	Ok = 1;
+};
+`
+
+	expected := `test.steamd:4:2: warning: Enum member EResult::Occupied has the same value 2 as Busy [duplicate-values]
+test.steamd:6:2: warning: Enum member EResult::Fail has the same value 3 as Timeout [duplicate-values]
+test.steamd:7:2: warning: Enum member EResult::Ok has the same value 1 as OK [duplicate-values]`
+
+	if actual := lint(t, &Linter{Rules: []Rule{duplicateValuesRule{}}}, data); actual != expected {
+		t.Fatalf("mismatch: got\n%s\nbut expected\n%s", actual, expected)
+	}
+
+	expected = strings.Replace(expected, "warning", "error", -1)
+
+	if actual := lint(t, &Linter{Rules: []Rule{duplicateValuesRule{}}, Strict: true}, data); actual != expected {
+		t.Fatalf("mismatch: got\n%s\nbut expected\n%s", actual, expected)
+	}
+}
+
+func TestUnusedImportsRule(t *testing.T) {
+	fsys := fstest.MapFS{
+		"emsg.steamd":    {Data: []byte("enum EMsg { Invalid = 0; Multi = 1; };\n")},
+		"eresult.steamd": {Data: []byte("enum EResult { OK = 1; };\n")},
+		"base.steamd":    {Data: []byte("#import \"eresult.steamd\"\nclass MsgBase { uint x; };\n")},
+		"size.steamd":    {Data: []byte("class Sizes { const uint KEY = 20; };\n")},
+		"unused.steamd":  {Data: []byte("enum EUnused { A; };\n")},
+		"main.steamd": {Data: []byte(`#import "emsg.steamd"
+#import "base.steamd"
+#import "size.steamd"
+#import "unused.steamd"
+
+class Msg<EMsg::Multi> {
+	EResult result;
+	byte<Sizes::KEY> key;
+};
+`)},
+	}
+
+	analyzer := parse.NewAnalyzer(token.NewTokenizer(fsys["main.steamd"].Data), "main.steamd")
+	analyzer.SetFS(fsys)
+	doc, err := analyzer.Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	diags, err := (&Linter{Rules: []Rule{unusedImportsRule{}}}).Lint(doc)
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expected := `main.steamd:4:9: warning: Import "unused.steamd" is unused [unused-imports]`
+
+	if actual := format(diags); actual != expected {
+		t.Fatalf("mismatch: got\n%s\nbut expected\n%s", actual, expected)
+	}
+
+	if diags[0].End.Col != 24 {
+		t.Fatalf("mismatch: got end %+v, but expected column 24", diags[0].End)
+	}
+}
+
+type testRule struct{}
+
+func (testRule) Name() string {
+	return "test-rule"
+}
+
+func (testRule) Doc() string {
+	return "Reports every declaration."
+}
+
+func (testRule) Check(p *Pass) {
+	for _, decl := range p.Declarations() {
+		p.Reportf(decl, "Declaration %s", decl.Name())
+	}
+}
+
+func TestLinter(t *testing.T) {
+	Register(testRule{})
+	defer func() {
+		mu.Lock()
+		delete(rules, "test-rule")
+		mu.Unlock()
+	}()
+
+	if r, ok := Lookup("test-rule"); !ok || r.Name() != "test-rule" {
+		t.Fatalf("expected test-rule to be registered")
+	}
+
+	var names []string
+
+	for _, r := range Rules() {
+		names = append(names, r.Name())
+	}
+
+	if actual := strings.Join(names, " "); actual != "duplicate-values flag-values naming obsolete-reason test-rule unused-imports" {
+		t.Fatalf("mismatch: got %s", actual)
+	}
+
+	data := "class msg { uint X; };\n"
+	expected := `test.steamd:1:7: warning: Class name msg should start with an uppercase letter [naming]
+test.steamd:1:7: error: Declaration msg [test-rule]
+test.steamd:1:18: warning: Field msg::X should start with a lowercase letter [naming]`
+
+	l := &Linter{Severity: map[string]parse.Severity{"test-rule": parse.SeverityError}}
+
+	if actual := lint(t, l, data); actual != expected {
+		t.Fatalf("mismatch: got\n%s\nbut expected\n%s", actual, expected)
+	}
+
+	if _, err := Select([]string{"naming", "unknown"}); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic")
+		}
+	}()
+
+	Register(namingRule{})
+}
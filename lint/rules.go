@@ -0,0 +1,193 @@
+package lint
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/parse"
+)
+
+func init() {
+	Register(namingRule{})
+	Register(flagValuesRule{})
+	Register(obsoleteReasonRule{})
+	Register(duplicateValuesRule{})
+}
+
+// namingRule checks the case of names, following SteamKit's schemas: classes,
+// enums, unions and enum members start with an uppercase letter, and fields
+// with a lowercase one. Constants are left alone.
+type namingRule struct{}
+
+func (namingRule) Name() string {
+	return "naming"
+}
+
+func (namingRule) Doc() string {
+	return "Namespaces, typedefs, classes, enums, unions and enum members start with an uppercase letter, fields with a lowercase one."
+}
+
+func (namingRule) Check(p *Pass) {
+	for _, decl := range p.Declarations() {
+		kind := "Class"
+
+		switch decl.(type) {
+		case *ast.EnumNode:
+			kind = "Enum"
+		case *ast.NamespaceNode:
+			kind = "Namespace"
+		case *ast.TypedefNode:
+			kind = "Typedef"
+		case *ast.UnionNode:
+			kind = "Union"
+		}
+
+		if !startsWith(decl.Name(), unicode.IsUpper) {
+			p.Reportf(decl, "%s name %s should start with an uppercase letter", kind, decl.Name())
+		}
+
+		for _, child := range decl.Children() {
+			switch n := child.(type) {
+			case *ast.EnumMemberNode:
+				if !startsWith(n.Name(), unicode.IsUpper) {
+					p.Reportf(n, "Enum member %s should start with an uppercase letter", qualifiedName(n))
+				}
+			case *ast.PropertyNode:
+				if !n.IsConst() && !startsWith(n.Name(), unicode.IsLower) {
+					p.Reportf(n, "Field %s should start with a lowercase letter", qualifiedName(n))
+				}
+			}
+		}
+	}
+}
+
+func startsWith(name string, is func(rune) bool) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return is(r)
+}
+
+// flagValuesRule checks that the members of flags enums are single bits, none
+// or combinations of other members. Other values are usually typos or
+// implicit values counting up from the previous member.
+type flagValuesRule struct{}
+
+func (flagValuesRule) Name() string {
+	return "flag-values"
+}
+
+func (flagValuesRule) Doc() string {
+	return "Members of flags enums are single bits, 0 or combinations of other members."
+}
+
+func (flagValuesRule) Check(p *Pass) {
+	for _, decl := range p.Declarations() {
+		enum, ok := decl.(*ast.EnumNode)
+
+		if !ok || !enum.Flags {
+			continue
+		}
+
+		members := enum.Members()
+		var singles uint64
+
+		for _, member := range members {
+			if v, ok := member.ResolvedValue(); ok && bits.OnesCount64(v) == 1 {
+				singles |= v
+			}
+		}
+
+		for _, member := range members {
+			v, ok := member.ResolvedValue()
+
+			switch {
+			case !ok || bits.OnesCount64(v) < 2:
+			case member.Expr == nil:
+				p.Reportf(member, "Flags member %s has the implicit value %#x, which isn't a single bit", qualifiedName(member), v)
+			case v&^singles != 0:
+				p.Reportf(member, "Flags member %s has the value %#x, which isn't a single bit nor a combination of other members", qualifiedName(member), v)
+			}
+		}
+	}
+}
+
+// obsoleteReasonRule checks that obsolete members say why, like SteamKit's
+// schemas do, so readers know what to use instead.
+type obsoleteReasonRule struct{}
+
+func (obsoleteReasonRule) Name() string {
+	return "obsolete-reason"
+}
+
+func (obsoleteReasonRule) Doc() string {
+	return "Members flagged obsolete give a reason."
+}
+
+func (obsoleteReasonRule) Check(p *Pass) {
+	for _, decl := range p.Declarations() {
+		for _, child := range decl.Children() {
+			var obsolete bool
+			var reason string
+
+			switch n := child.(type) {
+			case *ast.EnumMemberNode:
+				obsolete, reason = n.Obsolete, n.ObsoleteReason
+			case *ast.PropertyNode:
+				obsolete, reason = n.Obsolete, n.ObsoleteReason
+			}
+
+			if obsolete && strings.TrimSpace(reason) == "" {
+				p.Reportf(child, "Obsolete member %s has no reason", qualifiedName(child))
+			}
+		}
+	}
+}
+
+// duplicateValuesRule checks that the members of an enum have distinct
+// values. Duplicates make reverse lookups and generated String methods pick
+// one of the names arbitrarily. They're errors in strict mode.
+type duplicateValuesRule struct{}
+
+func (duplicateValuesRule) Name() string {
+	return "duplicate-values"
+}
+
+func (duplicateValuesRule) Doc() string {
+	return "Members of an enum have distinct values, errors in strict mode."
+}
+
+func (duplicateValuesRule) Check(p *Pass) {
+	severity := parse.SeverityWarning
+
+	if p.Strict {
+		severity = parse.SeverityError
+	}
+
+	for _, decl := range p.Declarations() {
+		enum, ok := decl.(*ast.EnumNode)
+
+		if !ok {
+			continue
+		}
+
+		first := make(map[uint64]*ast.EnumMemberNode)
+
+		for _, member := range enum.Members() {
+			v, ok := member.ResolvedValue()
+
+			if !ok {
+				continue
+			}
+
+			if prev, ok := first[v]; ok {
+				p.Report(member, severity, fmt.Sprintf("Enum member %s has the same value %d as %s", qualifiedName(member), v, prev.Name()))
+				continue
+			}
+
+			first[v] = member
+		}
+	}
+}
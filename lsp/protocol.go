@@ -0,0 +1,200 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidParams  = -32602
+	codeMethodNotFound = -32601
+)
+
+// Diagnostic severities.
+const (
+	SeverityError   = 1
+	SeverityWarning = 2
+)
+
+// Completion item kinds.
+const (
+	KindField      = 5
+	KindClass      = 7
+	KindInterface  = 8
+	KindEnum       = 13
+	KindKeyword    = 14
+	KindEnumMember = 20
+	KindConstant   = 21
+)
+
+// semanticTokenTypes and semanticTokenModifiers are the legend of the semantic
+// tokens, indexed by highlight class and modifier bit.
+var (
+	semanticTokenTypes     = []string{"keyword", "type", "enumMember", "property", "number", "string", "comment"}
+	semanticTokenModifiers = []string{"deprecated"}
+)
+
+// textDocumentSyncFull makes clients send the whole text on each change.
+const textDocumentSyncFull = 1
+
+// Position is a zero-based line and character offset in a document.
+// Characters are counted in runes, which only differ from the UTF-16 code
+// units of the protocol outside of the Basic Multilingual Plane.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Code     string `json:"code,omitempty"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+type CompletionItem struct {
+	Label  string `json:"label"`
+	Kind   int    `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// SemanticTokens holds the relative positions, lengths, types and modifiers
+// of tokens, five integers per token.
+type SemanticTokens struct {
+	Data []int `json:"data"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type textDocumentItem struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+type didOpenParams struct {
+	TextDocument textDocumentItem `json:"textDocument"`
+}
+
+type didChangeParams struct {
+	TextDocument   textDocumentIdentifier `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+type didCloseParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type semanticTokensParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// request is an incoming request, or a notification when ID is nil.
+type request struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result"`
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+// errorResponse leaves out the result, which must be absent on errors.
+type errorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   *responseError   `json:"error"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *responseError) Error() string {
+	return e.Message
+}
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// readMessage reads a message framed by a Content-Length header.
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+
+	if err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("Invalid message header: %v", err)
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("Invalid Content-Length %q", header.Get("Content-Length"))
+	}
+
+	body := make([]byte, length)
+
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+func writeMessage(w io.Writer, msg interface{}) error {
+	body, err := json.Marshal(msg)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+
+	_, err = w.Write(body)
+
+	return err
+}
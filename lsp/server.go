@@ -0,0 +1,655 @@
+// Package lsp implements a Language Server Protocol server for steamd files,
+// publishing diagnostics as documents are edited, resolving references to
+// enums, classes and their members, completing symbol names and classifying
+// tokens for highlighting.
+//
+// Documents are synchronized in full on each change, and imports are read
+// from the open documents before the filesystem, so diagnostics reflect
+// unsaved edits of imported files too.
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"path/filepath"
+	"unicode/utf8"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/highlight"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+// ErrNoShutdown is returned by Run when the client exits without requesting a
+// shutdown first.
+var ErrNoShutdown = errors.New("Exit without shutdown")
+
+// keywords are the identifiers with a meaning of their own, the keywords of
+// the grammar and the names of field annotations.
+var keywords = append(token.Keywords(), token.Annotations()...)
+
+// Server is a language server talking JSON-RPC over a reader and a writer,
+// usually stdin and stdout.
+type Server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	docs     map[string]*document
+	shutdown bool
+	// err is the first error writing a notification.
+	err error
+}
+
+// document is an open text document and its latest analysis.
+type document struct {
+	uri      string
+	filename string
+	text     []byte
+	root     *ast.DocumentNode
+}
+
+func NewServer(r io.Reader, w io.Writer) *Server {
+	return &Server{
+		in:   bufio.NewReader(r),
+		out:  w,
+		docs: make(map[string]*document),
+	}
+}
+
+// Run serves requests until the client exits or the input ends.
+func (s *Server) Run() error {
+	for {
+		body, err := readMessage(s.in)
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var req request
+
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := s.reply(nil, nil, &responseError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return ErrNoShutdown
+			}
+
+			return nil
+		}
+
+		result, rerr := s.handle(&req)
+
+		if s.err != nil {
+			return s.err
+		}
+
+		if req.ID == nil {
+			continue
+		}
+
+		if err := s.reply(req.ID, result, rerr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) reply(id *json.RawMessage, result interface{}, rerr *responseError) error {
+	if rerr != nil {
+		return writeMessage(s.out, &errorResponse{JSONRPC: "2.0", ID: id, Error: rerr})
+	}
+
+	return writeMessage(s.out, &response{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (s *Server) notify(method string, params interface{}) {
+	if s.err == nil {
+		s.err = writeMessage(s.out, &notification{JSONRPC: "2.0", Method: method, Params: params})
+	}
+}
+
+func (s *Server) handle(req *request) (interface{}, *responseError) {
+	switch req.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   textDocumentSyncFull,
+				"definitionProvider": true,
+				"completionProvider": map[string]interface{}{"triggerCharacters": []string{":"}},
+				"semanticTokensProvider": map[string]interface{}{
+					"legend": map[string]interface{}{
+						"tokenTypes":     semanticTokenTypes,
+						"tokenModifiers": semanticTokenModifiers,
+					},
+					"full": true,
+				},
+			},
+			"serverInfo": map[string]string{"name": "steamd"},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		s.update(params.TextDocument.URI, []byte(params.TextDocument.Text))
+	case "textDocument/didChange":
+		var params didChangeParams
+
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		if n := len(params.ContentChanges); n > 0 {
+			s.update(params.TextDocument.URI, []byte(params.ContentChanges[n-1].Text))
+		}
+	case "textDocument/didClose":
+		var params didCloseParams
+
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		delete(s.docs, params.TextDocument.URI)
+		s.publish(params.TextDocument.URI, nil)
+	case "textDocument/definition":
+		var params textDocumentPositionParams
+
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		if d := s.docs[params.TextDocument.URI]; d != nil {
+			if loc := s.definition(d, params.Position); loc != nil {
+				return loc, nil
+			}
+		}
+
+		return nil, nil
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		items := []CompletionItem{}
+
+		if d := s.docs[params.TextDocument.URI]; d != nil {
+			items = append(items, s.completion(d, params.Position)...)
+		}
+
+		return items, nil
+	case "textDocument/semanticTokens/full":
+		var params semanticTokensParams
+
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		tokens := &SemanticTokens{Data: []int{}}
+
+		if d := s.docs[params.TextDocument.URI]; d != nil {
+			tokens = semanticTokens(d)
+		}
+
+		return tokens, nil
+	}
+
+	if req.ID == nil {
+		return nil, nil
+	}
+
+	return nil, &responseError{Code: codeMethodNotFound, Message: "Unknown method " + req.Method}
+}
+
+// semanticTokens classifies the tokens of d, encoding each position relative
+// to the previous token.
+func semanticTokens(d *document) *SemanticTokens {
+	tokens := &SemanticTokens{Data: []int{}}
+	line, char := 0, 0
+
+	for _, span := range highlight.HighlightDocument(d.text, d.root) {
+		// Tokens spanning lines, like strings with newlines, are left out as
+		// not all clients support multiline tokens.
+		if bytes.ContainsRune(d.text[span.Offset:span.End], '\n') {
+			continue
+		}
+
+		spanLine, spanChar := span.Row-1, span.Col-1
+
+		if spanLine != line {
+			char = 0
+		}
+
+		modifiers := 0
+
+		if span.Obsolete {
+			modifiers = 1
+		}
+
+		tokens.Data = append(tokens.Data, spanLine-line, spanChar-char, span.Len, int(span.Class), modifiers)
+		line, char = spanLine, spanChar
+	}
+
+	return tokens
+}
+
+func invalidParams(err error) *responseError {
+	return &responseError{Code: codeInvalidParams, Message: err.Error()}
+}
+
+// update stores the text of a document, analyzes it and publishes its
+// diagnostics.
+func (s *Server) update(uri string, text []byte) {
+	d := &document{uri: uri, filename: uriFilename(uri), text: text}
+	s.docs[uri] = d
+	s.publish(uri, s.analyze(d))
+}
+
+func (s *Server) publish(uri string, diags []Diagnostic) {
+	if diags == nil {
+		diags = []Diagnostic{}
+	}
+
+	s.notify("textDocument/publishDiagnostics", &publishDiagnosticsParams{URI: uri, Diagnostics: diags})
+}
+
+// analyze analyzes d, recovering from errors, and returns the diagnostics of
+// its own errors and warnings. Errors in imported files are left to the
+// diagnostics of those files.
+func (s *Server) analyze(d *document) []Diagnostic {
+	a := parse.NewAnalyzer(token.NewTokenizer(d.text), d.filename)
+	a.SetRecovery(true)
+	a.SetResolver(parse.ImportResolverFunc(s.resolve))
+
+	d.root, _ = a.Analyze()
+
+	var diags []Diagnostic
+
+	for _, diag := range a.Diagnostics() {
+		if diag.Pos.Filename != "" && diag.Pos.Filename != d.filename {
+			continue
+		}
+
+		severity := SeverityError
+
+		if diag.Severity == parse.SeverityWarning {
+			severity = SeverityWarning
+		}
+
+		diags = append(diags, Diagnostic{
+			Range:    diagnosticRange(d.text, diag),
+			Severity: severity,
+			Code:     diag.Code,
+			Source:   "steamd",
+			Message:  diag.Message,
+		})
+	}
+
+	return diags
+}
+
+// resolve reads imports from the open documents, then from the filesystem.
+func (s *Server) resolve(fromFile, importPath string) ([]byte, string, error) {
+	name := filepath.Join(filepath.Dir(fromFile), importPath)
+
+	for _, d := range s.docs {
+		if d.filename == name {
+			return d.text, name, nil
+		}
+	}
+
+	return parse.OSResolver{}.Resolve(fromFile, importPath)
+}
+
+// diagnosticRange spans the offending token of diag, or the end of text for
+// diagnostics at the end of the input.
+func diagnosticRange(text []byte, diag parse.Diagnostic) Range {
+	if diag.Pos.Row == 0 {
+		end := endPosition(text)
+		return Range{Start: end, End: end}
+	}
+
+	return Range{
+		Start: Position{Line: diag.Pos.Row - 1, Character: diag.Pos.Col - 1},
+		End:   Position{Line: diag.End.Row - 1, Character: diag.End.Col - 1},
+	}
+}
+
+func endPosition(text []byte) Position {
+	var pos Position
+
+	for _, r := range string(text) {
+		if r == '\n' {
+			pos.Line++
+			pos.Character = 0
+		} else {
+			pos.Character++
+		}
+	}
+
+	return pos
+}
+
+// definition returns the declaration of the reference at pos, if any.
+func (s *Server) definition(d *document, pos Position) *Location {
+	if d.root == nil {
+		return nil
+	}
+
+	tokens := tokenize(d.text)
+	i := tokenAt(tokens, pos)
+
+	if i < 0 || tokens[i].Op != token.OpIdentifier {
+		return nil
+	}
+
+	// A reference is a path like EMsg::Multi, resolved up to the segment under
+	// the cursor.
+	start := i
+
+	for start >= 2 && tokens[start-1].Op == token.OpNamespace && tokens[start-2].Op == token.OpIdentifier {
+		start -= 2
+	}
+
+	var path []string
+
+	for j := start; j <= i; j += 2 {
+		path = append(path, tokens[j].ValueString())
+	}
+
+	node := s.lookup(d, scopeAt(d.root, pos), path)
+
+	if node == nil {
+		return nil
+	}
+
+	return s.location(d, node)
+}
+
+// lookup resolves path from scope, then from the document, to a declaration or
+// member. Placeholder symbols of undeclared names aren't declarations.
+func (s *Server) lookup(d *document, scope ast.Node, path []string) ast.Node {
+	for _, n := range []ast.Node{scope, d.root} {
+		sym := ast.LookupSymbol(n, path)
+
+		if sym == nil {
+			continue
+		}
+
+		switch sym.Node.(type) {
+		case *ast.NamespaceNode, *ast.TypedefNode, *ast.ClassNode, *ast.EnumNode, *ast.UnionNode, *ast.EnumMemberNode, *ast.PropertyNode:
+			return sym.Node
+		}
+	}
+
+	return nil
+}
+
+// location returns where n is declared, in d or in the file it imports n
+// from.
+func (s *Server) location(d *document, n ast.Node) *Location {
+	row, col := position(n)
+
+	if row == 0 {
+		return nil
+	}
+
+	decl := n
+
+	if _, ok := n.Parent().(*ast.DocumentNode); !ok {
+		decl = n.Parent()
+	}
+
+	filename := declaringFile(d.root, decl)
+
+	if filename == "" {
+		return nil
+	}
+
+	uri := filenameURI(filename)
+
+	for _, other := range s.docs {
+		if other.filename == filename {
+			uri = other.uri
+		}
+	}
+
+	start := Position{Line: row - 1, Character: col - 1}
+	end := Position{Line: row - 1, Character: col - 1 + utf8.RuneCountInString(n.Name())}
+
+	return &Location{URI: uri, Range: Range{Start: start, End: end}}
+}
+
+// completion returns the names that can be written at pos: the members of the
+// enum or class before a "::", and otherwise keywords, types, declarations and
+// the constants of the enclosing class or discriminator members in a union.
+func (s *Server) completion(d *document, pos Position) []CompletionItem {
+	if d.root == nil {
+		return nil
+	}
+
+	tokens := tokenize(d.text)
+	i := tokenBefore(tokens, pos)
+	scope := scopeAt(d.root, pos)
+
+	// Skip the partially written name under the cursor.
+	if i >= 0 && tokens[i].Op == token.OpIdentifier && touches(tokens[i], pos) {
+		i--
+	}
+
+	if i >= 1 && tokens[i].Op == token.OpNamespace && tokens[i-1].Op == token.OpIdentifier {
+		start := i - 1
+
+		for start >= 2 && tokens[start-1].Op == token.OpNamespace && tokens[start-2].Op == token.OpIdentifier {
+			start -= 2
+		}
+
+		var path []string
+
+		for j := start; j < i; j += 2 {
+			path = append(path, tokens[j].ValueString())
+		}
+
+		if n := s.lookup(d, scope, path); n != nil {
+			return memberItems(n)
+		}
+
+		return nil
+	}
+
+	var items []CompletionItem
+
+	for _, kw := range keywords {
+		items = append(items, CompletionItem{Label: kw, Kind: KindKeyword})
+	}
+
+	// const is one of the keywords
+	for f := ast.PropertyFlagConst + 1; f <= ast.PropertyFlagProto; f++ {
+		items = append(items, CompletionItem{Label: f.String(), Kind: KindKeyword})
+	}
+
+	for _, name := range ast.BuiltinTypes() {
+		items = append(items, CompletionItem{Label: name, Kind: KindKeyword, Detail: "builtin type"})
+	}
+
+	for _, child := range d.root.Types() {
+		switch n := child.(type) {
+		case *ast.ClassNode:
+			items = append(items, CompletionItem{Label: n.Name(), Kind: KindClass, Detail: "class"})
+		case *ast.EnumNode:
+			items = append(items, CompletionItem{Label: n.Name(), Kind: KindEnum, Detail: "enum"})
+		case *ast.UnionNode:
+			items = append(items, CompletionItem{Label: n.Name(), Kind: KindInterface, Detail: "union"})
+		}
+	}
+
+	switch n := scope.(type) {
+	case *ast.ClassNode:
+		items = append(items, memberItems(n)...)
+	case *ast.UnionNode:
+		// variants are named after the members of the discriminator
+		if enum := n.Discriminator(); enum != nil {
+			items = append(items, memberItems(enum)...)
+		}
+	}
+
+	return items
+}
+
+// memberItems lists the members of an enum or the constants of a class.
+func memberItems(n ast.Node) []CompletionItem {
+	var items []CompletionItem
+
+	switch n := n.(type) {
+	case *ast.EnumNode:
+		for _, m := range n.Members() {
+			items = append(items, CompletionItem{Label: m.Name(), Kind: KindEnumMember, Detail: n.Name()})
+		}
+	case *ast.ClassNode:
+		for _, c := range n.Consts() {
+			items = append(items, CompletionItem{Label: c.Name(), Kind: KindConstant, Detail: n.Name()})
+		}
+	}
+
+	return items
+}
+
+// tokenize returns the tokens of text, up to the first invalid one.
+func tokenize(text []byte) []*token.Token {
+	var tokens []*token.Token
+	t := token.NewTokenizer(text)
+
+	for {
+		tok, err := t.Next()
+
+		if err != nil {
+			return tokens
+		}
+
+		tokens = append(tokens, tok)
+	}
+}
+
+// tokenAt returns the index of the token covering pos, or -1.
+func tokenAt(tokens []*token.Token, pos Position) int {
+	for i, t := range tokens {
+		if t.Row-1 == pos.Line && t.Col-1 <= pos.Character && pos.Character < t.Col-1+utf8.RuneCount(t.Raw) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// tokenBefore returns the index of the last token starting before pos, or -1.
+func tokenBefore(tokens []*token.Token, pos Position) int {
+	last := -1
+
+	for i, t := range tokens {
+		if t.Row-1 > pos.Line || (t.Row-1 == pos.Line && t.Col-1 >= pos.Character) {
+			break
+		}
+
+		last = i
+	}
+
+	return last
+}
+
+// touches reports whether t ends at pos.
+func touches(t *token.Token, pos Position) bool {
+	return t.Row-1 == pos.Line && t.Col-1+utf8.RuneCount(t.Raw) == pos.Character
+}
+
+// scopeAt returns the declaration of root enclosing pos, or root. Declarations
+// extend up to the next one.
+func scopeAt(root *ast.DocumentNode, pos Position) ast.Node {
+	var scope ast.Node = root
+
+	for _, decl := range root.Declarations {
+		row, _ := position(decl)
+
+		if row == 0 || row-1 > pos.Line {
+			break
+		}
+
+		scope = decl
+	}
+
+	return scope
+}
+
+func position(n ast.Node) (int, int) {
+	switch n := n.(type) {
+	case *ast.NamespaceNode:
+		return n.Row, n.Col
+	case *ast.TypedefNode:
+		return n.Row, n.Col
+	case *ast.ClassNode:
+		return n.Row, n.Col
+	case *ast.EnumNode:
+		return n.Row, n.Col
+	case *ast.UnionNode:
+		return n.Row, n.Col
+	case *ast.UnionVariantNode:
+		return n.Row, n.Col
+	case *ast.EnumMemberNode:
+		return n.Row, n.Col
+	case *ast.PropertyNode:
+		return n.Row, n.Col
+	}
+
+	return 0, 0
+}
+
+// declaringFile returns the name of the file among root and its imports
+// declaring decl.
+func declaringFile(root *ast.DocumentNode, decl ast.Node) string {
+	for _, doc := range append([]*ast.DocumentNode{root}, root.Dependencies()...) {
+		for _, n := range doc.Declarations {
+			if n == decl {
+				return doc.Filename
+			}
+		}
+	}
+
+	return ""
+}
+
+func uriFilename(uri string) string {
+	u, err := url.Parse(uri)
+
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+
+	return filepath.FromSlash(u.Path)
+}
+
+func filenameURI(filename string) string {
+	if abs, err := filepath.Abs(filename); err == nil {
+		filename = abs
+	}
+
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(filename)}).String()
+}
@@ -0,0 +1,309 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	emsgURI = "file:///schemas/emsg.steamd"
+	msgURI  = "file:///schemas/msg.steamd"
+
+	emsgText = "enum EMsg {\n\tInvalid = 0;\n\tMulti = 1;\n};\n"
+	msgText  = "#import \"emsg.steamd\"\n\nclass MsgHdr<EMsg::Multi> {\n\tconst uint SIZE = 4;\n\tEMsg msg = EMsg::Multi;\n\tbyte<SIZE> key;\n};\n"
+)
+
+type received struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+// session runs a server over requests and returns the messages it sent.
+func session(t *testing.T, requests ...interface{}) []*received {
+	var in, out bytes.Buffer
+
+	for _, req := range requests {
+		if err := writeMessage(&in, req); err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+	}
+
+	if err := NewServer(&in, &out).Run(); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	var messages []*received
+	r := bufio.NewReader(&out)
+
+	for {
+		body, err := readMessage(r)
+
+		if err == io.EOF {
+			return messages
+		}
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		var msg received
+
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		messages = append(messages, &msg)
+	}
+}
+
+func call(id int, method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+}
+
+func notice(method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+}
+
+func open(uri, text string) map[string]interface{} {
+	return notice("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "steamd", "version": 1, "text": text},
+	})
+}
+
+func at(uri string, line, char int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"position":     Position{Line: line, Character: char},
+	}
+}
+
+func result(t *testing.T, messages []*received, id int, v interface{}) {
+	for _, msg := range messages {
+		if msg.ID != nil && *msg.ID == id {
+			if msg.Error != nil {
+				t.Fatalf("not expected error %v", msg.Error)
+			}
+
+			if err := json.Unmarshal(msg.Result, v); err != nil {
+				t.Fatalf("not expected error %v", err)
+			}
+
+			return
+		}
+	}
+
+	t.Fatalf("expected a response to request %d", id)
+}
+
+func diagnostics(t *testing.T, messages []*received, uri string) []Diagnostic {
+	var diags []Diagnostic
+
+	for _, msg := range messages {
+		if msg.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+
+		var params publishDiagnosticsParams
+
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		if params.URI == uri {
+			diags = params.Diagnostics
+		}
+	}
+
+	return diags
+}
+
+func TestServerInitialize(t *testing.T) {
+	messages := session(t,
+		call(1, "initialize", map[string]interface{}{}),
+		notice("initialized", map[string]interface{}{}),
+		call(2, "shutdown", nil),
+		notice("exit", nil),
+	)
+
+	var init struct {
+		Capabilities struct {
+			TextDocumentSync   int  `json:"textDocumentSync"`
+			DefinitionProvider bool `json:"definitionProvider"`
+		} `json:"capabilities"`
+	}
+
+	result(t, messages, 1, &init)
+
+	if init.Capabilities.TextDocumentSync != textDocumentSyncFull || !init.Capabilities.DefinitionProvider {
+		t.Fatalf("mismatch: got %+v", init)
+	}
+}
+
+func TestServerExitWithoutShutdown(t *testing.T) {
+	var in, out bytes.Buffer
+
+	writeMessage(&in, notice("exit", nil))
+
+	if err := NewServer(&in, &out).Run(); err != ErrNoShutdown {
+		t.Fatalf("mismatch: got %v, but expected %v", err, ErrNoShutdown)
+	}
+}
+
+func TestServerUnknownMethod(t *testing.T) {
+	messages := session(t, call(1, "textDocument/hover", at(msgURI, 0, 0)))
+
+	if len(messages) != 1 || messages[0].Error == nil || messages[0].Error.Code != codeMethodNotFound {
+		t.Fatalf("mismatch: got %+v, but expected a method not found error", messages)
+	}
+}
+
+func TestServerDiagnostics(t *testing.T) {
+	messages := session(t,
+		open(emsgURI, emsgText),
+		open(msgURI, "class C {\n\tuint x = ;\n};\n"),
+		notice("textDocument/didChange", map[string]interface{}{
+			"textDocument":   map[string]interface{}{"uri": emsgURI, "version": 2},
+			"contentChanges": []map[string]string{{"text": "enum EMsg {\n\tInvalid = 0;\n\tInvalid = 1;\n};\n"}},
+		}),
+	)
+
+	diags := diagnostics(t, messages, msgURI)
+
+	if len(diags) != 1 {
+		t.Fatalf("mismatch: got %+v, but expected 1 diagnostic", diags)
+	}
+
+	expected := Diagnostic{
+		Range:    Range{Start: Position{Line: 1, Character: 10}, End: Position{Line: 1, Character: 11}},
+		Severity: SeverityError,
+		Code:     "unexpected-token",
+		Source:   "steamd",
+		Message:  `Unexpected token ";"`,
+	}
+
+	if diags[0] != expected {
+		t.Fatalf("mismatch: got %+v, but expected %+v", diags[0], expected)
+	}
+
+	diags = diagnostics(t, messages, emsgURI)
+
+	if len(diags) != 1 || diags[0].Code != "duplicate-symbol" || diags[0].Range.Start != (Position{Line: 2, Character: 1}) {
+		t.Fatalf("mismatch: got %+v, but expected a duplicate symbol at 2:1", diags)
+	}
+}
+
+func TestServerDefinition(t *testing.T) {
+	messages := session(t,
+		open(emsgURI, emsgText),
+		open(msgURI, msgText),
+		// EMsg in "EMsg::Multi" of the class qualifier
+		call(1, "textDocument/definition", at(msgURI, 2, 14)),
+		// Multi in "EMsg::Multi" of the default value
+		call(2, "textDocument/definition", at(msgURI, 4, 19)),
+		// SIZE in "byte<SIZE>"
+		call(3, "textDocument/definition", at(msgURI, 5, 7)),
+		// uint, a builtin type
+		call(4, "textDocument/definition", at(msgURI, 3, 8)),
+	)
+
+	var loc Location
+
+	result(t, messages, 1, &loc)
+
+	if expected := (Location{URI: emsgURI, Range: Range{Start: Position{0, 5}, End: Position{0, 9}}}); loc != expected {
+		t.Fatalf("mismatch: got %+v, but expected %+v", loc, expected)
+	}
+
+	result(t, messages, 2, &loc)
+
+	if expected := (Location{URI: emsgURI, Range: Range{Start: Position{2, 1}, End: Position{2, 6}}}); loc != expected {
+		t.Fatalf("mismatch: got %+v, but expected %+v", loc, expected)
+	}
+
+	result(t, messages, 3, &loc)
+
+	if expected := (Location{URI: msgURI, Range: Range{Start: Position{3, 12}, End: Position{3, 16}}}); loc != expected {
+		t.Fatalf("mismatch: got %+v, but expected %+v", loc, expected)
+	}
+
+	var none *Location
+
+	result(t, messages, 4, &none)
+
+	if none != nil {
+		t.Fatalf("mismatch: got %+v, but expected no definition", none)
+	}
+}
+
+func TestServerCompletion(t *testing.T) {
+	text := strings.Replace(msgText, "byte<SIZE> key;", "EMsg other = EMsg::Mu", 1)
+
+	messages := session(t,
+		open(emsgURI, emsgText),
+		open(msgURI, text),
+		call(1, "textDocument/completion", at(msgURI, 5, 22)),
+		call(2, "textDocument/completion", at(msgURI, 5, 1)),
+	)
+
+	var items []CompletionItem
+
+	result(t, messages, 1, &items)
+
+	if len(items) != 2 || items[0].Label != "Invalid" || items[1].Label != "Multi" || items[1].Kind != KindEnumMember {
+		t.Fatalf("mismatch: got %+v, but expected the members of EMsg", items)
+	}
+
+	result(t, messages, 2, &items)
+
+	labels := make(map[string]int)
+
+	for _, item := range items {
+		if _, ok := labels[item.Label]; ok {
+			t.Fatalf("mismatch: got %s twice in %+v", item.Label, items)
+		}
+
+		labels[item.Label] = item.Kind
+	}
+
+	for label, kind := range map[string]int{"class": KindKeyword, "namespace": KindKeyword, "typedef": KindKeyword, "const": KindKeyword, "endian": KindKeyword, "uint": KindKeyword, "steamidmarshal": KindKeyword, "EMsg": KindEnum, "MsgHdr": KindClass, "SIZE": KindConstant} {
+		if labels[label] != kind {
+			t.Fatalf("mismatch: got kind %d for %s, but expected %d in %+v", labels[label], label, kind, items)
+		}
+	}
+}
+
+func TestServerSemanticTokens(t *testing.T) {
+	messages := session(t,
+		open(emsgURI, emsgText),
+		open(msgURI, "class C<EMsg::Multi> {\n\tuint x; // x\n};\n"),
+		call(1, "textDocument/semanticTokens/full", map[string]interface{}{
+			"textDocument": map[string]string{"uri": msgURI},
+		}),
+	)
+
+	var tokens SemanticTokens
+
+	result(t, messages, 1, &tokens)
+
+	expected := []int{
+		0, 0, 5, 0, 0, // class
+		0, 6, 1, 1, 0, // C
+		0, 2, 4, 1, 0, // EMsg
+		0, 6, 5, 2, 0, // Multi
+		1, 1, 4, 1, 0, // uint
+		0, 5, 1, 3, 0, // x
+		0, 3, 4, 6, 0, // comment
+	}
+
+	if !reflect.DeepEqual(tokens.Data, expected) {
+		t.Fatalf("mismatch: got %v, but expected %v", tokens.Data, expected)
+	}
+}
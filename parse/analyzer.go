@@ -0,0 +1,2177 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/token"
+)
+
+var (
+	openQualifierToken  = &token.Token{Op: token.OpOperator, Value: []byte("<")}
+	closeQualifierToken = &token.Token{Op: token.OpOperator, Value: []byte(">")}
+	openScopeToken      = &token.Token{Op: token.OpOperator, Value: []byte("{")}
+	closeScopeToken     = &token.Token{Op: token.OpOperator, Value: []byte("}")}
+	assignmentToken     = &token.Token{Op: token.OpOperator, Value: []byte("=")}
+	binaryOrToken       = &token.Token{Op: token.OpOperator, Value: []byte("|")}
+	baseToken           = &token.Token{Op: token.OpOperator, Value: []byte(":")}
+	conditionToken      = &token.Token{Op: token.OpIdentifier, Value: []byte("if")}
+	endianToken         = &token.Token{Op: token.OpIdentifier, Value: []byte("endian")}
+	encodingToken       = &token.Token{Op: token.OpIdentifier, Value: []byte("encoding")}
+	termToken           = &token.Token{Op: token.OpIdentifier, Value: []byte("term")}
+	annotationTokens    = []*token.Token{conditionToken, endianToken, encodingToken, termToken}
+)
+
+type Analyzer struct {
+	t        *token.Tokenizer
+	tokens   *token.TokenQueue
+	filename string
+	fset     *token.FileSet
+	doc      []string
+	manifest *Manifest
+	warnings []error
+	// diagnostics are the errors and warnings of the last analysis
+	diagnostics []Diagnostic
+	refs        []reference
+	// pending are the resolutions deferred until all declarations are known,
+	// and checks the validations deferred until they're all resolved
+	pending  []func() error
+	checks   []func() error
+	last     *token.Token
+	stats    *Stats
+	recovery bool
+	strict   bool
+	errors   ErrorList
+	// imports caches the documents imported during an analysis, shared with
+	// the analyzers of imported files. A nil entry is a document being
+	// analyzed.
+	imports  map[string]*ast.DocumentNode
+	resolver ImportResolver
+	include  []string
+	ctx      context.Context
+	workers  int
+	loader   *loader
+	cache    *Cache
+	// importDecls holds the number of declarations preceding each import
+	importDecls []int
+}
+
+func NewAnalyzer(t *token.Tokenizer, f string) *Analyzer {
+	return &Analyzer{
+		t:        t,
+		filename: f,
+		resolver: OSResolver{},
+	}
+}
+
+func (a *Analyzer) Errorf(row, col int, format string, v ...interface{}) error {
+	return &ParseError{
+		Filename: a.filename,
+		Row:      row,
+		Col:      col,
+		Pos:      a.pos(row, col),
+		Code:     ErrorGeneric,
+		Message:  fmt.Sprintf(format, v...),
+	}
+}
+
+// tokenError returns an error positioned at t, or at the end of the input if t
+// is nil. Errors about malformed tokens, like unterminated strings, report the
+// error of the token instead, whatever was expected in its place.
+func (a *Analyzer) tokenError(t *token.Token, code ErrorCode, format string, v ...interface{}) error {
+	var strErr *token.StringError
+
+	if t != nil && errors.As(t.Error, &strErr) {
+		code, format, v = ErrorInvalidToken, "Unterminated string %s", []interface{}{t.Raw}
+	}
+
+	err := &ParseError{
+		Filename: a.filename,
+		Token:    t,
+		Code:     code,
+		Message:  fmt.Sprintf(format, v...),
+	}
+
+	if t != nil {
+		err.Row = t.Row
+		err.Col = t.Col
+		err.Pos = t.Pos
+		err.Err = t.Error
+	}
+
+	return err
+}
+
+// pos returns the Pos of a row and column of the analyzed file, NoPos if its
+// tokenizer has no File.
+func (a *Analyzer) pos(row, col int) token.Pos {
+	if f := a.t.File(); f != nil {
+		return f.LinePos(row, col)
+	}
+
+	return token.NoPos
+}
+
+func (a *Analyzer) SetManifest(m *Manifest) {
+	a.manifest = m
+}
+
+func (a *Analyzer) SetStats(s *Stats) {
+	a.stats = s
+}
+
+// SetRecovery enables error recovery: instead of stopping at the first syntax
+// error, the Analyzer skips to the end of the offending statement and keeps
+// going. Analyze then returns all errors as an ErrorList.
+func (a *Analyzer) SetRecovery(enabled bool) {
+	a.recovery = enabled
+}
+
+// SetStrict enables strict symbol resolution: references to undeclared names
+// are reported as errors instead of resolving to placeholder symbols. Numeric
+// literals and builtin types are always accepted.
+func (a *Analyzer) SetStrict(enabled bool) {
+	a.strict = enabled
+}
+
+// SetFS makes the Analyzer read imported files from fsys instead of the OS
+// filesystem. Filenames are then slash-separated paths within fsys, so the
+// analyzed file should be named relative to its root for imports to resolve
+// next to it.
+func (a *Analyzer) SetFS(fsys fs.FS) {
+	a.SetResolver(&FSResolver{FS: fsys})
+}
+
+// SetImportRoot confines imports to the directory root of the OS filesystem.
+// Absolute import paths and paths resolving outside of root are rejected with
+// ErrImportEscape. It replaces the resolver set by SetFS or SetResolver.
+func (a *Analyzer) SetImportRoot(root string) {
+	a.SetResolver(OSResolver{Root: root})
+}
+
+// SetResolver sets how `#import` directives are loaded. The default, also
+// restored by a nil r, resolves them from the OS filesystem.
+func (a *Analyzer) SetResolver(r ImportResolver) {
+	if r == nil {
+		r = OSResolver{}
+	}
+
+	a.resolver = r
+}
+
+// SetIncludePath sets the directories searched, in order, for imports not
+// found next to the importing file. Each one is tried as if the importing file
+// were in that directory, and resolvers must report missing files with an
+// error matching fs.ErrNotExist for the search to go on.
+func (a *Analyzer) SetIncludePath(dirs []string) {
+	a.include = dirs
+}
+
+// SetWorkers makes the Analyzer resolve, tokenize and analyze imported files
+// on up to n goroutines before analyzing the file itself. The result is the
+// same as the sequential analysis, which n < 2 keeps. The resolver must be
+// safe for concurrent use.
+func (a *Analyzer) SetWorkers(n int) {
+	a.workers = n
+}
+
+// SetCache makes the Analyzer restore unchanged files, including imported
+// ones, from c instead of analyzing them, and store the ones it analyzes. The
+// cache is bypassed while collecting Stats, for tokenizers with a tab width,
+// since cached columns count tabs as one, and for tokenizers reading a stream,
+// whose source isn't known upfront to key the cache with. Failing to store an
+// analysis is reported as a warning.
+func (a *Analyzer) SetCache(c *Cache) {
+	a.cache = c
+}
+
+// useCache reports whether the analysis goes through the cache.
+func (a *Analyzer) useCache() bool {
+	return a.cache != nil && a.stats == nil && a.t.TabWidth() == 1 && a.t.Bytes() != nil
+}
+
+// SetCaseSensitive sets whether keywords, like class, annotation names, like
+// endian, and directives, like #import, must be lowercase, the default, or
+// match in any case. It's set on the Analyzer's Tokenizer, and on the ones of
+// imported files. Names of declarations and members are always case-sensitive.
+func (a *Analyzer) SetCaseSensitive(enabled bool) {
+	a.t.SetCaseSensitive(enabled)
+}
+
+// match reports whether t is the token expected, comparing their values
+// case-insensitively if the Tokenizer isn't case-sensitive.
+func (a *Analyzer) match(expected, t *token.Token) bool {
+	if a.t.CaseSensitive() {
+		return expected.Equal(t)
+	}
+
+	return expected.EqualFold(t)
+}
+
+// SetFileSet sets the FileSet the analyzed file and its imports are
+// registered in, so that the positions of their tokens, nodes and errors
+// resolve to the file they're in. By default each analysis has its own.
+func (a *Analyzer) SetFileSet(fset *token.FileSet) {
+	a.fset = fset
+}
+
+// FileSet returns the FileSet of the last analysis.
+func (a *Analyzer) FileSet() *token.FileSet {
+	return a.fset
+}
+
+func (a *Analyzer) Warnings() []error {
+	return a.warnings
+}
+
+// Diagnostics returns the errors of the last analysis, including the ones of
+// imported files, followed by its warnings.
+func (a *Analyzer) Diagnostics() []Diagnostic {
+	return a.diagnostics
+}
+
+func (a *Analyzer) Analyze() (*ast.DocumentNode, error) {
+	return a.AnalyzeContext(context.Background())
+}
+
+// AnalyzeContext is like Analyze, but gives up with ctx's error once ctx is
+// done. Cancellation is checked between declarations and before each import.
+func (a *Analyzer) AnalyzeContext(ctx context.Context) (*ast.DocumentNode, error) {
+	root, err := a.analyze(ctx)
+
+	if root != nil {
+		a.checkReasons(root)
+	}
+
+	a.diagnostics = Diagnose(err, SeverityError)
+
+	for _, warning := range a.warnings {
+		a.diagnostics = append(a.diagnostics, Diagnose(warning, SeverityWarning)...)
+	}
+
+	return root, err
+}
+
+func (a *Analyzer) analyze(ctx context.Context) (*ast.DocumentNode, error) {
+	if a.t == nil {
+		return nil, fmt.Errorf("Uninitialized Analyzer")
+	}
+
+	a.ctx = ctx
+
+	if a.fset == nil {
+		a.fset = token.NewFileSet()
+	}
+
+	if a.t.File() == nil && a.t.Bytes() != nil {
+		a.t.SetFile(a.fset.AddFile(a.filename, len(a.t.Bytes())))
+	}
+
+	if a.workers > 1 && a.loader == nil {
+		if err := a.preload(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.manifest != nil && a.filename != "" {
+		if err := a.manifest.Verify(a.filename, a.t.Bytes()); err != nil {
+			a.warnings = append(a.warnings, err)
+		}
+	}
+
+	a.stats.file()
+	root := ast.NewDocumentNode(a.filename)
+	root.Size = len(a.t.Bytes())
+
+	if a.t.Bytes() != nil {
+		root.Hash = hashData(a.t.Bytes())
+	}
+
+	if a.useCache() {
+		if entry := a.cached(); entry != nil {
+			return root, a.restore(root, entry)
+		}
+	}
+
+	// trivia is only inspected for trailing comments, tokens are otherwise
+	// handled the same
+	a.t.SetTrivia(true)
+	a.tokens = a.t.Stream()
+	t := a.next()
+
+	for t != nil {
+		if err := ctx.Err(); err != nil {
+			return root, err
+		}
+
+		if err := a.handleToken(t, root); err != nil {
+			// a tokenizing error cuts the stream short and surfaces as an
+			// unexpected EOF, so report the underlying cause instead
+			if tokErr := a.tokens.Err(); tokErr != nil {
+				return root, a.fail(tokErr)
+			}
+
+			if !a.recovery {
+				return root, err
+			}
+
+			a.errors.add(err)
+			a.skipDeclaration(false)
+		}
+
+		t = a.next()
+	}
+
+	if err := a.tokens.Err(); err != nil {
+		return root, a.fail(err)
+	}
+
+	if err := a.resolvePending(); err != nil {
+		return root, err
+	}
+
+	if err := a.errors.Err(); err != nil {
+		return root, err
+	}
+
+	if a.useCache() {
+		if err := a.storeCache(root); err != nil {
+			a.warnings = append(a.warnings, fmt.Errorf("Cannot cache %s: %w", a.filename, err))
+		}
+	}
+
+	return root, nil
+}
+
+// preload analyzes the imports ahead of time. Import cycles are left to the
+// sequential analysis to report.
+func (a *Analyzer) preload(ctx context.Context) error {
+	l := newLoader(a, a.workers)
+	ok, err := l.load(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		a.loader = l
+	}
+
+	return nil
+}
+
+func (a *Analyzer) fail(err error) error {
+	var (
+		numErr     *token.NumberError
+		escapeErr  *token.EscapeError
+		commentErr *token.CommentError
+		encErr     *token.EncodingError
+	)
+
+	switch {
+	case errors.As(err, &numErr):
+		parseErr := a.tokenError(numErr.Token, ErrorInvalidToken, "Number %q out of range", numErr.Token.Raw).(*ParseError)
+		parseErr.Err = numErr
+		err = parseErr
+	case errors.As(err, &escapeErr):
+		parseErr := a.tokenError(escapeErr.Token, ErrorInvalidToken, "Invalid escape sequence %q in string %s", escapeErr.Seq, escapeErr.Token.Raw).(*ParseError)
+		parseErr.Err = escapeErr
+		err = parseErr
+	case errors.As(err, &commentErr):
+		parseErr := a.tokenError(commentErr.Token, ErrorUnexpectedEOF, "Unterminated block comment").(*ParseError)
+		parseErr.Err = commentErr
+		err = parseErr
+	case errors.As(err, &encErr):
+		parseErr := a.tokenError(encErr.Token, ErrorInvalidToken, "Invalid UTF-8 byte %#02x", encErr.Token.Raw[0]).(*ParseError)
+		parseErr.Err = encErr
+		err = parseErr
+	}
+
+	if !a.recovery {
+		return err
+	}
+
+	a.errors.add(err)
+
+	return a.errors
+}
+
+// skipDeclaration discards tokens up to and including the next top-level
+// terminator, or up to the next top-level declaration. In a namespace, it also
+// stops at the brace closing the namespace.
+func (a *Analyzer) skipDeclaration(namespace bool) {
+	depth := 0
+
+	for t := a.tokens.Peek(); t != nil; t = a.tokens.Peek() {
+		switch {
+		case depth == 0 && (t.Op == token.OpPreprocess || t.Keyword.IsDeclaration()):
+			return
+		case a.match(openScopeToken, t):
+			depth++
+		case a.match(closeScopeToken, t) && depth > 0:
+			depth--
+		case a.match(closeScopeToken, t) && namespace:
+			return
+		case t.Op == token.OpTerminator && depth == 0:
+			a.next()
+			return
+		}
+
+		a.next()
+	}
+}
+
+// skipStatement discards tokens up to and including the next terminator in
+// the current scope, or up to the scope's closing brace.
+func (a *Analyzer) skipStatement() {
+	depth := 0
+
+	for t := a.tokens.Peek(); t != nil; t = a.tokens.Peek() {
+		switch {
+		case a.match(openScopeToken, t):
+			depth++
+		case a.match(closeScopeToken, t):
+			if depth == 0 {
+				return
+			}
+
+			depth--
+		case t.Op == token.OpTerminator && depth == 0:
+			a.next()
+			return
+		}
+
+		a.next()
+	}
+}
+
+// isNestedKeyword reports whether t starts a declaration allowed in a class.
+func isNestedKeyword(t *token.Token) bool {
+	return t != nil && (t.Keyword == token.KeywordClass || t.Keyword == token.KeywordEnum)
+}
+
+func (a *Analyzer) handleToken(t *token.Token, root *ast.DocumentNode) error {
+	switch t.Op {
+	case token.OpDoc:
+		a.addDoc(t)
+		return nil
+	case token.OpPreprocess:
+		return a.handlePreprocessToken(t, root)
+	case token.OpIdentifier:
+		return a.handleIdentifierToken(t, root)
+	default:
+		return a.tokenError(t, ErrorInvalidToken, "Invalid token %q", t.Raw)
+	}
+}
+
+func (a *Analyzer) handlePreprocessToken(t *token.Token, root *ast.DocumentNode) error {
+	a.takeDoc()
+	nextToken, err := a.expectOp(token.OpString)
+
+	if err != nil {
+		return err
+	}
+
+	if isImport(t, a.t.CaseSensitive()) {
+		a.stats.production(ProductionImport)
+		return a.importFile(nextToken, root)
+	}
+
+	return nil
+}
+
+// isImport reports whether t, a preprocess token, is an #import directive,
+// spelled in any case unless caseSensitive.
+func isImport(t *token.Token, caseSensitive bool) bool {
+	if caseSensitive {
+		return t.ValueEqualString("import")
+	}
+
+	return t.ValueEqualFold([]byte("import"))
+}
+
+func (a *Analyzer) handleIdentifierToken(t *token.Token, root *ast.DocumentNode) error {
+	return a.analyzeDeclaration(t, root, root)
+}
+
+// analyzeDeclaration analyzes the declaration starting with t in scope, root or
+// one of its namespaces.
+func (a *Analyzer) analyzeDeclaration(t *token.Token, root *ast.DocumentNode, scope ast.Node) error {
+	switch t.Keyword {
+	case token.KeywordClass:
+		return a.analyzeClass(root, scope)
+	case token.KeywordEnum:
+		return a.analyzeEnum(root, scope)
+	case token.KeywordNamespace:
+		return a.analyzeNamespace(root, scope)
+	case token.KeywordTypedef:
+		return a.analyzeTypedef(root, scope)
+	case token.KeywordUnion:
+		return a.analyzeUnion(root, scope)
+	default:
+		return a.tokenError(t, ErrorInvalidToken, "Invalid token %q", t.Raw)
+	}
+}
+
+func (a *Analyzer) analyzeNamespace(root *ast.DocumentNode, scope ast.Node) error {
+	a.stats.production(ProductionNamespaceBlock)
+	node := ast.NewNamespaceNode(scope)
+	root.Declarations = append(root.Declarations, node)
+	node.Doc = a.takeDoc()
+	name, err := a.expectOp(token.OpIdentifier)
+
+	if err != nil {
+		return err
+	}
+
+	node.Value = name.Value
+	node.Row, node.Col = name.Row, name.Col
+	node.Pos = name.Pos
+
+	if err := scope.AddSymbol(node.Symbol()); err != nil {
+		return a.symbolError(name, err)
+	}
+
+	if _, err := a.expectToken(openScopeToken); err != nil {
+		return err
+	}
+
+	for {
+		t := a.next()
+
+		if t == nil {
+			return a.tokenError(nil, ErrorUnexpectedEOF, "EOF")
+		}
+
+		if a.match(closeScopeToken, t) {
+			// terminated like the other declarations, or not
+			a.optionalOp(token.OpTerminator)
+			a.takeDoc()
+			return nil
+		}
+
+		switch {
+		case t.Op == token.OpDoc:
+			a.addDoc(t)
+			continue
+		case t.Op == token.OpIdentifier:
+			err = a.analyzeDeclaration(t, root, node)
+		default:
+			err = a.tokenError(t, ErrorInvalidToken, "Invalid token %q", t.Raw)
+		}
+
+		if err != nil {
+			if !a.recovery || a.tokens.Peek() == nil {
+				return err
+			}
+
+			a.errors.add(err)
+			a.skipDeclaration(true)
+		}
+	}
+}
+
+func (a *Analyzer) analyzeTypedef(root *ast.DocumentNode, scope ast.Node) error {
+	a.stats.production(ProductionTypedef)
+	node := ast.NewTypedefNode(scope)
+	root.Declarations = append(root.Declarations, node)
+	node.Doc = a.takeDoc()
+	name, err := a.expectOp(token.OpIdentifier)
+
+	if err != nil {
+		return err
+	}
+
+	node.Value = name.Value
+	node.Row, node.Col = name.Row, name.Col
+	node.Pos = name.Pos
+
+	if err := scope.AddSymbol(node.Symbol()); err != nil {
+		return a.symbolError(name, err)
+	}
+
+	if _, err := a.expectToken(assignmentToken); err != nil {
+		return err
+	}
+
+	target, err := a.expectOp(token.OpIdentifier)
+
+	if err != nil {
+		return err
+	}
+
+	if err := a.expectTerminator(); err != nil {
+		return err
+	}
+
+	node.Comment = trailingComment(a.last)
+
+	a.later(func() error {
+		var err error
+		node.Type, err = a.lookupType(scope, target)
+		return err
+	})
+
+	// typedefs may alias typedefs declared afterwards
+	a.checkLater(func() error {
+		if node.Type != nil && ast.Underlying(&ast.Symbol{Node: node}) == nil {
+			return a.tokenError(name, ErrorInvalidValue, "Recursive typedef %q", name.ValueString())
+		}
+
+		return nil
+	})
+
+	return nil
+}
+
+func (a *Analyzer) analyzeClass(root *ast.DocumentNode, scope ast.Node) error {
+	a.stats.production(ProductionClass)
+	node := ast.NewClassNode(scope)
+	root.Declarations = append(root.Declarations, node)
+	node.Doc = a.takeDoc()
+	name, err := a.expectOp(token.OpIdentifier)
+
+	if err != nil {
+		return err
+	}
+
+	node.Value = name.Value
+	node.Row, node.Col = name.Row, name.Col
+	node.Pos = name.Pos
+
+	if err := scope.AddSymbol(node.Symbol()); err != nil {
+		return a.symbolError(name, err)
+	}
+
+	qualifier, err := a.analyzeQualifier(scope, ast.QualifierEMsg)
+
+	if err != nil {
+		return err
+	}
+
+	node.Qualifier = qualifier
+
+	if a.optionalToken(baseToken) != nil {
+		if err := a.analyzeBase(node, scope); err != nil {
+			return err
+		}
+	}
+
+	if err := a.analyzeScope(root, node); err != nil {
+		return err
+	}
+
+	if err := a.expectTerminator(); err != nil {
+		return err
+	}
+
+	// inherited fields are known once bases are resolved
+	a.checkLater(func() error {
+		return a.checkOptional(node)
+	})
+
+	return nil
+}
+
+// checkOptional reports required fields of node following optional ones,
+// which can only be left out at the end of the payload.
+func (a *Analyzer) checkOptional(node *ast.ClassNode) error {
+	var optional *ast.PropertyNode
+
+	for _, f := range node.Fields() {
+		switch {
+		case f.Optional:
+			optional = f
+		case optional != nil && f.Parent() == node:
+			err := a.Errorf(f.Row, f.Col, "Required field %q follows optional field %q", f.Name(), optional.Name()).(*ParseError)
+			err.Code = ErrorInvalidValue
+			return err
+		}
+	}
+
+	return nil
+}
+
+// analyzeBase parses the base class of node, resolved from scope once all
+// declarations are known.
+func (a *Analyzer) analyzeBase(node *ast.ClassNode, scope ast.Node) error {
+	a.stats.production(ProductionBase)
+	tokens, err := a.getNamespacedIdentifier()
+
+	if err != nil {
+		return err
+	}
+
+	node.BasePath = token.StringValues(tokens)
+
+	a.later(func() error {
+		var err error
+		node.Base, err = a.resolve(scope, tokens)
+		return err
+	})
+
+	// the base may be named by a typedef resolved afterwards
+	a.checkLater(func() error {
+		return a.checkBase(node, tokens)
+	})
+
+	return nil
+}
+
+// checkBase reports bases that aren't classes, inheritance cycles and fields
+// declared again by a derived class.
+func (a *Analyzer) checkBase(node *ast.ClassNode, tokens []*token.Token) error {
+	name := strings.Join(node.BasePath, "::")
+	base := ast.Underlying(node.Base)
+
+	if base == nil || base.Node == nil {
+		return nil
+	}
+
+	if _, ok := base.Node.(*ast.ClassNode); !ok {
+		return a.tokenError(tokens[0], ErrorInvalidValue, "Base %q is not a class", name)
+	}
+
+	inherited := make(map[*ast.ClassNode]bool)
+
+	for c := node.BaseClass(); c != nil; c = c.BaseClass() {
+		if c == node {
+			return a.tokenError(tokens[0], ErrorInvalidValue, "Recursive base class %q", name)
+		}
+
+		if inherited[c] {
+			// a cycle among the bases, reported by the classes in it
+			return nil
+		}
+
+		inherited[c] = true
+	}
+
+	fields := node.Fields()
+	own := node.Properties()
+
+	for _, prop := range own {
+		for _, field := range fields[:len(fields)-len(own)] {
+			if field.Name() == prop.Name() {
+				err := a.Errorf(prop.Row, prop.Col, "Field %q already inherited from %q", prop.Name(), field.Parent().Name()).(*ParseError)
+				err.Code = ErrorDuplicateSymbol
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (a *Analyzer) analyzeEnum(root *ast.DocumentNode, scope ast.Node) error {
+	a.stats.production(ProductionEnum)
+	node := ast.NewEnumNode(scope)
+	root.Declarations = append(root.Declarations, node)
+	node.Doc = a.takeDoc()
+	name, err := a.expectOp(token.OpIdentifier)
+
+	if err != nil {
+		return err
+	}
+
+	node.Value = name.Value
+	node.Row, node.Col = name.Row, name.Col
+	node.Pos = name.Pos
+
+	if err := scope.AddSymbol(node.Symbol()); err != nil {
+		return a.symbolError(name, err)
+	}
+
+	qualifier, err := a.analyzeQualifier(scope, ast.QualifierStorageType)
+
+	if err != nil {
+		return err
+	}
+
+	node.Qualifier = qualifier
+
+	if qualifier != nil && qualifier.IsLiteral() {
+		node.Type, _ = ast.ParseStorageType(qualifier.Value)
+	}
+
+	if flag := a.optionalKeyword(token.KeywordFlags); flag != nil {
+		a.stats.production(ProductionEnumFlags)
+		node.Flags = true
+	}
+
+	if err := a.analyzeScope(root, node); err != nil {
+		return err
+	}
+
+	if err := a.expectTerminator(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (a *Analyzer) analyzeUnion(root *ast.DocumentNode, scope ast.Node) error {
+	a.stats.production(ProductionUnion)
+	node := ast.NewUnionNode(scope)
+	root.Declarations = append(root.Declarations, node)
+	node.Doc = a.takeDoc()
+	name, err := a.expectOp(token.OpIdentifier)
+
+	if err != nil {
+		return err
+	}
+
+	node.Value = name.Value
+	node.Row, node.Col = name.Row, name.Col
+	node.Pos = name.Pos
+
+	if err := scope.AddSymbol(node.Symbol()); err != nil {
+		return a.symbolError(name, err)
+	}
+
+	qualifier, err := a.analyzeQualifier(scope, ast.QualifierDiscriminator)
+
+	if err != nil {
+		return err
+	}
+
+	if qualifier == nil {
+		return a.tokenError(name, ErrorInvalidValue, "Union %q requires a discriminator enum", name.ValueString())
+	}
+
+	node.Qualifier = qualifier
+
+	if err := a.analyzeScope(root, node); err != nil {
+		return err
+	}
+
+	if err := a.expectTerminator(); err != nil {
+		return err
+	}
+
+	// the discriminator may be named by a typedef resolved afterwards
+	a.checkLater(func() error {
+		return a.checkUnion(node, name)
+	})
+
+	return nil
+}
+
+// checkUnion resolves the discriminator members of the variants of node and
+// reports variants that aren't classes or repeat the class of another one.
+func (a *Analyzer) checkUnion(node *ast.UnionNode, name *token.Token) error {
+	discriminator := strings.Join(node.Qualifier.Path, "::")
+
+	if sym := ast.Underlying(node.Qualifier.Symbol); !isPlaceholder(sym) || ast.IsBuiltinType(discriminator) {
+		if _, ok := sym.Node.(*ast.EnumNode); !ok {
+			return a.tokenError(name, ErrorInvalidValue, "Discriminator %q of union %q is not an enum", discriminator, node.Name())
+		}
+	}
+
+	enum := node.Discriminator()
+	classes := make(map[*ast.ClassNode]*ast.UnionVariantNode)
+
+	for _, variant := range node.Variants() {
+		if sym := ast.Underlying(variant.Type); !isPlaceholder(sym) || ast.IsBuiltinType(variant.Type.Value) {
+			if _, ok := sym.Node.(*ast.ClassNode); !ok {
+				err := a.Errorf(variant.Row, variant.Col, "Variant %q of union %q is not a class", variant.Type.Value, node.Name()).(*ParseError)
+				err.Code = ErrorInvalidValue
+				return err
+			}
+		}
+
+		if class := variant.Class(); class != nil {
+			if other, ok := classes[class]; ok {
+				err := a.Errorf(variant.Row, variant.Col, "Variant %q repeats the class of variant %q", variant.Name(), other.Name()).(*ParseError)
+				err.Code = ErrorDuplicateSymbol
+				return err
+			}
+
+			classes[class] = variant
+		}
+
+		if enum == nil {
+			continue
+		}
+
+		for _, member := range enum.Members() {
+			if member.Name() == variant.Name() {
+				variant.Member = member.Symbol()
+			}
+		}
+
+		if variant.Member == nil {
+			err := a.Errorf(variant.Row, variant.Col, "Unresolved symbol %q", discriminator+"::"+variant.Name()).(*ParseError)
+			err.Code = ErrorUnresolvedSymbol
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isPlaceholder reports whether sym is missing or names an undeclared type or
+// a builtin one, whose placeholder symbols are owned by the document.
+func isPlaceholder(sym *ast.Symbol) bool {
+	if sym == nil || sym.Node == nil {
+		return true
+	}
+
+	_, ok := sym.Node.(*ast.DocumentNode)
+
+	return ok
+}
+
+// analyzeVariant parses a `Type Member;` variant of node.
+func (a *Analyzer) analyzeVariant(node *ast.UnionNode) error {
+	a.stats.production(ProductionVariant)
+	variant := ast.NewUnionVariantNode(node)
+	variant.Doc = a.takeDoc()
+	typeToken, err := a.expectOp(token.OpIdentifier)
+
+	if err != nil {
+		return err
+	}
+
+	name, err := a.expectOp(token.OpIdentifier)
+
+	if err != nil {
+		return err
+	}
+
+	variant.Value = name.Value
+	variant.Row, variant.Col = name.Row, name.Col
+	variant.Pos = name.Pos
+
+	if err := node.AddSymbol(variant.Symbol()); err != nil {
+		return a.symbolError(name, err)
+	}
+
+	if err := a.expectTerminator(); err != nil {
+		return err
+	}
+
+	variant.Comment = trailingComment(a.last)
+
+	a.later(func() error {
+		var err error
+		variant.Type, err = a.lookupType(node.Parent(), typeToken)
+		return err
+	})
+
+	return nil
+}
+
+// analyzeScope parses the body of scope, the members of an enum, the variants
+// of a union or the properties of a class along with the classes and enums
+// nested in it.
+func (a *Analyzer) analyzeScope(root *ast.DocumentNode, scope ast.Node) error {
+	if _, err := a.expectToken(openScopeToken); err != nil {
+		return err
+	}
+
+	a.stats.production(ProductionScope)
+	a.collectDoc()
+	closeScope := a.optionalToken(closeScopeToken)
+
+	for closeScope == nil {
+		var err error
+
+		if enum, ok := scope.(*ast.EnumNode); ok {
+			err = a.analyzeEnumMember(enum)
+		} else if union, ok := scope.(*ast.UnionNode); ok {
+			err = a.analyzeVariant(union)
+		} else if t := a.tokens.Peek(); isNestedKeyword(t) {
+			a.next()
+			err = a.analyzeDeclaration(t, root, scope)
+		} else {
+			err = a.analyzeProperty(scope)
+		}
+
+		if err != nil {
+			if !a.recovery || a.tokens.Peek() == nil {
+				return err
+			}
+
+			a.errors.add(err)
+			a.skipStatement()
+		}
+
+		a.collectDoc()
+		closeScope = a.optionalToken(closeScopeToken)
+	}
+
+	a.takeDoc()
+
+	return nil
+}
+
+func (a *Analyzer) analyzeProperty(root ast.Node) error {
+	a.stats.production(ProductionProperty)
+	node := ast.NewPropertyNode(root)
+	node.Doc = a.takeDoc()
+
+	if a.optionalKeyword(token.KeywordOptional) != nil {
+		a.stats.production(ProductionOptional)
+		node.Optional = true
+	}
+
+	t1, err := a.expectOp(token.OpIdentifier)
+
+	if err != nil {
+		return err
+	}
+
+	if t1.Keyword.IsReserved() {
+		return a.keywordError(t1)
+	}
+
+	qualifier, err := a.analyzeQualifier(root, ast.QualifierSize)
+
+	if err != nil {
+		return err
+	}
+
+	t2 := a.optionalOp(token.OpIdentifier)
+	t3 := a.optionalOp(token.OpIdentifier)
+
+	var (
+		name       *token.Token
+		typeSymbol string
+		flags      ast.PropertyFlag
+		ok         bool
+		annotation *token.Token
+	)
+
+	// annotations follow the name
+	if a.isAnnotation(t3) {
+		annotation, t3 = t3, nil
+	} else if t3 == nil && a.isAnnotation(t2) {
+		annotation, t2 = t2, nil
+	}
+
+	if t3 != nil {
+		a.stats.production(ProductionPropertyFlags)
+		name = t3
+		typeSymbol = t2.ValueString()
+
+		if flags, ok = ast.ParsePropertyFlag(t1.ValueString()); !ok {
+			return a.tokenError(t1, ErrorInvalidToken, "Unknown property flag %q", t1.ValueString())
+		}
+	} else if t2 != nil {
+		name = t2
+		typeSymbol = t1.ValueString()
+	} else {
+		name = t1
+	}
+
+	node.Value = name.Value
+	node.Row, node.Col = name.Row, name.Col
+	node.Pos = name.Pos
+	node.Qualifier = qualifier
+
+	if err := root.AddSymbol(node.Symbol()); err != nil {
+		return a.symbolError(name, err)
+	}
+
+	// sizes are checked once the types they refer to are resolved
+	if qualifier != nil {
+		a.checkLater(func() error {
+			return a.checkSize(node)
+		})
+	}
+
+	if node.Optional && flags == ast.PropertyFlagConst {
+		return a.tokenError(name, ErrorInvalidValue, "Constant %q can't be optional", name.ValueString())
+	}
+
+	if typeSymbol != "" {
+		a.stats.production(ProductionPropertyType)
+		typeToken := t1
+
+		if t3 != nil {
+			typeToken = t2
+		}
+
+		if wire := flags.WireType(); a.strict && wire != "" && ast.IsBuiltinType(typeSymbol) && typeSymbol != wire {
+			return a.tokenError(typeToken, ErrorInvalidValue, "Property flag %s requires type %s, got %s", flags, wire, typeSymbol)
+		}
+
+		a.later(func() error {
+			return a.resolveType(node, root, typeToken)
+		})
+	}
+
+	node.Flags = flags
+
+	if annotation == nil {
+		annotation = a.optionalAnnotation()
+	}
+
+	seen := make(map[string]bool)
+
+	for ; annotation != nil; annotation = a.optionalAnnotation() {
+		key := strings.ToLower(annotation.ValueString())
+
+		if seen[key] {
+			return a.tokenError(annotation, ErrorInvalidValue, "Repeated annotation %q of %q", key, node.Name())
+		}
+
+		seen[key] = true
+
+		if err := a.analyzeAnnotation(node, root, annotation); err != nil {
+			return err
+		}
+	}
+
+	if assignment := a.optionalToken(assignmentToken); assignment != nil {
+		a.stats.production(ProductionDefault)
+
+		if node.Optional {
+			return a.tokenError(assignment, ErrorInvalidValue, "Optional field %q can't have a default value", node.Name())
+		}
+
+		expr, err := a.analyzeExpr(node)
+
+		if err != nil {
+			return err
+		}
+
+		node.Expr = expr
+	}
+
+	if err := a.expectTerminator(); err != nil {
+		return err
+	}
+
+	node.Obsolete, node.ObsoleteReason, node.Removed, node.RemovedReason = a.analyzeAttributes()
+	node.Comment = trailingComment(a.last)
+
+	return nil
+}
+
+// isAnnotation reports whether t is the keyword starting an annotation, like a
+// condition, followed by a colon, as opposed to a name.
+func (a *Analyzer) isAnnotation(t *token.Token) bool {
+	if t == nil || !a.isAnnotationToken(t) {
+		return false
+	}
+
+	next := a.tokens.Peek()
+
+	return next != nil && a.match(baseToken, next)
+}
+
+func (a *Analyzer) isAnnotationToken(t *token.Token) bool {
+	for _, keyword := range annotationTokens {
+		if a.match(keyword, t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *Analyzer) optionalAnnotation() *token.Token {
+	for _, keyword := range annotationTokens {
+		if t := a.optionalToken(keyword); t != nil {
+			return t
+		}
+	}
+
+	return nil
+}
+
+// analyzeAnnotation parses the annotation of node started by the keyword t.
+func (a *Analyzer) analyzeAnnotation(node *ast.PropertyNode, scope ast.Node, t *token.Token) error {
+	switch {
+	case a.match(endianToken, t):
+		return a.analyzeEndian(node, t)
+	case a.match(encodingToken, t):
+		return a.analyzeEncoding(node, t)
+	case a.match(termToken, t):
+		return a.analyzeTermination(node, t)
+	default:
+		return a.analyzeCondition(node, scope, t)
+	}
+}
+
+// annotationValue parses the colon and the identifier following the keyword
+// of an annotation, like `:be`, returning the identifier.
+func (a *Analyzer) annotationValue() (*token.Token, error) {
+	if _, err := a.expectToken(baseToken); err != nil {
+		return nil, err
+	}
+
+	return a.expectOp(token.OpIdentifier)
+}
+
+// analyzeEndian parses the byte order of node, like `endian:be`.
+func (a *Analyzer) analyzeEndian(node *ast.PropertyNode, t *token.Token) error {
+	a.stats.production(ProductionEndian)
+
+	value, err := a.annotationValue()
+
+	if err != nil {
+		return err
+	}
+
+	order, ok := ast.ParseByteOrder(value.ValueString())
+
+	if !ok {
+		return a.tokenError(value, ErrorInvalidValue, "Unknown byte order %q, expected le or be", value.ValueString())
+	}
+
+	if node.IsConst() {
+		return a.tokenError(t, ErrorInvalidValue, "Constant %q has no byte order", node.Name())
+	}
+
+	node.Endian = order
+
+	// field types may be named by typedefs resolved afterwards
+	a.checkLater(func() error {
+		return a.checkEndian(node, t)
+	})
+
+	return nil
+}
+
+// checkEndian reports byte orders given to fields that aren't numbers, arrays
+// of numbers or UTF-16 strings.
+func (a *Analyzer) checkEndian(node *ast.PropertyNode, t *token.Token) error {
+	sym := ast.Underlying(node.Type)
+
+	if sym == nil || node.Flags != ast.PropertyFlagNone {
+		return nil
+	}
+
+	switch sym.Node.(type) {
+	case *ast.ClassNode, *ast.UnionNode:
+	default:
+		if sym.Value != "string" || node.Encoding == ast.EncodingUTF16 {
+			return nil
+		}
+	}
+
+	return a.tokenError(t, ErrorInvalidValue, "Field %q of type %s has no byte order", node.Name(), sym.Value)
+}
+
+// analyzeEncoding parses the character encoding of the string node, like
+// `encoding:utf16`.
+func (a *Analyzer) analyzeEncoding(node *ast.PropertyNode, t *token.Token) error {
+	a.stats.production(ProductionEncoding)
+
+	value, err := a.annotationValue()
+
+	if err != nil {
+		return err
+	}
+
+	encoding, ok := ast.ParseStringEncoding(value.ValueString())
+
+	if !ok {
+		return a.tokenError(value, ErrorInvalidValue, "Unknown string encoding %q, expected utf8 or utf16", value.ValueString())
+	}
+
+	node.Encoding = encoding
+	a.laterCheckString(node, t)
+
+	return nil
+}
+
+// analyzeTermination parses how the end of the string node is found, like
+// `term:null`.
+func (a *Analyzer) analyzeTermination(node *ast.PropertyNode, t *token.Token) error {
+	a.stats.production(ProductionTermination)
+
+	value, err := a.annotationValue()
+
+	if err != nil {
+		return err
+	}
+
+	term, ok := ast.ParseTermination(value.ValueString())
+
+	if !ok {
+		return a.tokenError(value, ErrorInvalidValue, "Unknown termination %q, expected null or length", value.ValueString())
+	}
+
+	node.Termination = term
+	a.laterCheckString(node, t)
+
+	return nil
+}
+
+// laterCheckString queues checkString once the type of node, which may be
+// named by a typedef, is resolved.
+func (a *Analyzer) laterCheckString(node *ast.PropertyNode, t *token.Token) {
+	a.checkLater(func() error {
+		return a.checkString(node, t)
+	})
+}
+
+// checkString reports the string annotation t given to a field that isn't a
+// string, or a termination contradicting the size of the field: null-terminated
+// strings have no size, and the length of the others is given by a field.
+func (a *Analyzer) checkString(node *ast.PropertyNode, t *token.Token) error {
+	if node.IsConst() {
+		return a.tokenError(t, ErrorInvalidValue, "Constant %q isn't encoded", node.Name())
+	}
+
+	sym := ast.Underlying(node.Type)
+
+	if sym == nil {
+		return nil
+	}
+
+	if node.Flags != ast.PropertyFlagNone || sym.Value != "string" {
+		return a.tokenError(t, ErrorInvalidValue, "Field %q of type %s isn't a string", node.Name(), sym.Value)
+	}
+
+	if !a.match(termToken, t) {
+		return nil
+	}
+
+	if node.Termination == ast.TermNull && node.Qualifier != nil {
+		return a.tokenError(t, ErrorInvalidValue, "Null-terminated string %q can't have a size", node.Name())
+	}
+
+	if node.Termination == ast.TermLength && node.LengthField() == nil {
+		return a.tokenError(t, ErrorInvalidValue, "String %q has no length field", node.Name())
+	}
+
+	return nil
+}
+
+// analyzeCondition parses the `if:Enum::Member` condition of node, starting
+// at t, resolved from scope and checked once all declarations are known.
+func (a *Analyzer) analyzeCondition(node *ast.PropertyNode, scope ast.Node, t *token.Token) error {
+	a.stats.production(ProductionCondition)
+
+	if _, err := a.expectToken(baseToken); err != nil {
+		return err
+	}
+
+	tokens, err := a.getNamespacedIdentifier()
+
+	if err != nil {
+		return err
+	}
+
+	node.ConditionPath = token.StringValues(tokens)
+
+	if node.Optional {
+		return a.tokenError(t, ErrorInvalidValue, "Optional field %q can't be conditional", node.Name())
+	}
+
+	if node.IsConst() {
+		return a.tokenError(t, ErrorInvalidValue, "Constant %q can't be conditional", node.Name())
+	}
+
+	a.later(func() error {
+		var err error
+		node.Condition, err = a.resolve(scope, tokens)
+		return err
+	})
+
+	// field types may be named by typedefs resolved afterwards
+	a.checkLater(func() error {
+		if node.Condition == nil {
+			return nil
+		}
+
+		return a.checkCondition(node, tokens)
+	})
+
+	return nil
+}
+
+// checkCondition reports conditions that aren't members of flags enums or
+// that no preceding field can be tested for.
+func (a *Analyzer) checkCondition(node *ast.PropertyNode, tokens []*token.Token) error {
+	name := strings.Join(node.ConditionPath, "::")
+	member := node.ConditionMember()
+
+	if member == nil {
+		if a.strict {
+			return a.tokenError(tokens[0], ErrorInvalidValue, "Condition %q is not a member of a flags enum", name)
+		}
+
+		return nil
+	}
+
+	if enum, ok := member.Parent().(*ast.EnumNode); !ok || !enum.Flags {
+		return a.tokenError(tokens[0], ErrorInvalidValue, "Condition %q is not a member of a flags enum", name)
+	}
+
+	if node.ConditionField() == nil {
+		return a.tokenError(tokens[0], ErrorUnresolvedSymbol, "No field of type %q precedes conditional field %q", member.Parent().Name(), node.Name())
+	}
+
+	return nil
+}
+
+// checkSize reports arrays of unions and size qualifiers that are neither an
+// element count nor a length field.
+func (a *Analyzer) checkSize(node *ast.PropertyNode) error {
+	q := node.Qualifier
+
+	if t := ast.Underlying(node.Type); t != nil {
+		if _, ok := t.Node.(*ast.UnionNode); ok {
+			return a.invalidField(node, "Union field %q can't be an array", node.Name())
+		}
+	}
+
+	if q.IsLiteral() {
+		if _, err := q.Size(); err != nil {
+			return a.invalidField(node, "Invalid size %q of %q", q.Value, node.Name())
+		}
+
+		return nil
+	}
+
+	if isPlaceholder(q.Symbol) {
+		return nil
+	}
+
+	switch n := q.Symbol.Node.(type) {
+	case *ast.EnumMemberNode:
+		return nil
+	case *ast.PropertyNode:
+		if n.IsConst() {
+			return nil
+		}
+
+		return a.checkLength(node, n)
+	}
+
+	return a.invalidField(node, "Size of %q must be a number, a constant or a preceding field", node.Name())
+}
+
+// checkLength reports fields of variable length whose length isn't given by a
+// single preceding integer field that is always present.
+func (a *Analyzer) checkLength(node, length *ast.PropertyNode) error {
+	if length.Parent() != node.Parent() {
+		return a.invalidField(node, "Length of %q is given by %q, a field of another class", node.Name(), length.Name())
+	}
+
+	if node.Optional {
+		return a.invalidField(node, "Optional field %q can't have a variable length", node.Name())
+	}
+
+	if length.Optional || length.Condition != nil {
+		return a.invalidField(node, "Length field %q of %q can't be optional or conditional", length.Name(), node.Name())
+	}
+
+	for _, f := range node.Parent().(*ast.ClassNode).Properties() {
+		if f == node {
+			return a.invalidField(node, "Length field %q doesn't precede %q", length.Name(), node.Name())
+		}
+
+		if f == length {
+			break
+		}
+	}
+
+	if other := length.LengthOf(); other != node {
+		return a.invalidField(node, "Field %q already gives the length of %q", length.Name(), other.Name())
+	}
+
+	var integer bool
+
+	if t := ast.Underlying(length.Type); t != nil && length.Flags == ast.PropertyFlagNone {
+		_, integer = ast.ParseStorageType(t.Value)
+	}
+
+	if !integer {
+		return a.invalidField(node, "Length field %q of %q must have an integer type", length.Name(), node.Name())
+	}
+
+	return nil
+}
+
+// invalidField returns an ErrorInvalidValue error located at node.
+func (a *Analyzer) invalidField(node *ast.PropertyNode, format string, v ...interface{}) error {
+	err := a.Errorf(node.Row, node.Col, format, v...).(*ParseError)
+	err.Code = ErrorInvalidValue
+	return err
+}
+
+func (a *Analyzer) analyzeEnumMember(root *ast.EnumNode) error {
+	a.stats.production(ProductionEnumMember)
+	node := ast.NewEnumMemberNode(root)
+	node.Doc = a.takeDoc()
+	name, err := a.expectOp(token.OpIdentifier)
+
+	if err != nil {
+		return err
+	}
+
+	if name.Keyword.IsAttribute() {
+		return a.keywordError(name)
+	}
+
+	node.Value = name.Value
+	node.Row, node.Col = name.Row, name.Col
+	node.Pos = name.Pos
+
+	if err := root.AddSymbol(node.Symbol()); err != nil {
+		return a.symbolError(name, err)
+	}
+
+	if assignment := a.optionalToken(assignmentToken); assignment != nil {
+		a.stats.production(ProductionDefault)
+
+		if node.Expr, err = a.analyzeExpr(node); err != nil {
+			return err
+		}
+
+		if lit, ok := node.Expr.(*ast.LiteralExpr); ok && !root.Type.Fits(lit.Value) {
+			return a.tokenError(name, ErrorInvalidValue, "Value %s of %s::%s overflows %s", lit.Raw, root.Name(), node.Name(), root.Type)
+		}
+	} else if implicitOverflow(root) {
+		return a.tokenError(name, ErrorInvalidValue, "Implicit value of %s::%s overflows %s", root.Name(), node.Name(), root.Type)
+	}
+
+	if err := a.expectTerminator(); err != nil {
+		return err
+	}
+
+	node.Obsolete, node.ObsoleteReason, node.Removed, node.RemovedReason = a.analyzeAttributes()
+	node.Comment = trailingComment(a.last)
+
+	return nil
+}
+
+// implicitOverflow reports whether the last member of enum, which has no value,
+// continues past the storage type of enum from the literal value of a previous
+// member, or from 0. Values computed from other expressions are left to
+// ast.Evaluate.
+func implicitOverflow(enum *ast.EnumNode) bool {
+	members := enum.Members()
+	start := len(members) - 1
+	v := uint64(0)
+
+	for i := start - 1; i >= 0; i-- {
+		if members[i].Expr == nil {
+			continue
+		}
+
+		lit, ok := members[i].Expr.(*ast.LiteralExpr)
+
+		if !ok {
+			return false
+		}
+
+		start, v = start-i, lit.Value
+		break
+	}
+
+	next := v + uint64(start)
+
+	// unsigned values wrap past the largest ulong, signed ones cross 0
+	return (next < v && !enum.Type.Signed()) || !enum.Type.Fits(next)
+}
+
+// analyzeAttributes parses the optional `obsolete ["reason"]` and
+// `removed ["reason"]` attributes following a member's terminator.
+func (a *Analyzer) analyzeAttributes() (obsolete bool, obsoleteReason string, removed bool, removedReason string) {
+	for {
+		switch {
+		case a.optionalKeyword(token.KeywordObsolete) != nil:
+			a.stats.production(ProductionObsolete)
+			obsolete = true
+			obsoleteReason = a.attributeReason(ProductionObsoleteReason)
+		case a.optionalKeyword(token.KeywordRemoved) != nil:
+			a.stats.production(ProductionRemoved)
+			removed = true
+			removedReason = a.attributeReason(ProductionRemovedReason)
+		default:
+			return
+		}
+	}
+}
+
+// checkReasons warns about the members of root flagged obsolete or removed
+// without a reason.
+func (a *Analyzer) checkReasons(root *ast.DocumentNode) {
+	for _, decl := range root.Declarations {
+		for _, child := range decl.Children() {
+			var (
+				row, col                      int
+				obsolete, removed             bool
+				obsoleteReason, removedReason string
+			)
+
+			switch n := child.(type) {
+			case *ast.EnumMemberNode:
+				row, col = n.Row, n.Col
+				obsolete, obsoleteReason, removed, removedReason = n.Obsolete, n.ObsoleteReason, n.Removed, n.RemovedReason
+			case *ast.PropertyNode:
+				row, col = n.Row, n.Col
+				obsolete, obsoleteReason, removed, removedReason = n.Obsolete, n.ObsoleteReason, n.Removed, n.RemovedReason
+			default:
+				continue
+			}
+
+			for _, attr := range []struct {
+				name   string
+				set    bool
+				reason string
+			}{{"Obsolete", obsolete, obsoleteReason}, {"Removed", removed, removedReason}} {
+				if attr.set && attr.reason == "" {
+					name := []byte(child.Name())
+					t := &token.Token{Op: token.OpIdentifier, Value: name, Raw: name, Row: row, Col: col, Pos: a.pos(row, col)}
+					a.warnings = append(a.warnings, a.tokenError(t, ErrorMissingReason, "%s member %s::%s has no reason", attr.name, decl.Name(), child.Name()))
+				}
+			}
+		}
+	}
+}
+
+func (a *Analyzer) attributeReason(production string) string {
+	if reason := a.optionalOp(token.OpString); reason != nil {
+		a.stats.production(production)
+		return reason.ValueString()
+	}
+
+	a.optionalOp(token.OpTerminator)
+
+	return ""
+}
+
+func (a *Analyzer) analyzeQualifier(root ast.Node, kind ast.QualifierKind) (*ast.Qualifier, error) {
+	tokens, err := a.getQualifierIdentifier()
+
+	if err != nil || tokens == nil {
+		return nil, err
+	}
+
+	a.stats.production(ProductionQualifier)
+	values := token.StringValues(tokens)
+	q := &ast.Qualifier{Kind: kind, Path: values}
+
+	if len(values) == 1 && isQualifierLiteral(kind, values[0]) {
+		if kind == ast.QualifierStorageType && a.strict {
+			if _, ok := ast.ParseStorageType(values[0]); !ok {
+				return nil, a.tokenError(tokens[0], ErrorUnresolvedSymbol, "Unknown storage type %q", values[0])
+			}
+		}
+
+		q.Value = values[0]
+	} else {
+		a.later(func() error {
+			var err error
+			q.Symbol, err = a.resolve(root, tokens)
+			return err
+		})
+	}
+
+	return q, nil
+}
+
+// later defers a resolution until the declarations and imports of the whole
+// file are known, so that names can be referenced before they're declared.
+func (a *Analyzer) later(resolve func() error) {
+	a.pending = append(a.pending, resolve)
+}
+
+// checkLater defers a validation until the deferred resolutions have all run,
+// so that it sees the names resolved, typedefs included.
+func (a *Analyzer) checkLater(check func() error) {
+	a.checks = append(a.checks, check)
+}
+
+// resolvePending runs the deferred resolutions in source order, then the
+// deferred checks. In recovery mode their errors are collected instead of
+// returned.
+func (a *Analyzer) resolvePending() error {
+	for _, phase := range []*[]func() error{&a.pending, &a.checks} {
+		pending := *phase
+		*phase = nil
+
+		for _, run := range pending {
+			if err := run(); err != nil {
+				if !a.recovery {
+					a.pending, a.checks = nil, nil
+					return err
+				}
+
+				a.errors.add(err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolveType resolves the type of node, named by t, from scope.
+func (a *Analyzer) resolveType(node *ast.PropertyNode, scope ast.Node, t *token.Token) error {
+	sym, err := a.lookupType(scope, t)
+
+	if err != nil {
+		return err
+	}
+
+	a.setType(node, sym)
+
+	return nil
+}
+
+// lookupType resolves the type named by t from scope.
+func (a *Analyzer) lookupType(scope ast.Node, t *token.Token) (*ast.Symbol, error) {
+	name := t.ValueString()
+
+	if a.strict && !ast.IsBuiltinType(name) {
+		sym := ast.LookupSymbol(scope, []string{name})
+
+		if sym == nil {
+			return nil, a.tokenError(t, ErrorUnresolvedSymbol, "Unresolved type %q", name)
+		}
+
+		return sym, nil
+	}
+
+	sym := scope.FindSymbol(name, true)
+	a.reference(sym, []*token.Token{t})
+
+	return sym, nil
+}
+
+// setType sets the type of node to sym. Types named by typedefs are replaced
+// by the types they alias once all typedefs are resolved, recursive ones being
+// reported by the typedefs themselves.
+func (a *Analyzer) setType(node *ast.PropertyNode, sym *ast.Symbol) {
+	node.Type = sym
+
+	if _, ok := sym.Node.(*ast.TypedefNode); !ok {
+		return
+	}
+
+	node.Alias = sym
+
+	a.checkLater(func() error {
+		if underlying := ast.Underlying(sym); underlying != nil {
+			node.Type = underlying
+		}
+
+		return nil
+	})
+}
+
+// resolve looks up a namespaced reference from scope. Outside of strict mode
+// the last path element is created if it doesn't exist.
+func (a *Analyzer) resolve(scope ast.Node, tokens []*token.Token) (*ast.Symbol, error) {
+	values := token.StringValues(tokens)
+	sym := a.resolvePath(scope, values)
+
+	if sym == nil {
+		return nil, a.tokenError(tokens[0], ErrorUnresolvedSymbol, "Unresolved symbol %q", strings.Join(values, "::"))
+	}
+
+	if !a.strict {
+		a.reference(sym, tokens)
+	}
+
+	return sym, nil
+}
+
+func (a *Analyzer) resolvePath(scope ast.Node, values []string) *ast.Symbol {
+	if !a.strict || (len(values) == 1 && isLiteral(values[0])) {
+		return scope.FindNestedSymbol(values)
+	}
+
+	return ast.LookupSymbol(scope, values)
+}
+
+// isLiteral reports whether value is a number or the limit of a storage type,
+// which aren't declared.
+func isLiteral(value string) bool {
+	_, ok := ast.ParseLimit(value)
+	return ok || isNumber(value)
+}
+
+func isNumber(value string) bool {
+	if _, err := strconv.ParseInt(value, 0, 64); err == nil {
+		return true
+	}
+
+	_, err := strconv.ParseUint(value, 0, 64)
+
+	return err == nil
+}
+
+func isQualifierLiteral(kind ast.QualifierKind, value string) bool {
+	switch kind {
+	case ast.QualifierStorageType:
+		return true
+	case ast.QualifierSize:
+		return isNumber(value)
+	default:
+		return false
+	}
+}
+
+func (a *Analyzer) addDoc(t *token.Token) {
+	a.stats.production(ProductionDoc)
+	line := t.ValueString()
+
+	if len(line) > 0 && line[0] == ' ' {
+		line = line[1:]
+	}
+
+	a.doc = append(a.doc, line)
+}
+
+// trailingComment returns the text of a comment following t on the same line.
+func trailingComment(t *token.Token) string {
+	if t == nil {
+		return ""
+	}
+
+	for _, trivia := range t.Trailing {
+		if trivia.Op == token.OpComment {
+			return strings.TrimSpace(trivia.ValueString())
+		}
+	}
+
+	return ""
+}
+
+func (a *Analyzer) collectDoc() {
+	for t := a.optionalOp(token.OpDoc); t != nil; t = a.optionalOp(token.OpDoc) {
+		a.addDoc(t)
+	}
+}
+
+func (a *Analyzer) takeDoc() string {
+	doc := strings.Join(a.doc, "\n")
+	a.doc = nil
+	return doc
+}
+
+func (a *Analyzer) next() *token.Token {
+	t := a.tokens.Dequeue()
+
+	if t != nil {
+		a.stats.token(t)
+		a.last = t
+	}
+
+	return t
+}
+
+// expectTerminator suggests inserting the missing terminator only when the
+// offending token starts a new line, closes the scope or the input ended, so
+// that applying the fix can't merge two unrelated statements.
+func (a *Analyzer) expectTerminator() error {
+	_, err := a.expectOp(token.OpTerminator)
+
+	if err == nil {
+		return nil
+	}
+
+	t := a.tokens.Peek()
+
+	if t == nil || a.match(closeScopeToken, t) || (a.last != nil && t.Row > a.last.Row) {
+		a.stats.production(ProductionTerminatorFix)
+		return a.fixError(err, ";")
+	}
+
+	return err
+}
+
+func (a *Analyzer) fixError(err error, text string) error {
+	if a.last == nil {
+		return err
+	}
+
+	return &FixableError{
+		Err: err,
+		Fix: &Fix{
+			Filename: a.filename,
+			Offset:   a.last.End,
+			End:      a.last.End,
+			Row:      a.last.Row,
+			Col:      a.last.Col,
+			Text:     text,
+		},
+	}
+}
+
+func (a *Analyzer) expectOp(op token.OpCode) (*token.Token, error) {
+	t := a.tokens.Peek()
+
+	if t == nil {
+		return nil, a.tokenError(nil, ErrorUnexpectedEOF, "EOF")
+	}
+
+	if t.Op != op {
+		return nil, a.tokenError(t, ErrorUnexpectedToken, "Unexpected token %q", t.Raw)
+	}
+
+	return a.next(), nil
+}
+
+func (a *Analyzer) expectToken(t1 *token.Token) (*token.Token, error) {
+	t2 := a.tokens.Peek()
+
+	if t2 == nil {
+		return nil, a.tokenError(nil, ErrorUnexpectedEOF, "EOF")
+	}
+
+	if !a.match(t1, t2) {
+		return nil, a.tokenError(t2, ErrorUnexpectedToken, "Unexpected token %q", t2.Raw)
+	}
+
+	return a.next(), nil
+}
+
+func (a *Analyzer) optionalOp(op token.OpCode) *token.Token {
+	t := a.tokens.Peek()
+
+	if t == nil {
+		return nil
+	}
+
+	if t.Op != op {
+		return nil
+	}
+
+	return a.next()
+}
+
+func (a *Analyzer) optionalToken(t1 *token.Token) *token.Token {
+	t2 := a.tokens.Peek()
+
+	if t2 == nil {
+		return nil
+	}
+
+	if !a.match(t1, t2) {
+		return nil
+	}
+
+	return a.next()
+}
+
+// optionalKeyword consumes the next token if it's the keyword k.
+func (a *Analyzer) optionalKeyword(k token.Keyword) *token.Token {
+	if t := a.tokens.Peek(); t == nil || t.Keyword != k {
+		return nil
+	}
+
+	return a.next()
+}
+
+// keywordError reports a reserved keyword starting a member.
+func (a *Analyzer) keywordError(t *token.Token) error {
+	return a.tokenError(t, ErrorInvalidToken, "Keyword %q can't start a member", t.Raw)
+}
+
+func (a *Analyzer) getNamespacedIdentifier() ([]*token.Token, error) {
+	var result []*token.Token
+
+	if number := a.optionalOp(token.OpNumber); number != nil {
+		return append(result, number), nil
+	}
+
+	id, err := a.expectOp(token.OpIdentifier)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result = append(result, id)
+
+	for a.optionalOp(token.OpNamespace) != nil {
+		a.stats.production(ProductionNamespace)
+
+		id, err = a.expectOp(token.OpIdentifier)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, id)
+	}
+
+	return result, nil
+}
+
+func (a *Analyzer) getQualifierIdentifier() ([]*token.Token, error) {
+	openQualifier := a.optionalToken(openQualifierToken)
+
+	if openQualifier == nil {
+		return nil, nil
+	}
+
+	qualifiers, err := a.getNamespacedIdentifier()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := a.expectToken(closeQualifierToken); err != nil {
+		if t := a.tokens.Peek(); t != nil && (t.Op == token.OpIdentifier || t.Op == token.OpNumber) {
+			return nil, a.fixError(err, closeQualifierToken.ValueString())
+		}
+
+		return nil, err
+	}
+
+	return qualifiers, nil
+}
+
+func (a *Analyzer) importFile(t *token.Token, root *ast.DocumentNode) error {
+	filename := t.ValueString()
+	imp := &ast.Import{Path: filename, Row: t.Row, Col: t.Col, Pos: t.Pos}
+	root.Imports = append(root.Imports, imp)
+	a.importDecls = append(a.importDecls, len(root.Declarations))
+
+	if a.imports == nil {
+		a.imports = make(map[string]*ast.DocumentNode)
+
+		if a.filename != "" {
+			a.imports[a.filename] = nil
+		}
+	}
+
+	if err := a.ctx.Err(); err != nil {
+		return err
+	}
+
+	if loaded, ok := a.loader.lookup(a.filename, t); ok {
+		return a.importLoaded(t, root, imp, loaded)
+	}
+
+	data, input, err := resolveImport(a.resolver, a.include, a.filename, filename)
+
+	if err != nil {
+		return a.importError(t, err)
+	}
+
+	if cached, ok := a.imports[input]; ok {
+		if cached == nil {
+			return a.importError(t, fmt.Errorf("Import cycle through %q", input))
+		}
+
+		// declarations were adopted by the first importer already
+		imp.Document = cached
+		a.importSymbols(t, root, cached)
+		return nil
+	}
+
+	a.imports[input] = nil
+	importTokenizer := token.NewTokenizer(data)
+	importTokenizer.SetTabWidth(a.t.TabWidth())
+	importTokenizer.SetCaseSensitive(a.t.CaseSensitive())
+	importAnalyzer := NewAnalyzer(importTokenizer, input)
+	importAnalyzer.fset = a.fset
+	importAnalyzer.manifest = a.manifest
+	importAnalyzer.stats = a.stats
+	importAnalyzer.recovery = a.recovery
+	importAnalyzer.strict = a.strict
+	importAnalyzer.imports = a.imports
+	importAnalyzer.resolver = a.resolver
+	importAnalyzer.include = a.include
+	importAnalyzer.cache = a.cache
+	importRoot, err := importAnalyzer.AnalyzeContext(a.ctx)
+	imp.Document = importRoot
+	a.imports[input] = importRoot
+	a.warnings = append(a.warnings, importAnalyzer.warnings...)
+	a.refs = append(a.refs, importAnalyzer.refs...)
+
+	if err != nil && !a.recovery {
+		return err
+	}
+
+	root.AdoptChildren(importRoot)
+	a.importSymbols(t, root, importRoot)
+
+	return err
+}
+
+// importLoaded completes an import analyzed ahead of time by the loader.
+func (a *Analyzer) importLoaded(t *token.Token, root *ast.DocumentNode, imp *ast.Import, loaded *loadedImport) error {
+	if loaded.err != nil {
+		return a.importError(t, loaded.err)
+	}
+
+	f := loaded.file
+	imp.Document = f.doc
+
+	if !loaded.owner {
+		a.importSymbols(t, root, f.doc)
+		return nil
+	}
+
+	a.warnings = append(a.warnings, f.warnings...)
+	a.refs = append(a.refs, f.refs...)
+	a.stats.merge(f.stats)
+
+	if f.err != nil && !a.recovery {
+		return f.err
+	}
+
+	root.AdoptChildren(f.doc)
+	a.importSymbols(t, root, f.doc)
+
+	return f.err
+}
+
+// resolveImport loads a file imported from the file from, searching the
+// include path if it's not found next to it.
+func resolveImport(r ImportResolver, include []string, from, filename string) ([]byte, string, error) {
+	data, input, err := r.Resolve(from, filename)
+
+	base := "main.steamd"
+
+	if from != "" {
+		base = path.Base(filepath.ToSlash(from))
+	}
+
+	for i := 0; i < len(include) && errors.Is(err, fs.ErrNotExist); i++ {
+		var includeErr error
+		data, input, includeErr = r.Resolve(path.Join(filepath.ToSlash(include[i]), base), filename)
+
+		if includeErr == nil || !errors.Is(includeErr, fs.ErrNotExist) {
+			err = includeErr
+		}
+	}
+
+	return data, input, err
+}
+
+// importSymbols makes the symbols of imported visible from root. Conflicting
+// symbols are reported as warnings and keep their existing declaration.
+func (a *Analyzer) importSymbols(t *token.Token, root, imported *ast.DocumentNode) {
+	if err := root.ImportSymbols(imported); err != nil {
+		a.warnings = append(a.warnings, a.importError(t, err))
+	}
+}
+
+func (a *Analyzer) importError(t *token.Token, err error) error {
+	parseErr := a.tokenError(t, ErrorImport, "%v", err).(*ParseError)
+	parseErr.Err = err
+	return parseErr
+}
+
+func (a *Analyzer) symbolError(t *token.Token, err error) error {
+	code := ErrorGeneric
+
+	if errors.Is(err, ast.ErrDuplicateSymbol) {
+		code = ErrorDuplicateSymbol
+	}
+
+	parseErr := a.tokenError(t, code, "Duplicate symbol %q", t.ValueString()).(*ParseError)
+	parseErr.Err = err
+	return parseErr
+}
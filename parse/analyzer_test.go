@@ -0,0 +1,1834 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/token"
+)
+
+func analyzeString(t *testing.T, data string) *ast.DocumentNode {
+	analyzer := NewAnalyzer(token.NewTokenizer([]byte(data)), "")
+	root, err := analyzer.Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	return root
+}
+
+func TestAnalyzerQualifiers(t *testing.T) {
+	root := analyzeString(t, `
+		enum EMsg<uint> {
+			Invalid = 0;
+		};
+
+		class MsgHdr<EMsg::Invalid> {
+			byte<20> x;
+			int len;
+			proto<len> CMsg body;
+		};
+	`)
+
+	children := root.Children()
+
+	if len(children) != 2 {
+		t.Fatalf("expected %d children, got %d", 2, len(children))
+	}
+
+	enum := children[0].(*ast.EnumNode)
+
+	if enum.Qualifier == nil || enum.Qualifier.Kind != ast.QualifierStorageType {
+		t.Fatalf("expected enum qualifier of kind %s, got %v", ast.QualifierStorageType, enum.Qualifier)
+	}
+
+	if !enum.Qualifier.IsLiteral() || enum.Qualifier.Value != "uint" {
+		t.Fatalf("mismatch: got %q, but expected %q", enum.Qualifier.Value, "uint")
+	}
+
+	class := children[1].(*ast.ClassNode)
+
+	if class.Qualifier == nil || class.Qualifier.Kind != ast.QualifierEMsg {
+		t.Fatalf("expected class qualifier of kind %s, got %v", ast.QualifierEMsg, class.Qualifier)
+	}
+
+	if class.Qualifier.IsLiteral() || class.Qualifier.Symbol.Value != "Invalid" {
+		t.Fatalf("expected class qualifier to resolve to symbol %q", "Invalid")
+	}
+
+	props := class.Children()
+	x := props[0].(*ast.PropertyNode)
+
+	if x.Qualifier == nil || x.Qualifier.Kind != ast.QualifierSize || x.Qualifier.Value != "20" {
+		t.Fatalf("expected property qualifier of kind %s with value %q, got %v", ast.QualifierSize, "20", x.Qualifier)
+	}
+
+	body := props[2].(*ast.PropertyNode)
+
+	if body.Qualifier == nil || body.Qualifier.IsLiteral() || body.Qualifier.Symbol.Value != "len" {
+		t.Fatalf("expected property qualifier to resolve to symbol %q, got %v", "len", body.Qualifier)
+	}
+}
+
+func TestAnalyzerPositions(t *testing.T) {
+	root := analyzeString(t, "enum EMsg {\n\tInvalid = 0;\n};\n\nclass  MsgHdr {\n\tuint  len;\n};\n")
+	enum := root.Enums()[0]
+	class := root.Classes()[0]
+
+	positions := []struct {
+		name     string
+		row, col int
+	}{
+		{enum.Name(), enum.Row, enum.Col},
+		{enum.Members()[0].Name(), enum.Members()[0].Row, enum.Members()[0].Col},
+		{class.Name(), class.Row, class.Col},
+		{class.Properties()[0].Name(), class.Properties()[0].Row, class.Properties()[0].Col},
+	}
+
+	expected := []string{"EMsg 1:6", "Invalid 2:2", "MsgHdr 5:8", "len 6:8"}
+
+	for i, pos := range positions {
+		if actual := fmt.Sprintf("%s %d:%d", pos.name, pos.row, pos.col); actual != expected[i] {
+			t.Fatalf("mismatch: got %s, but expected %s", actual, expected[i])
+		}
+	}
+}
+
+func TestAnalyzerDocComments(t *testing.T) {
+	root := analyzeString(t, `
+		/// Header sent before
+		/// every message.
+		class MsgHdr {
+			/// Message type.
+			uint msg;
+			// not a doc comment
+			int len;
+		};
+	`)
+
+	class := root.Children()[0].(*ast.ClassNode)
+	expected := "Header sent before\nevery message."
+
+	if class.Doc != expected {
+		t.Fatalf("mismatch: got %q, but expected %q", class.Doc, expected)
+	}
+
+	props := class.Children()
+
+	if doc := props[0].(*ast.PropertyNode).Doc; doc != "Message type." {
+		t.Fatalf("mismatch: got %q, but expected %q", doc, "Message type.")
+	}
+
+	if doc := props[1].(*ast.PropertyNode).Doc; doc != "" {
+		t.Fatalf("mismatch: got %q, but expected %q", doc, "")
+	}
+}
+
+func TestAnalyzerFixSuggestions(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected string
+	}{
+		{"class C {\n\tuint x = 1\n\tuint y;\n};", "class C {\n\tuint x = 1;\n\tuint y;\n};"},
+		{"class C {\n\tbyte<20 x;\n};", "class C {\n\tbyte<20> x;\n};"},
+		{"enum E {\n\ta = 1;\n}", "enum E {\n\ta = 1;\n};"},
+	}
+
+	for _, test := range tests {
+		analyzer := NewAnalyzer(token.NewTokenizer([]byte(test.data)), "")
+		_, err := analyzer.Analyze()
+
+		var fixErr *FixableError
+
+		if !errors.As(err, &fixErr) {
+			t.Fatalf("expected FixableError for %q, got %v", test.data, err)
+		}
+
+		fix := fixErr.Fix
+		fixed := test.data[:fix.Offset] + fix.Text + test.data[fix.Offset:]
+
+		if fixed != test.expected {
+			t.Fatalf("mismatch: got %q, but expected %q", fixed, test.expected)
+		}
+	}
+
+	analyzer := NewAnalyzer(token.NewTokenizer([]byte("class C { uint x = 1 2; };")), "")
+	_, err := analyzer.Analyze()
+
+	var fixErr *FixableError
+
+	if err == nil || errors.As(err, &fixErr) {
+		t.Fatalf("expected non-fixable error, got %v", err)
+	}
+}
+
+func TestAnalyzerStats(t *testing.T) {
+	analyzer := NewAnalyzer(token.NewTokenizer([]byte(`
+		enum E flags {
+			a = 1;
+			b = a | 2; obsolete "gone"
+			c = 4; removed "unused"
+		};
+	`)), "")
+
+	stats := NewStats()
+	analyzer.SetStats(stats)
+
+	if _, err := analyzer.Analyze(); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expected := map[string]int{
+		ProductionEnum:           1,
+		ProductionEnumFlags:      1,
+		ProductionEnumMember:     3,
+		ProductionProperty:       0,
+		ProductionDefaultOr:      1,
+		ProductionObsoleteReason: 1,
+		ProductionRemoved:        1,
+		ProductionRemovedReason:  1,
+		ProductionClass:          0,
+	}
+
+	for p, n := range expected {
+		if stats.Productions[p] != n {
+			t.Fatalf("expected production %q to be hit %d times, got %d", p, n, stats.Productions[p])
+		}
+	}
+
+	if stats.Tokens[token.OpTerminator] != 4 {
+		t.Fatalf("expected %d terminator tokens, got %d", 4, stats.Tokens[token.OpTerminator])
+	}
+
+	if stats.Coverage() >= 1 {
+		t.Fatalf("expected partial coverage, got %f", stats.Coverage())
+	}
+}
+
+func TestAnalyzerRecovery(t *testing.T) {
+	data := []byte(`
+		class A {
+			= 1;
+			uint ok;
+			byte<20 bad;
+		};
+
+		enum B < {
+			x = 1;
+		};
+
+		class C {
+			uint fine;
+		}
+
+		enum D {
+			y = ;
+		};
+	`)
+
+	analyzer := NewAnalyzer(token.NewTokenizer(data), "")
+	analyzer.SetRecovery(true)
+	root, err := analyzer.Analyze()
+
+	var list ErrorList
+
+	if !errors.As(err, &list) {
+		t.Fatalf("expected ErrorList, got %v", err)
+	}
+
+	if len(list) != 5 {
+		t.Fatalf("expected %d errors, got %d:\n%v", 5, len(list), err)
+	}
+
+	var names []string
+
+	for _, child := range root.Children() {
+		names = append(names, child.Name())
+	}
+
+	if len(names) != 4 || names[2] != "C" || names[3] != "D" {
+		t.Fatalf("expected declarations after errors to be analyzed, got %v", names)
+	}
+
+	analyzer = NewAnalyzer(token.NewTokenizer(data), "")
+	_, err = analyzer.Analyze()
+
+	if errors.As(err, &list) {
+		t.Fatalf("expected a single error without recovery, got %v", err)
+	}
+}
+
+func TestAnalyzerParseError(t *testing.T) {
+	tests := []struct {
+		data    string
+		code    ErrorCode
+		row     int
+		message string
+	}{
+		{"class C {\n\tuint x = ;\n};", ErrorUnexpectedToken, 2, "test.steamd:2:11: Unexpected token \";\""},
+		{"class C {", ErrorUnexpectedEOF, 0, "test.steamd:EOF"},
+		{"\n\nconst", ErrorInvalidToken, 3, "test.steamd:3:1: Invalid token \"const\""},
+		{"#import \"missing.steamd\"", ErrorImport, 1, ""},
+		{"enum E {\n\ta = 1;\n\ta = 2;\n};", ErrorDuplicateSymbol, 3, ""},
+		{"class C {\n\tuint x = Missing::Value;\n};", ErrorUnresolvedSymbol, 2, ""},
+		{"enum E {\n\ta = 0x10000000000000000;\n};", ErrorInvalidToken, 2, ""},
+		{"enum E<byte> {\n\ta = 256;\n};", ErrorInvalidValue, 2, "test.steamd:2:2: Value 256 of E::a overflows byte"},
+		{"enum E<byte> {\n\ta = 0xFE;\n\tb;\n\tc;\n};", ErrorInvalidValue, 4, "test.steamd:4:2: Implicit value of E::c overflows byte"},
+		{"enum E<ulong> {\n\ta = 0xFFFFFFFFFFFFFFFF;\n\tb;\n\tc = 0;\n};", ErrorInvalidValue, 3, "test.steamd:3:2: Implicit value of E::b overflows ulong"},
+	}
+
+	for _, test := range tests {
+		analyzer := NewAnalyzer(token.NewTokenizer([]byte(test.data)), "test.steamd")
+		_, err := analyzer.Analyze()
+
+		var parseErr *ParseError
+
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("expected ParseError for %q, got %v", test.data, err)
+		}
+
+		if parseErr.Code != test.code {
+			t.Fatalf("mismatch: got code %s, but expected %s", parseErr.Code, test.code)
+		}
+
+		if parseErr.Row != test.row {
+			t.Fatalf("mismatch: got row %d, but expected %d", parseErr.Row, test.row)
+		}
+
+		if test.message != "" && err.Error() != test.message {
+			t.Fatalf("mismatch: got %q, but expected %q", err.Error(), test.message)
+		}
+
+		if test.code != ErrorUnexpectedEOF && parseErr.Token == nil {
+			t.Fatalf("expected offending token to be set")
+		}
+	}
+}
+
+func TestAnalyzerStrict(t *testing.T) {
+	valid := `
+		enum EResult<int> {
+			Invalid = 0;
+			OK = 1;
+		};
+
+		class Msg<EResult::OK> {
+			const uint VERSION = 0x10;
+			uint version = Msg::VERSION;
+			EResult result = EResult::Invalid | EResult::OK;
+			byte<20> key;
+			steamidmarshal ulong steamID;
+			boolmarshal byte valid = 1;
+			ulong jobID = ulong.MaxValue;
+		};
+	`
+
+	analyzer := NewAnalyzer(token.NewTokenizer([]byte(valid)), "")
+	analyzer.SetStrict(true)
+
+	if _, err := analyzer.Analyze(); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	invalid := []struct {
+		data   string
+		strict bool
+	}{
+		{"enum E<int> { a = 1; b = c; };", true},
+		{"enum E<integer> { a = 1; };", true},
+		{"class C<EMsg::Missing> { uint a; };", false},
+		{"class C { EResult result; };", true},
+		{"class C { byte<size> data; };", true},
+		{"class C { ulong a = ulong.Max; };", true},
+	}
+
+	for _, test := range invalid {
+		data := test.data
+		analyzer := NewAnalyzer(token.NewTokenizer([]byte(data)), "")
+		analyzer.SetStrict(true)
+		_, err := analyzer.Analyze()
+
+		var parseErr *ParseError
+
+		if !errors.As(err, &parseErr) || parseErr.Code != ErrorUnresolvedSymbol {
+			t.Fatalf("expected unresolved symbol error for %q, got %v", data, err)
+		}
+
+		if !test.strict {
+			continue
+		}
+
+		analyzer = NewAnalyzer(token.NewTokenizer([]byte(data)), "")
+
+		if _, err := analyzer.Analyze(); err != nil {
+			t.Fatalf("not expected error without strict mode for %q: %v", data, err)
+		}
+	}
+}
+
+func TestAnalyzerForwardReferences(t *testing.T) {
+	fsys := fstest.MapFS{
+		"emsg.steamd": {Data: []byte("enum EMsg { Invalid = 0; Multi = 1; };\n")},
+		"main.steamd": {Data: []byte(`class Msg<EMsg::Multi> {
+	EResult result = EResult::OK;
+	Header header;
+	byte<Header::SIZE> key;
+};
+
+enum EResult<int> {
+	OK = Fail + 1;
+	Fail = 1;
+};
+
+class Header {
+	const uint SIZE = 20;
+};
+
+#import "emsg.steamd"
+`)},
+	}
+
+	for _, strict := range []bool{false, true} {
+		analyzer := NewAnalyzer(token.NewTokenizer(fsys["main.steamd"].Data), "main.steamd")
+		analyzer.SetFS(fsys)
+		analyzer.SetStrict(strict)
+		doc, err := analyzer.Analyze()
+
+		if err != nil {
+			t.Fatalf("not expected error with strict %v: %v", strict, err)
+		}
+
+		if err := ast.Evaluate(doc); err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		msg := doc.Classes()[0]
+		props := msg.Properties()
+
+		if msg.Qualifier.Symbol == nil || msg.Qualifier.Symbol.Node.Name() != "Multi" {
+			t.Fatalf("mismatch: got qualifier %+v, but expected EMsg::Multi", msg.Qualifier)
+		}
+
+		if _, ok := props[0].Type.Node.(*ast.EnumNode); !ok {
+			t.Fatalf("mismatch: got type %+v, but expected the enum EResult", props[0].Type)
+		}
+
+		if _, ok := props[1].Type.Node.(*ast.ClassNode); !ok {
+			t.Fatalf("mismatch: got type %+v, but expected the class Header", props[1].Type)
+		}
+
+		if sym := props[2].Qualifier.Symbol; sym == nil || sym.Node.Name() != "SIZE" {
+			t.Fatalf("mismatch: got qualifier %+v, but expected Header::SIZE", props[2].Qualifier)
+		}
+
+		if v, ok := doc.Enums()[0].Members()[0].ResolvedValue(); !ok || v != 2 {
+			t.Fatalf("mismatch: got %d, but expected EResult::OK to be 2", v)
+		}
+	}
+}
+
+func TestAnalyzerNamespacedPaths(t *testing.T) {
+	root := analyzeString(t, "class A { const uint B = 1; };\nclass D<A::B::C> { uint x = A::B::C + A::B; };\n")
+	class := root.Classes()[1]
+
+	if path := strings.Join(class.Qualifier.Path, "::"); path != "A::B::C" {
+		t.Fatalf("mismatch: got qualifier %s, but expected A::B::C", path)
+	}
+
+	expr, ok := class.Properties()[0].Expr.(*ast.BinaryExpr)
+
+	if !ok {
+		t.Fatalf("mismatch: got %#v, but expected a binary expression", class.Properties()[0].Expr)
+	}
+
+	if x, ok := expr.X.(*ast.SymbolExpr); !ok || len(x.Path) != 3 || x.Symbol == nil || x.Symbol.Value != "C" {
+		t.Fatalf("mismatch: got %#v, but expected A::B::C", expr.X)
+	}
+
+	if y, ok := expr.Y.(*ast.SymbolExpr); !ok || y.Symbol == nil || y.Symbol.Node.Name() != "B" {
+		t.Fatalf("mismatch: got %#v, but expected A::B", expr.Y)
+	}
+}
+
+func TestAnalyzerNamespaces(t *testing.T) {
+	data := `
+		namespace Steam {
+			enum EMsg { Multi = 1; };
+			class MsgHdr<EMsg::Multi> { EMsg msg = EMsg::Multi; };
+
+			namespace GC {
+				class MsgGCHdr<Steam::EMsg::Multi> { EMsg msg; };
+			};
+		}
+
+		class Msg<Steam::GC::MsgGCHdr> { uint x = Steam::EMsg::Multi; };
+	`
+
+	analyzer := NewAnalyzer(token.NewTokenizer([]byte(data)), "")
+	analyzer.SetStrict(true)
+	root, err := analyzer.Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	var names []string
+
+	for _, decl := range root.Declarations {
+		names = append(names, strings.Join(append(ast.NamespacePath(decl), decl.Name()), "::"))
+	}
+
+	if expected := "Steam Steam::EMsg Steam::MsgHdr Steam::GC Steam::GC::MsgGCHdr Msg"; strings.Join(names, " ") != expected {
+		t.Fatalf("mismatch: got %v, but expected %s", names, expected)
+	}
+
+	for _, name := range []string{"EMsg", "MsgHdr", "GC"} {
+		if sym := root.FindSymbol(name, false); sym != nil {
+			t.Fatalf("expected %s to be scoped by its namespace, got %v", name, sym)
+		}
+	}
+
+	if types := root.Types(); len(types) != 4 {
+		t.Fatalf("mismatch: got %d types, but expected 4", len(types))
+	}
+
+	classes := root.Classes()
+	enum := root.Enums()[0]
+
+	if prop := classes[1].Properties()[0]; prop.Type.Node != enum {
+		t.Fatalf("mismatch: got %v, but expected %v", prop.Type.Node, enum)
+	}
+
+	if q := classes[2].Qualifier; q.Symbol == nil || q.Symbol.Node != classes[1] {
+		t.Fatalf("mismatch: got %v, but expected %v", q.Symbol, classes[1])
+	}
+
+	invalid := []struct {
+		data string
+		code ErrorCode
+	}{
+		{"namespace A { enum E { X = 1; }; }\nclass C<E::X> { };", ErrorUnresolvedSymbol},
+		{"namespace A { }\nnamespace A { }", ErrorDuplicateSymbol},
+		{"namespace A { uint x; }", ErrorInvalidToken},
+		{"namespace A { class C { };", ErrorUnexpectedEOF},
+	}
+
+	for _, test := range invalid {
+		analyzer := NewAnalyzer(token.NewTokenizer([]byte(test.data)), "")
+		analyzer.SetStrict(true)
+		_, err := analyzer.Analyze()
+
+		var parseErr *ParseError
+
+		if !errors.As(err, &parseErr) || parseErr.Code != test.code {
+			t.Fatalf("expected %s error for %q, got %v", test.code, test.data, err)
+		}
+	}
+}
+
+func TestAnalyzerStringEscapes(t *testing.T) {
+	root := analyzeString(t, `enum E { A = 1; obsolete "use \"B\"\nfor both" };`)
+
+	if reason := root.Enums()[0].Members()[0].ObsoleteReason; reason != "use \"B\"\nfor both" {
+		t.Fatalf("mismatch: got %q, but expected the unescaped reason", reason)
+	}
+
+	analyzer := NewAnalyzer(token.NewTokenizer([]byte("enum E {\n\tA = 1; obsolete \"bad \\q\"\n};")), "")
+	_, err := analyzer.Analyze()
+
+	var parseErr *ParseError
+
+	if !errors.As(err, &parseErr) || parseErr.Code != ErrorInvalidToken || parseErr.Row != 2 || parseErr.Col != 18 {
+		t.Fatalf("expected %s error at 2:18, got %v", ErrorInvalidToken, err)
+	}
+}
+
+func TestAnalyzerUnterminatedStrings(t *testing.T) {
+	analyzer := NewAnalyzer(token.NewTokenizer([]byte("enum E {\n\tA = 1; obsolete \"no closing quote\n\tB = 2;\n};\n\"top")), "")
+	analyzer.SetRecovery(true)
+	_, err := analyzer.Analyze()
+
+	var positions []string
+
+	for _, diag := range Diagnose(err, SeverityError) {
+		positions = append(positions, fmt.Sprintf("%d:%d %s", diag.Pos.Row, diag.Pos.Col, diag.Code))
+	}
+
+	if expected := "2:18 invalid-token, 5:1 invalid-token"; strings.Join(positions, ", ") != expected {
+		t.Fatalf("mismatch: got %v, but expected %s", positions, expected)
+	}
+}
+
+func TestAnalyzerBlockComments(t *testing.T) {
+	root := analyzeString(t, "/* header\n   spanning lines */\nclass C {\n\tuint a; /* first */\n\tuint /* inline */ b;\n};")
+
+	if fields := root.Classes()[0].Fields(); len(fields) != 2 || fields[0].Comment != "first" || fields[1].Name() != "b" {
+		t.Fatalf("mismatch: got %v, but expected fields a and b, commented first", fields)
+	}
+
+	_, err := NewAnalyzer(token.NewTokenizer([]byte("class C {\n\tuint a; /* open\n};")), "").Analyze()
+
+	var parseErr *ParseError
+
+	if !errors.As(err, &parseErr) || parseErr.Code != ErrorUnexpectedEOF || parseErr.Row != 2 || parseErr.Col != 10 {
+		t.Fatalf("expected %s error at 2:10, got %v", ErrorUnexpectedEOF, err)
+	}
+}
+
+func TestAnalyzerTypedefs(t *testing.T) {
+	data := `
+		class MsgJob { SourceJobID source; JobID target = 1; };
+		typedef SourceJobID = JobID;
+		typedef JobID = ulong; // job identifier
+	`
+
+	for _, strict := range []bool{false, true} {
+		analyzer := NewAnalyzer(token.NewTokenizer([]byte(data)), "")
+		analyzer.SetStrict(strict)
+		root, err := analyzer.Analyze()
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		props := root.Classes()[0].Properties()
+		typedef := root.Declarations[2].(*ast.TypedefNode)
+
+		if typedef.Type.Value != "ulong" || typedef.Comment != "job identifier" {
+			t.Fatalf("mismatch: got %s %q, but expected ulong \"job identifier\"", typedef.Type.Value, typedef.Comment)
+		}
+
+		for i, alias := range []string{"SourceJobID", "JobID"} {
+			if props[i].Alias == nil || props[i].Alias.Value != alias || props[i].Type.Value != "ulong" {
+				t.Fatalf("mismatch: got %v %v, but expected %s aliasing ulong", props[i].Alias, props[i].Type, alias)
+			}
+		}
+	}
+
+	invalid := []struct {
+		data string
+		code ErrorCode
+	}{
+		{"typedef A = B;\ntypedef B = A;", ErrorInvalidValue},
+		{"typedef A = Missing;", ErrorUnresolvedSymbol},
+		{"typedef A = ulong;\ntypedef A = uint;", ErrorDuplicateSymbol},
+		{"typedef A ulong;", ErrorUnexpectedToken},
+	}
+
+	for _, test := range invalid {
+		analyzer := NewAnalyzer(token.NewTokenizer([]byte(test.data)), "")
+		analyzer.SetStrict(true)
+		_, err := analyzer.Analyze()
+
+		var parseErr *ParseError
+
+		if !errors.As(err, &parseErr) || parseErr.Code != test.code {
+			t.Fatalf("expected %s error for %q, got %v", test.code, test.data, err)
+		}
+	}
+
+	// names that fail to resolve aren't checked afterwards
+	analyzer := NewAnalyzer(token.NewTokenizer([]byte("typedef A = Missing;\nclass C { A a; uint x if:Missing::B; };")), "")
+	analyzer.SetStrict(true)
+	analyzer.SetRecovery(true)
+	_, err := analyzer.Analyze()
+
+	var codes []string
+
+	for _, diag := range Diagnose(err, SeverityError) {
+		codes = append(codes, fmt.Sprintf("%d:%d %s", diag.Pos.Row, diag.Pos.Col, diag.Code))
+	}
+
+	if expected := "1:13 unresolved-symbol, 2:26 unresolved-symbol"; strings.Join(codes, ", ") != expected {
+		t.Fatalf("mismatch: got %v, but expected %s", codes, expected)
+	}
+}
+
+func TestAnalyzerNestedDeclarations(t *testing.T) {
+	data := `
+		class Outer {
+			const uint SIZE = 2;
+			enum EKind<byte> { A = 1; };
+			class Inner { EKind kind = EKind::A; byte<SIZE> data; };
+			Inner inner;
+		};
+
+		class Other { uint kind = Outer::EKind::A; };
+	`
+
+	analyzer := NewAnalyzer(token.NewTokenizer([]byte(data)), "")
+	analyzer.SetStrict(true)
+	root, err := analyzer.Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	var names []string
+
+	for _, decl := range root.Types() {
+		names = append(names, strings.Join(append(ast.ScopePath(decl), decl.Name()), "::"))
+	}
+
+	if expected := "Outer Outer::EKind Outer::Inner Other"; strings.Join(names, " ") != expected {
+		t.Fatalf("mismatch: got %v, but expected %s", names, expected)
+	}
+
+	if sym := root.FindSymbol("Inner", false); sym != nil {
+		t.Fatalf("expected Inner to be scoped by its class, got %v", sym)
+	}
+
+	outer, inner := root.Classes()[0], root.Classes()[1]
+
+	if props := outer.Properties(); len(props) != 1 || props[0].Type.Node != inner {
+		t.Fatalf("mismatch: got %v, but expected a single field of type Inner", props)
+	}
+
+	if prop := inner.Properties()[0]; prop.Type.Node != root.Enums()[0] {
+		t.Fatalf("mismatch: got %v, but expected %v", prop.Type.Node, root.Enums()[0])
+	}
+
+	invalid := []struct {
+		data string
+		code ErrorCode
+	}{
+		{"class A { class B {}; uint B; };", ErrorDuplicateSymbol},
+		{"class A { namespace B {} };", ErrorUnexpectedToken},
+		{"enum E { class B {}; };", ErrorUnexpectedToken},
+	}
+
+	for _, test := range invalid {
+		analyzer := NewAnalyzer(token.NewTokenizer([]byte(test.data)), "")
+		analyzer.SetStrict(true)
+		_, err := analyzer.Analyze()
+
+		var parseErr *ParseError
+
+		if !errors.As(err, &parseErr) || parseErr.Code != test.code {
+			t.Fatalf("expected %s error for %q, got %v", test.code, test.data, err)
+		}
+	}
+}
+
+func TestAnalyzerBaseClasses(t *testing.T) {
+	data := `
+		class MsgBar : Foo { uint bar; };
+		typedef Foo = MsgFoo;
+		class MsgFoo<EMsg::Foo> : Steam::MsgHdr { uint foo; };
+		namespace Steam { class MsgHdr { EMsg msg; ulong jobID; }; }
+		enum EMsg { Foo = 1; };
+	`
+
+	for _, strict := range []bool{false, true} {
+		analyzer := NewAnalyzer(token.NewTokenizer([]byte(data)), "")
+		analyzer.SetStrict(strict)
+		root, err := analyzer.Analyze()
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		class := root.Declarations[0].(*ast.ClassNode)
+
+		if base := class.BaseClass(); base == nil || base.Name() != "MsgFoo" {
+			t.Fatalf("mismatch: got %v, but expected MsgFoo", base)
+		}
+
+		var names []string
+
+		for _, f := range class.Fields() {
+			names = append(names, f.Name())
+		}
+
+		if strings.Join(names, " ") != "msg jobID foo bar" {
+			t.Fatalf("mismatch: got %v, but expected [msg jobID foo bar]", names)
+		}
+	}
+
+	invalid := []struct {
+		data string
+		code ErrorCode
+	}{
+		{"class A : B {};\nclass B : A {};", ErrorInvalidValue},
+		{"class A : E {};\nenum E { X = 1; };", ErrorInvalidValue},
+		{"class A : Missing {};", ErrorUnresolvedSymbol},
+		{"class A { uint a; };\nclass B : A { uint a; };", ErrorDuplicateSymbol},
+		{"class A : {};", ErrorUnexpectedToken},
+	}
+
+	for _, test := range invalid {
+		analyzer := NewAnalyzer(token.NewTokenizer([]byte(test.data)), "")
+		analyzer.SetStrict(true)
+		_, err := analyzer.Analyze()
+
+		var parseErr *ParseError
+
+		if !errors.As(err, &parseErr) || parseErr.Code != test.code {
+			t.Fatalf("expected %s error for %q, got %v", test.code, test.data, err)
+		}
+	}
+}
+
+func TestAnalyzerOptionalFields(t *testing.T) {
+	root := analyzeString(t, `
+		class MsgHdr { uint msg; optional ulong jobID; };
+		class MsgFoo { uint id; optional steamidmarshal ulong steamID; optional uint<2> extra; };
+	`)
+
+	var optional []string
+
+	for _, f := range root.Classes()[1].Fields() {
+		optional = append(optional, fmt.Sprint(f.Optional))
+	}
+
+	if expected := "false true true"; strings.Join(optional, " ") != expected {
+		t.Fatalf("mismatch: got %v, but expected %s", optional, expected)
+	}
+
+	if f := root.Classes()[1].Fields()[1]; f.Flags != ast.PropertyFlagSteamIDMarshal || f.Type.Value != "ulong" {
+		t.Fatalf("mismatch: got %s %v, but expected steamidmarshal ulong", f.Flags, f.Type)
+	}
+
+	invalid := []struct {
+		data string
+		code ErrorCode
+	}{
+		{"class A { optional uint a; uint b; };", ErrorInvalidValue},
+		{"class A { optional uint a; };\nclass B : A { uint b; };", ErrorInvalidValue},
+		{"class A { optional uint a = 1; };", ErrorInvalidValue},
+		{"class A { optional const uint A = 1; };", ErrorInvalidValue},
+	}
+
+	for _, test := range invalid {
+		analyzer := NewAnalyzer(token.NewTokenizer([]byte(test.data)), "")
+		analyzer.SetStrict(true)
+		_, err := analyzer.Analyze()
+
+		var parseErr *ParseError
+
+		if !errors.As(err, &parseErr) || parseErr.Code != test.code {
+			t.Fatalf("expected %s error for %q, got %v", test.code, test.data, err)
+		}
+	}
+}
+
+func TestAnalyzerUnions(t *testing.T) {
+	root := analyzeString(t, `
+		enum EMsg { Logon = 1; Logoff = 2; };
+		typedef Logon = MsgLogon;
+		class MsgLogon { uint version; };
+		class MsgLogoff { uint reason; };
+		union Body<EMsg> { Logon Logon; MsgLogoff Logoff; };
+		class MsgJob { ulong jobID; Body body; };
+	`)
+
+	union, ok := root.Types()[4].(*ast.UnionNode)
+
+	if !ok {
+		t.Fatalf("expected *ast.UnionNode, got %T", root.Types()[4])
+	}
+
+	if enum := union.Discriminator(); enum == nil || enum.Name() != "EMsg" {
+		t.Fatalf("mismatch: got %v, but expected EMsg", enum)
+	}
+
+	var variants []string
+
+	for _, v := range union.Variants() {
+		variants = append(variants, v.Discriminator().Name()+"="+v.Class().Name())
+	}
+
+	if expected := "Logon=MsgLogon Logoff=MsgLogoff"; strings.Join(variants, " ") != expected {
+		t.Fatalf("mismatch: got %v, but expected %s", variants, expected)
+	}
+
+	if err := ast.Evaluate(root); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if v := union.Variant(2); v == nil || v.Name() != "Logoff" {
+		t.Fatalf("mismatch: got %v, but expected Logoff", v)
+	}
+
+	if f := root.Classes()[2].Fields()[1]; f.Type.Node != union {
+		t.Fatalf("mismatch: got %v, but expected Body", f.Type)
+	}
+
+	invalid := []struct {
+		data string
+		code ErrorCode
+	}{
+		{"class A {};\nunion U { A B; };", ErrorInvalidValue},
+		{"class A {};\nunion U<A> { A B; };", ErrorInvalidValue},
+		{"enum E { A; };\nenum F { B; };\nunion U<E> { F A; };", ErrorInvalidValue},
+		{"enum E { A; B; };\nclass C {};\nunion U<E> { C A; C B; };", ErrorDuplicateSymbol},
+		{"enum E { A; };\nclass C {};\nunion U<E> { C B; };", ErrorUnresolvedSymbol},
+		{"enum E { A; };\nunion U<E> { uint A; };", ErrorInvalidValue},
+		{"class C {};\nunion U<uint> { C A; };", ErrorUnresolvedSymbol},
+		{"enum E { A; };\nclass C {};\nclass D {};\nunion U<E> { C A; D A; };", ErrorDuplicateSymbol},
+	}
+
+	for _, test := range invalid {
+		analyzer := NewAnalyzer(token.NewTokenizer([]byte(test.data)), "")
+		analyzer.SetStrict(true)
+		_, err := analyzer.Analyze()
+
+		var parseErr *ParseError
+
+		if !errors.As(err, &parseErr) || parseErr.Code != test.code {
+			t.Fatalf("expected %s error for %q, got %v", test.code, test.data, err)
+		}
+	}
+}
+
+func TestAnalyzerConditionalFields(t *testing.T) {
+	root := analyzeString(t, `
+		enum EFlags flags { HasExtra = 1; HasID = 2; };
+		class MsgFoo {
+			EFlags flags;
+			uint extra if:EFlags::HasExtra;
+			steamidmarshal ulong steamID if:EFlags::HasID = 0;
+		};
+	`)
+
+	fields := root.Classes()[0].Fields()
+	var conditions []string
+
+	for _, f := range fields[1:] {
+		conditions = append(conditions, f.ConditionMember().Name()+"@"+f.ConditionField().Name())
+	}
+
+	if expected := "HasExtra@flags HasID@flags"; strings.Join(conditions, " ") != expected {
+		t.Fatalf("mismatch: got %v, but expected %s", conditions, expected)
+	}
+
+	if f := fields[2]; f.Flags != ast.PropertyFlagSteamIDMarshal || len(f.Default) != 1 {
+		t.Fatalf("mismatch: got %s %v, but expected steamidmarshal with a default", f.Flags, f.Default)
+	}
+
+	if fields[0].Condition != nil || fields[0].ConditionField() != nil {
+		t.Fatalf("expected unconditional field %s", fields[0].Name())
+	}
+
+	invalid := []struct {
+		data string
+		code ErrorCode
+	}{
+		{"enum E { A = 1; };\nclass C { E e; uint a if:E::A; };", ErrorInvalidValue},
+		{"enum E flags { A = 1; };\nclass C { uint a if:E::A; E e; };", ErrorUnresolvedSymbol},
+		{"enum E flags { A = 1; };\nclass C { E e; uint a if:E::B; };", ErrorUnresolvedSymbol},
+		{"enum E flags { A = 1; };\nclass C { E e; optional uint a if:E::A; };", ErrorInvalidValue},
+		{"enum E flags { A = 1; };\nclass C { E e; const uint A if:E::A = 1; };", ErrorInvalidValue},
+		{"enum E flags { A = 1; };\nclass C { E e; uint a if E::A; };", ErrorInvalidToken},
+	}
+
+	for _, test := range invalid {
+		analyzer := NewAnalyzer(token.NewTokenizer([]byte(test.data)), "")
+		analyzer.SetStrict(true)
+		_, err := analyzer.Analyze()
+
+		var parseErr *ParseError
+
+		if !errors.As(err, &parseErr) || parseErr.Code != test.code {
+			t.Fatalf("expected %s error for %q, got %v", test.code, test.data, err)
+		}
+	}
+}
+
+func TestAnalyzerEndian(t *testing.T) {
+	root := analyzeString(t, `
+		enum EFlags flags { HasPort = 1; };
+		typedef Port = ushort;
+		class MsgFoo {
+			EFlags flags endian:be;
+			Port port if:EFlags::HasPort endian:be;
+			uint<2> ids endian:le;
+			ulong id;
+		};
+	`)
+
+	var orders []string
+
+	for _, f := range root.Classes()[0].Fields() {
+		orders = append(orders, f.Endian.String())
+	}
+
+	if expected := "be be le le"; strings.Join(orders, " ") != expected {
+		t.Fatalf("mismatch: got %v, but expected %s", orders, expected)
+	}
+
+	invalid := []struct {
+		data string
+		code ErrorCode
+	}{
+		{"class C { uint a endian:xx; };", ErrorInvalidValue},
+		{"class C { uint a endian:; };", ErrorUnexpectedToken},
+		{"class C { const uint A endian:be = 1; };", ErrorInvalidValue},
+		{"class D { uint a; };\nclass C { D d endian:be; };", ErrorInvalidValue},
+		{"class C { string<4> s endian:be; };", ErrorInvalidValue},
+		{"class C { uint a endian:be endian:le; };", ErrorInvalidValue},
+		{"enum E flags { A = 1; };\nclass C { E e; uint a if:E::A if:E::A; };", ErrorInvalidValue},
+	}
+
+	for _, test := range invalid {
+		analyzer := NewAnalyzer(token.NewTokenizer([]byte(test.data)), "")
+		analyzer.SetStrict(true)
+		_, err := analyzer.Analyze()
+
+		var parseErr *ParseError
+
+		if !errors.As(err, &parseErr) || parseErr.Code != test.code {
+			t.Fatalf("expected %s error for %q, got %v", test.code, test.data, err)
+		}
+	}
+}
+
+func TestAnalyzerStrings(t *testing.T) {
+	root := analyzeString(t, `
+		typedef Text = string;
+		class MsgFoo {
+			ushort size;
+			string<size> wide encoding:utf16 term:length endian:be;
+			Text name term:null;
+			string title encoding:utf8;
+		};
+	`)
+
+	var annotations []string
+
+	for _, f := range root.Classes()[0].Fields()[1:] {
+		annotations = append(annotations, fmt.Sprintf("%s:%s:%s", f.Encoding, f.Termination, f.Endian))
+	}
+
+	if expected := "utf16:length:be utf8:null:le utf8:length:le"; strings.Join(annotations, " ") != expected {
+		t.Fatalf("mismatch: got %v, but expected %s", annotations, expected)
+	}
+
+	invalid := []struct {
+		data string
+		code ErrorCode
+	}{
+		{"class C { string s encoding:ascii; };", ErrorInvalidValue},
+		{"class C { string s term:eof; };", ErrorInvalidValue},
+		{"class C { uint a term:null; };", ErrorInvalidValue},
+		{"class C { const uint A encoding:utf16 = 1; };", ErrorInvalidValue},
+		{"class C { string<4> s term:null; };", ErrorInvalidValue},
+		{"class C { string s term:length; };", ErrorInvalidValue},
+		{"class C { string s term:null endian:be; };", ErrorInvalidValue},
+		{"class C { string s term:null term:null; };", ErrorInvalidValue},
+	}
+
+	for _, test := range invalid {
+		analyzer := NewAnalyzer(token.NewTokenizer([]byte(test.data)), "")
+		analyzer.SetStrict(true)
+		_, err := analyzer.Analyze()
+
+		var parseErr *ParseError
+
+		if !errors.As(err, &parseErr) || parseErr.Code != test.code {
+			t.Fatalf("expected %s error for %q, got %v", test.code, test.data, err)
+		}
+	}
+}
+
+func TestAnalyzerArrays(t *testing.T) {
+	root := analyzeString(t, `
+		enum ESize { Small = 2; };
+		class Item { uint id; };
+		class MsgFoo {
+			const uint MAX = 3;
+			uint<4> ids;
+			Item<MAX> items;
+			ushort<ESize::Small> ports;
+			ulong id;
+		};
+	`)
+
+	var sizes []string
+
+	for _, f := range root.Classes()[1].Fields() {
+		size, ok, err := f.ArrayLen()
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		sizes = append(sizes, fmt.Sprintf("%s:%d:%v", f.Type.Value, size, ok))
+	}
+
+	if expected := "uint:4:true Item:3:true ushort:2:true ulong:0:false"; strings.Join(sizes, " ") != expected {
+		t.Fatalf("mismatch: got %v, but expected %s", sizes, expected)
+	}
+
+	invalid := []struct {
+		data string
+		code ErrorCode
+	}{
+		{"class C { uint<99999999999> a; };", ErrorInvalidValue},
+		{"class D {};\nclass C { uint<D> a; };", ErrorInvalidValue},
+		{"enum E { A; };\nclass D {};\nunion U<E> { D A; };\nclass C { U<2> a; };", ErrorInvalidValue},
+	}
+
+	for _, test := range invalid {
+		analyzer := NewAnalyzer(token.NewTokenizer([]byte(test.data)), "")
+		analyzer.SetStrict(true)
+		_, err := analyzer.Analyze()
+
+		var parseErr *ParseError
+
+		if !errors.As(err, &parseErr) || parseErr.Code != test.code {
+			t.Fatalf("expected %s error for %q, got %v", test.code, test.data, err)
+		}
+	}
+}
+
+func TestAnalyzerLengthFields(t *testing.T) {
+	root := analyzeString(t, `
+		typedef Count = ushort;
+		class MsgFoo {
+			const uint SIZE = 4;
+			Count count;
+			byte<count> data;
+			uint<SIZE> fixed;
+			int length;
+			string<length> name;
+		};
+	`)
+
+	fields := root.Classes()[0].Fields()
+
+	if f := fields[1].LengthField(); f != fields[0] {
+		t.Fatalf("mismatch: got %v, but expected %s", f, fields[0].Name())
+	}
+
+	if f := fields[0].LengthOf(); f != fields[1] {
+		t.Fatalf("mismatch: got %v, but expected %s", f, fields[1].Name())
+	}
+
+	if f := fields[4].LengthField(); f != fields[3] {
+		t.Fatalf("mismatch: got %v, but expected %s", f, fields[3].Name())
+	}
+
+	if fields[2].LengthField() != nil || fields[2].LengthOf() != nil {
+		t.Fatalf("expected fixed size field %s", fields[2].Name())
+	}
+
+	invalid := []struct {
+		data string
+		code ErrorCode
+	}{
+		{"class C { byte<n> data; byte n; };", ErrorInvalidValue},
+		{"class C { float n; byte<n> data; };", ErrorInvalidValue},
+		{"class C { steamidmarshal ulong n; byte<n> data; };", ErrorInvalidValue},
+		{"class C { byte n; optional byte<n> data; };", ErrorInvalidValue},
+		{"class C { byte n; byte<n> a; byte<n> b; };", ErrorInvalidValue},
+		{"enum E flags { A = 1; };\nclass C { E e; byte n if:E::A; byte<n> data; };", ErrorInvalidValue},
+		{"class C { byte n; class D { byte<n> data; }; };", ErrorInvalidValue},
+		{"class C { byte<m> data; };", ErrorUnresolvedSymbol},
+	}
+
+	for _, test := range invalid {
+		analyzer := NewAnalyzer(token.NewTokenizer([]byte(test.data)), "")
+		analyzer.SetStrict(true)
+		_, err := analyzer.Analyze()
+
+		var parseErr *ParseError
+
+		if !errors.As(err, &parseErr) || parseErr.Code != test.code {
+			t.Fatalf("expected %s error for %q, got %v", test.code, test.data, err)
+		}
+	}
+}
+
+func TestAnalyzerPropertyFlags(t *testing.T) {
+	root := analyzeString(t, `
+		class C {
+			const uint VERSION = 1;
+			steamidmarshal ulong steamID;
+			gameidmarshal ulong gameID;
+			boolmarshal byte valid;
+			protomask uint msg;
+			uint plain;
+		};
+	`)
+
+	expected := []ast.PropertyFlag{
+		ast.PropertyFlagConst,
+		ast.PropertyFlagSteamIDMarshal,
+		ast.PropertyFlagGameIDMarshal,
+		ast.PropertyFlagBoolMarshal,
+		ast.PropertyFlagProtoMask,
+		ast.PropertyFlagNone,
+	}
+
+	for i, child := range root.Children()[0].Children() {
+		if flags := child.(*ast.PropertyNode).Flags; flags != expected[i] {
+			t.Fatalf("mismatch: got %q, but expected %q", flags, expected[i])
+		}
+	}
+
+	invalid := []struct {
+		data   string
+		strict bool
+		code   ErrorCode
+	}{
+		{"class C { volatile uint a; };", false, ErrorInvalidToken},
+		{"class C { steamidmarshal uint a; };", true, ErrorInvalidValue},
+		{"class C { boolmarshal int a; };", true, ErrorInvalidValue},
+	}
+
+	for _, test := range invalid {
+		analyzer := NewAnalyzer(token.NewTokenizer([]byte(test.data)), "")
+		analyzer.SetStrict(test.strict)
+		_, err := analyzer.Analyze()
+
+		var parseErr *ParseError
+
+		if !errors.As(err, &parseErr) || parseErr.Code != test.code {
+			t.Fatalf("expected %s error for %q, got %v", test.code, test.data, err)
+		}
+	}
+}
+
+func TestAnalyzerDocument(t *testing.T) {
+	dir, err := ioutil.TempDir("", "steamd")
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"base.steamd": "enum EResult { OK = 1; };",
+		"main.steamd": "#import \"base.steamd\"\n\nclass C { EResult r; };\nenum E { a = 1; };\n",
+	}
+
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+	}
+
+	filename := filepath.Join(dir, "main.steamd")
+	data := []byte(files["main.steamd"])
+	doc, err := NewAnalyzer(token.NewTokenizer(data), filename).Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if doc.Filename != filename {
+		t.Fatalf("mismatch: got %q, but expected %q", doc.Filename, filename)
+	}
+
+	if doc.Size != len(data) {
+		t.Fatalf("mismatch: got size %d, but expected %d", doc.Size, len(data))
+	}
+
+	if len(doc.Imports) != 1 || doc.Imports[0].Path != "base.steamd" || doc.Imports[0].Document == nil {
+		t.Fatalf("mismatch: got imports %v", doc.Imports)
+	}
+
+	if enums := doc.Imports[0].Document.Enums(); len(enums) != 1 || enums[0].Name() != "EResult" {
+		t.Fatalf("mismatch: got imported enums %v", enums)
+	}
+
+	if classes := doc.Classes(); len(classes) != 1 || classes[0].Name() != "C" {
+		t.Fatalf("mismatch: got classes %v", classes)
+	}
+
+	if enums := doc.Enums(); len(enums) != 1 || enums[0].Name() != "E" {
+		t.Fatalf("mismatch: got enums %v", enums)
+	}
+
+	if children := doc.Children(); len(children) != 3 {
+		t.Fatalf("mismatch: got %d children, but expected 3", len(children))
+	}
+}
+
+func TestAnalyzerSharedImports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "steamd")
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"enums.steamd": "enum EResult { OK = 1; };",
+		"a.steamd":     "#import \"enums.steamd\"\nclass A { EResult r; };",
+		"b.steamd":     "#import \"./enums.steamd\"\nclass B { EResult r; };",
+		"main.steamd":  "#import \"a.steamd\"\n#import \"b.steamd\"\n",
+		"cycle.steamd": "#import \"cycle.steamd\"\n",
+	}
+
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+	}
+
+	analyzer := NewAnalyzer(token.NewTokenizer([]byte(files["main.steamd"])), filepath.Join(dir, "main.steamd"))
+	doc, err := analyzer.Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if warnings := analyzer.Warnings(); len(warnings) != 0 {
+		t.Fatalf("not expected warnings %v", warnings)
+	}
+
+	a, b := doc.Imports[0].Document, doc.Imports[1].Document
+
+	if a.Imports[0].Document != b.Imports[0].Document {
+		t.Fatalf("expected enums.steamd to be analyzed once")
+	}
+
+	var names []string
+
+	for _, child := range doc.Children() {
+		names = append(names, child.Name())
+	}
+
+	if strings.Join(names, " ") != "EResult A B" {
+		t.Fatalf("mismatch: got %v, but expected [EResult A B]", names)
+	}
+
+	if b.FindSymbol("EResult", false) != a.FindSymbol("EResult", false) {
+		t.Fatalf("expected shared EResult symbol")
+	}
+
+	cycle := filepath.Join(dir, "cycle.steamd")
+	_, err = NewAnalyzer(token.NewTokenizer([]byte(files["cycle.steamd"])), cycle).Analyze()
+
+	var parseErr *ParseError
+
+	if !errors.As(err, &parseErr) || parseErr.Code != ErrorImport {
+		t.Fatalf("expected import error for cycle, got %v", err)
+	}
+}
+
+func TestAnalyzerFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"schemas/base.steamd":   {Data: []byte("enum EResult { OK = 1; };")},
+		"schemas/main.steamd":   {Data: []byte("#import \"base.steamd\"\nclass C { EResult r = EResult::OK; };")},
+		"schemas/broken.steamd": {Data: []byte("#import \"missing.steamd\"\n")},
+	}
+
+	data, _ := fs.ReadFile(fsys, "schemas/main.steamd")
+	analyzer := NewAnalyzer(token.NewTokenizer(data), "schemas/main.steamd")
+	analyzer.SetFS(fsys)
+	doc, err := analyzer.Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if imported := doc.Imports[0].Document; imported == nil || imported.Filename != "schemas/base.steamd" {
+		t.Fatalf("mismatch: got imports %v", doc.Imports)
+	}
+
+	if sym := doc.FindSymbol("EResult", false); sym == nil {
+		t.Fatalf("expected imported symbol EResult")
+	}
+
+	data, _ = fs.ReadFile(fsys, "schemas/broken.steamd")
+	analyzer = NewAnalyzer(token.NewTokenizer(data), "schemas/broken.steamd")
+	analyzer.SetFS(fsys)
+
+	if _, err := analyzer.Analyze(); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected not exist error, got %v", err)
+	}
+}
+
+func TestAnalyzerIncludePath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "steamd")
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"shared/enums.steamd":    "enum EResult { OK = 1; };",
+		"shared/steammsg.steamd": "#import \"enums.steamd\"\nclass Shared {};",
+		"local/steammsg.steamd":  "#import \"enums.steamd\"\nclass Local {};",
+		"app/main.steamd":        "#import \"steammsg.steamd\"\n",
+	}
+
+	for name, data := range files {
+		filename := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		if err := ioutil.WriteFile(filename, []byte(data), 0644); err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+	}
+
+	tests := []struct {
+		include  []string
+		expected string
+	}{
+		{[]string{"shared"}, "Shared"},
+		{[]string{"local", "shared"}, "Local"},
+		{[]string{"missing", "shared"}, "Shared"},
+	}
+
+	for _, test := range tests {
+		var include []string
+
+		for _, d := range test.include {
+			include = append(include, filepath.Join(dir, d))
+		}
+
+		analyzer := NewAnalyzer(token.NewTokenizer([]byte(files["app/main.steamd"])), filepath.Join(dir, "app", "main.steamd"))
+		analyzer.SetIncludePath(include)
+		doc, err := analyzer.Analyze()
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		if doc.FindSymbol(test.expected, false) == nil || doc.FindSymbol("EResult", false) == nil {
+			t.Fatalf("expected %s and EResult to be imported from %v", test.expected, test.include)
+		}
+	}
+
+	analyzer := NewAnalyzer(token.NewTokenizer([]byte(files["app/main.steamd"])), filepath.Join(dir, "app", "main.steamd"))
+
+	if _, err := analyzer.Analyze(); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected not exist error without include path, got %v", err)
+	}
+}
+
+func TestAnalyzerResolver(t *testing.T) {
+	sources := map[string]string{
+		"registry:base": "enum EResult { OK = 1; };",
+	}
+
+	var calls []string
+
+	resolver := ImportResolverFunc(func(fromFile, importPath string) ([]byte, string, error) {
+		calls = append(calls, fromFile+" "+importPath)
+		name := "registry:" + importPath
+		data, ok := sources[name]
+
+		if !ok {
+			return nil, "", fmt.Errorf("Schema %q not found", importPath)
+		}
+
+		return []byte(data), name, nil
+	})
+
+	data := []byte("#import \"base\"\n#import \"base\"\nclass C { EResult r; };")
+	analyzer := NewAnalyzer(token.NewTokenizer(data), "main")
+	analyzer.SetResolver(resolver)
+	doc, err := analyzer.Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if strings.Join(calls, ",") != "main base,main base" {
+		t.Fatalf("mismatch: got calls %q", calls)
+	}
+
+	if doc.Imports[0].Document == nil || doc.Imports[0].Document != doc.Imports[1].Document {
+		t.Fatalf("expected imports to share the resolved document")
+	}
+
+	if doc.Imports[0].Document.Filename != "registry:base" {
+		t.Fatalf("mismatch: got %q, but expected %q", doc.Imports[0].Document.Filename, "registry:base")
+	}
+
+	analyzer = NewAnalyzer(token.NewTokenizer([]byte("#import \"missing\"")), "main")
+	analyzer.SetResolver(resolver)
+	_, err = analyzer.Analyze()
+
+	var parseErr *ParseError
+
+	if !errors.As(err, &parseErr) || parseErr.Code != ErrorImport || parseErr.Err == nil {
+		t.Fatalf("expected import error, got %v", err)
+	}
+}
+
+func TestAnalyzerWorkers(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.steamd":   {Data: []byte("#import \"a.steamd\"\n#import \"b.steamd\"\nclass Main { EA a; EB b; ED d; };")},
+		"a.steamd":      {Data: []byte("#import \"d.steamd\"\nenum EA { X = 1; };")},
+		"b.steamd":      {Data: []byte("#import \"d.steamd\"\n#import \"c.steamd\"\nenum EB { Y = 1; };")},
+		"c.steamd":      {Data: []byte("enum EC { Z = ; };\nenum EC2 { V = 1; };")},
+		"d.steamd":      {Data: []byte("enum ED { W = 1; };")},
+		"cycle.steamd":  {Data: []byte("#import \"cycle2.steamd\"\n")},
+		"cycle2.steamd": {Data: []byte("#import \"cycle.steamd\"\n")},
+	}
+
+	analyze := func(filename string, workers int) ([]string, *Stats, error) {
+		data, _ := fs.ReadFile(fsys, filename)
+		stats := NewStats()
+		analyzer := NewAnalyzer(token.NewTokenizer(data), filename)
+		analyzer.SetFS(fsys)
+		analyzer.SetRecovery(true)
+		analyzer.SetStats(stats)
+		analyzer.SetWorkers(workers)
+		doc, err := analyzer.Analyze()
+
+		var names []string
+
+		for _, child := range doc.Children() {
+			names = append(names, child.Name())
+		}
+
+		return names, stats, err
+	}
+
+	expectedNames, expectedStats, expectedErr := analyze("main.steamd", 0)
+	names, stats, err := analyze("main.steamd", 4)
+
+	if strings.Join(names, ",") != "ED,EA,EC,EC2,EB,Main" || strings.Join(names, ",") != strings.Join(expectedNames, ",") {
+		t.Fatalf("mismatch: got %q, but expected %q", names, expectedNames)
+	}
+
+	if err == nil || err.Error() != expectedErr.Error() || !strings.Contains(err.Error(), "c.steamd") {
+		t.Fatalf("mismatch: got %v, but expected %v", err, expectedErr)
+	}
+
+	if stats.Files != 5 || stats.Files != expectedStats.Files || stats.Productions[ProductionEnum] != expectedStats.Productions[ProductionEnum] {
+		t.Fatalf("mismatch: got stats %v, but expected %v", stats, expectedStats)
+	}
+
+	_, _, expectedErr = analyze("cycle.steamd", 0)
+	_, _, err = analyze("cycle.steamd", 4)
+
+	if err == nil || err.Error() != expectedErr.Error() {
+		t.Fatalf("mismatch: got %v, but expected %v", err, expectedErr)
+	}
+}
+
+func TestAnalyzerContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	data := []byte("enum EResult { OK = 1; };")
+	_, err := NewAnalyzer(token.NewTokenizer(data), "").AnalyzeContext(ctx)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("mismatch: got %v, but expected %v", err, context.Canceled)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	resolver := ImportResolverFunc(func(fromFile, importPath string) ([]byte, string, error) {
+		cancel()
+		return []byte("#import \"next\"\nenum EResult { OK = 1; };"), importPath, nil
+	})
+
+	data = []byte("#import \"base\"\nclass C { EResult r; };")
+	analyzer := NewAnalyzer(token.NewTokenizer(data), "main")
+	analyzer.SetResolver(resolver)
+	_, err = analyzer.AnalyzeContext(ctx)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("mismatch: got %v, but expected %v", err, context.Canceled)
+	}
+}
+
+func TestAnalyzerExpressions(t *testing.T) {
+	root := analyzeString(t, `
+		enum E {
+			a = 1;
+			b = a | 0x10 + 1 << (2 + a);
+			c = E::b >> 1;
+		};
+	`)
+
+	tests := []struct {
+		name     string
+		expected string
+		operands int
+	}{
+		{"a", "1", 1},
+		{"b", "a | 0x10 + 1 << (2 + a)", 5},
+		{"c", "E::b >> 1", 2},
+	}
+
+	enum := root.Children()[0]
+
+	for i, test := range tests {
+		prop := enum.Children()[i].(*ast.EnumMemberNode)
+
+		if got := prop.Expr.String(); got != test.expected {
+			t.Fatalf("mismatch: got %q, but expected %q", got, test.expected)
+		}
+
+		if len(prop.Default) != test.operands {
+			t.Fatalf("mismatch: got %d operands, but expected %d", len(prop.Default), test.operands)
+		}
+	}
+
+	b := enum.Children()[1].(*ast.EnumMemberNode).Expr.(*ast.BinaryExpr)
+
+	if b.Op != ast.BinaryOr {
+		t.Fatalf("mismatch: got operator %s, but expected %s", b.Op, ast.BinaryOr)
+	}
+
+	shift, ok := b.Y.(*ast.BinaryExpr)
+
+	if !ok || shift.Op != ast.BinaryShl {
+		t.Fatalf("expected shift operand, got %v", b.Y)
+	}
+
+	add, ok := shift.X.(*ast.BinaryExpr)
+
+	if !ok || add.Op != ast.BinaryAdd {
+		t.Fatalf("expected addition operand, got %v", shift.X)
+	}
+
+	if lit, ok := add.X.(*ast.LiteralExpr); !ok || lit.Value != 0x10 {
+		t.Fatalf("expected literal 0x10, got %v", add.X)
+	}
+}
+
+func TestAnalyzerFileSet(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base.steamd": {Data: []byte("// résumé\nenum EResult {\n\tOK = 1;\n};\n")},
+		"main.steamd": {Data: []byte("#import \"base.steamd\"\n\nclass Msg {\n\tEResult result;\n};\n")},
+	}
+
+	cache := NewCache(t.TempDir())
+
+	for _, tc := range []struct {
+		workers int
+		cache   *Cache
+	}{{1, nil}, {4, nil}, {1, cache}, {1, cache}} {
+		analyzer := NewAnalyzer(token.NewTokenizer(fsys["main.steamd"].Data), "main.steamd")
+		analyzer.SetFS(fsys)
+		analyzer.SetWorkers(tc.workers)
+		analyzer.SetCache(tc.cache)
+		doc, err := analyzer.Analyze()
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		fset := analyzer.FileSet()
+
+		if len(fset.Files()) != 2 {
+			t.Fatalf("mismatch: got %d files, but expected 2", len(fset.Files()))
+		}
+
+		actual := []string{
+			fset.Position(doc.Imports[0].Pos).String(),
+			fset.Position(doc.Classes()[0].Pos).String(),
+			fset.Position(doc.Classes()[0].Properties()[0].Pos).String(),
+			fset.Position(doc.Imports[0].Document.Enums()[0].Pos).String(),
+			fset.Position(doc.Imports[0].Document.Enums()[0].Children()[0].(*ast.EnumMemberNode).Pos).String(),
+		}
+
+		expected := []string{"main.steamd:1:9", "main.steamd:3:7", "main.steamd:4:10", "base.steamd:2:6", "base.steamd:3:2"}
+
+		if strings.Join(actual, " ") != strings.Join(expected, " ") {
+			t.Fatalf("mismatch: got %v, but expected %v with %d workers", actual, expected, tc.workers)
+		}
+	}
+
+	fsys["base.steamd"] = &fstest.MapFile{Data: []byte("enum EResult {\n\tOK = ;\n};\n")}
+	analyzer := NewAnalyzer(token.NewTokenizer(fsys["main.steamd"].Data), "main.steamd")
+	analyzer.SetFS(fsys)
+	_, err := analyzer.Analyze()
+
+	var parseErr *ParseError
+
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected ParseError, got %v", err)
+	}
+
+	if pos := analyzer.FileSet().Position(parseErr.Pos); pos.String() != "base.steamd:2:7" {
+		t.Fatalf("mismatch: got %v, but expected base.steamd:2:7", pos)
+	}
+}
+
+func TestAnalyzerTabWidth(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base.steamd": {Data: []byte("enum EResult {\n\tOK =\t;\n};\n")},
+		"main.steamd": {Data: []byte("\t#import \"base.steamd\"\n")},
+	}
+
+	for _, workers := range []int{1, 4} {
+		tokenizer := token.NewTokenizer(fsys["main.steamd"].Data)
+		tokenizer.SetTabWidth(4)
+		analyzer := NewAnalyzer(tokenizer, "main.steamd")
+		analyzer.SetFS(fsys)
+		analyzer.SetWorkers(workers)
+		analyzer.SetCache(NewCache(t.TempDir()))
+		doc, err := analyzer.Analyze()
+
+		var parseErr *ParseError
+
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("expected ParseError, got %v", err)
+		}
+
+		if !strings.HasPrefix(parseErr.Error(), "base.steamd:2:13:") {
+			t.Fatalf("mismatch: got %v, but expected an error at base.steamd:2:13", parseErr)
+		}
+
+		if pos := analyzer.FileSet().Position(parseErr.Pos); pos.String() != "base.steamd:2:13" {
+			t.Fatalf("mismatch: got %v, but expected base.steamd:2:13", pos)
+		}
+
+		if imp := doc.Imports[0]; imp.Row != 1 || imp.Col != 13 {
+			t.Fatalf("mismatch: got %d:%d, but expected 1:13", imp.Row, imp.Col)
+		}
+	}
+}
+
+func TestAnalyzerEncoding(t *testing.T) {
+	doc, err := NewAnalyzer(token.NewTokenizer([]byte("\xef\xbb\xbfenum EResult {\n\tOK = 1;\n};\n")), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if enum := doc.Enums()[0]; enum.Row != 1 || enum.Col != 6 {
+		t.Fatalf("mismatch: got %d:%d, but expected 1:6", enum.Row, enum.Col)
+	}
+
+	_, err = NewAnalyzer(token.NewTokenizer([]byte("enum EResult {\n\tOK = 1; // caf\xe9\n};\n")), "main.steamd").Analyze()
+
+	var parseErr *ParseError
+
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected ParseError, got %v", err)
+	}
+
+	if parseErr.Code != ErrorInvalidToken || parseErr.Error() != "main.steamd:2:16: Invalid UTF-8 byte 0xe9" {
+		t.Fatalf("mismatch: got %s %v, but expected invalid-token main.steamd:2:16: Invalid UTF-8 byte 0xe9", parseErr.Code, parseErr)
+	}
+}
+
+func TestAnalyzerKeywords(t *testing.T) {
+	data := "enum EFlags<byte> flags { Flags = 1; };\nclass Msg { const uint flags = 1; EFlags Optional; };"
+	doc, err := NewAnalyzer(token.NewTokenizer([]byte(data)), "").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if enum := doc.Enums()[0]; !enum.Flags {
+		t.Fatalf("expected flags enum")
+	}
+
+	// names that don't start a member can be keywords
+	valid := []string{
+		"class optional {};",
+		"class Msg { uint removed; };",
+		"class Msg { optional uint class; };",
+		"typedef union = uint;",
+		"namespace enum {}",
+		"enum EMsg { typedef = 1; }; class Msg {}; union Body<EMsg> { Msg typedef; };",
+		"enum EResult { optional = 1; union = 2; };",
+	}
+
+	for _, data := range valid {
+		if _, err := NewAnalyzer(token.NewTokenizer([]byte(data)), "").Analyze(); err != nil {
+			t.Fatalf("not expected error %v for %q", err, data)
+		}
+	}
+
+	invalid := []string{
+		"enum EResult { obsolete = 1; };",
+		"class Msg { removed; };",
+		"class Msg { obsolete uint x; };",
+		"class Msg { optional optional x; };",
+	}
+
+	for _, data := range invalid {
+		_, err := NewAnalyzer(token.NewTokenizer([]byte(data)), "").Analyze()
+
+		var parseErr *ParseError
+
+		if !errors.As(err, &parseErr) || parseErr.Code != ErrorInvalidToken || !strings.Contains(parseErr.Message, "can't start a member") {
+			t.Fatalf("expected keyword error for %q, got %v", data, err)
+		}
+	}
+}
+
+func TestAnalyzerCaseSensitivity(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base.steamd": {Data: []byte("Enum EResult { OK = 1; };")},
+		"main.steamd": {Data: []byte("#import \"base.steamd\"\nCLASS Msg { EResult result; ushort port Endian:be; };")},
+	}
+
+	newAnalyzer := func(caseSensitive bool) *Analyzer {
+		analyzer := NewAnalyzer(token.NewTokenizer(fsys["main.steamd"].Data), "main.steamd")
+		analyzer.SetFS(fsys)
+		analyzer.SetStrict(true)
+		analyzer.SetCaseSensitive(caseSensitive)
+		return analyzer
+	}
+
+	if _, err := newAnalyzer(true).Analyze(); err == nil || !strings.HasPrefix(err.Error(), "base.steamd:1:1:") {
+		t.Fatalf("expected invalid token error, got %v", err)
+	}
+
+	doc, err := newAnalyzer(false).Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if props := doc.Classes()[0].Properties(); props[0].Type.Node == nil || props[1].Endian != ast.BigEndian {
+		t.Fatalf("mismatch: got %v %v, but expected the EResult enum and a big-endian port", props[0].Type, props[1].Endian)
+	}
+
+	// names are case-sensitive either way
+	fsys["main.steamd"] = &fstest.MapFile{Data: []byte("#import \"base.steamd\"\nclass Msg { Eresult result; };")}
+
+	if _, err := newAnalyzer(false).Analyze(); err == nil || !strings.Contains(err.Error(), `Unresolved type "Eresult"`) {
+		t.Fatalf("expected unresolved type error, got %v", err)
+	}
+
+	// directives too, whether imports are loaded ahead or not
+	fsys["base.steamd"] = &fstest.MapFile{Data: []byte("enum EResult { OK = 1; };")}
+	fsys["main.steamd"] = &fstest.MapFile{Data: []byte("#IMPORT \"base.steamd\"\nclass Msg { EResult result; };")}
+
+	for _, workers := range []int{1, 4} {
+		analyzer := newAnalyzer(false)
+		analyzer.SetWorkers(workers)
+		doc, err := analyzer.Analyze()
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		if len(doc.Imports) != 1 || doc.Classes()[0].Properties()[0].Type.Node == nil {
+			t.Fatalf("mismatch: got %d imports, but expected the import of EResult", len(doc.Imports))
+		}
+
+		analyzer = newAnalyzer(true)
+		analyzer.SetWorkers(workers)
+
+		if _, err := analyzer.Analyze(); err == nil || !strings.Contains(err.Error(), `Unresolved type "EResult"`) {
+			t.Fatalf("expected unresolved type error, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,804 @@
+package parse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/token"
+)
+
+// cacheVersion is bumped whenever the analysis or the entry format changes,
+// invalidating existing entries.
+const cacheVersion = 12
+
+// Cache stores analyzed documents in a directory, keyed by the SHA-256 of
+// their source. An entry also records the imports of the document and is only
+// used while every transitively imported file is unchanged, so documents
+// restored from the cache are the same as freshly analyzed ones. Only
+// analyses without errors are stored.
+//
+// A Cache can be shared by concurrent analyses and processes.
+type Cache struct {
+	Dir string
+}
+
+func NewCache(dir string) *Cache {
+	return &Cache{Dir: dir}
+}
+
+type cacheEntry struct {
+	Version int          `json:"version"`
+	Size    int          `json:"size"`
+	Items   []*cacheItem `json:"items"`
+}
+
+// cacheItem is an import or a declaration, in source order.
+type cacheItem struct {
+	Import    *cacheImport    `json:"import,omitempty"`
+	Class     *cacheDecl      `json:"class,omitempty"`
+	Enum      *cacheDecl      `json:"enum,omitempty"`
+	Namespace *cacheNamespace `json:"namespace,omitempty"`
+	Typedef   *cacheTypedef   `json:"typedef,omitempty"`
+	Union     *cacheDecl      `json:"union,omitempty"`
+}
+
+// cacheImport is an `#import` directive. Hash is the deep hash of the imported
+// document.
+type cacheImport struct {
+	Path string `json:"path"`
+	Row  int    `json:"row"`
+	Col  int    `json:"col"`
+	Hash string `json:"hash"`
+}
+
+type cacheDecl struct {
+	Name      string         `json:"name"`
+	Row       int            `json:"row"`
+	Col       int            `json:"col"`
+	Doc       string         `json:"doc,omitempty"`
+	Qualifier []string       `json:"qualifier,omitempty"`
+	Base      []string       `json:"base,omitempty"`
+	Flags     bool           `json:"flags,omitempty"`
+	Members   []*cacheMember `json:"members,omitempty"`
+}
+
+// cacheNamespace is a namespace block and the declarations in it.
+type cacheNamespace struct {
+	Name  string       `json:"name"`
+	Row   int          `json:"row"`
+	Col   int          `json:"col"`
+	Doc   string       `json:"doc,omitempty"`
+	Items []*cacheItem `json:"items,omitempty"`
+}
+
+// cacheTypedef is a typedef and the name of the type it aliases.
+type cacheTypedef struct {
+	Name    string `json:"name"`
+	Row     int    `json:"row"`
+	Col     int    `json:"col"`
+	Doc     string `json:"doc,omitempty"`
+	Comment string `json:"comment,omitempty"`
+	Type    string `json:"type"`
+}
+
+// cacheMember is a class property, an enum member, a union variant or, with
+// Nested, a class or enum declared in a class.
+type cacheMember struct {
+	Nested         *cacheItem         `json:"nested,omitempty"`
+	Name           string             `json:"name"`
+	Row            int                `json:"row"`
+	Col            int                `json:"col"`
+	Doc            string             `json:"doc,omitempty"`
+	Comment        string             `json:"comment,omitempty"`
+	Optional       bool               `json:"optional,omitempty"`
+	Condition      []string           `json:"condition,omitempty"`
+	Endian         ast.ByteOrder      `json:"endian,omitempty"`
+	Encoding       ast.StringEncoding `json:"encoding,omitempty"`
+	Termination    ast.Termination    `json:"termination,omitempty"`
+	Flags          ast.PropertyFlag   `json:"flags,omitempty"`
+	Type           string             `json:"type,omitempty"`
+	Qualifier      []string           `json:"qualifier,omitempty"`
+	Expr           *cacheExpr         `json:"expr,omitempty"`
+	Obsolete       bool               `json:"obsolete,omitempty"`
+	ObsoleteReason string             `json:"obsoleteReason,omitempty"`
+	Removed        bool               `json:"removed,omitempty"`
+	RemovedReason  string             `json:"removedReason,omitempty"`
+}
+
+// cacheExpr is a value expression. Operands have a Path, which is the raw
+// number for literals, binary expressions an Op and parenthesized ones only X.
+type cacheExpr struct {
+	Path    []string      `json:"path,omitempty"`
+	Literal bool          `json:"literal,omitempty"`
+	Value   uint64        `json:"value,omitempty"`
+	Op      *ast.BinaryOp `json:"op,omitempty"`
+	X       *cacheExpr    `json:"x,omitempty"`
+	Y       *cacheExpr    `json:"y,omitempty"`
+}
+
+// filename returns the file of the entry of the source hashed as hash, apart
+// for strict analyses and the ones that aren't case-sensitive, which accept
+// sources the others reject.
+func (c *Cache) filename(hash string, strict, foldCase bool) string {
+	name := hash
+
+	if strict {
+		name += ".strict"
+	}
+
+	if foldCase {
+		name += ".fold"
+	}
+
+	return filepath.Join(c.Dir, name+".json")
+}
+
+// load returns the entry of the source hashed as hash, nil if there is none.
+func (c *Cache) load(hash string, strict, foldCase bool) *cacheEntry {
+	data, err := ioutil.ReadFile(c.filename(hash, strict, foldCase))
+
+	if err != nil {
+		return nil
+	}
+
+	entry := &cacheEntry{}
+
+	if err := json.Unmarshal(data, entry); err != nil || entry.Version != cacheVersion {
+		return nil
+	}
+
+	return entry
+}
+
+// store writes entry through a temporary file, so that concurrent readers
+// never see it partially written.
+func (c *Cache) store(hash string, strict, foldCase bool, entry *cacheEntry) error {
+	data, err := json.Marshal(entry)
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(c.Dir, hash+".*.tmp")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), c.filename(hash, strict, foldCase))
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+	}
+
+	return err
+}
+
+// deepHash hashes the source of doc along with the deep hashes of its
+// imports, in order.
+func deepHash(doc *ast.DocumentNode) string {
+	h := sha256.New()
+	h.Write([]byte(doc.Hash))
+
+	for _, imp := range doc.Imports {
+		h.Write([]byte{0})
+
+		if imp.Document != nil {
+			h.Write([]byte(deepHash(imp.Document)))
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cached returns the entry of the analyzed file, nil if there is none or if
+// any of its imports changed since it was stored.
+func (a *Analyzer) cached() *cacheEntry {
+	entry := a.cache.load(hashData(a.t.Bytes()), a.strict, !a.t.CaseSensitive())
+
+	if entry == nil {
+		return nil
+	}
+
+	if _, ok := a.validate(a.filename, a.t.Bytes(), entry, make(map[string]bool)); !ok {
+		return nil
+	}
+
+	return entry
+}
+
+// validate checks the imports of entry, the entry of data, against their
+// current sources and returns the deep hash of data.
+func (a *Analyzer) validate(from string, data []byte, entry *cacheEntry, visiting map[string]bool) (string, bool) {
+	if visiting[from] {
+		return "", false
+	}
+
+	visiting[from] = true
+	defer delete(visiting, from)
+
+	h := sha256.New()
+	h.Write([]byte(hashData(data)))
+
+	for _, item := range entry.Items {
+		imp := item.Import
+
+		if imp == nil {
+			continue
+		}
+
+		importData, input, err := resolveImport(a.resolver, a.include, from, imp.Path)
+
+		if err != nil {
+			return "", false
+		}
+
+		importEntry := a.cache.load(hashData(importData), a.strict, !a.t.CaseSensitive())
+
+		if importEntry == nil {
+			return "", false
+		}
+
+		hash, ok := a.validate(input, importData, importEntry, visiting)
+
+		if !ok || hash != imp.Hash {
+			return "", false
+		}
+
+		h.Write([]byte{0})
+		h.Write([]byte(hash))
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// storeCache records the analysis of root. importDecls holds the number of
+// declarations preceding each import. Declarations nested in namespaces and
+// classes are stored with them.
+func (a *Analyzer) storeCache(root *ast.DocumentNode) error {
+	entry := &cacheEntry{Version: cacheVersion, Size: root.Size}
+	next := 0
+
+	store := func(decl ast.Node) {
+		if _, ok := decl.Parent().(*ast.DocumentNode); ok {
+			entry.Items = append(entry.Items, cacheDeclItem(decl))
+		}
+	}
+
+	for i, imp := range root.Imports {
+		for ; next < a.importDecls[i]; next++ {
+			store(root.Declarations[next])
+		}
+
+		entry.Items = append(entry.Items, &cacheItem{Import: &cacheImport{
+			Path: imp.Path,
+			Row:  imp.Row,
+			Col:  imp.Col,
+			Hash: deepHash(imp.Document),
+		}})
+	}
+
+	for ; next < len(root.Declarations); next++ {
+		store(root.Declarations[next])
+	}
+
+	return a.cache.store(root.Hash, a.strict, !a.t.CaseSensitive(), entry)
+}
+
+func cacheDeclItem(decl ast.Node) *cacheItem {
+	switch n := decl.(type) {
+	case *ast.ClassNode:
+		d := &cacheDecl{Name: n.Name(), Row: n.Row, Col: n.Col, Doc: n.Doc, Qualifier: cacheQualifier(n.Qualifier), Base: n.BasePath}
+
+		for _, child := range n.Children() {
+			switch child := child.(type) {
+			case *ast.PropertyNode:
+				d.Members = append(d.Members, cacheProperty(child))
+			case *ast.ClassNode, *ast.EnumNode:
+				d.Members = append(d.Members, &cacheMember{Nested: cacheDeclItem(child)})
+			}
+		}
+
+		return &cacheItem{Class: d}
+	case *ast.EnumNode:
+		d := &cacheDecl{Name: n.Name(), Row: n.Row, Col: n.Col, Doc: n.Doc, Qualifier: cacheQualifier(n.Qualifier), Flags: n.Flags}
+
+		for _, member := range n.Members() {
+			d.Members = append(d.Members, &cacheMember{
+				Name:           member.Name(),
+				Row:            member.Row,
+				Col:            member.Col,
+				Doc:            member.Doc,
+				Comment:        member.Comment,
+				Expr:           cacheExprOf(member.Expr),
+				Obsolete:       member.Obsolete,
+				ObsoleteReason: member.ObsoleteReason,
+				Removed:        member.Removed,
+				RemovedReason:  member.RemovedReason,
+			})
+		}
+
+		return &cacheItem{Enum: d}
+	case *ast.UnionNode:
+		d := &cacheDecl{Name: n.Name(), Row: n.Row, Col: n.Col, Doc: n.Doc, Qualifier: cacheQualifier(n.Qualifier)}
+
+		for _, variant := range n.Variants() {
+			d.Members = append(d.Members, &cacheMember{
+				Name:    variant.Name(),
+				Row:     variant.Row,
+				Col:     variant.Col,
+				Doc:     variant.Doc,
+				Comment: variant.Comment,
+				Type:    variant.Type.Value,
+			})
+		}
+
+		return &cacheItem{Union: d}
+	case *ast.NamespaceNode:
+		d := &cacheNamespace{Name: n.Name(), Row: n.Row, Col: n.Col, Doc: n.Doc}
+
+		for _, child := range n.Children() {
+			d.Items = append(d.Items, cacheDeclItem(child))
+		}
+
+		return &cacheItem{Namespace: d}
+	case *ast.TypedefNode:
+		return &cacheItem{Typedef: &cacheTypedef{
+			Name:    n.Name(),
+			Row:     n.Row,
+			Col:     n.Col,
+			Doc:     n.Doc,
+			Comment: n.Comment,
+			Type:    n.Type.Value,
+		}}
+	default:
+		panic(fmt.Errorf("Unknown declaration %T", decl))
+	}
+}
+
+func cacheProperty(n *ast.PropertyNode) *cacheMember {
+	m := &cacheMember{
+		Name:           n.Name(),
+		Row:            n.Row,
+		Col:            n.Col,
+		Doc:            n.Doc,
+		Comment:        n.Comment,
+		Optional:       n.Optional,
+		Condition:      n.ConditionPath,
+		Endian:         n.Endian,
+		Encoding:       n.Encoding,
+		Termination:    n.Termination,
+		Flags:          n.Flags,
+		Qualifier:      cacheQualifier(n.Qualifier),
+		Expr:           cacheExprOf(n.Expr),
+		Obsolete:       n.Obsolete,
+		ObsoleteReason: n.ObsoleteReason,
+		Removed:        n.Removed,
+		RemovedReason:  n.RemovedReason,
+	}
+
+	if n.Alias != nil {
+		m.Type = n.Alias.Value
+	} else if n.Type != nil {
+		m.Type = n.Type.Value
+	}
+
+	return m
+}
+
+func cacheQualifier(q *ast.Qualifier) []string {
+	if q == nil {
+		return nil
+	}
+
+	return q.Path
+}
+
+func cacheExprOf(expr ast.Expr) *cacheExpr {
+	switch e := expr.(type) {
+	case nil:
+		return nil
+	case *ast.LiteralExpr:
+		return &cacheExpr{Path: []string{e.Raw}, Literal: true, Value: e.Value}
+	case *ast.SymbolExpr:
+		return &cacheExpr{Path: e.Path}
+	case *ast.BinaryExpr:
+		op := e.Op
+		return &cacheExpr{Op: &op, X: cacheExprOf(e.X), Y: cacheExprOf(e.Y)}
+	case *ast.ParenExpr:
+		return &cacheExpr{X: cacheExprOf(e.X)}
+	default:
+		panic(fmt.Errorf("Unknown expression %T", expr))
+	}
+}
+
+// restore rebuilds root from entry, creating nodes and resolving symbols in
+// the same order as the analysis did, that is once all nodes are created.
+func (a *Analyzer) restore(root *ast.DocumentNode, entry *cacheEntry) error {
+	root.Size = entry.Size
+
+	if f := a.t.File(); f != nil {
+		f.SetLinesForContent(a.t.Bytes())
+	}
+
+	for _, item := range entry.Items {
+		var err error
+
+		switch {
+		case item.Import != nil:
+			t := &token.Token{Op: token.OpString, Value: []byte(item.Import.Path), Row: item.Import.Row, Col: item.Import.Col, Pos: a.pos(item.Import.Row, item.Import.Col)}
+			err = a.importFile(t, root)
+		default:
+			err = a.restoreDecl(root, root, item)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return a.resolvePending()
+}
+
+// restoreDecl restores the declaration or namespace of item in scope.
+func (a *Analyzer) restoreDecl(root *ast.DocumentNode, scope ast.Node, item *cacheItem) error {
+	switch {
+	case item.Class != nil:
+		return a.restoreClass(root, scope, item.Class)
+	case item.Enum != nil:
+		return a.restoreEnum(root, scope, item.Enum)
+	case item.Namespace != nil:
+		return a.restoreNamespace(root, scope, item.Namespace)
+	case item.Typedef != nil:
+		return a.restoreTypedef(root, scope, item.Typedef)
+	case item.Union != nil:
+		return a.restoreUnion(root, scope, item.Union)
+	}
+
+	return nil
+}
+
+func (a *Analyzer) restoreTypedef(root *ast.DocumentNode, scope ast.Node, d *cacheTypedef) error {
+	node := ast.NewTypedefNode(scope)
+	root.Declarations = append(root.Declarations, node)
+	node.Doc, node.Comment = d.Doc, d.Comment
+	node.Value = []byte(d.Name)
+	node.Row, node.Col = d.Row, d.Col
+	node.Pos = a.pos(d.Row, d.Col)
+
+	if err := scope.AddSymbol(node.Symbol()); err != nil {
+		return err
+	}
+
+	a.later(func() error {
+		var err error
+		node.Type, err = a.restoreType(scope, d.Type)
+		return err
+	})
+
+	return nil
+}
+
+func (a *Analyzer) restoreNamespace(root *ast.DocumentNode, scope ast.Node, d *cacheNamespace) error {
+	node := ast.NewNamespaceNode(scope)
+	root.Declarations = append(root.Declarations, node)
+	node.Doc = d.Doc
+	node.Value = []byte(d.Name)
+	node.Row, node.Col = d.Row, d.Col
+	node.Pos = a.pos(d.Row, d.Col)
+
+	if err := scope.AddSymbol(node.Symbol()); err != nil {
+		return err
+	}
+
+	for _, item := range d.Items {
+		if err := a.restoreDecl(root, node, item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *Analyzer) restoreClass(root *ast.DocumentNode, scope ast.Node, d *cacheDecl) error {
+	node := ast.NewClassNode(scope)
+	root.Declarations = append(root.Declarations, node)
+	node.Doc = d.Doc
+	node.Value = []byte(d.Name)
+	node.Row, node.Col = d.Row, d.Col
+	node.Pos = a.pos(d.Row, d.Col)
+
+	if err := scope.AddSymbol(node.Symbol()); err != nil {
+		return err
+	}
+
+	var err error
+
+	if node.Qualifier, err = a.restoreQualifier(scope, ast.QualifierEMsg, d.Qualifier); err != nil {
+		return err
+	}
+
+	if d.Base != nil {
+		node.BasePath = d.Base
+
+		a.later(func() error {
+			if node.Base = a.resolvePath(scope, d.Base); node.Base == nil {
+				return fmt.Errorf("Unresolved symbol %v", d.Base)
+			}
+
+			return nil
+		})
+	}
+
+	for _, m := range d.Members {
+		if m.Nested != nil {
+			err = a.restoreDecl(root, node, m.Nested)
+		} else {
+			err = a.restoreProperty(node, m)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *Analyzer) restoreEnum(root *ast.DocumentNode, scope ast.Node, d *cacheDecl) error {
+	node := ast.NewEnumNode(scope)
+	root.Declarations = append(root.Declarations, node)
+	node.Doc = d.Doc
+	node.Value = []byte(d.Name)
+	node.Row, node.Col = d.Row, d.Col
+	node.Pos = a.pos(d.Row, d.Col)
+
+	if err := scope.AddSymbol(node.Symbol()); err != nil {
+		return err
+	}
+
+	qualifier, err := a.restoreQualifier(scope, ast.QualifierStorageType, d.Qualifier)
+
+	if err != nil {
+		return err
+	}
+
+	node.Qualifier = qualifier
+
+	if qualifier != nil && qualifier.IsLiteral() {
+		node.Type, _ = ast.ParseStorageType(qualifier.Value)
+	}
+
+	node.Flags = d.Flags
+
+	for _, m := range d.Members {
+		member := ast.NewEnumMemberNode(node)
+		member.Doc = m.Doc
+		member.Value = []byte(m.Name)
+		member.Row, member.Col = m.Row, m.Col
+		member.Pos = a.pos(m.Row, m.Col)
+
+		if err := node.AddSymbol(member.Symbol()); err != nil {
+			return err
+		}
+
+		if member.Expr, err = a.restoreExpr(member, m.Expr); err != nil {
+			return err
+		}
+
+		member.Obsolete, member.ObsoleteReason = m.Obsolete, m.ObsoleteReason
+		member.Removed, member.RemovedReason = m.Removed, m.RemovedReason
+		member.Comment = m.Comment
+	}
+
+	return nil
+}
+
+func (a *Analyzer) restoreUnion(root *ast.DocumentNode, scope ast.Node, d *cacheDecl) error {
+	node := ast.NewUnionNode(scope)
+	root.Declarations = append(root.Declarations, node)
+	node.Doc = d.Doc
+	node.Value = []byte(d.Name)
+	node.Row, node.Col = d.Row, d.Col
+	node.Pos = a.pos(d.Row, d.Col)
+
+	if err := scope.AddSymbol(node.Symbol()); err != nil {
+		return err
+	}
+
+	var err error
+
+	if node.Qualifier, err = a.restoreQualifier(scope, ast.QualifierDiscriminator, d.Qualifier); err != nil {
+		return err
+	}
+
+	for _, m := range d.Members {
+		variant := ast.NewUnionVariantNode(node)
+		variant.Doc, variant.Comment = m.Doc, m.Comment
+		variant.Value = []byte(m.Name)
+		variant.Row, variant.Col = m.Row, m.Col
+		variant.Pos = a.pos(m.Row, m.Col)
+
+		if err := node.AddSymbol(variant.Symbol()); err != nil {
+			return err
+		}
+
+		typeName := m.Type
+
+		a.later(func() error {
+			var err error
+			variant.Type, err = a.restoreType(scope, typeName)
+			return err
+		})
+	}
+
+	name := &token.Token{Op: token.OpIdentifier, Value: node.Value, Row: d.Row, Col: d.Col, Pos: node.Pos}
+
+	a.checkLater(func() error {
+		return a.checkUnion(node, name)
+	})
+
+	return nil
+}
+
+func (a *Analyzer) restoreProperty(root *ast.ClassNode, m *cacheMember) error {
+	node := ast.NewPropertyNode(root)
+	node.Doc = m.Doc
+	qualifier, err := a.restoreQualifier(root, ast.QualifierSize, m.Qualifier)
+
+	if err != nil {
+		return err
+	}
+
+	node.Value = []byte(m.Name)
+	node.Row, node.Col = m.Row, m.Col
+	node.Pos = a.pos(m.Row, m.Col)
+	node.Qualifier = qualifier
+
+	if err := root.AddSymbol(node.Symbol()); err != nil {
+		return err
+	}
+
+	if m.Type != "" {
+		a.later(func() error {
+			sym, err := a.restoreType(root, m.Type)
+
+			if err != nil {
+				return err
+			}
+
+			a.setType(node, sym)
+
+			return nil
+		})
+	}
+
+	node.Optional, node.Flags, node.Endian = m.Optional, m.Flags, m.Endian
+	node.Encoding, node.Termination = m.Encoding, m.Termination
+
+	if m.Condition != nil {
+		node.ConditionPath = m.Condition
+
+		a.later(func() error {
+			if node.Condition = a.resolvePath(root, m.Condition); node.Condition == nil {
+				return fmt.Errorf("Unresolved symbol %v", m.Condition)
+			}
+
+			return nil
+		})
+	}
+
+	if node.Expr, err = a.restoreExpr(node, m.Expr); err != nil {
+		return err
+	}
+
+	node.Obsolete, node.ObsoleteReason = m.Obsolete, m.ObsoleteReason
+	node.Removed, node.RemovedReason = m.Removed, m.RemovedReason
+	node.Comment = m.Comment
+
+	return nil
+}
+
+func (a *Analyzer) restoreType(scope ast.Node, name string) (*ast.Symbol, error) {
+	if !a.strict || ast.IsBuiltinType(name) {
+		return scope.FindSymbol(name, true), nil
+	}
+
+	if sym := ast.LookupSymbol(scope, []string{name}); sym != nil {
+		return sym, nil
+	}
+
+	return nil, fmt.Errorf("Unresolved type %q", name)
+}
+
+func (a *Analyzer) restoreQualifier(root ast.Node, kind ast.QualifierKind, path []string) (*ast.Qualifier, error) {
+	if path == nil {
+		return nil, nil
+	}
+
+	q := &ast.Qualifier{Kind: kind, Path: path}
+
+	if len(path) == 1 && isQualifierLiteral(kind, path[0]) {
+		q.Value = path[0]
+	} else {
+		a.later(func() error {
+			if q.Symbol = a.resolvePath(root, path); q.Symbol == nil {
+				return fmt.Errorf("Unresolved symbol %v", path)
+			}
+
+			return nil
+		})
+	}
+
+	return q, nil
+}
+
+func (a *Analyzer) restoreExpr(node valueNode, e *cacheExpr) (ast.Expr, error) {
+	switch {
+	case e == nil:
+		return nil, nil
+	case e.Op != nil:
+		x, err := a.restoreExpr(node, e.X)
+
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := a.restoreExpr(node, e.Y)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &ast.BinaryExpr{Op: *e.Op, X: x, Y: y}, nil
+	case e.Path == nil:
+		x, err := a.restoreExpr(node, e.X)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &ast.ParenExpr{X: x}, nil
+	}
+
+	var expr ast.Expr
+	symExpr := &ast.SymbolExpr{Path: e.Path}
+
+	if e.Literal {
+		expr = &ast.LiteralExpr{Raw: e.Path[0], Value: e.Value}
+	} else {
+		expr = symExpr
+	}
+
+	a.later(func() error {
+		sym := a.resolvePath(node, e.Path)
+
+		if sym == nil {
+			return fmt.Errorf("Unresolved symbol %v", e.Path)
+		}
+
+		symExpr.Symbol = sym
+
+		return node.AddDefault(sym)
+	})
+
+	return expr, nil
+}
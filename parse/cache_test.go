@@ -0,0 +1,283 @@
+package parse
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/token"
+)
+
+const cacheSchema = `enum EMsg { Invalid = 0; Multi = 1; };
+#import "base.steamd"
+/// Logon request
+class MsgLogon<EMsg::Multi> {
+	const uint SIZE = 4;
+	protomask EMsg msg = EMsg::Invalid;
+	byte<SIZE> key; // session key
+	EResult result = EResult::OK;
+	Unknown unknown;
+	ulong mask = (1 << 4) | SIZE + 2;
+	int old; obsolete "gone" removed
+};
+enum EFlags<byte> flags {
+	None = 0;
+	A = 1 << 0; // first
+	B = A | 2; obsolete
+};
+/// Game coordinator
+namespace GC {
+	enum EGCMsg { Hello = 1; };
+	class MsgGCHdr<EGCMsg::Hello> { EGCMsg msg = EGCMsg::Hello; JobID job; };
+}
+typedef JobID = ulong; // job identifier
+class MsgGCJob : GC::MsgGCHdr {
+	JobID target;
+	enum EState { Idle = 0; };
+	class Step { EState state = EState::Idle; };
+	Step step;
+	EFlags flags;
+	uint extra if:EFlags::A endian:be;
+	string name encoding:utf16 term:null;
+	optional uint retries;
+};
+/// Payload of a multi message
+union MsgBody<EMsg> {
+	MsgLogon Multi; // logon
+	MsgGCJob Invalid;
+};
+`
+
+// dumpDocument describes the children of doc, their symbols and what their
+// references resolve to.
+func dumpDocument(doc *ast.DocumentNode) string {
+	var b strings.Builder
+
+	symbolPath := func(sym *ast.Symbol) string {
+		if sym == nil {
+			return "-"
+		}
+
+		if sym.Node == nil {
+			return sym.Value + "@?"
+		}
+
+		return sym.Value + "@" + strings.Join(sym.Node.NamePath()[1:], "::")
+	}
+
+	qualifier := func(q *ast.Qualifier) string {
+		if q == nil {
+			return "-"
+		}
+
+		return fmt.Sprintf("%s:%s:%s", q.Kind, q.Value, symbolPath(q.Symbol))
+	}
+
+	ast.Inspect(doc, func(n ast.Node) bool {
+		if n == nil {
+			return true
+		}
+
+		fmt.Fprintf(&b, "%T %s:", n, n.Name())
+
+		for _, sym := range n.Symbols() {
+			fmt.Fprintf(&b, " %s", symbolPath(sym))
+		}
+
+		switch n := n.(type) {
+		case *ast.NamespaceNode:
+			fmt.Fprintf(&b, " doc=%q", n.Doc)
+		case *ast.ClassNode:
+			fmt.Fprintf(&b, " doc=%q q=%s base=%v:%s", n.Doc, qualifier(n.Qualifier), n.BasePath, symbolPath(n.Base))
+		case *ast.EnumNode:
+			fmt.Fprintf(&b, " doc=%q q=%s type=%s flags=%v", n.Doc, qualifier(n.Qualifier), n.Type, n.Flags)
+		case *ast.TypedefNode:
+			fmt.Fprintf(&b, " doc=%q comment=%q type=%s", n.Doc, n.Comment, symbolPath(n.Type))
+		case *ast.PropertyNode:
+			fmt.Fprintf(&b, " doc=%q comment=%q optional=%v flags=%v type=%s alias=%s q=%s expr=%v", n.Doc, n.Comment, n.Optional, n.Flags, symbolPath(n.Type), symbolPath(n.Alias), qualifier(n.Qualifier), n.Expr)
+			fmt.Fprintf(&b, " condition=%v:%s endian=%s encoding=%s term=%s", n.ConditionPath, symbolPath(n.Condition), n.Endian, n.Encoding, n.Termination)
+			fmt.Fprintf(&b, " obsolete=%v:%q removed=%v:%q", n.Obsolete, n.ObsoleteReason, n.Removed, n.RemovedReason)
+
+			for _, sym := range n.Default {
+				fmt.Fprintf(&b, " default=%s", symbolPath(sym))
+			}
+		case *ast.UnionNode:
+			fmt.Fprintf(&b, " doc=%q q=%s", n.Doc, qualifier(n.Qualifier))
+		case *ast.UnionVariantNode:
+			fmt.Fprintf(&b, " doc=%q comment=%q type=%s member=%s", n.Doc, n.Comment, symbolPath(n.Type), symbolPath(n.Member))
+		case *ast.EnumMemberNode:
+			fmt.Fprintf(&b, " doc=%q comment=%q expr=%v", n.Doc, n.Comment, n.Expr)
+			fmt.Fprintf(&b, " obsolete=%v:%q removed=%v:%q", n.Obsolete, n.ObsoleteReason, n.Removed, n.RemovedReason)
+
+			for _, sym := range n.Default {
+				fmt.Fprintf(&b, " default=%s", symbolPath(sym))
+			}
+		}
+
+		b.WriteString("\n")
+		return true
+	})
+
+	return b.String()
+}
+
+func TestCache(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.steamd": {Data: []byte(cacheSchema)},
+		"base.steamd": {Data: []byte("enum EResult { OK = 1; Fail = 2; };")},
+	}
+
+	cache := NewCache(t.TempDir())
+
+	newAnalyzer := func(strict bool) *Analyzer {
+		data, _ := fs.ReadFile(fsys, "main.steamd")
+		analyzer := NewAnalyzer(token.NewTokenizer(data), "main.steamd")
+		analyzer.SetFS(fsys)
+		analyzer.SetStrict(strict)
+		analyzer.SetCache(cache)
+		return analyzer
+	}
+
+	analyze := func() (*ast.DocumentNode, []error) {
+		analyzer := newAnalyzer(false)
+		doc, err := analyzer.Analyze()
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		return doc, analyzer.Warnings()
+	}
+
+	fresh, expectedWarnings := analyze()
+	restored, warnings := analyze()
+
+	if dumpDocument(restored) != dumpDocument(fresh) {
+		t.Fatalf("mismatch: got\n%s\nbut expected\n%s", dumpDocument(restored), dumpDocument(fresh))
+	}
+
+	if fmt.Sprint(warnings) != fmt.Sprint(expectedWarnings) {
+		t.Fatalf("mismatch: got %v, but expected %v", warnings, expectedWarnings)
+	}
+
+	if restored.Hash != fresh.Hash || restored.Size != fresh.Size || len(restored.Imports) != 1 {
+		t.Fatalf("mismatch: got %s %d %v, but expected %s %d", restored.Hash, restored.Size, restored.Imports, fresh.Hash, fresh.Size)
+	}
+
+	// tamper with the entry to tell restored documents apart
+	filename := cache.filename(fresh.Hash, false, false)
+	data, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	tampered := strings.Replace(string(data), "Logon request", "Cached", 1)
+
+	if err := ioutil.WriteFile(filename, []byte(tampered), 0644); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if doc, _ := analyze(); !strings.Contains(dumpDocument(doc), `doc="Cached"`) {
+		t.Fatalf("expected document restored from the cache")
+	}
+
+	// strict analyses don't share entries with non-strict ones
+	if _, err := newAnalyzer(true).Analyze(); err == nil || !strings.HasPrefix(err.Error(), "main.steamd:9:") {
+		t.Fatalf("expected unresolved type error, got %v", err)
+	}
+
+	fsys["base.steamd"] = &fstest.MapFile{Data: []byte("enum EResult { OK = 1; };")}
+
+	if doc, _ := analyze(); strings.Contains(dumpDocument(doc), `doc="Cached"`) {
+		t.Fatalf("expected changed import to invalidate the entry")
+	}
+}
+
+func TestCacheErrors(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewCache(filepath.Join(dir, "cache"))
+	data := []byte("enum EResult { OK = ; };")
+	analyzer := NewAnalyzer(token.NewTokenizer(data), "")
+	analyzer.SetCache(cache)
+
+	if _, err := analyzer.Analyze(); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	if files, _ := ioutil.ReadDir(cache.Dir); len(files) != 0 {
+		t.Fatalf("expected failed analysis not to be cached, got %d files", len(files))
+	}
+
+	if err := ioutil.WriteFile(cache.Dir, nil, 0644); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	analyzer = NewAnalyzer(token.NewTokenizer([]byte("enum EResult { OK = 1; };")), "")
+	analyzer.SetCache(cache)
+
+	if _, err := analyzer.Analyze(); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if len(analyzer.Warnings()) != 1 {
+		t.Fatalf("expected a warning for the unwritable cache, got %v", analyzer.Warnings())
+	}
+}
+
+func TestCacheReaderTokenizer(t *testing.T) {
+	cache := NewCache(t.TempDir())
+
+	analyze := func(source string) *ast.DocumentNode {
+		analyzer := NewAnalyzer(token.NewReaderTokenizer(strings.NewReader(source)), "")
+		analyzer.SetCache(cache)
+		doc, err := analyzer.Analyze()
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		return doc
+	}
+
+	analyze("class A { uint a; };")
+	doc := analyze("enum B { X = 1; };")
+
+	if len(doc.Classes()) != 0 || len(doc.Enums()) != 1 || doc.Enums()[0].Name() != "B" {
+		t.Fatalf("expected streamed document not to be restored from the cache\n%s", dumpDocument(doc))
+	}
+
+	if doc.Hash != "" {
+		t.Fatalf("mismatch: got %q, but expected no hash", doc.Hash)
+	}
+
+	if files, _ := ioutil.ReadDir(cache.Dir); len(files) != 0 {
+		t.Fatalf("expected streamed documents not to be cached, got %d files", len(files))
+	}
+}
+
+func TestCacheCaseSensitivity(t *testing.T) {
+	cache := NewCache(t.TempDir())
+	data := []byte("CLASS Msg { uint a; };")
+
+	analyze := func(caseSensitive bool) error {
+		analyzer := NewAnalyzer(token.NewTokenizer(data), "")
+		analyzer.SetCache(cache)
+		analyzer.SetCaseSensitive(caseSensitive)
+		_, err := analyzer.Analyze()
+		return err
+	}
+
+	if err := analyze(false); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if err := analyze(true); err == nil {
+		t.Fatalf("expected the entry of the case-insensitive analysis not to be restored")
+	}
+}
@@ -0,0 +1,199 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+// Severity tells hard errors, which fail an analysis, from warnings.
+type Severity int
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// MarshalText encodes s as its name.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Position is a 1-based row and column, counted in runes, in a file. Row is 0
+// for diagnostics without a position, like the ones at the end of the input.
+type Position struct {
+	Filename string `json:"filename,omitempty"`
+	Row      int    `json:"row"`
+	Col      int    `json:"col"`
+}
+
+// Diagnostic is an error or warning found analyzing a file. Pos is where it
+// starts and End where it ends, right after the offending token. Code is the
+// name of its ErrorCode, "generic" for errors that didn't originate in the
+// Analyzer.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Pos      Position `json:"pos"`
+	End      Position `json:"end"`
+	Message  string   `json:"message"`
+	Code     string   `json:"code"`
+}
+
+func (d Diagnostic) String() string {
+	var prefix string
+
+	if d.Pos.Filename != "" {
+		prefix = d.Pos.Filename + ":"
+	}
+
+	if d.Pos.Row > 0 {
+		prefix += fmt.Sprintf("%d:%d:", d.Pos.Row, d.Pos.Col)
+	}
+
+	if prefix != "" {
+		prefix += " "
+	}
+
+	return fmt.Sprintf("%s%s: %s", prefix, d.Severity, d.Message)
+}
+
+// Diagnose returns the diagnostics of err, one per error of an ErrorList.
+func Diagnose(err error, severity Severity) []Diagnostic {
+	var list ErrorList
+
+	if errors.As(err, &list) {
+		var diags []Diagnostic
+
+		for _, err := range list {
+			diags = append(diags, Diagnose(err, severity)...)
+		}
+
+		return diags
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	var parseErr *ParseError
+
+	if !errors.As(err, &parseErr) {
+		return []Diagnostic{{Severity: severity, Message: err.Error(), Code: ErrorGeneric.String()}}
+	}
+
+	d := Diagnostic{
+		Severity: severity,
+		Pos:      Position{Filename: parseErr.Filename, Row: parseErr.Row, Col: parseErr.Col},
+		Message:  parseErr.Message,
+		Code:     parseErr.Code.String(),
+	}
+
+	d.End = d.Pos
+
+	if d.End.Row > 0 {
+		if parseErr.Token != nil {
+			d.End.Col += utf8.RuneCount(parseErr.Token.Raw)
+		} else {
+			d.End.Col++
+		}
+	}
+
+	return []Diagnostic{d}
+}
+
+// WriteDiagnostic writes diag followed by an excerpt of src, its source, with
+// the offending token underlined:
+//
+//	msg.steamd:3:6: error: Unexpected token ";"
+//	  |
+//	3 | 	B = ;
+//	  | 	    ^
+//
+// Only diag is written if src is nil or diag has no position in it.
+func WriteDiagnostic(w io.Writer, diag Diagnostic, src []byte) error {
+	if _, err := fmt.Fprintln(w, diag); err != nil {
+		return err
+	}
+
+	line, ok := sourceLine(src, diag.Pos.Row)
+
+	if !ok {
+		return nil
+	}
+
+	gutter := strconv.Itoa(diag.Pos.Row)
+	pad := strings.Repeat(" ", len(gutter))
+	runes := []rune(line)
+	col := diag.Pos.Col - 1
+
+	switch {
+	case col < 0:
+		col = 0
+	case col > len(runes):
+		col = len(runes)
+	}
+
+	width := 1
+
+	if diag.End.Row == diag.Pos.Row && diag.End.Col > diag.Pos.Col {
+		width = diag.End.Col - diag.Pos.Col
+	}
+
+	// tabs are kept so the caret lines up however they're displayed
+	var indent strings.Builder
+
+	for _, r := range runes[:col] {
+		if r == '\t' {
+			indent.WriteRune('\t')
+		} else {
+			indent.WriteByte(' ')
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%s |\n%s | %s\n%s | %s%s\n", pad, gutter, line, pad, indent.String(), strings.Repeat("^", width))
+
+	return err
+}
+
+// sourceLine returns the 1-based row of src, without its line terminator.
+func sourceLine(src []byte, row int) (string, bool) {
+	if src == nil || row < 1 {
+		return "", false
+	}
+
+	lines := bytes.Split(src, []byte("\n"))
+
+	if row > len(lines) {
+		return "", false
+	}
+
+	return strings.TrimSuffix(string(lines[row-1]), "\r"), true
+}
+
+// WriteDiagnosticsJSON writes diags as a JSON array.
+func WriteDiagnosticsJSON(w io.Writer, diags []Diagnostic) error {
+	if diags == nil {
+		diags = []Diagnostic{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(diags)
+}
@@ -0,0 +1,142 @@
+package parse
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/13k/go-steam-language/token"
+)
+
+func TestAnalyzerDiagnostics(t *testing.T) {
+	data := "enum E {\n\tA = 1; obsolete\n\tB = ;\n};\nclass C {\n\tuint x; removed \"gone\"\n};\n"
+	analyzer := NewAnalyzer(token.NewTokenizer([]byte(data)), "test.steamd")
+	analyzer.SetRecovery(true)
+
+	if _, err := analyzer.Analyze(); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	expected := []Diagnostic{
+		{
+			Severity: SeverityError,
+			Pos:      Position{Filename: "test.steamd", Row: 3, Col: 6},
+			End:      Position{Filename: "test.steamd", Row: 3, Col: 7},
+			Message:  `Unexpected token ";"`,
+			Code:     "unexpected-token",
+		},
+		{
+			Severity: SeverityWarning,
+			Pos:      Position{Filename: "test.steamd", Row: 2, Col: 2},
+			End:      Position{Filename: "test.steamd", Row: 2, Col: 3},
+			Message:  "Obsolete member E::A has no reason",
+			Code:     "missing-reason",
+		},
+	}
+
+	if actual := analyzer.Diagnostics(); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("mismatch: got %+v, but expected %+v", actual, expected)
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	list := ErrorList{
+		&ParseError{Filename: "a.steamd", Code: ErrorUnexpectedEOF, Message: "Unexpected EOF"},
+		errors.New("Cannot read b.steamd"),
+	}
+
+	expected := []Diagnostic{
+		{Severity: SeverityError, Pos: Position{Filename: "a.steamd"}, End: Position{Filename: "a.steamd"}, Message: "Unexpected EOF", Code: "unexpected-eof"},
+		{Severity: SeverityError, Message: "Cannot read b.steamd", Code: "generic"},
+	}
+
+	if actual := Diagnose(list, SeverityError); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("mismatch: got %+v, but expected %+v", actual, expected)
+	}
+
+	if actual := expected[0].String(); actual != "a.steamd: error: Unexpected EOF" {
+		t.Fatalf("mismatch: got %q", actual)
+	}
+
+	if diags := Diagnose(nil, SeverityError); diags != nil {
+		t.Fatalf("mismatch: got %+v, but expected no diagnostics", diags)
+	}
+}
+
+func TestWriteDiagnosticsJSON(t *testing.T) {
+	var buf bytes.Buffer
+
+	diags := []Diagnostic{{
+		Severity: SeverityWarning,
+		Pos:      Position{Row: 1, Col: 2},
+		End:      Position{Row: 1, Col: 3},
+		Message:  "Obsolete member E::A has no reason",
+		Code:     "missing-reason",
+	}}
+
+	if err := WriteDiagnosticsJSON(&buf, diags); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	expected := `[
+  {
+    "severity": "warning",
+    "pos": {
+      "row": 1,
+      "col": 2
+    },
+    "end": {
+      "row": 1,
+      "col": 3
+    },
+    "message": "Obsolete member E::A has no reason",
+    "code": "missing-reason"
+  }
+]
+`
+
+	if buf.String() != expected {
+		t.Fatalf("mismatch: got %s, but expected %s", buf.String(), expected)
+	}
+
+	buf.Reset()
+
+	if err := WriteDiagnosticsJSON(&buf, nil); err != nil || buf.String() != "[]\n" {
+		t.Fatalf("mismatch: got %q, %v, but expected an empty array", buf.String(), err)
+	}
+}
+
+func TestWriteDiagnostic(t *testing.T) {
+	src := []byte("enum E {\n\tA = 1;\n\tB = ;\n};\n\n\n\n\n\nclass MsgÉtat { uint x; };\r\n")
+
+	tests := []struct {
+		diag     Diagnostic
+		expected string
+	}{
+		{
+			Diagnostic{Severity: SeverityError, Pos: Position{Filename: "e.steamd", Row: 3, Col: 6}, End: Position{Row: 3, Col: 7}, Message: `Unexpected token ";"`},
+			"e.steamd:3:6: error: Unexpected token \";\"\n  |\n3 | \tB = ;\n  | \t    ^\n",
+		},
+		{
+			Diagnostic{Severity: SeverityWarning, Pos: Position{Row: 10, Col: 17}, End: Position{Row: 10, Col: 21}, Message: "Unknown type"},
+			"10:17: warning: Unknown type\n   |\n10 | class MsgÉtat { uint x; };\n   |                 ^^^^\n",
+		},
+		{
+			Diagnostic{Severity: SeverityError, Pos: Position{Filename: "e.steamd"}, Message: "Unexpected EOF"},
+			"e.steamd: error: Unexpected EOF\n",
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+
+		if err := WriteDiagnostic(&buf, test.diag, src); err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		if buf.String() != test.expected {
+			t.Fatalf("mismatch: got\n%s\nbut expected\n%s", buf.String(), test.expected)
+		}
+	}
+}
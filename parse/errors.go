@@ -0,0 +1,121 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/13k/go-steam-language/token"
+)
+
+const (
+	ErrorGeneric ErrorCode = iota
+	ErrorInvalidToken
+	ErrorUnexpectedToken
+	ErrorUnexpectedEOF
+	ErrorImport
+	ErrorDuplicateSymbol
+	ErrorUnresolvedSymbol
+	ErrorInvalidValue
+	ErrorMissingReason
+)
+
+type ErrorCode int
+
+func (c ErrorCode) String() string {
+	switch c {
+	case ErrorGeneric:
+		return "generic"
+	case ErrorInvalidToken:
+		return "invalid-token"
+	case ErrorUnexpectedToken:
+		return "unexpected-token"
+	case ErrorUnexpectedEOF:
+		return "unexpected-eof"
+	case ErrorImport:
+		return "import"
+	case ErrorDuplicateSymbol:
+		return "duplicate-symbol"
+	case ErrorUnresolvedSymbol:
+		return "unresolved-symbol"
+	case ErrorInvalidValue:
+		return "invalid-value"
+	case ErrorMissingReason:
+		return "missing-reason"
+	default:
+		panic(fmt.Errorf("Unknown ErrorCode %d", c))
+	}
+}
+
+// ParseError is a positioned error reported by the Analyzer. Token is the
+// offending token, nil at the end of the input. Err is the underlying cause
+// for errors that didn't originate in the Analyzer, like failed imports. Pos
+// is the position of the error in the FileSet of the Analyzer, if it has one.
+type ParseError struct {
+	Filename string
+	Row      int
+	Col      int
+	Pos      token.Pos
+	Token    *token.Token
+	Code     ErrorCode
+	Message  string
+	Err      error
+}
+
+func (e *ParseError) Error() string {
+	var prefix string
+
+	if e.Filename != "" {
+		prefix = e.Filename + ":"
+	}
+
+	if e.Row > 0 || e.Col > 0 {
+		prefix += fmt.Sprintf("%d:%d: ", e.Row, e.Col)
+	}
+
+	return prefix + e.Message
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorList collects the errors reported by an Analyzer in recovery mode.
+type ErrorList []error
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+
+	var messages []string
+
+	for _, err := range l {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+func (l ErrorList) Unwrap() []error {
+	return l
+}
+
+// Err returns nil for an empty list and the list itself otherwise.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+
+	return l
+}
+
+func (l *ErrorList) add(err error) {
+	if list, ok := err.(ErrorList); ok {
+		*l = append(*l, list...)
+	} else {
+		*l = append(*l, err)
+	}
+}
@@ -0,0 +1,141 @@
+package parse
+
+import (
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/token"
+)
+
+var (
+	openParenToken  = &token.Token{Op: token.OpOperator, Value: []byte("(")}
+	closeParenToken = &token.Token{Op: token.OpOperator, Value: []byte(")")}
+)
+
+// valueNode is a node holding a value expression.
+type valueNode interface {
+	ast.Node
+	AddDefault(*ast.Symbol) error
+}
+
+type binaryOperator struct {
+	token      *token.Token
+	op         ast.BinaryOp
+	production string
+}
+
+// binaryLevels lists the binary operators from the lowest to the highest
+// precedence.
+var binaryLevels = [][]binaryOperator{
+	{
+		{binaryOrToken, ast.BinaryOr, ProductionDefaultOr},
+	},
+	{
+		{&token.Token{Op: token.OpOperator, Value: []byte("<<")}, ast.BinaryShl, ProductionDefaultShift},
+		{&token.Token{Op: token.OpOperator, Value: []byte(">>")}, ast.BinaryShr, ProductionDefaultShift},
+	},
+	{
+		{&token.Token{Op: token.OpOperator, Value: []byte("+")}, ast.BinaryAdd, ProductionDefaultAdd},
+	},
+}
+
+func (a *Analyzer) analyzeExpr(node valueNode) (ast.Expr, error) {
+	return a.analyzeBinaryExpr(node, 0)
+}
+
+func (a *Analyzer) analyzeBinaryExpr(node valueNode, level int) (ast.Expr, error) {
+	if level == len(binaryLevels) {
+		return a.analyzeOperand(node)
+	}
+
+	x, err := a.analyzeBinaryExpr(node, level+1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := a.optionalBinaryOp(level)
+
+		if !ok {
+			return x, nil
+		}
+
+		y, err := a.analyzeBinaryExpr(node, level+1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		x = &ast.BinaryExpr{Op: op, X: x, Y: y}
+	}
+}
+
+func (a *Analyzer) optionalBinaryOp(level int) (ast.BinaryOp, bool) {
+	for _, b := range binaryLevels[level] {
+		if t := a.optionalToken(b.token); t != nil {
+			a.stats.production(b.production)
+			return b.op, true
+		}
+	}
+
+	return 0, false
+}
+
+func (a *Analyzer) analyzeOperand(node valueNode) (ast.Expr, error) {
+	if paren := a.optionalToken(openParenToken); paren != nil {
+		a.stats.production(ProductionDefaultParen)
+		x, err := a.analyzeExpr(node)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := a.expectToken(closeParenToken); err != nil {
+			return nil, err
+		}
+
+		return &ast.ParenExpr{X: x}, nil
+	}
+
+	tokens, err := a.getNamespacedIdentifier()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var expr ast.Expr
+	symExpr := &ast.SymbolExpr{Path: token.StringValues(tokens)}
+
+	if t := tokens[0]; t.Op == token.OpNumber {
+		expr = &ast.LiteralExpr{Raw: t.ValueString(), Value: numberValue(t.Number)}
+	} else if v, ok := ast.ParseLimit(t.ValueString()); ok && len(tokens) == 1 {
+		expr = &ast.LiteralExpr{Raw: t.ValueString(), Value: v}
+	} else {
+		expr = symExpr
+	}
+
+	// operands are resolved in source order, keeping Default in that order too
+	a.later(func() error {
+		sym, err := a.resolve(node, tokens)
+
+		if err != nil {
+			return err
+		}
+
+		symExpr.Symbol = sym
+
+		return node.AddDefault(sym)
+	})
+
+	return expr, nil
+}
+
+// numberValue returns n as an uint64, negative numbers in two's complement.
+func numberValue(n token.Number) uint64 {
+	if u, ok := n.Uint64(); ok {
+		return u
+	}
+
+	i, _ := n.Int64()
+
+	return uint64(i)
+}
@@ -0,0 +1,168 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/13k/go-steam-language/token"
+)
+
+const (
+	obsoleteReasonPlaceholder = "TODO"
+	maxRepairPasses           = 100
+)
+
+var hexLiteralRegexp = regexp.MustCompile(`^(-?)0[xX]([0-9a-fA-F]+)$`)
+
+// Fix is a machine-applicable repair: the bytes in [Offset, End) of the
+// source buffer are replaced with Text. Row and Col locate Offset for display.
+type Fix struct {
+	Filename string
+	Offset   int
+	End      int
+	Row      int
+	Col      int
+	Text     string
+}
+
+func (f *Fix) String() string {
+	switch {
+	case f.Offset == f.End:
+		return fmt.Sprintf("%d:%d: insert %q", f.Row, f.Col, f.Text)
+	case f.Text == "":
+		return fmt.Sprintf("%d:%d: delete %d bytes", f.Row, f.Col, f.End-f.Offset)
+	default:
+		return fmt.Sprintf("%d:%d: replace with %q", f.Row, f.Col, f.Text)
+	}
+}
+
+// FixableError is a syntax error for which the Analyzer could suggest a fix.
+type FixableError struct {
+	Err error
+	Fix *Fix
+}
+
+func (e *FixableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FixableError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyFixes returns a copy of data with fixes applied. Fixes overlapping an
+// already applied fix are skipped.
+func ApplyFixes(data []byte, fixes []*Fix) []byte {
+	sorted := append([]*Fix(nil), fixes...)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Offset > sorted[j].Offset
+	})
+
+	result := append([]byte(nil), data...)
+	limit := len(data)
+
+	for _, f := range sorted {
+		if f.End > limit || f.Offset > f.End {
+			continue
+		}
+
+		tail := append([]byte(f.Text), result[f.End:]...)
+		result = append(result[:f.Offset], tail...)
+		limit = f.Offset
+	}
+
+	return result
+}
+
+// Repair applies the safe subset of automated fixes to a steamd source:
+// lowercase hex prefixes and uppercase hex digits, duplicate imports removal,
+// placeholder reasons for bare obsolete attributes and missing terminators.
+// It returns the repaired source and every fix applied.
+func Repair(filename string, data []byte) ([]byte, []*Fix, error) {
+	tokens, err := token.NewTokenizer(data).Tokenize()
+
+	if err != nil {
+		return data, nil, err
+	}
+
+	fixes := tokenFixes(filename, tokens)
+	data = ApplyFixes(data, fixes)
+
+	for i := 0; i < maxRepairPasses; i++ {
+		_, err := NewAnalyzer(token.NewTokenizer(data), filename).Analyze()
+
+		var fixErr *FixableError
+
+		if err == nil || !errors.As(err, &fixErr) || fixErr.Fix.Filename != filename {
+			return data, fixes, err
+		}
+
+		fixes = append(fixes, fixErr.Fix)
+		data = ApplyFixes(data, []*Fix{fixErr.Fix})
+	}
+
+	return data, fixes, fmt.Errorf("%s: too many repairs", filename)
+}
+
+func tokenFixes(filename string, q *token.TokenQueue) []*Fix {
+	var (
+		fixes   []*Fix
+		prev    *token.Token
+		imports = make(map[string]bool)
+	)
+
+	for t := q.Dequeue(); t != nil; t = q.Dequeue() {
+		switch {
+		case t.Op == token.OpNumber && hexLiteralRegexp.Match(t.Value):
+			m := hexLiteralRegexp.FindSubmatch(t.Value)
+			normalized := string(m[1]) + "0x" + strings.ToUpper(string(m[2]))
+
+			if normalized != t.ValueString() {
+				fixes = append(fixes, tokenFix(filename, t, t.Start, t.End, normalized))
+			}
+		case t.Op == token.OpPreprocess && t.ValueEqualString("import"):
+			path := q.Peek()
+
+			if path == nil || path.Op != token.OpString {
+				break
+			}
+
+			q.Dequeue()
+
+			if imports[path.ValueString()] {
+				end := path.End
+
+				if next := q.Peek(); next != nil {
+					end = next.Start
+				}
+
+				fixes = append(fixes, tokenFix(filename, t, t.Start, end, ""))
+			}
+
+			imports[path.ValueString()] = true
+		case t.Keyword == token.KeywordObsolete && prev != nil && prev.Op == token.OpTerminator:
+			if next := q.Peek(); next == nil || next.Op != token.OpString {
+				fixes = append(fixes, tokenFix(filename, t, t.End, t.End, fmt.Sprintf(" %q", obsoleteReasonPlaceholder)))
+			}
+		}
+
+		prev = t
+	}
+
+	return fixes
+}
+
+func tokenFix(filename string, t *token.Token, offset, end int, text string) *Fix {
+	return &Fix{
+		Filename: filename,
+		Offset:   offset,
+		End:      end,
+		Row:      t.Row,
+		Col:      t.Col,
+		Text:     text,
+	}
+}
@@ -0,0 +1,257 @@
+package parse
+
+import (
+	"context"
+	"sync"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/token"
+)
+
+// importKey identifies an `#import` directive by the file containing it and
+// the position of its path.
+type importKey struct {
+	from     string
+	row, col int
+}
+
+// loadedImport is the outcome of an `#import` directive resolved ahead of the
+// analysis. owner marks the directive that first reaches file in sequential
+// order, which adopts its declarations.
+type loadedImport struct {
+	file  *loadedFile
+	err   error
+	owner bool
+}
+
+// loadedFile is an imported file, analyzed once pending drops to zero, that is
+// once all of its own imports are.
+type loadedFile struct {
+	input     string
+	data      []byte
+	imports   []*loadedImport
+	importers []*loadedFile
+	pending   int
+	doc       *ast.DocumentNode
+	err       error
+	warnings  []error
+	refs      []reference
+	stats     *Stats
+}
+
+// loader resolves, tokenizes and analyzes the imports of a file on a pool of
+// workers before the file itself is analyzed. Files only start once their
+// imports are done, so analyzers never wait on each other, and every directive
+// gets the outcome the sequential analysis would have given it.
+type loader struct {
+	a       *Analyzer
+	sem     chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	files   map[string]*loadedFile
+	imports map[importKey]*loadedImport
+}
+
+func newLoader(a *Analyzer, workers int) *loader {
+	return &loader{
+		a:       a,
+		sem:     make(chan struct{}, workers),
+		files:   make(map[string]*loadedFile),
+		imports: make(map[importKey]*loadedImport),
+	}
+}
+
+// load analyzes every file reachable from the imports of the root file. It
+// returns false if the imports form a cycle, which is left to the sequential
+// analysis to report.
+func (l *loader) load(ctx context.Context) (bool, error) {
+	root := &loadedFile{input: l.a.filename, data: l.a.t.Bytes()}
+
+	if root.input != "" {
+		l.files[root.input] = root
+	}
+
+	l.wg.Add(1)
+	go l.scan(ctx, root)
+	l.wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	if !l.own(root, make(map[*loadedFile]bool), make(map[*loadedFile]bool)) {
+		return false, nil
+	}
+
+	var ready []*loadedFile
+
+	for _, f := range l.files {
+		if f == root {
+			continue
+		}
+
+		for _, imp := range f.imports {
+			if imp.file != nil {
+				imp.file.importers = append(imp.file.importers, f)
+				f.pending++
+			}
+		}
+
+		if f.pending == 0 {
+			ready = append(ready, f)
+		}
+	}
+
+	l.wg.Add(len(ready))
+
+	for _, f := range ready {
+		go l.analyze(ctx, f)
+	}
+
+	l.wg.Wait()
+
+	return true, ctx.Err()
+}
+
+// scan resolves the imports of f and scans the files they lead to.
+func (l *loader) scan(ctx context.Context, f *loadedFile) {
+	defer l.wg.Done()
+
+	l.sem <- struct{}{}
+	defer func() { <-l.sem }()
+
+	for _, t := range scanImports(f.data, l.a.t.TabWidth(), l.a.t.CaseSensitive()) {
+		if ctx.Err() != nil {
+			return
+		}
+
+		data, input, err := resolveImport(l.a.resolver, l.a.include, f.input, t.ValueString())
+		imp := &loadedImport{err: err}
+
+		l.mu.Lock()
+
+		f.imports = append(f.imports, imp)
+		l.imports[importKey{f.input, t.Row, t.Col}] = imp
+
+		if err == nil {
+			if imp.file = l.files[input]; imp.file == nil {
+				imp.file = &loadedFile{input: input, data: data}
+				l.files[input] = imp.file
+				l.wg.Add(1)
+				go l.scan(ctx, imp.file)
+			}
+		}
+
+		l.mu.Unlock()
+	}
+}
+
+// own walks the imports depth-first in source order, like the sequential
+// analysis, marking the first directive to reach each file as its owner. It
+// returns false on reaching a file being walked.
+func (l *loader) own(f *loadedFile, seen, visiting map[*loadedFile]bool) bool {
+	seen[f] = true
+	visiting[f] = true
+	defer delete(visiting, f)
+
+	for _, imp := range f.imports {
+		switch {
+		case imp.file == nil:
+		case visiting[imp.file]:
+			return false
+		case !seen[imp.file]:
+			imp.owner = true
+
+			if !l.own(imp.file, seen, visiting) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// analyze analyzes f and starts the importers it was the last import of.
+func (l *loader) analyze(ctx context.Context, f *loadedFile) {
+	defer l.wg.Done()
+
+	l.sem <- struct{}{}
+
+	t := token.NewTokenizer(f.data)
+	t.SetTabWidth(l.a.t.TabWidth())
+	t.SetCaseSensitive(l.a.t.CaseSensitive())
+	a := NewAnalyzer(t, f.input)
+	a.fset = l.a.fset
+	a.manifest = l.a.manifest
+	a.recovery = l.a.recovery
+	a.strict = l.a.strict
+	a.resolver = l.a.resolver
+	a.include = l.a.include
+	a.cache = l.a.cache
+	a.loader = l
+
+	if l.a.stats != nil {
+		a.stats = NewStats()
+	}
+
+	f.doc, f.err = a.AnalyzeContext(ctx)
+	f.warnings = a.warnings
+	f.refs = a.refs
+	f.stats = a.stats
+
+	<-l.sem
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, importer := range f.importers {
+		if importer.pending--; importer.pending == 0 {
+			l.wg.Add(1)
+			go l.analyze(ctx, importer)
+		}
+	}
+}
+
+// lookup returns the outcome of the directive whose path is t in the file
+// from, if it was seen ahead of the analysis.
+func (l *loader) lookup(from string, t *token.Token) (*loadedImport, bool) {
+	if l == nil {
+		return nil, false
+	}
+
+	imp, ok := l.imports[importKey{from, t.Row, t.Col}]
+	return imp, ok
+}
+
+// scanImports returns the path tokens of the `#import` directives in data, up
+// to the first tokenizing error, positioned with tabWidth and matched with
+// caseSensitive like the tokens of the analysis.
+func scanImports(data []byte, tabWidth int, caseSensitive bool) []*token.Token {
+	var (
+		paths []*token.Token
+		t     = token.NewTokenizer(data)
+	)
+
+	t.SetTabWidth(tabWidth)
+	t.SetCaseSensitive(caseSensitive)
+
+	for {
+		tok, err := t.Next()
+
+		if err != nil || tok == nil {
+			return paths
+		}
+
+		if tok.Op != token.OpPreprocess || !isImport(tok, caseSensitive) {
+			continue
+		}
+
+		if tok, err = t.Next(); err != nil || tok == nil {
+			return paths
+		}
+
+		if tok.Op == token.OpString {
+			paths = append(paths, tok)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package parse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+)
+
+// Manifest records the provenance of a vendored set of steamd files: the
+// upstream repository and commit they were taken from, its license, and the
+// SHA-256 of every file. File paths are slash-separated and relative to the
+// directory containing the manifest.
+type Manifest struct {
+	Upstream string            `json:"upstream"`
+	Commit   string            `json:"commit"`
+	License  string            `json:"license"`
+	Files    map[string]string `json:"files"`
+	root     string
+}
+
+func NewManifest(root, upstream, commit, license string) *Manifest {
+	return &Manifest{
+		Upstream: upstream,
+		Commit:   commit,
+		License:  license,
+		Files:    make(map[string]string),
+		root:     root,
+	}
+}
+
+func LoadManifest(filename string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{}
+
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	if m.Files == nil {
+		m.Files = make(map[string]string)
+	}
+
+	m.root = filepath.Dir(filename)
+
+	return m, nil
+}
+
+func (m *Manifest) Save(filename string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+func (m *Manifest) Root() string {
+	return m.root
+}
+
+func (m *Manifest) Paths() []string {
+	var paths []string
+
+	for p := range m.Files {
+		paths = append(paths, p)
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+func (m *Manifest) Record(filename string, data []byte) error {
+	key, err := m.key(filename)
+
+	if err != nil {
+		return err
+	}
+
+	m.Files[key] = hashData(data)
+
+	return nil
+}
+
+func (m *Manifest) Contains(filename string) bool {
+	key, err := m.key(filename)
+
+	if err != nil {
+		return false
+	}
+
+	_, ok := m.Files[key]
+
+	return ok
+}
+
+// Verify returns an error if filename is recorded in the manifest and data
+// doesn't match the recorded hash. Files unknown to the manifest are accepted.
+func (m *Manifest) Verify(filename string, data []byte) error {
+	key, err := m.key(filename)
+
+	if err != nil {
+		return nil
+	}
+
+	expected, ok := m.Files[key]
+
+	if !ok {
+		return nil
+	}
+
+	if actual := hashData(data); actual != expected {
+		return fmt.Errorf("%s: locally modified (sha256 %s, manifest has %s from %s@%s)", filename, actual, expected, m.Upstream, m.Commit)
+	}
+
+	return nil
+}
+
+func (m *Manifest) key(filename string) (string, error) {
+	abs, err := filepath.Abs(filename)
+
+	if err != nil {
+		return "", err
+	}
+
+	root, err := filepath.Abs(m.root)
+
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(root, abs)
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.ToSlash(rel), nil
+}
+
+func hashData(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
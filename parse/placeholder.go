@@ -0,0 +1,77 @@
+package parse
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/token"
+)
+
+// Placeholder is a symbol created resolving a name that isn't declared, which
+// outside of strict mode is accepted as is. Refs are the positions of the
+// references to it, in source order.
+type Placeholder struct {
+	Symbol *ast.Symbol
+	Refs   []Diagnostic
+}
+
+// reference is a name resolved outside of strict mode.
+type reference struct {
+	sym  *ast.Symbol
+	diag Diagnostic
+}
+
+// reference records the resolution of the name spelled by tokens to sym.
+// Numbers and builtin types are always resolved to placeholders, so they're
+// left out.
+func (a *Analyzer) reference(sym *ast.Symbol, tokens []*token.Token) {
+	values := token.StringValues(tokens)
+
+	if sym == nil || len(values) == 1 && (isNumber(values[0]) || ast.IsBuiltinType(values[0])) {
+		return
+	}
+
+	name := strings.Join(values, "::")
+	first := tokens[0]
+	pos := Position{Filename: a.filename, Row: first.Row, Col: first.Col}
+	end := pos
+	end.Col += utf8.RuneCountInString(name)
+
+	a.refs = append(a.refs, reference{sym: sym, diag: Diagnostic{
+		Severity: SeverityWarning,
+		Pos:      pos,
+		End:      end,
+		Message:  "Undeclared symbol " + `"` + name + `"`,
+		Code:     ErrorUnresolvedSymbol.String(),
+	}})
+}
+
+// Placeholders returns the symbols of the last analysis, including the ones
+// of imported files, that were created by references to undeclared names,
+// in the order they're first referenced. References aren't recorded in strict
+// mode, which rejects them, nor for files restored from a cache.
+func (a *Analyzer) Placeholders() []*Placeholder {
+	var list []*Placeholder
+
+	index := make(map[*ast.Symbol]*Placeholder)
+
+	for _, ref := range a.refs {
+		switch ref.sym.Node.(type) {
+		case *ast.NamespaceNode, *ast.TypedefNode, *ast.ClassNode, *ast.EnumNode, *ast.EnumMemberNode, *ast.PropertyNode, *ast.UnionNode, *ast.UnionVariantNode:
+			continue
+		}
+
+		p, ok := index[ref.sym]
+
+		if !ok {
+			p = &Placeholder{Symbol: ref.sym}
+			index[ref.sym] = p
+			list = append(list, p)
+		}
+
+		p.Refs = append(p.Refs, ref.diag)
+	}
+
+	return list
+}
@@ -0,0 +1,70 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/13k/go-steam-language/token"
+)
+
+func TestAnalyzerPlaceholders(t *testing.T) {
+	fsys := fstest.MapFS{
+		"emsg.steamd": {Data: []byte("enum EMsg { Multi = 1; };\nclass Hdr { Unknown u; };\n")},
+		"main.steamd": {Data: []byte(`#import "emsg.steamd"
+
+class C<EMsg::Multi> {
+	const uint SIZE = 4;
+	EResutl r = EResutl::OK;
+	uint x = 1;
+	byte<SIZE> key;
+	byte<SIZ> typo;
+	EResutl other;
+};
+`)},
+	}
+
+	for _, workers := range []int{0, 2} {
+		analyzer := NewAnalyzer(token.NewTokenizer(fsys["main.steamd"].Data), "main.steamd")
+		analyzer.SetFS(fsys)
+		analyzer.SetWorkers(workers)
+
+		if _, err := analyzer.Analyze(); err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		var actual []string
+
+		for _, p := range analyzer.Placeholders() {
+			var refs []string
+
+			for _, ref := range p.Refs {
+				refs = append(refs, fmt.Sprintf("%s:%d:%d-%d", ref.Pos.Filename, ref.Pos.Row, ref.Pos.Col, ref.End.Col))
+			}
+
+			actual = append(actual, p.Symbol.Value+" "+strings.Join(refs, " "))
+		}
+
+		expected := []string{
+			"Unknown emsg.steamd:2:13-20",
+			"EResutl main.steamd:5:2-9 main.steamd:9:2-9",
+			"OK main.steamd:5:14-25",
+			"SIZ main.steamd:8:7-10",
+		}
+
+		if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+			t.Fatalf("mismatch with %d workers: got\n%s\nbut expected\n%s", workers, strings.Join(actual, "\n"), strings.Join(expected, "\n"))
+		}
+	}
+
+	analyzer := NewAnalyzer(token.NewTokenizer([]byte("class C { uint x = 1; };")), "")
+
+	if _, err := analyzer.Analyze(); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if placeholders := analyzer.Placeholders(); len(placeholders) != 0 {
+		t.Fatalf("mismatch: got %+v, but expected no placeholders", placeholders)
+	}
+}
@@ -0,0 +1,130 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrImportEscape is returned by resolvers for imports leaving the directory
+// they're confined to.
+var ErrImportEscape = errors.New("Import escapes the root directory")
+
+// ImportResolver loads the files referenced by `#import` directives.
+// Resolve returns the contents of importPath, imported from the file named
+// fromFile, and the name of the resolved file. Names identify files: a file is
+// analyzed once per analysis however many times it's imported, and it's used
+// as fromFile for the imports of the resolved file.
+type ImportResolver interface {
+	Resolve(fromFile, importPath string) ([]byte, string, error)
+}
+
+// ImportResolverFunc adapts a function to the ImportResolver interface.
+type ImportResolverFunc func(fromFile, importPath string) ([]byte, string, error)
+
+func (f ImportResolverFunc) Resolve(fromFile, importPath string) ([]byte, string, error) {
+	return f(fromFile, importPath)
+}
+
+// OSResolver resolves imports from the OS filesystem, relative to the
+// directory of the importing file. When Root is set, absolute import paths
+// and files outside of Root are rejected, symbolic links resolved.
+type OSResolver struct {
+	Root string
+}
+
+func (r OSResolver) Resolve(fromFile, importPath string) ([]byte, string, error) {
+	var dir string
+
+	if fromFile != "" {
+		dir = filepath.Dir(fromFile)
+	}
+
+	name := filepath.Join(dir, importPath)
+	filename := name
+
+	if r.Root != "" {
+		var err error
+
+		if filename, err = r.confine(importPath, name); err != nil {
+			return nil, "", err
+		}
+	}
+
+	data, err := ioutil.ReadFile(filename)
+
+	return data, name, err
+}
+
+// confine returns the file name leads to, its symbolic links resolved, and
+// fails if it's outside of Root. Missing files are left to fail reading.
+func (r OSResolver) confine(importPath, name string) (string, error) {
+	if filepath.IsAbs(importPath) {
+		return "", fmt.Errorf("%w: %q is absolute", ErrImportEscape, importPath)
+	}
+
+	root, err := filepath.Abs(r.Root)
+
+	if err != nil {
+		return "", err
+	}
+
+	abs, err := filepath.Abs(name)
+
+	if err != nil {
+		return "", err
+	}
+
+	if !within(root, abs) {
+		return "", fmt.Errorf("%w: %q is outside of %q", ErrImportEscape, importPath, r.Root)
+	}
+
+	target, err := filepath.EvalSymlinks(abs)
+
+	if err != nil {
+		return name, nil
+	}
+
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return "", err
+	}
+
+	if !within(root, target) {
+		return "", fmt.Errorf("%w: %q links outside of %q", ErrImportEscape, importPath, r.Root)
+	}
+
+	return target, nil
+}
+
+// within reports whether name, an absolute path, is in the directory root.
+func within(root, name string) bool {
+	rel, err := filepath.Rel(root, name)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// FSResolver resolves imports from FS, relative to the directory of the
+// importing file. Names are slash-separated paths within FS, so imports can't
+// leave it: absolute paths and paths escaping its root are rejected.
+type FSResolver struct {
+	FS fs.FS
+}
+
+func (r *FSResolver) Resolve(fromFile, importPath string) ([]byte, string, error) {
+	if path.IsAbs(importPath) {
+		return nil, "", fmt.Errorf("%w: %q is absolute", ErrImportEscape, importPath)
+	}
+
+	name := path.Join(path.Dir(fromFile), importPath)
+
+	if !fs.ValidPath(name) {
+		return nil, "", fmt.Errorf("%w: %q is outside of the filesystem", ErrImportEscape, importPath)
+	}
+
+	data, err := fs.ReadFile(r.FS, name)
+
+	return data, name, err
+}
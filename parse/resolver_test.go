@@ -0,0 +1,117 @@
+package parse
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/13k/go-steam-language/token"
+)
+
+func TestOSResolverRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "steamd")
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "schemas")
+
+	for _, name := range []string{"schemas/base.steamd", "schemas/sub/enums.steamd", "secret.steamd"} {
+		filename := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		if err := ioutil.WriteFile(filename, []byte("enum E {};"), 0644); err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+	}
+
+	from := filepath.Join(root, "main.steamd")
+	r := OSResolver{Root: root}
+
+	tests := []struct {
+		importPath string
+		escape     bool
+	}{
+		{"base.steamd", false},
+		{"sub/enums.steamd", false},
+		{"sub/../base.steamd", false},
+		{"../secret.steamd", true},
+		{"sub/../../secret.steamd", true},
+		{filepath.Join(dir, "secret.steamd"), true},
+		{filepath.Join(root, "base.steamd"), true},
+	}
+
+	for _, test := range tests {
+		_, _, err := r.Resolve(from, test.importPath)
+
+		if test.escape != errors.Is(err, ErrImportEscape) {
+			t.Fatalf("mismatch for %q: got %v, but expected escape %v", test.importPath, err, test.escape)
+		}
+
+		if !test.escape && err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+	}
+
+	if _, _, err := (OSResolver{}).Resolve(from, "../secret.steamd"); err != nil {
+		t.Fatalf("not expected error without root %v", err)
+	}
+
+	analyzer := NewAnalyzer(token.NewTokenizer([]byte("#import \"../secret.steamd\"")), from)
+	analyzer.SetImportRoot(root)
+
+	if _, err := analyzer.Analyze(); !errors.Is(err, ErrImportEscape) {
+		t.Fatalf("expected ErrImportEscape, got %v", err)
+	}
+
+	// symbolic links are followed, within root only
+	links := map[string]string{
+		"schemas/linked.steamd": "base.steamd",
+		"schemas/escape.steamd": "../secret.steamd",
+		"schemas/outside":       "..",
+	}
+
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Skipf("cannot create symbolic links: %v", err)
+		}
+	}
+
+	for importPath, escape := range map[string]bool{"linked.steamd": false, "escape.steamd": true, "outside/secret.steamd": true} {
+		_, _, err := r.Resolve(from, importPath)
+
+		if escape != errors.Is(err, ErrImportEscape) || !escape && err != nil {
+			t.Fatalf("mismatch for %q: got %v, but expected escape %v", importPath, err, escape)
+		}
+	}
+}
+
+func TestFSResolverEscape(t *testing.T) {
+	r := &FSResolver{FS: fstest.MapFS{
+		"schemas/base.steamd": {Data: []byte("enum E {};")},
+		"secret.steamd":       {Data: []byte("enum E {};")},
+	}}
+
+	if _, name, err := r.Resolve("schemas/main.steamd", "base.steamd"); err != nil || name != "schemas/base.steamd" {
+		t.Fatalf("mismatch: got %q, %v", name, err)
+	}
+
+	if _, _, err := r.Resolve("schemas/main.steamd", "../secret.steamd"); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	for _, importPath := range []string{"../../secret.steamd", "/secret.steamd"} {
+		if _, _, err := r.Resolve("schemas/main.steamd", importPath); !errors.Is(err, ErrImportEscape) {
+			t.Fatalf("expected ErrImportEscape for %q, got %v", importPath, err)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package parse
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/13k/go-steam-language/token"
+)
+
+const (
+	ProductionImport         = "import"
+	ProductionClass          = "class"
+	ProductionBase           = "base"
+	ProductionEnum           = "enum"
+	ProductionEnumFlags      = "enum-flags"
+	ProductionNamespaceBlock = "namespace-block"
+	ProductionTypedef        = "typedef"
+	ProductionUnion          = "union"
+	ProductionVariant        = "variant"
+	ProductionScope          = "scope"
+	ProductionProperty       = "property"
+	ProductionEnumMember     = "enum-member"
+	ProductionPropertyType   = "property-type"
+	ProductionPropertyFlags  = "property-flags"
+	ProductionOptional       = "optional"
+	ProductionCondition      = "condition"
+	ProductionEndian         = "endian"
+	ProductionEncoding       = "encoding"
+	ProductionTermination    = "termination"
+	ProductionQualifier      = "qualifier"
+	ProductionNamespace      = "namespace"
+	ProductionDefault        = "default"
+	ProductionDefaultOr      = "default-or"
+	ProductionDefaultAdd     = "default-add"
+	ProductionDefaultShift   = "default-shift"
+	ProductionDefaultParen   = "default-paren"
+	ProductionObsolete       = "obsolete"
+	ProductionObsoleteReason = "obsolete-reason"
+	ProductionRemoved        = "removed"
+	ProductionRemovedReason  = "removed-reason"
+	ProductionDoc            = "doc"
+	ProductionTerminatorFix  = "terminator-fix"
+)
+
+var (
+	productions = []string{
+		ProductionImport,
+		ProductionClass,
+		ProductionBase,
+		ProductionEnum,
+		ProductionEnumFlags,
+		ProductionNamespaceBlock,
+		ProductionTypedef,
+		ProductionUnion,
+		ProductionVariant,
+		ProductionScope,
+		ProductionProperty,
+		ProductionEnumMember,
+		ProductionPropertyType,
+		ProductionPropertyFlags,
+		ProductionOptional,
+		ProductionCondition,
+		ProductionEndian,
+		ProductionEncoding,
+		ProductionTermination,
+		ProductionQualifier,
+		ProductionNamespace,
+		ProductionDefault,
+		ProductionDefaultOr,
+		ProductionDefaultAdd,
+		ProductionDefaultShift,
+		ProductionDefaultParen,
+		ProductionObsolete,
+		ProductionObsoleteReason,
+		ProductionRemoved,
+		ProductionRemovedReason,
+		ProductionDoc,
+		ProductionTerminatorFix,
+	}
+
+	tokenOps = []token.OpCode{
+		token.OpTerminator,
+		token.OpString,
+		token.OpDoc,
+		token.OpIdentifier,
+		token.OpNumber,
+		token.OpNamespace,
+		token.OpPreprocess,
+		token.OpOperator,
+		token.OpInvalid,
+	}
+)
+
+// Stats records which grammar productions and token kinds an Analyzer
+// exercised. A single Stats can be shared by several analyses to measure
+// grammar coverage over a corpus.
+type Stats struct {
+	Files       int
+	Productions map[string]int
+	Tokens      map[token.OpCode]int
+}
+
+func NewStats() *Stats {
+	return &Stats{
+		Productions: make(map[string]int),
+		Tokens:      make(map[token.OpCode]int),
+	}
+}
+
+func (s *Stats) production(name string) {
+	if s != nil {
+		s.Productions[name]++
+	}
+}
+
+func (s *Stats) token(t *token.Token) {
+	if s != nil {
+		s.Tokens[t.Op]++
+	}
+}
+
+func (s *Stats) file() {
+	if s != nil {
+		s.Files++
+	}
+}
+
+// merge adds the counts of other, which may be nil.
+func (s *Stats) merge(other *Stats) {
+	if s == nil || other == nil {
+		return
+	}
+
+	s.Files += other.Files
+
+	for p, n := range other.Productions {
+		s.Productions[p] += n
+	}
+
+	for op, n := range other.Tokens {
+		s.Tokens[op] += n
+	}
+}
+
+// Uncovered returns the productions and token kinds that were never exercised.
+func (s *Stats) Uncovered() []string {
+	var result []string
+
+	for _, p := range productions {
+		if s.Productions[p] == 0 {
+			result = append(result, p)
+		}
+	}
+
+	for _, op := range tokenOps {
+		if s.Tokens[op] == 0 {
+			result = append(result, "token:"+op.String())
+		}
+	}
+
+	return result
+}
+
+func (s *Stats) Coverage() float64 {
+	total := len(productions) + len(tokenOps)
+	return float64(total-len(s.Uncovered())) / float64(total)
+}
+
+func (s *Stats) Report(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "files: %d\n\nproductions:\n", s.Files); err != nil {
+		return err
+	}
+
+	for _, p := range productions {
+		if _, err := fmt.Fprintf(w, "  %-20s %8d%s\n", p, s.Productions[p], uncoveredMark(s.Productions[p])); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintf(w, "\ntokens:\n"); err != nil {
+		return err
+	}
+
+	for _, op := range tokenOps {
+		if _, err := fmt.Fprintf(w, "  %-20s %8d%s\n", op, s.Tokens[op], uncoveredMark(s.Tokens[op])); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "\ncoverage: %.1f%%\n", s.Coverage()*100)
+
+	return err
+}
+
+func uncoveredMark(n int) string {
+	if n == 0 {
+		return "  (not covered)"
+	}
+
+	return ""
+}
@@ -0,0 +1,204 @@
+// Package parser is kept for compatibility. New code should import the token,
+// ast and parse packages directly.
+package parser
+
+import (
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+type (
+	OpCode     = token.OpCode
+	Token      = token.Token
+	TokenQueue = token.TokenQueue
+	Tokenizer  = token.Tokenizer
+	Number     = token.Number
+
+	Node           = ast.Node
+	DocumentNode   = ast.DocumentNode
+	Import         = ast.Import
+	Symbol         = ast.Symbol
+	Qualifier      = ast.Qualifier
+	QualifierKind  = ast.QualifierKind
+	StorageType    = ast.StorageType
+	PropertyFlag   = ast.PropertyFlag
+	FieldLayout    = ast.FieldLayout
+	ClassLayout    = ast.ClassLayout
+	ClassNode      = ast.ClassNode
+	EnumNode       = ast.EnumNode
+	PropertyNode   = ast.PropertyNode
+	EnumMemberNode = ast.EnumMemberNode
+	Expr           = ast.Expr
+	LiteralExpr    = ast.LiteralExpr
+	SymbolExpr     = ast.SymbolExpr
+	BinaryExpr     = ast.BinaryExpr
+	ParenExpr      = ast.ParenExpr
+	Visitor        = ast.Visitor
+	Cursor         = ast.Cursor
+	RewriteFunc    = ast.RewriteFunc
+
+	Analyzer     = parse.Analyzer
+	Fix          = parse.Fix
+	FixableError = parse.FixableError
+	Manifest     = parse.Manifest
+	Stats        = parse.Stats
+	Cache        = parse.Cache
+
+	ImportResolver     = parse.ImportResolver
+	ImportResolverFunc = parse.ImportResolverFunc
+	OSResolver         = parse.OSResolver
+	FSResolver         = parse.FSResolver
+)
+
+const (
+	OpWhitespace = token.OpWhitespace
+	OpTerminator = token.OpTerminator
+	OpString     = token.OpString
+	OpComment    = token.OpComment
+	OpDoc        = token.OpDoc
+	OpIdentifier = token.OpIdentifier
+	OpNamespace  = token.OpNamespace
+	OpPreprocess = token.OpPreprocess
+	OpOperator   = token.OpOperator
+	OpInvalid    = token.OpInvalid
+	OpNumber     = token.OpNumber
+
+	QualifierEMsg        = ast.QualifierEMsg
+	QualifierStorageType = ast.QualifierStorageType
+	QualifierSize        = ast.QualifierSize
+
+	StorageInt    = ast.StorageInt
+	StorageByte   = ast.StorageByte
+	StorageSByte  = ast.StorageSByte
+	StorageShort  = ast.StorageShort
+	StorageUShort = ast.StorageUShort
+	StorageUInt   = ast.StorageUInt
+	StorageLong   = ast.StorageLong
+	StorageULong  = ast.StorageULong
+
+	PropertyFlagNone           = ast.PropertyFlagNone
+	PropertyFlagConst          = ast.PropertyFlagConst
+	PropertyFlagSteamIDMarshal = ast.PropertyFlagSteamIDMarshal
+	PropertyFlagGameIDMarshal  = ast.PropertyFlagGameIDMarshal
+	PropertyFlagBoolMarshal    = ast.PropertyFlagBoolMarshal
+	PropertyFlagProtoMask      = ast.PropertyFlagProtoMask
+	PropertyFlagProtoMaskGC    = ast.PropertyFlagProtoMaskGC
+	PropertyFlagProto          = ast.PropertyFlagProto
+
+	ProtoMask = ast.ProtoMask
+)
+
+var ErrImportEscape = parse.ErrImportEscape
+
+func NewTokenQueue() *TokenQueue {
+	return token.NewTokenQueue()
+}
+
+func NewTokenizer(data []byte) *Tokenizer {
+	return token.NewTokenizer(data)
+}
+
+func NewNode(parent Node) Node {
+	return ast.NewNode(parent)
+}
+
+func NewDocumentNode(filename string) *DocumentNode {
+	return ast.NewDocumentNode(filename)
+}
+
+func NewClassNode(parent Node) *ClassNode {
+	return ast.NewClassNode(parent)
+}
+
+func NewEnumNode(parent Node) *EnumNode {
+	return ast.NewEnumNode(parent)
+}
+
+func NewEnumMemberNode(parent Node) *EnumMemberNode {
+	return ast.NewEnumMemberNode(parent)
+}
+
+func NewPropertyNode(parent Node) *PropertyNode {
+	return ast.NewPropertyNode(parent)
+}
+
+func ParseStorageType(name string) (StorageType, bool) {
+	return ast.ParseStorageType(name)
+}
+
+func Layout(n *ClassNode) (*ClassLayout, error) {
+	return ast.Layout(n)
+}
+
+func ParsePropertyFlag(name string) (PropertyFlag, bool) {
+	return ast.ParsePropertyFlag(name)
+}
+
+func EvalFlags(enum *EnumNode, expr string) (uint64, error) {
+	return ast.EvalFlags(enum, expr)
+}
+
+func DecomposeFlags(enum *EnumNode, v uint64) ([]*EnumMemberNode, uint64, error) {
+	return ast.DecomposeFlags(enum, v)
+}
+
+func NewAnalyzer(t *Tokenizer, f string) *Analyzer {
+	return parse.NewAnalyzer(t, f)
+}
+
+func ApplyFixes(data []byte, fixes []*Fix) []byte {
+	return parse.ApplyFixes(data, fixes)
+}
+
+func Repair(filename string, data []byte) ([]byte, []*Fix, error) {
+	return parse.Repair(filename, data)
+}
+
+func NewManifest(root, upstream, commit, license string) *Manifest {
+	return parse.NewManifest(root, upstream, commit, license)
+}
+
+func LoadManifest(filename string) (*Manifest, error) {
+	return parse.LoadManifest(filename)
+}
+
+func NewStats() *Stats {
+	return parse.NewStats()
+}
+
+func NewCache(dir string) *Cache {
+	return parse.NewCache(dir)
+}
+
+func Walk(node Node, v *Visitor) {
+	ast.Walk(node, v)
+}
+
+func Inspect(node Node, f func(Node) bool) {
+	ast.Inspect(node, f)
+}
+
+func Rewrite(root Node, pre, post RewriteFunc) {
+	ast.Rewrite(root, pre, post)
+}
+
+func Rename(n Node, name string) error {
+	return ast.Rename(n, name)
+}
+
+func Evaluate(root Node) error {
+	return ast.Evaluate(root)
+}
+
+func DOT(node Node) string {
+	return ast.DOT(node)
+}
+
+func ImportGraphDOT(doc *DocumentNode) string {
+	return ast.ImportGraphDOT(doc)
+}
+
+func Fingerprint(doc *DocumentNode) string {
+	return ast.Fingerprint(doc)
+}
@@ -0,0 +1,183 @@
+// Package sarif exports diagnostics in the Static Analysis Results Interchange
+// Format (SARIF) 2.1.0, read by code review tools to annotate sources.
+package sarif
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/13k/go-steam-language/parse"
+)
+
+const (
+	Version = "2.1.0"
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// ToolName and ToolURI identify the tool in the logs.
+const (
+	ToolName = "steamd"
+	ToolURI  = "https://github.com/13k/go-steam-language"
+)
+
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []*Run `json:"runs"`
+}
+
+// Run holds the results of a single analysis. Columns are counted in Unicode
+// code points, like the ones of diagnostics.
+type Run struct {
+	Tool       Tool      `json:"tool"`
+	ColumnKind string    `json:"columnKind"`
+	Results    []*Result `json:"results"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	Name           string  `json:"name"`
+	InformationURI string  `json:"informationUri,omitempty"`
+	Rules          []*Rule `json:"rules"`
+}
+
+// Rule describes a kind of result, identified by the code of its
+// diagnostics.
+type Rule struct {
+	ID string `json:"id"`
+}
+
+type Result struct {
+	RuleID    string      `json:"ruleId"`
+	RuleIndex int         `json:"ruleIndex"`
+	Level     string      `json:"level"`
+	Message   Message     `json:"message"`
+	Locations []*Location `json:"locations,omitempty"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type Region struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+// New returns a log with a run holding diags. Diagnostics without a file are
+// results without a location, and the ones without a position locate the
+// whole file.
+func New(diags []parse.Diagnostic) *Log {
+	run := &Run{
+		Tool:       Tool{Driver: Driver{Name: ToolName, InformationURI: ToolURI, Rules: []*Rule{}}},
+		ColumnKind: "unicodeCodePoints",
+		Results:    []*Result{},
+	}
+
+	codes := make(map[string]int)
+
+	for _, diag := range diags {
+		codes[diag.Code] = 0
+	}
+
+	for _, code := range sortedKeys(codes) {
+		codes[code] = len(run.Tool.Driver.Rules)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, &Rule{ID: code})
+	}
+
+	for _, diag := range diags {
+		result := &Result{
+			RuleID:    diag.Code,
+			RuleIndex: codes[diag.Code],
+			Level:     level(diag.Severity),
+			Message:   Message{Text: diag.Message},
+		}
+
+		if diag.Pos.Filename != "" {
+			loc := &Location{PhysicalLocation: PhysicalLocation{ArtifactLocation: ArtifactLocation{URI: fileURI(diag.Pos.Filename)}}}
+
+			if diag.Pos.Row > 0 {
+				loc.PhysicalLocation.Region = &Region{
+					StartLine:   diag.Pos.Row,
+					StartColumn: diag.Pos.Col,
+					EndLine:     diag.End.Row,
+					EndColumn:   diag.End.Col,
+				}
+			}
+
+			result.Locations = []*Location{loc}
+		}
+
+		run.Results = append(run.Results, result)
+	}
+
+	return &Log{Schema: Schema, Version: Version, Runs: []*Run{run}}
+}
+
+// Write writes the log of diags as JSON.
+func Write(w io.Writer, diags []parse.Diagnostic) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(New(diags))
+}
+
+func level(s parse.Severity) string {
+	if s == parse.SeverityWarning {
+		return "warning"
+	}
+
+	return "error"
+}
+
+// fileURI returns the relative reference of a relative filename, which
+// readers resolve against the root of the analyzed sources, and a file URI
+// otherwise.
+func fileURI(filename string) string {
+	u := &url.URL{Path: filepath.ToSlash(filename)}
+
+	if filepath.IsAbs(filename) {
+		u.Scheme = "file"
+
+		// volume names, like C:
+		if !strings.HasPrefix(u.Path, "/") {
+			u.Path = "/" + u.Path
+		}
+	}
+
+	return u.String()
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
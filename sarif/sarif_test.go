@@ -0,0 +1,96 @@
+package sarif
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/13k/go-steam-language/parse"
+)
+
+func TestWrite(t *testing.T) {
+	diags := []parse.Diagnostic{
+		{
+			Severity: parse.SeverityError,
+			Pos:      parse.Position{Filename: "schemas/msg.steamd", Row: 3, Col: 6},
+			End:      parse.Position{Filename: "schemas/msg.steamd", Row: 3, Col: 7},
+			Message:  `Unexpected token ";"`,
+			Code:     "unexpected-token",
+		},
+		{
+			Severity: parse.SeverityWarning,
+			Pos:      parse.Position{Filename: "schemas/msg.steamd", Row: 2, Col: 2},
+			End:      parse.Position{Filename: "schemas/msg.steamd", Row: 2, Col: 3},
+			Message:  "Obsolete member E::A has no reason",
+			Code:     "missing-reason",
+		},
+		{
+			Severity: parse.SeverityError,
+			Pos:      parse.Position{Filename: "schemas/emsg.steamd"},
+			Message:  "Unexpected EOF",
+			Code:     "unexpected-eof",
+		},
+	}
+
+	var buf bytes.Buffer
+
+	if err := Write(&buf, diags); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	var log Log
+
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	if log.Version != Version || len(log.Runs) != 1 {
+		t.Fatalf("mismatch: got %+v, but expected a single run of version %s", log, Version)
+	}
+
+	run := log.Runs[0]
+	var rules []string
+
+	for _, rule := range run.Tool.Driver.Rules {
+		rules = append(rules, rule.ID)
+	}
+
+	if expected := []string{"missing-reason", "unexpected-eof", "unexpected-token"}; len(rules) != 3 || rules[0] != expected[0] || rules[1] != expected[1] || rules[2] != expected[2] {
+		t.Fatalf("mismatch: got %v, but expected %v", rules, expected)
+	}
+
+	if len(run.Results) != 3 {
+		t.Fatalf("mismatch: got %d results, but expected 3", len(run.Results))
+	}
+
+	result := run.Results[0]
+	expected := Region{StartLine: 3, StartColumn: 6, EndLine: 3, EndColumn: 7}
+
+	if result.RuleID != "unexpected-token" || result.RuleIndex != 2 || result.Level != "error" || result.Message.Text != `Unexpected token ";"` {
+		t.Fatalf("mismatch: got %+v", result)
+	}
+
+	if loc := result.Locations[0].PhysicalLocation; loc.ArtifactLocation.URI != "schemas/msg.steamd" || loc.Region == nil || *loc.Region != expected {
+		t.Fatalf("mismatch: got %+v, but expected %+v in schemas/msg.steamd", loc, expected)
+	}
+
+	if result := run.Results[1]; result.Level != "warning" || result.RuleIndex != 0 {
+		t.Fatalf("mismatch: got %+v, but expected a missing-reason warning", result)
+	}
+
+	if loc := run.Results[2].Locations[0].PhysicalLocation; loc.ArtifactLocation.URI != "schemas/emsg.steamd" || loc.Region != nil {
+		t.Fatalf("mismatch: got %+v, but expected the whole of schemas/emsg.steamd", loc)
+	}
+}
+
+func TestFileURI(t *testing.T) {
+	for filename, expected := range map[string]string{
+		"msg.steamd":          "msg.steamd",
+		"schemas/a b.steamd":  "schemas/a%20b.steamd",
+		"/schemas/msg.steamd": "file:///schemas/msg.steamd",
+	} {
+		if actual := fileURI(filename); actual != expected {
+			t.Fatalf("mismatch: got %q, but expected %q", actual, expected)
+		}
+	}
+}
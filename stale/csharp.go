@@ -0,0 +1,271 @@
+package stale
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/13k/go-steam-language/ast"
+)
+
+var (
+	csEnumRe   = regexp.MustCompile(`^\s*public\s+enum\s+(\w+)\s*(?::\s*(\w+))?`)
+	csClassRe  = regexp.MustCompile(`^\s*public\s+(?:sealed\s+)?class\s+(\w+)`)
+	csMemberRe = regexp.MustCompile(`^\s*(\w+)\s*=\s*(.+?)\s*,\s*(?://.*)?$`)
+	csArrayRe  = regexp.MustCompile(`^\s*(\w+)\s*=\s*new\s+[\w.]+\s*\[\s*(\d+)\s*\]\s*;`)
+)
+
+// csExpr is the unevaluated value of a member of a C# enum.
+type csExpr struct {
+	enum  string
+	name  string
+	value string
+}
+
+// parseCSharp finds the enums of generated C# code, and the arrays that the
+// constructors of its classes allocate. It reads the code line by line, as the
+// csharp backend writes it, and evaluates the values of members once all of
+// them are known.
+func parseCSharp(src []byte) (*code, error) {
+	c := newCode()
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+
+	var (
+		enum, class string
+		exprs       []*csExpr
+	)
+
+	for row := 1; scanner.Scan(); row++ {
+		line := scanner.Text()
+
+		if m := csEnumRe.FindStringSubmatch(line); m != nil {
+			storage := ast.StorageInt
+
+			if m[2] != "" {
+				t, ok := ast.ParseStorageType(m[2])
+
+				if !ok {
+					return nil, fmt.Errorf("%d: Unknown storage type %q of enum %s", row, m[2], m[1])
+				}
+
+				storage = t
+			}
+
+			enum, class = m[1], ""
+			c.enums[enum] = &codeEnum{line: row, storage: storage, members: make(map[string]*codeMember)}
+			continue
+		}
+
+		if m := csClassRe.FindStringSubmatch(line); m != nil {
+			enum, class = "", m[1]
+			continue
+		}
+
+		switch trimmed := strings.TrimSpace(line); {
+		case enum != "" && trimmed == "}":
+			enum = ""
+		case enum != "":
+			if m := csMemberRe.FindStringSubmatch(line); m != nil {
+				c.enums[enum].members[m[1]] = &codeMember{line: row}
+				exprs = append(exprs, &csExpr{enum: enum, name: m[1], value: m[2]})
+			}
+		case class != "":
+			if m := csArrayRe.FindStringSubmatch(line); m != nil {
+				size, _ := strconv.Atoi(m[2])
+				c.array(class, m[1], &codeArray{line: row, size: size})
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	e := &csEvaluator{code: c, exprs: make(map[string]*csExpr), visiting: make(map[string]bool), done: make(map[string]bool)}
+
+	for _, x := range exprs {
+		e.exprs[x.enum+"."+x.name] = x
+	}
+
+	for _, x := range exprs {
+		if _, err := e.member(x.enum, x.name); err != nil {
+			return nil, fmt.Errorf("%d: %v", c.enums[x.enum].members[x.name].line, err)
+		}
+	}
+
+	return c, nil
+}
+
+// csEvaluator computes the values of C# enum members, written by the csharp
+// backend with the operators of steamd, which have the same precedence in C#,
+// and casts of the members of other enums.
+type csEvaluator struct {
+	code     *code
+	exprs    map[string]*csExpr
+	visiting map[string]bool
+	done     map[string]bool
+}
+
+func (e *csEvaluator) member(enum, name string) (uint64, error) {
+	key := enum + "." + name
+	x, ok := e.exprs[key]
+
+	if !ok {
+		return 0, fmt.Errorf("Unknown member %s", key)
+	}
+
+	m := e.code.enums[enum].members[name]
+
+	if e.done[key] {
+		return m.value, nil
+	}
+
+	if e.visiting[key] {
+		return 0, fmt.Errorf("Circular value of %s", key)
+	}
+
+	e.visiting[key] = true
+	defer delete(e.visiting, key)
+
+	p := &csParser{e: e, enum: enum, tokens: csTokens(x.value)}
+	v, err := p.or()
+
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("Unexpected %q in value of %s", p.tokens[p.pos], key)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	m.value = v
+	e.done[key] = true
+
+	return v, nil
+}
+
+var csTokenRe = regexp.MustCompile(`<<|>>|[|+\-()]|[\w.]+`)
+
+func csTokens(s string) []string {
+	return csTokenRe.FindAllString(s, -1)
+}
+
+// csParser evaluates a value expression of a member of enum.
+type csParser struct {
+	e      *csEvaluator
+	enum   string
+	tokens []string
+	pos    int
+}
+
+func (p *csParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+
+	return ""
+}
+
+func (p *csParser) or() (uint64, error) {
+	v, err := p.shift()
+
+	for err == nil && p.peek() == "|" {
+		p.pos++
+
+		var rhs uint64
+
+		if rhs, err = p.shift(); err == nil {
+			v |= rhs
+		}
+	}
+
+	return v, err
+}
+
+func (p *csParser) shift() (uint64, error) {
+	v, err := p.add()
+
+	for err == nil && (p.peek() == "<<" || p.peek() == ">>") {
+		op := p.peek()
+		p.pos++
+
+		var rhs uint64
+
+		if rhs, err = p.add(); err == nil {
+			if op == "<<" {
+				v <<= rhs
+			} else {
+				v >>= rhs
+			}
+		}
+	}
+
+	return v, err
+}
+
+func (p *csParser) add() (uint64, error) {
+	v, err := p.unary()
+
+	for err == nil && p.peek() == "+" {
+		p.pos++
+
+		var rhs uint64
+
+		if rhs, err = p.unary(); err == nil {
+			v += rhs
+		}
+	}
+
+	return v, err
+}
+
+func (p *csParser) unary() (uint64, error) {
+	switch tok := p.peek(); {
+	case tok == "-":
+		p.pos++
+		v, err := p.unary()
+		return -v, err
+	case tok == "(":
+		// a cast, like (uint)EFlags.A, is a parenthesized type followed by
+		// an operand
+		if p.pos+3 < len(p.tokens) && p.tokens[p.pos+2] == ")" && isCSOperand(p.tokens[p.pos+3]) {
+			if _, ok := ast.ParseStorageType(p.tokens[p.pos+1]); ok {
+				p.pos += 3
+				return p.unary()
+			}
+		}
+
+		p.pos++
+		v, err := p.or()
+
+		if err == nil && p.peek() != ")" {
+			err = fmt.Errorf("Missing ) in value of %s", p.enum)
+		}
+
+		p.pos++
+
+		return v, err
+	case tok == "":
+		return 0, fmt.Errorf("Missing operand in value of %s", p.enum)
+	}
+
+	tok := p.tokens[p.pos]
+	p.pos++
+
+	if tok[0] >= '0' && tok[0] <= '9' {
+		return strconv.ParseUint(strings.TrimRight(strings.ToLower(tok), "ul"), 0, 64)
+	}
+
+	if i := strings.LastIndex(tok, "."); i >= 0 {
+		return p.e.member(tok[:i], tok[i+1:])
+	}
+
+	return p.e.member(p.enum, tok)
+}
+
+func isCSOperand(tok string) bool {
+	return tok == "(" || tok == "-" || tok != "" && tok != ")" && !strings.ContainsAny(tok[:1], "|+<>")
+}
@@ -0,0 +1,120 @@
+package stale
+
+import (
+	"errors"
+	goast "go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"github.com/13k/go-steam-language/ast"
+)
+
+// goStorageTypes maps the underlying types of generated enums to the storage
+// types they're generated from.
+var goStorageTypes = map[types.BasicKind]ast.StorageType{
+	types.Uint8:  ast.StorageByte,
+	types.Int8:   ast.StorageSByte,
+	types.Int16:  ast.StorageShort,
+	types.Uint16: ast.StorageUShort,
+	types.Int32:  ast.StorageInt,
+	types.Uint32: ast.StorageUInt,
+	types.Int64:  ast.StorageLong,
+	types.Uint64: ast.StorageULong,
+}
+
+// noImporter fails every import. Declarations of generated code only depend
+// on other declarations of the file, which type-check without the imports.
+type noImporter struct{}
+
+func (noImporter) Import(path string) (*types.Package, error) {
+	return nil, errors.New("imports aren't checked")
+}
+
+// parseGo finds the enums of generated Go code, integer types with constants
+// of the type named after it, and the arrays of its structs. Constant values
+// are computed by the type checker, whose errors are ignored.
+func parseGo(filename string, src []byte) (*code, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	conf := types.Config{Importer: noImporter{}, Error: func(error) {}}
+	pkg, _ := conf.Check(file.Name.Name, fset, []*goast.File{file}, nil)
+	c := newCode()
+	scope := pkg.Scope()
+
+	line := func(obj types.Object) int {
+		return fset.Position(obj.Pos()).Line
+	}
+
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+
+		if !ok || tn.IsAlias() {
+			continue
+		}
+
+		switch t := tn.Type().Underlying().(type) {
+		case *types.Basic:
+			if storage, ok := goStorageTypes[t.Kind()]; ok {
+				c.enums[name] = &codeEnum{line: line(tn), storage: storage, members: make(map[string]*codeMember)}
+			}
+		case *types.Struct:
+			for i := 0; i < t.NumFields(); i++ {
+				if a, ok := t.Field(i).Type().(*types.Array); ok {
+					c.array(name, t.Field(i).Name(), &codeArray{line: line(t.Field(i)), size: int(a.Len())})
+				}
+			}
+		}
+	}
+
+	for _, name := range scope.Names() {
+		cn, ok := scope.Lookup(name).(*types.Const)
+
+		if !ok {
+			continue
+		}
+
+		named, ok := cn.Type().(*types.Named)
+
+		if !ok {
+			continue
+		}
+
+		e, ok := c.enums[named.Obj().Name()]
+
+		if !ok || !strings.HasPrefix(name, named.Obj().Name()+"_") {
+			continue
+		}
+
+		// values the type checker can't compute, like the ones of code that
+		// doesn't compile, aren't checked
+		e.members[name] = &codeMember{line: line(cn), value: constValue(cn.Val()), unknown: cn.Val().Kind() != constant.Int}
+	}
+
+	// integer types without members aren't enums, like typedefs
+	for name, e := range c.enums {
+		if len(e.members) == 0 {
+			delete(c.enums, name)
+		}
+	}
+
+	return c, nil
+}
+
+// constValue converts v to the two's complement representation of values.
+func constValue(v constant.Value) uint64 {
+	if u, ok := constant.Uint64Val(v); ok {
+		return u
+	}
+
+	i, _ := constant.Int64Val(v)
+
+	return uint64(i)
+}
@@ -0,0 +1,313 @@
+// Package stale checks code generated by earlier runs, in Go or C#, against
+// the current schemas, catching enum values, enum storage types and array sizes
+// that changed since the code was generated.
+package stale
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/backend"
+)
+
+// Language is the language of generated code.
+type Language int
+
+const (
+	Go Language = iota
+	CSharp
+)
+
+// LanguageOf returns the language of the generated file named filename, from
+// its extension.
+func LanguageOf(filename string) (Language, bool) {
+	switch filepath.Ext(filename) {
+	case ".go":
+		return Go, true
+	case ".cs":
+		return CSharp, true
+	}
+
+	return 0, false
+}
+
+func (l Language) String() string {
+	switch l {
+	case Go:
+		return "go"
+	case CSharp:
+		return "csharp"
+	default:
+		return fmt.Sprintf("Language(%d)", int(l))
+	}
+}
+
+// Issue is a declaration of generated code that doesn't match the schemas
+// anymore. Name is the qualified steamd name of the declaration, like
+// EMsg::Multi.
+type Issue struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+func (i *Issue) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", i.File, i.Line, i.Name, i.Message)
+}
+
+// code is what generated code declares: enums and fixed size arrays, by their
+// names in the generated language.
+type code struct {
+	enums  map[string]*codeEnum
+	arrays map[string]map[string]*codeArray
+}
+
+type codeEnum struct {
+	line    int
+	storage ast.StorageType
+	members map[string]*codeMember
+}
+
+type codeMember struct {
+	line    int
+	value   uint64
+	unknown bool
+}
+
+type codeArray struct {
+	line int
+	size int
+}
+
+func newCode() *code {
+	return &code{enums: make(map[string]*codeEnum), arrays: make(map[string]map[string]*codeArray)}
+}
+
+func (c *code) array(class, field string, a *codeArray) {
+	if c.arrays[class] == nil {
+		c.arrays[class] = make(map[string]*codeArray)
+	}
+
+	c.arrays[class][field] = a
+}
+
+// Check parses src, a file generated in lang from the schemas rooted at docs,
+// their imports included, and returns the issues of the enums and classes it
+// declares, in order of their lines. Declarations of the schemas that the file
+// doesn't declare aren't issues, since files hold parts of the schemas.
+func Check(lang Language, filename string, src []byte, docs ...*ast.DocumentNode) ([]*Issue, error) {
+	var (
+		c   *code
+		err error
+	)
+
+	switch lang {
+	case Go:
+		c, err = parseGo(filename, src)
+	case CSharp:
+		c, err = parseCSharp(src)
+	default:
+		return nil, fmt.Errorf("Unknown language %v", lang)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newChecker(lang, filename, docs).check(c), nil
+}
+
+type checker struct {
+	lang    Language
+	file    string
+	enums   map[string]*ast.EnumNode
+	classes map[string]*ast.ClassNode
+	issues  []*Issue
+}
+
+func newChecker(lang Language, filename string, docs []*ast.DocumentNode) *checker {
+	ch := &checker{
+		lang:    lang,
+		file:    filename,
+		enums:   make(map[string]*ast.EnumNode),
+		classes: make(map[string]*ast.ClassNode),
+	}
+
+	for _, doc := range docs {
+		ast.Walk(doc, &ast.Visitor{
+			Class: func(n *ast.ClassNode) bool {
+				ch.classes[ch.typeName(n)] = n
+				return true
+			},
+			Enum: func(n *ast.EnumNode) bool {
+				ch.enums[ch.typeName(n)] = n
+				return false
+			},
+		})
+	}
+
+	return ch
+}
+
+// typeName returns the name generated for n, a class or enum. Go prefixes the
+// names of namespaces and enclosing classes.
+func (ch *checker) typeName(n ast.Node) string {
+	if ch.lang == Go {
+		return strings.Join(append(ast.ScopePath(n), n.Name()), "")
+	}
+
+	return n.Name()
+}
+
+// memberName returns the name generated for m, a member of an enum.
+func (ch *checker) memberName(m *ast.EnumMemberNode) string {
+	if ch.lang == Go {
+		return ch.typeName(m.Parent()) + "_" + m.Name()
+	}
+
+	return m.Name()
+}
+
+func (ch *checker) fieldName(f *ast.PropertyNode) string {
+	r, size := utf8.DecodeRuneInString(f.Name())
+	return string(unicode.ToUpper(r)) + f.Name()[size:]
+}
+
+func (ch *checker) report(line int, name, format string, args ...interface{}) {
+	ch.issues = append(ch.issues, &Issue{File: ch.file, Line: line, Name: name, Message: fmt.Sprintf(format, args...)})
+}
+
+func (ch *checker) check(c *code) []*Issue {
+	for name, e := range c.enums {
+		ch.checkEnum(name, e)
+	}
+
+	for name, fields := range c.arrays {
+		ch.checkClass(name, fields)
+	}
+
+	sort.SliceStable(ch.issues, func(i, j int) bool {
+		if ch.issues[i].Line != ch.issues[j].Line {
+			return ch.issues[i].Line < ch.issues[j].Line
+		}
+
+		return ch.issues[i].Name < ch.issues[j].Name
+	})
+
+	return ch.issues
+}
+
+func (ch *checker) checkEnum(name string, e *codeEnum) {
+	n, ok := ch.enums[name]
+
+	if !ok {
+		ch.report(e.line, name, "Enum isn't declared anymore")
+		return
+	}
+
+	qualified := backend.QualifiedName(n)
+
+	if e.storage != n.Type {
+		ch.report(e.line, qualified, "Storage type %s doesn't match %s", e.storage, n.Type)
+	}
+
+	if err := ast.Evaluate(n); err != nil {
+		ch.report(e.line, qualified, "%v", err)
+		return
+	}
+
+	members := make(map[string]bool)
+
+	for _, m := range n.Members() {
+		memberName := ch.memberName(m)
+		members[memberName] = true
+		cm, ok := e.members[memberName]
+
+		if !ok {
+			if !m.Removed {
+				ch.report(e.line, backend.QualifiedName(m), "Member is missing")
+			}
+
+			continue
+		}
+
+		v, _ := m.ResolvedValue()
+
+		if mask := storageMask(n.Type); !cm.unknown && v&mask != cm.value&mask {
+			ch.report(cm.line, backend.QualifiedName(m), "Value %s doesn't match %s", formatValue(cm.value&mask, n.Type), formatValue(v&mask, n.Type))
+		}
+	}
+
+	for memberName, cm := range e.members {
+		if !members[memberName] {
+			ch.report(cm.line, qualified+"::"+strings.TrimPrefix(memberName, name+"_"), "Member isn't declared anymore")
+		}
+	}
+}
+
+func (ch *checker) checkClass(name string, arrays map[string]*codeArray) {
+	n, ok := ch.classes[name]
+
+	if !ok {
+		line := 0
+
+		for _, a := range arrays {
+			if line == 0 || a.line < line {
+				line = a.line
+			}
+		}
+
+		ch.report(line, name, "Class isn't declared anymore")
+		return
+	}
+
+	fields := make(map[string]*ast.PropertyNode)
+
+	for _, f := range n.Fields() {
+		fields[ch.fieldName(f)] = f
+	}
+
+	for fieldName, a := range arrays {
+		f, ok := fields[fieldName]
+
+		if !ok {
+			ch.report(a.line, backend.QualifiedName(n)+"::"+fieldName, "Field isn't declared anymore")
+			continue
+		}
+
+		size, ok, err := f.ArrayLen()
+
+		switch {
+		case err != nil:
+			ch.report(a.line, backend.QualifiedName(f), "%v", err)
+		case !ok:
+			ch.report(a.line, backend.QualifiedName(f), "Field isn't a fixed size array anymore")
+		case size != a.size:
+			ch.report(a.line, backend.QualifiedName(f), "Array size %d doesn't match %d", a.size, size)
+		}
+	}
+}
+
+// storageMask keeps the bits of a value stored in t.
+func storageMask(t ast.StorageType) uint64 {
+	if t.Size() == 8 {
+		return ^uint64(0)
+	}
+
+	return 1<<(8*uint(t.Size())) - 1
+}
+
+// formatValue renders v, masked to t, as a signed value for signed types.
+func formatValue(v uint64, t ast.StorageType) string {
+	if bits := 8 * uint(t.Size()); t.Signed() && v&(1<<(bits-1)) != 0 {
+		return fmt.Sprintf("%d", int64(v|^storageMask(t)))
+	}
+
+	return fmt.Sprintf("%d", v)
+}
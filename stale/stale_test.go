@@ -0,0 +1,153 @@
+package stale
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/backend"
+	_ "github.com/13k/go-steam-language/backend/csharp"
+	_ "github.com/13k/go-steam-language/generator"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+const oldSchema = `enum EMsg { Invalid = 0; Multi = 1; Other = Multi + 1; };
+enum EFlags<byte> flags { A = 1; B = 2; AB = A | B; Old = 4; removed };
+enum ENeg<short> { X = -1; Y = 0x10 << 2; };
+namespace NS { enum EInner { I = 1; }; }
+enum EGone { G = 1; };
+class MsgHdr { byte<20> key; uint<4> ids; EMsg msg; };
+`
+
+const newSchema = `enum EMsg { Invalid = 0; Multi = 2; Other = Multi + 1; Added = 9; };
+enum EFlags<ushort> flags { A = 1; B = 2; AB = A | B; };
+enum ENeg<short> { X = -2; Y = 0x10 << 2; };
+namespace NS { enum EInner { I = 1; }; }
+class MsgHdr { byte<16> key; uint ids; EMsg msg; };
+`
+
+func analyze(t *testing.T, source string) *ast.DocumentNode {
+	doc, err := parse.NewAnalyzer(token.NewTokenizer([]byte(source)), "schema.steamd").Analyze()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	return doc
+}
+
+func generate(t *testing.T, name string, doc *ast.DocumentNode) backend.OutputFile {
+	gen, _ := backend.Lookup(name)
+	files, err := gen.Generate(doc, backend.Options{Package: "steamlang"})
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	return files[0]
+}
+
+func TestCheck(t *testing.T) {
+	oldDoc, newDoc := analyze(t, oldSchema), analyze(t, newSchema)
+
+	tests := []struct {
+		gen      string
+		expected []string
+	}{
+		{"go", []string{
+			"EMsg::Added: Member is missing",
+			"EMsg::Multi: Value 1 doesn't match 2",
+			"EMsg::Other: Value 2 doesn't match 3",
+			"EFlags: Storage type byte doesn't match ushort",
+			"EFlags::Old: Member isn't declared anymore",
+			"ENeg::X: Value -1 doesn't match -2",
+			"EGone: Enum isn't declared anymore",
+			"MsgHdr::key: Array size 20 doesn't match 16",
+			"MsgHdr::ids: Field isn't a fixed size array anymore",
+		}},
+		{"csharp", []string{
+			"EMsg::Added: Member is missing",
+			"EMsg::Multi: Value 1 doesn't match 2",
+			"EMsg::Other: Value 2 doesn't match 3",
+			"EFlags: Storage type byte doesn't match ushort",
+			"EFlags::Old: Member isn't declared anymore",
+			"ENeg::X: Value -1 doesn't match -2",
+			"EGone: Enum isn't declared anymore",
+			"MsgHdr::key: Array size 20 doesn't match 16",
+			"MsgHdr::ids: Field isn't a fixed size array anymore",
+		}},
+	}
+
+	for _, test := range tests {
+		file := generate(t, test.gen, oldDoc)
+		lang, ok := LanguageOf(file.Name)
+
+		if !ok {
+			t.Fatalf("expected language of %s", file.Name)
+		}
+
+		issues, err := Check(lang, file.Name, file.Content, oldDoc)
+
+		if err != nil || len(issues) != 0 {
+			t.Fatalf("mismatch: got %v %v, but expected no issues for %s", issues, err, test.gen)
+		}
+
+		issues, err = Check(lang, file.Name, file.Content, newDoc)
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		var got []string
+
+		for _, issue := range issues {
+			got = append(got, issue.Name+": "+issue.Message)
+
+			if issue.File != file.Name || issue.Line == 0 {
+				t.Fatalf("mismatch: got %s, but expected a line of %s", issue, file.Name)
+			}
+		}
+
+		if fmt.Sprint(got) != fmt.Sprint(test.expected) {
+			t.Fatalf("mismatch for %s: got\n%q\nbut expected\n%q", test.gen, got, test.expected)
+		}
+	}
+}
+
+func TestCheckCSharpValues(t *testing.T) {
+	src := `namespace steamlang
+{
+	public enum EFlags : byte
+	{
+		A = 1,
+		B = 0x2, // second
+		AB = A | B,
+		C = (AB + 1) << 1,
+	}
+
+	public enum EOther : uint
+	{
+		X = (uint)EFlags.C | 0x100u,
+	}
+
+	public enum ENeg : short
+	{
+		Y = -1,
+	}
+}
+`
+	doc := analyze(t, `enum EFlags<byte> { A = 1; B = 2; AB = A | B; C = (AB + 1) << 1; };
+enum EOther<uint> { X = EFlags::C | 0x100; };
+enum ENeg<short> { Y = -1; };`)
+
+	issues, err := Check(CSharp, "enums.cs", []byte(src), doc)
+
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("mismatch: got %v %v, but expected no issues", issues, err)
+	}
+
+	if _, err := Check(CSharp, "enums.cs", []byte("public enum E\n{\n\tA = B,\n\tB = A,\n}\n"), doc); err == nil {
+		t.Fatalf("expected circular value error")
+	}
+}
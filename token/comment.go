@@ -0,0 +1,20 @@
+package token
+
+import "fmt"
+
+// blockCommentStart and blockCommentEnd delimit block comments, which may span
+// lines.
+var (
+	blockCommentStart = []byte("/*")
+	blockCommentEnd   = []byte("*/")
+)
+
+// CommentError reports a block comment left unterminated at the end of the
+// input.
+type CommentError struct {
+	Token *Token
+}
+
+func (e *CommentError) Error() string {
+	return fmt.Sprintf("%d:%d: Unterminated block comment", e.Token.Row, e.Token.Col)
+}
@@ -0,0 +1,17 @@
+package token
+
+import "fmt"
+
+// byteOrderMark is the UTF-8 encoding of U+FEFF, which editors on Windows put
+// at the start of files. It's scanned as whitespace taking no column.
+var byteOrderMark = []byte("\xef\xbb\xbf")
+
+// EncodingError reports input that isn't valid UTF-8. Token is the first
+// invalid byte.
+type EncodingError struct {
+	Token *Token
+}
+
+func (e *EncodingError) Error() string {
+	return fmt.Sprintf("%d:%d: Invalid UTF-8 byte %#02x", e.Token.Row, e.Token.Col, e.Token.Raw[0])
+}
@@ -0,0 +1,127 @@
+package token
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	KeywordNone Keyword = iota
+	KeywordClass
+	KeywordConst
+	KeywordEnum
+	KeywordFlags
+	KeywordNamespace
+	KeywordObsolete
+	KeywordOptional
+	KeywordRemoved
+	KeywordTypedef
+	KeywordUnion
+)
+
+// Keyword classifies the identifiers with a meaning in the grammar. Reserved
+// keywords can't start members, while the others are only keywords where
+// they're expected, and can name anything. Keywords are lowercase, so
+// identifiers differing in case, like Flags, aren't keywords unless the
+// Tokenizer isn't case-sensitive. Annotation names, like endian, and the
+// property flags other than const aren't keywords either.
+type Keyword int
+
+var keywords = map[string]Keyword{
+	"class":     KeywordClass,
+	"const":     KeywordConst,
+	"enum":      KeywordEnum,
+	"flags":     KeywordFlags,
+	"namespace": KeywordNamespace,
+	"obsolete":  KeywordObsolete,
+	"optional":  KeywordOptional,
+	"removed":   KeywordRemoved,
+	"typedef":   KeywordTypedef,
+	"union":     KeywordUnion,
+}
+
+// annotations are the names of field annotations, like endian in endian:big.
+var annotations = []string{"encoding", "endian", "if", "term"}
+
+// Keywords returns the spellings of the keywords, sorted.
+func Keywords() []string {
+	names := make([]string, 0, len(keywords))
+
+	for name := range keywords {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Annotations returns the names of the field annotations, sorted. They aren't
+// keywords, and can name declarations and members.
+func Annotations() []string {
+	return append([]string(nil), annotations...)
+}
+
+// LookupKeyword returns the keyword spelled by ident, KeywordNone if it's not
+// one.
+func LookupKeyword(ident []byte) Keyword {
+	return keywords[string(ident)]
+}
+
+func (k Keyword) String() string {
+	switch k {
+	case KeywordNone:
+		return ""
+	case KeywordClass:
+		return "class"
+	case KeywordConst:
+		return "const"
+	case KeywordEnum:
+		return "enum"
+	case KeywordFlags:
+		return "flags"
+	case KeywordNamespace:
+		return "namespace"
+	case KeywordObsolete:
+		return "obsolete"
+	case KeywordOptional:
+		return "optional"
+	case KeywordRemoved:
+		return "removed"
+	case KeywordTypedef:
+		return "typedef"
+	case KeywordUnion:
+		return "union"
+	default:
+		panic(fmt.Errorf("Unknown Keyword %d", k))
+	}
+}
+
+// IsReserved reports whether k can't start a class member, since it would be
+// mistaken for the keyword: class and enum start nested declarations, optional
+// starts an optional field, and obsolete and removed annotate the member
+// before. Names following a type or keyword, like those of declarations, can
+// be any keyword.
+func (k Keyword) IsReserved() bool {
+	switch k {
+	case KeywordClass, KeywordEnum, KeywordOptional:
+		return true
+	default:
+		return k.IsAttribute()
+	}
+}
+
+// IsAttribute reports whether k annotates the member before it.
+func (k Keyword) IsAttribute() bool {
+	return k == KeywordObsolete || k == KeywordRemoved
+}
+
+// IsDeclaration reports whether k starts a declaration.
+func (k Keyword) IsDeclaration() bool {
+	switch k {
+	case KeywordClass, KeywordEnum, KeywordNamespace, KeywordTypedef, KeywordUnion:
+		return true
+	default:
+		return false
+	}
+}
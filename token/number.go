@@ -0,0 +1,108 @@
+package token
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Number is the value of an OpNumber token: a decimal or hexadecimal integer
+// literal, optionally negative.
+type Number struct {
+	abs uint64
+	neg bool
+}
+
+func (n Number) Negative() bool {
+	return n.neg
+}
+
+// Int64 returns the value as an int64 and whether it fits.
+func (n Number) Int64() (int64, bool) {
+	if n.neg {
+		return -int64(n.abs), n.abs <= 1<<63
+	}
+
+	return int64(n.abs), n.abs < 1<<63
+}
+
+// Uint64 returns the value as an uint64 and whether it fits.
+func (n Number) Uint64() (uint64, bool) {
+	return n.abs, !n.neg || n.abs == 0
+}
+
+// NumberError reports a numeric literal that can't be represented in 64 bits.
+type NumberError struct {
+	Token *Token
+	Err   error
+}
+
+func (e *NumberError) Error() string {
+	return fmt.Sprintf("%d:%d: Number %q out of range", e.Token.Row, e.Token.Col, e.Token.Raw)
+}
+
+func (e *NumberError) Unwrap() error {
+	return e.Err
+}
+
+// isNumber reports whether an identifier lexeme is an integer literal.
+func isNumber(lit []byte) bool {
+	if len(lit) > 0 && lit[0] == '-' {
+		lit = lit[1:]
+	}
+
+	if len(lit) > 2 && lit[0] == '0' && (lit[1] == 'x' || lit[1] == 'X') {
+		for _, c := range lit[2:] {
+			if !isHexDigit(c) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for _, c := range lit {
+		if !isDigit(c) {
+			return false
+		}
+	}
+
+	return len(lit) > 0
+}
+
+func parseNumber(lit []byte) (Number, error) {
+	var n Number
+
+	if lit[0] == '-' {
+		n.neg = true
+		lit = lit[1:]
+	}
+
+	base := 10
+
+	if len(lit) > 2 && lit[0] == '0' && (lit[1] == 'x' || lit[1] == 'X') {
+		base = 16
+		lit = lit[2:]
+	}
+
+	abs, err := strconv.ParseUint(string(lit), base, 64)
+
+	if err != nil {
+		return n, err
+	}
+
+	n.abs = abs
+
+	if n.neg && abs > 1<<63 {
+		return n, strconv.ErrRange
+	}
+
+	return n, nil
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
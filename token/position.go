@@ -0,0 +1,281 @@
+package token
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"unicode/utf8"
+)
+
+// Pos is a compact position in the files of a FileSet: the base of a file plus
+// a byte offset in it. The zero value, NoPos, is no position.
+type Pos int
+
+const NoPos Pos = 0
+
+func (p Pos) IsValid() bool {
+	return p != NoPos
+}
+
+// Position is a resolved Pos. Offset is the byte offset in the file, and Row
+// and Col are 1-based, with Col counting runes. Row is 0 for invalid positions.
+type Position struct {
+	Filename string
+	Offset   int
+	Row      int
+	Col      int
+}
+
+func (p Position) IsValid() bool {
+	return p.Row > 0
+}
+
+// String returns the position as "file:row:col", leaving out the parts that
+// are missing, or "-" if there are none.
+func (p Position) String() string {
+	s := p.Filename
+
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+
+		s += fmt.Sprintf("%d:%d", p.Row, p.Col)
+	}
+
+	if s == "" {
+		s = "-"
+	}
+
+	return s
+}
+
+// wideRune is a rune that isn't one column per byte: a rune encoded in more
+// than one byte, a tab expanded to a tab stop of width tab, or a leading byte
+// order mark, which takes no column.
+type wideRune struct {
+	offset int
+	size   int
+	tab    int
+	bom    bool
+}
+
+// next returns the column following r at col.
+func (r wideRune) next(col int) int {
+	switch {
+	case r.bom:
+		return col
+	case r.tab > 1:
+		return tabStop(col, r.tab)
+	default:
+		return col + 1
+	}
+}
+
+// File is a file registered in a FileSet. It learns the offsets of its lines,
+// and of its multi-byte runes and expanded tabs, from AddLine or as a
+// Tokenizer scans it, so only positions already scanned resolve to rows and
+// columns. Columns agree with the ones of the tokens.
+type File struct {
+	name string
+	base int
+	size int
+
+	mu    sync.Mutex
+	lines []int
+	wide  []wideRune
+}
+
+func (f *File) Name() string {
+	return f.name
+}
+
+// Base returns the Pos of the first byte of the file.
+func (f *File) Base() int {
+	return f.base
+}
+
+func (f *File) Size() int {
+	return f.size
+}
+
+// LineCount returns the number of lines known so far.
+func (f *File) LineCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.lines)
+}
+
+// AddLine records the offset of the start of a line. Offsets that don't follow
+// the last line recorded, or that are past the end of the file, are ignored.
+// A line can start at the end of the file, after a trailing newline.
+func (f *File) AddLine(offset int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if offset > f.lines[len(f.lines)-1] && offset <= f.size {
+		f.lines = append(f.lines, offset)
+	}
+}
+
+// scan records the lines, multi-byte runes and tabs of data, found at offset
+// and tokenized with tabWidth.
+func (f *File) scan(offset int, data []byte, tabWidth int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := 0; i < len(data); {
+		r, s := utf8.DecodeRune(data[i:])
+		off := offset + i
+		i += s
+
+		switch {
+		case r == '\n':
+			if next := off + 1; next > f.lines[len(f.lines)-1] && next <= f.size {
+				f.lines = append(f.lines, next)
+			}
+		case off == 0 && r == '\uFEFF':
+			f.addWide(wideRune{offset: off, size: s, bom: true})
+		case r == '\t' && tabWidth > 1:
+			f.addWide(wideRune{offset: off, size: s, tab: tabWidth})
+		case s > 1:
+			f.addWide(wideRune{offset: off, size: s})
+		}
+	}
+}
+
+// SetLinesForContent records the lines and multi-byte runes of data, the
+// content of the whole file, for positions to resolve without tokenizing it.
+func (f *File) SetLinesForContent(data []byte) {
+	f.scan(0, data, 1)
+}
+
+// addWide records r unless it was recorded already.
+func (f *File) addWide(r wideRune) {
+	if n := len(f.wide); n == 0 || r.offset > f.wide[n-1].offset {
+		f.wide = append(f.wide, r)
+	}
+}
+
+// Pos returns the Pos of offset, NoPos if it's out of the file.
+func (f *File) Pos(offset int) Pos {
+	if offset < 0 || offset > f.size {
+		return NoPos
+	}
+
+	return Pos(f.base + offset)
+}
+
+// Offset returns the byte offset of p in the file.
+func (f *File) Offset(p Pos) int {
+	return int(p) - f.base
+}
+
+// LinePos returns the Pos of a 1-based row and column, NoPos if the row isn't
+// known.
+func (f *File) LinePos(row, col int) Pos {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if row < 1 || row > len(f.lines) || col < 1 {
+		return NoPos
+	}
+
+	offset := f.lines[row-1]
+	i := sort.Search(len(f.wide), func(i int) bool { return f.wide[i].offset >= offset })
+
+	for c := 1; c < col || (i < len(f.wide) && f.wide[i].offset == offset && f.wide[i].bom); {
+		if i < len(f.wide) && f.wide[i].offset == offset {
+			c = f.wide[i].next(c)
+			offset += f.wide[i].size
+			i++
+		} else {
+			c++
+			offset++
+		}
+	}
+
+	return f.Pos(offset)
+}
+
+// Position resolves p, a position in the file.
+func (f *File) Position(p Pos) Position {
+	offset := f.Offset(p)
+
+	if !p.IsValid() || offset < 0 || offset > f.size {
+		return Position{}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	row := sort.Search(len(f.lines), func(i int) bool { return f.lines[i] > offset })
+	cursor := f.lines[row-1]
+	col := 1
+
+	for i := sort.Search(len(f.wide), func(i int) bool { return f.wide[i].offset >= cursor }); i < len(f.wide) && f.wide[i].offset < offset; i++ {
+		col = f.wide[i].next(col + f.wide[i].offset - cursor)
+		cursor = f.wide[i].offset + f.wide[i].size
+	}
+
+	col += offset - cursor
+
+	return Position{Filename: f.name, Offset: offset, Row: row, Col: col}
+}
+
+// FileSet assigns each of its files a distinct range of Pos values, so that a
+// Pos alone tells the file, row and column it stands for. It's safe for
+// concurrent use.
+type FileSet struct {
+	mu    sync.RWMutex
+	base  int
+	files []*File
+}
+
+func NewFileSet() *FileSet {
+	return &FileSet{base: 1}
+}
+
+// AddFile registers a file of size bytes. The base of the file follows the
+// ranges of the files registered before it.
+func (s *FileSet) AddFile(name string, size int) *File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f := &File{name: name, base: s.base, size: size, lines: []int{0}}
+	// the extra Pos keeps the end of a file apart from the start of the next
+	s.base += size + 1
+	s.files = append(s.files, f)
+
+	return f
+}
+
+// File returns the file holding p, nil if there's none.
+func (s *FileSet) File(p Pos) *File {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := sort.Search(len(s.files), func(i int) bool { return s.files[i].base > int(p) }) - 1
+
+	if !p.IsValid() || i < 0 || int(p) > s.files[i].base+s.files[i].size {
+		return nil
+	}
+
+	return s.files[i]
+}
+
+// Position resolves p, the zero Position if it's not in the set.
+func (s *FileSet) Position(p Pos) Position {
+	if f := s.File(p); f != nil {
+		return f.Position(p)
+	}
+
+	return Position{}
+}
+
+// Files returns the registered files, in order.
+func (s *FileSet) Files() []*File {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*File(nil), s.files...)
+}
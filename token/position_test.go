@@ -0,0 +1,89 @@
+package token
+
+import (
+	"testing"
+)
+
+func TestFileSet(t *testing.T) {
+	sources := []struct {
+		name string
+		data string
+	}{
+		{"a.steamd", "enum EResult {\n\tOK = 1; // ok\n};\n"},
+		{"b.steamd", "/* wörds\n   àcross */ class Msg {\n\tstring<8> naïve \"é\";\n};"},
+	}
+
+	fset := NewFileSet()
+
+	for _, src := range sources {
+		data := []byte(src.data)
+		f := fset.AddFile(src.name, len(data))
+		tokenizer := NewTokenizer(data)
+		tokenizer.SetFile(f)
+		tokenizer.SetTrivia(true)
+
+		for {
+			tok, err := tokenizer.Next()
+
+			if err != nil {
+				break
+			}
+
+			for _, tok := range append(append(tok.Leading, tok), tok.Trailing...) {
+				expected := Position{Filename: src.name, Offset: tok.Start, Row: tok.Row, Col: tok.Col}
+
+				if pos := fset.Position(tok.Pos); pos != expected {
+					t.Fatalf("mismatch: got %v, but expected %v for %q", pos, expected, tok.Raw)
+				}
+
+				if fset.File(tok.Pos) != f {
+					t.Fatalf("mismatch: got file %v, but expected %s", fset.File(tok.Pos), src.name)
+				}
+
+				if p := f.LinePos(tok.Row, tok.Col); p != tok.Pos {
+					t.Fatalf("mismatch: got %d, but expected %d for %q", p, tok.Pos, tok.Raw)
+				}
+			}
+		}
+	}
+
+	if pos := fset.Position(NoPos); pos.IsValid() || pos.String() != "-" {
+		t.Fatalf("mismatch: got %v, but expected an invalid position", pos)
+	}
+
+	files := fset.Files()
+
+	if len(files) != 2 || files[1].Base() != files[0].Base()+files[0].Size()+1 {
+		t.Fatalf("mismatch: got files %v, but expected consecutive ranges", files)
+	}
+
+	if pos := fset.Position(files[1].Pos(files[1].Size())); pos.String() != "b.steamd:4:3" {
+		t.Fatalf("mismatch: got %v, but expected b.steamd:4:3", pos)
+	}
+}
+
+func TestFileLinesForContent(t *testing.T) {
+	data := []byte("ä\nbc\n\nd")
+	f := NewFileSet().AddFile("", len(data))
+	f.SetLinesForContent(data)
+	f.AddLine(3)
+
+	if f.LineCount() != 4 {
+		t.Fatalf("mismatch: got %d, but expected 4 lines", f.LineCount())
+	}
+
+	for _, tc := range []struct {
+		offset   int
+		expected string
+	}{
+		{0, "1:1"},
+		{2, "1:2"},
+		{4, "2:2"},
+		{6, "3:1"},
+		{7, "4:1"},
+	} {
+		if pos := f.Position(f.Pos(tc.offset)); pos.String() != tc.expected {
+			t.Fatalf("mismatch: got %v, but expected %s for offset %d", pos, tc.expected, tc.offset)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package token
+
+import "bytes"
+
+// scan recognizes the lexeme starting at data[pos]. It returns the lexeme's
+// OpCode, the end offset of the whole lexeme and the offsets of its value.
+// Alternatives are tried in the same order as the original grammar:
+//
+//	whitespace  [\t\n\f\r ]+
+//	terminator  ;
+//	string      "(.+?)", with \" \\ \n and \t escapes
+//	block       /\*(.*?)\*/, a comment spanning lines
+//	doc         ///(.*)$
+//	comment     //(.*)$
+//	identifier  -?[a-zA-Z_0-9][a-zA-Z0-9_.]*
+//	namespace   ::
+//	preprocess  #([a-zA-Z]*)
+//	operator    <<|>>|[{}<>\]=|+()]
+//	invalid     [^\t\n\f\r ]+
+//
+// Identifiers spelling a decimal or hexadecimal integer are numbers. Escaped
+// quotes don't end strings, and the values of strings are unescaped by the
+// Tokenizer. Unterminated strings are invalid up to the end of the line.
+// Unterminated block comments extend to the end of data, with the value ending
+// at the end of the lexeme.
+func scan(data []byte, pos int) (op OpCode, end, vstart, vend int) {
+	n := len(data)
+	c := data[pos]
+
+	switch {
+	case isSpace(c):
+		end = pos + 1
+
+		for end < n && isSpace(data[end]) {
+			end++
+		}
+
+		return OpWhitespace, end, pos, end
+	case c == ';':
+		return OpTerminator, pos + 1, pos, pos + 1
+	case c == '"':
+		if end, ok := scanString(data, pos); ok {
+			return OpString, end, pos + 1, end - 1
+		}
+
+		end = scanLine(data, pos+1)
+
+		return OpInvalid, end, pos, end
+	case bytes.HasPrefix(data[pos:], blockCommentStart):
+		vstart = pos + len(blockCommentStart)
+
+		if i := bytes.Index(data[vstart:], blockCommentEnd); i >= 0 {
+			end = vstart + i + len(blockCommentEnd)
+			return OpComment, end, vstart, end - len(blockCommentEnd)
+		}
+
+		return OpComment, n, vstart, n
+	case c == '/' && pos+1 < n && data[pos+1] == '/':
+		if pos+2 < n && data[pos+2] == '/' {
+			end = scanLine(data, pos+3)
+			return OpDoc, end, pos + 3, end
+		}
+
+		end = scanLine(data, pos+2)
+
+		return OpComment, end, pos + 2, end
+	case isIdentifierStart(c):
+		end = scanIdentifier(data, pos+1)
+		return identifierOp(data[pos:end]), end, pos, end
+	case c == '-' && pos+1 < n && isIdentifierStart(data[pos+1]):
+		end = scanIdentifier(data, pos+2)
+		return identifierOp(data[pos:end]), end, pos, end
+	case c == ':' && pos+1 < n && data[pos+1] == ':':
+		return OpNamespace, pos + 2, pos, pos + 2
+	case c == '#':
+		end = pos + 1
+
+		for end < n && isLetter(data[end]) {
+			end++
+		}
+
+		return OpPreprocess, end, pos + 1, end
+	case (c == '<' || c == '>') && pos+1 < n && data[pos+1] == c:
+		return OpOperator, pos + 2, pos, pos + 2
+	case isOperator(c):
+		return OpOperator, pos + 1, pos, pos + 1
+	}
+
+	end = pos + 1
+
+	for end < n && !isSpace(data[end]) {
+		end++
+	}
+
+	return OpInvalid, end, pos, end
+}
+
+// scanString matches a non-empty, single-line, double-quoted string starting
+// at data[pos] and returns the offset past its closing quote. A backslash
+// escapes the character following it, which can't be a line break.
+func scanString(data []byte, pos int) (int, bool) {
+	n := len(data)
+
+	for i := pos + 1; i < n; i++ {
+		switch data[i] {
+		case '\\':
+			i++
+
+			if i >= n || data[i] == '\n' {
+				return 0, false
+			}
+		case '"':
+			// the first character is part of the string, even a quote
+			if i > pos+1 {
+				return i + 1, true
+			}
+		case '\n':
+			return 0, false
+		}
+	}
+
+	return 0, false
+}
+
+func scanLine(data []byte, pos int) int {
+	for pos < len(data) && data[pos] != '\n' {
+		pos++
+	}
+
+	return pos
+}
+
+func scanIdentifier(data []byte, pos int) int {
+	for pos < len(data) && (isIdentifierStart(data[pos]) || data[pos] == '.') {
+		pos++
+	}
+
+	return pos
+}
+
+func identifierOp(lit []byte) OpCode {
+	if isNumber(lit) {
+		return OpNumber
+	}
+
+	return OpIdentifier
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
+
+func isLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isIdentifierStart(c byte) bool {
+	return isLetter(c) || ('0' <= c && c <= '9') || c == '_'
+}
+
+func isOperator(c byte) bool {
+	switch c {
+	case '{', '}', '<', '>', ']', '=', '|', '+', '(', ')', ':':
+		return true
+	default:
+		return false
+	}
+}
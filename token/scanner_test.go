@@ -0,0 +1,682 @@
+package token
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// regexpPattern is the grammar the scanner replaced. It's kept as a reference
+// implementation to check the scanner against and to benchmark it.
+const regexpPattern = `(?m:(?P<whitespace>\s+)|` +
+	`(?P<terminator>[;])|` +
+	`["](?P<string>.+?)["]|` +
+	`///(?P<doc>.*)$|` +
+	`//(?P<comment>.*)$|` +
+	`(?P<identifier>-?[a-zA-Z_0-9][a-zA-Z0-9_.]*)|` +
+	`(?P<namespace>::)|` +
+	`[#](?P<preprocess>[a-zA-Z]*)|` +
+	`(?P<operator>[{}<>\]=|:])|` +
+	`(?P<invalid>[^\s]+))`
+
+var (
+	referenceRegexp = regexp.MustCompile(regexpPattern)
+	referenceGroups = referenceRegexp.SubexpNames()
+	referenceOps    = map[string]OpCode{
+		"whitespace": OpWhitespace,
+		"terminator": OpTerminator,
+		"string":     OpString,
+		"doc":        OpDoc,
+		"comment":    OpComment,
+		"identifier": OpIdentifier,
+		"namespace":  OpNamespace,
+		"preprocess": OpPreprocess,
+		"operator":   OpOperator,
+		"invalid":    OpInvalid,
+	}
+
+	scannerInputs = []string{
+		"",
+		"class MsgHdr<EMsg::Invalid> {\n\tbyte<20> x; // comment\n};",
+		"/// doc\n//// four\n// comment\r\n/",
+		`"" "a" """ "x""y" "unterminated` + "\n" + `"ok"`,
+		"-1 -x - -- --1 a-b 0x1F 1.2.3 _id",
+		":: : ::: #import #1 # #ab12",
+		"{}<>]=|[&^%$ a&b ;;",
+		"\t\f\r\n  \v x",
+		"é界 \"é界\" // é界",
+		"bad \xff utf8",
+	}
+)
+
+func referenceTokenize(data []byte) ([]*Token, error) {
+	var tokens []*Token
+	row, col := 1, 1
+
+	for _, m := range referenceRegexp.FindAllSubmatchIndex(data, -1) {
+		for i := 2; i < len(m); i += 2 {
+			if m[i] < 0 {
+				continue
+			}
+
+			op := referenceOps[referenceGroups[i/2]]
+
+			if op == OpIdentifier {
+				op = identifierOp(data[m[i]:m[i+1]])
+			}
+
+			if op != OpWhitespace && op != OpComment {
+				tokens = append(tokens, &Token{Op: op, Value: data[m[i]:m[i+1]], Raw: data[m[0]:m[1]], Row: row, Col: col, Start: m[0], End: m[1]})
+			}
+
+			var invalid int
+
+			if row, col, invalid = advance(data[m[0]:m[1]], row, col, 1); invalid >= 0 {
+				return nil, fmt.Errorf("%d:%d: Invalid UTF-8 byte", row, col)
+			}
+
+			break
+		}
+	}
+
+	return tokens, nil
+}
+
+func formatToken(t *Token) string {
+	return fmt.Sprintf("{%s %q %q %d:%d @%d-%d}", t.Op, t.Value, t.Raw, t.Row, t.Col, t.Start, t.End)
+}
+
+func TestScannerMatchesReference(t *testing.T) {
+	for _, input := range scannerInputs {
+		expected, expectedErr := referenceTokenize([]byte(input))
+		q, err := NewTokenizer([]byte(input)).Tokenize()
+
+		if (err != nil) != (expectedErr != nil) {
+			t.Fatalf("error mismatch for %q: got %v, but expected %v", input, err, expectedErr)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if q.Len() != len(expected) {
+			t.Fatalf("expected %d tokens for %q, got %d", len(expected), input, q.Len())
+		}
+
+		for _, e := range expected {
+			token := q.Dequeue()
+
+			if formatToken(token) != formatToken(e) {
+				t.Fatalf("mismatch for %q:\nexpected: %s\ngot: %s", input, formatToken(e), formatToken(token))
+			}
+		}
+	}
+}
+
+var benchmarkInput = func() []byte {
+	buf := &bytes.Buffer{}
+
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(buf, "/// Message %d\nclass Msg%d<EMsg::Msg%d>\n{\n", i, i, i)
+		fmt.Fprintf(buf, "\tconst uint PROTOCOL_VERSION = 0x%X;\n", i)
+		fmt.Fprintf(buf, "\tsteamidmarshal ulong steamID = 0; // the sender\n")
+		fmt.Fprintf(buf, "\tbyte<20> key;\n\tEResult result = EResult::Invalid | EResult::OK;\n")
+		fmt.Fprintf(buf, "\tstring old; obsolete \"not used anymore\"\n};\n\n")
+	}
+
+	return buf.Bytes()
+}()
+
+func BenchmarkTokenize(b *testing.B) {
+	b.SetBytes(int64(len(benchmarkInput)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := NewTokenizer(benchmarkInput).Tokenize(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReferenceTokenize(b *testing.B) {
+	b.SetBytes(int64(len(benchmarkInput)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := referenceTokenize(benchmarkInput); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestReaderTokenizerMatchesTokenizer(t *testing.T) {
+	inputs := append(append([]string(nil), scannerInputs...), string(benchmarkInput))
+
+	for _, input := range inputs {
+		expected, expectedErr := NewTokenizer([]byte(input)).Tokenize()
+		q, err := NewReaderTokenizer(iotest.HalfReader(strings.NewReader(input))).Tokenize()
+
+		if (err != nil) != (expectedErr != nil) {
+			t.Fatalf("error mismatch: got %v, but expected %v", err, expectedErr)
+		}
+
+		if q.Len() != expected.Len() {
+			t.Fatalf("expected %d tokens, got %d", expected.Len(), q.Len())
+		}
+
+		for e := expected.Dequeue(); e != nil; e = expected.Dequeue() {
+			token := q.Dequeue()
+
+			if formatToken(token) != formatToken(e) {
+				t.Fatalf("mismatch:\nexpected: %s\ngot: %s", formatToken(e), formatToken(token))
+			}
+		}
+	}
+}
+
+func TestTokenizerStream(t *testing.T) {
+	tokenizer := NewReaderTokenizer(strings.NewReader("a b\n\xff"))
+	q := tokenizer.Stream()
+
+	for _, expected := range []string{"a", "b"} {
+		token := q.Dequeue()
+
+		if token == nil || token.ValueString() != expected {
+			t.Fatalf("expected token %q, got %v", expected, token)
+		}
+	}
+
+	if token := q.Dequeue(); token != nil {
+		t.Fatalf("expected token to be nil but it is not")
+	}
+
+	if q.Err() == nil {
+		t.Fatalf("expected stream error")
+	}
+}
+
+func reconstruct(t *testing.T, tokenizer *Tokenizer) []byte {
+	buf := &bytes.Buffer{}
+
+	for {
+		token, err := tokenizer.Next()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		for _, trivia := range token.Leading {
+			buf.Write(trivia.Raw)
+		}
+
+		buf.Write(token.Raw)
+
+		for _, trivia := range token.Trailing {
+			if bytes.IndexByte(trivia.Raw, '\n') >= 0 {
+				t.Fatalf("not expected newline in trailing trivia %q", trivia.Raw)
+			}
+
+			buf.Write(trivia.Raw)
+		}
+	}
+
+	for _, trivia := range tokenizer.Trivia() {
+		buf.Write(trivia.Raw)
+	}
+
+	return buf.Bytes()
+}
+
+func TestTokenizerTrivia(t *testing.T) {
+	inputs := append(append([]string(nil), scannerInputs...), string(benchmarkInput), "  // only a comment\n\n")
+
+	for _, input := range inputs {
+		if _, err := NewTokenizer([]byte(input)).Tokenize(); err != nil {
+			continue
+		}
+
+		tokenizer := NewTokenizer([]byte(input))
+		tokenizer.SetTrivia(true)
+
+		if got := reconstruct(t, tokenizer); string(got) != input {
+			t.Fatalf("mismatch: got %q, but expected %q", got, input)
+		}
+
+		tokenizer = NewReaderTokenizer(iotest.HalfReader(strings.NewReader(input)))
+		tokenizer.SetTrivia(true)
+
+		if got := reconstruct(t, tokenizer); string(got) != input {
+			t.Fatalf("mismatch: got %q, but expected %q", got, input)
+		}
+	}
+
+	tokenizer := NewTokenizer([]byte("a; // trailing\n\t// leading\nb"))
+	tokenizer.SetTrivia(true)
+	tokenizer.Next()
+	semicolon, _ := tokenizer.Next()
+	b, _ := tokenizer.Next()
+
+	if len(semicolon.Trailing) != 2 || semicolon.Trailing[1].ValueString() != " trailing" {
+		t.Fatalf("mismatch: got trailing trivia %q", StringValues(semicolon.Trailing))
+	}
+
+	if len(b.Leading) != 3 || b.Leading[1].ValueString() != " leading" {
+		t.Fatalf("mismatch: got leading trivia %q", StringValues(b.Leading))
+	}
+}
+
+func TestTokenizerNumbers(t *testing.T) {
+	tests := []struct {
+		input    string
+		op       OpCode
+		i        int64
+		iok      bool
+		u        uint64
+		uok      bool
+		negative bool
+	}{
+		{"0", OpNumber, 0, true, 0, true, false},
+		{"-0", OpNumber, 0, true, 0, true, true},
+		{"1303", OpNumber, 1303, true, 1303, true, false},
+		{"0x1F", OpNumber, 31, true, 31, true, false},
+		{"-0x10", OpNumber, -16, true, 0, false, true},
+		{"-9223372036854775808", OpNumber, -9223372036854775808, true, 0, false, true},
+		{"0xFFFFFFFFFFFFFFFF", OpNumber, -1, false, 18446744073709551615, true, false},
+		{"1.5", OpIdentifier, 0, false, 0, false, false},
+		{"0x", OpIdentifier, 0, false, 0, false, false},
+		{"20abc", OpIdentifier, 0, false, 0, false, false},
+		{"-x", OpIdentifier, 0, false, 0, false, false},
+	}
+
+	for _, test := range tests {
+		token, err := NewTokenizer([]byte(test.input)).Next()
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		if token.Op != test.op {
+			t.Fatalf("mismatch for %q: got %s, but expected %s", test.input, token.Op, test.op)
+		}
+
+		if token.Op != OpNumber {
+			continue
+		}
+
+		if i, ok := token.Number.Int64(); ok != test.iok || (ok && i != test.i) {
+			t.Fatalf("mismatch for %q: got int64 %d (%v), but expected %d (%v)", test.input, i, ok, test.i, test.iok)
+		}
+
+		if u, ok := token.Number.Uint64(); ok != test.uok || (ok && u != test.u) {
+			t.Fatalf("mismatch for %q: got uint64 %d (%v), but expected %d (%v)", test.input, u, ok, test.u, test.uok)
+		}
+
+		if token.Number.Negative() != test.negative {
+			t.Fatalf("mismatch for %q: got negative %v, but expected %v", test.input, token.Number.Negative(), test.negative)
+		}
+	}
+
+	for _, input := range []string{"18446744073709551616", "-9223372036854775809", "0x10000000000000000"} {
+		_, err := NewTokenizer([]byte("a = " + input)).Tokenize()
+
+		var numErr *NumberError
+
+		if !errors.As(err, &numErr) || !errors.Is(err, strconv.ErrRange) || string(numErr.Token.Raw) != input {
+			t.Fatalf("expected range error for %q, got %v", input, err)
+		}
+	}
+}
+
+func TestTokenizerStrings(t *testing.T) {
+	tests := []struct {
+		input string
+		value string
+	}{
+		{`"plain"`, "plain"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"a\\b"`, `a\b`},
+		{`"line\nbreak\ttab"`, "line\nbreak\ttab"},
+		{`"\\"`, `\`},
+	}
+
+	for _, test := range tests {
+		token, err := NewTokenizer([]byte(test.input)).Next()
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		if token.Op != OpString || token.ValueString() != test.value || string(token.Raw) != test.input {
+			t.Fatalf("mismatch for %s: got %s %q, but expected string %q", test.input, token.Op, token.Value, test.value)
+		}
+
+		if quoted := Quote(test.value); quoted != test.input {
+			t.Fatalf("mismatch: got %s, but expected %s", quoted, test.input)
+		}
+	}
+
+	for _, input := range []string{`"a\qb"`, `"\x41"`} {
+		_, err := NewTokenizer([]byte("obsolete " + input)).Tokenize()
+
+		var escapeErr *EscapeError
+
+		if !errors.As(err, &escapeErr) || string(escapeErr.Token.Raw) != input {
+			t.Fatalf("expected escape error for %s, got %v", input, err)
+		}
+	}
+
+	// an escaped line break leaves the string unterminated
+	token, err := NewTokenizer([]byte("\"a\\\nb\"")).Next()
+
+	if err != nil || token.Op != OpInvalid {
+		t.Fatalf("expected invalid token, got %v %v", token, err)
+	}
+}
+
+func TestTokenizerBlockComments(t *testing.T) {
+	input := "a /* one\n two */ b /**/ c /* é\n*/\nd"
+
+	for _, tokenizer := range []*Tokenizer{
+		NewTokenizer([]byte(input)),
+		NewReaderTokenizer(iotest.OneByteReader(strings.NewReader(input))),
+	} {
+		tokenizer.SetTrivia(true)
+		var tokens, comments []string
+
+		for {
+			token, err := tokenizer.Next()
+
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				t.Fatalf("not expected error %v", err)
+			}
+
+			tokens = append(tokens, fmt.Sprintf("%s@%d:%d", token.Value, token.Row, token.Col))
+
+			for _, trivia := range append(token.Leading, token.Trailing...) {
+				if trivia.Op == OpComment {
+					comments = append(comments, fmt.Sprintf("%q@%d:%d", trivia.Value, trivia.Row, trivia.Col))
+				}
+			}
+		}
+
+		if expected := "a@1:1 b@2:9 c@2:16 d@4:1"; strings.Join(tokens, " ") != expected {
+			t.Fatalf("mismatch: got %v, but expected %s", tokens, expected)
+		}
+
+		if expected := `" one\n two "@1:3 ""@2:11 " é\n"@2:18`; strings.Join(comments, " ") != expected {
+			t.Fatalf("mismatch: got %v, but expected %s", comments, expected)
+		}
+	}
+
+	tokenizer := NewReaderTokenizer(iotest.OneByteReader(strings.NewReader(input)))
+	tokenizer.SetTrivia(true)
+
+	if got := reconstruct(t, tokenizer); string(got) != input {
+		t.Fatalf("mismatch: got %q, but expected %q", got, input)
+	}
+
+	for _, tokenizer := range []*Tokenizer{
+		NewTokenizer([]byte("a /* open\n*")),
+		NewReaderTokenizer(iotest.OneByteReader(strings.NewReader("a /* open\n*"))),
+	} {
+		_, err := tokenizer.Tokenize()
+
+		var commentErr *CommentError
+
+		if !errors.As(err, &commentErr) || commentErr.Token.Row != 1 || commentErr.Token.Col != 3 {
+			t.Fatalf("expected unterminated comment error at 1:3, got %v", err)
+		}
+	}
+}
+
+func TestTokenizerUnterminatedStrings(t *testing.T) {
+	q, err := NewTokenizer([]byte("obsolete \"no closing \\\" quote\n;")).Tokenize()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	q.Dequeue()
+	token := q.Dequeue()
+
+	var strErr *StringError
+
+	if token.Op != OpInvalid || string(token.Raw) != `"no closing \" quote` || !errors.As(token.Error, &strErr) || strErr.Token != token {
+		t.Fatalf("expected unterminated string error token, got %s %q %v", token.Op, token.Raw, token.Error)
+	}
+
+	if token.Error.Error() != `1:10: Unterminated string "no closing \" quote` {
+		t.Fatalf("mismatch: got %q", token.Error.Error())
+	}
+
+	if token := q.Dequeue(); token == nil || token.Op != OpTerminator || token.Row != 2 {
+		t.Fatalf("expected terminator on line 2, got %v", token)
+	}
+}
+
+func TestTokenizerOffsets(t *testing.T) {
+	input := "é界 = \"a\\\"b\"; // c\n/* d */ x"
+	tokenizer := NewReaderTokenizer(iotest.OneByteReader(strings.NewReader(input)))
+	tokenizer.SetTrivia(true)
+	var ranges []string
+
+	for {
+		token, err := tokenizer.Next()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		for _, tok := range append(append(append([]*Token(nil), token.Leading...), token), token.Trailing...) {
+			if input[tok.Start:tok.End] != string(tok.Raw) {
+				t.Fatalf("mismatch: got %q at %d-%d, but expected %q", input[tok.Start:tok.End], tok.Start, tok.End, tok.Raw)
+			}
+
+			if !tok.IsTrivia() {
+				ranges = append(ranges, fmt.Sprintf("%d-%d", tok.Start, tok.End))
+			}
+		}
+	}
+
+	if expected := "0-5 6-7 8-14 14-15 29-30"; strings.Join(ranges, " ") != expected {
+		t.Fatalf("mismatch: got %v, but expected %s", ranges, expected)
+	}
+}
+
+func TestTokenizerColumns(t *testing.T) {
+	testCases := []struct {
+		input    string
+		tabWidth int
+		expected string
+	}{
+		{"a", 0, "a@1:1"},
+		{"\n\ta;\n  b", 1, "a@2:2 ;@2:3 b@3:3"},
+		{"\n\ta;\n  b", 4, "a@2:5 ;@2:6 b@3:3"},
+		{"x\t\ty", 4, "x@1:1 y@1:9"},
+		{"ab\tc", 4, "ab@1:1 c@1:5"},
+		{"/* a\n\tb */\tc", 1, "c@2:7"},
+		{"/* a\n\tb */\tc", 8, "c@2:17"},
+		{"é\tx", 4, "é@1:1 x@1:5"},
+	}
+
+	for _, tc := range testCases {
+		for _, reader := range []bool{false, true} {
+			var tokenizer *Tokenizer
+
+			if reader {
+				tokenizer = NewReaderTokenizer(iotest.OneByteReader(strings.NewReader(tc.input)))
+			} else {
+				tokenizer = NewTokenizer([]byte(tc.input))
+			}
+
+			f := NewFileSet().AddFile("", len(tc.input))
+			tokenizer.SetFile(f)
+			tokenizer.SetTabWidth(tc.tabWidth)
+			var tokens []string
+
+			for {
+				token, err := tokenizer.Next()
+
+				if err == io.EOF {
+					break
+				}
+
+				if err != nil {
+					t.Fatalf("not expected error %v", err)
+				}
+
+				tokens = append(tokens, fmt.Sprintf("%s@%d:%d", token.Raw, token.Row, token.Col))
+
+				if pos := f.Position(token.Pos); pos.Row != token.Row || pos.Col != token.Col {
+					t.Fatalf("mismatch: got %v, but expected %d:%d for %q", pos, token.Row, token.Col, token.Raw)
+				}
+
+				if p := f.LinePos(token.Row, token.Col); p != token.Pos {
+					t.Fatalf("mismatch: got %d, but expected %d for %q", p, token.Pos, token.Raw)
+				}
+			}
+
+			if strings.Join(tokens, " ") != tc.expected {
+				t.Fatalf("mismatch: got %v, but expected %s for %q with tab width %d", tokens, tc.expected, tc.input, tc.tabWidth)
+			}
+		}
+	}
+}
+
+func TestTokenizerByteOrderMark(t *testing.T) {
+	input := "\xef\xbb\xbfenum E {\n\tA = 1; // \xef\xbb\xbf\n};"
+
+	for _, reader := range []bool{false, true} {
+		var tokenizer *Tokenizer
+
+		if reader {
+			tokenizer = NewReaderTokenizer(iotest.OneByteReader(strings.NewReader(input)))
+		} else {
+			tokenizer = NewTokenizer([]byte(input))
+		}
+
+		f := NewFileSet().AddFile("", len(input))
+		tokenizer.SetFile(f)
+		tokenizer.SetTrivia(true)
+		token, err := tokenizer.Next()
+
+		if err != nil {
+			t.Fatalf("not expected error %v", err)
+		}
+
+		if token.ValueString() != "enum" || token.Row != 1 || token.Col != 1 || token.Start != 3 {
+			t.Fatalf("mismatch: got %s, but expected enum at 1:1", formatToken(token))
+		}
+
+		if pos := f.Position(token.Pos); pos.Row != 1 || pos.Col != 1 || pos.Offset != 3 {
+			t.Fatalf("mismatch: got %v, but expected 1:1 at offset 3", pos)
+		}
+
+		if p := f.LinePos(1, 1); p != token.Pos {
+			t.Fatalf("mismatch: got %d, but expected %d", p, token.Pos)
+		}
+
+		if len(token.Leading) != 1 || token.Leading[0].Op != OpWhitespace || len(token.Leading[0].Value) != 0 {
+			t.Fatalf("mismatch: got leading %v, but expected the byte order mark", token.Leading)
+		}
+	}
+
+	tokenizer := NewReaderTokenizer(iotest.OneByteReader(strings.NewReader(input)))
+	tokenizer.SetTrivia(true)
+
+	if got := reconstruct(t, tokenizer); string(got) != input {
+		t.Fatalf("mismatch: got %q, but expected %q", got, input)
+	}
+}
+
+func TestTokenizerInvalidUTF8(t *testing.T) {
+	input := "a\n  b\xff;"
+
+	for _, reader := range []bool{false, true} {
+		var tokenizer *Tokenizer
+
+		if reader {
+			tokenizer = NewReaderTokenizer(iotest.OneByteReader(strings.NewReader(input)))
+		} else {
+			tokenizer = NewTokenizer([]byte(input))
+		}
+
+		_, err := tokenizer.Tokenize()
+
+		var encErr *EncodingError
+
+		if !errors.As(err, &encErr) {
+			t.Fatalf("expected EncodingError, got %v", err)
+		}
+
+		if encErr.Token.Row != 2 || encErr.Token.Col != 4 || encErr.Token.Start != 5 {
+			t.Fatalf("mismatch: got %s, but expected the invalid byte at 2:4", formatToken(encErr.Token))
+		}
+
+		if expected := "2:4: Invalid UTF-8 byte 0xff"; err.Error() != expected {
+			t.Fatalf("mismatch: got %q, but expected %q", err, expected)
+		}
+	}
+}
+
+func TestTokenizerKeywords(t *testing.T) {
+	q, err := NewTokenizer([]byte("class Foo flags Flags obsolete constant const \"enum\" union::x")).Tokenize()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	var keywords []string
+
+	for token := q.Dequeue(); token != nil; token = q.Dequeue() {
+		keywords = append(keywords, token.Keyword.String())
+	}
+
+	expected := []string{"class", "", "flags", "", "obsolete", "", "const", "", "union", "", ""}
+
+	if fmt.Sprintf("%q", keywords) != fmt.Sprintf("%q", expected) {
+		t.Fatalf("mismatch: got %q, but expected %q", keywords, expected)
+	}
+
+	tokenizer := NewTokenizer([]byte("CLASS Flags removed"))
+	tokenizer.SetCaseSensitive(false)
+	q, err = tokenizer.Tokenize()
+
+	if err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	keywords = nil
+
+	for token := q.Dequeue(); token != nil; token = q.Dequeue() {
+		keywords = append(keywords, token.Keyword.String())
+	}
+
+	if expected := "class flags removed"; strings.Join(keywords, " ") != expected {
+		t.Fatalf("mismatch: got %q, but expected %q", keywords, expected)
+	}
+
+	for k := KeywordClass; k <= KeywordUnion; k++ {
+		if LookupKeyword([]byte(k.String())) != k {
+			t.Fatalf("mismatch: got %v, but expected %v", LookupKeyword([]byte(k.String())), k)
+		}
+	}
+}
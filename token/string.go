@@ -0,0 +1,71 @@
+package token
+
+import (
+	"fmt"
+	"strings"
+)
+
+// escapes maps the characters following a backslash in a string literal to the
+// characters they stand for.
+var escapes = map[byte]byte{
+	'"':  '"',
+	'\\': '\\',
+	'n':  '\n',
+	't':  '\t',
+}
+
+var quoteReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+
+// Quote returns s as a string literal, escaping quotes, backslashes, newlines
+// and tabs.
+func Quote(s string) string {
+	return `"` + quoteReplacer.Replace(s) + `"`
+}
+
+// StringError reports a string literal, Token, left unterminated at the end of
+// its line.
+type StringError struct {
+	Token *Token
+}
+
+func (e *StringError) Error() string {
+	return fmt.Sprintf("%d:%d: Unterminated string %s", e.Token.Row, e.Token.Col, e.Token.Raw)
+}
+
+// EscapeError reports an invalid escape sequence, Seq, in a string literal.
+type EscapeError struct {
+	Token *Token
+	Seq   string
+}
+
+func (e *EscapeError) Error() string {
+	return fmt.Sprintf("%d:%d: Invalid escape sequence %q in string %s", e.Token.Row, e.Token.Col, e.Seq, e.Token.Raw)
+}
+
+// unescape returns the value of a string literal, lit, with its escape
+// sequences replaced, or the first invalid sequence.
+func unescape(lit []byte) ([]byte, string, bool) {
+	value := make([]byte, 0, len(lit))
+
+	for i := 0; i < len(lit); i++ {
+		if lit[i] != '\\' {
+			value = append(value, lit[i])
+			continue
+		}
+
+		if i+1 >= len(lit) {
+			return nil, `\`, false
+		}
+
+		c, ok := escapes[lit[i+1]]
+
+		if !ok {
+			return nil, string(lit[i : i+2]), false
+		}
+
+		value = append(value, c)
+		i++
+	}
+
+	return value, "", true
+}
@@ -0,0 +1,557 @@
+package token
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+const (
+	OpWhitespace OpCode = iota
+	OpTerminator
+	OpString
+	OpComment
+	OpDoc
+	OpIdentifier
+	OpNamespace
+	OpPreprocess
+	OpOperator
+	OpInvalid
+	OpNumber
+)
+
+type OpCode int
+
+func (op OpCode) String() string {
+	switch op {
+	case OpWhitespace:
+		return "whitespace"
+	case OpTerminator:
+		return "terminator"
+	case OpString:
+		return "string"
+	case OpComment:
+		return "comment"
+	case OpDoc:
+		return "doc"
+	case OpIdentifier:
+		return "identifier"
+	case OpNamespace:
+		return "namespace"
+	case OpPreprocess:
+		return "preprocess"
+	case OpOperator:
+		return "operator"
+	case OpInvalid:
+		return "invalid"
+	case OpNumber:
+		return "number"
+	default:
+		panic(fmt.Errorf("Unknown OpCode %d", op))
+	}
+}
+
+// Token is a lexeme of the input. Number holds the value of OpNumber tokens,
+// Value the unescaped value of OpString tokens, and Keyword the classification
+// of OpIdentifier tokens spelling a keyword. Error is set on OpInvalid
+// tokens of malformed lexemes, like unterminated strings, positioned at them.
+// When the Tokenizer retains trivia, Leading holds the whitespace and comments
+// preceding the token and Trailing the ones following it up to the end of its
+// line. Row and Col locate the start of the lexeme, 1-based, with Col counting
+// runes, and Start and End are the byte offsets of Raw in the input. Pos is the
+// position of the lexeme in a FileSet, NoPos unless the Tokenizer has a File.
+type Token struct {
+	Op       OpCode
+	Keyword  Keyword
+	Name     string
+	Value    []byte
+	Raw      []byte
+	Row      int
+	Col      int
+	Start    int
+	End      int
+	Pos      Pos
+	Error    error
+	Number   Number
+	Leading  []*Token
+	Trailing []*Token
+}
+
+// Equal reports whether other has the same OpCode and value, compared
+// case-sensitively.
+func (t *Token) Equal(other *Token) bool {
+	return t.Op == other.Op && t.ValueEqual(other.Value)
+}
+
+// EqualFold is like Equal, but compares values case-insensitively.
+func (t *Token) EqualFold(other *Token) bool {
+	return t.Op == other.Op && t.ValueEqualFold(other.Value)
+}
+
+func (t *Token) ValueString() string {
+	return string(t.Value)
+}
+
+func (t *Token) ValueEqual(val []byte) bool {
+	return bytes.Equal(t.Value, val)
+}
+
+// ValueEqualFold reports whether val is the value of the token in any case.
+func (t *Token) ValueEqualFold(val []byte) bool {
+	return bytes.EqualFold(t.Value, val)
+}
+
+func (t *Token) ValueEqualString(val string) bool {
+	return t.ValueEqual([]byte(val))
+}
+
+// IsTrivia reports whether the token is whitespace or a comment.
+func (t *Token) IsTrivia() bool {
+	return t.Op == OpWhitespace || t.Op == OpComment
+}
+
+// Span returns the byte offsets of the token's raw text in the tokenized
+// buffer.
+//
+// Deprecated: use Start and End.
+func (t *Token) Span() (int, int) {
+	return t.Start, t.End
+}
+
+func StringValues(tokens []*Token) []string {
+	var values []string
+
+	for _, t := range tokens {
+		values = append(values, t.ValueString())
+	}
+
+	return values
+}
+
+type TokenQueue struct {
+	list *list.List
+	src  *Tokenizer
+	err  error
+}
+
+func NewTokenQueue() *TokenQueue {
+	return &TokenQueue{list: list.New()}
+}
+
+func (q *TokenQueue) enqueue(t *Token) {
+	q.list.PushBack(t)
+}
+
+// pull reads the next token from the queue's tokenizer, if it's a stream and
+// nothing is buffered.
+func (q *TokenQueue) pull() {
+	if q.src == nil || q.err != nil || q.list.Len() > 0 {
+		return
+	}
+
+	t, err := q.src.Next()
+
+	switch {
+	case err == io.EOF:
+		q.src = nil
+	case err != nil:
+		q.err = err
+	default:
+		q.enqueue(t)
+	}
+}
+
+func (q *TokenQueue) Len() int {
+	return q.list.Len()
+}
+
+// Err returns the error that interrupted a stream queue, if any.
+func (q *TokenQueue) Err() error {
+	return q.err
+}
+
+func (q *TokenQueue) Peek() *Token {
+	q.pull()
+
+	if e := q.list.Front(); e != nil {
+		return e.Value.(*Token)
+	} else {
+		return nil
+	}
+}
+
+func (q *TokenQueue) Dequeue() *Token {
+	q.pull()
+
+	if e := q.list.Front(); e != nil {
+		q.list.Remove(e)
+		return e.Value.(*Token)
+	} else {
+		return nil
+	}
+}
+
+const readChunkSize = 4096
+
+type Tokenizer struct {
+	data     []byte
+	r        io.Reader
+	buf      []byte
+	base     int
+	pos      int
+	eof      bool
+	row      int
+	col      int
+	trivia   bool
+	trailing []*Token
+	file     *File
+	tabWidth int
+	foldCase bool
+}
+
+func NewTokenizer(data []byte) *Tokenizer {
+	return &Tokenizer{data: data, buf: data, eof: true, row: 1, col: 1, tabWidth: 1}
+}
+
+// NewReaderTokenizer returns a Tokenizer reading its input from r in chunks,
+// so that only the unconsumed part of the current line is kept in memory.
+func NewReaderTokenizer(r io.Reader) *Tokenizer {
+	return &Tokenizer{r: r, row: 1, col: 1, tabWidth: 1}
+}
+
+// SetTrivia makes the Tokenizer attach whitespace and comments to the
+// surrounding tokens instead of dropping them, so that the input can be
+// reproduced byte for byte from the tokens.
+func (t *Tokenizer) SetTrivia(enabled bool) {
+	t.trivia = enabled
+}
+
+// SetTabWidth makes tabs advance the columns of the following tokens to the
+// next multiple of n plus one, like editors aligning them to tab stops, instead
+// of counting as a single column. Widths below 2 restore the default.
+func (t *Tokenizer) SetTabWidth(n int) {
+	if n < 1 {
+		n = 1
+	}
+
+	t.tabWidth = n
+}
+
+// TabWidth returns the width set with SetTabWidth, 1 by default.
+func (t *Tokenizer) TabWidth() int {
+	return t.tabWidth
+}
+
+// SetCaseSensitive sets whether keywords must be lowercase, the default, or
+// are recognized in any case, like CLASS.
+func (t *Tokenizer) SetCaseSensitive(enabled bool) {
+	t.foldCase = !enabled
+}
+
+func (t *Tokenizer) CaseSensitive() bool {
+	return !t.foldCase
+}
+
+// SetFile makes the Tokenizer record the lines of its input in f, and position
+// its tokens in f's FileSet. f should be empty and as big as the whole input.
+func (t *Tokenizer) SetFile(f *File) {
+	t.file = f
+}
+
+// File returns the File set with SetFile, if any.
+func (t *Tokenizer) File() *File {
+	return t.file
+}
+
+// Trivia returns the whitespace and comments following the last token. It's
+// only populated once Next has returned io.EOF with trivia retention enabled.
+func (t *Tokenizer) Trivia() []*Token {
+	return t.trailing
+}
+
+// Bytes returns the whole input of a Tokenizer created with NewTokenizer, or
+// nil for reader based tokenizers.
+func (t *Tokenizer) Bytes() []byte {
+	return t.data
+}
+
+func (t *Tokenizer) Tokenize() (*TokenQueue, error) {
+	return t.TokenizeContext(context.Background())
+}
+
+// TokenizeContext is like Tokenize, but stops with ctx's error once ctx is
+// done. The queue then holds the tokens scanned so far.
+func (t *Tokenizer) TokenizeContext(ctx context.Context) (*TokenQueue, error) {
+	q := NewTokenQueue()
+	err := t.tokenize(ctx, q)
+	return q, err
+}
+
+// Stream returns a queue that pulls tokens from the Tokenizer on demand.
+// Tokenizing errors stop the stream and are reported by the queue's Err.
+func (t *Tokenizer) Stream() *TokenQueue {
+	q := NewTokenQueue()
+	q.src = t
+	return q
+}
+
+func (t *Tokenizer) tokenize(ctx context.Context, q *TokenQueue) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		token, err := t.Next()
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		q.enqueue(token)
+	}
+}
+
+// Next returns the next token, or io.EOF at the end of the input.
+func (t *Tokenizer) Next() (*Token, error) {
+	var leading []*Token
+
+	for {
+		token, err := t.scanToken()
+
+		if err == io.EOF && t.trivia {
+			t.trailing = leading
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !token.IsTrivia() {
+			if t.trivia {
+				token.Leading = leading
+				token.Trailing, err = t.scanTrailing()
+			}
+
+			return token, err
+		}
+
+		if t.trivia {
+			leading = append(leading, token)
+		}
+	}
+}
+
+// scanTrailing consumes the trivia following a token on the same line.
+func (t *Tokenizer) scanTrailing() ([]*Token, error) {
+	var trailing []*Token
+
+	for {
+		if err := t.fill(); err != nil {
+			return trailing, err
+		}
+
+		if t.pos >= len(t.buf) {
+			return trailing, nil
+		}
+
+		op, end, _, _ := scan(t.buf, t.pos)
+
+		if (op != OpWhitespace && op != OpComment) || bytes.IndexByte(t.buf[t.pos:end], '\n') >= 0 {
+			return trailing, nil
+		}
+
+		token, err := t.scanToken()
+
+		if err != nil {
+			return trailing, err
+		}
+
+		trailing = append(trailing, token)
+	}
+}
+
+func (t *Tokenizer) scanToken() (*Token, error) {
+	if err := t.fill(); err != nil {
+		return nil, err
+	}
+
+	if t.pos >= len(t.buf) {
+		return nil, io.EOF
+	}
+
+	op, end, vstart, vend := scan(t.buf, t.pos)
+	row, col, start := t.row, t.col, t.base+t.pos
+	bom := start == 0 && bytes.HasPrefix(t.buf, byteOrderMark)
+
+	if bom {
+		op, end = OpWhitespace, len(byteOrderMark)
+		vstart, vend = end, end
+	}
+
+	matched := t.buf[t.pos:end]
+
+	if t.file != nil {
+		t.file.scan(start, matched, t.tabWidth)
+	}
+
+	if !bom {
+		nextRow, nextCol, invalid := advance(matched, row, col, t.tabWidth)
+
+		if invalid >= 0 {
+			return nil, &EncodingError{Token: &Token{
+				Op:    OpInvalid,
+				Name:  OpInvalid.String(),
+				Value: matched[invalid : invalid+1],
+				Raw:   matched[invalid : invalid+1],
+				Row:   nextRow,
+				Col:   nextCol,
+				Start: start + invalid,
+				End:   start + invalid + 1,
+				Pos:   t.position(start + invalid),
+			}}
+		}
+
+		t.row, t.col = nextRow, nextCol
+	}
+
+	t.pos = end
+
+	token := &Token{
+		Op:    op,
+		Name:  op.String(),
+		Value: t.buf[vstart:vend],
+		Raw:   matched,
+		Row:   row,
+		Col:   col,
+		Start: start,
+		End:   t.base + end,
+		Pos:   t.position(start),
+	}
+
+	if op == OpInvalid && matched[0] == '"' {
+		token.Error = &StringError{Token: token}
+	}
+
+	if op == OpComment && vend == end && bytes.HasPrefix(matched, blockCommentStart) {
+		return nil, &CommentError{Token: token}
+	}
+
+	if op == OpIdentifier {
+		token.Keyword = t.lookupKeyword(token.Value)
+	}
+
+	if op == OpNumber {
+		var err error
+
+		if token.Number, err = parseNumber(token.Value); err != nil {
+			return nil, &NumberError{Token: token, Err: err}
+		}
+	}
+
+	if op == OpString && bytes.IndexByte(token.Value, '\\') >= 0 {
+		value, seq, ok := unescape(token.Value)
+
+		if !ok {
+			return nil, &EscapeError{Token: token, Seq: seq}
+		}
+
+		token.Value = value
+	}
+
+	return token, nil
+}
+
+// lookupKeyword returns the keyword spelled by ident, in any case if the
+// Tokenizer folds case.
+func (t *Tokenizer) lookupKeyword(ident []byte) Keyword {
+	if t.foldCase {
+		return LookupKeyword(bytes.ToLower(ident))
+	}
+
+	return LookupKeyword(ident)
+}
+
+// position returns the Pos of offset in the File of the Tokenizer, if any.
+func (t *Tokenizer) position(offset int) Pos {
+	if t.file == nil {
+		return NoPos
+	}
+
+	return t.file.Pos(offset)
+}
+
+// fill reads input until the buffer holds the rest of the current line, or the
+// whole block comment starting at the current position. No lexeme other than
+// whitespace and block comments spans lines, so scanning a complete line gives
+// the same result as scanning the whole input. The remaining bytes are moved
+// to a new buffer rather than compacted in place since emitted tokens still
+// reference the old one.
+func (t *Tokenizer) fill() error {
+	for !t.eof && !t.filled() {
+		rest := t.buf[t.pos:]
+		buf := make([]byte, len(rest), len(rest)+readChunkSize)
+		copy(buf, rest)
+		n, err := t.r.Read(buf[len(rest):cap(buf)])
+		t.base += t.pos
+		t.buf = buf[:len(rest)+n]
+		t.pos = 0
+
+		if err == io.EOF {
+			t.eof = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// filled reports whether the buffer holds the lexeme at the current position
+// whole.
+func (t *Tokenizer) filled() bool {
+	rest := t.buf[t.pos:]
+
+	if bytes.HasPrefix(rest, blockCommentStart) {
+		return bytes.Index(rest[len(blockCommentStart):], blockCommentEnd) >= 0
+	}
+
+	return bytes.IndexByte(rest, '\n') >= 0
+}
+
+// advance returns the row and column following data, starting at row and col.
+// Newlines start a new row at column 1, and tabs move to the next tab stop if
+// tabWidth is greater than 1. If data isn't valid UTF-8, it returns the row
+// and column of the first invalid byte and its index, -1 otherwise.
+func advance(data []byte, row, col, tabWidth int) (int, int, int) {
+	for pos := 0; pos < len(data); {
+		r, s := utf8.DecodeRune(data[pos:])
+
+		switch {
+		case r == '\n':
+			row++
+			col = 1
+		case r == utf8.RuneError && s == 1:
+			return row, col, pos
+		case r == '\t' && tabWidth > 1:
+			col = tabStop(col, tabWidth)
+		default:
+			col++
+		}
+
+		pos += s
+	}
+
+	return row, col, -1
+}
+
+// tabStop returns the column following a tab at col.
+func tabStop(col, tabWidth int) int {
+	return col + tabWidth - (col-1)%tabWidth
+}
@@ -1,6 +1,8 @@
-package parser
+package token
 
 import (
+	"context"
+	"errors"
 	"testing"
 )
 
@@ -35,6 +37,32 @@ func TestTokenValueEqual(t *testing.T) {
 	if !token.ValueEqual([]byte("hello")) {
 		t.Fatalf("expected ValueEqual() to return true but returned false")
 	}
+
+	if token.ValueEqual([]byte("Hello")) {
+		t.Fatalf("expected ValueEqual() to return false but returned true")
+	}
+
+	if !token.ValueEqualFold([]byte("Hello")) {
+		t.Fatalf("expected ValueEqualFold() to return true but returned false")
+	}
+}
+
+func TestTokenEqualFold(t *testing.T) {
+	token := &Token{Op: OpString, Value: []byte("HELLO")}
+
+	if token3.Equal(token) {
+		t.Fatalf("expected Equal() to return false but returned true")
+	}
+
+	if !token3.EqualFold(token) {
+		t.Fatalf("expected EqualFold() to return true but returned false")
+	}
+
+	token.Op = OpIdentifier
+
+	if token3.EqualFold(token) {
+		t.Fatalf("expected EqualFold() to return false but returned true")
+	}
 }
 
 func TestTokenValueEqualString(t *testing.T) {
@@ -53,10 +81,10 @@ func TestTokenValueString(t *testing.T) {
 	}
 }
 
-func TestTokenStringValues(t *testing.T) {
+func TestStringValues(t *testing.T) {
 	tokens := []*Token{token1, token2, token3}
 	expected := []string{"\n", ";", "hello"}
-	values := tokenStringValues(tokens)
+	values := StringValues(tokens)
 
 	for i, s := range values {
 		if s != expected[i] {
@@ -200,11 +228,11 @@ func TestTokenizerTokenize(t *testing.T) {
 	expectedQ.enqueue(&Token{Op: OpIdentifier, Value: []byte("uint")})
 	expectedQ.enqueue(&Token{Op: OpIdentifier, Value: []byte("C")})
 	expectedQ.enqueue(&Token{Op: OpOperator, Value: []byte("=")})
-	expectedQ.enqueue(&Token{Op: OpIdentifier, Value: []byte("1")})
+	expectedQ.enqueue(&Token{Op: OpNumber, Value: []byte("1")})
 	expectedQ.enqueue(&Token{Op: OpTerminator, Value: []byte(";")})
 	expectedQ.enqueue(&Token{Op: OpIdentifier, Value: []byte("byte")})
 	expectedQ.enqueue(&Token{Op: OpOperator, Value: []byte("<")})
-	expectedQ.enqueue(&Token{Op: OpIdentifier, Value: []byte("20")})
+	expectedQ.enqueue(&Token{Op: OpNumber, Value: []byte("20")})
 	expectedQ.enqueue(&Token{Op: OpOperator, Value: []byte(">")})
 	expectedQ.enqueue(&Token{Op: OpIdentifier, Value: []byte("x")})
 	expectedQ.enqueue(&Token{Op: OpTerminator, Value: []byte(";")})
@@ -260,15 +288,38 @@ func TestTokenizerTokenize(t *testing.T) {
 	}
 }
 
-func TestCountRunes(t *testing.T) {
+func TestAdvance(t *testing.T) {
 	data := []byte("a\nb\r\ncdéfgåí界")
-	rows, cols, err := countRunes(data)
+	row, col, invalid := advance(data, 1, 1, 1)
 
-	if err != nil {
-		t.Fatalf("not expected error %v", err)
+	if invalid >= 0 {
+		t.Fatalf("not expected invalid byte at %d", invalid)
+	}
+
+	if row != 3 || col != 9 {
+		t.Fatalf("mismatch: expected row %d and column %d, got row %d and column %d", 3, 9, row, col)
+	}
+
+	if row, col, _ = advance([]byte("\t\n\tab\t"), 1, 1, 4); row != 2 || col != 9 {
+		t.Fatalf("mismatch: expected row %d and column %d, got row %d and column %d", 2, 9, row, col)
 	}
 
-	if rows != 2 || cols != 8 {
-		t.Fatalf("mismatch: expected %d rows and %d columns, got %d rows and %d columns", 2, 8, rows, cols)
+	if row, col, invalid = advance([]byte("a\nb\xff\xef\xbf\xbd"), 1, 1, 1); row != 2 || col != 2 || invalid != 3 {
+		t.Fatalf("mismatch: expected invalid byte 3 at 2:2, got %d at %d:%d", invalid, row, col)
+	}
+
+	if _, _, invalid = advance([]byte("\xef\xbf\xbd"), 1, 1, 1); invalid >= 0 {
+		t.Fatalf("not expected invalid byte in U+FFFD")
+	}
+}
+
+func TestTokenizerTokenizeContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewTokenizer([]byte("enum EResult { OK = 1; };")).TokenizeContext(ctx)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("mismatch: got %v, but expected %v", err, context.Canceled)
 	}
 }
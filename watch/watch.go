@@ -0,0 +1,162 @@
+// Package watch re-runs an analysis whenever one of the schemas it read
+// changes. Files are polled, comparing their size and modification time.
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/13k/go-steam-language/ast"
+)
+
+// Interval is how often the watched files are polled for changes.
+var Interval = 50 * time.Millisecond
+
+// Delay is how long Watch waits for changes to settle before building again,
+// so that a burst of changes, like an editor saving through a temporary file,
+// triggers a single build.
+var Delay = 100 * time.Millisecond
+
+// Build analyzes the watched schemas, and possibly generates code from them. It
+// returns the documents it analyzed, even when failing, so that their files
+// and imports are watched.
+type Build func(ctx context.Context) ([]*ast.DocumentNode, error)
+
+// Watch runs build, then runs it again whenever one of inputs, or of the files
+// of the documents returned by the last build and their imports, changes. The
+// error of each build, nil on success, is passed to report. Filenames are
+// paths of the OS filesystem. Watch returns when ctx is done, with ctx's error.
+func Watch(ctx context.Context, inputs []string, build Build, report func(error)) error {
+	files := watchedFiles(inputs, nil)
+
+	for {
+		// files are stated before the build, so that the ones already watched
+		// can't change unnoticed while it runs
+		before := stat(files)
+		docs, err := build(ctx)
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		files = watchedFiles(inputs, docs)
+		state := stat(files)
+
+		for file := range files {
+			if prev, ok := before[file]; ok {
+				state[file] = prev
+			}
+		}
+
+		report(err)
+
+		if err := wait(ctx, files, state); err != nil {
+			return err
+		}
+	}
+}
+
+// fileState is what tells a change of a file. Missing files have the zero
+// state.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+func stat(files map[string]bool) map[string]fileState {
+	state := make(map[string]fileState, len(files))
+
+	for file := range files {
+		if info, err := os.Stat(file); err == nil {
+			state[file] = fileState{size: info.Size(), modTime: info.ModTime()}
+		} else {
+			state[file] = fileState{}
+		}
+	}
+
+	return state
+}
+
+func changed(a, b map[string]fileState) bool {
+	for file, s := range a {
+		if t := b[file]; s.size != t.size || !s.modTime.Equal(t.modTime) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// watchedFiles returns the absolute paths of inputs and of the files of docs
+// and their imports.
+func watchedFiles(inputs []string, docs []*ast.DocumentNode) map[string]bool {
+	files := make(map[string]bool)
+	seen := make(map[*ast.DocumentNode]bool)
+
+	add := func(filename string) {
+		if abs, err := filepath.Abs(filename); err == nil {
+			files[abs] = true
+		}
+	}
+
+	var addDoc func(doc *ast.DocumentNode)
+
+	addDoc = func(doc *ast.DocumentNode) {
+		if doc == nil || seen[doc] {
+			return
+		}
+
+		seen[doc] = true
+
+		if doc.Filename != "" {
+			add(doc.Filename)
+		}
+
+		for _, imp := range doc.Imports {
+			// imports that couldn't be read may still appear next to the
+			// importing file
+			if imp.Document == nil && doc.Filename != "" {
+				add(filepath.Join(filepath.Dir(doc.Filename), imp.Path))
+			}
+
+			addDoc(imp.Document)
+		}
+	}
+
+	for _, input := range inputs {
+		add(input)
+	}
+
+	for _, doc := range docs {
+		addDoc(doc)
+	}
+
+	return files
+}
+
+// wait polls files every Interval, starting from state, and returns once one
+// of them changed and no further changes were seen for Delay.
+func wait(ctx context.Context, files map[string]bool, state map[string]fileState) error {
+	ticker := time.NewTicker(Interval)
+	defer ticker.Stop()
+
+	var settled time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			current := stat(files)
+
+			if changed(state, current) {
+				state = current
+				settled = now.Add(Delay)
+			} else if !settled.IsZero() && !now.Before(settled) {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package watch
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/13k/go-steam-language/ast"
+	"github.com/13k/go-steam-language/parse"
+	"github.com/13k/go-steam-language/token"
+)
+
+func writeFile(t *testing.T, filename, data string) {
+	if err := ioutil.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.steamd")
+	base := filepath.Join(dir, "base.steamd")
+	other := filepath.Join(dir, "other.steamd")
+
+	writeFile(t, main, "#import \"base.steamd\"\nclass C { EResult r; };")
+	writeFile(t, base, "enum EResult { OK = 1; };")
+	writeFile(t, other, "enum EOther { A = 1; };")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	build := func(ctx context.Context) ([]*ast.DocumentNode, error) {
+		data, err := ioutil.ReadFile(main)
+
+		if err != nil {
+			return nil, err
+		}
+
+		doc, err := parse.NewAnalyzer(token.NewTokenizer(data), main).AnalyzeContext(ctx)
+		return []*ast.DocumentNode{doc}, err
+	}
+
+	reports := make(chan error, 10)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- Watch(ctx, []string{main}, build, func(err error) { reports <- err })
+	}()
+
+	next := func() error {
+		select {
+		case err := <-reports:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected a build")
+			return nil
+		}
+	}
+
+	if err := next(); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	writeFile(t, other, "enum EOther { A = 2; };")
+	writeFile(t, base, "enum EResult { OK = ; };")
+
+	if err := next(); err == nil {
+		t.Fatalf("expected error after breaking the import")
+	}
+
+	select {
+	case err := <-reports:
+		t.Fatalf("expected a single build, got another one reporting %v", err)
+	case <-time.After(3 * Delay):
+	}
+
+	writeFile(t, base, "enum EResult { OK = 1; };")
+
+	if err := next(); err != nil {
+		t.Fatalf("not expected error %v", err)
+	}
+
+	cancel()
+
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("mismatch: got %v, but expected %v", err, context.Canceled)
+	}
+}